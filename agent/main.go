@@ -44,12 +44,18 @@ func main() {
 		IncludeLocation: true,
 	})
 
+	// Replay a recorded test run instead of running the agent
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:], logger))
+	}
+
 	// Create a safe restart flag (which can safely be set by other go routines)
 	var restartSync atomic.Value
 	restartSync.Store(true)
 	for restartSync.Load().(bool) {
 		s := SynHeart{}
 		logger.Info("starting synthetic heart", "version", Version)
+		pluginmanager.AgentVersion = Version // reported in the agent status
 		restartSync.Store(false)
 		ctx, cancel := context.WithCancel(context.Background())
 
@@ -0,0 +1,222 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultAlertFailureThreshold = 3
+	DefaultAlertResendInterval   = 1 * time.Minute
+	SyntheticTestFailingAlert    = "SyntheticTestFailing"
+)
+
+// AlertmanagerNotifier fires alerts to the alertmanager api when a test fails a number of consecutive times,
+// and resolves them when the test passes again
+type AlertmanagerNotifier struct {
+	config   common.AlertmanagerConfig
+	client   *http.Client
+	agentId  string
+	tests    map[string]*alertState // state of every test, by test config id
+	silences *SilenceMap
+	logger   hclog.Logger
+}
+
+// alertState tracks the consecutive failures of a test, and its alert (if firing)
+type alertState struct {
+	consecutiveFailures int
+	firing              bool
+	alert               alertmanagerAlert
+}
+
+// alertmanagerAlert is an alert in the alertmanager v2 api
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+func NewAlertmanagerNotifier(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, silences *SilenceMap) (AlertmanagerNotifier, error) {
+	a := AlertmanagerNotifier{config: agentConfig.AlertmanagerConfig, agentId: agentId, silences: silences, logger: logger}
+	if a.config.FailureThreshold <= 0 {
+		a.config.FailureThreshold = DefaultAlertFailureThreshold
+	}
+	if a.config.ResendInterval <= 0 {
+		a.config.ResendInterval = DefaultAlertResendInterval
+	}
+	a.config.Url = strings.TrimSuffix(a.config.Url, "/")
+	a.client = &http.Client{Timeout: 10 * time.Second}
+	a.tests = map[string]*alertState{}
+	return a, nil
+}
+
+func (a *AlertmanagerNotifier) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("alertmanager", common.DefaultChannelSize, a.logger)
+	ticker := time.NewTicker(a.config.ResendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case res := <-resChan:
+			a.ProcessTestRun(ctx, res)
+		case <-ticker.C:
+			// re-send firing alerts, so alertmanager doesn't resolve them
+			err := a.send(ctx, a.firingAlerts())
+			if err != nil {
+				a.logger.Error("error re-sending alerts to alertmanager", "err", err)
+			}
+		case <-ctx.Done():
+			a.logger.Info("alertmanager notifier exiting")
+			return
+		}
+	}
+}
+
+// ProcessTestRun Updates the consecutive failures of the test, fires an alert if it reached the threshold, or
+// resolves the alert if the test passed
+func (a *AlertmanagerNotifier) ProcessTestRun(ctx context.Context, testRun proto.TestRun) {
+	testId := common.ComputeSynTestConfigId(testRun.TestConfig.Name, testRun.TestConfig.Namespace)
+	state, ok := a.tests[testId]
+	if !ok {
+		state = &alertState{}
+		a.tests[testId] = state
+	}
+	alerting := testRun.TestConfig.GetAlerting()
+
+	if testRun.TestResult.Marks >= testRun.TestResult.MaxMarks {
+		state.consecutiveFailures = 0
+		if state.firing {
+			state.firing = false
+			endsAt := time.Now()
+			state.alert.EndsAt = &endsAt
+			a.logger.Info("resolving alert", "test", testId)
+			err := a.send(ctx, []alertmanagerAlert{state.alert})
+			if err != nil {
+				a.logger.Error("error resolving alert", "test", testId, "err", err)
+			}
+		}
+		return
+	}
+
+	state.consecutiveFailures++
+	threshold := a.config.FailureThreshold
+	if alerting.GetFailureThreshold() > 0 {
+		threshold = int(alerting.GetFailureThreshold())
+	}
+	if alerting.GetDisabled() || state.consecutiveFailures < threshold || a.silences.Silenced(testRun.TestConfig) {
+		return
+	}
+	if !state.firing {
+		state.firing = true
+		state.alert = alertmanagerAlert{StartsAt: time.Now(), GeneratorURL: a.config.GeneratorUrl}
+		a.logger.Info("firing alert", "test", testId, "consecutiveFailures", state.consecutiveFailures)
+	}
+	// update the labels/annotations with the latest test run
+	state.alert.Labels, state.alert.Annotations = a.alertLabels(testRun, state.consecutiveFailures)
+	err := a.send(ctx, []alertmanagerAlert{state.alert})
+	if err != nil {
+		a.logger.Error("error sending alert", "test", testId, "err", err)
+	}
+}
+
+func (a *AlertmanagerNotifier) alertLabels(testRun proto.TestRun, consecutiveFailures int) (map[string]string, map[string]string) {
+	testConfig := testRun.TestConfig
+	labels := map[string]string{}
+	for k, v := range a.config.Labels {
+		labels[k] = v
+	}
+	for k, v := range testConfig.GetAlerting().GetLabels() {
+		labels[k] = v
+	}
+	labels["alertname"] = SyntheticTestFailingAlert
+	labels["test_name"] = testConfig.Name
+	labels["test_namespace"] = testConfig.Namespace
+	labels["plugin"] = testConfig.PluginName
+	labels["agent_id"] = a.agentId
+	labels["importance"] = common.ImportanceLevel(testConfig.Importance)
+	if _, ok := labels["severity"]; !ok {
+		labels["severity"] = common.ImportanceSeverities[labels["importance"]]
+	}
+
+	displayName := testConfig.DisplayName
+	if displayName == "" {
+		displayName = testConfig.Name
+	}
+	annotations := map[string]string{
+		"summary": fmt.Sprintf("Synthetic test %s failed %d consecutive times", displayName, consecutiveFailures),
+		"description": fmt.Sprintf("test %s/%s failed on agent %s (%d/%d): %s", testConfig.Namespace, testConfig.Name,
+			a.agentId, testRun.TestResult.Marks, testRun.TestResult.MaxMarks, testRun.TestResult.Details[common.ErrorKey]),
+	}
+	if testConfig.Description != "" {
+		annotations["test_description"] = testConfig.Description
+	}
+	for k, v := range testConfig.GetAlerting().GetAnnotations() {
+		annotations[k] = v
+	}
+	return labels, annotations
+}
+
+func (a *AlertmanagerNotifier) firingAlerts() []alertmanagerAlert {
+	alerts := []alertmanagerAlert{}
+	for _, state := range a.tests {
+		if state.firing {
+			alerts = append(alerts, state.alert)
+		}
+	}
+	return alerts
+}
+
+// send Posts the alerts to the alertmanager v2 api
+func (a *AlertmanagerNotifier) send(ctx context.Context, alerts []alertmanagerAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling alerts")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.Url+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request to alertmanager")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("alertmanager returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+)
+
+// AuditLogger records the lifecycle events of the plugins (started, stopped, restarts) in the audit log
+type AuditLogger struct {
+	agentId string
+	store   storage.SynHeartStore
+	logger  hclog.Logger
+}
+
+func NewAuditLogger(logger hclog.Logger, agentId string, store storage.SynHeartStore) AuditLogger {
+	return AuditLogger{agentId: agentId, store: store, logger: logger}
+}
+
+// Record Adds a plugin event to the audit log, errors are only logged
+func (a *AuditLogger) Record(pluginId string, action string, message string, details map[string]string) {
+	if a == nil {
+		return
+	}
+	event := common.AuditEvent{
+		Time:    time.Now(),
+		Source:  a.agentId,
+		Kind:    common.AuditKindPlugin,
+		Object:  pluginId,
+		Action:  action,
+		Message: message,
+		Details: details,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := a.store.WriteAuditEvent(ctx, event)
+	if err != nil {
+		a.logger.Warn("error writing audit event", "pluginId", pluginId, "action", action, "err", err)
+	}
+}
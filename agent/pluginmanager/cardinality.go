@@ -0,0 +1,95 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	DefaultMaxLabelValues = 100
+	OverflowLabelValue    = "other"
+	DroppedSeriesCounter  = "syntheticheart_exporter_dropped_series_total"
+)
+
+// droppedSeries is registered once, as the exporter can be recreated (e.g. when an agent profile changes its config)
+var droppedSeries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: DroppedSeriesCounter,
+	Help: "The number of custom metric updates whose label values were replaced with '" + OverflowLabelValue + "' by the cardinality limit",
+}, []string{"metric"})
+
+// cardinalityLimiter caps the number of distinct values of every label (per metric) added by plugins,
+// any new values over the limit are replaced with OverflowLabelValue
+type cardinalityLimiter struct {
+	maxValues int
+	values    map[string]map[string]time.Time // metric/label -> label value -> last seen
+}
+
+func newCardinalityLimiter(maxValues int) *cardinalityLimiter {
+	if maxValues == 0 {
+		maxValues = DefaultMaxLabelValues
+	}
+	return &cardinalityLimiter{maxValues: maxValues, values: map[string]map[string]time.Time{}}
+}
+
+// Limit Replaces the label values that are over the limit (in place), returns whether any value was replaced
+func (c *cardinalityLimiter) Limit(metric string, labels map[string]string) bool {
+	if c.maxValues < 0 { // limit disabled
+		return false
+	}
+	limited := false
+	now := time.Now()
+	for k, v := range labels {
+		key := metric + "/" + k
+		seen, ok := c.values[key]
+		if !ok {
+			seen = map[string]time.Time{}
+			c.values[key] = seen
+		}
+		if _, ok := seen[v]; ok || len(seen) < c.maxValues {
+			seen[v] = now
+			continue
+		}
+		labels[k] = OverflowLabelValue
+		limited = true
+	}
+	if limited {
+		droppedSeries.WithLabelValues(metric).Inc()
+	}
+	return limited
+}
+
+// Expire Forgets label values not seen within the window (i.e. their series have been removed)
+func (c *cardinalityLimiter) Expire(now time.Time, window time.Duration) {
+	for key, seen := range c.values {
+		for v, lastSeen := range seen {
+			if now.Sub(lastSeen) > window {
+				delete(seen, v)
+			}
+		}
+		if len(seen) == 0 {
+			delete(c.values, key)
+		}
+	}
+}
+
+func (c *cardinalityLimiter) Reset() {
+	c.values = map[string]map[string]time.Time{}
+}
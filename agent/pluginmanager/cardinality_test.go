@@ -0,0 +1,131 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCardinalityLimiter(t *testing.T) {
+	type update struct {
+		metric      string
+		labels      map[string]string
+		wantLabels  map[string]string
+		wantLimited bool
+	}
+	tests := []struct {
+		name      string
+		maxValues int
+		updates   []update
+	}{
+		{
+			name:      "values over the limit go to the overflow bucket",
+			maxValues: 2,
+			updates: []update{
+				{"latency", map[string]string{"host": "a"}, map[string]string{"host": "a"}, false},
+				{"latency", map[string]string{"host": "b"}, map[string]string{"host": "b"}, false},
+				{"latency", map[string]string{"host": "c"}, map[string]string{"host": OverflowLabelValue}, true},
+				{"latency", map[string]string{"host": "a"}, map[string]string{"host": "a"}, false}, // already seen
+				{"latency", map[string]string{"host": "d"}, map[string]string{"host": OverflowLabelValue}, true},
+			},
+		},
+		{
+			name:      "only the labels over the limit are replaced",
+			maxValues: 1,
+			updates: []update{
+				{"latency", map[string]string{"host": "a", "region": "eu"}, map[string]string{"host": "a", "region": "eu"}, false},
+				{"latency", map[string]string{"host": "b", "region": "eu"}, map[string]string{"host": OverflowLabelValue, "region": "eu"}, true},
+				{"latency", map[string]string{"host": "a", "region": "us"}, map[string]string{"host": "a", "region": OverflowLabelValue}, true},
+			},
+		},
+		{
+			name:      "every metric has its own limit",
+			maxValues: 1,
+			updates: []update{
+				{"latency", map[string]string{"host": "a"}, map[string]string{"host": "a"}, false},
+				{"errors", map[string]string{"host": "b"}, map[string]string{"host": "b"}, false},
+				{"latency", map[string]string{"host": "b"}, map[string]string{"host": OverflowLabelValue}, true},
+			},
+		},
+		{
+			name:      "disabled",
+			maxValues: -1,
+			updates: []update{
+				{"latency", map[string]string{"host": "a"}, map[string]string{"host": "a"}, false},
+				{"latency", map[string]string{"host": "b"}, map[string]string{"host": "b"}, false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCardinalityLimiter(tt.maxValues)
+			for i, u := range tt.updates {
+				dropped := testutil.ToFloat64(droppedSeries.WithLabelValues(u.metric))
+				limited := c.Limit(u.metric, u.labels)
+				if limited != u.wantLimited {
+					t.Errorf("update %d: Limit() = %v, want %v", i, limited, u.wantLimited)
+				}
+				if !reflect.DeepEqual(u.labels, u.wantLabels) {
+					t.Errorf("update %d: labels = %v, want %v", i, u.labels, u.wantLabels)
+				}
+				wantDropped := dropped
+				if u.wantLimited {
+					wantDropped++
+				}
+				if got := testutil.ToFloat64(droppedSeries.WithLabelValues(u.metric)); got != wantDropped {
+					t.Errorf("update %d: dropped series = %v, want %v", i, got, wantDropped)
+				}
+			}
+		})
+	}
+}
+
+func TestCardinalityLimiterDefault(t *testing.T) {
+	c := newCardinalityLimiter(0)
+	if c.maxValues != DefaultMaxLabelValues {
+		t.Errorf("maxValues = %d, want %d", c.maxValues, DefaultMaxLabelValues)
+	}
+}
+
+func TestCardinalityLimiterExpire(t *testing.T) {
+	c := newCardinalityLimiter(1)
+	c.Limit("latency", map[string]string{"host": "a"})
+
+	// a isn't expired yet, so b still overflows
+	c.Expire(time.Now(), time.Hour)
+	if labels := map[string]string{"host": "b"}; !c.Limit("latency", labels) {
+		t.Errorf("Limit() = false before a expired, labels %v", labels)
+	}
+
+	// once a is expired, b takes its place
+	c.Expire(time.Now().Add(2*time.Hour), time.Hour)
+	if labels := map[string]string{"host": "b"}; c.Limit("latency", labels) {
+		t.Errorf("Limit() = true after a expired, labels %v", labels)
+	}
+	if labels := map[string]string{"host": "a"}; !c.Limit("latency", labels) {
+		t.Errorf("Limit() = false for a after b took its place, labels %v", labels)
+	}
+
+	c.Reset()
+	if labels := map[string]string{"host": "c"}; c.Limit("latency", labels) {
+		t.Errorf("Limit() = true after a reset, labels %v", labels)
+	}
+}
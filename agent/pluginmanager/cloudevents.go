@@ -0,0 +1,192 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	CloudEventsSpecVersion      = "1.0"
+	CloudEventsModeBinary       = "binary"
+	CloudEventsModeStructured   = "structured"
+	CloudEventTypeTestPassed    = "com.synthetic-heart.testrun.passed"
+	CloudEventTypeTestFailed    = "com.synthetic-heart.testrun.failed"
+	DefaultCloudEventsTimeout   = 10 * time.Second
+	cloudEventsStructuredCType  = "application/cloudevents+json"
+	cloudEventsDataContentType  = "application/json"
+	cloudEventsDefaultSourceFmt = "/synthetic-heart/agent/"
+)
+
+// CloudEventsExporter posts every test run as a CloudEvent to a http sink (e.g. Knative broker, EventBridge api destination)
+type CloudEventsExporter struct {
+	config     common.CloudEventsConfig
+	client     *http.Client
+	agentId    string
+	marshaller protojson.MarshalOptions
+	logger     hclog.Logger
+}
+
+// cloudEvent is a CloudEvent (v1.0) in the structured json format
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	// extensions
+	TestName      string `json:"testname"`
+	TestNamespace string `json:"testnamespace"`
+	AgentId       string `json:"agentid"`
+}
+
+func NewCloudEventsExporter(logger hclog.Logger, agentConfig common.AgentConfig, agentId string) (CloudEventsExporter, error) {
+	c := CloudEventsExporter{config: agentConfig.CloudEventsConfig, agentId: agentId, logger: logger}
+	if c.config.Mode == "" {
+		c.config.Mode = CloudEventsModeBinary
+	}
+	if c.config.Mode != CloudEventsModeBinary && c.config.Mode != CloudEventsModeStructured {
+		return c, errors.New("invalid cloudevents mode: " + c.config.Mode + ", must be binary or structured")
+	}
+	if c.config.Source == "" {
+		c.config.Source = cloudEventsDefaultSourceFmt + agentId
+	}
+	if c.config.Timeout <= 0 {
+		c.config.Timeout = DefaultCloudEventsTimeout
+	}
+	c.client = &http.Client{Timeout: c.config.Timeout}
+	c.marshaller = protojson.MarshalOptions{EmitUnpopulated: true}
+	return c, nil
+}
+
+func (c *CloudEventsExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("cloudevents", common.DefaultChannelSize, c.logger)
+	for {
+		select {
+		case res := <-resChan:
+			if c.config.OnlyFailed && res.TestResult.Marks >= res.TestResult.MaxMarks {
+				continue
+			}
+			err := c.SendTestRun(ctx, res)
+			if err != nil {
+				c.logger.Error("error sending cloudevent", "test", res.TestConfig.Name, "runId", res.Id, "err", err)
+			}
+		case <-ctx.Done():
+			c.logger.Info("cloudevents exporter exiting")
+			return
+		}
+	}
+}
+
+// SendTestRun Posts the test run as a CloudEvent to the sink
+func (c *CloudEventsExporter) SendTestRun(ctx context.Context, testRun proto.TestRun) error {
+	event, err := c.newEvent(testRun)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	headers := http.Header{}
+	if c.config.Mode == CloudEventsModeStructured {
+		body, err = json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "error marshalling cloudevent")
+		}
+		headers.Set("Content-Type", cloudEventsStructuredCType)
+	} else {
+		// binary mode: the attributes are http headers and the body is the data
+		body = event.Data
+		headers.Set("Content-Type", event.DataContentType)
+		headers.Set("ce-specversion", event.SpecVersion)
+		headers.Set("ce-id", event.Id)
+		headers.Set("ce-source", event.Source)
+		headers.Set("ce-type", event.Type)
+		headers.Set("ce-subject", event.Subject)
+		headers.Set("ce-time", event.Time)
+		headers.Set("ce-testname", event.TestName)
+		headers.Set("ce-testnamespace", event.TestNamespace)
+		headers.Set("ce-agentid", event.AgentId)
+	}
+	return c.post(ctx, headers, body)
+}
+
+func (c *CloudEventsExporter) newEvent(testRun proto.TestRun) (cloudEvent, error) {
+	data, err := c.marshaller.Marshal(&testRun)
+	if err != nil {
+		return cloudEvent{}, errors.Wrap(err, "error marshalling test run")
+	}
+	eventTime := time.Now()
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err == nil {
+		eventTime = endTime
+	}
+	eventType := CloudEventTypeTestPassed
+	if testRun.TestResult.Marks < testRun.TestResult.MaxMarks {
+		eventType = CloudEventTypeTestFailed
+	}
+	return cloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		Id:              c.agentId + "/" + testRun.Id, // run ids are only unique per agent
+		Source:          c.config.Source,
+		Type:            eventType,
+		Subject:         common.ComputeSynTestConfigId(testRun.TestConfig.Name, testRun.TestConfig.Namespace),
+		Time:            eventTime.UTC().Format(time.RFC3339Nano),
+		DataContentType: cloudEventsDataContentType,
+		Data:            data,
+		TestName:        testRun.TestConfig.Name,
+		TestNamespace:   testRun.TestConfig.Namespace,
+		AgentId:         c.agentId,
+	}, nil
+}
+
+func (c *CloudEventsExporter) post(ctx context.Context, headers http.Header, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Sink, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	for k, v := range c.config.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request to sink")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("sink returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
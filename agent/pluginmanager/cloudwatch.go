@@ -0,0 +1,182 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultCloudWatchFlushInterval = 60 * time.Second
+	CloudWatchMaxDatumsPerRequest  = 1000
+	CloudWatchMaxDimensions        = 30
+)
+
+// CloudWatchExporter publishes the result (pass/fail) and latency of every test run to AWS CloudWatch
+// The average of the TestPassed metric over a period is the pass rate of the test
+type CloudWatchExporter struct {
+	config      common.CloudWatchConfig
+	client      *cloudwatch.Client
+	datums      []types.MetricDatum // metrics buffered till the next flush
+	logger      hclog.Logger
+	runTimeInfo common.AgentInfo
+}
+
+func NewCloudWatchExporter(ctx context.Context, logger hclog.Logger, agentConfig common.AgentConfig) (CloudWatchExporter, error) {
+	c := CloudWatchExporter{config: agentConfig.CloudWatchConfig, logger: logger, runTimeInfo: agentConfig.RunTimeInfo}
+	if c.config.FlushInterval <= 0 {
+		c.config.FlushInterval = DefaultCloudWatchFlushInterval
+	}
+
+	// credentials are picked up from the environment (e.g. IRSA on EKS)
+	var opts []func(*awsconfig.LoadOptions) error
+	if c.config.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(c.config.Region))
+	}
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return c, errors.Wrap(err, "error loading aws config")
+	}
+	c.client = cloudwatch.NewFromConfig(awsConfig)
+	return c, nil
+}
+
+func (c *CloudWatchExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("cloudwatch", common.DefaultChannelSize, c.logger)
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case res := <-resChan:
+			err := c.AddTestRunMetrics(res)
+			if err != nil {
+				c.logger.Error("error adding test run metrics", "err", err)
+			}
+		case <-ticker.C:
+			err := c.Flush(ctx)
+			if err != nil {
+				c.logger.Error("error publishing metrics to cloudwatch", "err", err)
+			}
+		case <-ctx.Done():
+			// publish whatever is left in the buffer
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := c.Flush(flushCtx)
+			cancel()
+			if err != nil {
+				c.logger.Error("error publishing metrics to cloudwatch", "err", err)
+			}
+			c.logger.Info("cloudwatch exporter exiting")
+			return
+		}
+	}
+}
+
+// AddTestRunMetrics Adds the metrics of the test run to the buffer, they are published on the next flush
+func (c *CloudWatchExporter) AddTestRunMetrics(testRun proto.TestRun) error {
+	startTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing start time")
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing end time")
+	}
+
+	dimensions, err := c.dimensions(testRun.TestConfig)
+	if err != nil {
+		return err
+	}
+
+	passed := 1.0
+	if testRun.TestResult.Marks < testRun.TestResult.MaxMarks {
+		passed = 0.0
+	}
+	c.datums = append(c.datums,
+		types.MetricDatum{
+			MetricName: aws.String("TestPassed"),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(endTime),
+			Unit:       types.StandardUnitNone,
+			Value:      aws.Float64(passed),
+		},
+		types.MetricDatum{
+			MetricName: aws.String("TestDuration"),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(endTime),
+			Unit:       types.StandardUnitMilliseconds,
+			Value:      aws.Float64(float64(endTime.Sub(startTime).Microseconds()) / 1000),
+		},
+		types.MetricDatum{
+			MetricName: aws.String("TestMarks"),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(endTime),
+			Unit:       types.StandardUnitCount,
+			Value:      aws.Float64(float64(testRun.TestResult.Marks)),
+		})
+	return nil
+}
+
+// Flush Publishes all the buffered metrics to cloudwatch
+func (c *CloudWatchExporter) Flush(ctx context.Context) error {
+	for len(c.datums) > 0 {
+		n := len(c.datums)
+		if n > CloudWatchMaxDatumsPerRequest {
+			n = CloudWatchMaxDatumsPerRequest
+		}
+		_, err := c.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(c.config.Namespace),
+			MetricData: c.datums[:n],
+		})
+		if err != nil {
+			return err
+		}
+		c.datums = c.datums[n:]
+	}
+	return nil
+}
+
+func (c *CloudWatchExporter) dimensions(testConfig *proto.SynTestConfig) ([]types.Dimension, error) {
+	rendered, err := renderLabelTemplates(c.config.Dimensions, &c.runTimeInfo, testConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error rendering cloudwatch dimensions")
+	}
+	rendered["TestName"] = testConfig.Name
+	rendered["TestNamespace"] = testConfig.Namespace
+	if len(rendered) > CloudWatchMaxDimensions {
+		return nil, errors.Errorf("too many cloudwatch dimensions (%d), max is %d", len(rendered), CloudWatchMaxDimensions)
+	}
+
+	var dimensions []types.Dimension
+	for k, v := range rendered {
+		if v == "" { // cloudwatch doesn't allow empty dimension values
+			continue
+		}
+		dimensions = append(dimensions, types.Dimension{Name: aws.String(k), Value: aws.String(v)})
+	}
+	return dimensions, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultDatadogSite          = "datadoghq.com"
+	DefaultDatadogApiKeyEnv     = "DD_API_KEY"
+	DefaultDatadogFlushInterval = 30 * time.Second
+	DatadogMetricTypeGauge      = 3
+)
+
+// DatadogExporter sends test run metrics, test state changes and agent health events to the Datadog api
+type DatadogExporter struct {
+	config      common.DatadogConfig
+	apiKey      string
+	client      *http.Client
+	sm          *StateMap
+	silences    *SilenceMap
+	agentId     string
+	series      []datadogSeries                 // metrics buffered till the next flush
+	testPassed  map[string]bool                 // last known result of every test, to send events when it changes
+	pluginState map[string]common.RoutineStatus // last known status of every plugin, to send events when it changes
+	logger      hclog.Logger
+	runTimeInfo common.AgentInfo
+}
+
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type datadogSeries struct {
+	Metric string         `json:"metric"`
+	Type   int            `json:"type"`
+	Points []datadogPoint `json:"points"`
+	Tags   []string       `json:"tags"`
+}
+
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	Tags           []string `json:"tags"`
+}
+
+func NewDatadogExporter(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, sm *StateMap, silences *SilenceMap) (DatadogExporter, error) {
+	d := DatadogExporter{config: agentConfig.DatadogConfig, sm: sm, silences: silences, agentId: agentId, logger: logger, runTimeInfo: agentConfig.RunTimeInfo}
+	if d.config.Site == "" {
+		d.config.Site = DefaultDatadogSite
+	}
+	if d.config.ApiKeyEnv == "" {
+		d.config.ApiKeyEnv = DefaultDatadogApiKeyEnv
+	}
+	if d.config.FlushInterval <= 0 {
+		d.config.FlushInterval = DefaultDatadogFlushInterval
+	}
+	d.apiKey = os.Getenv(d.config.ApiKeyEnv)
+	if d.apiKey == "" {
+		return d, errors.New("datadog api key not found in env var " + d.config.ApiKeyEnv)
+	}
+	d.client = &http.Client{Timeout: 10 * time.Second}
+	d.testPassed = map[string]bool{}
+	d.pluginState = map[string]common.RoutineStatus{}
+	return d, nil
+}
+
+func (d *DatadogExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("datadog", common.DefaultChannelSize, d.logger)
+	ticker := time.NewTicker(d.config.FlushInterval)
+	defer ticker.Stop()
+
+	d.sendEvent(ctx, datadogEvent{
+		Title:     "synthetic-heart agent started: " + d.agentId,
+		Text:      fmt.Sprintf("agent %s started on node %s", d.agentId, d.runTimeInfo.NodeName),
+		AlertType: "info",
+		Tags:      d.agentTags(),
+	})
+	for {
+		select {
+		case res := <-resChan:
+			d.AddTestRunMetrics(ctx, res)
+		case <-ticker.C:
+			d.checkPluginHealth(ctx)
+			err := d.Flush(ctx)
+			if err != nil {
+				d.logger.Error("error sending metrics to datadog", "err", err)
+			}
+		case <-ctx.Done():
+			exitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := d.Flush(exitCtx)
+			if err != nil {
+				d.logger.Error("error sending metrics to datadog", "err", err)
+			}
+			d.sendEvent(exitCtx, datadogEvent{
+				Title:     "synthetic-heart agent stopped: " + d.agentId,
+				Text:      fmt.Sprintf("agent %s on node %s is exiting", d.agentId, d.runTimeInfo.NodeName),
+				AlertType: "warning",
+				Tags:      d.agentTags(),
+			})
+			cancel()
+			d.logger.Info("datadog exporter exiting")
+			return
+		}
+	}
+}
+
+// AddTestRunMetrics Buffers the metrics of the test run, and sends an event if the test started failing (or recovered)
+func (d *DatadogExporter) AddTestRunMetrics(ctx context.Context, testRun proto.TestRun) {
+	startTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		d.logger.Error("error parsing start time", "err", err)
+		return
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err != nil {
+		d.logger.Error("error parsing end time", "err", err)
+		return
+	}
+
+	passed := testRun.TestResult.Marks >= testRun.TestResult.MaxMarks
+	passedVal := 0.0
+	if passed {
+		passedVal = 1.0
+	}
+	tags := d.testTags(testRun.TestConfig)
+	ts := endTime.Unix()
+	d.series = append(d.series,
+		datadogSeries{Metric: "syntheticheart.test.passed", Type: DatadogMetricTypeGauge, Tags: tags,
+			Points: []datadogPoint{{Timestamp: ts, Value: passedVal}}},
+		datadogSeries{Metric: "syntheticheart.test.duration_ms", Type: DatadogMetricTypeGauge, Tags: tags,
+			Points: []datadogPoint{{Timestamp: ts, Value: float64(endTime.Sub(startTime).Microseconds()) / 1000}}},
+		datadogSeries{Metric: "syntheticheart.test.marks", Type: DatadogMetricTypeGauge, Tags: tags,
+			Points: []datadogPoint{{Timestamp: ts, Value: float64(testRun.TestResult.Marks)}}})
+
+	// only send events when the result changes
+	testId := common.ComputeSynTestConfigId(testRun.TestConfig.Name, testRun.TestConfig.Namespace)
+	lastPassed, seen := d.testPassed[testId]
+	d.testPassed[testId] = passed
+	if (!seen && passed) || (seen && lastPassed == passed) || d.silences.Silenced(testRun.TestConfig) {
+		return
+	}
+	event := datadogEvent{
+		Title:          "synthetic test recovered: " + testId,
+		Text:           fmt.Sprintf("test %s passed on agent %s (%d/%d)", testId, d.agentId, testRun.TestResult.Marks, testRun.TestResult.MaxMarks),
+		AlertType:      "success",
+		AggregationKey: testId,
+		Tags:           tags,
+	}
+	if !passed {
+		event.Title = "synthetic test failing: " + testId
+		event.Text = fmt.Sprintf("test %s failed on agent %s (%d/%d): %s", testId, d.agentId, testRun.TestResult.Marks,
+			testRun.TestResult.MaxMarks, testRun.TestResult.Details[common.ErrorKey])
+		event.AlertType = "error"
+	}
+	d.sendEvent(ctx, event)
+}
+
+// checkPluginHealth Sends an event whenever a plugin goes into error/restarting state
+func (d *DatadogExporter) checkPluginHealth(ctx context.Context) {
+	for pluginId, state := range d.sm.GetAllPluginState().PluginStates {
+		lastStatus := d.pluginState[pluginId]
+		d.pluginState[pluginId] = state.Status
+		if lastStatus == state.Status || (state.Status != common.Error && state.Status != common.Restarting) {
+			continue
+		}
+		d.sendEvent(ctx, datadogEvent{
+			Title:          fmt.Sprintf("synthetic-heart plugin %s: %s", state.Status, pluginId),
+			Text:           fmt.Sprintf("plugin %s is %s (total restarts: %d): %s", pluginId, state.Status, state.TotalRestarts, state.StatusMsg),
+			AlertType:      "warning",
+			AggregationKey: pluginId,
+			Tags:           d.agentTags(),
+		})
+	}
+}
+
+// Flush Sends all the buffered metrics to datadog
+func (d *DatadogExporter) Flush(ctx context.Context) error {
+	if len(d.series) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"series": d.series})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling series")
+	}
+	err = d.post(ctx, "/api/v2/series", body)
+	if err != nil {
+		return err
+	}
+	d.series = nil
+	return nil
+}
+
+func (d *DatadogExporter) sendEvent(ctx context.Context, event datadogEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("error marshalling event", "err", err)
+		return
+	}
+	err = d.post(ctx, "/api/v1/events", body)
+	if err != nil {
+		d.logger.Error("error sending event to datadog", "title", event.Title, "err", err)
+	}
+}
+
+func (d *DatadogExporter) post(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api."+d.config.Site+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", d.apiKey)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request to datadog")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("datadog returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+func (d *DatadogExporter) agentTags() []string {
+	tags := []string{"agent_id:" + d.agentId, "test_node:" + d.runTimeInfo.NodeName}
+	for k, v := range d.config.Tags {
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (d *DatadogExporter) testTags(testConfig *proto.SynTestConfig) []string {
+	tags := d.agentTags()
+	tags = append(tags, "test_name:"+testConfig.Name, "test_namespace:"+testConfig.Namespace, "plugin:"+testConfig.PluginName,
+		"importance:"+common.ImportanceLevel(testConfig.Importance))
+	if len(d.config.TestLabels) == 0 {
+		for k, v := range testConfig.Labels {
+			tags = append(tags, k+":"+v)
+		}
+	} else {
+		for _, k := range d.config.TestLabels {
+			if v, ok := testConfig.Labels[k]; ok {
+				tags = append(tags, k+":"+v)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
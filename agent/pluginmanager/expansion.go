@@ -0,0 +1,119 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/pkg/errors"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// configEnvRegex matches the env vars in the configs of the tests: ${NAME}, or ${NAME:-default} which is replaced with
+// the default if the env var is unset or empty. $${NAME} is escaped, and replaced with ${NAME}.
+var configEnvRegex = regexp.MustCompile(`\$\$?\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// configTemplateData is the data the configs of the tests are rendered with,
+// e.g. "{{ .Agent.NodeName }}", "{{ .Vars.region }}" or "{{ index .Agent.PodLabels "zone" }}"
+type configTemplateData struct {
+	Agent      *common.AgentInfo
+	TestConfig *proto.SynTestConfig
+	Vars       map[string]string // from the agent config
+	Env        map[string]string // the allowed env vars which are set
+	Secrets    map[string]string // the secret refs of the test, by name
+}
+
+// expandConfig Returns the config of the test with its go templates rendered, and then its env vars expanded. The
+// config is returned as is if expansion isn't enabled, unless the test references secrets: it's then only rendered
+// (with the secrets). Unknown vars and env vars which aren't allowed are errors.
+func expandConfig(testConfig *proto.SynTestConfig, expansion common.ConfigExpansionConfig, agentInfo *common.AgentInfo,
+	secrets map[string]string) (string, error) {
+	if !expansion.Enabled && len(testConfig.SecretRefs) == 0 {
+		return testConfig.Config, nil
+	}
+
+	allowedEnv := func(name string) bool {
+		for _, pattern := range expansion.AllowedEnv {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	data := configTemplateData{Agent: agentInfo, TestConfig: testConfig, Vars: expansion.Vars, Env: map[string]string{},
+		Secrets: map[string]string{}}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+	for _, kv := range os.Environ() {
+		name, val, _ := strings.Cut(kv, "=")
+		if allowedEnv(name) {
+			data.Env[name] = val
+		}
+	}
+	for name, val := range secrets {
+		if expansion.Enabled {
+			val = strings.ReplaceAll(val, "${", "$${") // the env vars aren't expanded in the secrets
+		}
+		data.Secrets[name] = val
+	}
+
+	tmpl, err := template.New("config").Option("missingkey=error").Parse(testConfig.Config)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing config template")
+	}
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, data)
+	if err != nil {
+		return "", errors.Wrap(err, "error rendering config template")
+	}
+	if !expansion.Enabled {
+		return rendered.String(), nil
+	}
+
+	var envErr error
+	config := configEnvRegex.ReplaceAllStringFunc(rendered.String(), func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		groups := configEnvRegex.FindStringSubmatch(match)
+		name, def := groups[1], strings.TrimPrefix(groups[2], ":-")
+		if !allowedEnv(name) {
+			if envErr == nil {
+				envErr = errors.New("env var " + name + " isn't allowed in configs (configExpansion.allowedEnv)")
+			}
+			return match
+		}
+		val, ok := data.Env[name]
+		if val == "" && groups[2] != "" {
+			return def
+		}
+		if !ok && envErr == nil {
+			envErr = errors.New("env var " + name + " isn't set")
+		}
+		return val
+	})
+	if envErr != nil {
+		return "", envErr
+	}
+	return config, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+// PluginExporter runs an exporter plugin (exporter-<name> binary) and sends it every broadcasted test run,
+// the plugin is started once and restarted if it exits
+type PluginExporter struct {
+	config      common.ExporterPluginConfig
+	agentId     string
+	runTimeInfo common.AgentInfo
+	logger      hclog.Logger
+	client      *plugin.Client
+	exporter    common.ExporterPlugin
+}
+
+func NewPluginExporter(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, config common.ExporterPluginConfig) (PluginExporter, error) {
+	e := PluginExporter{config: config, agentId: agentId, runTimeInfo: agentConfig.RunTimeInfo, logger: logger}
+	if e.config.Name == "" {
+		e.config.Name = e.config.Plugin
+	}
+	if _, ok := ExporterCmdMap[e.config.Plugin]; !ok {
+		return e, errors.New("exporter plugin not found: " + e.config.Plugin)
+	}
+	return e, nil
+}
+
+func (e *PluginExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	err := e.connect()
+	if err != nil {
+		e.logger.Error("error starting exporter plugin, will retry on the next test run", "err", err)
+	}
+	resChan := broadcaster.SubscribeToTestRuns("exporter-"+e.config.Name, common.DefaultChannelSize, e.logger)
+	for {
+		select {
+		case res := <-resChan:
+			err := e.Export(res)
+			if err != nil {
+				e.logger.Error("error exporting test run", "test", res.TestConfig.Name, "err", err)
+			}
+		case <-ctx.Done():
+			e.stop()
+			e.logger.Info("exporter plugin exiting")
+			return
+		}
+	}
+}
+
+// Export Sends the test run to the plugin, (re)starting the plugin if it isn't running
+func (e *PluginExporter) Export(testRun proto.TestRun) error {
+	if e.client == nil || e.client.Exited() {
+		if e.client != nil {
+			e.logger.Warn("exporter plugin exited, restarting")
+		}
+		err := e.connect()
+		if err != nil {
+			return errors.Wrap(err, "error starting exporter plugin")
+		}
+	}
+	return e.exporter.Export(testRun)
+}
+
+// connect Starts the plugin process and initialises it
+func (e *PluginExporter) connect() error {
+	e.stop()
+	cmd := ExporterCmdMap[e.config.Plugin]
+	e.logger.Info("starting exporter plugin", "plugin", e.config.Plugin, "cmd", cmd)
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  common.DefaultExporterPluginHandshakeConfig,
+		Plugins:          ExporterNameMap,
+		Cmd:              exec.Command(cmd[0], cmd[1:]...),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Level: hclog.Off,
+		}),
+		// unlike syntests the plugin is long-lived, so its logs are streamed into the agent logs
+		Stderr: e.logger.Named(e.config.Plugin).StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true}),
+	})
+	e.client = client
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		return errors.Wrap(err, "error connecting to plugin")
+	}
+	raw, err := rpcClient.Dispense(e.config.Plugin)
+	if err != nil {
+		return errors.Wrap(err, "error dispensing plugin")
+	}
+	e.exporter = raw.(common.ExporterPlugin)
+
+	err = e.exporter.Initialise(proto.ExporterConfig{
+		Name:    e.config.Name,
+		AgentId: e.agentId,
+		Config:  e.config.Config,
+		Runtime: map[string]string{
+			"nodeName":       e.runTimeInfo.NodeName,
+			"podName":        e.runTimeInfo.PodName,
+			"agentNamespace": e.runTimeInfo.AgentNamespace,
+		},
+	})
+	if err != nil {
+		client.Kill()
+		return errors.Wrap(err, "error initialising plugin")
+	}
+	return nil
+}
+
+// stop Finishes and kills the plugin (if running)
+func (e *PluginExporter) stop() {
+	if e.client == nil {
+		return
+	}
+	if !e.client.Exited() && e.exporter != nil {
+		err := e.exporter.Finish()
+		if err != nil {
+			e.logger.Warn("error finishing exporter plugin", "err", err)
+		}
+	}
+	e.client.Kill()
+	e.client = nil
+	e.exporter = nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+)
+
+// Transition of a test between passing and failing
+type Transition string
+
+const (
+	TransitionNone      Transition = ""
+	TransitionFailing   Transition = "fail"
+	TransitionRecovered Transition = "recover"
+	TransitionFlapping  Transition = "flapping"
+)
+
+const (
+	DefaultFlapHighThreshold = 0.5
+	DefaultFlapLowThreshold  = 0.25
+)
+
+// notificationTemplateFuncs are the extra functions available in notification templates
+var notificationTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// NotificationData is the data available in notification templates
+type NotificationData struct {
+	Transition    Transition
+	DisplayName   string
+	Importance    string // importance level of the test (medium if not set)
+	AgentId       string
+	FailingAgents []string // all agents where the latest run of the test failed
+	TestRun       proto.TestRun
+}
+
+func newNotificationData(ctx context.Context, store storage.SynHeartStore, agentId string, transition Transition,
+	testRun proto.TestRun, logger hclog.Logger) NotificationData {
+	data := NotificationData{
+		Transition:    transition,
+		DisplayName:   testRun.TestConfig.DisplayName,
+		Importance:    common.ImportanceLevel(testRun.TestConfig.Importance),
+		AgentId:       agentId,
+		FailingAgents: []string{},
+		TestRun:       testRun,
+	}
+	if data.DisplayName == "" {
+		data.DisplayName = testRun.TestConfig.Name
+	}
+	agents, err := failingAgents(ctx, store, testRun.TestConfig)
+	if err != nil {
+		logger.Warn("unable to fetch failing agents, continuing", "err", err)
+	}
+	// the status of this agent in storage may not be updated yet, so use the test run
+	if transition == TransitionFailing {
+		data.FailingAgents = append(data.FailingAgents, agentId)
+	}
+	for _, id := range agents {
+		if id != agentId {
+			data.FailingAgents = append(data.FailingAgents, id)
+		}
+	}
+	return data
+}
+
+// transitionTracker detects when tests start failing or recover (or flap between the two), from the test runs
+// of the agent
+type transitionTracker struct {
+	flap  common.FlapDetectionConfig
+	tests map[string]*testResults // by test config id
+}
+
+// testResults are the latest results of a test
+type testResults struct {
+	window   []bool // results in the flap detection window, oldest first
+	flapping bool
+}
+
+func newTransitionTracker(flap common.FlapDetectionConfig) transitionTracker {
+	if flap.HighThreshold <= 0 {
+		flap.HighThreshold = DefaultFlapHighThreshold
+	}
+	if flap.LowThreshold <= 0 {
+		flap.LowThreshold = DefaultFlapLowThreshold
+	}
+	return transitionTracker{flap: flap, tests: map[string]*testResults{}}
+}
+
+// Update Records the result of the test run, and returns the transition (if any), a test that fails on its first
+// run is a failing transition, but a test that passes isn't a recovery.
+// If flap detection is enabled, a test that changes state too often in the window is flapping: it's reported once,
+// and no transitions are reported till it stops flapping (then its current state is reported)
+func (t *transitionTracker) Update(testRun proto.TestRun) Transition {
+	testId := common.ComputeSynTestConfigId(testRun.TestConfig.Name, testRun.TestConfig.Namespace)
+	passed := testRun.TestResult.Marks >= testRun.TestResult.MaxMarks
+	res, seen := t.tests[testId]
+	if !seen {
+		res = &testResults{}
+		t.tests[testId] = res
+	}
+	lastPassed := seen && res.window[len(res.window)-1]
+	windowSize := t.flap.Window
+	if windowSize < 1 {
+		windowSize = 1
+	}
+	res.window = append(res.window, passed)
+	if len(res.window) > windowSize {
+		res.window = res.window[len(res.window)-windowSize:]
+	}
+
+	if t.flap.Window > 1 && len(res.window) == t.flap.Window {
+		changes := 0
+		for i := 1; i < len(res.window); i++ {
+			if res.window[i] != res.window[i-1] {
+				changes++
+			}
+		}
+		changeRatio := float64(changes) / float64(len(res.window)-1)
+		switch {
+		case !res.flapping && changeRatio >= t.flap.HighThreshold:
+			res.flapping = true
+			return TransitionFlapping
+		case res.flapping && changeRatio <= t.flap.LowThreshold:
+			res.flapping = false
+			if passed {
+				return TransitionRecovered
+			}
+			return TransitionFailing
+		}
+	}
+	if res.flapping {
+		return TransitionNone
+	}
+
+	switch {
+	case !passed && (!seen || lastPassed):
+		return TransitionFailing
+	case passed && seen && !lastPassed:
+		return TransitionRecovered
+	}
+	return TransitionNone
+}
+
+// Flapping Returns whether the test is flapping
+func (t *transitionTracker) Flapping(testConfig *proto.SynTestConfig) bool {
+	res, ok := t.tests[common.ComputeSynTestConfigId(testConfig.Name, testConfig.Namespace)]
+	return ok && res.flapping
+}
+
+// failingAgents Returns the ids of all the agents where the latest run of the test failed
+func failingAgents(ctx context.Context, store storage.SynHeartStore, testConfig *proto.SynTestConfig) ([]string, error) {
+	allStatus, err := store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	agents := []string{}
+	for pluginId, status := range allStatus {
+		testName, testNs, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || testName != testConfig.Name || testNs != testConfig.Namespace {
+			continue
+		}
+		passRatio, err := strconv.ParseFloat(status, 64)
+		if err == nil && passRatio < 1 {
+			agents = append(agents, common.ComputeAgentId(podName, podNs))
+		}
+	}
+	sort.Strings(agents)
+	return agents, nil
+}
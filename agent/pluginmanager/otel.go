@@ -0,0 +1,176 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DefaultOtelServiceName = "synthetic-heart-agent"
+
+// OtelTraceExporter exports a span for every test run to an OTLP endpoint
+type OtelTraceExporter struct {
+	config   common.OtelConfig
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	logger   hclog.Logger
+	agentId  string
+}
+
+type runIdCtxKey struct{}
+
+// runIdGenerator derives the trace id from the test run id (stored in the context), so the trace of a test run can be
+// found using just the run id (e.g. from exemplars or logs)
+type runIdGenerator struct{}
+
+func (g runIdGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	runId, _ := ctx.Value(runIdCtxKey{}).(string)
+	return TraceIdForRun(runId), g.NewSpanID(ctx, trace.TraceID{})
+}
+
+func (g runIdGenerator) NewSpanID(ctx context.Context, _ trace.TraceID) trace.SpanID {
+	runId, _ := ctx.Value(runIdCtxKey{}).(string)
+	sum := sha256.Sum256([]byte("span/" + runId))
+	spanId := trace.SpanID{}
+	copy(spanId[:], sum[:])
+	return spanId
+}
+
+// TraceIdForRun Returns the trace id of a test run
+func TraceIdForRun(runId string) trace.TraceID {
+	sum := sha256.Sum256([]byte(runId))
+	traceId := trace.TraceID{}
+	copy(traceId[:], sum[:])
+	return traceId
+}
+
+func NewOtelTraceExporter(ctx context.Context, logger hclog.Logger, agentConfig common.AgentConfig, agentId string) (OtelTraceExporter, error) {
+	o := OtelTraceExporter{config: agentConfig.OtelConfig, logger: logger, agentId: agentId}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.config.Endpoint), otlptracegrpc.WithHeaders(o.config.Headers)}
+	if o.config.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return o, errors.Wrap(err, "error creating otlp trace exporter")
+	}
+
+	res, err := newOtelResource(agentConfig, agentId)
+	if err != nil {
+		return o, err
+	}
+	o.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithIDGenerator(runIdGenerator{}),
+	)
+	o.tracer = o.provider.Tracer("github.com/cisco-open/synthetic-heart/agent")
+	return o, nil
+}
+
+func newOtelResource(agentConfig common.AgentConfig, agentId string) (*resource.Resource, error) {
+	serviceName := agentConfig.OtelConfig.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultOtelServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceInstanceID(agentId),
+		semconv.K8SNodeName(agentConfig.RunTimeInfo.NodeName),
+		semconv.K8SPodName(agentConfig.RunTimeInfo.PodName),
+		semconv.K8SNamespaceName(agentConfig.RunTimeInfo.AgentNamespace),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating otel resource")
+	}
+	return res, nil
+}
+
+func (o *OtelTraceExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("otel-traces", common.DefaultChannelSize, o.logger)
+	for {
+		select {
+		case res := <-resChan:
+			err := o.ExportTestRunSpan(res)
+			if err != nil {
+				o.logger.Error("error exporting test run span", "err", err)
+			}
+		case <-ctx.Done():
+			// flush any pending spans
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := o.provider.Shutdown(shutdownCtx)
+			cancel()
+			if err != nil {
+				o.logger.Error("error shutting down trace provider", "err", err)
+			}
+			o.logger.Info("otel trace exporter exiting")
+			return
+		}
+	}
+}
+
+func (o *OtelTraceExporter) ExportTestRunSpan(testRun proto.TestRun) error {
+	startTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing start time")
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing end time")
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("synheart.test.name", testRun.TestConfig.Name),
+		attribute.String("synheart.test.namespace", testRun.TestConfig.Namespace),
+		attribute.String("synheart.test.plugin", testRun.TestConfig.PluginName),
+		attribute.String("synheart.run.id", testRun.Id),
+		attribute.String("synheart.agent.id", testRun.AgentId),
+		attribute.Int64("synheart.result.marks", int64(testRun.TestResult.Marks)),
+		attribute.Int64("synheart.result.max_marks", int64(testRun.TestResult.MaxMarks)),
+	}
+	if testRun.Trigger != nil {
+		attrs = append(attrs, attribute.String("synheart.trigger.type", testRun.Trigger.TriggerType))
+	}
+
+	ctx := context.WithValue(context.Background(), runIdCtxKey{}, testRun.Id)
+	_, span := o.tracer.Start(ctx, fmt.Sprintf("syntest %s/%s", testRun.TestConfig.Namespace, testRun.TestConfig.Name),
+		trace.WithTimestamp(startTime), trace.WithAttributes(attrs...), trace.WithSpanKind(trace.SpanKindInternal))
+	if testRun.TestResult.Marks < testRun.TestResult.MaxMarks {
+		span.SetStatus(codes.Error, testRun.TestResult.Details[common.ErrorKey])
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End(trace.WithTimestamp(endTime))
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultOtelMetricsInterval = 30 * time.Second
+
+// OtelMetricsExporter exports the test run metrics (the same ones exposed to prometheus) to an OTLP endpoint
+type OtelMetricsExporter struct {
+	config      common.OtelConfig
+	provider    *sdkmetric.MeterProvider
+	meter       metric.Meter
+	gauges      map[string]metric.Float64Gauge
+	logger      hclog.Logger
+	runTimeInfo common.AgentInfo
+}
+
+func NewOtelMetricsExporter(ctx context.Context, logger hclog.Logger, agentConfig common.AgentConfig, agentId string) (OtelMetricsExporter, error) {
+	o := OtelMetricsExporter{config: agentConfig.OtelConfig, logger: logger, runTimeInfo: agentConfig.RunTimeInfo}
+	o.gauges = map[string]metric.Float64Gauge{}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(o.config.Endpoint), otlpmetricgrpc.WithHeaders(o.config.Headers)}
+	if o.config.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return o, errors.Wrap(err, "error creating otlp metric exporter")
+	}
+
+	res, err := newOtelResource(agentConfig, agentId)
+	if err != nil {
+		return o, err
+	}
+	interval := o.config.MetricsInterval
+	if interval <= 0 {
+		interval = DefaultOtelMetricsInterval
+	}
+	o.provider = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(res),
+	)
+	o.meter = o.provider.Meter("github.com/cisco-open/synthetic-heart/agent")
+	return o, nil
+}
+
+func (o *OtelMetricsExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("otel-metrics", common.DefaultChannelSize, o.logger)
+	for {
+		select {
+		case res := <-resChan:
+			err := o.ExportTestRunMetrics(res)
+			if err != nil {
+				o.logger.Error("error exporting test run metrics", "err", err)
+			}
+		case <-ctx.Done():
+			// flush the last recorded values
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := o.provider.Shutdown(shutdownCtx)
+			cancel()
+			if err != nil {
+				o.logger.Error("error shutting down meter provider", "err", err)
+			}
+			o.logger.Info("otel metrics exporter exiting")
+			return
+		}
+	}
+}
+
+func (o *OtelMetricsExporter) ExportTestRunMetrics(testRun proto.TestRun) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("test_name", testRun.TestConfig.Name),
+		attribute.String("test_namespace", testRun.TestConfig.Namespace),
+		attribute.String("plugin", testRun.TestConfig.PluginName),
+		attribute.String("importance", common.ImportanceLevel(testRun.TestConfig.Importance)),
+		attribute.String("test_node", o.runTimeInfo.NodeName),
+	}
+
+	startTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing start time")
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing end time")
+	}
+
+	err = o.recordGauge(MarksGauge, "The marks obtained in the test", float64(testRun.TestResult.Marks), attrs)
+	if err != nil {
+		return err
+	}
+	err = o.recordGauge(MaxMarksGauge, "The max marks in the test", float64(testRun.TestResult.MaxMarks), attrs)
+	if err != nil {
+		return err
+	}
+	err = o.recordGauge(TimeGauge, "The runtime of the test in nano seconds", float64(endTime.Sub(startTime).Nanoseconds()), attrs)
+	if err != nil {
+		return err
+	}
+
+	// Adds custom metrics passed by the tests (if there are any)
+	if promResults, ok := testRun.TestResult.Details[common.PrometheusKey]; ok {
+		promMetrics := common.PrometheusMetrics{}
+		err := yaml.Unmarshal([]byte(promResults), &promMetrics)
+		if err != nil {
+			return errors.Wrap(err, "error parsing custom metrics")
+		}
+		for _, gauge := range promMetrics.Gauges {
+			gaugeAttrs := append([]attribute.KeyValue{}, attrs...)
+			for k, v := range gauge.Labels {
+				gaugeAttrs = append(gaugeAttrs, attribute.String(k, v))
+			}
+			err = o.recordGauge(cleanMetricName(fmt.Sprintf(CustomGauge, gauge.Name)), gauge.Help, gauge.Value, gaugeAttrs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (o *OtelMetricsExporter) recordGauge(name string, help string, value float64, attrs []attribute.KeyValue) error {
+	if _, ok := o.gauges[name]; !ok {
+		g, err := o.meter.Float64Gauge(name, metric.WithDescription(help))
+		if err != nil {
+			return errors.Wrap(err, "error creating gauge "+name)
+		}
+		o.gauges[name] = g
+	}
+	o.gauges[name].Record(context.Background(), value, metric.WithAttributes(attrs...))
+	return nil
+}
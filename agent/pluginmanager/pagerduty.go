@@ -0,0 +1,222 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPagerDutyUrl           = "https://events.pagerduty.com/v2/enqueue"
+	DefaultPagerDutyRoutingKeyEnv = "PAGERDUTY_ROUTING_KEY"
+	PagerDutyActionTrigger        = "trigger"
+	PagerDutyActionResolve        = "resolve"
+)
+
+// DefaultPagerDutySeverities maps the importance of a test to the severity of its incidents
+var DefaultPagerDutySeverities = map[string]string{
+	common.ImportanceCritical: "critical",
+	common.ImportanceHigh:     "error",
+	common.ImportanceMedium:   "warning",
+	common.ImportanceLow:      "info",
+}
+
+// PagerDutyNotifier opens an incident (via the events v2 api) when a test starts failing, and resolves it when
+// the test recovers on all agents
+type PagerDutyNotifier struct {
+	config      common.PagerDutyConfig
+	routingKey  string
+	client      *http.Client
+	store       storage.SynHeartStore
+	agentId     string
+	importances map[string]bool
+	transitions transitionTracker
+	silences    *SilenceMap
+	logger      hclog.Logger
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func NewPagerDutyNotifier(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, store storage.SynHeartStore, silences *SilenceMap) (PagerDutyNotifier, error) {
+	p := PagerDutyNotifier{config: agentConfig.PagerDutyConfig, store: store, silences: silences, agentId: agentId, logger: logger}
+	if p.config.Url == "" {
+		p.config.Url = DefaultPagerDutyUrl
+	}
+	if p.config.RoutingKeyEnv == "" {
+		p.config.RoutingKeyEnv = DefaultPagerDutyRoutingKeyEnv
+	}
+	p.routingKey = os.Getenv(p.config.RoutingKeyEnv)
+	if p.routingKey == "" {
+		return p, errors.New("pagerduty routing key not found in env var " + p.config.RoutingKeyEnv)
+	}
+	p.importances = map[string]bool{}
+	for _, importance := range p.config.Importances {
+		p.importances[strings.ToLower(importance)] = true
+	}
+	p.client = &http.Client{Timeout: 10 * time.Second}
+	p.transitions = newTransitionTracker(agentConfig.FlapDetection)
+	return p, nil
+}
+
+func (p *PagerDutyNotifier) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("pagerduty", common.DefaultChannelSize, p.logger)
+	for {
+		select {
+		case res := <-resChan:
+			transition := p.transitions.Update(res)
+			// flapping tests don't change incidents (the incident stays open if it was failing)
+			if transition == TransitionNone || transition == TransitionFlapping || !p.shouldNotify(res.TestConfig) {
+				continue
+			}
+			err := p.Notify(ctx, transition, res)
+			if err != nil {
+				p.logger.Error("error sending pagerduty event", "test", res.TestConfig.Name, "err", err)
+			}
+		case <-ctx.Done():
+			p.logger.Info("pagerduty notifier exiting")
+			return
+		}
+	}
+}
+
+// shouldNotify Returns whether the test opens incidents, based on its importance
+func (p *PagerDutyNotifier) shouldNotify(testConfig *proto.SynTestConfig) bool {
+	if testConfig.GetAlerting().GetDisabled() || p.silences.Silenced(testConfig) {
+		return false
+	}
+	return len(p.importances) == 0 || p.importances[strings.ToLower(testConfig.Importance)]
+}
+
+// DedupKey Returns the dedup key of the incidents of the test, so all agents (in the cluster) update the same incident
+func (p *PagerDutyNotifier) DedupKey(testConfig *proto.SynTestConfig) string {
+	return fmt.Sprintf("synthetic-heart/%s/%s/%s", p.config.Cluster, testConfig.Namespace, testConfig.Name)
+}
+
+// Notify Triggers an incident if the test is failing, or resolves it if the test recovered (and isn't failing on
+// any other agent)
+func (p *PagerDutyNotifier) Notify(ctx context.Context, transition Transition, testRun proto.TestRun) error {
+	testConfig := testRun.TestConfig
+	event := pagerDutyEvent{RoutingKey: p.routingKey, DedupKey: p.DedupKey(testConfig)}
+
+	if transition == TransitionRecovered {
+		agents, err := failingAgents(ctx, p.store, testConfig)
+		if err != nil {
+			p.logger.Warn("unable to fetch failing agents, resolving anyway", "err", err)
+		}
+		for _, agentId := range agents {
+			if agentId != p.agentId {
+				p.logger.Info("test still failing on other agents, not resolving incident", "test", testConfig.Name, "agent", agentId)
+				return nil
+			}
+		}
+		event.EventAction = PagerDutyActionResolve
+		return p.send(ctx, event)
+	}
+
+	displayName := testConfig.DisplayName
+	if displayName == "" {
+		displayName = testConfig.Name
+	}
+	summary := fmt.Sprintf("Synthetic test %s is failing", displayName)
+	if p.config.Cluster != "" {
+		summary += " in " + p.config.Cluster
+	}
+	customDetails := map[string]string{
+		"test":        common.ComputeSynTestConfigId(testConfig.Name, testConfig.Namespace),
+		"description": testConfig.Description,
+		"agent":       p.agentId,
+		"marks":       fmt.Sprintf("%d/%d", testRun.TestResult.Marks, testRun.TestResult.MaxMarks),
+		"error":       testRun.TestResult.Details[common.ErrorKey],
+	}
+	for k, v := range testConfig.GetAlerting().GetAnnotations() {
+		customDetails[k] = v
+	}
+	event.EventAction = PagerDutyActionTrigger
+	event.Payload = &pagerDutyPayload{
+		Summary:       summary,
+		Source:        p.agentId,
+		Severity:      p.severity(testConfig.Importance),
+		Component:     testConfig.Name,
+		Group:         testConfig.Namespace,
+		Class:         testConfig.PluginName,
+		CustomDetails: customDetails,
+	}
+	return p.send(ctx, event)
+}
+
+func (p *PagerDutyNotifier) severity(importance string) string {
+	importance = strings.ToLower(importance)
+	if severity, ok := p.config.Severities[importance]; ok {
+		return severity
+	}
+	if severity, ok := DefaultPagerDutySeverities[importance]; ok {
+		return severity
+	}
+	return "error"
+}
+
+func (p *PagerDutyNotifier) send(ctx context.Context, event pagerDutyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling pagerduty event")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request to pagerduty")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("pagerduty returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
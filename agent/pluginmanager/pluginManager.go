@@ -32,6 +32,8 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"slices"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -47,26 +49,37 @@ type PluginManager struct {
 	broadcaster    utils.Broadcaster
 	sm             StateMap
 	esh            ExtStorageHandler
+	silences       SilenceMap               // silences of test notifications (e.g. maintenance windows)
+	audit          AuditLogger              // records plugin lifecycle events in the audit log
+	secrets        *SecretReader            // reads the kubernetes secrets referenced by the tests
+	baseConfig     common.AgentConfig       // config from the config file, agent profiles are applied on top of it
+	profile        common.AgentProfile      // agent profile applied to the config
+	promRestart    chan common.AgentConfig  // restarts the prometheus exporter with the config (see RestartPrometheus)
 	SyntheticTests map[string]SyntheticTest // cache and metadata of synthetictest configs that run on this agent
 }
 
 const DefaultLabelFilePath string = "/etc/podinfo/labels"
 
+// AgentVersion is the version of the agent (set by main), reported in the agent status
+var AgentVersion string
+
 type RunnablePlugin interface {
 	Run(ctx context.Context) error
 }
 
 type SyntheticTest struct {
-	config  proto.SynTestConfig
-	version string
-	cancel  context.CancelFunc
-	wg      *sync.WaitGroup
+	config      proto.SynTestConfig
+	version     string
+	cancel      context.CancelFunc
+	wg          *sync.WaitGroup
+	runRequests chan common.TestRunRequest // requested (one-shot) runs of the test, handled by its routine
 }
 
 // NewPluginManager creates a new plugin manager with given config file path
 func NewPluginManager(configPath string) (*PluginManager, error) {
 	pm := PluginManager{
 		SyntheticTests: map[string]SyntheticTest{},
+		promRestart:    make(chan common.AgentConfig, 1),
 	}
 	pm.logger = hclog.New(&hclog.LoggerOptions{
 		Name:            "pm",
@@ -88,9 +101,10 @@ func NewPluginManager(configPath string) (*PluginManager, error) {
 	}
 
 	pm.config.DiscoveredPlugins = map[string][]string{}
+	pm.config.DiscoveredExporters = map[string][]string{}
 	// Iterate over the enabled plugins config and discover all plugins
 	for _, pluginDiscoveryConfig := range pm.config.EnabledPlugins {
-		plugins, err := DiscoverPlugins(pluginDiscoveryConfig)
+		plugins, err := DiscoverPlugins(pluginDiscoveryConfig, SyntestPrefix)
 		if err != nil {
 			return nil, errors.Wrap(err, "error discovering plugins")
 		}
@@ -98,6 +112,17 @@ func NewPluginManager(configPath string) (*PluginManager, error) {
 		for name, cmds := range plugins {
 			pm.config.DiscoveredPlugins[name] = cmds
 		}
+
+		exporters, err := DiscoverPlugins(pluginDiscoveryConfig, ExporterPrefix)
+		if err != nil {
+			return nil, errors.Wrap(err, "error discovering exporter plugins")
+		}
+		if len(exporters) > 0 {
+			pm.logger.Info("discovered exporter plugins", "plugins", exporters)
+		}
+		for name, cmds := range exporters {
+			pm.config.DiscoveredExporters[name] = cmds
+		}
 	}
 
 	if len(pm.config.DiscoveredPlugins) == 0 {
@@ -110,7 +135,13 @@ func NewPluginManager(configPath string) (*PluginManager, error) {
 		RegisterSynTestPlugin(pluginName, cmds)
 	}
 
+	for pluginName, cmds := range pm.config.DiscoveredExporters {
+		pm.logger.Info("registering exporter plugin", "name", pluginName, "cmd", cmds)
+		RegisterExporterPlugin(pluginName, cmds)
+	}
+
 	pm.sm = NewStateMap(pm.logger, pm.config)
+	pm.silences = NewSilenceMap(pm.AgentId)
 	pm.broadcaster = utils.NewBroadcaster(pm.logger)
 	pm.logger.Info("Agent Id: " + pm.AgentId)
 
@@ -119,25 +150,25 @@ func NewPluginManager(configPath string) (*PluginManager, error) {
 		return nil, errors.Wrap(err, "error creating storage client")
 	}
 	pm.esh = esh
+	pm.audit = NewAuditLogger(pm.logger.Named("audit"), pm.AgentId, esh.Store)
+
+	secrets, err := NewSecretReader(pm.config.Secrets, pm.config.RunTimeInfo.AgentNamespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating secret reader")
+	}
+	pm.secrets = secrets
 
+	pm.baseConfig = pm.config
 	pm.logger.Info("pm config", "val", pm.config)
 
 	return &pm, nil
 }
 
 func (pm *PluginManager) parsePluginManagerConfig(configPath string) error {
-	conf := common.AgentConfig{}
-	config, err := os.ReadFile(configPath)
+	err := pm.readConfigFile(configPath)
 	if err != nil {
-		pm.logger.Error("error reading config file", "file", configPath)
-		return errors.Wrap(err, "error reading config file")
-	}
-	err = yaml.Unmarshal(config, &conf)
-	if err != nil {
-		pm.logger.Error("error unmarshalling config yaml", "file", configPath)
-		return errors.Wrap(err, "error unmarshalling config yaml")
+		return err
 	}
-	pm.config = conf
 
 	// Get the node name
 	pm.config.RunTimeInfo.NodeName = os.Getenv("NODE_NAME") // Get node name from environmental variables
@@ -167,6 +198,7 @@ func (pm *PluginManager) parsePluginManagerConfig(configPath string) error {
 		return errors.Wrap(err, "error parsing label file")
 	}
 	pm.config.RunTimeInfo.PodLabels = labels
+	pm.config.RunTimeInfo.Version = AgentVersion
 
 	// check if there is the discover label
 	if val, ok := pm.config.RunTimeInfo.PodLabels[common.K8sDiscoverLabel]; !ok || val != common.K8sDiscoverLabelVal {
@@ -191,11 +223,39 @@ func (pm *PluginManager) parsePluginManagerConfig(configPath string) error {
 		pm.config.PrintPluginLogs = common.LogNever
 	}
 
+	// Validate the recording config and set its defaults
+	if pm.config.RecordingConfig.Enabled && pm.config.RecordingConfig.Dir == "" {
+		return errors.New("recording.dir must be set when recording is enabled")
+	}
+	if pm.config.RecordingConfig.When != common.LogOnFail && pm.config.RecordingConfig.When != common.LogAlways && pm.config.RecordingConfig.When != common.LogNever {
+		pm.config.RecordingConfig.When = common.LogOnFail
+	}
+	if pm.config.RecordingConfig.MaxPerTest <= 0 {
+		pm.config.RecordingConfig.MaxPerTest = DefaultMaxRecordings
+	}
+
 	pm.logger.Info("running with config:")
 	pm.printConfig()
 	return nil
 }
 
+// readConfigFile Reads the agent config from the config file
+func (pm *PluginManager) readConfigFile(configPath string) error {
+	conf := common.AgentConfig{}
+	config, err := os.ReadFile(configPath)
+	if err != nil {
+		pm.logger.Error("error reading config file", "file", configPath)
+		return errors.Wrap(err, "error reading config file")
+	}
+	err = yaml.Unmarshal(config, &conf)
+	if err != nil {
+		pm.logger.Error("error unmarshalling config yaml", "file", configPath)
+		return errors.Wrap(err, "error unmarshalling config yaml")
+	}
+	pm.config = conf
+	return nil
+}
+
 // parseLabelFile parses the label file and returns the labels
 func (pm *PluginManager) parseLabelFile(labelFilePath string) (map[string]string, error) {
 	pm.logger.Info("parsing label file", "file", labelFilePath)
@@ -240,9 +300,11 @@ func (pm *PluginManager) Start(ctx context.Context) error {
 	defer goPlugin.CleanupClients()
 
 	// Create a wait group so we know which routines are running
-	bwg := sync.WaitGroup{}          // wait group for broadcaster
-	eshwg := sync.WaitGroup{}        // wait group for external storage helper
-	prometheuswg := sync.WaitGroup{} // wait group for prometheus exporter
+	bwg := sync.WaitGroup{}   // wait group for broadcaster
+	eshwg := sync.WaitGroup{} // wait group for external storage helper
+
+	// Collect agent metrics from the state map and broadcaster
+	agentMetrics.setSources(&pm.sm, &pm.broadcaster)
 
 	// Run the Broadcaster
 	bwg.Add(1)
@@ -274,11 +336,26 @@ func (pm *PluginManager) Start(ctx context.Context) error {
 		}
 	}(ctx)
 
-	// start the prometheus server
+	// subscribe for requested (one-shot) test runs
+	pm.logger.Info("subscribing to test run requests from ext-storage")
+	runRequestChan := make(chan common.TestRunRequest, 10)
+	go func(ctx context.Context) {
+		err := pm.esh.Store.SubscribeToTestRunRequests(ctx, 1000, runRequestChan)
+		if err != nil && !errors.Is(ctx.Err(), context.Canceled) && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			pm.logger.Error("error watching for test run requests", "err", err)
+			pm.Exit(errors.Wrap(err, "error watching for test run requests"))
+		}
+	}(ctx)
+
+	// apply the agent profile before the exporters start, as they use the config
+	pm.SyncAgentProfile(ctx)
+
+	// start the exporters and notifiers
 	promConfigChange := make(chan struct{}, 2)
-	cancelPrometheus := pm.StartPrometheus(ctx, &prometheuswg, promConfigChange)
+	stopExporters := pm.startExporters(ctx, promConfigChange)
 
-	ticker := time.NewTicker(pm.config.SyncFrequency)
+	syncFrequency := pm.config.SyncFrequency
+	ticker := time.NewTicker(syncFrequency)
 	pm.logger.Trace("sending empty msg to force sync, timer also set", "frequency", pm.config.SyncFrequency)
 
 	// send a signal to all agents and controller that a new agent is joining
@@ -304,6 +381,8 @@ configWatch:
 			if configChanged {
 				promConfigChange <- struct{}{} // notify prometheus that config has changed
 			}
+		case request := <-runRequestChan:
+			pm.RequestTestRun(request)
 		case <-ticker.C:
 			pm.logger.Trace("sync triggered by timer")
 			pm.logger.Debug("checking redis connection")
@@ -325,6 +404,13 @@ configWatch:
 		case <-ctx.Done():
 			break configWatch
 		}
+
+		// the sync frequency can be changed by an agent profile
+		if pm.config.SyncFrequency != syncFrequency {
+			syncFrequency = pm.config.SyncFrequency
+			pm.logger.Info("sync frequency changed", "frequency", syncFrequency)
+			ticker.Reset(syncFrequency)
+		}
 	}
 
 	// Wait for syntests to finish
@@ -337,10 +423,7 @@ configWatch:
 	pm.logger.Warn("allowing time for agent to export all test results...", "gracePeriod", pm.config.GracePeriod)
 	time.Sleep(pm.config.GracePeriod)
 
-	// Wait for prometheus to finish
-	cancelPrometheus()
-	pm.logger.Info("waiting for prometheus to finish...")
-	prometheuswg.Wait()
+	stopExporters()
 
 	pm.logger.Info("cleaning up external storage")
 	pm.cleanupAndUnregister()
@@ -355,24 +438,371 @@ configWatch:
 	return nil
 }
 
-// StartPrometheus Starts prometheus server, returns a cancel function
+// startExporters Starts the exporters and notifiers of the test runs, returns a function stopping them (and waiting for
+// them to finish)
+func (pm *PluginManager) startExporters(ctx context.Context, promConfigChange chan struct{}) func() {
+	prometheuswg := sync.WaitGroup{} // wait group for prometheus exporter
+	otelwg := sync.WaitGroup{}       // wait group for otel exporters
+	statsdwg := sync.WaitGroup{}     // wait group for statsd exporter
+	cloudwatchwg := sync.WaitGroup{} // wait group for cloudwatch exporter
+	datadogwg := sync.WaitGroup{}    // wait group for datadog exporter
+	cewg := sync.WaitGroup{}         // wait group for cloudevents exporter
+	amwg := sync.WaitGroup{}         // wait group for alertmanager notifier
+	slackwg := sync.WaitGroup{}      // wait group for slack notifier
+	pdwg := sync.WaitGroup{}         // wait group for pagerduty notifier
+	webhookwg := sync.WaitGroup{}    // wait group for webhook notifier
+	exporterwg := sync.WaitGroup{}   // wait group for exporter plugins
+
+	// start the prometheus server
+	cancelPrometheus := pm.StartPrometheus(ctx, &prometheuswg, promConfigChange)
+
+	// start the otel exporters
+	cancelOtel := pm.StartOtel(ctx, &otelwg)
+
+	// start the statsd exporter
+	cancelStatsd := pm.StartStatsd(ctx, &statsdwg)
+
+	// start the cloudwatch exporter
+	cancelCloudWatch := pm.StartCloudWatch(ctx, &cloudwatchwg)
+
+	// start the datadog exporter
+	cancelDatadog := pm.StartDatadog(ctx, &datadogwg)
+
+	// start the cloudevents exporter
+	cancelCloudEvents := pm.StartCloudEvents(ctx, &cewg)
+
+	// start the alertmanager notifier
+	cancelAlertmanager := pm.StartAlertmanager(ctx, &amwg)
+
+	// start the slack notifier
+	cancelSlack := pm.StartSlack(ctx, &slackwg)
+
+	// start the pagerduty notifier
+	cancelPagerDuty := pm.StartPagerDuty(ctx, &pdwg)
+
+	// start the webhook notifier
+	cancelWebhook := pm.StartWebhook(ctx, &webhookwg)
+
+	// start the exporter plugins
+	cancelExporterPlugins := pm.StartExporterPlugins(ctx, &exporterwg)
+
+	return func() {
+		// Wait for prometheus to finish
+		cancelPrometheus()
+		pm.logger.Info("waiting for prometheus to finish...")
+		prometheuswg.Wait()
+
+		// Wait for otel exporters to finish
+		cancelOtel()
+		pm.logger.Info("waiting for otel exporters to finish...")
+		otelwg.Wait()
+
+		// Wait for statsd exporter to finish
+		cancelStatsd()
+		pm.logger.Info("waiting for statsd exporter to finish...")
+		statsdwg.Wait()
+
+		// Wait for cloudwatch exporter to finish
+		cancelCloudWatch()
+		pm.logger.Info("waiting for cloudwatch exporter to finish...")
+		cloudwatchwg.Wait()
+
+		// Wait for datadog exporter to finish
+		cancelDatadog()
+		pm.logger.Info("waiting for datadog exporter to finish...")
+		datadogwg.Wait()
+
+		// Wait for cloudevents exporter to finish
+		cancelCloudEvents()
+		pm.logger.Info("waiting for cloudevents exporter to finish...")
+		cewg.Wait()
+
+		// Wait for alertmanager notifier to finish
+		cancelAlertmanager()
+		pm.logger.Info("waiting for alertmanager notifier to finish...")
+		amwg.Wait()
+
+		// Wait for slack notifier to finish
+		cancelSlack()
+		pm.logger.Info("waiting for slack notifier to finish...")
+		slackwg.Wait()
+
+		// Wait for pagerduty notifier to finish
+		cancelPagerDuty()
+		pm.logger.Info("waiting for pagerduty notifier to finish...")
+		pdwg.Wait()
+
+		// Wait for webhook notifier to finish
+		cancelWebhook()
+		pm.logger.Info("waiting for webhook notifier to finish...")
+		webhookwg.Wait()
+
+		// Wait for exporter plugins to finish
+		cancelExporterPlugins()
+		pm.logger.Info("waiting for exporter plugins to finish...")
+		exporterwg.Wait()
+	}
+}
+
+// StartPrometheus Starts prometheus server, returns a cancel function. The exporter is stopped and started again (in
+// the agent process) with the config sent by RestartPrometheus
 func (pm *PluginManager) StartPrometheus(ctx context.Context, wg *sync.WaitGroup, configChange chan struct{}) context.CancelFunc {
 	prometheusContext, cancelPrometheus := context.WithCancel(ctx)
 	wg.Add(1)
 	go func(ctx context.Context) {
-		if pm.config.PrometheusConfig.ServerAddress != "" {
-			prom, err := NewPrometheusExporter(pm.logger.Named("prometheus"), pm.config, pm.AgentId, pm.config.DebugMode)
-			if err != nil {
-				pm.logger.Error("error creating prometheus exporter", "err", err)
-				pm.Exit(errors.Wrap(err, "error creating prometheus exporter"))
+		defer wg.Done()
+		config := pm.config
+		for {
+			exporterContext, stopExporter := context.WithCancel(ctx)
+			exporterDone := make(chan struct{})
+			go func() {
+				defer close(exporterDone)
+				pm.runPrometheus(exporterContext, config, configChange)
+			}()
+			select {
+			case config = <-pm.promRestart:
+				pm.logger.Info("restarting prometheus exporter")
+				stopExporter()
+				<-exporterDone
+			case <-ctx.Done():
+				stopExporter()
+				<-exporterDone
+				return
 			}
-			prom.Run(ctx, &pm.broadcaster, configChange)
 		}
-		wg.Done()
 	}(prometheusContext)
 	return cancelPrometheus
 }
 
+// runPrometheus Runs the prometheus exporter with the config (if it's enabled) until the context is done
+func (pm *PluginManager) runPrometheus(ctx context.Context, config common.AgentConfig, configChange chan struct{}) {
+	if config.PrometheusConfig.ServerAddress == "" && !config.PrometheusConfig.Push {
+		<-ctx.Done()
+		return
+	}
+	prom, err := NewPrometheusExporter(pm.logger.Named("prometheus"), config, pm.AgentId, config.DebugMode, &pm.silences)
+	if err != nil {
+		pm.logger.Error("error creating prometheus exporter", "err", err)
+		pm.Exit(errors.Wrap(err, "error creating prometheus exporter"))
+		return
+	}
+	prom.Run(ctx, &pm.broadcaster, configChange)
+}
+
+// StartOtel Starts the otel exporters (if an endpoint is configured), returns a cancel function
+func (pm *PluginManager) StartOtel(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	otelContext, cancelOtel := context.WithCancel(ctx)
+	if pm.config.OtelConfig.Endpoint == "" {
+		return cancelOtel
+	}
+	if pm.config.OtelConfig.Traces {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			traces, err := NewOtelTraceExporter(ctx, pm.logger.Named("otel-traces"), pm.config, pm.AgentId)
+			if err != nil {
+				pm.logger.Error("error creating otel trace exporter", "err", err)
+				pm.Exit(errors.Wrap(err, "error creating otel trace exporter"))
+				return
+			}
+			traces.Run(ctx, &pm.broadcaster)
+		}(otelContext)
+	}
+	if pm.config.OtelConfig.Metrics {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			metrics, err := NewOtelMetricsExporter(ctx, pm.logger.Named("otel-metrics"), pm.config, pm.AgentId)
+			if err != nil {
+				pm.logger.Error("error creating otel metrics exporter", "err", err)
+				pm.Exit(errors.Wrap(err, "error creating otel metrics exporter"))
+				return
+			}
+			metrics.Run(ctx, &pm.broadcaster)
+		}(otelContext)
+	}
+	return cancelOtel
+}
+
+// StartStatsd Starts the statsd exporter (if an address is configured), returns a cancel function
+func (pm *PluginManager) StartStatsd(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	statsdContext, cancelStatsd := context.WithCancel(ctx)
+	if pm.config.StatsdConfig.Address == "" {
+		return cancelStatsd
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		statsd, err := NewStatsdExporter(pm.logger.Named("statsd"), pm.config, &pm.sm)
+		if err != nil {
+			pm.logger.Error("error creating statsd exporter", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating statsd exporter"))
+			return
+		}
+		statsd.Run(ctx, &pm.broadcaster)
+	}(statsdContext)
+	return cancelStatsd
+}
+
+// StartCloudWatch Starts the cloudwatch exporter (if a namespace is configured), returns a cancel function
+func (pm *PluginManager) StartCloudWatch(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	cloudWatchContext, cancelCloudWatch := context.WithCancel(ctx)
+	if pm.config.CloudWatchConfig.Namespace == "" {
+		return cancelCloudWatch
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		cw, err := NewCloudWatchExporter(ctx, pm.logger.Named("cloudwatch"), pm.config)
+		if err != nil {
+			pm.logger.Error("error creating cloudwatch exporter", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating cloudwatch exporter"))
+			return
+		}
+		cw.Run(ctx, &pm.broadcaster)
+	}(cloudWatchContext)
+	return cancelCloudWatch
+}
+
+// StartDatadog Starts the datadog exporter (if enabled), returns a cancel function
+func (pm *PluginManager) StartDatadog(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	datadogContext, cancelDatadog := context.WithCancel(ctx)
+	if !pm.config.DatadogConfig.Enabled {
+		return cancelDatadog
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		dd, err := NewDatadogExporter(pm.logger.Named("datadog"), pm.config, pm.AgentId, &pm.sm, &pm.silences)
+		if err != nil {
+			pm.logger.Error("error creating datadog exporter", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating datadog exporter"))
+			return
+		}
+		dd.Run(ctx, &pm.broadcaster)
+	}(datadogContext)
+	return cancelDatadog
+}
+
+// StartCloudEvents Starts the cloudevents exporter (if a sink is configured), returns a cancel function
+func (pm *PluginManager) StartCloudEvents(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	cloudEventsContext, cancelCloudEvents := context.WithCancel(ctx)
+	if pm.config.CloudEventsConfig.Sink == "" {
+		return cancelCloudEvents
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		ce, err := NewCloudEventsExporter(pm.logger.Named("cloudevents"), pm.config, pm.AgentId)
+		if err != nil {
+			pm.logger.Error("error creating cloudevents exporter", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating cloudevents exporter"))
+			return
+		}
+		ce.Run(ctx, &pm.broadcaster)
+	}(cloudEventsContext)
+	return cancelCloudEvents
+}
+
+// StartAlertmanager Starts the alertmanager notifier (if an url is configured), returns a cancel function
+func (pm *PluginManager) StartAlertmanager(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	alertmanagerContext, cancelAlertmanager := context.WithCancel(ctx)
+	if pm.config.AlertmanagerConfig.Url == "" {
+		return cancelAlertmanager
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		am, err := NewAlertmanagerNotifier(pm.logger.Named("alertmanager"), pm.config, pm.AgentId, &pm.silences)
+		if err != nil {
+			pm.logger.Error("error creating alertmanager notifier", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating alertmanager notifier"))
+			return
+		}
+		am.Run(ctx, &pm.broadcaster)
+	}(alertmanagerContext)
+	return cancelAlertmanager
+}
+
+// StartSlack Starts the slack notifier (if enabled), returns a cancel function
+func (pm *PluginManager) StartSlack(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	slackContext, cancelSlack := context.WithCancel(ctx)
+	if !pm.config.SlackConfig.Enabled {
+		return cancelSlack
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		s, err := NewSlackNotifier(pm.logger.Named("slack"), pm.config, pm.AgentId, pm.esh.Store, &pm.silences)
+		if err != nil {
+			pm.logger.Error("error creating slack notifier", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating slack notifier"))
+			return
+		}
+		s.Run(ctx, &pm.broadcaster)
+	}(slackContext)
+	return cancelSlack
+}
+
+// StartPagerDuty Starts the pagerduty notifier (if enabled), returns a cancel function
+func (pm *PluginManager) StartPagerDuty(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	pagerDutyContext, cancelPagerDuty := context.WithCancel(ctx)
+	if !pm.config.PagerDutyConfig.Enabled {
+		return cancelPagerDuty
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		pd, err := NewPagerDutyNotifier(pm.logger.Named("pagerduty"), pm.config, pm.AgentId, pm.esh.Store, &pm.silences)
+		if err != nil {
+			pm.logger.Error("error creating pagerduty notifier", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating pagerduty notifier"))
+			return
+		}
+		pd.Run(ctx, &pm.broadcaster)
+	}(pagerDutyContext)
+	return cancelPagerDuty
+}
+
+// StartWebhook Starts the webhook notifier (if any webhooks are configured), returns a cancel function
+func (pm *PluginManager) StartWebhook(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	webhookContext, cancelWebhook := context.WithCancel(ctx)
+	if len(pm.config.Webhooks) == 0 {
+		return cancelWebhook
+	}
+	wg.Add(1)
+	go func(ctx context.Context) {
+		defer wg.Done()
+		w, err := NewWebhookNotifier(pm.logger.Named("webhook"), pm.config, pm.AgentId, pm.esh.Store, &pm.silences)
+		if err != nil {
+			pm.logger.Error("error creating webhook notifier", "err", err)
+			pm.Exit(errors.Wrap(err, "error creating webhook notifier"))
+			return
+		}
+		w.Run(ctx, &pm.broadcaster)
+	}(webhookContext)
+	return cancelWebhook
+}
+
+// StartExporterPlugins Starts the configured exporter plugins, returns a cancel function
+func (pm *PluginManager) StartExporterPlugins(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	exporterContext, cancelExporters := context.WithCancel(ctx)
+	for _, exporterConfig := range pm.config.ExporterPlugins {
+		e, err := NewPluginExporter(pm.logger.Named("exporter."+exporterConfig.Name), pm.config, pm.AgentId, exporterConfig)
+		if err != nil {
+			pm.logger.Error("error creating exporter plugin", "name", exporterConfig.Name, "err", err)
+			pm.Exit(errors.Wrap(err, "error creating exporter plugin"))
+			return cancelExporters
+		}
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			e.Run(ctx, &pm.broadcaster)
+		}(exporterContext)
+	}
+	return cancelExporters
+}
+
 func (pm *PluginManager) Exit(err error) {
 	pm.logger.Error("FATAL Error", "err", err.Error())
 	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
@@ -382,7 +812,7 @@ func (pm *PluginManager) cleanupAndUnregister() {
 	// Cleanup all synthetic test plugin data
 	for testConfigId, _ := range pm.SyntheticTests {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		pm.StopAndDeleteSynTest(ctx, testConfigId)
+		pm.StopAndDeleteSynTest(ctx, testConfigId, "agent exiting")
 		cancel()
 	}
 
@@ -403,15 +833,32 @@ func (pm *PluginManager) cleanupAndUnregister() {
 
 // SyncConfig syncs the syntest configs from redis
 func (pm *PluginManager) SyncConfig(ctx context.Context) (bool, error) {
+	if pm.SyncAgentProfile(ctx) {
+		pm.RestartPrometheus("agent profile changed the prometheus config")
+	}
 	pm.logger.Info("syncing syntest configs...")
+	start := time.Now()
 	configChanged, err := pm.SyncSyntestPluginConfigs(ctx)
+	configSyncDuration.Observe(time.Since(start).Seconds())
 	if err != nil {
+		configSyncErrors.Inc()
 		return configChanged, errors.Wrap(err, "error syncing syntest configs")
 	}
 	pm.logger.Info("finished syncing syntest configs")
+	pm.SyncSilences(ctx)
 	return configChanged, nil
 }
 
+// SyncSilences Fetches the silences from external storage, keeps the previous silences if it fails
+func (pm *PluginManager) SyncSilences(ctx context.Context) {
+	silences, err := pm.esh.Store.FetchAllSilences(ctx)
+	if err != nil {
+		pm.logger.Error("error syncing silences, using the previous silences", "err", err)
+		return
+	}
+	pm.silences.Update(silences)
+}
+
 // SyncSyntestPluginConfigs checks external storage for new syntest config or change in existing ones and then start/stops appropriate plugins
 func (pm *PluginManager) SyncSyntestPluginConfigs(ctx context.Context) (bool, error) {
 	configChanged := false
@@ -424,7 +871,7 @@ func (pm *PluginManager) SyncSyntestPluginConfigs(ctx context.Context) (bool, er
 		_, ok := latestSynTestConfigs[testConfigId]
 		if !ok {
 			pm.logger.Info("syntest deleted", "test", testConfigId)
-			pm.StopAndDeleteSynTest(ctx, testConfigId)
+			pm.StopAndDeleteSynTest(ctx, testConfigId, "syntest deleted")
 			configChanged = true
 		}
 	}
@@ -433,26 +880,40 @@ func (pm *PluginManager) SyncSyntestPluginConfigs(ctx context.Context) (bool, er
 	for testConfigId, configSummary := range latestSynTestConfigs {
 		st, ok := pm.SyntheticTests[testConfigId] // get the local cache of the config
 		latestVersion := configSummary.Version    // latest version from the configs
+		// canary agents run the new version of the config till it's promoted (or rolled back)
+		canary := configSummary.Canary != nil && slices.Contains(configSummary.Canary.Agents, pm.AgentId)
+		if canary {
+			latestVersion = configSummary.Canary.Version
+		}
 		// if the syntest already exists, and we are running on latest version, then continue to next syntest config
 		if ok && st.version == latestVersion {
 			pm.logger.Trace("test already running and is latest version", "test", testConfigId, "version", latestVersion)
 			continue
 		}
-		latestSynTestConfig, err := pm.esh.Store.FetchTestConfig(ctx, testConfigId)
+		fetchConfig := pm.esh.Store.FetchTestConfig
+		if canary {
+			fetchConfig = pm.esh.Store.FetchTestConfigCanary
+		}
+		latestSynTestConfig, err := fetchConfig(ctx, testConfigId)
 		if err != nil {
 			pm.logger.Warn("error getting latest config", "test", testConfigId, "err", err)
 			continue
 		}
-		if ok { // test is running but version changed - so we stop and delete it for now
+		if ok && (st.config.Paused || latestSynTestConfig.Paused) {
+			// test is being paused or resumed, so we stop it but keep its data
+			pm.logger.Info("syntest paused or resumed", "test", testConfigId, "old", st.version, "new", latestVersion)
+			pm.StopSynTest(testConfigId, "syntest paused")
+			configChanged = true
+		} else if ok { // test is running but version changed - so we stop and delete it for now
 			pm.logger.Info("syntest config changed", "test", testConfigId, "old", st.version, "new", latestVersion)
-			pm.StopAndDeleteSynTest(ctx, testConfigId)
+			pm.StopAndDeleteSynTest(ctx, testConfigId, "syntest config changed")
 			configChanged = true
 		}
 
 		pm.logger.Trace("checking if test matches agent selector", "test", testConfigId)
 		// check if it matches the agentSelector, otherwise dont run
 		ok, err = common.IsAgentValidForSynTest(pm.config, pm.AgentId, latestSynTestConfig.Name, latestSynTestConfig.Namespace,
-			latestSynTestConfig.NodeSelector, latestSynTestConfig.PodLabelSelector, latestSynTestConfig.Labels, pm.logger)
+			latestSynTestConfig.PluginName, latestSynTestConfig.NodeSelector, latestSynTestConfig.PodLabelSelector, latestSynTestConfig.Labels, pm.logger)
 		if err != nil {
 			pm.logger.Warn("error checking agent selector", "test", testConfigId, "err", err)
 			continue
@@ -460,13 +921,19 @@ func (pm *PluginManager) SyncSyntestPluginConfigs(ctx context.Context) (bool, er
 		if ok {
 			tCtx, cancel := context.WithCancel(ctx)
 			pm.SyntheticTests[testConfigId] = SyntheticTest{
-				config:  latestSynTestConfig,
-				version: latestVersion,
-				cancel:  cancel,
-				wg:      &sync.WaitGroup{},
+				config:      latestSynTestConfig,
+				version:     latestVersion,
+				cancel:      cancel,
+				wg:          &sync.WaitGroup{},
+				runRequests: make(chan common.TestRunRequest, 10),
+			}
+			if latestSynTestConfig.Paused {
+				pm.logger.Info("syntest paused, not starting it", "test", testConfigId)
+				pm.PauseTestRoutine(pm.SyntheticTests[testConfigId])
+			} else {
+				pm.logger.Info("(re)starting syntest", "test", testConfigId)
+				pm.StartTestRoutine(tCtx, pm.SyntheticTests[testConfigId])
 			}
-			pm.logger.Info("(re)starting syntest", "test", testConfigId)
-			pm.StartTestRoutine(tCtx, pm.SyntheticTests[testConfigId])
 			configChanged = true
 		} else {
 			pm.logger.Debug("not running test as it didn't match agent selector",
@@ -478,25 +945,74 @@ func (pm *PluginManager) SyncSyntestPluginConfigs(ctx context.Context) (bool, er
 }
 
 // StopAndDeleteSynTest stops the syntest plugin and deletes data associated with the syntest
-func (pm *PluginManager) StopAndDeleteSynTest(ctx context.Context, testConfigId string) {
+func (pm *PluginManager) StopAndDeleteSynTest(ctx context.Context, testConfigId string, reason string) {
 	pm.logger.Debug("stopping and deleting", "test", testConfigId)
+	pluginId := pm.StopSynTest(testConfigId, reason)
+
+	// delete plugin state
+	pm.sm.DeletePluginState(pluginId)
+
+	err := pm.esh.Store.DeleteAllTestRunInfo(ctx, pluginId)
+	if err != nil {
+		pm.logger.Warn("error deleting syntest data from ext-storage", "name", pluginId, "err", err)
+	}
+}
+
+// StopSynTest stops the syntest plugin and removes it from the local cache (keeping its data), returns its plugin id
+func (pm *PluginManager) StopSynTest(testConfigId string, reason string) string {
 	pm.SyntheticTests[testConfigId].cancel()
 	(pm.SyntheticTests[testConfigId].wg).Wait() // wait until the test stops
 
 	testName := pm.SyntheticTests[testConfigId].config.Name
 	testNamespace := pm.SyntheticTests[testConfigId].config.Namespace
 	pluginId := common.ComputePluginId(testName, testNamespace, pm.AgentId)
-
-	// delete plugin state
-	pm.sm.DeletePluginState(pluginId)
+	if !pm.SyntheticTests[testConfigId].config.Paused { // the plugin of a paused test isn't running
+		pm.audit.Record(pluginId, common.AuditActionStopped, reason, map[string]string{
+			"version": pm.SyntheticTests[testConfigId].version,
+		})
+	}
 
 	// delete config from local cache
 	delete(pm.SyntheticTests, testConfigId)
+	return pluginId
+}
 
-	err := pm.esh.Store.DeleteAllTestRunInfo(ctx, pluginId)
-	if err != nil {
-		pm.logger.Warn("error deleting syntest data from ext-storage", "name", pluginId, "err", err)
+// RequestTestRun Passes a requested (one-shot) run of a test to its routine, if the test runs on this agent
+func (pm *PluginManager) RequestTestRun(request common.TestRunRequest) {
+	st, ok := pm.SyntheticTests[request.ConfigId]
+	if !ok {
+		pm.logger.Trace("ignoring test run request, test not running on this agent", "test", request.ConfigId)
+		return
+	}
+	if len(request.Agents) > 0 && !slices.Contains(request.Agents, pm.AgentId) {
+		pm.logger.Trace("ignoring test run request, not requested on this agent", "test", request.ConfigId)
+		return
 	}
+	if st.config.Paused {
+		pm.logger.Info("ignoring test run request, test is paused", "test", request.ConfigId, "request", request.Id)
+		return
+	}
+	pm.logger.Info("test run requested", "test", request.ConfigId, "request", request.Id)
+	select {
+	case st.runRequests <- request:
+	default:
+		pm.logger.Warn("dropping test run request, too many pending requests", "test", request.ConfigId, "request", request.Id)
+	}
+}
+
+// PauseTestRoutine Sets the state of a paused synthetic test, its plugin isn't started (its data is kept)
+func (pm *PluginManager) PauseTestRoutine(s SyntheticTest) {
+	pluginId := common.ComputePluginId(s.config.Name, s.config.Namespace, pm.AgentId)
+	pm.sm.SetPluginState(pluginId, common.PluginState{
+		Status:        common.Paused,
+		StatusMsg:     "test paused",
+		Config:        s.config,
+		Restarts:      -1,
+		TotalRestarts: -1,
+	})
+	pm.audit.Record(pluginId, common.AuditActionPaused, "", map[string]string{
+		"version": s.version,
+	})
 }
 
 // StartTestRoutine Starts the synthetic test go routine (that manages the plugin process)
@@ -533,6 +1049,11 @@ func (pm *PluginManager) StartTestRoutine(ctx context.Context, s SyntheticTest)
 			broadcaster:     &pm.broadcaster,
 			storageHandler:  &pm.esh,
 			printPluginLogs: pm.config.PrintPluginLogs,
+			runRequests:     s.runRequests,
+			recording:       pm.config.RecordingConfig,
+			runTimeInfo:     pm.config.RunTimeInfo,
+			expansion:       pm.config.ConfigExpansion,
+			secrets:         pm.secrets,
 		}
 
 		// Add the go routine to the wait group
@@ -548,10 +1069,16 @@ func (pm *PluginManager) StartTestRoutine(ctx context.Context, s SyntheticTest)
 			restartPolicy = common.DefaultRestartPolicy
 		}
 
+		pm.audit.Record(pluginId, common.AuditActionStarted, "", map[string]string{
+			"version":       s.version,
+			"plugin":        s.config.PluginName,
+			"restartPolicy": string(restartPolicy),
+		})
+
 		// Start the go routine with the params
 		go func(ctx context.Context, id string, pluginName string, restartPolicy common.PluginRestartPolicy, routine SynTestRoutine, sm StateMap) {
 			defer s.wg.Done()
-			StartPlugin(ctx, id, pluginName, &routine, restartPolicy, sm)
+			StartPlugin(ctx, id, pluginName, &routine, restartPolicy, sm, &pm.audit)
 		}(ctx, pluginId, t.config.PluginName, restartPolicy, t, pm.sm)
 	} else {
 		// Set error state for the plugin
@@ -562,8 +1089,8 @@ func (pm *PluginManager) StartTestRoutine(ctx context.Context, s SyntheticTest)
 	}
 }
 
-// StartPlugin Starts a plugin and manages the lifecycle (i.e. syntest)
-func StartPlugin(ctx context.Context, pluginId string, pluginName string, plugin RunnablePlugin, restartPolicy common.PluginRestartPolicy, sm StateMap) {
+// StartPlugin Starts a plugin and manages the lifecycle (i.e. syntest), restarts are recorded in the audit log (if not nil)
+func StartPlugin(ctx context.Context, pluginId string, pluginName string, plugin RunnablePlugin, restartPolicy common.PluginRestartPolicy, sm StateMap, audit *AuditLogger) {
 	logger := hclog.New(&hclog.LoggerOptions{
 		Name:            "pm.pluginStarter",
 		Level:           hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
@@ -606,6 +1133,7 @@ func StartPlugin(ctx context.Context, pluginId string, pluginName string, plugin
 			if restartPolicy == common.RestartNever {
 				s.Status = common.Error
 				sm.SetPluginState(pluginId, s)
+				audit.Record(pluginId, common.AuditActionExited, s.StatusMsg, map[string]string{"restartPolicy": string(restartPolicy)})
 				break // dont restart
 			} else {
 				s.Status = common.Restarting
@@ -616,6 +1144,7 @@ func StartPlugin(ctx context.Context, pluginId string, pluginName string, plugin
 			if restartPolicy == common.RestartNever || restartPolicy == common.RestartOnError {
 				s.Status = common.NotRunning
 				sm.SetPluginState(pluginId, s)
+				audit.Record(pluginId, common.AuditActionExited, s.StatusMsg, map[string]string{"restartPolicy": string(restartPolicy)})
 				break // dont restart
 			} else {
 				s.Status = common.Restarting
@@ -644,6 +1173,12 @@ func StartPlugin(ctx context.Context, pluginId string, pluginName string, plugin
 		// Set the restart backoff time
 		s.RestartBackOff = backOffTime.String()
 		sm.SetPluginState(pluginId, s)
+		if ctx.Err() == nil { // not a restart if the plugin is being stopped
+			audit.Record(pluginId, common.AuditActionRestarting, s.StatusMsg, map[string]string{
+				"backOff":       s.RestartBackOff,
+				"totalRestarts": strconv.Itoa(s.TotalRestarts),
+			})
+		}
 
 		// Wait before retrying
 		ticker := time.NewTicker(backOffTime)
@@ -25,7 +25,8 @@ import (
 )
 
 const (
-	SyntestPrefix = "test-"
+	SyntestPrefix  = "test-"
+	ExporterPrefix = "exporter-"
 )
 
 // SynTestNameMap is a map of plugin names to go-plugin objs
@@ -40,8 +41,20 @@ func RegisterSynTestPlugin(pluginName string, cmd []string) {
 	SynTestCmdMap[pluginName] = cmd
 }
 
-// DiscoverPlugins returns a list of all plugins discovered in the plugin directory
-func DiscoverPlugins(config common.PluginDiscoveryConfig) (map[string][]string, error) {
+// ExporterNameMap is a map of exporter plugin names to go-plugin objs
+var ExporterNameMap = map[string]plugin.Plugin{}
+
+// ExporterCmdMap is a map of exporter plugin names to plugin commands
+var ExporterCmdMap = map[string][]string{}
+
+// RegisterExporterPlugin registers an exporter plugin with the plugin manager
+func RegisterExporterPlugin(pluginName string, cmd []string) {
+	ExporterNameMap[pluginName] = &common.ExporterGRPCPlugin{}
+	ExporterCmdMap[pluginName] = cmd
+}
+
+// DiscoverPlugins returns a list of all plugins with the given prefix (test- or exporter-) discovered in the plugin directory
+func DiscoverPlugins(config common.PluginDiscoveryConfig, prefix string) (map[string][]string, error) {
 	plugins := map[string][]string{}
 
 	files, err := filepath.Glob(config.Path)
@@ -51,10 +64,10 @@ func DiscoverPlugins(config common.PluginDiscoveryConfig) (map[string][]string,
 	for _, filePath := range files {
 		cmd := []string{}
 
-		// Check if the file starts with the syntest prefix, all files must start with test-
+		// Check if the file starts with the prefix, e.g. all syntest files must start with test-
 		components := strings.Split(filePath, "/")
 		fileName := components[len(components)-1]
-		if !strings.HasPrefix(fileName, SyntestPrefix) {
+		if !strings.HasPrefix(fileName, prefix) {
 			continue
 		}
 
@@ -68,7 +81,7 @@ func DiscoverPlugins(config common.PluginDiscoveryConfig) (map[string][]string,
 		}
 
 		// use the file name as the plugin name so test-abc will be "abc" plugin
-		pluginName := strings.TrimPrefix(fileName, SyntestPrefix)
+		pluginName := strings.TrimPrefix(fileName, prefix)
 		plugins[pluginName] = cmd
 	}
 	return plugins, nil
@@ -0,0 +1,77 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"os"
+	"reflect"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/hashicorp/go-hclog"
+)
+
+// SyncAgentProfile Fetches the agent profiles from external storage and applies the one matching the agent (on top of
+// the config file), keeps the current config if it fails. Returns whether the prometheus exporter needs a restart to
+// apply it, as the prometheus config can't be changed while the exporter is running
+func (pm *PluginManager) SyncAgentProfile(ctx context.Context) bool {
+	profiles, err := pm.esh.Store.FetchAllAgentProfiles(ctx)
+	if err != nil {
+		pm.logger.Error("error syncing agent profiles, keeping the current config", "err", err)
+		return false
+	}
+	profile, ok := common.SelectAgentProfile(profiles, pm.baseConfig, pm.AgentId, pm.logger)
+	if !ok {
+		profile = common.AgentProfile{}
+	}
+	if reflect.DeepEqual(profile, pm.profile) {
+		return false
+	}
+
+	pm.logger.Info("applying agent profile", "profile", profile.Name, "previous", pm.profile.Name)
+	config := profile.Apply(pm.baseConfig)
+	restart := !reflect.DeepEqual(config.PrometheusConfig, pm.config.PrometheusConfig)
+	pm.config = config
+	pm.profile = profile
+	pm.sm.SetAgentConfig(config)
+
+	logLevel := profile.LogLevel
+	if logLevel == "" {
+		logLevel = os.Getenv("LOG_LEVEL")
+	}
+	pm.logger.SetLevel(hclog.LevelFromString(logLevel))
+
+	// stop the tests of plugins which aren't allowed anymore
+	for testConfigId, st := range pm.SyntheticTests {
+		if !common.IsPluginAllowed(pm.config, st.config.PluginName) {
+			pm.logger.Info("plugin not allowed by agent profile, stopping syntest", "test", testConfigId, "plugin", st.config.PluginName)
+			pm.StopAndDeleteSynTest(ctx, testConfigId, "plugin not allowed by agent profile")
+		}
+	}
+	return restart
+}
+
+// RestartPrometheus Restarts the prometheus exporter (in the agent process) with the current config, e.g. to apply an
+// agent profile. The metrics of the tests are registered again by the next test runs, the tests keep running.
+func (pm *PluginManager) RestartPrometheus(reason string) {
+	pm.logger.Warn("restarting prometheus exporter", "reason", reason)
+	select {
+	case <-pm.promRestart: // only the latest config is applied
+	default:
+	}
+	pm.promRestart <- pm.config
+}
@@ -31,7 +31,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/push"
 	"gopkg.in/yaml.v3"
 	"net/http"
+	"os"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
@@ -45,37 +48,79 @@ type PrometheusExporter struct {
 	broadcaster *utils.Broadcaster
 	srv         *http.Server
 	gauges      map[string]*prometheus.GaugeVec
+	runCounter  *prometheus.CounterVec
+	runtimeHist *prometheus.HistogramVec
 	pusher      *push.Pusher
 	logger      hclog.Logger
 	runTimeInfo common.AgentInfo
+	traceIds    bool                      // whether test runs are exported as traces (so the trace id can be added to exemplars)
+	series      map[string]*trackedSeries // last time every series was updated, used to remove stale series
+	limiter     *cardinalityLimiter       // limits the label values of custom metrics
+	flapping    bool                      // whether flap detection is enabled
+	transitions transitionTracker         // detects flapping tests
+	silences    *SilenceMap
+}
+
+// trackedSeries is a single series (metric + label values) exported by the agent
+type trackedSeries struct {
+	lastUpdated time.Time
+	delete      func() bool // removes the series from its metric vec
 }
 
 const (
-	MarksGauge    = "syntheticheart_marks_total"
-	MaxMarksGauge = "syntheticheart_max_marks_total"
-	TimeGauge     = "syntheticheart_runtime_ns"
+	MarksGauge    = common.MetricMarks
+	MaxMarksGauge = common.MetricMaxMarks
+	TimeGauge     = common.MetricRuntime
+	RunCounter    = common.MetricTestRuns
+	RuntimeHist   = common.MetricRuntimeHist
+	FlappingGauge = common.MetricFlapping
+	SilencedGauge = common.MetricSilenced
 	CustomGauge   = "syntheticheart_%s" // Gauge name
 )
 
 const PrometheusLabelRegex = "[a-zA-Z_][a-zA-Z0-9_]*"
 
-func NewPrometheusExporter(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, debugMode bool) (PrometheusExporter, error) {
-	p := PrometheusExporter{}
+const (
+	HistogramClassic = "classic"
+	HistogramNative  = "native"
+	HistogramBoth    = "both"
+
+	DefaultNativeBucketFactor = 1.1
+	DefaultNativeMaxBuckets   = 160
+)
+
+func NewPrometheusExporter(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, debugMode bool, silences *SilenceMap) (PrometheusExporter, error) {
+	p := PrometheusExporter{silences: silences}
 	p.config = agentConfig.PrometheusConfig
 	p.logger = logger
 	if !p.config.Push {
 		mux := http.NewServeMux()
-		mux.Handle("/metrics", promhttp.Handler())
+		// open metrics is needed to expose exemplars
+		mux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+			promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})))
 		if debugMode {
 			mux.Handle("/debug/", http.DefaultServeMux)
 		}
 		srv := &http.Server{Addr: p.config.ServerAddress, Handler: mux}
 		p.srv = srv
 	} else {
-		p.pusher = push.New(p.config.PrometheusPushUrl, agentId).Gatherer(prometheus.DefaultGatherer)
+		if p.config.PushJob != "" {
+			p.pusher = push.New(p.config.PrometheusPushUrl, p.config.PushJob).Grouping("instance", agentId)
+		} else {
+			p.pusher = push.New(p.config.PrometheusPushUrl, agentId)
+		}
+		p.pusher = p.pusher.Gatherer(prometheus.DefaultGatherer)
+		if p.config.PushUsername != "" {
+			p.pusher = p.pusher.BasicAuth(p.config.PushUsername, os.Getenv(p.config.PushPasswordEnv))
+		}
 	}
 	p.gauges = map[string]*prometheus.GaugeVec{}
+	p.series = map[string]*trackedSeries{}
+	p.limiter = newCardinalityLimiter(p.config.MaxLabelValues)
 	p.runTimeInfo = agentConfig.RunTimeInfo
+	p.traceIds = agentConfig.OtelConfig.Endpoint != "" && agentConfig.OtelConfig.Traces
+	p.flapping = agentConfig.FlapDetection.Window > 1
+	p.transitions = newTransitionTracker(agentConfig.FlapDetection)
 
 	return p, nil
 }
@@ -89,6 +134,23 @@ func (p *PrometheusExporter) Run(ctx context.Context, broadcaster *utils.Broadca
 		wg.Add(1)
 		go func() { p.startPrometheusClient(); wg.Done() }()
 	}
+
+	// if a push interval is set, push on a timer rather than after every test run
+	var pushTicker <-chan time.Time
+	if p.config.Push && p.config.PushInterval > 0 {
+		ticker := time.NewTicker(p.config.PushInterval)
+		defer ticker.Stop()
+		pushTicker = ticker.C
+	}
+
+	// if a staleness window is set, periodically remove series that weren't updated within the window
+	var stalenessTicker <-chan time.Time
+	if p.config.StalenessWindow > 0 {
+		ticker := time.NewTicker(p.config.StalenessWindow / 2)
+		defer ticker.Stop()
+		stalenessTicker = ticker.C
+	}
+
 	for {
 		select {
 		case res := <-resChan:
@@ -97,19 +159,36 @@ func (p *PrometheusExporter) Run(ctx context.Context, broadcaster *utils.Broadca
 				p.logger.Error("error exporting test run metrics", "err", err)
 			}
 
+		case <-pushTicker:
+			err := p.pusher.Push()
+			if err != nil {
+				p.logger.Error("error pushing metrics to push server", "err", err)
+			}
+
+		case <-stalenessTicker:
+			p.RemoveStaleSeries(time.Now())
+
 		case <-configChange:
+			if p.config.StalenessWindow > 0 {
+				p.logger.Info("config changed, series of removed tests will expire after the staleness window", "window", p.config.StalenessWindow)
+				break
+			}
 			p.logger.Info("config changed, cleaning up prometheus")
 			p.Cleanup()
 		case <-ctx.Done():
-			// stop the client server and wait for it to stop
-			err := p.stopPrometheusClient()
-			if err != nil {
-				p.logger.Error("error exporting test run metrics", "err", err)
-			}
-			if !p.config.Push {
+			if p.config.Push {
+				p.stopPushing()
+			} else {
+				// stop the client server and wait for it to stop
+				err := p.stopPrometheusClient()
+				if err != nil {
+					p.logger.Error("error exporting test run metrics", "err", err)
+				}
 				p.logger.Info("waiting prometheus client server to finish")
 				wg.Wait()
 			}
+			broadcaster.UnsubscribeFromTestRuns(resChan, p.logger)
+			p.Unregister()
 			p.logger.Info("prometheus exporter exiting")
 			return
 		}
@@ -120,6 +199,56 @@ func (p *PrometheusExporter) Cleanup() {
 	for _, gauge := range p.gauges {
 		gauge.Reset()
 	}
+	if p.runCounter != nil {
+		p.runCounter.Reset()
+	}
+	if p.runtimeHist != nil {
+		p.runtimeHist.Reset()
+	}
+	p.series = map[string]*trackedSeries{}
+	p.limiter.Reset()
+}
+
+// Unregister Unregisters the metrics of the exporter, so a new exporter (e.g. with the config of an agent profile) can
+// register them again
+func (p *PrometheusExporter) Unregister() {
+	for _, gauge := range p.gauges {
+		prometheus.Unregister(gauge)
+	}
+	if p.runCounter != nil {
+		prometheus.Unregister(p.runCounter)
+	}
+	if p.runtimeHist != nil {
+		prometheus.Unregister(p.runtimeHist)
+	}
+}
+
+// RemoveStaleSeries Removes all the series that weren't updated within the staleness window
+func (p *PrometheusExporter) RemoveStaleSeries(now time.Time) {
+	for key, s := range p.series {
+		if now.Sub(s.lastUpdated) <= p.config.StalenessWindow {
+			continue
+		}
+		p.logger.Debug("removing stale series", "series", key, "lastUpdated", s.lastUpdated)
+		s.delete()
+		delete(p.series, key)
+	}
+	p.limiter.Expire(now, p.config.StalenessWindow)
+}
+
+// trackSeries Records that a series was updated, so it can be removed once it goes stale
+func (p *PrometheusExporter) trackSeries(name string, labels prometheus.Labels, delete func() bool) {
+	var kvs []string
+	for k, v := range labels {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	key := name + "{" + strings.Join(kvs, ",") + "}"
+	if s, ok := p.series[key]; ok {
+		s.lastUpdated = time.Now()
+		return
+	}
+	p.series[key] = &trackedSeries{lastUpdated: time.Now(), delete: delete}
 }
 
 func (p *PrometheusExporter) ExportTestRunMetrics(res proto.TestRun) error {
@@ -138,7 +267,7 @@ func (p *PrometheusExporter) ExportTestRunMetrics(res proto.TestRun) error {
 		}
 	}
 
-	if p.config.Push {
+	if p.config.Push && p.config.PushInterval <= 0 {
 		err := p.pusher.Push()
 		if err != nil {
 			return errors.Wrap(err, "error pushing metrics to push server")
@@ -160,8 +289,80 @@ func (p *PrometheusExporter) stopPrometheusClient() error {
 	return err
 }
 
+// runtimeHistogramOpts Returns the options for the runtime histogram, it can have classic buckets, native buckets or both
+func (p *PrometheusExporter) runtimeHistogramOpts() prometheus.HistogramOpts {
+	config := p.config.Histogram
+	opts := prometheus.HistogramOpts{
+		Name: RuntimeHist,
+		Help: "The runtime of the test in seconds",
+	}
+	if config.Type == HistogramNative || config.Type == HistogramBoth {
+		opts.NativeHistogramBucketFactor = config.NativeBucketFactor
+		if opts.NativeHistogramBucketFactor <= 1 {
+			opts.NativeHistogramBucketFactor = DefaultNativeBucketFactor
+		}
+		opts.NativeHistogramMaxBucketNumber = config.NativeMaxBuckets
+		if opts.NativeHistogramMaxBucketNumber == 0 {
+			opts.NativeHistogramMaxBucketNumber = DefaultNativeMaxBuckets
+		}
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+	if config.Type != HistogramNative {
+		opts.Buckets = config.Buckets
+		if len(opts.Buckets) == 0 {
+			opts.Buckets = prometheus.DefBuckets
+		}
+	}
+	if config.Type != "" && config.Type != HistogramClassic && config.Type != HistogramNative && config.Type != HistogramBoth {
+		p.logger.Warn("unknown histogram type, using classic", "type", config.Type)
+	}
+	return opts
+}
+
+// stopPushing Either deletes the metrics from the push gateway, or pushes the latest metrics before exiting
+func (p *PrometheusExporter) stopPushing() {
+	if p.config.PushDeleteOnExit {
+		p.logger.Info("deleting metrics from push server...")
+		err := p.pusher.Delete()
+		if err != nil {
+			p.logger.Error("error deleting metrics from push server", "err", err)
+		}
+		return
+	}
+	if p.config.PushInterval > 0 {
+		err := p.pusher.Push()
+		if err != nil {
+			p.logger.Error("error pushing metrics to push server", "err", err)
+		}
+	}
+}
+
 func (p *PrometheusExporter) renderLabels(testConfig *proto.SynTestConfig) (map[string]string, error) {
+	renderedLabels, err := renderLabelTemplates(p.config.Labels, &p.runTimeInfo, testConfig)
+	if err != nil {
+		return renderedLabels, err
+	}
+
+	// Add the metric labels declared by the test, every metric needs the same label keys, so only the keys from
+	// the agent config are added (with an empty value if the test doesn't declare it)
+	for _, k := range p.config.TestLabelKeys {
+		renderedLabels[k] = testConfig.MetricLabels[k]
+	}
+	for k := range renderedLabels {
+		// Check if the label matches the prometheus regex
+		isValid := validMetricLabelRegex.MatchString(k)
+		if !isValid {
+			return renderedLabels, errors.New(fmt.Sprintf("label %s does not match the prometheus regex %s", k, PrometheusLabelRegex))
+		}
 
+		p.logger.Info("new prometheus metric label", "label", k, "value", renderedLabels)
+	}
+	return renderedLabels, nil
+}
+
+// renderLabelTemplates Renders label values which can be templates using the agent info and test config
+// e.g. "{{.Agent.NodeName}}" or "{{.TestConfig.PluginName}}"
+func renderLabelTemplates(labels map[string]string, agentInfo *common.AgentInfo, testConfig *proto.SynTestConfig) (map[string]string, error) {
 	renderedLabels := map[string]string{}
 
 	type Vals struct {
@@ -169,25 +370,20 @@ func (p *PrometheusExporter) renderLabels(testConfig *proto.SynTestConfig) (map[
 		TestConfig *proto.SynTestConfig
 	}
 
-	vals := Vals{Agent: &p.runTimeInfo, TestConfig: testConfig}
+	vals := Vals{Agent: agentInfo, TestConfig: testConfig}
 
 	// Render the labels
-	for k, v := range p.config.Labels {
+	for k, v := range labels {
 		tmpl, err := template.New("val").Parse(v)
 		if err != nil {
 			return renderedLabels, errors.Wrap(err, "error parsing label template")
 		}
 		buf := new(bytes.Buffer)
 		err = tmpl.Execute(buf, vals)
-		renderedLabels[k] = buf.String()
-
-		// Check if the label matches the prometheus regex
-		isValid := validMetricLabelRegex.MatchString(k)
-		if !isValid {
-			return renderedLabels, errors.New(fmt.Sprintf("label %s does not match the prometheus regex %s", k, PrometheusLabelRegex))
+		if err != nil {
+			return renderedLabels, errors.Wrap(err, "error rendering label template")
 		}
-
-		p.logger.Info("new prometheus metric label", "label", k, "value", renderedLabels)
+		renderedLabels[k] = buf.String()
 	}
 	return renderedLabels, nil
 }
@@ -201,6 +397,7 @@ func (p *PrometheusExporter) addDefaultMetrics(testRun proto.TestRun) error {
 	}
 	labels["test_name"] = testRun.TestConfig.Name
 	labels["test_namespace"] = testRun.TestConfig.Namespace
+	labels["importance"] = common.ImportanceLevel(testRun.TestConfig.Importance)
 
 	// Add the marks of the test as a prometheus Gauge
 	p.setOrCreateGauge(MarksGauge,
@@ -233,9 +430,84 @@ func (p *PrometheusExporter) addDefaultMetrics(testRun proto.TestRun) error {
 		labels,
 		testRun)
 
+	// Add the test run count and runtime histogram, with exemplars pointing to the test run (and its trace)
+	p.addRunMetricsWithExemplars(labels, runtime, testRun)
+
+	// Add whether notifications of the test are silenced as a prometheus Gauge
+	silenced := 0.0
+	if p.silences.Silenced(testRun.TestConfig) {
+		silenced = 1.0
+	}
+	p.setOrCreateGauge(SilencedGauge,
+		"Whether notifications of the test are silenced (e.g. maintenance window)",
+		silenced,
+		labels,
+		testRun)
+
+	// Add whether the test is flapping as a prometheus Gauge
+	if p.flapping {
+		p.transitions.Update(testRun)
+		flapping := 0.0
+		if p.transitions.Flapping(testRun.TestConfig) {
+			flapping = 1.0
+		}
+		p.setOrCreateGauge(FlappingGauge,
+			"Whether the test is flapping (changing state too often)",
+			flapping,
+			labels,
+			testRun)
+	}
+
 	return nil
 }
 
+func (p *PrometheusExporter) addRunMetricsWithExemplars(labels map[string]string, runtime time.Duration, testRun proto.TestRun) {
+	var labelKeys []string
+	for k := range labels {
+		labelKeys = append(labelKeys, k)
+	}
+	if p.runCounter == nil {
+		p.runCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: RunCounter,
+			Help: "The number of test runs, by result",
+		}, append(labelKeys, "result"))
+	}
+	if p.runtimeHist == nil {
+		p.runtimeHist = promauto.NewHistogramVec(p.runtimeHistogramOpts(), labelKeys)
+	}
+
+	exemplar := prometheus.Labels{"run_id": testRun.Id}
+	if p.traceIds {
+		exemplar["trace_id"] = TraceIdForRun(testRun.Id).String()
+	}
+
+	result := "pass"
+	if testRun.TestResult.Marks < testRun.TestResult.MaxMarks {
+		result = "fail"
+	}
+	counterLabels := prometheus.Labels{"result": result}
+	for k, v := range labels {
+		counterLabels[k] = v
+	}
+	c, err := p.runCounter.GetMetricWith(counterLabels)
+	if err != nil {
+		p.logger.Error("error getting metric with labels", "err", err)
+	} else {
+		c.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+		runCounter := p.runCounter
+		p.trackSeries(RunCounter, counterLabels, func() bool { return runCounter.Delete(counterLabels) })
+	}
+
+	h, err := p.runtimeHist.GetMetricWith(labels)
+	if err != nil {
+		p.logger.Error("error getting metric with labels", "err", err)
+	} else {
+		h.(prometheus.ExemplarObserver).ObserveWithExemplar(runtime.Seconds(), exemplar)
+		runtimeHist := p.runtimeHist
+		p.trackSeries(RuntimeHist, labels, func() bool { return runtimeHist.Delete(labels) })
+	}
+}
+
 // Parses the custom metrics passed by the test result
 func (p *PrometheusExporter) addCustomMetrics(promMetricsStr string, res proto.TestRun) error {
 	p.logger.Debug("processing prometheus specific results...")
@@ -251,9 +523,18 @@ func (p *PrometheusExporter) addCustomMetrics(promMetricsStr string, res proto.T
 	}
 	labels["test_name"] = res.TestConfig.Name
 	labels["test_namespace"] = res.TestConfig.Namespace
+	labels["importance"] = common.ImportanceLevel(res.TestConfig.Importance)
 	for _, gauge := range promMetrics.Gauges {
 		gaugeName := cleanMetricName(fmt.Sprintf(CustomGauge, gauge.Name))
 		p.logger.Debug("adding " + gaugeName)
+		if gauge.Labels == nil {
+			gauge.Labels = map[string]string{}
+		}
+		// Limit the label values added by the plugin
+		if p.limiter.Limit(gaugeName, gauge.Labels) {
+			p.logger.Warn("custom metric has too many label values, replaced with '"+OverflowLabelValue+"'",
+				"metric", gaugeName, "test", res.TestConfig.Name, "max", p.limiter.maxValues)
+		}
 		// Inject metadata labels into the custom gauge
 		for k, v := range labels {
 			gauge.Labels[k] = v
@@ -283,8 +564,11 @@ func (p *PrometheusExporter) setOrCreateGauge(name string, help string, value fl
 	g, err := p.gauges[name].GetMetricWith(labels)
 	if err != nil {
 		p.logger.Error("error getting metric with labels", "err", err)
+		return
 	}
 	g.Set(value)
+	gauge := p.gauges[name]
+	p.trackSeries(name, labels, func() bool { return gauge.Delete(labels) })
 }
 
 func cleanMetricName(dirty string) string {
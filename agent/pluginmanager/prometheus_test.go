@@ -0,0 +1,39 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"testing"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestPrometheusExporterRestart(t *testing.T) {
+	config := common.AgentConfig{PrometheusConfig: common.PrometheusConfig{ServerAddress: ":0"}}
+	labels := map[string]string{"test": "ping"}
+	for i := 0; i < 2; i++ {
+		// registering the gauge again panics unless the previous exporter unregistered it
+		p, err := NewPrometheusExporter(hclog.NewNullLogger(), config, "agent", false, &SilenceMap{})
+		if err != nil {
+			t.Fatalf("NewPrometheusExporter() error = %v", err)
+		}
+		p.setOrCreateGauge("synheart_test_restart", "test gauge", 1, labels, proto.TestRun{})
+		p.Unregister()
+	}
+}
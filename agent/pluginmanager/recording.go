@@ -0,0 +1,474 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	goPlugin "github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRecordings is the number of recordings kept per test if not configured
+const DefaultMaxRecordings = 20
+
+// recorder records the calls of a syntest routine to its plugin, and the logs of the plugin, in a test run
+type recorder struct {
+	start     time.Time
+	mutex     sync.Mutex
+	recording common.TestRunRecording
+	logs      io.Writer            // the logs of the plugin are also written here
+	config    *proto.SynTestConfig // the config of the test as it was written (not expanded)
+	closed    bool                 // calls returning after the recording is closed aren't recorded
+}
+
+func newRecorder(agentId string, runTimeInfo common.AgentInfo, config *proto.SynTestConfig, trigger *proto.Trigger, logs io.Writer) *recorder {
+	r := recorder{start: time.Now(), logs: logs, config: config}
+	r.recording = common.TestRunRecording{
+		AgentId:     agentId,
+		RunTimeInfo: runTimeInfo,
+		RecordedAt:  r.start,
+		Config:      marshalRecorded(config),
+		Trigger:     marshalRecorded(trigger),
+		Calls:       []common.RecordedCall{},
+		Logs:        []common.RecordedLog{},
+	}
+	return &r
+}
+
+// marshalRecorded Marshals a request or response of a plugin to protojson
+func marshalRecorded(m protoreflect.ProtoMessage) json.RawMessage {
+	bs, err := protojson.Marshal(m)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return bs
+}
+
+// Write Records the logs written by the plugin, and passes them on
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	if !r.closed {
+		r.recording.Logs = append(r.recording.Logs, common.RecordedLog{Offset: time.Since(r.start), Data: string(p)})
+	}
+	r.mutex.Unlock()
+	return r.logs.Write(p)
+}
+
+// record Records the start of a call to the plugin, the returned function records its response
+func (r *recorder) record(method string, request protoreflect.ProtoMessage) func(response protoreflect.ProtoMessage, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return func(protoreflect.ProtoMessage, error) {}
+	}
+	r.recording.Calls = append(r.recording.Calls, common.RecordedCall{
+		Method:   method,
+		Request:  marshalRecorded(request),
+		Offset:   time.Since(r.start),
+		NoReturn: true, // until it returns
+	})
+	i := len(r.recording.Calls) - 1
+	return func(response protoreflect.ProtoMessage, err error) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if r.closed {
+			return
+		}
+		call := &r.recording.Calls[i]
+		call.Duration = time.Since(r.start) - call.Offset
+		call.NoReturn = false
+		if response != nil {
+			call.Response = marshalRecorded(response)
+		}
+		if err != nil {
+			call.Error = err.Error()
+		}
+	}
+}
+
+// setTestRun Sets the id and the result of the test run
+func (r *recorder) setTestRun(id string, failed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.recording.TestRunId = id
+	r.recording.Failed = failed
+}
+
+// close Stops recording and returns the recording, the run failed if a call errored or didn't return
+func (r *recorder) close() common.TestRunRecording {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+	for _, call := range r.recording.Calls {
+		if call.Error != "" || call.NoReturn {
+			r.recording.Failed = true
+		}
+	}
+	return r.recording
+}
+
+// wrap Wraps the plugin so its calls are recorded
+func (r *recorder) wrap(plugin common.SynTestPlugin) common.SynTestPlugin {
+	return &recordingPlugin{plugin: plugin, recorder: r}
+}
+
+// recordingPlugin is a syntest plugin recording the calls to the plugin it wraps
+type recordingPlugin struct {
+	plugin   common.SynTestPlugin
+	recorder *recorder
+}
+
+func (p *recordingPlugin) Initialise(config proto.SynTestConfig) error {
+	// the config expanded with the secrets of the test isn't recorded, it's recorded as it was written instead
+	request := &config
+	if len(config.SecretRefs) > 0 {
+		request = p.recorder.config
+	}
+	done := p.recorder.record("Initialise", request)
+	err := p.plugin.Initialise(config)
+	done(&proto.Empty{}, err)
+	return err
+}
+
+func (p *recordingPlugin) PerformTest(trigger proto.Trigger) (proto.TestResult, error) {
+	done := p.recorder.record("PerformTest", &trigger)
+	res, err := p.plugin.PerformTest(trigger)
+	done(&res, err)
+	return res, err
+}
+
+func (p *recordingPlugin) Finish() error {
+	done := p.recorder.record("Finish", &proto.Empty{})
+	err := p.plugin.Finish()
+	done(&proto.Empty{}, err)
+	return err
+}
+
+// replayPlugin is a syntest plugin replaying the recorded calls and logs of a plugin, the calls take their recorded
+// duration (divided by the speed), and the calls that didn't return block until the plugin is killed
+type replayPlugin struct {
+	mutex    sync.Mutex
+	calls    map[string][]common.RecordedCall // the calls left to replay by method
+	speed    float64
+	killed   chan struct{}
+	killOnce sync.Once
+}
+
+func newReplayPlugin(recording *common.TestRunRecording, speed float64, logs io.Writer) *replayPlugin {
+	p := replayPlugin{
+		calls:  map[string][]common.RecordedCall{},
+		speed:  speed,
+		killed: make(chan struct{}),
+	}
+	for _, call := range recording.Calls {
+		p.calls[call.Method] = append(p.calls[call.Method], call)
+	}
+
+	// write the logs at the time they were written by the plugin
+	go func(recordedLogs []common.RecordedLog) {
+		start := time.Now()
+		for _, l := range recordedLogs {
+			select {
+			case <-time.After(p.scale(l.Offset) - time.Since(start)):
+				_, _ = logs.Write([]byte(l.Data))
+			case <-p.killed:
+				return
+			}
+		}
+	}(recording.Logs)
+	return &p
+}
+
+// scale Scales a recorded duration by the speed of the replay
+func (p *replayPlugin) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / p.speed)
+}
+
+// kill Kills the plugin, unblocking its calls
+func (p *replayPlugin) kill() {
+	p.killOnce.Do(func() {
+		close(p.killed)
+	})
+}
+
+// replay Replays the next recorded call of the method
+func (p *replayPlugin) replay(method string) (common.RecordedCall, error) {
+	p.mutex.Lock()
+	calls := p.calls[method]
+	if len(calls) == 0 || calls[0].NoReturn {
+		p.mutex.Unlock()
+		<-p.killed
+		return common.RecordedCall{}, errors.New("plugin killed before " + method + " returned")
+	}
+	call := calls[0]
+	p.calls[method] = calls[1:]
+	p.mutex.Unlock()
+
+	select {
+	case <-time.After(p.scale(call.Duration)):
+	case <-p.killed:
+		return call, errors.New("plugin killed before " + method + " returned")
+	}
+	if call.Error != "" {
+		return call, errors.New(call.Error)
+	}
+	return call, nil
+}
+
+func (p *replayPlugin) Initialise(config proto.SynTestConfig) error {
+	_, err := p.replay("Initialise")
+	return err
+}
+
+func (p *replayPlugin) PerformTest(trigger proto.Trigger) (proto.TestResult, error) {
+	call, err := p.replay("PerformTest")
+	if len(call.Response) == 0 {
+		return common.FailedTestResult(), err
+	}
+	res := &proto.TestResult{}
+	uErr := protojson.Unmarshal(call.Response, res)
+	if uErr != nil {
+		return common.FailedTestResult(), errors.Wrap(uErr, "error unmarshalling recorded test result")
+	}
+	return *res, err
+}
+
+func (p *replayPlugin) Finish() error {
+	_, err := p.replay("Finish")
+	return err
+}
+
+// isRecorded Checks if the runs of the test are recorded
+func (str *SynTestRoutine) isRecorded() bool {
+	if !str.recording.Enabled || str.recording.When == common.LogNever {
+		return false
+	}
+	if len(str.recording.Tests) == 0 {
+		return true
+	}
+	configId := common.ComputeSynTestConfigId(str.config.Name, str.config.Namespace)
+	for _, pattern := range str.recording.Tests {
+		if matched, _ := path.Match(pattern, configId); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// saveRecording Writes the recording of a test run to the recording dir (one dir per test), and deletes the oldest
+// recordings of the test over the max, errors are only logged
+func (str *SynTestRoutine) saveRecording(rec *recorder) {
+	recording := rec.close()
+	if len(recording.Calls) == 0 || (str.recording.When == common.LogOnFail && !recording.Failed) {
+		return
+	}
+	dir := filepath.Join(str.recording.Dir, str.config.Name+"_"+str.config.Namespace)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		str.logger.Error("error creating recording dir", "dir", dir, "err", err)
+		return
+	}
+	runId := recording.TestRunId
+	if runId == "" {
+		runId = "init"
+	}
+	file := filepath.Join(dir, recording.RecordedAt.UTC().Format("20060102T150405.000Z")+"-"+runId+".json")
+	bs, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		str.logger.Error("error marshalling recording", "err", err)
+		return
+	}
+	err = os.WriteFile(file, bs, 0644)
+	if err != nil {
+		str.logger.Error("error writing recording", "file", file, "err", err)
+		return
+	}
+	str.logger.Info("recorded test run", "file", file, "failed", recording.Failed)
+
+	// delete the oldest recordings (the file names start with the time)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		str.logger.Warn("error listing recordings", "dir", dir, "err", err)
+		return
+	}
+	files := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, e.Name())
+		}
+	}
+	sort.Strings(files)
+	for i := 0; i < len(files)-str.recording.MaxPerTest; i++ {
+		err = os.Remove(filepath.Join(dir, files[i]))
+		if err != nil {
+			str.logger.Warn("error deleting old recording", "file", files[i], "err", err)
+		}
+	}
+}
+
+// ReadRecording Reads a recording of a test run written by the agent
+func ReadRecording(file string) (common.TestRunRecording, error) {
+	recording := common.TestRunRecording{}
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return recording, errors.Wrap(err, "error reading recording")
+	}
+	err = json.Unmarshal(bs, &recording)
+	if err != nil {
+		return recording, errors.Wrap(err, "error unmarshalling recording")
+	}
+	if len(recording.Config) == 0 || len(recording.Calls) == 0 {
+		return recording, errors.New("recording has no test config or calls")
+	}
+	return recording, nil
+}
+
+// NewReplayPluginManager Creates a plugin manager replaying a recording of a test run, with the exporters and notifiers
+// of the agent config. It doesn't need the pod info (the recording has the agent's) and doesn't run any syntest plugin.
+func NewReplayPluginManager(configPath string, recording common.TestRunRecording) (*PluginManager, error) {
+	pm := PluginManager{
+		SyntheticTests: map[string]SyntheticTest{},
+	}
+	pm.logger = hclog.New(&hclog.LoggerOptions{
+		Name:            "pm",
+		Level:           hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+		Color:           hclog.ForceColor,
+		IncludeLocation: true,
+	})
+
+	err := pm.readConfigFile(configPath)
+	if err != nil {
+		pm.logger.Error("error parsing config", "err", err)
+		return nil, err
+	}
+	pm.AgentId = recording.AgentId
+	pm.config.RunTimeInfo = recording.RunTimeInfo
+	pm.config.RecordingConfig = common.RecordingConfig{} // don't record the replays
+	if pm.config.GracePeriod <= 0 {
+		return nil, errors.New("gracePeriod must be a positive value")
+	}
+	if pm.config.PrintPluginLogs != common.LogOnFail && pm.config.PrintPluginLogs != common.LogAlways && pm.config.PrintPluginLogs != common.LogNever {
+		pm.config.PrintPluginLogs = common.LogNever
+	}
+
+	// only the exporter plugins are needed
+	pm.config.DiscoveredExporters = map[string][]string{}
+	for _, pluginDiscoveryConfig := range pm.config.EnabledPlugins {
+		exporters, err := DiscoverPlugins(pluginDiscoveryConfig, ExporterPrefix)
+		if err != nil {
+			return nil, errors.Wrap(err, "error discovering exporter plugins")
+		}
+		for name, cmds := range exporters {
+			pm.config.DiscoveredExporters[name] = cmds
+			RegisterExporterPlugin(name, cmds)
+		}
+	}
+
+	pm.sm = NewStateMap(pm.logger, pm.config)
+	pm.silences = NewSilenceMap(pm.AgentId)
+	pm.broadcaster = utils.NewBroadcaster(pm.logger)
+
+	// the notifiers read the status of the other agents from storage (nothing is written to it)
+	esh, err := NewExtStorageHandler(pm.AgentId, pm.config.StoreConfig, pm.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating storage client")
+	}
+	pm.esh = esh
+	pm.audit = NewAuditLogger(pm.logger.Named("audit"), pm.AgentId, esh.Store)
+	pm.baseConfig = pm.config
+	return &pm, nil
+}
+
+// Replay Replays the recorded test run the given number of times, the test runs are sent to the exporters and
+// notifiers like the runs of the plugin. Blocks until the replays are exported (after the grace period).
+func (pm *PluginManager) Replay(ctx context.Context, recording *common.TestRunRecording, times int, speed float64) error {
+	defer goPlugin.CleanupClients()
+	if speed <= 0 {
+		return errors.New("speed must be a positive value")
+	}
+
+	config := &proto.SynTestConfig{}
+	err := protojson.Unmarshal(recording.Config, config)
+	if err != nil {
+		return errors.Wrap(err, "error unmarshalling recorded test config")
+	}
+	trigger := &proto.Trigger{}
+	err = protojson.Unmarshal(recording.Trigger, trigger)
+	if err != nil {
+		return errors.Wrap(err, "error unmarshalling recorded trigger")
+	}
+
+	bwg := sync.WaitGroup{} // wait group for broadcaster
+	agentMetrics.setSources(&pm.sm, &pm.broadcaster)
+	bwg.Add(1)
+	go func() {
+		pm.broadcaster.Start()
+		bwg.Done()
+	}()
+	stopExporters := pm.startExporters(ctx, make(chan struct{}, 2))
+
+	pluginId := common.ComputePluginId(config.Name, config.Namespace, pm.AgentId)
+	pm.sm.SetPluginState(pluginId, common.PluginState{
+		Status:       common.Running,
+		Config:       config,
+		RunningSince: time.Now(),
+		LastUpdated:  time.Now(),
+	})
+
+	str := SynTestRoutine{
+		agentId:         pm.AgentId,
+		config:          *config,
+		broadcaster:     &pm.broadcaster,
+		storageHandler:  &pm.esh,
+		printPluginLogs: pm.config.PrintPluginLogs,
+		expandedConfig:  config.Config, // the plugin isn't run
+		replay:          recording,
+		replaySpeed:     speed,
+	}
+	initTimeout, testTimeout, finishTimeout := str.setup()
+	for i := 1; i <= times && ctx.Err() == nil; i++ {
+		pm.logger.Info("replaying test run", "test", config.Name, "namespace", config.Namespace, "testRun", recording.TestRunId, "replay", i, "times", times)
+		err = str.testPlugin(ctx, *trigger, initTimeout, testTimeout, finishTimeout)
+		if err != nil {
+			pm.logger.Warn("replayed test run errored", "err", err)
+		}
+	}
+
+	pm.logger.Warn("allowing time for agent to export all test results...", "gracePeriod", pm.config.GracePeriod)
+	time.Sleep(pm.config.GracePeriod)
+	stopExporters()
+
+	pm.broadcaster.Stop()
+	pm.logger.Info("waiting for broadcaster finish...")
+	bwg.Wait()
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"path/filepath"
+)
+
+// SecretReader reads the keys of the kubernetes secrets referenced by the tests (secretRefs), from the secrets mounted
+// in the agent or with the kubernetes api. The values are only rendered into the configs given to the plugins.
+type SecretReader struct {
+	config    common.SecretsConfig
+	namespace string               // namespace of the agent, only its secrets can be mounted
+	client    kubernetes.Interface // nil if the api isn't enabled
+}
+
+// NewSecretReader Creates a secret reader, with a kubernetes client if the secrets are read with the api
+func NewSecretReader(config common.SecretsConfig, agentNamespace string) (*SecretReader, error) {
+	r := SecretReader{config: config, namespace: agentNamespace}
+	if config.Api {
+		k8sConfig, err := utils.GetK8sConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting k8s config")
+		}
+		r.client, err = kubernetes.NewForConfig(k8sConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating k8s client")
+		}
+	}
+	return &r, nil
+}
+
+// Resolve Returns the values of the secret refs of the test by name. Namespaced tests can only reference the secrets
+// of their namespace, and the tests written through the rest api can't reference secrets (its users may not be allowed
+// to read them).
+func (r *SecretReader) Resolve(ctx context.Context, testConfig *proto.SynTestConfig) (map[string]string, error) {
+	secrets := map[string]string{}
+	if len(testConfig.SecretRefs) == 0 {
+		return secrets, nil
+	}
+	if r == nil {
+		return nil, errors.New("the agent doesn't read secrets")
+	}
+	if testConfig.Labels[common.ConfigSourceLabel] == common.ConfigSourceAPI {
+		return nil, errors.New("tests written through the rest api can't reference secrets")
+	}
+	err := common.ValidateSecretRefs(testConfig.SecretRefs)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range testConfig.SecretRefs {
+		namespace, err := common.SecretRefNamespace(ref, testConfig.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		val, err := r.read(ctx, namespace, ref.Secret, ref.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading secret ref '%s'", ref.Name)
+		}
+		secrets[ref.Name] = val
+	}
+	return secrets, nil
+}
+
+// read Returns the value of a key of a secret, from the mounted secret if there's one, otherwise with the api
+func (r *SecretReader) read(ctx context.Context, namespace, secret, key string) (string, error) {
+	if r.config.MountPath != "" && namespace == r.namespace {
+		val, err := os.ReadFile(filepath.Join(r.config.MountPath, secret, key))
+		if err == nil {
+			return string(val), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", errors.Wrap(err, "error reading mounted secret")
+		}
+	}
+	if r.client == nil {
+		return "", errors.New("secret " + namespace + "/" + secret + " (key " + key + ") isn't mounted in the agent, " +
+			"and the kubernetes api isn't enabled (secrets.api)")
+	}
+	s, err := r.client.CoreV1().Secrets(namespace).Get(ctx, secret, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "error getting secret "+namespace+"/"+secret)
+	}
+	val, ok := s.Data[key]
+	if !ok {
+		return "", errors.New("secret " + namespace + "/" + secret + " has no key " + key)
+	}
+	return string(val), nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics about the agent itself (rather than the tests it runs)
+var (
+	configSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "syntheticheart_agent_config_sync_duration_seconds",
+		Help: "How long it takes to sync the syntest configs from external storage",
+	})
+	configSyncErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "syntheticheart_agent_config_sync_errors_total",
+		Help: "The number of failed syntest config syncs",
+	})
+	storageOpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "syntheticheart_agent_storage_operation_duration_seconds",
+		Help: "The latency of external storage operations",
+	}, []string{"operation"})
+	storageOpErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "syntheticheart_agent_storage_operation_errors_total",
+		Help: "The number of failed external storage operations",
+	}, []string{"operation"})
+
+	agentMetrics = &agentCollector{
+		restarts: prometheus.NewDesc(common.MetricPluginRestarts,
+			"The number of times the plugin of the test was restarted", []string{"test_name", "test_namespace"}, nil),
+		backOff: prometheus.NewDesc("syntheticheart_agent_plugin_restart_backoff_seconds",
+			"How long the agent is waiting before restarting the plugin of the test (0 if not waiting)", []string{"test_name", "test_namespace"}, nil),
+		status: prometheus.NewDesc("syntheticheart_agent_plugin_status",
+			"The status of the plugin of the test (1 for the current status)", []string{"test_name", "test_namespace", "status"}, nil),
+		running: prometheus.NewDesc(common.MetricRunningSynTests,
+			"The number of syntests with a running plugin", nil, nil),
+		queueDepth: prometheus.NewDesc(common.MetricBroadcasterQueue,
+			"The number of test runs waiting in the broadcaster queues", []string{"queue"}, nil),
+	}
+)
+
+func init() {
+	prometheus.MustRegister(agentMetrics)
+}
+
+// agentCollector collects the state of plugins and broadcaster queues when the metrics are scraped
+type agentCollector struct {
+	lock        sync.Mutex
+	sm          *StateMap
+	broadcaster *utils.Broadcaster
+
+	restarts   *prometheus.Desc
+	backOff    *prometheus.Desc
+	status     *prometheus.Desc
+	running    *prometheus.Desc
+	queueDepth *prometheus.Desc
+}
+
+// setSources Sets the state map and broadcaster to collect metrics from (they change every time the plugin manager restarts)
+func (c *agentCollector) setSources(sm *StateMap, broadcaster *utils.Broadcaster) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sm = sm
+	c.broadcaster = broadcaster
+}
+
+func (c *agentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.restarts
+	ch <- c.backOff
+	ch <- c.status
+	ch <- c.running
+	ch <- c.queueDepth
+}
+
+func (c *agentCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.sm != nil {
+		running := 0
+		for pluginId, state := range c.sm.GetAllPluginState().PluginStates {
+			testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+			if err != nil {
+				continue
+			}
+			if state.Status == common.Running {
+				running++
+			}
+			restarts := state.TotalRestarts
+			if restarts < 0 { // plugin was never started
+				restarts = 0
+			}
+			ch <- prometheus.MustNewConstMetric(c.restarts, prometheus.CounterValue, float64(restarts), testName, testNs)
+			backOff, _ := time.ParseDuration(state.RestartBackOff)
+			ch <- prometheus.MustNewConstMetric(c.backOff, prometheus.GaugeValue, backOff.Seconds(), testName, testNs)
+			ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, 1, testName, testNs, string(state.Status))
+		}
+		ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(running))
+	}
+	if c.broadcaster != nil {
+		publishDepth, listenerDepths := c.broadcaster.QueueDepths()
+		ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(publishDepth), "publish")
+		for name, depth := range listenerDepths {
+			ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(depth), name)
+		}
+	}
+}
+
+// observeStorageOp Records the latency of an external storage operation, and whether it failed
+func observeStorageOp(operation string, start time.Time, err error) {
+	storageOpDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		storageOpErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// instrumentedStore records metrics for the external storage operations used by the agent
+type instrumentedStore struct {
+	storage.SynHeartStore
+}
+
+func (s instrumentedStore) WriteTestRun(ctx context.Context, pluginId string, testRun proto.TestRun) error {
+	start := time.Now()
+	err := s.SynHeartStore.WriteTestRun(ctx, pluginId, testRun)
+	observeStorageOp("writeTestRun", start, err)
+	return err
+}
+
+func (s instrumentedStore) DeleteAllTestRunInfo(ctx context.Context, pluginId string) error {
+	start := time.Now()
+	err := s.SynHeartStore.DeleteAllTestRunInfo(ctx, pluginId)
+	observeStorageOp("deleteAllTestRunInfo", start, err)
+	return err
+}
+
+func (s instrumentedStore) WritePluginHealthStatus(ctx context.Context, pluginId string, state common.PluginState) error {
+	start := time.Now()
+	err := s.SynHeartStore.WritePluginHealthStatus(ctx, pluginId, state)
+	observeStorageOp("writePluginHealthStatus", start, err)
+	return err
+}
+
+func (s instrumentedStore) FetchTestConfig(ctx context.Context, configId string) (proto.SynTestConfig, error) {
+	start := time.Now()
+	config, err := s.SynHeartStore.FetchTestConfig(ctx, configId)
+	observeStorageOp("fetchTestConfig", start, err)
+	return config, err
+}
+
+func (s instrumentedStore) FetchAllTestConfigSummary(ctx context.Context) (map[string]common.SyntestConfigSummary, error) {
+	start := time.Now()
+	summaries, err := s.SynHeartStore.FetchAllTestConfigSummary(ctx)
+	observeStorageOp("fetchAllTestConfigSummary", start, err)
+	return summaries, err
+}
+
+func (s instrumentedStore) WriteAgentStatus(ctx context.Context, agentId string, status common.AgentStatus) error {
+	start := time.Now()
+	err := s.SynHeartStore.WriteAgentStatus(ctx, agentId, status)
+	observeStorageOp("writeAgentStatus", start, err)
+	return err
+}
+
+func (s instrumentedStore) DeleteAgentStatus(ctx context.Context, agentId string) error {
+	start := time.Now()
+	err := s.SynHeartStore.DeleteAgentStatus(ctx, agentId)
+	observeStorageOp("deleteAgentStatus", start, err)
+	return err
+}
+
+func (s instrumentedStore) NewAgentEvent(ctx context.Context, event string) error {
+	start := time.Now()
+	err := s.SynHeartStore.NewAgentEvent(ctx, event)
+	observeStorageOp("newAgentEvent", start, err)
+	return err
+}
+
+func (s instrumentedStore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.SynHeartStore.Ping(ctx)
+	observeStorageOp("ping", start, err)
+	return err
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+// SilenceMap holds the silences (synced from external storage), so notifiers can check if a test is silenced
+type SilenceMap struct {
+	agentId  string
+	lock     *sync.RWMutex
+	silences map[string]common.Silence
+}
+
+func NewSilenceMap(agentId string) SilenceMap {
+	return SilenceMap{
+		agentId:  agentId,
+		lock:     &sync.RWMutex{},
+		silences: map[string]common.Silence{},
+	}
+}
+
+// Update Replaces the silences
+func (s *SilenceMap) Update(silences map[string]common.Silence) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.silences = silences
+}
+
+// Silenced Returns whether notifications of the test (running on this agent) are silenced right now
+func (s *SilenceMap) Silenced(testConfig *proto.SynTestConfig) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	now := time.Now()
+	for _, silence := range s.silences {
+		if silence.IsActive(now) && silence.Matches(testConfig, s.agentId) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultSlackWebhookUrlEnv = "SLACK_WEBHOOK_URL"
+	DefaultSlackTemplate      = `{{if eq .Transition "fail"}}:red_circle: *{{.DisplayName}}* is failing{{else if eq .Transition "flapping"}}:large_orange_circle: *{{.DisplayName}}* is flapping{{else}}:large_green_circle: *{{.DisplayName}}* recovered{{end}} ({{.TestRun.TestConfig.Namespace}}/{{.TestRun.TestConfig.Name}})
+*Agent:* {{.AgentId}} ({{.TestRun.TestResult.Marks}}/{{.TestRun.TestResult.MaxMarks}})
+*Importance:* {{.Importance}}
+{{- if .FailingAgents}}
+*Failing agents:* {{join .FailingAgents ", "}}
+{{- end}}
+{{- with index .TestRun.TestResult.Details "_error"}}
+*Details:* {{.}}
+{{- end}}`
+)
+
+// SlackNotifier posts a message to a slack incoming webhook when a test starts failing or recovers
+type SlackNotifier struct {
+	config      common.SlackConfig
+	webhookUrl  string
+	tmpl        *template.Template
+	client      *http.Client
+	store       storage.SynHeartStore
+	agentId     string
+	transitions transitionTracker
+	silences    *SilenceMap
+	logger      hclog.Logger
+}
+
+type slackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+func NewSlackNotifier(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, store storage.SynHeartStore, silences *SilenceMap) (SlackNotifier, error) {
+	s := SlackNotifier{config: agentConfig.SlackConfig, store: store, silences: silences, agentId: agentId, logger: logger}
+	if s.config.WebhookUrlEnv == "" {
+		s.config.WebhookUrlEnv = DefaultSlackWebhookUrlEnv
+	}
+	if s.config.Template == "" {
+		s.config.Template = DefaultSlackTemplate
+	}
+	s.webhookUrl = os.Getenv(s.config.WebhookUrlEnv)
+	if s.webhookUrl == "" {
+		return s, errors.New("slack webhook url not found in env var " + s.config.WebhookUrlEnv)
+	}
+	tmpl, err := template.New("slack").Funcs(notificationTemplateFuncs).Parse(s.config.Template)
+	if err != nil {
+		return s, errors.Wrap(err, "error parsing slack message template")
+	}
+	s.tmpl = tmpl
+	s.client = &http.Client{Timeout: 10 * time.Second}
+	s.transitions = newTransitionTracker(agentConfig.FlapDetection)
+	return s, nil
+}
+
+func (s *SlackNotifier) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("slack", common.DefaultChannelSize, s.logger)
+	for {
+		select {
+		case res := <-resChan:
+			transition := s.transitions.Update(res)
+			if transition == TransitionNone || res.TestConfig.GetAlerting().GetDisabled() || s.silences.Silenced(res.TestConfig) {
+				continue
+			}
+			err := s.Notify(ctx, transition, res)
+			if err != nil {
+				s.logger.Error("error sending slack notification", "test", res.TestConfig.Name, "err", err)
+			}
+		case <-ctx.Done():
+			s.logger.Info("slack notifier exiting")
+			return
+		}
+	}
+}
+
+// Notify Renders the message for the test run and posts it to slack
+func (s *SlackNotifier) Notify(ctx context.Context, transition Transition, testRun proto.TestRun) error {
+	data := newNotificationData(ctx, s.store, s.agentId, transition, testRun, s.logger)
+	buf := new(bytes.Buffer)
+	err := s.tmpl.Execute(buf, data)
+	if err != nil {
+		return errors.Wrap(err, "error rendering slack message template")
+	}
+	msg := slackMessage{Channel: s.config.Channel, Text: buf.String()}
+	if channel := testRun.TestConfig.GetAlerting().GetSlackChannel(); channel != "" {
+		msg.Channel = channel
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling slack message")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request to slack")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("slack returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -79,15 +79,22 @@ func (sm *StateMap) DeletePluginState(id string) {
 	delete(sm.state.PluginStates, id)
 }
 
+// SetAgentConfig updates the agent config in the agent status (e.g. after an agent profile is applied)
+func (sm *StateMap) SetAgentConfig(agentConfig common.AgentConfig) {
+	sm.stateLock.Lock()
+	defer sm.stateLock.Unlock()
+	sm.c = agentConfig
+}
+
 // GetAgentStatus returns the state the agent is in including the status of all the plugins, as well as the agent config
 func (sm *StateMap) GetAgentStatus() common.AgentStatus {
+	sm.stateLock.Lock()
+	defer sm.stateLock.Unlock()
 	status := common.AgentStatus{
 		SynTests:    []string{},
 		StatusTime:  time.Now().Format(common.TimeFormat),
 		AgentConfig: sm.c,
 	}
-	sm.stateLock.Lock()
-	defer sm.stateLock.Unlock()
 	for k, _ := range sm.state.PluginStates {
 		testName, testNs, _, _, _ := common.GetPluginIdComponents(k)
 		status.SynTests = append(status.SynTests, common.ComputeSynTestConfigId(testName, testNs))
@@ -0,0 +1,174 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultStatsdPrefix        = "syntheticheart"
+	DefaultStatsdFlushInterval = 15 * time.Second
+)
+
+var invalidStatsdNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+
+// StatsdExporter sends test run results (pass/fail, latency) and plugin restarts to a StatsD (or DogStatsD) server
+type StatsdExporter struct {
+	config      common.StatsdConfig
+	conn        net.Conn
+	sm          *StateMap
+	logger      hclog.Logger
+	runTimeInfo common.AgentInfo
+}
+
+func NewStatsdExporter(logger hclog.Logger, agentConfig common.AgentConfig, sm *StateMap) (StatsdExporter, error) {
+	s := StatsdExporter{config: agentConfig.StatsdConfig, sm: sm, logger: logger, runTimeInfo: agentConfig.RunTimeInfo}
+	if s.config.Prefix == "" {
+		s.config.Prefix = DefaultStatsdPrefix
+	}
+	if s.config.FlushInterval <= 0 {
+		s.config.FlushInterval = DefaultStatsdFlushInterval
+	}
+	conn, err := net.Dial("udp", s.config.Address)
+	if err != nil {
+		return s, errors.Wrap(err, "error connecting to statsd server")
+	}
+	s.conn = conn
+	return s, nil
+}
+
+func (s *StatsdExporter) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("statsd", common.DefaultChannelSize, s.logger)
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case res := <-resChan:
+			err := s.ExportTestRunMetrics(res)
+			if err != nil {
+				s.logger.Error("error exporting test run metrics", "err", err)
+			}
+		case <-ticker.C:
+			err := s.ExportRestartMetrics()
+			if err != nil {
+				s.logger.Error("error exporting plugin restart metrics", "err", err)
+			}
+		case <-ctx.Done():
+			_ = s.conn.Close()
+			s.logger.Info("statsd exporter exiting")
+			return
+		}
+	}
+}
+
+func (s *StatsdExporter) ExportTestRunMetrics(testRun proto.TestRun) error {
+	startTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing start time")
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing end time")
+	}
+
+	result := "passed"
+	if testRun.TestResult.Marks < testRun.TestResult.MaxMarks {
+		result = "failed"
+	}
+	tags := map[string]string{
+		"test_name":      testRun.TestConfig.Name,
+		"test_namespace": testRun.TestConfig.Namespace,
+		"plugin":         testRun.TestConfig.PluginName,
+		"importance":     common.ImportanceLevel(testRun.TestConfig.Importance),
+		"test_node":      s.runTimeInfo.NodeName,
+	}
+
+	var lines []string
+	lines = append(lines, s.line(testRun.TestConfig, "test."+result, "1|c", tags))
+	lines = append(lines, s.line(testRun.TestConfig, "test.duration", fmt.Sprintf("%d|ms", endTime.Sub(startTime).Milliseconds()), tags))
+	lines = append(lines, s.line(testRun.TestConfig, "test.marks", fmt.Sprintf("%d|g", testRun.TestResult.Marks), tags))
+	lines = append(lines, s.line(testRun.TestConfig, "test.max_marks", fmt.Sprintf("%d|g", testRun.TestResult.MaxMarks), tags))
+	return s.send(lines)
+}
+
+// ExportRestartMetrics Sends the total number of restarts of every plugin
+func (s *StatsdExporter) ExportRestartMetrics() error {
+	var lines []string
+	for pluginId, state := range s.sm.GetAllPluginState().PluginStates {
+		testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+		if err != nil {
+			continue
+		}
+		restarts := state.TotalRestarts
+		if restarts < 0 { // plugin was never started
+			restarts = 0
+		}
+		testConfig := &proto.SynTestConfig{Name: testName, Namespace: testNs}
+		tags := map[string]string{
+			"test_name":      testName,
+			"test_namespace": testNs,
+			"test_node":      s.runTimeInfo.NodeName,
+		}
+		lines = append(lines, s.line(testConfig, "plugin.restarts", fmt.Sprintf("%d|g", restarts), tags))
+	}
+	return s.send(lines)
+}
+
+// line Renders a single statsd line, for plain statsd the test namespace and name become part of the metric name
+func (s *StatsdExporter) line(testConfig *proto.SynTestConfig, name string, value string, tags map[string]string) string {
+	if !s.config.DogStatsd {
+		return fmt.Sprintf("%s.%s.%s.%s:%s", s.config.Prefix, cleanStatsdName(testConfig.Namespace),
+			cleanStatsdName(testConfig.Name), name, value)
+	}
+	var tagList []string
+	for k, v := range s.config.Tags {
+		tagList = append(tagList, k+":"+v)
+	}
+	for k, v := range tags {
+		tagList = append(tagList, k+":"+v)
+	}
+	sort.Strings(tagList)
+	return fmt.Sprintf("%s.%s:%s|#%s", s.config.Prefix, name, value, strings.Join(tagList, ","))
+}
+
+func (s *StatsdExporter) send(lines []string) error {
+	for _, l := range lines {
+		// send each metric in its own packet, so we never go over the max udp packet size
+		_, err := s.conn.Write([]byte(l))
+		if err != nil {
+			return errors.Wrap(err, "error writing to statsd server")
+		}
+	}
+	return nil
+}
+
+func cleanStatsdName(dirty string) string {
+	return invalidStatsdNameRegex.ReplaceAllString(dirty, "_")
+}
@@ -20,13 +20,20 @@ import (
 	"context"
 	"github.com/cisco-open/synthetic-heart/agent/utils"
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/cisco-open/synthetic-heart/common/storage"
 	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
+	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	DefaultForwardedLogLines = 200
+	DefaultForwardedLogTtl   = 24 * time.Hour
+)
+
 // ExtStorageHandler manages all communication with external storage (redis)
 type ExtStorageHandler struct {
 	agentId      string
@@ -46,9 +53,18 @@ func NewExtStorageHandler(agentId string, config common.StorageConfig, logger hc
 	if err != nil {
 		return ExtStorageHandler{}, err
 	}
+	if config.PluginLogs.When == "" {
+		config.PluginLogs.When = common.LogOnFail
+	}
+	if config.PluginLogs.Lines <= 0 {
+		config.PluginLogs.Lines = DefaultForwardedLogLines
+	}
+	if config.PluginLogs.Ttl <= 0 {
+		config.PluginLogs.Ttl = DefaultForwardedLogTtl
+	}
 	return ExtStorageHandler{
 		agentId:      agentId,
-		Store:        store,
+		Store:        instrumentedStore{store},
 		config:       config,
 		logger:       logger.Named("esh"),
 		filterLock:   &sync.Mutex{},
@@ -140,10 +156,45 @@ func (esh *ExtStorageHandler) runTestRunExporter(ctx context.Context, wg *sync.W
 				continue
 			}
 			esh.logger.Debug("exporting test run to external storage", "testName", testRun.TestConfig.Name)
-			err := esh.Store.WriteTestRun(ctx, common.ComputePluginId(testRun.TestConfig.Name, testRun.TestConfig.Namespace, testRun.AgentId), testRun)
+			pluginId := common.ComputePluginId(testRun.TestConfig.Name, testRun.TestConfig.Namespace, testRun.AgentId)
+			err := esh.Store.WriteTestRun(ctx, pluginId, testRun)
 			if err != nil {
 				esh.logger.Error("error exporting test run", "err", err)
 			}
+			esh.forwardTestRunLogs(ctx, pluginId, testRun)
+		}
+	}
+}
+
+// forwardTestRunLogs Writes the last lines of the logs of the test run to external storage (if enabled)
+func (esh *ExtStorageHandler) forwardTestRunLogs(ctx context.Context, pluginId string, testRun proto.TestRun) {
+	logConfig := esh.config.PluginLogs
+	if !logConfig.Enabled || logConfig.When == common.LogNever {
+		return
+	}
+	failed := testRun.TestResult.Marks < testRun.TestResult.MaxMarks || testRun.Details[common.ErrorKey] != ""
+	if logConfig.When == common.LogOnFail && !failed {
+		return
+	}
+	logs := lastLines(testRun.Details[common.LogKey], logConfig.Lines)
+	if logs == "" {
+		return
+	}
+	err := esh.Store.WriteTestRunLogs(ctx, pluginId, testRun.Id, logs, logConfig.Ttl)
+	if err != nil {
+		esh.logger.Error("error forwarding test run logs", "pluginId", pluginId, "runId", testRun.Id, "err", err)
+	}
+}
+
+// lastLines Returns the last n lines of the logs
+func lastLines(logs string, n int) string {
+	logs = strings.TrimRight(logs, "\n")
+	start := len(logs)
+	for ; n > 0; n-- {
+		start = strings.LastIndex(logs[:start], "\n")
+		if start < 0 {
+			return logs
 		}
 	}
+	return logs[start+1:]
 }
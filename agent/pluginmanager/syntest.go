@@ -22,11 +22,13 @@ import (
 	"fmt"
 	"github.com/cisco-open/synthetic-heart/agent/utils"
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/activation"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/docker/distribution/uuid"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
 	"github.com/pkg/errors"
+	goProto "google.golang.org/protobuf/proto"
 	"io"
 	"io/ioutil"
 	"math/rand"
@@ -46,42 +48,40 @@ type SynTestRoutine struct {
 	logger          hclog.Logger
 	logWaitTime     time.Duration
 	printPluginLogs common.PrintPluginLogOption
+	runRequests     <-chan common.TestRunRequest // requested (one-shot) runs of the test
+	recording       common.RecordingConfig       // recording of the gRPC interaction of the plugin in the runs
+	runTimeInfo     common.AgentInfo             // info of the agent, in the recordings and the config templates
+	expansion       common.ConfigExpansionConfig // expansion of env vars and templates in the config of the test
+	expandedConfig  string                       // the config of the test expanded, the plugin is initialised with it
+	secrets         *SecretReader                // reads the secrets the config of the test references
+	replay          *common.TestRunRecording     // recording replayed instead of running the plugin (see Replay)
+	replaySpeed     float64                      // speed of the replay (1 replays the calls with their recorded durations)
 }
 
 func (str *SynTestRoutine) Run(ctx context.Context) error {
-	// Initialise the routine
-	str.logger = hclog.New(&hclog.LoggerOptions{
-		Name:            "pm." + str.config.Name + ".routine",
-		Level:           hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
-		Color:           hclog.ForceColor,
-		IncludeLocation: true,
-	})
-
-	// Parse timeouts and duration
-	initTimeout, err := time.ParseDuration(str.config.Timeouts.Init)
-	if err != nil {
-		str.logger.Warn("warning: init timeout duration could not be parsed, using default", "err", err, "default", common.DefaultInitTimeout.String())
-		initTimeout = common.DefaultInitTimeout
-	}
-	testTimeout, err := time.ParseDuration(str.config.Timeouts.Run)
-	if err != nil {
-		str.logger.Warn("warning: test timeout duration could not be parsed, using default", "err", err, "default", common.DefaultRunTimeout.String())
-		testTimeout = common.DefaultRunTimeout
-	}
-	finishTimeout, err := time.ParseDuration(str.config.Timeouts.Finish)
+	initTimeout, testTimeout, finishTimeout := str.setup()
+	testRepeatDuration, err := time.ParseDuration(str.config.Repeat)
 	if err != nil {
-		str.logger.Warn("warning: finish timeout duration could not be parsed, using default", "err", err, "default", common.DefaultFinishTimeout.String())
-		finishTimeout = common.DefaultFinishTimeout
+		return errors.Wrap(err, "error parsing repeat duration")
 	}
-	logWaitTime, err := time.ParseDuration(str.config.LogWaitTime)
-	if err != nil {
-		str.logger.Warn("warning: logWaitTime duration could not be parsed, using default", "err", err, "default", common.DefaultLogWaitTime.String())
-		logWaitTime = common.DefaultLogWaitTime
+
+	// Expand the config for the plugin with the secrets it references, the test runs keep the config as it was written
+	if str.replay == nil {
+		secrets, err := str.secrets.Resolve(ctx, &str.config)
+		if err != nil {
+			return errors.Wrap(err, "error reading secrets")
+		}
+		str.expandedConfig, err = expandConfig(&str.config, str.expansion, &str.runTimeInfo, secrets)
+		if err != nil {
+			return errors.Wrap(err, "error expanding config")
+		}
 	}
-	str.logWaitTime = logWaitTime
-	testRepeatDuration, err := time.ParseDuration(str.config.Repeat)
+
+	// The test is only run while it's active (e.g. in business hours), the controller only deploys valid schedules
+	schedule, err := activation.Parse(&str.config)
 	if err != nil {
-		return errors.Wrap(err, "error parsing repeat duration")
+		str.logger.Warn("warning: activation could not be parsed, the test is always active", "err", err)
+		schedule = activation.Schedule{}
 	}
 
 	// Iterate over triggers and set defaults
@@ -116,7 +116,11 @@ func (str *SynTestRoutine) Run(ctx context.Context) error {
 			if str.isCtxCancelled(ctx) { // Check if ctx is cancelled before proceeding (this is to maintain priority of cancel signal if >1 channels are ready)
 				return nil
 			}
-			err := str.testPlugin(ctx, initTimeout, testTimeout, finishTimeout)
+			if !schedule.Active(time.Now()) {
+				str.logger.Debug("test isn't active, skipping run")
+				continue
+			}
+			err := str.testPlugin(ctx, proto.Trigger{TriggerType: common.TriggerTypeTimer}, initTimeout, testTimeout, finishTimeout)
 			if err != nil {
 				return err
 			}
@@ -127,23 +131,72 @@ func (str *SynTestRoutine) Run(ctx context.Context) error {
 				if str.isCtxCancelled(ctx) { // Check if ctx is cancelled before proceeding (this is to maintain priority of cancel signal  if >1 channels are ready)
 					return nil
 				}
-				err := str.testPlugin(ctx, initTimeout, testTimeout, finishTimeout)
+				if !schedule.Active(time.Now()) {
+					str.logger.Debug("test isn't active, skipping run triggered by " + testRun.TestConfig.Name)
+					continue
+				}
+				err := str.testPlugin(ctx, proto.Trigger{TriggerType: common.TriggerTypeTimer}, initTimeout, testTimeout, finishTimeout)
 				if err != nil {
 					return err
 				}
 			}
+		case request := <-str.runRequests: // Watch for requested runs, they run even if the test isn't active
+			if str.isCtxCancelled(ctx) {
+				return nil
+			}
+			str.logger.Info("running requested test run", "request", request.Id)
+			err := str.testPlugin(ctx, proto.Trigger{TriggerType: common.TriggerTypeRun, Details: request.Id}, initTimeout, testTimeout, finishTimeout)
+			if err != nil {
+				return err
+			}
 		case <-ctx.Done(): // Watch for cancellation signal
 			str.logger.Info("kill signal received")
 			return nil
 		}
 
-		if timerChan == nil && testRunChan == nil {
+		if timerChan == nil && testRunChan == nil && str.runRequests == nil {
 			str.logger.Info("all trigger channels are nil")
 			return nil
 		}
 	}
 }
 
+// setup Sets up the logger of the routine and parses the timeouts of the test (the defaults if they're invalid)
+func (str *SynTestRoutine) setup() (initTimeout time.Duration, testTimeout time.Duration, finishTimeout time.Duration) {
+	// Initialise the routine
+	str.logger = hclog.New(&hclog.LoggerOptions{
+		Name:            "pm." + str.config.Name + ".routine",
+		Level:           hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+		Color:           hclog.ForceColor,
+		IncludeLocation: true,
+	})
+
+	// Parse timeouts and duration
+	var err error
+	initTimeout, err = time.ParseDuration(str.config.Timeouts.Init)
+	if err != nil {
+		str.logger.Warn("warning: init timeout duration could not be parsed, using default", "err", err, "default", common.DefaultInitTimeout.String())
+		initTimeout = common.DefaultInitTimeout
+	}
+	testTimeout, err = time.ParseDuration(str.config.Timeouts.Run)
+	if err != nil {
+		str.logger.Warn("warning: test timeout duration could not be parsed, using default", "err", err, "default", common.DefaultRunTimeout.String())
+		testTimeout = common.DefaultRunTimeout
+	}
+	finishTimeout, err = time.ParseDuration(str.config.Timeouts.Finish)
+	if err != nil {
+		str.logger.Warn("warning: finish timeout duration could not be parsed, using default", "err", err, "default", common.DefaultFinishTimeout.String())
+		finishTimeout = common.DefaultFinishTimeout
+	}
+	logWaitTime, err := time.ParseDuration(str.config.LogWaitTime)
+	if err != nil {
+		str.logger.Warn("warning: logWaitTime duration could not be parsed, using default", "err", err, "default", common.DefaultLogWaitTime.String())
+		logWaitTime = common.DefaultLogWaitTime
+	}
+	str.logWaitTime = logWaitTime
+	return initTimeout, testTimeout, finishTimeout
+}
+
 func (str *SynTestRoutine) isCtxCancelled(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
@@ -156,7 +209,7 @@ func (str *SynTestRoutine) isCtxCancelled(ctx context.Context) bool {
 
 // Does a test run with a timeout
 // this is similar to running performTest() using runFuncWithTimeout, but this needs to return a proto.TestRun which is why runFuncWithTimeout is not used
-func (str *SynTestRoutine) runTest(ctx context.Context, st common.SynTestPlugin, triggerInfo proto.Trigger, timeout time.Duration, pluginLogs *utils.Buffer) error {
+func (str *SynTestRoutine) runTest(ctx context.Context, st common.SynTestPlugin, triggerInfo proto.Trigger, timeout time.Duration, pluginLogs *utils.Buffer, rec *recorder) error {
 	s := time.Now()
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -204,7 +257,13 @@ func (str *SynTestRoutine) runTest(ctx context.Context, st common.SynTestPlugin,
 	// Add the logs from the plugin
 	t.Details[common.LogKey] = string(logs)
 	t.AgentId = str.agentId
+	if rec != nil {
+		rec.setTestRun(t.Id, testErr != nil || t.TestResult.Marks < t.TestResult.MaxMarks)
+	}
 	str.broadcaster.PublishTestRun(t, str.logger)
+	if triggerInfo.TriggerType == common.TriggerTypeRun && str.replay == nil { // replays don't answer requests
+		str.writeRunRequestResult(ctx, triggerInfo.Details, t)
+	}
 	e := time.Now()
 	str.logger.Info("handling test took", "time", e.Sub(s).String())
 	return testErr
@@ -322,29 +381,53 @@ func (str *SynTestRoutine) performTest(ctx context.Context, plugin common.SynTes
 	return res, err
 }
 
-func (str *SynTestRoutine) testPlugin(ctx context.Context, initTimeout time.Duration, testTimeout time.Duration, finishTimeout time.Duration) error {
-	// Connect with the Plugin
+func (str *SynTestRoutine) testPlugin(ctx context.Context, trigger proto.Trigger, initTimeout time.Duration, testTimeout time.Duration, finishTimeout time.Duration) error {
+	// Record the calls to the plugin and its logs (if enabled for the test), saved once the plugin is killed
 	pluginLogs := new(utils.Buffer)
-	st, client, _, err := str.connectWithPlugin(str.config.PluginName, SynTestCmdMap[str.config.PluginName], pluginLogs)
-	if err != nil {
-		str.logger.Error("error connecting to plugin!", "err", err)
-		return errors.Wrap(err, "error connecting to plugin")
+	var logs io.Writer = pluginLogs
+	var rec *recorder
+	if str.isRecorded() {
+		rec = newRecorder(str.agentId, str.runTimeInfo, &str.config, &trigger, pluginLogs)
+		logs = rec
+		defer str.saveRecording(rec)
+	}
+
+	// Connect with the Plugin, or replay its recording
+	var st common.SynTestPlugin
+	if str.replay != nil {
+		replay := newReplayPlugin(str.replay, str.replaySpeed, logs)
+		defer replay.kill()
+		st = replay
+	} else {
+		plugin, client, _, err := str.connectWithPlugin(str.config.PluginName, SynTestCmdMap[str.config.PluginName], logs)
+		if err != nil {
+			str.logger.Error("error connecting to plugin!", "err", err)
+			err = errors.Wrap(err, "error connecting to plugin")
+			str.failRunRequest(ctx, trigger, err)
+			return err
+		}
+		defer client.Kill()
+		st = plugin
+	}
+	if rec != nil {
+		st = rec.wrap(st)
 	}
-	defer client.Kill()
 
 	// Initialise the plugin with timeout
-	err = str.runFuncWithTimeout(ctx, initTimeout, "initialise", func(errCh chan error) {
+	err := str.runFuncWithTimeout(ctx, initTimeout, "initialise", func(errCh chan error) {
 		defer str.panicHandler("initialise")
-		err := st.Initialise(str.config)
+		config := goProto.Clone(&str.config).(*proto.SynTestConfig)
+		config.Config = str.expandedConfig
+		err := st.Initialise(*config)
 		errCh <- err
 	})
 	if err != nil {
 		str.logger.Error("error initialising plugin", "err", err)
-		return errors.Wrap(err, "error initialising plugin: --- LOGS ---\n"+pluginLogs.String())
+		err = errors.Wrap(err, "error initialising plugin: --- LOGS ---\n"+pluginLogs.String())
+		str.failRunRequest(ctx, trigger, err)
+		return err
 	}
-	err = str.runTest(ctx, st, proto.Trigger{
-		TriggerType: common.TriggerTypeTimer,
-	}, testTimeout, pluginLogs)
+	err = str.runTest(ctx, st, trigger, testTimeout, pluginLogs, rec)
 	if err != nil {
 		str.logger.Error("error run testing!", "err", err)
 		return err
@@ -353,6 +436,34 @@ func (str *SynTestRoutine) testPlugin(ctx context.Context, initTimeout time.Dura
 	return nil
 }
 
+// writeRunRequestResult Writes the result of a requested test run to storage, for the requester (e.g. a SynTestRun)
+func (str *SynTestRoutine) writeRunRequestResult(ctx context.Context, requestId string, testRun proto.TestRun) {
+	err := str.storageHandler.Store.WriteTestRunRequestResult(ctx, requestId, str.agentId, testRun)
+	if err != nil {
+		str.logger.Error("error writing result of requested test run", "request", requestId, "err", err)
+	}
+}
+
+// failRunRequest Writes a failed result for a requested test run which couldn't be performed (e.g. the plugin didn't
+// initialise), so the requester doesn't wait for it
+func (str *SynTestRoutine) failRunRequest(ctx context.Context, trigger proto.Trigger, err error) {
+	if trigger.TriggerType != common.TriggerTypeRun || str.replay != nil {
+		return
+	}
+	now := time.Now().Format(common.TimeFormat)
+	failedTestResult := common.FailedTestResult()
+	str.writeRunRequestResult(ctx, trigger.Details, proto.TestRun{
+		Id:         uuid.Generate().String(),
+		AgentId:    str.agentId,
+		StartTime:  now,
+		EndTime:    now,
+		TestConfig: &str.config,
+		Trigger:    &trigger,
+		TestResult: &failedTestResult,
+		Details:    map[string]string{common.ErrorKey: err.Error()},
+	})
+}
+
 func (str *SynTestRoutine) connectWithPlugin(pluginName string, executableCmd []string, pluginLogs io.Writer) (common.SynTestPlugin, *plugin.Client, plugin.ClientProtocol, error) {
 	str.logger.Info("connecting with plugin...")
 	var command = exec.Command("")
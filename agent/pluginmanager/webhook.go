@@ -0,0 +1,175 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/agent/utils"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const DefaultWebhookTemplate = `{
+  "event": {{json .Transition}},
+  "test": {{json .TestRun.TestConfig.Name}},
+  "namespace": {{json .TestRun.TestConfig.Namespace}},
+  "displayName": {{json .DisplayName}},
+  "importance": {{json .Importance}},
+  "agent": {{json .AgentId}},
+  "failingAgents": {{json .FailingAgents}},
+  "marks": {{.TestRun.TestResult.Marks}},
+  "maxMarks": {{.TestRun.TestResult.MaxMarks}},
+  "details": {{json .TestRun.TestResult.Details}}
+}`
+
+// WebhookNotifier sends templated json payloads to webhooks when a test starts failing or recovers, so any
+// ticketing/chat system can be integrated
+type WebhookNotifier struct {
+	webhooks    []webhook
+	client      *http.Client
+	store       storage.SynHeartStore
+	agentId     string
+	transitions transitionTracker
+	silences    *SilenceMap
+	logger      hclog.Logger
+}
+
+type webhook struct {
+	config  common.WebhookConfig
+	tmpl    *template.Template
+	headers http.Header
+	events  map[Transition]bool
+}
+
+func NewWebhookNotifier(logger hclog.Logger, agentConfig common.AgentConfig, agentId string, store storage.SynHeartStore, silences *SilenceMap) (WebhookNotifier, error) {
+	w := WebhookNotifier{store: store, silences: silences, agentId: agentId, logger: logger}
+	for i, config := range agentConfig.Webhooks {
+		if config.Name == "" {
+			config.Name = fmt.Sprintf("webhook-%d", i)
+		}
+		if config.Url == "" {
+			return w, errors.New("no url for webhook " + config.Name)
+		}
+		if config.Method == "" {
+			config.Method = http.MethodPost
+		}
+		if config.Template == "" {
+			config.Template = DefaultWebhookTemplate
+		}
+		tmpl, err := template.New(config.Name).Funcs(notificationTemplateFuncs).Parse(config.Template)
+		if err != nil {
+			return w, errors.Wrap(err, "error parsing template of webhook "+config.Name)
+		}
+		headers := http.Header{}
+		headers.Set("Content-Type", "application/json")
+		for k, v := range config.Headers {
+			headers.Set(k, v)
+		}
+		for k, env := range config.HeaderEnvs {
+			val, ok := os.LookupEnv(env)
+			if !ok {
+				return w, errors.New("env var " + env + " not found for header " + k + " of webhook " + config.Name)
+			}
+			headers.Set(k, val)
+		}
+		events := map[Transition]bool{}
+		for _, event := range config.Events {
+			switch Transition(event) {
+			case TransitionFailing, TransitionRecovered, TransitionFlapping:
+				events[Transition(event)] = true
+			default:
+				return w, errors.New("invalid event " + event + " for webhook " + config.Name + ", must be fail, recover or flapping")
+			}
+		}
+		if len(events) == 0 {
+			events = map[Transition]bool{TransitionFailing: true, TransitionRecovered: true, TransitionFlapping: true}
+		}
+		w.webhooks = append(w.webhooks, webhook{config: config, tmpl: tmpl, headers: headers, events: events})
+	}
+	w.client = &http.Client{Timeout: 10 * time.Second}
+	w.transitions = newTransitionTracker(agentConfig.FlapDetection)
+	return w, nil
+}
+
+func (w *WebhookNotifier) Run(ctx context.Context, broadcaster *utils.Broadcaster) {
+	resChan := broadcaster.SubscribeToTestRuns("webhook", common.DefaultChannelSize, w.logger)
+	for {
+		select {
+		case res := <-resChan:
+			transition := w.transitions.Update(res)
+			if transition == TransitionNone || res.TestConfig.GetAlerting().GetDisabled() || w.silences.Silenced(res.TestConfig) {
+				continue
+			}
+			w.Notify(ctx, transition, res)
+		case <-ctx.Done():
+			w.logger.Info("webhook notifier exiting")
+			return
+		}
+	}
+}
+
+// Notify Sends the test run to all the webhooks subscribed to the transition
+func (w *WebhookNotifier) Notify(ctx context.Context, transition Transition, testRun proto.TestRun) {
+	data := newNotificationData(ctx, w.store, w.agentId, transition, testRun, w.logger)
+	for _, hook := range w.webhooks {
+		if !hook.events[transition] {
+			continue
+		}
+		err := w.send(ctx, hook, data)
+		if err != nil {
+			w.logger.Error("error sending webhook", "webhook", hook.config.Name, "test", testRun.TestConfig.Name, "err", err)
+		}
+	}
+}
+
+func (w *WebhookNotifier) send(ctx context.Context, hook webhook, data NotificationData) error {
+	buf := new(bytes.Buffer)
+	err := hook.tmpl.Execute(buf, data)
+	if err != nil {
+		return errors.Wrap(err, "error rendering template")
+	}
+	if !json.Valid(buf.Bytes()) {
+		return errors.New("rendered template is not valid json: " + buf.String())
+	}
+	req, err := http.NewRequestWithContext(ctx, hook.config.Method, hook.config.Url, buf)
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header = hook.headers.Clone()
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("webhook returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
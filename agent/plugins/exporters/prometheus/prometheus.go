@@ -0,0 +1,148 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const PluginName = "prometheus"
+
+const DefaultAddress = ":2113"
+
+// PrometheusExporter exposes the results of the test runs as prometheus metrics on its own server
+type PrometheusExporter struct {
+	config    PrometheusExporterConfig
+	srv       *http.Server
+	labelKeys []string
+	marks     *prometheus.GaugeVec
+	maxMarks  *prometheus.GaugeVec
+	runtime   *prometheus.GaugeVec
+	runs      *prometheus.CounterVec
+}
+
+type PrometheusExporterConfig struct {
+	Address string            `yaml:"address"` // address of the metrics server
+	Labels  map[string]string `yaml:"labels"`  // static labels added to all metrics
+}
+
+func (e *PrometheusExporter) Initialise(config proto.ExporterConfig) error {
+	e.config = PrometheusExporterConfig{}
+	err := common.ParseYMLConfig(config.Config, &e.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if e.config.Address == "" {
+		e.config.Address = DefaultAddress
+	}
+
+	e.labelKeys = []string{"test_name", "test_namespace", "importance"}
+	for k := range e.config.Labels {
+		e.labelKeys = append(e.labelKeys, k)
+	}
+	sort.Strings(e.labelKeys)
+
+	e.marks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricMarks,
+		Help: "The marks obtained in the test",
+	}, e.labelKeys)
+	e.maxMarks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricMaxMarks,
+		Help: "The max marks in the test",
+	}, e.labelKeys)
+	e.runtime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricRuntime,
+		Help: "The runtime of the test in nano seconds",
+	}, e.labelKeys)
+	e.runs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: common.MetricTestRuns,
+		Help: "The number of test runs, by result",
+	}, append(e.labelKeys, "result"))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(e.marks, e.maxMarks, e.runtime, e.runs)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	e.srv = &http.Server{Addr: e.config.Address, Handler: mux}
+	go func() {
+		log.Println("starting metrics server on " + e.config.Address)
+		if err := e.srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Println("error running metrics server: " + err.Error())
+		}
+	}()
+	return nil
+}
+
+func (e *PrometheusExporter) Export(testRun proto.TestRun) error {
+	labels := prometheus.Labels{
+		"test_name":      testRun.TestConfig.Name,
+		"test_namespace": testRun.TestConfig.Namespace,
+		"importance":     common.ImportanceLevel(testRun.TestConfig.Importance),
+	}
+	for k, v := range e.config.Labels {
+		labels[k] = v
+	}
+	startTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing start time")
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	if err != nil {
+		return errors.Wrap(err, "error parsing end time")
+	}
+
+	e.marks.With(labels).Set(float64(testRun.TestResult.Marks))
+	e.maxMarks.With(labels).Set(float64(testRun.TestResult.MaxMarks))
+	e.runtime.With(labels).Set(float64(endTime.Sub(startTime).Nanoseconds()))
+
+	result := "pass"
+	if testRun.TestResult.Marks < testRun.TestResult.MaxMarks {
+		result = "fail"
+	}
+	labels["result"] = result
+	e.runs.With(labels).Inc()
+	return nil
+}
+
+func (e *PrometheusExporter) Finish() error {
+	if e.srv == nil {
+		return nil
+	}
+	return e.srv.Close()
+}
+
+func main() {
+	pluginImpl := &PrometheusExporter{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultExporterPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.ExporterGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
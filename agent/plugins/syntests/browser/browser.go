@@ -0,0 +1,186 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "browser"
+
+/*
+ * Test to load a page in headless chromium, wait for an element and report the page load timings
+ */
+type BrowserTest struct {
+	config BrowserTestConfig
+}
+
+type BrowserTestConfig = pluginconfig.BrowserTestConfig
+
+// Subset of the PerformanceNavigationTiming entry (all in ms, relative to the start of the navigation)
+type NavigationTiming struct {
+	ResponseStart            float64 `json:"responseStart"`
+	DomInteractive           float64 `json:"domInteractive"`
+	DomContentLoadedEventEnd float64 `json:"domContentLoadedEventEnd"`
+	LoadEventEnd             float64 `json:"loadEventEnd"`
+}
+
+func (t *BrowserTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = BrowserTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Url == "" {
+		return errors.New("url must be set")
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 30 * time.Second
+	}
+	return nil
+}
+
+func (t *BrowserTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: 1, Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.NoSandbox, // the agent usually runs as a container, where the chromium sandbox is not available
+		chromedp.Flag("disable-dev-shm-usage", true),
+	)
+	if t.config.ChromePath != "" {
+		opts = append(opts, chromedp.ExecPath(t.config.ChromePath))
+	}
+	if t.config.UserAgent != "" {
+		opts = append(opts, chromedp.UserAgent(t.config.UserAgent))
+	}
+	if t.config.IgnoreCertErrors {
+		opts = append(opts, chromedp.IgnoreCertErrors)
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	defer cancelAlloc()
+	ctx, cancelCtx := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Printf))
+	defer cancelCtx()
+	ctx, cancelTimeout := context.WithTimeout(ctx, t.config.Timeout)
+	defer cancelTimeout()
+
+	// Collect console errors and uncaught exceptions
+	consoleErrors := []string{}
+	mu := sync.Mutex{}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			if ev.Type != runtime.APITypeError {
+				return
+			}
+			args := []string{}
+			for _, arg := range ev.Args {
+				args = append(args, strings.Trim(string(arg.Value), "\""))
+			}
+			consoleErrors = append(consoleErrors, strings.Join(args, " "))
+		case *runtime.EventExceptionThrown:
+			consoleErrors = append(consoleErrors, ev.ExceptionDetails.Error())
+		}
+	})
+
+	log.Println("loading " + t.config.Url)
+	actions := []chromedp.Action{chromedp.Navigate(t.config.Url)}
+	if t.config.WaitSelector != "" {
+		actions = append(actions, chromedp.WaitVisible(t.config.WaitSelector, chromedp.ByQuery))
+	}
+	timing := NavigationTiming{}
+	actions = append(actions, chromedp.Evaluate(
+		`JSON.parse(JSON.stringify(performance.getEntriesByType("navigation")[0] || {}))`, &timing))
+
+	start := time.Now()
+	err := chromedp.Run(ctx, actions...)
+	totalDuration := time.Since(start)
+
+	mu.Lock()
+	errCount := len(consoleErrors)
+	for _, e := range consoleErrors {
+		log.Println("console error: " + e)
+	}
+	mu.Unlock()
+
+	labels := map[string]string{"url": t.config.Url}
+	promMetrics.Gauges = append(promMetrics.Gauges,
+		common.PrometheusGauge{Name: "browser_total_duration_ns", Help: "Time taken to load the page and find the selector",
+			Value: float64(totalDuration.Nanoseconds()), Labels: labels},
+		common.PrometheusGauge{Name: "browser_console_errors", Help: "Number of errors logged to the console",
+			Value: float64(errCount), Labels: labels})
+
+	if err != nil {
+		log.Println("error loading page", err)
+		testResult.Details[common.ErrorKey] = err.Error()
+	} else {
+		log.Printf("page loaded in %dms (ttfb: %.0fms, domContentLoaded: %.0fms, load: %.0fms)\n",
+			totalDuration.Milliseconds(), timing.ResponseStart, timing.DomContentLoadedEventEnd, timing.LoadEventEnd)
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			timingGauge("browser_ttfb_ms", "Time to first byte", timing.ResponseStart, labels),
+			timingGauge("browser_dom_interactive_ms", "Time until the DOM was interactive", timing.DomInteractive, labels),
+			timingGauge("browser_dom_content_loaded_ms", "Time until the DOMContentLoaded event finished", timing.DomContentLoadedEventEnd, labels),
+			timingGauge("browser_load_ms", "Time until the load event finished", timing.LoadEventEnd, labels))
+
+		if t.config.FailOnConsoleErrors && errCount > 0 {
+			testResult.Details[common.ErrorKey] = fmt.Sprintf("%d console errors", errCount)
+		} else {
+			testResult.Marks = 1
+		}
+	}
+
+	err = common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func timingGauge(name string, help string, value float64, labels map[string]string) common.PrometheusGauge {
+	return common.PrometheusGauge{Name: name, Help: help, Value: value, Labels: labels}
+}
+
+func (t *BrowserTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &BrowserTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "connMatrix"
+
+// MatrixKey Key in the test details map containing this agent's row of the matrix
+const MatrixKey = "_matrix"
+
+/*
+ * Test to probe echo endpoints in a set of namespaces, producing one row of a namespace <-> namespace
+ * connectivity matrix. Running the test on agents in each source namespace gives the full matrix.
+ */
+type ConnMatrixTest struct {
+	config          ConnMatrixTestConfig
+	sourceNamespace string
+	nodeName        string
+}
+
+type ConnMatrixTestConfig = pluginconfig.ConnMatrixTestConfig
+
+type Endpoint = pluginconfig.ConnMatrixEndpoint
+
+type MatrixCell struct {
+	Reachable bool  `json:"reachable"`
+	LatencyNs int64 `json:"latencyNs"`
+}
+
+type probeJob struct {
+	Endpoint Endpoint
+	Timeout  time.Duration
+}
+
+func (t *ConnMatrixTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = ConnMatrixTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 3
+	}
+	t.sourceNamespace = t.config.SourceNamespace
+	if t.sourceNamespace == "" {
+		t.sourceNamespace = synTestConfig.Runtime[common.SpecialKeyAgentNs]
+	}
+	t.nodeName = synTestConfig.Runtime[common.SpecialKeyNodeName]
+	for i, e := range t.config.Endpoints {
+		if e.Namespace == "" || e.Address == "" {
+			return errors.Errorf("endpoint %d: namespace and addr must be set", i)
+		}
+	}
+	return nil
+}
+
+func (t *ConnMatrixTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	if len(t.config.Endpoints) <= 0 {
+		return common.FailedTestResult(), errors.New("no endpoints to test")
+	}
+	testResult := proto.TestResult{Marks: 0, MaxMarks: uint64(len(t.config.Endpoints)), Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	wp := utils.NewWorkerPool(t.config.Workers, len(t.config.Endpoints), probe, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+	for _, e := range t.config.Endpoints {
+		wp.AddJob(probeJob{Endpoint: e, Timeout: time.Duration(t.config.Timeout) * time.Second})
+	}
+
+	// Row of the matrix for the source namespace, keyed by destination namespace
+	row := map[string]MatrixCell{}
+	for i := 0; i < len(t.config.Endpoints); i++ {
+		res := <-wp.ResultChan
+		dst := res.Job.(probeJob).Endpoint.Namespace
+		latency := res.ReturnValues.(time.Duration)
+		cell := MatrixCell{Reachable: res.Error == nil, LatencyNs: latency.Nanoseconds()}
+		reachable := 0
+		if cell.Reachable {
+			testResult.Marks++
+			reachable = 1
+		}
+		row[dst] = cell
+
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		labels := map[string]string{"srcNamespace": t.sourceNamespace, "dstNamespace": dst, "node": t.nodeName}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{
+				Name:   "conn_matrix_reachable",
+				Help:   "Whether the destination namespace is reachable from the source namespace",
+				Value:  float64(reachable),
+				Labels: labels,
+			},
+			common.PrometheusGauge{
+				Name:   "conn_matrix_latency_ns",
+				Help:   "Latency from the source namespace to the destination namespace",
+				Value:  float64(latency.Nanoseconds()),
+				Labels: labels,
+			})
+	}
+
+	matrixRow, err := json.Marshal(map[string]map[string]MatrixCell{t.sourceNamespace: row})
+	if err != nil {
+		return testResult, errors.Wrap(err, "error marshalling matrix")
+	}
+	testResult.Details[MatrixKey] = string(matrixRow)
+
+	err = common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func probe(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	job := j.(probeJob)
+	e := job.Endpoint
+	start := time.Now()
+	if e.Path == "" {
+		log.Printf("dialing %s (%s)...\n", e.Address, e.Namespace)
+		conn, err := net.DialTimeout("tcp", e.Address, job.Timeout)
+		if err != nil {
+			log.Println("could not connect", err)
+			return time.Duration(0), errors.Wrap(err, "could not connect to "+e.Address)
+		}
+		conn.Close()
+	} else {
+		url := "http://" + e.Address + e.Path
+		log.Printf("requesting %s (%s)...\n", url, e.Namespace)
+		client := http.Client{Timeout: job.Timeout}
+		resp, err := client.Get(url)
+		if err != nil {
+			log.Println("request failed", err)
+			return time.Duration(0), errors.Wrap(err, "request to "+url+" failed")
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			log.Println("unexpected status", resp.Status)
+			return time.Duration(0), errors.Errorf("unexpected status from %s: %s", url, resp.Status)
+		}
+	}
+	latency := time.Since(start)
+	log.Printf("reachable in %dms\n", latency.Milliseconds())
+	return latency, nil
+}
+
+func (t *ConnMatrixTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &ConnMatrixTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -18,6 +18,7 @@ package main
 
 import (
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/hashicorp/go-plugin"
 	"github.com/pkg/errors"
@@ -33,16 +34,9 @@ type CurlTest struct {
 	config CurlTestConfig
 }
 
-type CurlTestConfig struct {
-	Url           string         `yaml:"url"`
-	OutputOptions []OutputOption `yaml:"outputOptions"`
-}
+type CurlTestConfig = pluginconfig.CurlTestConfig
 
-type OutputOption struct {
-	Name             string `yaml:"name"`
-	PrometheusMetric bool   `yaml:"metric"`
-	PrometheusLabel  bool   `yaml:"label"`
-}
+type OutputOption = pluginconfig.CurlOutputOption
 
 func (t *CurlTest) Initialise(synTestConfig proto.SynTestConfig) error {
 	t.config = CurlTestConfig{}
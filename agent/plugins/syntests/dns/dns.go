@@ -21,16 +21,23 @@ import (
 	"errors"
 	"fmt"
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/cisco-open/synthetic-heart/common/utils"
 	"github.com/hashicorp/go-plugin"
 	"log"
 	"net"
 	"strings"
+	"time"
 )
 
 const PluginName = "dns"
 
+const (
+	ModeNodeLocalCache = "nodeLocalCache"
+	DefaultNodeLocalIP = "169.254.20.10"
+)
+
 /*
  * Test to check if domains are resolvable
  */
@@ -38,11 +45,7 @@ type DNSTest struct {
 	config DNSTestConfig
 }
 
-type DNSTestConfig struct {
-	Domains []string `yaml:"domains"`
-	Workers int      `yaml:"workers"`
-	Repeats int      `yaml:"repeats"`
-}
+type DNSTestConfig = pluginconfig.DNSTestConfig
 
 func (t *DNSTest) Initialise(synTestConfig proto.SynTestConfig) error {
 	t.config = DNSTestConfig{}
@@ -57,6 +60,20 @@ func (t *DNSTest) Initialise(synTestConfig proto.SynTestConfig) error {
 	if t.config.Repeats <= 0 {
 		t.config.Repeats = 1
 	}
+
+	if t.config.Mode == ModeNodeLocalCache {
+		if t.config.ClusterDNSIP == "" {
+			return errors.New("clusterDNSIP must be set in " + ModeNodeLocalCache + " mode")
+		}
+		if t.config.NodeLocalIP == "" {
+			t.config.NodeLocalIP = DefaultNodeLocalIP
+		}
+		if t.config.Timeout <= 0 {
+			t.config.Timeout = 2 * time.Second
+		}
+	} else if t.config.Mode != "" {
+		return errors.New("unknown mode: " + t.config.Mode)
+	}
 	return err
 }
 
@@ -65,6 +82,9 @@ func (t *DNSTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
 	if len(t.config.Domains) <= 0 {
 		return common.FailedTestResult(), errors.New("no domains to test")
 	}
+	if t.config.Mode == ModeNodeLocalCache {
+		return t.nodeLocalCacheTest()
+	}
 
 	// Create an empty test result struct
 	testResult := proto.TestResult{
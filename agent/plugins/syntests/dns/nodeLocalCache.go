@@ -0,0 +1,118 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+// Queries each domain using both the node-local dns cache and the cluster dns service, and compares the answers.
+// A domain gets a mark if both servers answer, and the answers match.
+func (t *DNSTest) nodeLocalCacheTest() (proto.TestResult, error) {
+	testResult := proto.TestResult{
+		Marks:    0,
+		MaxMarks: uint64(len(t.config.Domains)),
+		Details:  map[string]string{},
+	}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+	nodeLocal := newResolver(t.config.NodeLocalIP)
+	cluster := newResolver(t.config.ClusterDNSIP)
+
+	for _, domain := range t.config.Domains {
+		nodeLocalIPs, nodeLocalDuration, nodeLocalErr := t.query(nodeLocal, domain)
+		clusterIPs, clusterDuration, clusterErr := t.query(cluster, domain)
+
+		match := 0
+		switch {
+		case nodeLocalErr != nil:
+			log.Printf("%s: node-local cache (%s) failed: %s\n", domain, t.config.NodeLocalIP, nodeLocalErr)
+		case clusterErr != nil:
+			log.Printf("%s: cluster dns (%s) failed: %s\n", domain, t.config.ClusterDNSIP, clusterErr)
+		case strings.Join(nodeLocalIPs, ",") != strings.Join(clusterIPs, ","):
+			log.Printf("%s: answers differ - node-local cache: %v, cluster dns: %v\n", domain, nodeLocalIPs, clusterIPs)
+		default:
+			log.Printf("%s: answers match %v (node-local cache: %dms, cluster dns: %dms)\n", domain, nodeLocalIPs,
+				nodeLocalDuration.Milliseconds(), clusterDuration.Milliseconds())
+			testResult.Marks++
+			match = 1
+		}
+
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{
+				Name:   "dns_node_local_cache_match",
+				Help:   "Whether the node-local dns cache and cluster dns gave the same answer",
+				Value:  float64(match),
+				Labels: map[string]string{"domain": domain},
+			},
+			queryDurationGauge(domain, "nodeLocalCache", nodeLocalDuration),
+			queryDurationGauge(domain, "clusterDNS", clusterDuration))
+	}
+	log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+// Returns the sorted ips for the domain
+func (t *DNSTest) query(r *net.Resolver, domain string) ([]string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+	start := time.Now()
+	addrs, err := r.LookupIPAddr(ctx, domain)
+	d := time.Since(start)
+	if err != nil {
+		return nil, d, err
+	}
+	ips := []string{}
+	for _, a := range addrs {
+		ips = append(ips, a.IP.String())
+	}
+	sort.Strings(ips)
+	return ips, d, nil
+}
+
+// Creates a resolver that sends all queries to the given server
+func newResolver(serverIP string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network, net.JoinHostPort(serverIP, "53"))
+		},
+	}
+}
+
+func queryDurationGauge(domain string, server string, d time.Duration) common.PrometheusGauge {
+	return common.PrometheusGauge{
+		Name:   "dns_query_duration_ns",
+		Help:   "Duration of the dns query",
+		Value:  float64(d.Nanoseconds()),
+		Labels: map[string]string{"domain": domain, "server": server},
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "egress"
+
+// EgressIPKey Key in the test details map containing the egress ip seen by the echo service
+const EgressIPKey = "egressIP"
+
+/*
+ * Test to check outbound internet access from the node - dns resolution of external names, reachability of
+ * well-known endpoints, and the egress (NAT) ip
+ */
+type EgressTest struct {
+	config   EgressTestConfig
+	nodeName string
+}
+
+type EgressTestConfig = pluginconfig.EgressTestConfig
+
+func (t *EgressTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = EgressTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 5 * time.Second
+	}
+	if len(t.config.ExpectedEgressIPs) > 0 && t.config.EchoUrl == "" {
+		return errors.New("echoUrl must be set to check expectedEgressIPs")
+	}
+	t.nodeName = synTestConfig.Runtime[common.SpecialKeyNodeName]
+	return nil
+}
+
+func (t *EgressTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: 0, Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+	client := http.Client{Timeout: t.config.Timeout}
+
+	// DNS resolution of external names
+	for _, domain := range t.config.Domains {
+		testResult.MaxMarks++
+		ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+		start := time.Now()
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, domain)
+		cancel()
+		d := time.Since(start)
+		ok := 0
+		if err != nil {
+			log.Printf("unable to resolve %s: %s\n", domain, err)
+		} else {
+			log.Printf("resolved %s to %v in %dms\n", domain, ips, d.Milliseconds())
+			testResult.Marks++
+			ok = 1
+		}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			t.gauge("egress_dns_success", "Whether the external name resolved", float64(ok), "domain", domain),
+			t.gauge("egress_dns_duration_ns", "Duration of the dns lookup", float64(d.Nanoseconds()), "domain", domain))
+	}
+
+	// Reachability of well known endpoints
+	for _, url := range t.config.Urls {
+		testResult.MaxMarks++
+		start := time.Now()
+		resp, err := client.Get(url)
+		d := time.Since(start)
+		ok := 0
+		if err != nil {
+			log.Printf("unable to reach %s: %s\n", url, err)
+		} else {
+			resp.Body.Close()
+			log.Printf("got %s from %s in %dms\n", resp.Status, url, d.Milliseconds())
+			testResult.Marks++
+			ok = 1
+		}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			t.gauge("egress_http_success", "Whether the endpoint responded", float64(ok), "url", url),
+			t.gauge("egress_http_duration_ns", "Duration of the request", float64(d.Nanoseconds()), "url", url))
+	}
+
+	// Egress ip, as seen by the echo service
+	if t.config.EchoUrl != "" {
+		testResult.MaxMarks++
+		ip, err := t.getEgressIP(&client)
+		if err != nil {
+			log.Println("unable to get egress ip", err)
+		} else {
+			log.Println("egress ip: " + ip)
+			testResult.Details[EgressIPKey] = ip
+			if len(t.config.ExpectedEgressIPs) == 0 || contains(t.config.ExpectedEgressIPs, ip) {
+				testResult.Marks++
+			} else {
+				log.Printf("egress ip %s is not one of the expected ips %v\n", ip, t.config.ExpectedEgressIPs)
+			}
+		}
+	}
+
+	if testResult.MaxMarks == 0 {
+		return common.FailedTestResult(), errors.New("nothing to test")
+	}
+	log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func (t *EgressTest) getEgressIP(client *http.Client) (string, error) {
+	resp, err := client.Get(t.config.EchoUrl)
+	if err != nil {
+		return "", errors.Wrap(err, "error calling echo service")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", errors.Wrap(err, "error reading echo service response")
+	}
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", errors.Errorf("echo service returned an invalid ip: %q", ip)
+	}
+	return ip, nil
+}
+
+func (t *EgressTest) gauge(name string, help string, value float64, labelKey string, labelVal string) common.PrometheusGauge {
+	return common.PrometheusGauge{
+		Name:   name,
+		Help:   help,
+		Value:  value,
+		Labels: map[string]string{labelKey: labelVal, "node": t.nodeName},
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *EgressTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &EgressTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/cisco-open/synthetic-heart/common/utils"
 	"github.com/hashicorp/go-plugin"
@@ -47,13 +48,8 @@ const ParallelWorkers = 5
 const DefaultWaitBetweenRepeats = "5s"
 
 type HttpPingTestConfig struct {
-	Address           string `yaml:"address"`
-	ExpectedCodeRegex string `yaml:"expectedCodeRegex"`
-	MaxRetries        int    `yaml:"retries"`
-	MaxTimeoutRetry   int    `yaml:"timeoutRetries"`
-	RepeatWithoutFail int    `yaml:"repeatsWithoutFail"`
-	WaitBetweenRepeat string `yaml:"waitBetweenRepeats"`
-	timeout           time.Duration
+	pluginconfig.HttpPingTestConfig `yaml:",inline"`
+	timeout                         time.Duration
 }
 
 func (t *HttpPingTest) Initialise(synTestConfig proto.SynTestConfig) error {
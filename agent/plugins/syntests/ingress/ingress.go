@@ -0,0 +1,259 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const PluginName = "ingress"
+
+var httpRouteGVR = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+
+/*
+ * Test to discover Ingresses (and Gateway API HTTPRoutes) and probe their hostnames
+ */
+type IngressTest struct {
+	config    IngressTestConfig
+	k8sClient *kubernetes.Clientset
+	dynClient dynamic.Interface
+	codeRegex *regexp.Regexp
+}
+
+type IngressTestConfig = pluginconfig.IngressTestConfig
+
+type IngressTarget struct {
+	Source string // namespace/name of the Ingress or HTTPRoute
+	Host   string
+	TLS    bool
+}
+
+func (t *IngressTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = IngressTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 10 * time.Second
+	}
+	if t.config.Path == "" {
+		t.config.Path = "/"
+	}
+	if t.config.ExpectedCodeRegex == "" {
+		t.config.ExpectedCodeRegex = "^[23]"
+	}
+	t.codeRegex, err = regexp.Compile(t.config.ExpectedCodeRegex)
+	if err != nil {
+		return errors.Wrap(err, "error compiling expectedCodeRegex")
+	}
+
+	k8sConfig, err := utils.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "error getting k8s config")
+	}
+	t.k8sClient, err = kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating k8s client")
+	}
+	t.dynClient, err = dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating dynamic k8s client")
+	}
+	return nil
+}
+
+func (t *IngressTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	targets, err := t.discoverTargets()
+	if err != nil {
+		return common.FailedTestResult(), err
+	}
+	if len(targets) <= 0 {
+		return common.FailedTestResult(), errors.New("no ingress hosts found")
+	}
+	log.Printf("discovered %d hosts\n", len(targets))
+
+	testResult := proto.TestResult{Marks: 0, MaxMarks: uint64(len(targets)), Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	wp := utils.NewWorkerPool(t.config.Workers, len(targets), t.probe, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+	for _, target := range targets {
+		wp.AddJob(target)
+	}
+
+	for i := 0; i < len(targets); i++ {
+		res := <-wp.ResultChan
+		ok := 0
+		if res.Error == nil {
+			testResult.Marks++
+			ok = 1
+		}
+
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		target := res.Job.(IngressTarget)
+		labels := map[string]string{"host": target.Host, "source": target.Source}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{
+				Name:   "ingress_probe_success",
+				Help:   "Whether the ingress host returned the expected response",
+				Value:  float64(ok),
+				Labels: labels,
+			},
+			common.PrometheusGauge{
+				Name:   "ingress_probe_duration_ns",
+				Help:   "Duration of the request to the ingress host",
+				Value:  float64(res.ReturnValues.(time.Duration).Nanoseconds()),
+				Labels: labels,
+			})
+	}
+
+	err = common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+// Lists the matching Ingresses (and HTTPRoutes) and returns a target for each host
+func (t *IngressTest) discoverTargets() ([]IngressTarget, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+	listOpts := metav1.ListOptions{LabelSelector: t.config.LabelSelector}
+	targets := []IngressTarget{}
+
+	ingresses, err := t.k8sClient.NetworkingV1().Ingresses(t.config.Namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing ingresses")
+	}
+	for _, ing := range ingresses.Items {
+		tlsHosts := map[string]bool{}
+		for _, tlsSpec := range ing.Spec.TLS {
+			for _, h := range tlsSpec.Hosts {
+				tlsHosts[h] = true
+			}
+		}
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host == "" || strings.HasPrefix(rule.Host, "*") {
+				continue
+			}
+			targets = append(targets, IngressTarget{Source: ing.Namespace + "/" + ing.Name, Host: rule.Host, TLS: tlsHosts[rule.Host]})
+		}
+	}
+
+	if t.config.IncludeHTTPRoutes {
+		routes, err := t.dynClient.Resource(httpRouteGVR).Namespace(t.config.Namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing httproutes")
+		}
+		for _, route := range routes.Items {
+			hosts, _, err := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+			if err != nil {
+				return nil, errors.Wrap(err, "error reading hostnames of httproute "+route.GetName())
+			}
+			for _, h := range hosts {
+				if strings.HasPrefix(h, "*") {
+					continue
+				}
+				// Gateways usually terminate TLS, so HTTPRoutes are probed with https
+				targets = append(targets, IngressTarget{Source: route.GetNamespace() + "/" + route.GetName(), Host: h, TLS: true})
+			}
+		}
+	}
+	return targets, nil
+}
+
+func (t *IngressTest) probe(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	target := j.(IngressTarget)
+	scheme := "http"
+	if target.TLS {
+		scheme = "https"
+	}
+	url := scheme + "://" + target.Host + t.config.Path
+
+	dialer := &net.Dialer{Timeout: t.config.Timeout}
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{ServerName: target.Host, InsecureSkipVerify: t.config.InsecureSkipVerify},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if t.config.Address != "" {
+				// Keep the Host header and SNI, but connect to the configured address
+				addr = t.config.Address
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		DisableKeepAlives: true,
+	}
+	client := http.Client{Transport: transport, Timeout: t.config.Timeout}
+
+	log.Printf("requesting %s (from %s)\n", url, target.Source)
+	start := time.Now()
+	resp, err := client.Get(url)
+	duration := time.Since(start)
+	if err != nil {
+		log.Println("request failed", err)
+		return duration, errors.Wrap(err, "request to "+url+" failed")
+	}
+	defer resp.Body.Close()
+	log.Printf("got %s in %dms\n", resp.Status, duration.Milliseconds())
+
+	code := strconv.Itoa(resp.StatusCode)
+	if !t.codeRegex.MatchString(code) {
+		return duration, errors.Errorf("unexpected status code %s", code)
+	}
+	return duration, nil
+}
+
+func (t *IngressTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &IngressTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
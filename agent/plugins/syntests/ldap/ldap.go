@@ -0,0 +1,235 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "ldap"
+
+/*
+ * Test to check if directory servers are reachable and accept a bind (and optionally a search)
+ */
+type LDAPTest struct {
+	config LDAPTestConfig
+}
+
+type LDAPTestConfig = pluginconfig.LDAPTestConfig
+
+type LDAPServer = pluginconfig.LDAPServer
+
+type LDAPSearch = pluginconfig.LDAPSearch
+
+type LDAPResult struct {
+	BindDuration   time.Duration
+	SearchDuration time.Duration
+	SearchResults  int
+}
+
+func (t *LDAPTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = LDAPTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	// Set default workers to 3
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	for i, s := range t.config.Servers {
+		if s.Url == "" {
+			return errors.Errorf("server %d: url must be set", i)
+		}
+		if s.Timeout <= 0 {
+			t.config.Servers[i].Timeout = 5
+		}
+		if s.Search != nil && s.Search.Filter == "" {
+			t.config.Servers[i].Search.Filter = "(objectClass=*)"
+		}
+	}
+	return nil
+}
+
+func (t *LDAPTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	log.Println("performing ldap tests...")
+	if len(t.config.Servers) <= 0 {
+		return common.FailedTestResult(), errors.New("no servers to test")
+	}
+
+	testResult := proto.TestResult{
+		Marks:    0,
+		MaxMarks: uint64(len(t.config.Servers)),
+		Details:  map[string]string{},
+	}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	// Create a worker pool to test the servers in parallel
+	wp := utils.NewWorkerPool(t.config.Workers, len(t.config.Servers), ldapTest, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+
+	for _, s := range t.config.Servers {
+		wp.AddJob(s)
+	}
+
+	// Collect the results and logs from the tests one-by-one
+	for i := 0; i < len(t.config.Servers); i++ {
+		res := <-wp.ResultChan
+		ok := 0
+		if res.Error == nil {
+			testResult.Marks++
+			ok = 1
+		}
+
+		// Print the logs
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		server := res.Job.(LDAPServer)
+		labels := map[string]string{"url": server.Url, "bindDN": server.BindDN}
+		result := res.ReturnValues.(LDAPResult)
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{
+				Name:   "ldap_success",
+				Help:   "Whether the bind (and search, if configured) was successful",
+				Value:  float64(ok),
+				Labels: labels,
+			},
+			common.PrometheusGauge{
+				Name:   "ldap_bind_duration_ns",
+				Help:   "Duration of the connect and bind",
+				Value:  float64(result.BindDuration.Nanoseconds()),
+				Labels: labels,
+			})
+		if server.Search != nil {
+			promMetrics.Gauges = append(promMetrics.Gauges,
+				common.PrometheusGauge{
+					Name:   "ldap_search_duration_ns",
+					Help:   "Duration of the search",
+					Value:  float64(result.SearchDuration.Nanoseconds()),
+					Labels: labels,
+				},
+				common.PrometheusGauge{
+					Name:   "ldap_search_results",
+					Help:   "Number of entries returned by the search",
+					Value:  float64(result.SearchResults),
+					Labels: labels,
+				})
+		}
+	}
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func ldapTest(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	server := j.(LDAPServer)
+	result := LDAPResult{}
+	timeout := time.Duration(server.Timeout) * time.Second
+	tlsConfig := &tls.Config{InsecureSkipVerify: server.InsecureSkipVerify}
+
+	log.Println("connecting to " + server.Url + "...")
+	start := time.Now()
+	conn, err := ldap.DialURL(server.Url, ldap.DialWithTLSConfig(tlsConfig),
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		log.Println("could not connect", err)
+		return result, errors.Wrap(err, "could not connect to "+server.Url)
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+
+	if server.StartTLS {
+		log.Println("upgrading connection with StartTLS")
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			log.Println("StartTLS failed", err)
+			return result, errors.Wrap(err, "StartTLS failed")
+		}
+	}
+
+	password := server.BindPassword
+	if server.BindPasswordEnv != "" {
+		password = os.Getenv(server.BindPasswordEnv)
+	}
+	if server.BindDN == "" {
+		log.Println("performing anonymous bind")
+		err = conn.UnauthenticatedBind("")
+	} else {
+		log.Println("binding as " + server.BindDN)
+		err = conn.Bind(server.BindDN, password)
+	}
+	result.BindDuration = time.Since(start)
+	if err != nil {
+		log.Println("bind failed", err)
+		return result, errors.Wrap(err, "bind failed")
+	}
+	log.Printf("bind took %dms\n", result.BindDuration.Milliseconds())
+
+	if server.Search == nil {
+		return result, nil
+	}
+
+	log.Printf("searching %s with filter %s\n", server.Search.BaseDN, server.Search.Filter)
+	req := ldap.NewSearchRequest(server.Search.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, server.Timeout, false, server.Search.Filter, server.Search.Attributes, nil)
+	start = time.Now()
+	sr, err := conn.Search(req)
+	result.SearchDuration = time.Since(start)
+	if err != nil {
+		log.Println("search failed", err)
+		return result, errors.Wrap(err, "search failed")
+	}
+	result.SearchResults = len(sr.Entries)
+	log.Printf("search returned %d entries in %dms\n", result.SearchResults, result.SearchDuration.Milliseconds())
+	if result.SearchResults < server.Search.MinResults {
+		return result, errors.Errorf("expected at least %d entries, got %d", server.Search.MinResults, result.SearchResults)
+	}
+	return result, nil
+}
+
+func (t *LDAPTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &LDAPTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
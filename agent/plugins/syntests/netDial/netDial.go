@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/cisco-open/synthetic-heart/common/utils"
 	"github.com/hashicorp/go-plugin"
@@ -37,16 +38,9 @@ type NetDialTest struct {
 	timeout time.Duration
 }
 
-type NetDialTestConfig struct {
-	Addresses []Address
-	Workers   int
-}
+type NetDialTestConfig = pluginconfig.NetDialTestConfig
 
-type Address struct {
-	Network string `yaml:"net"`
-	Address string `yaml:"addr"`
-	Timeout int    `yaml:"timeout"`
-}
+type Address = pluginconfig.NetDialAddress
 
 func (t *NetDialTest) Initialise(synTestConfig proto.SynTestConfig) error {
 	netDialConfig := NetDialTestConfig{}
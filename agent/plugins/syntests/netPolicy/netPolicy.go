@@ -0,0 +1,204 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "netPolicy"
+
+const (
+	ExpectReachable = "reachable"
+	ExpectBlocked   = "blocked"
+)
+
+/*
+ * Test to check that network policies allow the connections they should, and block the ones they shouldn't
+ */
+type NetPolicyTest struct {
+	config NetPolicyTestConfig
+}
+
+type NetPolicyTestConfig = pluginconfig.NetPolicyTestConfig
+
+// dialError is the error of a target which couldn't be tested, the dial failed for another reason than a network policy
+// (e.g. a dns error, an invalid address or a connection refused by the target)
+type dialError struct {
+	err error
+}
+
+func (e dialError) Error() string {
+	return e.err.Error()
+}
+
+type Target = pluginconfig.NetPolicyTarget
+
+func (t *NetPolicyTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = NetPolicyTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	// Set default workers to 3
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	return nil
+}
+
+func (t *NetPolicyTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	targets := []Target{}
+	for _, target := range t.config.Reachable {
+		target.Expect = ExpectReachable
+		targets = append(targets, target)
+	}
+	for _, target := range t.config.Blocked {
+		target.Expect = ExpectBlocked
+		targets = append(targets, target)
+	}
+	if len(targets) <= 0 {
+		return common.FailedTestResult(), errors.New("no targets to test")
+	}
+
+	testResult := proto.TestResult{Marks: 0, MaxMarks: uint64(len(targets)), Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	// Create a worker pool to do the connection tests in parallel
+	wp := utils.NewWorkerPool(t.config.Workers, len(targets), netPolicyTest, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+	for _, target := range targets {
+		wp.AddJob(target)
+	}
+
+	violations, dialErrors := []string{}, []string{}
+	for i := 0; i < len(targets); i++ {
+		res := <-wp.ResultChan
+		target := res.Job.(Target)
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		ok := 0
+		if res.Error == nil {
+			testResult.Marks++
+			ok = 1
+		} else if errors.As(res.Error, &dialError{}) {
+			// not known whether it's as expected, so no metric
+			dialErrors = append(dialErrors, res.Error.Error())
+			continue
+		} else {
+			violations = append(violations, res.Error.Error())
+		}
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{
+				Name:  "net_policy_as_expected",
+				Help:  "Whether the target was reachable/blocked as expected",
+				Value: float64(ok),
+				Labels: map[string]string{
+					"net":    target.Network,
+					"addr":   target.Address,
+					"expect": target.Expect,
+				},
+			})
+	}
+	if len(violations) > 0 {
+		testResult.Details[common.ErrorKey] = strings.Join(violations, "\n")
+	}
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	if len(dialErrors) > 0 {
+		return testResult, errors.New("unable to test targets: " + strings.Join(dialErrors, "; "))
+	}
+	return testResult, nil
+}
+
+func netPolicyTest(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	target := j.(Target)
+	// Set defaults
+	if target.Network == "" {
+		target.Network = "tcp"
+	}
+	if target.Timeout == 0 {
+		target.Timeout = 3
+	}
+	log.Printf("dialing on %s (expecting %s)...\n", target.Address, target.Expect)
+	conn, err := net.DialTimeout(target.Network, target.Address, time.Duration(target.Timeout)*time.Second)
+	if err == nil {
+		conn.Close()
+	} else if !blocked(err) {
+		log.Println("unable to dial:", err)
+		return nil, dialError{errors.Wrapf(err, "unable to dial %s", target.Address)}
+	}
+	reachable := err == nil
+
+	switch {
+	case reachable && target.Expect == ExpectReachable:
+		log.Println("reachable, as expected")
+	case !reachable && target.Expect == ExpectBlocked:
+		log.Println("blocked, as expected:", err)
+	case reachable:
+		log.Println("reachable, but should be blocked - network policy is too permissive")
+		return nil, errors.Errorf("%s is reachable, but should be blocked", target.Address)
+	default:
+		log.Println("blocked, but should be reachable:", err)
+		return nil, errors.Errorf("%s is not reachable: %s", target.Address, err.Error())
+	}
+	return nil, nil
+}
+
+// blocked Returns whether a dial error is caused by a network policy: the connection times out (the packets are
+// dropped), or the host or network is unreachable (rejected with an icmp error)
+func blocked(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
+
+func (t *NetPolicyTest) Finish() error {
+	return nil
+}
+
+func main() {
+	pluginImpl := &NetPolicyTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const PluginName = "nodeLocal"
+
+// NodeIPPlaceholder Replaced with the InternalIP of the agent's node in endpoint urls
+const NodeIPPlaceholder = "$nodeIP"
+
+const ServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+/*
+ * Test to check the node-local dependencies of the node the agent is running on - health endpoints of node
+ * components (kubelet, cni etc.) and the node conditions (including the ones set by node-problem-detector)
+ */
+type NodeLocalTest struct {
+	config      NodeLocalTestConfig
+	codeRegexes []*regexp.Regexp // compiled expectedCodeRegex of each endpoint
+	k8sClient   *kubernetes.Clientset
+	nodeName    string
+}
+
+type NodeLocalTestConfig = pluginconfig.NodeLocalTestConfig
+
+type Endpoint = pluginconfig.NodeLocalEndpoint
+
+func (t *NodeLocalTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = NodeLocalTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 5 * time.Second
+	}
+	t.codeRegexes = nil
+	for i, e := range t.config.Endpoints {
+		if e.Component == "" || e.Url == "" {
+			return errors.Errorf("endpoint %d: component and url must be set", i)
+		}
+		if e.ExpectedCodeRegex == "" {
+			e.ExpectedCodeRegex = "^2"
+		}
+		codeRegex, err := regexp.Compile(e.ExpectedCodeRegex)
+		if err != nil {
+			return errors.Wrap(err, "error compiling expectedCodeRegex for "+e.Component)
+		}
+		t.codeRegexes = append(t.codeRegexes, codeRegex)
+	}
+
+	t.nodeName = synTestConfig.Runtime[common.SpecialKeyNodeName]
+	if t.nodeName == "" {
+		return errors.New("agent's node name is unknown")
+	}
+	k8sConfig, err := utils.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "error getting k8s config")
+	}
+	t.k8sClient, err = kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating k8s client")
+	}
+	return nil
+}
+
+func (t *NodeLocalTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: 0, Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+	node, err := t.k8sClient.CoreV1().Nodes().Get(ctx, t.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return common.FailedTestResult(), errors.Wrap(err, "error getting node "+t.nodeName)
+	}
+	nodeIP := ""
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			nodeIP = addr.Address
+			break
+		}
+	}
+
+	// Health endpoints of node components
+	for i, e := range t.config.Endpoints {
+		testResult.MaxMarks++
+		ok := 0
+		url := strings.ReplaceAll(e.Url, NodeIPPlaceholder, nodeIP)
+		d, err := t.checkEndpoint(e, t.codeRegexes[i], url)
+		if err != nil {
+			log.Printf("%s (%s) is unhealthy: %s\n", e.Component, url, err)
+		} else {
+			log.Printf("%s (%s) is healthy, took %dms\n", e.Component, url, d.Milliseconds())
+			testResult.Marks++
+			ok = 1
+		}
+		labels := map[string]string{"component": e.Component, "node": t.nodeName}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{Name: "node_component_healthy", Help: "Whether the node component's health endpoint is healthy",
+				Value: float64(ok), Labels: labels},
+			common.PrometheusGauge{Name: "node_component_duration_ns", Help: "Duration of the request to the health endpoint",
+				Value: float64(d.Nanoseconds()), Labels: labels})
+	}
+
+	// Node conditions - Ready must be True, everything else (e.g. MemoryPressure, KernelDeadlock) must be False
+	if t.config.CheckConditions {
+		for _, c := range node.Status.Conditions {
+			if contains(t.config.IgnoreConditions, string(c.Type)) {
+				continue
+			}
+			testResult.MaxMarks++
+			expected := corev1.ConditionFalse
+			if c.Type == corev1.NodeReady {
+				expected = corev1.ConditionTrue
+			}
+			ok := 0
+			if c.Status == expected {
+				testResult.Marks++
+				ok = 1
+			} else {
+				log.Printf("node condition %s is %s (reason: %s, message: %s)\n", c.Type, c.Status, c.Reason, c.Message)
+			}
+			promMetrics.Gauges = append(promMetrics.Gauges,
+				common.PrometheusGauge{Name: "node_condition_ok", Help: "Whether the node condition has the healthy status",
+					Value: float64(ok), Labels: map[string]string{"condition": string(c.Type), "node": t.nodeName}})
+		}
+	}
+
+	if testResult.MaxMarks == 0 {
+		return common.FailedTestResult(), errors.New("nothing to test")
+	}
+	log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+	err = common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func (t *NodeLocalTest) checkEndpoint(e Endpoint, codeRegex *regexp.Regexp, url string) (time.Duration, error) {
+	client := http.Client{
+		Timeout:   t.config.Timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: e.InsecureSkipVerify}},
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if e.UseServiceAccount {
+		token, err := os.ReadFile(ServiceAccountTokenPath)
+		if err != nil {
+			return 0, errors.Wrap(err, "error reading service account token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	d := time.Since(start)
+	if err != nil {
+		return d, err
+	}
+	resp.Body.Close()
+	if !codeRegex.MatchString(strconv.Itoa(resp.StatusCode)) {
+		return d, errors.Errorf("unexpected status: %s", resp.Status)
+	}
+	return d, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *NodeLocalTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &NodeLocalTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "nodePressure"
+
+/*
+ * Test to check the node's resource usage (memory, pids, disk, inodes, conntrack) against thresholds
+ */
+type NodePressureTest struct {
+	config   NodePressureTestConfig
+	nodeName string
+}
+
+type NodePressureTestConfig = pluginconfig.NodePressureTestConfig
+
+type Thresholds = pluginconfig.NodePressureThresholds
+
+func (t *NodePressureTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = NodePressureTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.ProcPath == "" {
+		t.config.ProcPath = "/proc"
+	}
+	if len(t.config.DiskPaths) == 0 {
+		t.config.DiskPaths = []string{"/"}
+	}
+	setDefault(&t.config.Thresholds.Memory, 90)
+	setDefault(&t.config.Thresholds.Pids, 80)
+	setDefault(&t.config.Thresholds.Disk, 85)
+	setDefault(&t.config.Thresholds.Inodes, 85)
+	setDefault(&t.config.Thresholds.Conntrack, 80)
+	t.nodeName = synTestConfig.Runtime[common.SpecialKeyNodeName]
+	return nil
+}
+
+func setDefault(v *float64, d float64) {
+	if *v <= 0 {
+		*v = d
+	}
+}
+
+func (t *NodePressureTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: 0, Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	// Checks the usage against the threshold, and records the metric
+	check := func(resource string, path string, usage float64, threshold float64, err error) {
+		testResult.MaxMarks++
+		if err != nil {
+			log.Printf("unable to read %s usage: %s\n", resource, err)
+			return
+		}
+		labels := map[string]string{"resource": resource, "node": t.nodeName}
+		if path != "" {
+			labels["path"] = path
+		}
+		promMetrics.Gauges = append(promMetrics.Gauges, common.PrometheusGauge{
+			Name:   "node_resource_usage_percent",
+			Help:   "Usage of the node resource in percent",
+			Value:  usage,
+			Labels: labels,
+		})
+		if usage > threshold {
+			log.Printf("%s %s usage is %.1f%%, above the threshold of %.1f%%\n", resource, path, usage, threshold)
+			return
+		}
+		log.Printf("%s %s usage is %.1f%%\n", resource, path, usage)
+		testResult.Marks++
+	}
+
+	usage, err := t.memoryUsage()
+	check("memory", "", usage, t.config.Thresholds.Memory, err)
+
+	usage, err = t.pidUsage()
+	check("pids", "", usage, t.config.Thresholds.Pids, err)
+
+	for _, p := range t.config.DiskPaths {
+		diskUsage, inodeUsage, err := fsUsage(p)
+		check("disk", p, diskUsage, t.config.Thresholds.Disk, err)
+		check("inodes", p, inodeUsage, t.config.Thresholds.Inodes, err)
+	}
+
+	count, max, err := utils.ReadConntrackUsage(t.config.ProcPath)
+	usage = 0
+	if err == nil && max > 0 {
+		usage = float64(count) * 100 / float64(max)
+	}
+	check("conntrack", "", usage, t.config.Thresholds.Conntrack, err)
+
+	log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+	err = common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+// Memory usage from /proc/meminfo (1 - MemAvailable/MemTotal)
+func (t *NodePressureTest) memoryUsage() (float64, error) {
+	f, err := os.Open(filepath.Join(t.config.ProcPath, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = v
+	}
+	if values["MemTotal"] <= 0 {
+		return 0, errors.New("MemTotal not found in meminfo")
+	}
+	return (1 - values["MemAvailable"]/values["MemTotal"]) * 100, nil
+}
+
+// Pid usage from the number of threads in /proc/loadavg and /proc/sys/kernel/pid_max
+func (t *NodePressureTest) pidUsage() (float64, error) {
+	b, err := os.ReadFile(filepath.Join(t.config.ProcPath, "loadavg"))
+	if err != nil {
+		return 0, err
+	}
+	// Format: "0.00 0.01 0.05 2/345 12345", 4th field is running/total
+	fields := strings.Fields(string(b))
+	if len(fields) < 4 || !strings.Contains(fields[3], "/") {
+		return 0, errors.Errorf("unexpected loadavg format: %q", string(b))
+	}
+	total, err := strconv.ParseFloat(strings.Split(fields[3], "/")[1], 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "error parsing loadavg")
+	}
+	pidMax, err := utils.ReadProcUint(filepath.Join(t.config.ProcPath, "sys/kernel/pid_max"))
+	if err != nil {
+		return 0, err
+	}
+	return total * 100 / float64(pidMax), nil
+}
+
+// Disk and inode usage of the filesystem at the path
+func fsUsage(path string) (float64, float64, error) {
+	st := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, errors.Wrap(err, "statfs failed for "+path)
+	}
+	diskUsage := 0.0
+	// Same as df - blocks reserved for root are not counted as available
+	if used := float64(st.Blocks - st.Bfree); used+float64(st.Bavail) > 0 {
+		diskUsage = used * 100 / (used + float64(st.Bavail))
+	}
+	inodeUsage := 0.0
+	if st.Files > 0 {
+		inodeUsage = float64(st.Files-st.Ffree) * 100 / float64(st.Files)
+	}
+	return diskUsage, inodeUsage, nil
+}
+
+func (t *NodePressureTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &NodePressureTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
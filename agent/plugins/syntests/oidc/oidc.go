@@ -0,0 +1,262 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const PluginName = "oidc"
+
+const (
+	GrantClientCredentials = "clientCredentials"
+	GrantDeviceCode        = "deviceCode"
+)
+
+/*
+ * Test to check if a token can be acquired from an OIDC provider, and that the token is valid
+ */
+type OIDCTest struct {
+	config OIDCTestConfig
+}
+
+type OIDCTestConfig = pluginconfig.OIDCTestConfig
+
+func (t *OIDCTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = OIDCTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.IssuerUrl == "" {
+		return errors.New("issuerUrl must be set")
+	}
+	if t.config.ClientId == "" {
+		return errors.New("clientId must be set")
+	}
+	if t.config.GrantType == "" {
+		t.config.GrantType = GrantClientCredentials
+	}
+	if t.config.GrantType != GrantClientCredentials && t.config.GrantType != GrantDeviceCode {
+		return errors.Errorf("unsupported grantType: %s", t.config.GrantType)
+	}
+	if len(t.config.ExpectedClaims) > 0 && t.config.Audience == "" {
+		return errors.New("expectedClaims needs an audience, the claims of the token are only verified for an audience")
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 10 * time.Second
+	}
+	if t.config.ClientSecretEnv != "" {
+		t.config.ClientSecret = os.Getenv(t.config.ClientSecretEnv)
+	}
+	return nil
+}
+
+func (t *OIDCTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: 3, Details: map[string]string{}}
+	if t.config.GrantType == GrantDeviceCode {
+		// The device flow can't be completed without a user, so only discovery and the device authorization are tested
+		testResult.MaxMarks = 2
+	}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+	defer func() {
+		err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+		if err != nil {
+			log.Println("unable to add prometheus metrics", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+
+	// Discovery
+	log.Println("fetching provider metadata from " + t.config.IssuerUrl)
+	start := time.Now()
+	provider, err := oidc.NewProvider(ctx, t.config.IssuerUrl)
+	promMetrics.Gauges = append(promMetrics.Gauges, t.gauge("oidc_discovery_duration_ns",
+		"Duration of the OIDC discovery request", float64(time.Since(start).Nanoseconds())))
+	if err != nil {
+		log.Println("discovery failed", err)
+		return testResult, nil
+	}
+	testResult.Marks++
+	log.Printf("discovery took %dms\n", time.Since(start).Milliseconds())
+
+	if t.config.GrantType == GrantDeviceCode {
+		t.deviceAuth(ctx, provider, &testResult, &promMetrics)
+		return testResult, nil
+	}
+
+	// Token acquisition
+	ccConfig := clientcredentials.Config{
+		ClientID:       t.config.ClientId,
+		ClientSecret:   t.config.ClientSecret,
+		TokenURL:       provider.Endpoint().TokenURL,
+		Scopes:         t.config.Scopes,
+		EndpointParams: url.Values{},
+	}
+	if t.config.Audience != "" {
+		ccConfig.EndpointParams.Set("audience", t.config.Audience)
+	}
+	log.Println("requesting token from " + ccConfig.TokenURL)
+	start = time.Now()
+	token, err := ccConfig.Token(ctx)
+	promMetrics.Gauges = append(promMetrics.Gauges, t.gauge("oidc_token_duration_ns",
+		"Duration of the token request", float64(time.Since(start).Nanoseconds())))
+	if err != nil {
+		log.Println("token request failed", err)
+		return testResult, nil
+	}
+	testResult.Marks++
+	log.Printf("token request took %dms\n", time.Since(start).Milliseconds())
+
+	// Token validation: access tokens aren't always JWTs (unlike id tokens), so the token is only verified as a JWT
+	// signed by the provider when it's requested for an audience, otherwise only its expiry is checked
+	expiry := token.Expiry
+	if t.config.Audience != "" {
+		expiry, err = t.verifyToken(ctx, provider, token.AccessToken)
+	} else if token.AccessToken == "" {
+		err = errors.New("no access token issued")
+	}
+	if err == nil {
+		err = t.checkExpiry(expiry, &promMetrics)
+	}
+	if err != nil {
+		log.Println("token validation failed", err)
+		return testResult, nil
+	}
+	testResult.Marks++
+	log.Println("token is valid")
+	return testResult, nil
+}
+
+func (t *OIDCTest) deviceAuth(ctx context.Context, provider *oidc.Provider, testResult *proto.TestResult,
+	promMetrics *common.PrometheusMetrics) {
+	metadata := struct {
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	}{}
+	if err := provider.Claims(&metadata); err != nil || metadata.DeviceAuthorizationEndpoint == "" {
+		log.Println("provider does not advertise a device authorization endpoint", err)
+		return
+	}
+	endpoint := provider.Endpoint()
+	endpoint.DeviceAuthURL = metadata.DeviceAuthorizationEndpoint
+	oauthConfig := oauth2.Config{ClientID: t.config.ClientId, ClientSecret: t.config.ClientSecret,
+		Endpoint: endpoint, Scopes: t.config.Scopes}
+
+	log.Println("requesting device code from " + endpoint.DeviceAuthURL)
+	start := time.Now()
+	resp, err := oauthConfig.DeviceAuth(ctx)
+	promMetrics.Gauges = append(promMetrics.Gauges, t.gauge("oidc_device_auth_duration_ns",
+		"Duration of the device authorization request", float64(time.Since(start).Nanoseconds())))
+	if err != nil {
+		log.Println("device authorization failed", err)
+		return
+	}
+	if resp.DeviceCode == "" || resp.VerificationURI == "" {
+		log.Println("device authorization response is missing device_code or verification_uri")
+		return
+	}
+	testResult.Marks++
+	log.Printf("device authorization took %dms, verification uri: %s\n", time.Since(start).Milliseconds(),
+		resp.VerificationURI)
+}
+
+// verifyToken Verifies the access token as a JWT for the audience, and returns its expiry
+func (t *OIDCTest) verifyToken(ctx context.Context, provider *oidc.Provider, rawToken string) (time.Time, error) {
+	// Checks the signature (against the provider's JWKS), issuer, expiry and audience
+	verifier := provider.Verifier(&oidc.Config{ClientID: t.config.Audience})
+	token, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "error verifying token")
+	}
+	log.Printf("token subject: %s\n", token.Subject)
+
+	if len(t.config.ExpectedClaims) > 0 {
+		claims := map[string]interface{}{}
+		if err := token.Claims(&claims); err != nil {
+			return time.Time{}, errors.Wrap(err, "error parsing claims")
+		}
+		for k, v := range t.config.ExpectedClaims {
+			actual, ok := claims[k]
+			if !ok {
+				return time.Time{}, errors.Errorf("claim %s not found in token", k)
+			}
+			if fmt.Sprint(actual) != v {
+				return time.Time{}, errors.Errorf("claim %s is %v, expected %s", k, actual, v)
+			}
+		}
+	}
+	return token.Expiry, nil
+}
+
+// checkExpiry Checks the token hasn't expired and is valid for at least minValidity (a zero expiry means the provider
+// didn't say when it expires)
+func (t *OIDCTest) checkExpiry(expiry time.Time, promMetrics *common.PrometheusMetrics) error {
+	if expiry.IsZero() {
+		log.Println("token has no expiry")
+		return nil
+	}
+	validFor := time.Until(expiry)
+	promMetrics.Gauges = append(promMetrics.Gauges, t.gauge("oidc_token_validity_seconds",
+		"Seconds until the token expires", validFor.Seconds()))
+	log.Printf("token expires in: %s\n", validFor.Round(time.Second))
+	if validFor <= 0 {
+		return errors.New("token has expired")
+	}
+	if validFor < t.config.MinValidity {
+		return errors.Errorf("token expires in %s, expected at least %s", validFor, t.config.MinValidity)
+	}
+	return nil
+}
+
+func (t *OIDCTest) gauge(name string, help string, value float64) common.PrometheusGauge {
+	return common.PrometheusGauge{
+		Name:   name,
+		Help:   help,
+		Value:  value,
+		Labels: map[string]string{"issuer": t.config.IssuerUrl, "clientId": t.config.ClientId},
+	}
+}
+
+func (t *OIDCTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &OIDCTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/go-ping/ping"
 	"github.com/hashicorp/go-plugin"
@@ -35,12 +36,7 @@ type PingTest struct {
 	interval time.Duration
 }
 
-type PingTestConfig struct {
-	Domain     string
-	Pings      int
-	Interval   string
-	Privileged bool
-}
+type PingTestConfig = pluginconfig.PingTestConfig
 
 func (t *PingTest) Initialise(synTestConfig proto.SynTestConfig) error {
 	t.config = PingTestConfig{}
@@ -0,0 +1,210 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "portExhaustion"
+
+const tcpStateListen = "0A"
+
+/*
+ * Test to check the ephemeral port range and conntrack table usage on the node, to warn before SNAT port
+ * exhaustion starts dropping new connections
+ */
+type PortExhaustionTest struct {
+	config   PortExhaustionTestConfig
+	nodeName string
+}
+
+type PortExhaustionTestConfig = pluginconfig.PortExhaustionTestConfig
+
+type PortUsage struct {
+	RangeSize         int // size of ip_local_port_range
+	InUse             int // distinct ephemeral ports in use
+	MaxPerDestination int // most ephemeral ports used towards a single destination (ip:port)
+	Destination       string
+}
+
+func (t *PortExhaustionTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = PortExhaustionTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.ProcPath == "" {
+		t.config.ProcPath = "/proc"
+	}
+	if t.config.EphemeralThreshold <= 0 {
+		t.config.EphemeralThreshold = 70
+	}
+	if t.config.ConntrackThreshold <= 0 {
+		t.config.ConntrackThreshold = 80
+	}
+	t.nodeName = synTestConfig.Runtime[common.SpecialKeyNodeName]
+	return nil
+}
+
+func (t *PortExhaustionTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: 2, Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+	labels := map[string]string{"node": t.nodeName}
+
+	// Ephemeral ports - a port can be reused for different destinations, so the limit is per destination
+	usage, err := t.ephemeralPortUsage()
+	if err != nil {
+		log.Println("unable to read ephemeral port usage", err)
+	} else {
+		perDestPercent := float64(usage.MaxPerDestination) * 100 / float64(usage.RangeSize)
+		log.Printf("ephemeral ports: %d in use (range size: %d), max towards a single destination: %d (%.1f%%, %s)\n",
+			usage.InUse, usage.RangeSize, usage.MaxPerDestination, perDestPercent, usage.Destination)
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{Name: "ephemeral_ports_in_use", Help: "Number of distinct ephemeral ports in use",
+				Value: float64(usage.InUse), Labels: labels},
+			common.PrometheusGauge{Name: "ephemeral_port_range_size", Help: "Size of the ephemeral port range",
+				Value: float64(usage.RangeSize), Labels: labels},
+			common.PrometheusGauge{Name: "ephemeral_ports_max_destination_percent",
+				Help:  "Percent of the ephemeral port range used towards the busiest destination",
+				Value: perDestPercent, Labels: labels})
+		if perDestPercent > t.config.EphemeralThreshold {
+			log.Printf("ephemeral port usage towards %s is above the threshold of %.1f%%\n", usage.Destination,
+				t.config.EphemeralThreshold)
+		} else {
+			testResult.Marks++
+		}
+	}
+
+	// Conntrack table
+	count, max, err := utils.ReadConntrackUsage(t.config.ProcPath)
+	if err != nil || max == 0 {
+		log.Println("unable to read conntrack usage", err)
+	} else {
+		percent := float64(count) * 100 / float64(max)
+		log.Printf("conntrack: %d/%d entries (%.1f%%)\n", count, max, percent)
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{Name: "conntrack_usage_percent", Help: "Percent of the conntrack table in use",
+				Value: percent, Labels: labels})
+		if percent > t.config.ConntrackThreshold {
+			log.Printf("conntrack usage is above the threshold of %.1f%%\n", t.config.ConntrackThreshold)
+		} else {
+			testResult.Marks++
+		}
+	}
+
+	log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+	err = common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func (t *PortExhaustionTest) ephemeralPortUsage() (PortUsage, error) {
+	usage := PortUsage{}
+	b, err := os.ReadFile(filepath.Join(t.config.ProcPath, "sys/net/ipv4/ip_local_port_range"))
+	if err != nil {
+		return usage, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return usage, errors.Errorf("unexpected ip_local_port_range format: %q", string(b))
+	}
+	low, err1 := strconv.Atoi(fields[0])
+	high, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || high < low {
+		return usage, errors.Errorf("unexpected ip_local_port_range format: %q", string(b))
+	}
+	usage.RangeSize = high - low + 1
+
+	inUse := map[int]bool{}
+	perDestination := map[string]int{}
+	// Sockets of pid 1's network namespace - i.e. the host's if the host's proc is mounted
+	for _, f := range []string{"1/net/tcp", "1/net/tcp6"} {
+		err := readSockets(filepath.Join(t.config.ProcPath, f), func(localPort int, remote string) {
+			if localPort < low || localPort > high {
+				return
+			}
+			inUse[localPort] = true
+			perDestination[remote]++
+		})
+		if err != nil {
+			return usage, err
+		}
+	}
+	usage.InUse = len(inUse)
+	for dest, n := range perDestination {
+		if n > usage.MaxPerDestination {
+			usage.MaxPerDestination = n
+			usage.Destination = dest
+		}
+	}
+	return usage, nil
+}
+
+// Calls f for each non-listening socket in a /proc/net/tcp(6) file, with the local port and the remote address (hex)
+func readSockets(path string, f func(localPort int, remote string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // skip the header
+	for scanner.Scan() {
+		// Format: "sl local_address rem_address st ...", addresses are "<hex ip>:<hex port>"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[3] == tcpStateListen {
+			continue
+		}
+		local := strings.Split(fields[1], ":")
+		port, err := strconv.ParseInt(local[len(local)-1], 16, 32)
+		if err != nil {
+			continue
+		}
+		f(int(port), fields[2])
+	}
+	return scanner.Err()
+}
+
+func (t *PortExhaustionTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &PortExhaustionTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const PluginName = "pvc"
+
+const ManagedByLabel = "app.kubernetes.io/managed-by"
+const ManagedByValue = "synthetic-heart"
+
+/*
+ * Test to check that volumes can be provisioned, by creating a pvc for each storage class and waiting for it to bind
+ */
+type PVCTest struct {
+	config    PVCTestConfig
+	k8sClient *kubernetes.Clientset
+	nodeName  string
+}
+
+type PVCTestConfig = pluginconfig.PVCTestConfig
+
+type ProvisionResult struct {
+	BindDuration  time.Duration
+	MountDuration time.Duration
+}
+
+func (t *PVCTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = PVCTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Namespace == "" {
+		return errors.New("namespace must be set")
+	}
+	if len(t.config.StorageClasses) == 0 {
+		t.config.StorageClasses = []string{""}
+	}
+	if t.config.Size == "" {
+		t.config.Size = "1Gi"
+	}
+	if _, err := resource.ParseQuantity(t.config.Size); err != nil {
+		return errors.Wrap(err, "invalid size")
+	}
+	if t.config.PodImage == "" {
+		t.config.PodImage = "busybox"
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 2 * time.Minute
+	}
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	t.nodeName = synTestConfig.Runtime[common.SpecialKeyNodeName]
+
+	k8sConfig, err := utils.GetK8sConfig()
+	if err != nil {
+		return errors.Wrap(err, "error getting k8s config")
+	}
+	t.k8sClient, err = kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating k8s client")
+	}
+	return nil
+}
+
+func (t *PVCTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	testResult := proto.TestResult{Marks: 0, MaxMarks: uint64(len(t.config.StorageClasses)), Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	wp := utils.NewWorkerPool(t.config.Workers, len(t.config.StorageClasses), t.provisionTest, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+	for _, sc := range t.config.StorageClasses {
+		wp.AddJob(sc)
+	}
+
+	for i := 0; i < len(t.config.StorageClasses); i++ {
+		res := <-wp.ResultChan
+		ok := 0
+		if res.Error == nil {
+			testResult.Marks++
+			ok = 1
+		}
+
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		sc := res.Job.(string)
+		if sc == "" {
+			sc = "default"
+		}
+		result := res.ReturnValues.(ProvisionResult)
+		labels := map[string]string{"storageClass": sc}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{Name: "pvc_provision_success", Help: "Whether the pvc was provisioned (and mounted)",
+				Value: float64(ok), Labels: labels},
+			common.PrometheusGauge{Name: "pvc_bind_duration_ns", Help: "Time taken for the pvc to bind",
+				Value: float64(result.BindDuration.Nanoseconds()), Labels: labels})
+		if t.config.MountPod {
+			promMetrics.Gauges = append(promMetrics.Gauges,
+				common.PrometheusGauge{Name: "pvc_mount_duration_ns", Help: "Time taken for the pod mounting the pvc to start",
+					Value: float64(result.MountDuration.Nanoseconds()), Labels: labels})
+		}
+	}
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func (t *PVCTest) provisionTest(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	sc := j.(string)
+	result := ProvisionResult{}
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "synheart-pvc-test-",
+			Labels:       map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(t.config.Size)},
+			},
+		},
+	}
+	if sc != "" {
+		pvc.Spec.StorageClassName = &sc
+	}
+
+	start := time.Now()
+	pvc, err := t.k8sClient.CoreV1().PersistentVolumeClaims(t.config.Namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	if err != nil {
+		log.Println("error creating pvc", err)
+		return result, errors.Wrap(err, "error creating pvc")
+	}
+	log.Printf("created pvc %s/%s (storage class: %s)\n", pvc.Namespace, pvc.Name, sc)
+	defer t.cleanup(log, pvc.Name)
+
+	if t.config.MountPod {
+		// The pod is created straight away, as WaitForFirstConsumer storage classes only bind once the pvc is used
+		err = t.createPod(ctx, pvc.Name)
+		if err != nil {
+			log.Println("error creating pod", err)
+			return result, errors.Wrap(err, "error creating pod")
+		}
+		log.Println("created pod " + pvc.Name)
+	}
+
+	err = wait.PollImmediateUntilWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		p, err := t.k8sClient.CoreV1().PersistentVolumeClaims(t.config.Namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return p.Status.Phase == corev1.ClaimBound, nil
+	})
+	result.BindDuration = time.Since(start)
+	if err != nil {
+		log.Println("pvc did not bind", err)
+		return result, errors.Wrap(err, "pvc did not bind")
+	}
+	log.Printf("pvc bound in %dms\n", result.BindDuration.Milliseconds())
+
+	if !t.config.MountPod {
+		return result, nil
+	}
+	err = wait.PollImmediateUntilWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		p, err := t.k8sClient.CoreV1().Pods(t.config.Namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		return p.Status.Phase == corev1.PodRunning || p.Status.Phase == corev1.PodSucceeded, nil
+	})
+	result.MountDuration = time.Since(start)
+	if err != nil {
+		log.Println("pod did not start", err)
+		return result, errors.Wrap(err, "pod mounting the pvc did not start")
+	}
+	log.Printf("pod started in %dms\n", result.MountDuration.Milliseconds())
+	return result, nil
+}
+
+func (t *PVCTest) createPod(ctx context.Context, name string) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:         "mount",
+				Image:        t.config.PodImage,
+				Command:      []string{"sh", "-c", "touch /data/synheart && sleep 3600"},
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: name},
+				},
+			}},
+		},
+	}
+	if t.nodeName != "" {
+		// Mount on the node the agent is running on, to test the csi driver on this node
+		pod.Spec.NodeName = t.nodeName
+	}
+	_, err := t.k8sClient.CoreV1().Pods(t.config.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	return err
+}
+
+// Deletes the pod and pvc (both use the same name)
+func (t *PVCTest) cleanup(log *log.Logger, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if t.config.MountPod {
+		grace := int64(0)
+		err := t.k8sClient.CoreV1().Pods(t.config.Namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+		if err != nil {
+			log.Println("error deleting pod", err)
+		}
+	}
+	err := t.k8sClient.CoreV1().PersistentVolumeClaims(t.config.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		log.Println("error deleting pvc", err)
+		return
+	}
+	log.Println("cleaned up pvc " + name)
+}
+
+func (t *PVCTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &PVCTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
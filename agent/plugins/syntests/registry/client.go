@@ -0,0 +1,296 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DockerHubRegistry = "docker.io"
+	DockerHubApiHost  = "registry-1.docker.io"
+)
+
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+func (r ImageReference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Parses an image reference, e.g. alpine:3.20, ghcr.io/org/image@sha256:...
+func parseReference(image string) (ImageReference, error) {
+	ref := ImageReference{Registry: DockerHubRegistry, Tag: "latest"}
+	if i := strings.Index(image, "@"); i >= 0 {
+		ref.Digest = image[i+1:]
+		image = image[:i]
+	}
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		image = parts[1]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 {
+		ref.Tag = image[i+1:]
+		image = image[:i]
+	}
+	if image == "" {
+		return ref, errors.New("invalid image reference")
+	}
+	if ref.Registry == DockerHubRegistry && !strings.Contains(image, "/") {
+		image = "library/" + image
+	}
+	ref.Repository = image
+	return ref, nil
+}
+
+// Reads the credentials from a docker config json, returns a map of registry host -> auth
+func loadDockerConfig(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	creds := map[string]string{}
+	for k, v := range config.Auths {
+		host := strings.TrimPrefix(strings.TrimPrefix(k, "https://"), "http://")
+		host = strings.Split(host, "/")[0]
+		if host == "index.docker.io" || host == DockerHubApiHost {
+			host = DockerHubRegistry
+		}
+		creds[host] = v.Auth
+	}
+	return creds, nil
+}
+
+type registryClient struct {
+	ref    ImageReference
+	host   string
+	auth   string // base64 encoded user:password, empty for anonymous
+	token  string // bearer token, once fetched
+	client *http.Client
+	log    *log.Logger
+}
+
+func newRegistryClient(ref ImageReference, auth string, log *log.Logger) *registryClient {
+	host := ref.Registry
+	if host == DockerHubRegistry {
+		host = DockerHubApiHost
+	}
+	return &registryClient{ref: ref, host: host, auth: auth, client: &http.Client{}, log: log}
+}
+
+func (c *registryClient) manifest(ctx context.Context, method string, reference string) (*http.Response, []byte, error) {
+	u := "https://" + c.host + "/v2/" + c.ref.Repository + "/manifests/" + reference
+	resp, err := c.do(ctx, method, u, strings.Join(manifestMediaTypes, ","))
+	if err != nil {
+		return resp, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, errors.Wrap(err, "error reading manifest")
+	}
+	return resp, body, nil
+}
+
+// Pulls the config and all the layers of the image (discarding the data), returns the number of bytes pulled
+func (c *registryClient) pull(ctx context.Context, mediaType string, manifest []byte) (int64, error) {
+	m := struct {
+		Manifests []struct {
+			Digest   string `json:"digest"`
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}{}
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return 0, errors.Wrap(err, "error parsing manifest")
+	}
+
+	if strings.Contains(mediaType, "index") || strings.Contains(mediaType, "manifest.list") {
+		// Pick the manifest for the platform the agent is running on
+		for _, pm := range m.Manifests {
+			if pm.Platform.OS == "linux" && pm.Platform.Architecture == runtime.GOARCH {
+				c.log.Printf("pulling manifest %s for linux/%s\n", pm.Digest, runtime.GOARCH)
+				resp, body, err := c.manifest(ctx, "GET", pm.Digest)
+				if err != nil {
+					return 0, err
+				}
+				return c.pull(ctx, resp.Header.Get("Content-Type"), body)
+			}
+		}
+		return 0, errors.Errorf("no manifest found for linux/%s", runtime.GOARCH)
+	}
+
+	digests := []string{m.Config.Digest}
+	for _, l := range m.Layers {
+		digests = append(digests, l.Digest)
+	}
+	total := int64(0)
+	for _, d := range digests {
+		resp, err := c.do(ctx, "GET", "https://"+c.host+"/v2/"+c.ref.Repository+"/blobs/"+d, "")
+		if err != nil {
+			return total, err
+		}
+		n, err := io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		total += n
+		if err != nil {
+			return total, errors.Wrap(err, "error downloading blob "+d)
+		}
+	}
+	return total, nil
+}
+
+// Performs the request, authenticating if the registry asks for it
+func (c *registryClient) do(ctx context.Context, method string, u string, accept string) (*http.Response, error) {
+	resp, err := c.request(ctx, method, u, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+		resp, err = c.request(ctx, method, u, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return resp, errors.Errorf("unexpected status from %s: %s", u, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *registryClient) request(ctx context.Context, method string, u string, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else if c.auth != "" {
+		req.Header.Set("Authorization", "Basic "+c.auth)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending request to "+u)
+	}
+	return resp, nil
+}
+
+// Fetches a bearer token as described in the WWW-Authenticate challenge
+func (c *registryClient) authenticate(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return errors.Errorf("unauthorized, and unsupported auth challenge: %q", challenge)
+	}
+	params := map[string]string{}
+	for _, p := range strings.Split(strings.TrimPrefix(challenge, "Bearer "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) == 2 {
+			params[kv[0]] = strings.Trim(kv[1], "\"")
+		}
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return errors.Errorf("invalid realm in auth challenge: %q", challenge)
+	}
+	q := realm.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", "repository:"+c.ref.Repository+":pull")
+	realm.RawQuery = q.Encode()
+
+	c.log.Println("fetching token from " + realm.Host)
+	req, err := http.NewRequestWithContext(ctx, "GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.auth != "" {
+		req.Header.Set("Authorization", "Basic "+c.auth)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error fetching token")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status fetching token: %s", resp.Status)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return errors.Wrap(err, "error parsing token response")
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return errors.New("no token in token response")
+	}
+	return nil
+}
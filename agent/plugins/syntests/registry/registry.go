@@ -0,0 +1,198 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "registry"
+
+const (
+	ModeHead = "head" // HEAD the manifest only
+	ModePull = "pull" // fetch the manifest, config and all the layers
+)
+
+/*
+ * Test to check that images can be pulled from container registries
+ */
+type RegistryTest struct {
+	config RegistryTestConfig
+	creds  map[string]string // registry host -> base64 encoded user:password
+}
+
+type RegistryTestConfig = pluginconfig.RegistryTestConfig
+
+type PullResult struct {
+	ManifestDuration   time.Duration
+	PullDuration       time.Duration
+	StatusCode         int
+	RateLimitRemaining int // -1 if the registry doesn't report it
+}
+
+func (t *RegistryTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = RegistryTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 30 * time.Second
+	}
+	if t.config.Mode == "" {
+		t.config.Mode = ModeHead
+	}
+	if t.config.Mode != ModeHead && t.config.Mode != ModePull {
+		return errors.Errorf("unsupported mode: %s", t.config.Mode)
+	}
+	t.creds = map[string]string{}
+	if t.config.DockerConfigPath != "" {
+		t.creds, err = loadDockerConfig(t.config.DockerConfigPath)
+		if err != nil {
+			return errors.Wrap(err, "error loading docker config")
+		}
+	}
+	return nil
+}
+
+func (t *RegistryTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	if len(t.config.Images) <= 0 {
+		return common.FailedTestResult(), errors.New("no images to test")
+	}
+	testResult := proto.TestResult{Marks: 0, MaxMarks: uint64(len(t.config.Images)), Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	wp := utils.NewWorkerPool(t.config.Workers, len(t.config.Images), t.pullTest, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+	for _, image := range t.config.Images {
+		wp.AddJob(image)
+	}
+
+	for i := 0; i < len(t.config.Images); i++ {
+		res := <-wp.ResultChan
+		ok := 0
+		if res.Error == nil {
+			testResult.Marks++
+			ok = 1
+		}
+
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		result := res.ReturnValues.(PullResult)
+		labels := map[string]string{"image": res.Job.(string), "mode": t.config.Mode}
+		promMetrics.Gauges = append(promMetrics.Gauges,
+			common.PrometheusGauge{Name: "registry_pull_success", Help: "Whether the image could be pulled",
+				Value: float64(ok), Labels: labels},
+			common.PrometheusGauge{Name: "registry_status_code", Help: "Status code of the manifest request",
+				Value: float64(result.StatusCode), Labels: labels},
+			common.PrometheusGauge{Name: "registry_manifest_duration_ns", Help: "Duration of the manifest request (including auth)",
+				Value: float64(result.ManifestDuration.Nanoseconds()), Labels: labels})
+		if t.config.Mode == ModePull {
+			promMetrics.Gauges = append(promMetrics.Gauges,
+				common.PrometheusGauge{Name: "registry_pull_duration_ns", Help: "Duration of the whole pull",
+					Value: float64(result.PullDuration.Nanoseconds()), Labels: labels})
+		}
+		if result.RateLimitRemaining >= 0 {
+			promMetrics.Gauges = append(promMetrics.Gauges,
+				common.PrometheusGauge{Name: "registry_ratelimit_remaining", Help: "Remaining pulls reported by the registry",
+					Value: float64(result.RateLimitRemaining), Labels: labels})
+		}
+	}
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func (t *RegistryTest) pullTest(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	result := PullResult{RateLimitRemaining: -1}
+	ref, err := parseReference(j.(string))
+	if err != nil {
+		return result, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+	client := newRegistryClient(ref, t.creds[ref.Registry], log)
+
+	log.Printf("fetching manifest %s from %s\n", ref.Reference(), ref.Registry)
+	start := time.Now()
+	method := "HEAD"
+	if t.config.Mode == ModePull {
+		method = "GET"
+	}
+	resp, body, err := client.manifest(ctx, method, ref.Reference())
+	result.ManifestDuration = time.Since(start)
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+		if remaining := resp.Header.Get("RateLimit-Remaining"); remaining != "" {
+			// Format is "<limit>;w=<window>"
+			if n, err := strconv.Atoi(strings.Split(remaining, ";")[0]); err == nil {
+				result.RateLimitRemaining = n
+			}
+		}
+	}
+	if err != nil {
+		log.Println("error fetching manifest", err)
+		return result, err
+	}
+	log.Printf("got manifest (%s) in %dms\n", resp.Header.Get("Docker-Content-Digest"), result.ManifestDuration.Milliseconds())
+
+	if t.config.Mode == ModeHead {
+		return result, nil
+	}
+	size, err := client.pull(ctx, resp.Header.Get("Content-Type"), body)
+	result.PullDuration = time.Since(start)
+	if err != nil {
+		log.Println("error pulling image", err)
+		return result, err
+	}
+	log.Printf("pulled %d bytes in %dms\n", size, result.PullDuration.Milliseconds())
+	return result, nil
+}
+
+func (t *RegistryTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &RegistryTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
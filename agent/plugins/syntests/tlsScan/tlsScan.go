@@ -0,0 +1,228 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/utils"
+	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
+)
+
+const PluginName = "tlsScan"
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+/*
+ * Test to enumerate the TLS versions and cipher suites supported by endpoints, and fail when deprecated
+ * versions or weak ciphers are offered
+ */
+type TLSScanTest struct {
+	config TLSScanTestConfig
+}
+
+type TLSScanTestConfig = pluginconfig.TLSScanTestConfig
+
+type Target = pluginconfig.TLSScanTarget
+
+type ScanResult struct {
+	Versions    []string // supported versions
+	WeakCiphers []string // weak cipher suites that were accepted
+}
+
+func (t *TLSScanTest) Initialise(synTestConfig proto.SynTestConfig) error {
+	t.config = TLSScanTestConfig{}
+	err := common.ParseYMLConfig(synTestConfig.Config, &t.config)
+	if err != nil {
+		return errors.Wrap(err, "error parsing config")
+	}
+	if len(t.config.AllowedVersions) == 0 {
+		t.config.AllowedVersions = []string{"1.2", "1.3"}
+	}
+	for _, v := range t.config.AllowedVersions {
+		if _, ok := tlsVersions[v]; !ok {
+			return errors.Errorf("unknown tls version: %s", v)
+		}
+	}
+	if t.config.Timeout <= 0 {
+		t.config.Timeout = 5 * time.Second
+	}
+	if t.config.Workers <= 0 {
+		t.config.Workers = 3
+	}
+	return nil
+}
+
+func (t *TLSScanTest) PerformTest(_ proto.Trigger) (proto.TestResult, error) {
+	if len(t.config.Targets) <= 0 {
+		return common.FailedTestResult(), errors.New("no targets to scan")
+	}
+	testResult := proto.TestResult{Marks: 0, MaxMarks: uint64(len(t.config.Targets)), Details: map[string]string{}}
+	promMetrics := common.PrometheusMetrics{Gauges: []common.PrometheusGauge{}}
+
+	wp := utils.NewWorkerPool(t.config.Workers, len(t.config.Targets), t.scan, false)
+	wp.Start(context.Background())
+	defer wp.Stop()
+	for _, target := range t.config.Targets {
+		wp.AddJob(target)
+	}
+
+	for i := 0; i < len(t.config.Targets); i++ {
+		res := <-wp.ResultChan
+		if res.Error == nil {
+			testResult.Marks++
+		}
+		log.Println("---\n" + strings.TrimSuffix(res.Logs, "\n"))
+		log.Printf("total marks: %d/%d \n", testResult.Marks, testResult.MaxMarks)
+
+		addr := res.Job.(Target).Address
+		result := res.ReturnValues.(ScanResult)
+		for v := range tlsVersions {
+			supported := 0
+			if contains(result.Versions, v) {
+				supported = 1
+			}
+			promMetrics.Gauges = append(promMetrics.Gauges, common.PrometheusGauge{
+				Name: "tls_version_supported", Help: "Whether the endpoint supports the tls version",
+				Value: float64(supported), Labels: map[string]string{"addr": addr, "version": v},
+			})
+		}
+		promMetrics.Gauges = append(promMetrics.Gauges, common.PrometheusGauge{
+			Name: "tls_weak_ciphers", Help: "Number of weak cipher suites accepted by the endpoint",
+			Value: float64(len(result.WeakCiphers)), Labels: map[string]string{"addr": addr},
+		})
+	}
+
+	err := common.AddPrometheusMetricsToResults(promMetrics, testResult)
+	if err != nil {
+		log.Println("unable to add prometheus metrics")
+		return testResult, err
+	}
+	return testResult, nil
+}
+
+func (t *TLSScanTest) scan(_ context.Context, log *log.Logger, j interface{}) (interface{}, error) {
+	target := j.(Target)
+	result := ScanResult{}
+	serverName := target.ServerName
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(target.Address)
+		if err != nil {
+			return result, errors.Wrap(err, "invalid address "+target.Address)
+		}
+		serverName = host
+	}
+
+	// Supported versions
+	problems := []string{}
+	for name, v := range tlsVersions {
+		if t.handshake(target.Address, &tls.Config{ServerName: serverName, MinVersion: v, MaxVersion: v}) != nil {
+			continue
+		}
+		result.Versions = append(result.Versions, name)
+		if !contains(t.config.AllowedVersions, name) {
+			problems = append(problems, "TLS "+name+" is supported")
+		}
+	}
+	if len(result.Versions) == 0 {
+		log.Println("no tls version could be negotiated")
+		return result, errors.New("unable to complete a tls handshake with " + target.Address)
+	}
+	sort.Strings(result.Versions)
+	log.Printf("supported versions: %v\n", result.Versions)
+
+	// Weak cipher suites (TLS 1.3 suites aren't configurable, so only up to TLS 1.2 is checked)
+	for _, cs := range t.weakCipherSuites() {
+		config := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12,
+			CipherSuites: []uint16{cs.ID}}
+		if t.handshake(target.Address, config) == nil {
+			result.WeakCiphers = append(result.WeakCiphers, cs.Name)
+		}
+	}
+	if len(result.WeakCiphers) > 0 {
+		log.Printf("weak ciphers accepted: %v\n", result.WeakCiphers)
+		problems = append(problems, "weak ciphers are accepted: "+strings.Join(result.WeakCiphers, ", "))
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Println(p)
+		}
+		return result, errors.New(strings.Join(problems, "; "))
+	}
+	log.Println("no deprecated versions or weak ciphers offered")
+	return result, nil
+}
+
+func (t *TLSScanTest) weakCipherSuites() []*tls.CipherSuite {
+	weak := tls.InsecureCipherSuites()
+	for _, cs := range tls.CipherSuites() {
+		if contains(t.config.WeakCiphers, cs.Name) {
+			weak = append(weak, cs)
+		}
+	}
+	return weak
+}
+
+func (t *TLSScanTest) handshake(addr string, config *tls.Config) error {
+	// Only the protocol is of interest here, not the certificate
+	config.InsecureSkipVerify = true
+	dialer := &net.Dialer{Timeout: t.config.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TLSScanTest) Finish() error { return nil }
+
+func main() {
+	pluginImpl := &TLSScanTest{}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: common.DefaultTestPluginHandshakeConfig,
+		Plugins: map[string]plugin.Plugin{
+			PluginName: &common.SynTestGRPCPlugin{Impl: pluginImpl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
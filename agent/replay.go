@@ -0,0 +1,77 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/cisco-open/synthetic-heart/agent/pluginmanager"
+	"github.com/hashicorp/go-hclog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runReplay Replays a recording of a test run (written by the agent when recording is enabled) through the exporters
+// and notifiers of the agent config, returns the exit code
+func runReplay(args []string, logger hclog.Logger) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", DefaultConfigFilePath, "agent config file, with the exporters and notifiers")
+	times := flags.Int("times", 1, "number of times the test run is replayed")
+	speed := flags.Float64("speed", 1, "speed of the replay (e.g. 2 replays the calls to the plugin twice as fast)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s replay [flags] <recording.json>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	recording, err := pluginmanager.ReadRecording(flags.Arg(0))
+	if err != nil {
+		logger.Error("error reading recording", "file", flags.Arg(0), "err", err)
+		return 1
+	}
+	logger.Info("replaying test run", "file", flags.Arg(0), "agent", recording.AgentId, "recordedAt", recording.RecordedAt, "failed", recording.Failed)
+
+	pluginmanager.AgentVersion = Version
+	pm, err := pluginmanager.NewReplayPluginManager(*configPath, recording)
+	if err != nil {
+		logger.Error("error creating plugin manager", "err", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		logger.Warn("kill signal from system")
+		cancel()
+	}()
+
+	err = pm.Replay(ctx, &recording, *times, *speed)
+	if err != nil {
+		logger.Error("error replaying test run", "err", err)
+		return 1
+	}
+	return 0
+}
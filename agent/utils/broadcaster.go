@@ -17,6 +17,8 @@
 package utils
 
 import (
+	"sync"
+
 	"github.com/cisco-open/synthetic-heart/common"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/hashicorp/go-hclog"
@@ -30,6 +32,8 @@ type Broadcaster struct {
 	testRunPubCh   chan proto.TestRun
 	stopCh         chan struct{}
 	logger         hclog.Logger
+	subsLock       *sync.Mutex
+	testRunSubs    map[chan proto.TestRun]Listener
 }
 
 // Struct to hold metadata of the listener, useful for debugging
@@ -46,6 +50,8 @@ func NewBroadcaster(log hclog.Logger) Broadcaster {
 		testRunUnsubCh: make(chan chan proto.TestRun, 1),
 		testRunPubCh:   make(chan proto.TestRun, common.BroadcasterPublishChannelSize),
 		stopCh:         make(chan struct{}),
+		subsLock:       &sync.Mutex{},
+		testRunSubs:    map[chan proto.TestRun]Listener{},
 	}
 }
 func (b *Broadcaster) PublishTestRun(testRun proto.TestRun, logger hclog.Logger) {
@@ -69,6 +75,17 @@ func (b *Broadcaster) UnsubscribeFromTestRuns(rCh chan proto.TestRun, logger hcl
 	b.testRunUnsubCh <- rCh
 }
 
+// QueueDepths Returns the number of test runs waiting to be published, and waiting in the queue of every listener
+func (b *Broadcaster) QueueDepths() (int, map[string]int) {
+	b.subsLock.Lock()
+	defer b.subsLock.Unlock()
+	listenerDepths := map[string]int{}
+	for resCh, listener := range b.testRunSubs {
+		listenerDepths[listener.Name] += len(resCh)
+	}
+	return len(b.testRunPubCh), listenerDepths
+}
+
 func (b *Broadcaster) Stop() {
 	b.logger.Debug("stopping broadcaster...")
 	close(b.stopCh)
@@ -76,7 +93,6 @@ func (b *Broadcaster) Stop() {
 
 func (b *Broadcaster) Start() {
 	b.logger.Debug("starting broadcaster...")
-	testRunSubs := map[chan proto.TestRun]Listener{}
 	for {
 		select {
 		case <-b.stopCh:
@@ -85,15 +101,20 @@ func (b *Broadcaster) Start() {
 
 		case listener := <-b.testRunSubCh:
 			b.logger.Debug("test run sub")
-			testRunSubs[listener.ResCh] = listener
+			b.subsLock.Lock()
+			b.testRunSubs[listener.ResCh] = listener
+			b.subsLock.Unlock()
 
 		case ch := <-b.testRunUnsubCh:
 			b.logger.Debug("test run unsub")
-			delete(testRunSubs, ch)
+			b.subsLock.Lock()
+			delete(b.testRunSubs, ch)
+			b.subsLock.Unlock()
 
 		case res := <-b.testRunPubCh:
 			b.logger.Debug("new test run event")
-			for resCh, listener := range testRunSubs {
+			b.subsLock.Lock()
+			for resCh, listener := range b.testRunSubs {
 				// Check if the testRun passes the provided filters
 				select {
 				case resCh <- res:
@@ -102,6 +123,7 @@ func (b *Broadcaster) Start() {
 						"testName", res.TestConfig.Name)
 				}
 			}
+			b.subsLock.Unlock()
 		}
 	}
 }
@@ -0,0 +1,159 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package activation
+
+// package containing code to check whether a test is active (scheduled to run), from its activeFrom/activeUntil
+// timestamps and recurring active windows (e.g. business hours)
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // the agent image may not have the timezone database
+
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/pkg/errors"
+)
+
+// TimeOfDayFormat is the format of the start and end of the active windows
+const TimeOfDayFormat = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Schedule is when a test is active, a zero Schedule is always active
+type Schedule struct {
+	From    time.Time // zero if not set
+	Until   time.Time // zero if not set
+	Windows []Window  // the test is active in any of them, always if empty
+}
+
+// Window is a recurring window of time in a timezone
+type Window struct {
+	Days     map[time.Weekday]bool // days the window starts on, every day if empty
+	Start    time.Duration         // since midnight
+	End      time.Duration         // since midnight, the next day if it's not after Start
+	Location *time.Location
+}
+
+// Parse Returns the schedule of the test
+func Parse(config *proto.SynTestConfig) (Schedule, error) {
+	schedule := Schedule{}
+	var err error
+	if config.ActiveFrom != "" {
+		schedule.From, err = time.Parse(time.RFC3339, config.ActiveFrom)
+		if err != nil {
+			return schedule, errors.Wrap(err, "invalid activeFrom, must be RFC3339 (e.g. 2024-05-01T09:00:00Z)")
+		}
+	}
+	if config.ActiveUntil != "" {
+		schedule.Until, err = time.Parse(time.RFC3339, config.ActiveUntil)
+		if err != nil {
+			return schedule, errors.Wrap(err, "invalid activeUntil, must be RFC3339 (e.g. 2024-05-01T17:00:00Z)")
+		}
+	}
+	if !schedule.From.IsZero() && !schedule.Until.IsZero() && !schedule.Until.After(schedule.From) {
+		return schedule, errors.New("activeUntil must be after activeFrom")
+	}
+	for i, w := range config.ActiveWindows {
+		window, err := parseWindow(w)
+		if err != nil {
+			return schedule, errors.Wrapf(err, "invalid active window %d", i)
+		}
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return schedule, nil
+}
+
+func parseWindow(w *proto.ActiveWindow) (Window, error) {
+	window := Window{Days: map[time.Weekday]bool{}, Location: time.UTC}
+	for _, day := range w.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return window, errors.New("invalid day '" + day + "', must be mon, tue, wed, thu, fri, sat or sun")
+		}
+		window.Days[weekday] = true
+	}
+	var err error
+	window.Start, err = parseTimeOfDay(w.Start)
+	if err != nil {
+		return window, errors.Wrap(err, "invalid start")
+	}
+	window.End, err = parseTimeOfDay(w.End)
+	if err != nil {
+		return window, errors.Wrap(err, "invalid end")
+	}
+	if window.Start == window.End {
+		return window, errors.New("start and end must be different")
+	}
+	if w.Timezone != "" {
+		window.Location, err = time.LoadLocation(w.Timezone)
+		if err != nil {
+			return window, errors.Wrap(err, "invalid timezone")
+		}
+	}
+	return window, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse(TimeOfDayFormat, s)
+	if err != nil {
+		return 0, errors.New("'" + s + "' isn't a time of day (e.g. 09:30)")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Active Returns whether the test is active at the time
+func (s Schedule) Active(t time.Time) bool {
+	if !s.From.IsZero() && t.Before(s.From) {
+		return false
+	}
+	if !s.Until.IsZero() && !t.Before(s.Until) {
+		return false
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.Windows {
+		if w.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active Returns whether the time is in the window
+func (w Window) Active(t time.Time) bool {
+	t = t.In(w.Location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	startsOn := func(day time.Weekday) bool {
+		return len(w.Days) == 0 || w.Days[day]
+	}
+	if w.Start < w.End {
+		return startsOn(t.Weekday()) && sinceMidnight >= w.Start && sinceMidnight < w.End
+	}
+	// the window crosses midnight, so it's either the evening of a day it starts on, or the morning after
+	yesterday := (t.Weekday() + 6) % 7
+	return (startsOn(t.Weekday()) && sinceMidnight >= w.Start) || (startsOn(yesterday) && sinceMidnight < w.End)
+}
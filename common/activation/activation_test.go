@@ -0,0 +1,137 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package activation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("error loading location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *proto.SynTestConfig
+		wantErr bool
+	}{
+		{"nothing", &proto.SynTestConfig{}, false},
+		{"from and until", &proto.SynTestConfig{ActiveFrom: "2024-05-01T09:00:00Z", ActiveUntil: "2024-05-02T09:00:00+02:00"}, false},
+		{"invalid from", &proto.SynTestConfig{ActiveFrom: "2024-05-01 09:00"}, true},
+		{"until before from", &proto.SynTestConfig{ActiveFrom: "2024-05-02T09:00:00Z", ActiveUntil: "2024-05-01T09:00:00Z"}, true},
+		{"until equal to from", &proto.SynTestConfig{ActiveFrom: "2024-05-01T09:00:00Z", ActiveUntil: "2024-05-01T09:00:00Z"}, true},
+		{"window", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Days: []string{"Mon", "tuesday"}, Start: "09:00", End: "17:30", Timezone: "Europe/London"},
+		}}, false},
+		{"invalid day", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{{Days: []string{"tues"}, Start: "09:00", End: "17:00"}}}, true},
+		{"invalid start", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{{Start: "9am", End: "17:00"}}}, true},
+		{"invalid end", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{{Start: "09:00", End: "24:00"}}}, true},
+		{"empty window", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{{Start: "09:00", End: "09:00"}}}, true},
+		{"invalid timezone", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleActive(t *testing.T) {
+	utc := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	newYork := mustLoad(t, "America/New_York")
+	london := mustLoad(t, "Europe/London")
+	businessHours := &proto.ActiveWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", Timezone: "Europe/London"}
+	nightly := &proto.ActiveWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"} // UTC, friday night to saturday morning
+	tests := []struct {
+		name   string
+		config *proto.SynTestConfig
+		time   time.Time
+		want   bool
+	}{
+		{"always active", &proto.SynTestConfig{}, utc("2024-05-01T03:00:00Z"), true},
+		{"before from", &proto.SynTestConfig{ActiveFrom: "2024-05-01T09:00:00Z"}, utc("2024-05-01T08:59:59Z"), false},
+		{"at from", &proto.SynTestConfig{ActiveFrom: "2024-05-01T09:00:00Z"}, utc("2024-05-01T09:00:00Z"), true},
+		{"before until", &proto.SynTestConfig{ActiveUntil: "2024-05-01T17:00:00Z"}, utc("2024-05-01T16:59:59Z"), true},
+		{"at until", &proto.SynTestConfig{ActiveUntil: "2024-05-01T17:00:00Z"}, utc("2024-05-01T17:00:00Z"), false},
+		{"in a window but after until", &proto.SynTestConfig{ActiveUntil: "2024-05-01T00:00:00Z",
+			ActiveWindows: []*proto.ActiveWindow{businessHours}}, utc("2024-05-01T10:00:00Z"), false},
+
+		// 2024-05-01 is a wednesday, london is on BST (UTC+1)
+		{"business hours, start", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, time.Date(2024, 5, 1, 9, 0, 0, 0, london), true},
+		{"business hours, before start", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, utc("2024-05-01T07:30:00Z"), false}, // 08:30 BST
+		{"business hours, end", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, time.Date(2024, 5, 1, 17, 0, 0, 0, london), false},
+		{"business hours, other day", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, time.Date(2024, 5, 4, 12, 0, 0, 0, london), false},
+		{"business hours, any window", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly, businessHours}}, time.Date(2024, 5, 1, 12, 0, 0, 0, london), true},
+
+		// the nightly window crosses midnight: it starts on friday, and ends on saturday
+		{"across midnight, friday evening", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-03T23:00:00Z"), true},
+		{"across midnight, friday before start", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-03T21:59:00Z"), false},
+		{"across midnight, saturday at midnight", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-04T00:00:00Z"), true},
+		{"across midnight, saturday morning", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-04T05:59:59Z"), true},
+		{"across midnight, saturday at end", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-04T06:00:00Z"), false},
+		{"across midnight, saturday evening", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-04T23:00:00Z"), false},
+		{"across midnight, friday morning", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{nightly}}, utc("2024-05-03T03:00:00Z"), false},
+		{"across midnight, every day", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{{Start: "22:00", End: "06:00"}}}, utc("2024-05-06T03:00:00Z"), true},
+
+		// the windows follow the wall clock of their timezone across daylight saving time changes
+		{"dst, business hours in winter", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, utc("2024-01-10T09:00:00Z"), true},
+		{"dst, business hours in summer", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, utc("2024-07-10T08:00:00Z"), true},
+		{"dst, before business hours in summer", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{businessHours}}, utc("2024-07-10T07:59:00Z"), false},
+		{"dst, spring forward before the gap", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Start: "01:30", End: "02:30", Timezone: "America/New_York"}}}, time.Date(2024, 3, 10, 1, 45, 0, 0, newYork), true},
+		{"dst, spring forward after the gap", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Start: "01:30", End: "02:30", Timezone: "America/New_York"}}}, utc("2024-03-10T07:00:00Z"), false}, // 03:00 EDT
+		{"dst, fall back first 01:30", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Start: "01:00", End: "02:00", Timezone: "America/New_York"}}}, utc("2024-11-03T05:30:00Z"), true}, // 01:30 EDT
+		{"dst, fall back second 01:30", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Start: "01:00", End: "02:00", Timezone: "America/New_York"}}}, utc("2024-11-03T06:30:00Z"), true}, // 01:30 EST
+		{"dst, fall back 02:00", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Start: "01:00", End: "02:00", Timezone: "America/New_York"}}}, utc("2024-11-03T07:00:00Z"), false},
+		{"dst, across midnight on the change", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Days: []string{"sat"}, Start: "23:00", End: "03:00", Timezone: "America/New_York"}}}, utc("2024-03-10T06:59:00Z"), true}, // 01:59 EST sunday
+		{"dst, across midnight after the change", &proto.SynTestConfig{ActiveWindows: []*proto.ActiveWindow{
+			{Days: []string{"sat"}, Start: "23:00", End: "03:00", Timezone: "America/New_York"}}}, utc("2024-03-10T07:00:00Z"), false}, // 03:00 EDT sunday
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.config)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := schedule.Active(tt.time); got != tt.want {
+				t.Errorf("Active(%s) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
@@ -30,6 +30,13 @@ var DefaultTestPluginHandshakeConfig = plugin.HandshakeConfig{
 	MagicCookieValue: "synthetic-heart",
 }
 
+// Handshake config for Hashicorp plugins to talk to exporter plugins
+var DefaultExporterPluginHandshakeConfig = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "EXPORTER_PLUGIN",
+	MagicCookieValue: "synthetic-heart",
+}
+
 var DefaultBackoff = wait.Backoff{
 	Steps:    20,
 	Duration: 10 * time.Millisecond,
@@ -51,12 +58,71 @@ const (
 	DefaultFinishTimeout          = 10 * time.Second
 	DefaultLogWaitTime            = 15 * time.Millisecond
 	DefaultRestartPolicy          = RestartAlways
+	DefaultRepeat                 = 1 * time.Minute     // repeat interval set by the defaulting webhook if a test has none
+	TestRunHistoryRetention       = 32 * 24 * time.Hour // how long test run results are kept to compute SLOs (max SLO window)
+	TestRunRequestRetention       = 24 * time.Hour      // how long the results of a requested (one-shot) test run are kept
 )
 
 // Trigger Type Values
 const (
 	TriggerTypeTimer = "timer"
 	TriggerTypeTest  = "test"
+	TriggerTypeRun   = "run" // requested run (SynTestRun), the details of the trigger are the id of the request
+)
+
+// Prometheus metrics exported by the agent (test results) and the controller (health score, slos)
+const (
+	MetricMarks              = "syntheticheart_marks_total"
+	MetricMaxMarks           = "syntheticheart_max_marks_total"
+	MetricRuntime            = "syntheticheart_runtime_ns"
+	MetricTestRuns           = "syntheticheart_test_runs_total"
+	MetricRuntimeHist        = "syntheticheart_runtime_seconds"
+	MetricFlapping           = "syntheticheart_test_flapping"
+	MetricSilenced           = "syntheticheart_test_silenced"
+	MetricPluginRestarts     = "syntheticheart_agent_plugin_restarts_total"
+	MetricRunningSynTests    = "syntheticheart_agent_running_syntests"
+	MetricBroadcasterQueue   = "syntheticheart_agent_broadcaster_queue_depth"
+	MetricHealthScore        = "syntheticheart_health_score"
+	MetricClusterHealthScore = "syntheticheart_cluster_health_score"
+	MetricSLOTarget          = "syntheticheart_slo_target"
+	MetricSLOAvailability    = "syntheticheart_slo_availability"
+	MetricSLOBudgetRemaining = "syntheticheart_slo_error_budget_remaining"
+	MetricSLOBurnRate        = "syntheticheart_slo_burn_rate"
+	MetricGCReclaimed        = "syntheticheart_gc_reclaimed_total"
+	MetricGCOrphans          = "syntheticheart_gc_orphans"
+	MetricAgentUp            = "syntheticheart_agent_up"
+	MetricAgentStatusAge     = "syntheticheart_agent_last_status_age_seconds"
+	MetricTestNotRunning     = "syntheticheart_test_not_running"
+)
+
+// Audit log kinds and actions
+const (
+	AuditKindSynTest = "syntest"
+	AuditKindPlugin  = "plugin"
+	AuditKindSilence = "silence"
+	AuditKindAgent   = "agent"
+	AuditKindApiCall = "apiCall" // mutating call of the rest api, the action is the http method
+
+	AuditActionCreated    = "created"
+	AuditActionUpdated    = "updated"
+	AuditActionReassigned = "reassigned"
+	AuditActionDeleted    = "deleted"
+	AuditActionStarted    = "started"
+	AuditActionStopped    = "stopped"
+	AuditActionRestarting = "restarting"
+	AuditActionExited     = "exited"
+	AuditActionCanary     = "canary"
+	AuditActionPromoted   = "promoted"
+	AuditActionRolledBack = "rolledBack"
+	AuditActionPinned     = "pinned"
+	AuditActionUnpinned   = "unpinned"
+	AuditActionPaused     = "paused"
+	AuditActionResumed    = "resumed"
+	AuditActionTriggered  = "triggered"
+
+	AuditActionDeregistered = "deregistered" // agent deleted from storage through the rest api
+
+	AuditLogMaxLen = 100000 // approximate number of events kept in the audit log
 )
 
 // Importance Values
@@ -65,8 +131,26 @@ const (
 	ImportanceHigh     = "high"
 	ImportanceMedium   = "medium"
 	ImportanceLow      = "low"
+
+	DefaultImportance = ImportanceMedium // importance of the tests which don't set one, in metrics and alerts
 )
 
+// Weights of the importance levels when computing the health score (tests with no/unknown importance are treated as low)
+var ImportanceWeights = map[string]float64{
+	ImportanceCritical: 4,
+	ImportanceHigh:     3,
+	ImportanceMedium:   2,
+	ImportanceLow:      1,
+}
+
+// Default severity label of the alerts of the tests (prometheus rules and alertmanager), by importance
+var ImportanceSeverities = map[string]string{
+	ImportanceCritical: "critical",
+	ImportanceHigh:     "critical",
+	ImportanceMedium:   "warning",
+	ImportanceLow:      "info",
+}
+
 // Special Keys in Details of TestDetailsMap
 const (
 	ErrorKey      = "_error"      // special key for error details
@@ -104,6 +188,7 @@ const (
 	Error         RoutineStatus = "error"
 	Restarting    RoutineStatus = "restarting"
 	StatusUnknown RoutineStatus = "unknown"
+	Paused        RoutineStatus = "paused"
 )
 
 // Other constants
@@ -115,3 +200,17 @@ const (
 	SpecialKeyPodName   string = "$podName"
 	SpecialKeyAgentNs   string = "$agentNamespace"
 )
+
+// ClusterTestNamespace is the namespace of cluster scoped tests (ClusterSyntheticTests) in storage,
+// it isn't a valid k8s namespace so it can't clash with a namespaced test
+const ClusterTestNamespace = "_cluster"
+
+// ConfigSourceLabel is set on the labels of test configs which don't come from a CRD (e.g. from a git repo or a url), with the
+// source of the config. The controller doesn't delete them when syncing the CRDs to storage.
+const (
+	ConfigSourceLabel = "synheart.infra.webex.com/source"
+	ConfigSourceGit   = "git"
+	ConfigSourceHTTP  = "http"
+	ConfigSourceAPI   = "restapi"    // created through the rest api
+	ConfigSourceSim   = "simulation" // generated by the simulation mode of the rest api
+)
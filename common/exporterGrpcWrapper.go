@@ -0,0 +1,77 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package common
+
+import (
+	"context"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+)
+
+type ExporterPluginGRPCClient struct {
+	client proto.ExporterPluginClient
+}
+
+func (e *ExporterPluginGRPCClient) Initialise(config proto.ExporterConfig) error {
+	_, err := e.client.Initialise(context.Background(), &config)
+	return err
+}
+
+func (e *ExporterPluginGRPCClient) Export(testRun proto.TestRun) error {
+	_, err := e.client.Export(context.Background(), &testRun)
+	return err
+}
+
+func (e *ExporterPluginGRPCClient) Finish() error {
+	_, err := e.client.Finish(context.Background(), &proto.Empty{})
+	return err
+}
+
+type ExporterPluginGRPCServer struct {
+	Impl ExporterPlugin
+	proto.UnimplementedExporterPluginServer
+}
+
+func (s *ExporterPluginGRPCServer) Initialise(ctx context.Context, config *proto.ExporterConfig) (*proto.Empty, error) {
+	err := s.Impl.Initialise(*config)
+	return &proto.Empty{}, err
+}
+
+func (s *ExporterPluginGRPCServer) Export(ctx context.Context, testRun *proto.TestRun) (*proto.Empty, error) {
+	err := s.Impl.Export(*testRun)
+	return &proto.Empty{}, err
+}
+
+func (s *ExporterPluginGRPCServer) Finish(context.Context, *proto.Empty) (*proto.Empty, error) {
+	err := s.Impl.Finish()
+	return &proto.Empty{}, err
+}
+
+type ExporterGRPCPlugin struct {
+	plugin.Plugin                // Implement the plugin.Plugin Interface even tho its a GRPC interface (necessary)
+	Impl          ExporterPlugin // The real implementation is injected into this variable
+}
+
+func (p *ExporterGRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error { // Used on plugin
+	proto.RegisterExporterPluginServer(s, &ExporterPluginGRPCServer{Impl: p.Impl})
+	return nil
+}
+
+func (ExporterGRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) { // Used on host
+	return &ExporterPluginGRPCClient{client: proto.NewExporterPluginClient(c)}, nil
+}
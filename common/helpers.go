@@ -22,10 +22,14 @@ import (
 	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 const ActiveClusterStatus = 1
@@ -80,20 +84,104 @@ func GetPluginIdComponents(pluginId string) (testName, testNs, podName, podNs st
 	return comp[0], comp[1], comp[2], comp[3], nil
 }
 
+// secretRefNameRegex matches the names of secret refs, they're keys of the config templates ({{ .Secrets.<name> }})
+var secretRefNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateSecretRefs Checks the secret refs of a test have unique names and reference a key of a secret
+func ValidateSecretRefs(refs []*proto.SecretRef) error {
+	names := map[string]bool{}
+	for i, ref := range refs {
+		if !secretRefNameRegex.MatchString(ref.Name) {
+			return fmt.Errorf("secretRefs[%d]: name '%s' must be a letter or '_' followed by letters, digits or '_'", i, ref.Name)
+		}
+		if names[ref.Name] {
+			return fmt.Errorf("secretRefs[%d]: duplicate name '%s'", i, ref.Name)
+		}
+		names[ref.Name] = true
+		if msgs := validation.IsDNS1123Subdomain(ref.Secret); len(msgs) > 0 {
+			return fmt.Errorf("secretRefs[%d]: invalid secret '%s': %s", i, ref.Secret, msgs[0])
+		}
+		if msgs := validation.IsConfigMapKey(ref.Key); len(msgs) > 0 {
+			return fmt.Errorf("secretRefs[%d]: invalid key '%s': %s", i, ref.Key, msgs[0])
+		}
+		if msgs := validation.IsDNS1123Label(ref.Namespace); ref.Namespace != "" && len(msgs) > 0 {
+			return fmt.Errorf("secretRefs[%d]: invalid namespace '%s': %s", i, ref.Namespace, msgs[0])
+		}
+	}
+	return nil
+}
+
+// SecretRefNamespace Returns the namespace of the secret referenced by a test, namespaced tests can only reference the
+// secrets of their namespace, and cluster tests must set the namespace of the secret
+func SecretRefNamespace(ref *proto.SecretRef, testNamespace string) (string, error) {
+	if ref.Namespace == "" {
+		if testNamespace == ClusterTestNamespace {
+			return "", fmt.Errorf("secret ref '%s' of a cluster test must set the namespace of the secret", ref.Name)
+		}
+		return testNamespace, nil
+	}
+	if testNamespace != ClusterTestNamespace && ref.Namespace != testNamespace {
+		return "", fmt.Errorf("secret ref '%s' isn't in the namespace of the test", ref.Name)
+	}
+	return ref.Namespace, nil
+}
+
+// ImportanceWeight Returns the weight of the importance level (when computing health scores)
+func ImportanceWeight(importance string) float64 {
+	if w, ok := ImportanceWeights[strings.ToLower(importance)]; ok {
+		return w
+	}
+	return ImportanceWeights[ImportanceLow]
+}
+
+// ImportanceLevel Returns the importance level of a test for its metrics and alerts (lower case), the default if the
+// test doesn't set a known one
+func ImportanceLevel(importance string) string {
+	importance = strings.ToLower(importance)
+	if _, ok := ImportanceWeights[importance]; !ok {
+		return DefaultImportance
+	}
+	return importance
+}
+
+// IsActive Returns whether the silence is active at the given time
+func (s Silence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Matches Returns whether the silence matches the test running on the agent
+func (s Silence) Matches(testConfig *proto.SynTestConfig, agentId string) bool {
+	if len(s.TestNamespaces) > 0 && !slices.Contains(s.TestNamespaces, testConfig.Namespace) {
+		return false
+	}
+	if len(s.TestNames) > 0 && !slices.Contains(s.TestNames, testConfig.Name) {
+		return false
+	}
+	if len(s.Agents) > 0 && !slices.Contains(s.Agents, agentId) {
+		return false
+	}
+	for k, v := range s.TestLabels {
+		if testConfig.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
 // ComputeAgentId Computes agent id: it's just a string representing the pod name & namespace
 func ComputeAgentId(podName string, namespace string) string {
 	return podName + "/" + namespace
 }
 
 // IsAgentValidForSynTest checks if the agent matches the selectors in the SynTest
-func IsAgentValidForSynTest(agentConfig AgentConfig, agentId, testName, testNs, testNodeSelector string,
+func IsAgentValidForSynTest(agentConfig AgentConfig, agentId, testName, testNs, testPlugin, testNodeSelector string,
 	testPodLabelSelector, testLabels map[string]string, logger hclog.Logger) (bool, error) {
 
 	logger.Debug("checking agent selector for syntest", "testName", testName, "testNs", testNs,
 		"agentId", agentId, "nodeSelector", testNodeSelector, "podLabelSelector", testPodLabelSelector)
 
-	// if watchNamespaceSet is not empty, then check if the namespace is in the set
-	if len(agentConfig.MatchNamespaceSet) > 0 {
+	// if watchNamespaceSet is not empty, then check if the namespace is in the set (cluster tests aren't in any namespace)
+	if len(agentConfig.MatchNamespaceSet) > 0 && testNs != ClusterTestNamespace {
 		if ok := agentConfig.MatchNamespaceSet[testNs]; !ok {
 			logger.Debug("syntest not in a 'watched' namespace, ignoring syntest...", "test", testName, "testNs", testNs, "watchedNs", agentConfig.MatchNamespaceSet)
 			return false, nil
@@ -123,51 +211,135 @@ func IsAgentValidForSynTest(agentConfig AgentConfig, agentId, testName, testNs,
 	}
 
 	// if podLabelSelector is not empty, then check if the selector matches the pod labels for the agent
-	if len(testPodLabelSelector) > 0 {
-		for k, v := range testPodLabelSelector {
-			// check for special labels (like agentId, agentNs, podName) match
-			if k == SpecialKeyAgentId && v == agentId {
+	matchesPod, err := matchesPodLabelSelector(agentConfig, agentId, testPodLabelSelector, logger)
+	if err != nil || !matchesPod {
+		return false, err
+	}
+
+	// check if the agent runs tests of the plugin
+	if !IsPluginAllowed(agentConfig, testPlugin) {
+		logger.Debug("syntest plugin not allowed on agent, ignoring syntest...", "plugin", testPlugin, "allowedPlugins", agentConfig.AllowedPlugins)
+		return false, nil
+	}
+
+	// everything matches
+	return true, nil
+}
+
+// matchesPodLabelSelector checks if the pod label selector (which can have special keys like agentId, agentNs, podName) matches the agent
+func matchesPodLabelSelector(agentConfig AgentConfig, agentId string, podLabelSelector map[string]string, logger hclog.Logger) (bool, error) {
+	for k, v := range podLabelSelector {
+		// check for special labels (like agentId, agentNs, podName) match
+		if k == SpecialKeyAgentId && v == agentId {
+			continue
+		}
+		if k == SpecialKeyAgentNs {
+			matchesAgentNs, err := filepath.Match(v, agentConfig.RunTimeInfo.AgentNamespace)
+			if err != nil {
+				return false, errors.Wrap(err, "error matching "+SpecialKeyAgentNs+" in podLabelSelector")
+			}
+			if matchesAgentNs {
 				continue
 			}
-			if k == SpecialKeyAgentNs {
-				matchesAgentNs, err := filepath.Match(v, agentConfig.RunTimeInfo.AgentNamespace)
-				if err != nil {
-					return false, errors.Wrap(err, "error matching "+SpecialKeyAgentNs+" in podLabelSelector")
-				}
-				if matchesAgentNs {
-					continue
-				}
+		}
+		if k == SpecialKeyPodName {
+			matchesPodName, err := filepath.Match(v, agentConfig.RunTimeInfo.PodName)
+			if err != nil {
+				return false, errors.Wrap(err, "error matching "+SpecialKeyPodName+" in podLabelSelector")
+			}
+			if matchesPodName {
+				continue
 			}
-			if k == SpecialKeyPodName {
-				matchesPodName, err := filepath.Match(v, agentConfig.RunTimeInfo.PodName)
-				if err != nil {
-					return false, errors.Wrap(err, "error matching "+SpecialKeyPodName+" in podLabelSelector")
-				}
-				if matchesPodName {
-					continue
-				}
+		}
+		// check if the labels match
+		if val, ok := agentConfig.RunTimeInfo.PodLabels[k]; ok {
+			matchesPodLabel, err := filepath.Match(v, val)
+			if err != nil {
+				return false, errors.Wrap(err, "error matching podLabelSelector")
 			}
-			// check if the labels match
-			if val, ok := agentConfig.RunTimeInfo.PodLabels[k]; ok {
-				matchesPodLabel, err := filepath.Match(v, val)
-				if err != nil {
-					return false, errors.Wrap(err, "error matching podLabelSelector")
-				}
-				if !matchesPodLabel {
-					logger.Debug("syntest podLabelSelector value not did not match, ignoring syntest...", "key", k, "selectorVal", v, "podLabelVal", val)
-					return false, nil
-				}
-			} else {
-				logger.Debug("syntest podLabelSelector key not found in pod, ignoring syntest...", "selector", testPodLabelSelector, "podLabels", agentConfig.RunTimeInfo.PodLabels)
+			if !matchesPodLabel {
+				logger.Debug("syntest podLabelSelector value not did not match, ignoring syntest...", "key", k, "selectorVal", v, "podLabelVal", val)
 				return false, nil
 			}
+		} else {
+			logger.Debug("syntest podLabelSelector key not found in pod, ignoring syntest...", "selector", podLabelSelector, "podLabels", agentConfig.RunTimeInfo.PodLabels)
+			return false, nil
 		}
 	}
-
-	// everything matches
 	return true, nil
 }
 
+// IsPluginAllowed checks if the agent runs tests of the plugin (i.e. it's in the allowed plugins, if any)
+func IsPluginAllowed(agentConfig AgentConfig, plugin string) bool {
+	return len(agentConfig.AllowedPlugins) == 0 || slices.Contains(agentConfig.AllowedPlugins, plugin)
+}
+
+// Matches checks if the agent profile selectors match the agent
+func (p AgentProfile) Matches(agentConfig AgentConfig, agentId string, logger hclog.Logger) (bool, error) {
+	if p.NodeSelector != "" {
+		matchesNode, err := filepath.Match(p.NodeSelector, agentConfig.RunTimeInfo.NodeName)
+		if err != nil {
+			return false, errors.Wrap(err, "error matching nodeSelector")
+		}
+		if !matchesNode {
+			return false, nil
+		}
+	}
+	return matchesPodLabelSelector(agentConfig, agentId, p.PodLabelSelector, logger)
+}
+
+// SelectAgentProfile Returns the matching agent profile with the highest priority (by name if equal), false if none match
+func SelectAgentProfile(profiles map[string]AgentProfile, agentConfig AgentConfig, agentId string, logger hclog.Logger) (AgentProfile, bool) {
+	selected := AgentProfile{}
+	found := false
+	for _, profile := range profiles {
+		ok, err := profile.Matches(agentConfig, agentId, logger)
+		if err != nil {
+			logger.Warn("error matching agent profile, ignoring it", "profile", profile.Name, "err", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !found || profile.Priority > selected.Priority || (profile.Priority == selected.Priority && profile.Name < selected.Name) {
+			selected = profile
+			found = true
+		}
+	}
+	return selected, found
+}
+
+// Apply Returns the agent config with the settings of the profile applied
+func (p AgentProfile) Apply(agentConfig AgentConfig) AgentConfig {
+	agentConfig.AgentProfile = p.Name
+	if p.SyncFrequency > 0 {
+		agentConfig.SyncFrequency = p.SyncFrequency
+	}
+	if len(p.AllowedPlugins) > 0 {
+		agentConfig.AllowedPlugins = p.AllowedPlugins
+	}
+	if p.Prometheus != nil {
+		labels := map[string]string{}
+		for k, v := range agentConfig.PrometheusConfig.Labels {
+			labels[k] = v
+		}
+		for k, v := range p.Prometheus.Labels {
+			labels[k] = v
+		}
+		agentConfig.PrometheusConfig.Labels = labels
+		if len(p.Prometheus.TestLabelKeys) > 0 {
+			agentConfig.PrometheusConfig.TestLabelKeys = p.Prometheus.TestLabelKeys
+		}
+		if p.Prometheus.StalenessWindow > 0 {
+			agentConfig.PrometheusConfig.StalenessWindow = p.Prometheus.StalenessWindow
+		}
+		if p.Prometheus.MaxLabelValues != 0 {
+			agentConfig.PrometheusConfig.MaxLabelValues = p.Prometheus.MaxLabelValues
+		}
+	}
+	return agentConfig
+}
+
 // TestDetailsLogger is a simple helper struct to output logs in the test result details
 type TestDetailsLogger struct {
 	Res       proto.TestResult
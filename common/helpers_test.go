@@ -0,0 +1,85 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package common
+
+import (
+	"testing"
+
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+func TestValidateSecretRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		refs    []*proto.SecretRef
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", []*proto.SecretRef{
+			{Name: "password", Secret: "db-creds", Key: "password"},
+			{Name: "_token2", Secret: "api.creds", Key: "token.txt", Namespace: "payments"},
+		}, false},
+		{"name starting with a digit", []*proto.SecretRef{{Name: "1password", Secret: "db-creds", Key: "password"}}, true},
+		{"name with a dash", []*proto.SecretRef{{Name: "bind-password", Secret: "db-creds", Key: "password"}}, true},
+		{"empty name", []*proto.SecretRef{{Secret: "db-creds", Key: "password"}}, true},
+		{"duplicate name", []*proto.SecretRef{
+			{Name: "password", Secret: "db-creds", Key: "password"},
+			{Name: "password", Secret: "other-creds", Key: "password"},
+		}, true},
+		{"invalid secret", []*proto.SecretRef{{Name: "password", Secret: "DB_creds", Key: "password"}}, true},
+		{"empty key", []*proto.SecretRef{{Name: "password", Secret: "db-creds"}}, true},
+		{"invalid key", []*proto.SecretRef{{Name: "password", Secret: "db-creds", Key: "pass/word"}}, true},
+		{"invalid namespace", []*proto.SecretRef{{Name: "password", Secret: "db-creds", Key: "password", Namespace: "pay.ments"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSecretRefs(tt.refs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSecretRefs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretRefNamespace(t *testing.T) {
+	tests := []struct {
+		name          string
+		refNamespace  string
+		testNamespace string
+		want          string
+		wantErr       bool
+	}{
+		{"namespaced test, namespace of the test", "", "payments", "payments", false},
+		{"namespaced test, same namespace", "payments", "payments", "payments", false},
+		{"namespaced test, other namespace", "kube-system", "payments", "", true},
+		{"namespaced test, cluster namespace", ClusterTestNamespace, "payments", "", true},
+		{"cluster test, namespace set", "payments", ClusterTestNamespace, "payments", false},
+		{"cluster test, namespace not set", "", ClusterTestNamespace, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := &proto.SecretRef{Name: "password", Secret: "db-creds", Key: "password", Namespace: tt.refNamespace}
+			got, err := SecretRefNamespace(ref, tt.testNamespace)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SecretRefNamespace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("SecretRefNamespace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -30,3 +30,15 @@ type SynTestPlugin interface {
 	// Called once at the end, before the plugin is killed
 	Finish() error
 }
+
+// The interface all exporter plugins must implement
+type ExporterPlugin interface {
+	// Called once at the start when plugin is run
+	Initialise(config proto.ExporterConfig) error
+
+	// Called for every test run broadcasted by the agent
+	Export(testRun proto.TestRun) error
+
+	// Called once at the end, before the plugin is killed
+	Finish() error
+}
@@ -16,7 +16,10 @@
 
 package common
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 /*
  * Any Go structs shared between different components go here
@@ -46,15 +49,32 @@ type AgentConfig struct {
 	SyncFrequency       time.Duration           `yaml:"syncFrequency" json:"syncFrequency"`
 	GracePeriod         time.Duration           `yaml:"gracePeriod" json:"gracePeriod"`
 	PrometheusConfig    PrometheusConfig        `yaml:"prometheus" json:"prometheusConfig"`
+	OtelConfig          OtelConfig              `yaml:"otel" json:"otelConfig"`
+	StatsdConfig        StatsdConfig            `yaml:"statsd" json:"statsdConfig"`
+	CloudWatchConfig    CloudWatchConfig        `yaml:"cloudWatch" json:"cloudWatchConfig"`
+	DatadogConfig       DatadogConfig           `yaml:"datadog" json:"datadogConfig"`
+	CloudEventsConfig   CloudEventsConfig       `yaml:"cloudEvents" json:"cloudEventsConfig"`
+	AlertmanagerConfig  AlertmanagerConfig      `yaml:"alertmanager" json:"alertmanagerConfig"`
+	SlackConfig         SlackConfig             `yaml:"slack" json:"slackConfig"`
+	PagerDutyConfig     PagerDutyConfig         `yaml:"pagerDuty" json:"pagerDutyConfig"`
+	Webhooks            []WebhookConfig         `yaml:"webhooks" json:"webhooks"`
+	ExporterPlugins     []ExporterPluginConfig  `yaml:"exporterPlugins" json:"exporterPlugins"`
+	FlapDetection       FlapDetectionConfig     `yaml:"flapDetection" json:"flapDetection"`
 	StoreConfig         StorageConfig           `yaml:"storage" json:"storeConfig"`
 	PrintPluginLogs     PrintPluginLogOption    `yaml:"printPluginLogs" json:"printPluginLogs"`
+	RecordingConfig     RecordingConfig         `yaml:"recording" json:"recordingConfig"`
+	ConfigExpansion     ConfigExpansionConfig   `yaml:"configExpansion" json:"configExpansion"`
+	Secrets             SecretsConfig           `yaml:"secrets" json:"secrets"`
 	EnabledPlugins      []PluginDiscoveryConfig `yaml:"enabledPlugins" json:"enabledPlugins"`
 	DebugMode           bool                    `yaml:"debugMode" json:"debugMode"`
+	AllowedPlugins      []string                `yaml:"allowedPlugins" json:"allowedPlugins"` // plugins the agent runs tests of (empty means all)
 
 	// Populated at run time
-	DiscoveredPlugins map[string][]string `json:"discoveredPlugins"`
-	RunTimeInfo       AgentInfo           `json:"runTimeInfo"`
-	MatchNamespaceSet map[string]bool     `json:"matchNamespaceSet"` // so we can check if a namespace is being watched in O(1)
+	DiscoveredPlugins   map[string][]string `json:"discoveredPlugins"`
+	DiscoveredExporters map[string][]string `json:"discoveredExporters"`
+	RunTimeInfo         AgentInfo           `json:"runTimeInfo"`
+	MatchNamespaceSet   map[string]bool     `json:"matchNamespaceSet"` // so we can check if a namespace is being watched in O(1)
+	AgentProfile        string              `json:"agentProfile"`      // name of the agent profile applied to the config
 }
 
 type PluginDiscoveryConfig struct {
@@ -67,6 +87,7 @@ type AgentInfo struct {
 	PodName        string            `json:"podName"`        // derived from Downward api
 	PodLabels      map[string]string `json:"podLabels"`      // derived from Downward api
 	AgentNamespace string            `json:"agentNamespace"` // derived from Downward api
+	Version        string            `json:"version"`        // version of the agent binary
 }
 
 type SyntestConfigSummary struct {
@@ -78,6 +99,143 @@ type SyntestConfigSummary struct {
 	Description string `json:"description"`
 	Plugin      string `json:"plugin"`
 	Repeat      string `json:"repeat"`
+	Importance  string `json:"importance,omitempty"`
+	// Source of the config if it isn't a CRD, e.g. git (see ConfigSourceLabel)
+	Source string `json:"source,omitempty"`
+	// Canary is the new version of the config being rolled out to some agents first (nil if there's none)
+	Canary *SyntestCanary `json:"canary,omitempty"`
+}
+
+// SyntestCanary is a new version of a syntest config which only runs on the canary agents, till it's promoted (or
+// rolled back if it fails on them)
+type SyntestCanary struct {
+	Version   string    `json:"version"`
+	Agents    []string  `json:"agents"` // agents running the canary version instead of the stable one
+	StartedAt time.Time `json:"startedAt"`
+	Failed    bool      `json:"failed,omitempty"` // the canary was rolled back, all agents run the stable version
+	Message   string    `json:"message,omitempty"`
+}
+
+// SyntestConfigVersion is a version of a syntest config kept in the config history, so it can be rolled back to
+type SyntestConfigVersion struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`   // when the version was written
+	Raw     string    `json:"raw"`    // raw config (the spec of the CRD)
+	Config  string    `json:"config"` // json of the config stored for the agents
+}
+
+// AuditEvent is an entry of the audit log: changes to syntest configs and lifecycle events of the plugins
+type AuditEvent struct {
+	Time    time.Time         `json:"time"`
+	Source  string            `json:"source"`          // component that recorded the event: controller or the agent id
+	Actor   string            `json:"actor,omitempty"` // who made the change (e.g. the kubernetes field manager), if known
+	Kind    string            `json:"kind"`            // kind of the object (syntest, plugin)
+	Object  string            `json:"object"`          // id of the object (test config id, plugin id)
+	Action  string            `json:"action"`
+	Message string            `json:"message,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// TestRunResult is a test run result in the test run history (used to compute SLOs and the results over time)
+type TestRunResult struct {
+	Time      time.Time     `json:"time"`
+	PassRatio float64       `json:"passRatio"`
+	Runtime   time.Duration `json:"runtime,omitempty"` // 0 if unknown
+}
+
+// TestRunRequest is a request (from a SynTestRun or the rest api) to run a test once, immediately, on the agents running it. The agents
+// write the result of the run under the id of the request.
+type TestRunRequest struct {
+	Id       string    `json:"id"`
+	ConfigId string    `json:"configId"`         // id of the test config to run
+	Time     time.Time `json:"time"`             // when the run was requested
+	Agents   []string  `json:"agents,omitempty"` // ids of the agents to run it on (empty means all the agents running it)
+}
+
+// Silence suppresses the notifications of the matching tests between StartsAt and EndsAt (e.g. a maintenance window),
+// the tests still run and their results are recorded
+type Silence struct {
+	Id             string            `json:"id"`
+	Comment        string            `json:"comment"`
+	CreatedBy      string            `json:"createdBy"`
+	StartsAt       time.Time         `json:"startsAt"`
+	EndsAt         time.Time         `json:"endsAt"`
+	TestNamespaces []string          `json:"testNamespaces,omitempty"` // namespaces of the tests (empty means all)
+	TestNames      []string          `json:"testNames,omitempty"`      // names of the tests (empty means all)
+	TestLabels     map[string]string `json:"testLabels,omitempty"`     // labels the tests must have
+	Agents         []string          `json:"agents,omitempty"`         // ids of the agents (empty means all)
+}
+
+// AgentProfile is agent level config (from an AgentProfile CRD) pushed by the controller via storage,
+// the agents matching its selectors apply it on top of their config file
+type AgentProfile struct {
+	Name             string             `json:"name"`
+	Priority         int32              `json:"priority"`                   // the matching profile with the highest priority is applied
+	NodeSelector     string             `json:"nodeSelector,omitempty"`     // glob matching the node name of the agent
+	PodLabelSelector map[string]string  `json:"podLabelSelector,omitempty"` // same as the podLabelSelector of syntests
+	SyncFrequency    time.Duration      `json:"syncFrequency,omitempty"`
+	LogLevel         string             `json:"logLevel,omitempty"`
+	Prometheus       *PrometheusProfile `json:"prometheus,omitempty"`
+	AllowedPlugins   []string           `json:"allowedPlugins,omitempty"`
+}
+
+// PrometheusProfile is the prometheus config which can be set by an agent profile
+type PrometheusProfile struct {
+	Labels          map[string]string `json:"labels,omitempty"` // added to the labels in the config file
+	TestLabelKeys   []string          `json:"testLabelKeys,omitempty"`
+	StalenessWindow time.Duration     `json:"stalenessWindow,omitempty"`
+	MaxLabelValues  int               `json:"maxLabelValues,omitempty"`
+}
+
+// ClusterSummary is the summary of the latest results of the tests of a cluster, replicated by its controller to the
+// central storage of a federation
+type ClusterSummary struct {
+	Cluster     string                `json:"cluster"`
+	Time        time.Time             `json:"time"`                  // when the summary was written
+	HealthScore *float64              `json:"healthScore,omitempty"` // nil if there are no results
+	Agents      int                   `json:"agents"`                // active agents of the cluster
+	Tests       []FederatedTestResult `json:"tests"`
+}
+
+// ControllerHeartbeat is written by the controller every time it checks the agents, with the agents it expects (the
+// running agent pods), so the health of the whole installation can be checked from storage
+type ControllerHeartbeat struct {
+	Time           time.Time `json:"time"`
+	ExpectedAgents int       `json:"expectedAgents"`        // agents with a running pod
+	LiveAgents     int       `json:"liveAgents"`            // expected agents which posted a status within the deadline
+	StaleAgents    []string  `json:"staleAgents,omitempty"` // expected agents which didn't
+}
+
+// SynTestQuota are the limits a SynTestQuota of the controller puts on the tests of its namespace
+type SynTestQuota struct {
+	Name       string `json:"name"`
+	MaxTests   int32  `json:"maxTests,omitempty"`   // max number of tests in the namespace, 0 means no limit
+	MinRepeat  string `json:"minRepeat,omitempty"`  // shortest repeat interval of the tests, e.g. "1m"
+	MaxTimeout string `json:"maxTimeout,omitempty"` // longest init, run and finish timeout of the tests, e.g. "5m"
+}
+
+// NamespacePolicies are the SynTestQuotas of the namespaces and the namespaces watched by the controller, written
+// periodically by the controller (which reads them from kubernetes), so the rest api enforces them on the tests written
+// through it
+type NamespacePolicies struct {
+	Time     time.Time                 `json:"time"`
+	Quotas   map[string][]SynTestQuota `json:"quotas,omitempty"`   // by namespace
+	Filtered bool                      `json:"filtered,omitempty"` // whether only the watched namespaces are deployed
+	Watched  []string                  `json:"watched,omitempty"`  // namespaces whose tests are deployed, if filtered
+}
+
+// FederatedTestResult is the summary of the latest results of a test on all the agents of a cluster
+type FederatedTestResult struct {
+	Cluster            string    `json:"cluster"`
+	Name               string    `json:"name"`
+	Namespace          string    `json:"namespace"`
+	Plugin             string    `json:"plugin"`
+	Importance         string    `json:"importance,omitempty"`
+	PassingAgents      int32     `json:"passingAgents"`
+	FailingAgents      int32     `json:"failingAgents"`
+	LastRunTime        time.Time `json:"lastRunTime,omitempty"`
+	LastFailureTime    time.Time `json:"lastFailureTime,omitempty"`
+	LastFailureMessage string    `json:"lastFailureMessage,omitempty"`
 }
 
 type SyntestConfigStatus struct {
@@ -88,10 +246,74 @@ type SyntestConfigStatus struct {
 }
 
 type StorageConfig struct {
-	Type       string        `yaml:"type"`
-	BufferSize int           `yaml:"bufferSize"`
-	Address    string        `yaml:"address"`
-	ExportRate time.Duration `yaml:"exportRate"`
+	Type       string                    `yaml:"type"`
+	BufferSize int                       `yaml:"bufferSize"`
+	Address    string                    `yaml:"address"`
+	ExportRate time.Duration             `yaml:"exportRate"`
+	PluginLogs PluginLogForwardingConfig `yaml:"pluginLogs"`
+}
+
+// PluginLogForwardingConfig configures forwarding the logs of test runs to external storage (keyed by plugin id and run id)
+type PluginLogForwardingConfig struct {
+	Enabled bool                 `yaml:"enabled" json:"enabled"`
+	When    PrintPluginLogOption `yaml:"when" json:"when"`   // forward logs of every run (always) or failed runs only (onFail, default)
+	Lines   int                  `yaml:"lines" json:"lines"` // last N lines of the run (default 200)
+	Ttl     time.Duration        `yaml:"ttl" json:"ttl"`     // how long the logs are kept (default 24h)
+}
+
+// RecordingConfig configures recording the gRPC interaction of the syntest plugins in test runs, to replay them later
+type RecordingConfig struct {
+	Enabled    bool                 `yaml:"enabled" json:"enabled"`
+	Dir        string               `yaml:"dir" json:"dir"`               // directory the recordings are written to
+	Tests      []string             `yaml:"tests" json:"tests"`           // config ids (name/namespace) or globs of the recorded tests (all if empty)
+	When       PrintPluginLogOption `yaml:"when" json:"when"`             // record every run (always) or failed runs only (onFail, default)
+	MaxPerTest int                  `yaml:"maxPerTest" json:"maxPerTest"` // recordings kept per test, the oldest are deleted (default 20)
+}
+
+// ConfigExpansionConfig configures the expansion of env vars (${NAME}) and go templates in the configs of the tests,
+// done by the agent when the plugin of a test starts
+type ConfigExpansionConfig struct {
+	Enabled    bool              `yaml:"enabled" json:"enabled"`
+	AllowedEnv []string          `yaml:"allowedEnv" json:"allowedEnv"` // env vars (or globs, e.g. SYNHEART_*) the configs can use
+	Vars       map[string]string `yaml:"vars" json:"vars"`             // values for the templates, e.g. {{ .Vars.region }}
+}
+
+// SecretsConfig configures how the agent reads the keys of the kubernetes secrets referenced by the tests (secretRefs),
+// which are rendered into their configs when the plugin starts
+type SecretsConfig struct {
+	MountPath string `yaml:"mountPath" json:"mountPath"` // secrets of the agent's namespace mounted at <mountPath>/<secret>/<key>
+	Api       bool   `yaml:"api" json:"api"`             // read the secrets which aren't mounted with the kubernetes api
+}
+
+// TestRunRecording is the gRPC interaction of a syntest plugin in a test run: the calls of the agent with their
+// responses, and the logs of the plugin, timed from the start of the run
+type TestRunRecording struct {
+	AgentId     string          `json:"agentId"`
+	RunTimeInfo AgentInfo       `json:"runTimeInfo"`
+	TestRunId   string          `json:"testRunId"` // empty if the plugin didn't initialise
+	RecordedAt  time.Time       `json:"recordedAt"`
+	Failed      bool            `json:"failed"`
+	Config      json.RawMessage `json:"config"`  // the test config (protojson)
+	Trigger     json.RawMessage `json:"trigger"` // the trigger of the run (protojson)
+	Calls       []RecordedCall  `json:"calls"`
+	Logs        []RecordedLog   `json:"logs"`
+}
+
+// RecordedCall is a call of the agent to a syntest plugin, the request and response are protojson
+type RecordedCall struct {
+	Method   string          `json:"method"` // Initialise, PerformTest or Finish
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Offset   time.Duration   `json:"offset"`             // since the start of the run
+	Duration time.Duration   `json:"duration"`           // how long the call took
+	NoReturn bool            `json:"noReturn,omitempty"` // the call hadn't returned when the run ended (e.g. timed out)
+}
+
+// RecordedLog is a write of a syntest plugin to its logs
+type RecordedLog struct {
+	Offset time.Duration `json:"offset"` // since the start of the run
+	Data   string        `json:"data"`
 }
 
 type PrometheusConfig struct {
@@ -99,6 +321,115 @@ type PrometheusConfig struct {
 	Push              bool              `yaml:"push"`
 	PrometheusPushUrl string            `yaml:"pushUrl"`
 	Labels            map[string]string `yaml:"labels"`
+
+	PushJob          string        `yaml:"pushJob"`          // job name to push under (agent is added as the instance label), defaults to the agent id
+	PushInterval     time.Duration `yaml:"pushInterval"`     // push periodically instead of after every test run
+	PushUsername     string        `yaml:"pushUsername"`     // basic auth for the push gateway
+	PushPasswordEnv  string        `yaml:"pushPasswordEnv"`  // env var containing the basic auth password
+	PushDeleteOnExit bool          `yaml:"pushDeleteOnExit"` // delete the pushed metrics when the agent exits
+
+	Histogram PrometheusHistogramConfig `yaml:"histogram"` // the test runtime histogram
+
+	StalenessWindow time.Duration `yaml:"stalenessWindow"` // remove series not updated within this window (0 means reset all series on config change)
+	MaxLabelValues  int           `yaml:"maxLabelValues"`  // max distinct values per label of custom metrics (default 100, -1 to disable)
+	TestLabelKeys   []string      `yaml:"testLabelKeys"`   // keys of the test's metricLabels to add to all metrics of the test
+}
+
+type PrometheusHistogramConfig struct {
+	Type               string    `yaml:"type"`               // classic, native or both (default classic)
+	Buckets            []float64 `yaml:"buckets"`            // classic buckets in seconds
+	NativeBucketFactor float64   `yaml:"nativeBucketFactor"` // growth factor between native buckets (default 1.1)
+	NativeMaxBuckets   uint32    `yaml:"nativeMaxBuckets"`   // max number of native buckets before the resolution is reduced (default 160)
+}
+
+type OtelConfig struct {
+	Endpoint    string            `yaml:"endpoint"` // OTLP (grpc) endpoint, e.g. otel-collector:4317
+	Insecure    bool              `yaml:"insecure"`
+	Headers     map[string]string `yaml:"headers"`
+	ServiceName string            `yaml:"serviceName"`
+	Traces      bool              `yaml:"traces"` // export a span for every test run
+
+	Metrics         bool          `yaml:"metrics"`         // export the test run metrics (same as the prometheus metrics)
+	MetricsInterval time.Duration `yaml:"metricsInterval"` // how often to push metrics to the endpoint
+}
+
+type StatsdConfig struct {
+	Address       string            `yaml:"address"`       // udp address of the statsd server (disabled if empty)
+	Prefix        string            `yaml:"prefix"`        // prefix for all the metric names
+	DogStatsd     bool              `yaml:"dogStatsd"`     // whether to use DogStatsD tags (otherwise the test name/namespace are part of the metric name)
+	Tags          map[string]string `yaml:"tags"`          // extra tags to add to every metric (DogStatsD only)
+	FlushInterval time.Duration     `yaml:"flushInterval"` // how often to send plugin restart metrics
+}
+
+type CloudWatchConfig struct {
+	Namespace     string            `yaml:"namespace"`     // CloudWatch namespace to publish to (disabled if empty)
+	Region        string            `yaml:"region"`        // AWS region, defaults to the region from the environment
+	Dimensions    map[string]string `yaml:"dimensions"`    // extra dimensions, values can be templates (same as prometheus labels)
+	FlushInterval time.Duration     `yaml:"flushInterval"` // how often to publish the buffered metrics
+}
+
+type DatadogConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	Site          string            `yaml:"site"`          // datadog site, e.g. datadoghq.com, datadoghq.eu
+	ApiKeyEnv     string            `yaml:"apiKeyEnv"`     // env var containing the api key (mounted from a secret)
+	Tags          map[string]string `yaml:"tags"`          // extra tags to add to every metric and event
+	TestLabels    []string          `yaml:"testLabels"`    // test labels to add as tags (empty means all labels)
+	FlushInterval time.Duration     `yaml:"flushInterval"` // how often to send the buffered metrics and check the agent health
+}
+
+type CloudEventsConfig struct {
+	Sink       string            `yaml:"sink"`       // url to post the events to, e.g. a knative broker (disabled if empty)
+	Source     string            `yaml:"source"`     // source attribute of the events, defaults to /synthetic-heart/agent/<agentId>
+	Mode       string            `yaml:"mode"`       // content mode: binary (default) or structured
+	Headers    map[string]string `yaml:"headers"`    // extra http headers, e.g. for authentication
+	OnlyFailed bool              `yaml:"onlyFailed"` // only send events for failed test runs
+	Timeout    time.Duration     `yaml:"timeout"`    // timeout of the http request
+}
+
+type AlertmanagerConfig struct {
+	Url              string            `yaml:"url"`              // alertmanager url, e.g. http://alertmanager:9093 (disabled if empty)
+	FailureThreshold int               `yaml:"failureThreshold"` // consecutive failed runs before an alert fires (tests can override it)
+	Labels           map[string]string `yaml:"labels"`           // extra labels added to every alert
+	ResendInterval   time.Duration     `yaml:"resendInterval"`   // how often firing alerts are re-sent (must be less than alertmanager's resolve_timeout)
+	GeneratorUrl     string            `yaml:"generatorUrl"`     // link added to the alerts, e.g. the synthetic heart ui
+}
+
+type SlackConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	WebhookUrlEnv string `yaml:"webhookUrlEnv"` // env var containing the incoming webhook url (mounted from a secret)
+	Channel       string `yaml:"channel"`       // channel to notify (the webhook's default channel if empty), tests can override it
+	Template      string `yaml:"template"`      // go template of the message
+}
+
+type PagerDutyConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	RoutingKeyEnv string            `yaml:"routingKeyEnv"` // env var containing the events v2 integration (routing) key
+	Cluster       string            `yaml:"cluster"`       // name of the cluster, part of the dedup key (so incidents of different clusters are separate)
+	Importances   []string          `yaml:"importances"`   // importance of the tests that open incidents (empty means all)
+	Severities    map[string]string `yaml:"severities"`    // pagerduty severity per test importance (critical, error, warning or info)
+	Url           string            `yaml:"url"`           // events api url
+}
+
+type WebhookConfig struct {
+	Name       string            `yaml:"name"`
+	Url        string            `yaml:"url"`
+	Method     string            `yaml:"method"`     // http method (default POST)
+	Headers    map[string]string `yaml:"headers"`    // extra http headers
+	HeaderEnvs map[string]string `yaml:"headerEnvs"` // http headers with the value from an env var (e.g. a token mounted from a secret)
+	Template   string            `yaml:"template"`   // go template of the json payload
+	Events     []string          `yaml:"events"`     // transitions to notify on: fail, recover, flapping (default: all)
+}
+
+type ExporterPluginConfig struct {
+	Name   string `yaml:"name"`
+	Plugin string `yaml:"plugin"` // name of the exporter plugin (exporter-<plugin> binary)
+	Config string `yaml:"config"` // config passed to the plugin (yaml string)
+}
+
+type FlapDetectionConfig struct {
+	Window        int     `yaml:"window"`        // number of test runs to check for state changes (disabled if less than 2)
+	HighThreshold float64 `yaml:"highThreshold"` // ratio of state changes in the window at which a test starts flapping
+	LowThreshold  float64 `yaml:"lowThreshold"`  // ratio of state changes in the window at which a test stops flapping
 }
 
 type PrometheusMetrics struct {
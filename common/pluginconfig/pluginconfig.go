@@ -0,0 +1,308 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginconfig
+
+// package containing the configs of the built-in plugins, the plugins decode their config into these types and the
+// controller decodes the config of new tests strictly into them, so typos and unknown fields are rejected before the
+// test is stored
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configs returns a new config of each built-in plugin, plugins that aren't listed (e.g. python plugins) are only
+// checked to be valid yaml
+var configs = map[string]func() interface{}{
+	"browser":        func() interface{} { return &BrowserTestConfig{} },
+	"connMatrix":     func() interface{} { return &ConnMatrixTestConfig{} },
+	"curl":           func() interface{} { return &CurlTestConfig{} },
+	"dns":            func() interface{} { return &DNSTestConfig{} },
+	"egress":         func() interface{} { return &EgressTestConfig{} },
+	"httpPing":       func() interface{} { return &httpPingConfigs{} },
+	"ingress":        func() interface{} { return &IngressTestConfig{} },
+	"ldap":           func() interface{} { return &LDAPTestConfig{} },
+	"netDial":        func() interface{} { return &NetDialTestConfig{} },
+	"netPolicy":      func() interface{} { return &NetPolicyTestConfig{} },
+	"nodeLocal":      func() interface{} { return &NodeLocalTestConfig{} },
+	"nodePressure":   func() interface{} { return &NodePressureTestConfig{} },
+	"oidc":           func() interface{} { return &OIDCTestConfig{} },
+	"ping":           func() interface{} { return &PingTestConfig{} },
+	"portExhaustion": func() interface{} { return &PortExhaustionTestConfig{} },
+	"pvc":            func() interface{} { return &PVCTestConfig{} },
+	"registry":       func() interface{} { return &RegistryTestConfig{} },
+	"tlsScan":        func() interface{} { return &TLSScanTestConfig{} },
+}
+
+// Validate Decodes the config of a built-in plugin strictly, and returns the fields which are unknown or have the wrong
+// type. Configs with templates (secrets, env vars) are expanded by the agent, so only their unknown fields are
+// reported. Configs of other plugins aren't checked.
+func Validate(plugin, config string) []string {
+	newConfig, ok := configs[plugin]
+	if !ok {
+		return nil
+	}
+	err := yaml.UnmarshalStrict([]byte(config), newConfig())
+	if err == nil {
+		return nil
+	}
+	templated := strings.Contains(config, "{{") || strings.Contains(config, "${")
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		if templated {
+			return nil
+		}
+		return []string{err.Error()}
+	}
+	var errs []string
+	for _, msg := range typeErr.Errors {
+		if !templated || strings.Contains(msg, " not found in type ") {
+			errs = append(errs, msg)
+		}
+	}
+	return errs
+}
+
+type BrowserTestConfig struct {
+	Url                 string        `yaml:"url"`
+	WaitSelector        string        `yaml:"waitSelector"`        // css selector to wait for after the page loads
+	Timeout             time.Duration `yaml:"timeout"`             // default 30s
+	FailOnConsoleErrors bool          `yaml:"failOnConsoleErrors"` // fail the test if errors are logged to the console
+	ChromePath          string        `yaml:"chromePath"`          // path to the chromium binary, found in $PATH by default
+	UserAgent           string        `yaml:"userAgent"`
+	IgnoreCertErrors    bool          `yaml:"ignoreCertErrors"`
+}
+
+type ConnMatrixTestConfig struct {
+	SourceNamespace string               `yaml:"sourceNamespace"` // defaults to the namespace of the agent
+	Endpoints       []ConnMatrixEndpoint `yaml:"endpoints"`
+	Timeout         int                  `yaml:"timeout"` // Seconds, default = 3
+	Workers         int                  `yaml:"workers"`
+}
+
+type ConnMatrixEndpoint struct {
+	Namespace string `yaml:"namespace"` // destination namespace
+	Address   string `yaml:"addr"`      // host:port of the echo endpoint
+	Path      string `yaml:"path"`      // if set, a http GET is made to the path, otherwise only a tcp connection is made
+}
+
+type CurlTestConfig struct {
+	Url           string             `yaml:"url"`
+	OutputOptions []CurlOutputOption `yaml:"outputOptions"`
+}
+
+type CurlOutputOption struct {
+	Name             string `yaml:"name"`
+	PrometheusMetric bool   `yaml:"metric"`
+	PrometheusLabel  bool   `yaml:"label"`
+}
+
+type DNSTestConfig struct {
+	Domains []string `yaml:"domains"`
+	Workers int      `yaml:"workers"`
+	Repeats int      `yaml:"repeats"`
+
+	// Mode to run the test in - "" (default) resolves the domains using the system resolver, "nodeLocalCache" queries
+	// both the node-local dns cache and the cluster dns service directly, and compares the answers
+	Mode         string        `yaml:"mode"`
+	NodeLocalIP  string        `yaml:"nodeLocalIP"`  // ip of the node-local dns cache, default 169.254.20.10
+	ClusterDNSIP string        `yaml:"clusterDNSIP"` // ip of the cluster dns service (e.g. kube-dns)
+	Timeout      time.Duration `yaml:"timeout"`      // timeout for each query in nodeLocalCache mode, default 2s
+}
+
+type EgressTestConfig struct {
+	Domains           []string      `yaml:"domains"`           // external names that must resolve
+	Urls              []string      `yaml:"urls"`              // well-known endpoints that must respond
+	EchoUrl           string        `yaml:"echoUrl"`           // service that responds with the caller's ip in plain text
+	ExpectedEgressIPs []string      `yaml:"expectedEgressIPs"` // egress ip must be one of these (if set)
+	Timeout           time.Duration `yaml:"timeout"`           // default 5s
+}
+
+type HttpPingTestConfig struct {
+	Address           string `yaml:"address"`
+	ExpectedCodeRegex string `yaml:"expectedCodeRegex"`
+	MaxRetries        int    `yaml:"retries"`
+	MaxTimeoutRetry   int    `yaml:"timeoutRetries"`
+	RepeatWithoutFail int    `yaml:"repeatsWithoutFail"`
+	WaitBetweenRepeat string `yaml:"waitBetweenRepeats"`
+}
+
+// httpPingConfigs the httpPing plugin takes a list of configs, or a single config
+type httpPingConfigs []HttpPingTestConfig
+
+func (c *httpPingConfigs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw interface{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if _, ok := raw.([]interface{}); ok {
+		return unmarshal((*[]HttpPingTestConfig)(c))
+	}
+	single := HttpPingTestConfig{}
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+	*c = httpPingConfigs{single}
+	return nil
+}
+
+type IngressTestConfig struct {
+	Namespace          string        `yaml:"namespace"`          // namespace to discover in, all namespaces if empty
+	LabelSelector      string        `yaml:"labelSelector"`      // label selector for the Ingresses/HTTPRoutes
+	IncludeHTTPRoutes  bool          `yaml:"includeHTTPRoutes"`  // also discover Gateway API HTTPRoutes
+	Address            string        `yaml:"address"`            // connect to this address (host:port) instead of resolving the hostname
+	Path               string        `yaml:"path"`               // path to request, default "/"
+	ExpectedCodeRegex  string        `yaml:"expectedCodeRegex"`  // default ^[23]
+	Timeout            time.Duration `yaml:"timeout"`            // default 10s
+	InsecureSkipVerify bool          `yaml:"insecureSkipVerify"` // skip verifying the server certificate
+	Workers            int           `yaml:"workers"`
+}
+
+type LDAPTestConfig struct {
+	Servers []LDAPServer `yaml:"servers"`
+	Workers int          `yaml:"workers"`
+}
+
+type LDAPServer struct {
+	Url                string      `yaml:"url"`                // ldap://host:389 or ldaps://host:636
+	StartTLS           bool        `yaml:"startTLS"`           // upgrade a plain ldap:// connection with StartTLS
+	InsecureSkipVerify bool        `yaml:"insecureSkipVerify"` // skip verifying the server certificate
+	BindDN             string      `yaml:"bindDN"`             // leave empty for an anonymous bind
+	BindPassword       string      `yaml:"bindPassword"`
+	BindPasswordEnv    string      `yaml:"bindPasswordEnv"` // read the bind password from this env var instead
+	Timeout            int         `yaml:"timeout"`         // Seconds, default = 5
+	Search             *LDAPSearch `yaml:"search"`
+}
+
+type LDAPSearch struct {
+	BaseDN     string   `yaml:"baseDN"`
+	Filter     string   `yaml:"filter"`
+	Attributes []string `yaml:"attributes"`
+	MinResults int      `yaml:"minResults"`
+}
+
+type NetDialTestConfig struct {
+	Addresses []NetDialAddress
+	Workers   int
+}
+
+type NetDialAddress struct {
+	Network string `yaml:"net"`
+	Address string `yaml:"addr"`
+	Timeout int    `yaml:"timeout"`
+}
+
+type NetPolicyTestConfig struct {
+	Reachable []NetPolicyTarget `yaml:"reachable"` // targets that must be reachable
+	Blocked   []NetPolicyTarget `yaml:"blocked"`   // targets that must be blocked
+	Workers   int               `yaml:"workers"`
+}
+
+type NetPolicyTarget struct {
+	Network string `yaml:"net"`
+	Address string `yaml:"addr"`
+	Timeout int    `yaml:"timeout"` // Seconds
+	Expect  string `yaml:"-"`
+}
+
+type NodeLocalTestConfig struct {
+	Endpoints        []NodeLocalEndpoint `yaml:"endpoints"`
+	CheckConditions  bool                `yaml:"checkConditions"`  // check the node's conditions
+	IgnoreConditions []string            `yaml:"ignoreConditions"` // condition types to ignore
+	Timeout          time.Duration       `yaml:"timeout"`          // default 5s
+}
+
+type NodeLocalEndpoint struct {
+	Component          string `yaml:"component"` // e.g. kubelet, cni
+	Url                string `yaml:"url"`       // may contain $nodeIP
+	ExpectedCodeRegex  string `yaml:"expectedCodeRegex"`
+	UseServiceAccount  bool   `yaml:"useServiceAccount"` // send the agent's service account token
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+}
+
+type NodePressureTestConfig struct {
+	ProcPath   string                 `yaml:"procPath"`   // default /proc, e.g. /host/proc if the host's proc is mounted
+	DiskPaths  []string               `yaml:"diskPaths"`  // filesystems to check disk/inode usage of, default ["/"]
+	Thresholds NodePressureThresholds `yaml:"thresholds"` // max usage (in percent) before the test fails
+}
+
+type NodePressureThresholds struct {
+	Memory    float64 `yaml:"memory"`    // default 90
+	Pids      float64 `yaml:"pids"`      // default 80
+	Disk      float64 `yaml:"disk"`      // default 85
+	Inodes    float64 `yaml:"inodes"`    // default 85
+	Conntrack float64 `yaml:"conntrack"` // default 80
+}
+
+type OIDCTestConfig struct {
+	IssuerUrl       string            `yaml:"issuerUrl"`
+	GrantType       string            `yaml:"grantType"` // clientCredentials (default) or deviceCode
+	ClientId        string            `yaml:"clientId"`
+	ClientSecret    string            `yaml:"clientSecret"`
+	ClientSecretEnv string            `yaml:"clientSecretEnv"` // read the client secret from this env var instead
+	Scopes          []string          `yaml:"scopes"`
+	Audience        string            `yaml:"audience"`       // sent as the 'audience' param, the token is verified as a JWT for it if set
+	ExpectedClaims  map[string]string `yaml:"expectedClaims"` // claims that must be present in the token with the given value (needs audience)
+	MinValidity     time.Duration     `yaml:"minValidity"`    // token must be valid for at least this long
+	Timeout         time.Duration     `yaml:"timeout"`
+}
+
+type PingTestConfig struct {
+	Domain     string
+	Pings      int
+	Interval   string
+	Privileged bool
+}
+
+type PortExhaustionTestConfig struct {
+	ProcPath           string  `yaml:"procPath"`           // default /proc
+	EphemeralThreshold float64 `yaml:"ephemeralThreshold"` // max ephemeral port usage (per destination) in percent, default 70
+	ConntrackThreshold float64 `yaml:"conntrackThreshold"` // max conntrack table usage in percent, default 80
+}
+
+type PVCTestConfig struct {
+	Namespace      string        `yaml:"namespace"`      // namespace to create the pvcs (and pods) in
+	StorageClasses []string      `yaml:"storageClasses"` // storage classes to test, "" for the default storage class
+	Size           string        `yaml:"size"`           // default 1Gi
+	MountPod       bool          `yaml:"mountPod"`       // also mount the volume in a pod (required for WaitForFirstConsumer classes)
+	PodImage       string        `yaml:"podImage"`       // image for the pod, default busybox
+	Timeout        time.Duration `yaml:"timeout"`        // how long to wait for the pvc to bind/pod to start, default 2m
+	Workers        int           `yaml:"workers"`
+}
+
+type RegistryTestConfig struct {
+	Images           []string      `yaml:"images"`           // e.g. docker.io/library/alpine:3.20
+	Mode             string        `yaml:"mode"`             // head (default) or pull
+	DockerConfigPath string        `yaml:"dockerConfigPath"` // docker config json with credentials, e.g. the node's kubelet config
+	Timeout          time.Duration `yaml:"timeout"`          // default 30s
+	Workers          int           `yaml:"workers"`
+}
+
+type TLSScanTestConfig struct {
+	Targets         []TLSScanTarget `yaml:"targets"`
+	AllowedVersions []string        `yaml:"allowedVersions"` // default ["1.2", "1.3"]
+	WeakCiphers     []string        `yaml:"weakCiphers"`     // extra cipher suite names considered weak (in addition to go's insecure list)
+	Timeout         time.Duration   `yaml:"timeout"`         // timeout for each handshake, default 5s
+	Workers         int             `yaml:"workers"`
+}
+
+type TLSScanTarget struct {
+	Address    string `yaml:"addr"`       // host:port
+	ServerName string `yaml:"serverName"` // SNI, defaults to the host
+}
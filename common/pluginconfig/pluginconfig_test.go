@@ -0,0 +1,52 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginconfig
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugin  string
+		config  string
+		wantErr bool
+	}{
+		{"valid", "dns", "domains: [example.com]\nworkers: 2\ntimeout: 2s", false},
+		{"empty", "dns", "", false},
+		{"unknown field", "dns", "domain: example.com", true},
+		{"wrong type", "dns", "workers: two", true},
+		{"invalid duration", "dns", "timeout: soon", true},
+		{"nested unknown field", "ldap", "servers:\n  - url: ldap://ldap:389\n    bindPasword: x", true},
+		{"untagged fields", "ping", "domain: example.com\npings: 3", false},
+		{"ignored field", "netPolicy", "reachable:\n  - addr: svc:80\n    expect: blocked", true},
+		{"httpPing list", "httpPing", "- address: https://example.com\n- address: https://example.org\n  retries: 2", false},
+		{"httpPing single", "httpPing", "address: https://example.com", false},
+		{"httpPing list unknown field", "httpPing", "- address: https://example.com\n  retry: 2", true},
+		{"httpPing single unknown field", "httpPing", "adress: https://example.com", true},
+		{"templated", "ldap", "servers:\n  - url: ldap://ldap:389\n    timeout: ${LDAP_TIMEOUT}", false},
+		{"templated unknown field", "oidc", "issuerUrl: https://issuer\nclientSecrets: '{{ .secret }}'", true},
+		{"unknown plugin", "myPythonPlugin", "anything: goes", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate(tt.plugin, tt.config)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("Validate() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,7 +13,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\rsyntest.proto\x12\rproto.syntest\"\xfd\x06\n\rSynTestConfig\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n\x07version\x18\x02 \x01(\tR\x07version\x12@\n\x06labels\x18\x03 \x03(\x0b\x32(.proto.syntest.SynTestConfig.LabelsEntryR\x06labels\x12\x1e\n\npluginName\x18\x04 \x01(\tR\npluginName\x12 \n\x0b\x64isplayName\x18\x05 \x01(\tR\x0b\x64isplayName\x12 \n\x0b\x64\x65scription\x18\x06 \x01(\tR\x0b\x64\x65scription\x12\x1c\n\tnamespace\x18\x07 \x01(\tR\tnamespace\x12\x1e\n\nimportance\x18\x08 \x01(\tR\nimportance\x12\x16\n\x06repeat\x18\t \x01(\tR\x06repeat\x12\"\n\x0cnodeSelector\x18\n \x01(\tR\x0cnodeSelector\x12^\n\x10podLabelSelector\x18\x0b \x03(\x0b\x32\x32.proto.syntest.SynTestConfig.PodLabelSelectorEntryR\x10podLabelSelector\x12\x1c\n\tdependsOn\x18\x0c \x03(\tR\tdependsOn\x12\x33\n\x08timeouts\x18\r \x01(\x0b\x32\x17.proto.syntest.TimeoutsR\x08timeouts\x12\x30\n\x13pluginRestartPolicy\x18\x0e \x01(\tR\x13pluginRestartPolicy\x12 \n\x0blogWaitTime\x18\x0f \x01(\tR\x0blogWaitTime\x12\x16\n\x06\x63onfig\x18\x10 \x01(\tR\x06\x63onfig\x12\x43\n\x07runtime\x18\x11 \x03(\x0b\x32).proto.syntest.SynTestConfig.RuntimeEntryR\x07runtime\x1a\x39\n\x0bLabelsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\x1a\x43\n\x15PodLabelSelectorEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\x1a:\n\x0cRuntimeEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"\x91\x03\n\x07TestRun\x12\x0e\n\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n\x07\x61gentId\x18\x02 \x01(\tR\x07\x61gentId\x12\x1c\n\tstartTime\x18\x03 \x01(\tR\tstartTime\x12\x18\n\x07\x65ndTime\x18\x04 \x01(\tR\x07\x65ndTime\x12<\n\ntestConfig\x18\x05 \x01(\x0b\x32\x1c.proto.syntest.SynTestConfigR\ntestConfig\x12\x30\n\x07trigger\x18\x06 \x01(\x0b\x32\x16.proto.syntest.TriggerR\x07trigger\x12\x39\n\ntestResult\x18\x07 \x01(\x0b\x32\x19.proto.syntest.TestResultR\ntestResult\x12=\n\x07\x64\x65tails\x18\x08 \x03(\x0b\x32#.proto.syntest.TestRun.DetailsEntryR\x07\x64\x65tails\x1a:\n\x0c\x44\x65tailsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"\x85\x01\n\x07Trigger\x12 \n\x0btriggerType\x18\x01 \x01(\tR\x0btriggerType\x12>\n\x0etriggeringTest\x18\x02 \x01(\x0b\x32\x16.proto.syntest.TestRunR\x0etriggeringTest\x12\x18\n\x07\x64\x65tails\x18\x03 \x01(\tR\x07\x64\x65tails\"\xbc\x01\n\nTestResult\x12\x14\n\x05marks\x18\x01 \x01(\x04R\x05marks\x12\x1a\n\x08maxMarks\x18\x02 \x01(\x04R\x08maxMarks\x12@\n\x07\x64\x65tails\x18\x03 \x03(\x0b\x32&.proto.syntest.TestResult.DetailsEntryR\x07\x64\x65tails\x1a:\n\x0c\x44\x65tailsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"H\n\x08Timeouts\x12\x12\n\x04init\x18\x01 \x01(\tR\x04init\x12\x10\n\x03run\x18\x02 \x01(\tR\x03run\x12\x16\n\x06\x66inish\x18\x03 \x01(\tR\x06\x66inish\"\x07\n\x05\x45mpty2\xc9\x01\n\rSynTestPlugin\x12@\n\nInitialise\x12\x1c.proto.syntest.SynTestConfig\x1a\x14.proto.syntest.Empty\x12@\n\x0bPerformTest\x12\x16.proto.syntest.Trigger\x1a\x19.proto.syntest.TestResult\x12\x34\n\x06\x46inish\x12\x14.proto.syntest.Empty\x1a\x14.proto.syntest.EmptyB\x0cZ\x07./proto\x90\x01\x01\x62\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\rsyntest.proto\x12\rproto.syntest\"\xc4\n\n\rSynTestConfig\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n\x07version\x18\x02 \x01(\tR\x07version\x12@\n\x06labels\x18\x03 \x03(\x0b\x32(.proto.syntest.SynTestConfig.LabelsEntryR\x06labels\x12\x1e\n\npluginName\x18\x04 \x01(\tR\npluginName\x12 \n\x0b\x64isplayName\x18\x05 \x01(\tR\x0b\x64isplayName\x12 \n\x0b\x64\x65scription\x18\x06 \x01(\tR\x0b\x64\x65scription\x12\x1c\n\tnamespace\x18\x07 \x01(\tR\tnamespace\x12\x1e\n\nimportance\x18\x08 \x01(\tR\nimportance\x12\x16\n\x06repeat\x18\t \x01(\tR\x06repeat\x12\"\n\x0cnodeSelector\x18\n \x01(\tR\x0cnodeSelector\x12^\n\x10podLabelSelector\x18\x0b \x03(\x0b\x32\x32.proto.syntest.SynTestConfig.PodLabelSelectorEntryR\x10podLabelSelector\x12\x1c\n\tdependsOn\x18\x0c \x03(\tR\tdependsOn\x12\x33\n\x08timeouts\x18\r \x01(\x0b\x32\x17.proto.syntest.TimeoutsR\x08timeouts\x12\x30\n\x13pluginRestartPolicy\x18\x0e \x01(\tR\x13pluginRestartPolicy\x12 \n\x0blogWaitTime\x18\x0f \x01(\tR\x0blogWaitTime\x12\x16\n\x06\x63onfig\x18\x10 \x01(\tR\x06\x63onfig\x12\x43\n\x07runtime\x18\x11 \x03(\x0b\x32).proto.syntest.SynTestConfig.RuntimeEntryR\x07runtime\x12R\n\x0cmetricLabels\x18\x12 \x03(\x0b\x32..proto.syntest.SynTestConfig.MetricLabelsEntryR\x0cmetricLabels\x12\x33\n\x08\x61lerting\x18\x13 \x01(\x0b\x32\x17.proto.syntest.AlertingR\x08\x61lerting\x12$\n\x03slo\x18\x14 \x01(\x0b\x32\x12.proto.syntest.SLOR\x03slo\x12\x1e\n\nactiveFrom\x18\x15 \x01(\tR\nactiveFrom\x12 \n\x0b\x61\x63tiveUntil\x18\x16 \x01(\tR\x0b\x61\x63tiveUntil\x12\x41\n\ractiveWindows\x18\x17 \x03(\x0b\x32\x1b.proto.syntest.ActiveWindowR\ractiveWindows\x12\x16\n\x06paused\x18\x18 \x01(\x08R\x06paused\x12\x38\n\nsecretRefs\x18\x19 \x03(\x0b\x32\x18.proto.syntest.SecretRefR\nsecretRefs\x1a\x39\n\x0bLabelsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\x1a\x43\n\x15PodLabelSelectorEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\x1a:\n\x0cRuntimeEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\x1a?\n\x11MetricLabelsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"\x91\x03\n\x07TestRun\x12\x0e\n\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n\x07\x61gentId\x18\x02 \x01(\tR\x07\x61gentId\x12\x1c\n\tstartTime\x18\x03 \x01(\tR\tstartTime\x12\x18\n\x07\x65ndTime\x18\x04 \x01(\tR\x07\x65ndTime\x12<\n\ntestConfig\x18\x05 \x01(\x0b\x32\x1c.proto.syntest.SynTestConfigR\ntestConfig\x12\x30\n\x07trigger\x18\x06 \x01(\x0b\x32\x16.proto.syntest.TriggerR\x07trigger\x12\x39\n\ntestResult\x18\x07 \x01(\x0b\x32\x19.proto.syntest.TestResultR\ntestResult\x12=\n\x07\x64\x65tails\x18\x08 \x03(\x0b\x32#.proto.syntest.TestRun.DetailsEntryR\x07\x64\x65tails\x1a:\n\x0c\x44\x65tailsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"\x85\x01\n\x07Trigger\x12 \n\x0btriggerType\x18\x01 \x01(\tR\x0btriggerType\x12>\n\x0etriggeringTest\x18\x02 \x01(\x0b\x32\x16.proto.syntest.TestRunR\x0etriggeringTest\x12\x18\n\x07\x64\x65tails\x18\x03 \x01(\tR\x07\x64\x65tails\"\xbc\x01\n\nTestResult\x12\x14\n\x05marks\x18\x01 \x01(\x04R\x05marks\x12\x1a\n\x08maxMarks\x18\x02 \x01(\x04R\x08maxMarks\x12@\n\x07\x64\x65tails\x18\x03 \x03(\x0b\x32&.proto.syntest.TestResult.DetailsEntryR\x07\x64\x65tails\x1a:\n\x0c\x44\x65tailsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"f\n\x0c\x41\x63tiveWindow\x12\x12\n\x04\x64\x61ys\x18\x01 \x03(\tR\x04\x64\x61ys\x12\x14\n\x05start\x18\x02 \x01(\tR\x05start\x12\x10\n\x03\x65nd\x18\x03 \x01(\tR\x03\x65nd\x12\x1a\n\x08timezone\x18\x04 \x01(\tR\x08timezone\"g\n\tSecretRef\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n\x06secret\x18\x02 \x01(\tR\x06secret\x12\x10\n\x03key\x18\x03 \x01(\tR\x03key\x12\x1c\n\tnamespace\x18\x04 \x01(\tR\tnamespace\"\xfa\x02\n\x08\x41lerting\x12*\n\x10\x66\x61ilureThreshold\x18\x01 \x01(\x05R\x10\x66\x61ilureThreshold\x12;\n\x06labels\x18\x02 \x03(\x0b\x32#.proto.syntest.Alerting.LabelsEntryR\x06labels\x12J\n\x0b\x61nnotations\x18\x03 \x03(\x0b\x32(.proto.syntest.Alerting.AnnotationsEntryR\x0b\x61nnotations\x12\x1a\n\x08\x64isabled\x18\x04 \x01(\x08R\x08\x64isabled\x12\"\n\x0cslackChannel\x18\x05 \x01(\tR\x0cslackChannel\x1a\x39\n\x0bLabelsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\x1a>\n\x10\x41nnotationsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"5\n\x03SLO\x12\x16\n\x06target\x18\x01 \x01(\tR\x06target\x12\x16\n\x06window\x18\x02 \x01(\tR\x06window\"H\n\x08Timeouts\x12\x12\n\x04init\x18\x01 \x01(\tR\x04init\x12\x10\n\x03run\x18\x02 \x01(\tR\x03run\x12\x16\n\x06\x66inish\x18\x03 \x01(\tR\x06\x66inish\"\xd8\x01\n\x0e\x45xporterConfig\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n\x07\x61gentId\x18\x02 \x01(\tR\x07\x61gentId\x12\x16\n\x06\x63onfig\x18\x03 \x01(\tR\x06\x63onfig\x12\x44\n\x07runtime\x18\x04 \x03(\x0b\x32*.proto.syntest.ExporterConfig.RuntimeEntryR\x07runtime\x1a:\n\x0cRuntimeEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x02\x38\x01\"\x07\n\x05\x45mpty2\xc9\x01\n\rSynTestPlugin\x12@\n\nInitialise\x12\x1c.proto.syntest.SynTestConfig\x1a\x14.proto.syntest.Empty\x12@\n\x0bPerformTest\x12\x16.proto.syntest.Trigger\x1a\x19.proto.syntest.TestResult\x12\x34\n\x06\x46inish\x12\x14.proto.syntest.Empty\x1a\x14.proto.syntest.Empty2\xc1\x01\n\x0e\x45xporterPlugin\x12\x41\n\nInitialise\x12\x1d.proto.syntest.ExporterConfig\x1a\x14.proto.syntest.Empty\x12\x36\n\x06\x45xport\x12\x16.proto.syntest.TestRun\x1a\x14.proto.syntest.Empty\x12\x34\n\x06\x46inish\x12\x14.proto.syntest.Empty\x1a\x14.proto.syntest.EmptyB\x0cZ\x07./proto\x90\x01\x01\x62\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -27,33 +27,61 @@ if _descriptor._USE_C_DESCRIPTORS == False:
   _globals['_SYNTESTCONFIG_PODLABELSELECTORENTRY']._serialized_options = b'8\001'
   _globals['_SYNTESTCONFIG_RUNTIMEENTRY']._options = None
   _globals['_SYNTESTCONFIG_RUNTIMEENTRY']._serialized_options = b'8\001'
+  _globals['_SYNTESTCONFIG_METRICLABELSENTRY']._options = None
+  _globals['_SYNTESTCONFIG_METRICLABELSENTRY']._serialized_options = b'8\001'
   _globals['_TESTRUN_DETAILSENTRY']._options = None
   _globals['_TESTRUN_DETAILSENTRY']._serialized_options = b'8\001'
   _globals['_TESTRESULT_DETAILSENTRY']._options = None
   _globals['_TESTRESULT_DETAILSENTRY']._serialized_options = b'8\001'
+  _globals['_ALERTING_LABELSENTRY']._options = None
+  _globals['_ALERTING_LABELSENTRY']._serialized_options = b'8\001'
+  _globals['_ALERTING_ANNOTATIONSENTRY']._options = None
+  _globals['_ALERTING_ANNOTATIONSENTRY']._serialized_options = b'8\001'
+  _globals['_EXPORTERCONFIG_RUNTIMEENTRY']._options = None
+  _globals['_EXPORTERCONFIG_RUNTIMEENTRY']._serialized_options = b'8\001'
   _globals['_SYNTESTCONFIG']._serialized_start=33
-  _globals['_SYNTESTCONFIG']._serialized_end=926
-  _globals['_SYNTESTCONFIG_LABELSENTRY']._serialized_start=740
-  _globals['_SYNTESTCONFIG_LABELSENTRY']._serialized_end=797
-  _globals['_SYNTESTCONFIG_PODLABELSELECTORENTRY']._serialized_start=799
-  _globals['_SYNTESTCONFIG_PODLABELSELECTORENTRY']._serialized_end=866
-  _globals['_SYNTESTCONFIG_RUNTIMEENTRY']._serialized_start=868
-  _globals['_SYNTESTCONFIG_RUNTIMEENTRY']._serialized_end=926
-  _globals['_TESTRUN']._serialized_start=929
-  _globals['_TESTRUN']._serialized_end=1330
-  _globals['_TESTRUN_DETAILSENTRY']._serialized_start=1272
-  _globals['_TESTRUN_DETAILSENTRY']._serialized_end=1330
-  _globals['_TRIGGER']._serialized_start=1333
-  _globals['_TRIGGER']._serialized_end=1466
-  _globals['_TESTRESULT']._serialized_start=1469
-  _globals['_TESTRESULT']._serialized_end=1657
-  _globals['_TESTRESULT_DETAILSENTRY']._serialized_start=1272
-  _globals['_TESTRESULT_DETAILSENTRY']._serialized_end=1330
-  _globals['_TIMEOUTS']._serialized_start=1659
-  _globals['_TIMEOUTS']._serialized_end=1731
-  _globals['_EMPTY']._serialized_start=1733
-  _globals['_EMPTY']._serialized_end=1740
-  _globals['_SYNTESTPLUGIN']._serialized_start=1743
-  _globals['_SYNTESTPLUGIN']._serialized_end=1944
+  _globals['_SYNTESTCONFIG']._serialized_end=1381
+  _globals['_SYNTESTCONFIG_LABELSENTRY']._serialized_start=1130
+  _globals['_SYNTESTCONFIG_LABELSENTRY']._serialized_end=1187
+  _globals['_SYNTESTCONFIG_PODLABELSELECTORENTRY']._serialized_start=1189
+  _globals['_SYNTESTCONFIG_PODLABELSELECTORENTRY']._serialized_end=1256
+  _globals['_SYNTESTCONFIG_RUNTIMEENTRY']._serialized_start=1258
+  _globals['_SYNTESTCONFIG_RUNTIMEENTRY']._serialized_end=1316
+  _globals['_SYNTESTCONFIG_METRICLABELSENTRY']._serialized_start=1318
+  _globals['_SYNTESTCONFIG_METRICLABELSENTRY']._serialized_end=1381
+  _globals['_TESTRUN']._serialized_start=1384
+  _globals['_TESTRUN']._serialized_end=1785
+  _globals['_TESTRUN_DETAILSENTRY']._serialized_start=1727
+  _globals['_TESTRUN_DETAILSENTRY']._serialized_end=1785
+  _globals['_TRIGGER']._serialized_start=1788
+  _globals['_TRIGGER']._serialized_end=1921
+  _globals['_TESTRESULT']._serialized_start=1924
+  _globals['_TESTRESULT']._serialized_end=2112
+  _globals['_TESTRESULT_DETAILSENTRY']._serialized_start=1727
+  _globals['_TESTRESULT_DETAILSENTRY']._serialized_end=1785
+  _globals['_ACTIVEWINDOW']._serialized_start=2114
+  _globals['_ACTIVEWINDOW']._serialized_end=2216
+  _globals['_SECRETREF']._serialized_start=2218
+  _globals['_SECRETREF']._serialized_end=2321
+  _globals['_ALERTING']._serialized_start=2324
+  _globals['_ALERTING']._serialized_end=2702
+  _globals['_ALERTING_LABELSENTRY']._serialized_start=1130
+  _globals['_ALERTING_LABELSENTRY']._serialized_end=1187
+  _globals['_ALERTING_ANNOTATIONSENTRY']._serialized_start=2640
+  _globals['_ALERTING_ANNOTATIONSENTRY']._serialized_end=2702
+  _globals['_SLO']._serialized_start=2704
+  _globals['_SLO']._serialized_end=2757
+  _globals['_TIMEOUTS']._serialized_start=2759
+  _globals['_TIMEOUTS']._serialized_end=2831
+  _globals['_EXPORTERCONFIG']._serialized_start=2834
+  _globals['_EXPORTERCONFIG']._serialized_end=3050
+  _globals['_EXPORTERCONFIG_RUNTIMEENTRY']._serialized_start=1258
+  _globals['_EXPORTERCONFIG_RUNTIMEENTRY']._serialized_end=1316
+  _globals['_EMPTY']._serialized_start=3052
+  _globals['_EMPTY']._serialized_end=3059
+  _globals['_SYNTESTPLUGIN']._serialized_start=3062
+  _globals['_SYNTESTPLUGIN']._serialized_end=3263
+  _globals['_EXPORTERPLUGIN']._serialized_start=3266
+  _globals['_EXPORTERPLUGIN']._serialized_end=3459
 _builder.BuildServices(DESCRIPTOR, 'syntest_pb2', _globals)
 # @@protoc_insertion_point(module_scope)
@@ -205,3 +205,173 @@ var SynTestPlugin_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "syntest.proto",
 }
+
+const (
+	ExporterPlugin_Initialise_FullMethodName = "/proto.syntest.ExporterPlugin/Initialise"
+	ExporterPlugin_Export_FullMethodName     = "/proto.syntest.ExporterPlugin/Export"
+	ExporterPlugin_Finish_FullMethodName     = "/proto.syntest.ExporterPlugin/Finish"
+)
+
+// ExporterPluginClient is the client API for ExporterPlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ExporterPluginClient interface {
+	// Called once at the start - for setup
+	Initialise(ctx context.Context, in *ExporterConfig, opts ...grpc.CallOption) (*Empty, error)
+	// Called for every test run broadcasted by the agent
+	Export(ctx context.Context, in *TestRun, opts ...grpc.CallOption) (*Empty, error)
+	// Called once before the plugin is killed
+	Finish(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type exporterPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewExporterPluginClient(cc grpc.ClientConnInterface) ExporterPluginClient {
+	return &exporterPluginClient{cc}
+}
+
+func (c *exporterPluginClient) Initialise(ctx context.Context, in *ExporterConfig, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ExporterPlugin_Initialise_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exporterPluginClient) Export(ctx context.Context, in *TestRun, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ExporterPlugin_Export_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exporterPluginClient) Finish(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ExporterPlugin_Finish_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExporterPluginServer is the server API for ExporterPlugin service.
+// All implementations must embed UnimplementedExporterPluginServer
+// for forward compatibility
+type ExporterPluginServer interface {
+	// Called once at the start - for setup
+	Initialise(context.Context, *ExporterConfig) (*Empty, error)
+	// Called for every test run broadcasted by the agent
+	Export(context.Context, *TestRun) (*Empty, error)
+	// Called once before the plugin is killed
+	Finish(context.Context, *Empty) (*Empty, error)
+	mustEmbedUnimplementedExporterPluginServer()
+}
+
+// UnimplementedExporterPluginServer must be embedded to have forward compatible implementations.
+type UnimplementedExporterPluginServer struct {
+}
+
+func (UnimplementedExporterPluginServer) Initialise(context.Context, *ExporterConfig) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Initialise not implemented")
+}
+func (UnimplementedExporterPluginServer) Export(context.Context, *TestRun) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedExporterPluginServer) Finish(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Finish not implemented")
+}
+func (UnimplementedExporterPluginServer) mustEmbedUnimplementedExporterPluginServer() {}
+
+// UnsafeExporterPluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ExporterPluginServer will
+// result in compilation errors.
+type UnsafeExporterPluginServer interface {
+	mustEmbedUnimplementedExporterPluginServer()
+}
+
+func RegisterExporterPluginServer(s grpc.ServiceRegistrar, srv ExporterPluginServer) {
+	s.RegisterService(&ExporterPlugin_ServiceDesc, srv)
+}
+
+func _ExporterPlugin_Initialise_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExporterConfig)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExporterPluginServer).Initialise(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExporterPlugin_Initialise_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExporterPluginServer).Initialise(ctx, req.(*ExporterConfig))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExporterPlugin_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestRun)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExporterPluginServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExporterPlugin_Export_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExporterPluginServer).Export(ctx, req.(*TestRun))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExporterPlugin_Finish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExporterPluginServer).Finish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExporterPlugin_Finish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExporterPluginServer).Finish(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ExporterPlugin_ServiceDesc is the grpc.ServiceDesc for ExporterPlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ExporterPlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.syntest.ExporterPlugin",
+	HandlerType: (*ExporterPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Initialise",
+			Handler:    _ExporterPlugin_Initialise_Handler,
+		},
+		{
+			MethodName: "Export",
+			Handler:    _ExporterPlugin_Export_Handler,
+		},
+		{
+			MethodName: "Finish",
+			Handler:    _ExporterPlugin_Finish_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "syntest.proto",
+}
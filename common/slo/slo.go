@@ -0,0 +1,228 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package slo
+
+// package containing code to compute the availability SLOs (error budget, burn rates) of tests from the test run history
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const DefaultWindow = "30d"
+
+var ErrNoSLO = errors.New("test has no slo")
+
+// BurnRateWindows are the windows the burn rates are computed over (e.g. for multi-window burn rate alerts)
+var BurnRateWindows = map[string]time.Duration{
+	"1h": time.Hour,
+	"6h": 6 * time.Hour,
+	"1d": 24 * time.Hour,
+	"3d": 3 * 24 * time.Hour,
+}
+
+// Report is the state of the availability SLO of a test, computed from the test runs (on all agents) in the window
+type Report struct {
+	TestName      string  `json:"testName"`
+	TestNamespace string  `json:"testNamespace"`
+	Target        float64 `json:"target"` // availability target (0-1)
+	Window        string  `json:"window"`
+	TestRuns      int     `json:"testRuns"`     // number of test runs in the window
+	Availability  float64 `json:"availability"` // average pass ratio of the test runs in the window (1 if no runs)
+	// ErrorBudgetRemaining is the ratio of the error budget left in the window (negative once exhausted)
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining"`
+	// BurnRates are the rates at which the error budget is consumed (1 exhausts it exactly at the end of the window)
+	BurnRates map[string]float64 `json:"burnRates"`
+}
+
+// ParseWindow Parses the SLO window, a duration which can also be in days (e.g. 30d)
+func ParseWindow(window string) (time.Duration, error) {
+	if window == "" {
+		window = DefaultWindow
+	}
+	var dur time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(window, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		dur = time.Duration(n) * 24 * time.Hour
+	} else {
+		dur, err = time.ParseDuration(window)
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid slo window "+window)
+	}
+	if dur <= 0 || dur > common.TestRunHistoryRetention {
+		return 0, errors.Errorf("slo window %s must be > 0 and <= %s", window, common.TestRunHistoryRetention)
+	}
+	return dur, nil
+}
+
+// Parse Parses and validates the SLO of a test, the target is a ratio (0-1)
+func Parse(slo *proto.SLO) (target float64, window time.Duration, err error) {
+	if slo == nil || slo.Target == "" {
+		return 0, 0, ErrNoSLO
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(slo.Target, "%"), 64)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid slo target "+slo.Target)
+	}
+	if percent <= 0 || percent >= 100 {
+		return 0, 0, errors.Errorf("slo target %s must be > 0 and < 100", slo.Target)
+	}
+	window, err = ParseWindow(slo.Window)
+	if err != nil {
+		return 0, 0, err
+	}
+	return percent / 100, window, nil
+}
+
+// Compute Computes the SLO report of the test from its test run results (on all agents)
+func Compute(testConfig *proto.SynTestConfig, results []common.TestRunResult, now time.Time) (Report, error) {
+	target, window, err := Parse(testConfig.Slo)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{
+		TestName:             testConfig.Name,
+		TestNamespace:        testConfig.Namespace,
+		Target:               target,
+		Window:               testConfig.Slo.Window,
+		Availability:         1,
+		ErrorBudgetRemaining: 1,
+		BurnRates:            map[string]float64{},
+	}
+	if report.Window == "" {
+		report.Window = DefaultWindow
+	}
+	errorBudget := 1 - target
+
+	passed, total := availability(results, now.Add(-window))
+	report.TestRuns = total
+	if total > 0 {
+		report.Availability = passed / float64(total)
+		report.ErrorBudgetRemaining = 1 - (1-report.Availability)/errorBudget
+	}
+	for name, burnWindow := range BurnRateWindows {
+		if burnWindow > window {
+			continue
+		}
+		passed, total := availability(results, now.Add(-burnWindow))
+		if total == 0 {
+			continue
+		}
+		report.BurnRates[name] = (1 - passed/float64(total)) / errorBudget
+	}
+	return report, nil
+}
+
+// availability Returns the sum of the pass ratios and the number of test runs since the given time
+func availability(results []common.TestRunResult, since time.Time) (float64, int) {
+	passed := 0.0
+	total := 0
+	for _, res := range results {
+		if res.Time.Before(since) {
+			continue
+		}
+		passed += res.PassRatio
+		total++
+	}
+	return passed, total
+}
+
+// FetchReport Computes the SLO report of a test from the test run history in storage
+func FetchReport(ctx context.Context, store storage.SynHeartStore, configId string, now time.Time) (Report, error) {
+	testConfig, err := store.FetchTestConfig(ctx, configId)
+	if err != nil {
+		return Report{}, err
+	}
+	allStatus, err := store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return Report{}, errors.Wrap(err, "error fetching test run status")
+	}
+	return report(ctx, store, &testConfig, pluginIds(allStatus)[configId], now)
+}
+
+// FetchAllReports Computes the SLO reports of all tests with an SLO (by test config id)
+func FetchAllReports(ctx context.Context, store storage.SynHeartStore, logger hclog.Logger, now time.Time) (map[string]Report, error) {
+	summaries, err := store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		return map[string]Report{}, errors.Wrap(err, "error fetching test config summaries")
+	}
+	allStatus, err := store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return map[string]Report{}, errors.Wrap(err, "error fetching test run status")
+	}
+	testPluginIds := pluginIds(allStatus)
+
+	reports := map[string]Report{}
+	for configId := range summaries {
+		testConfig, err := store.FetchTestConfig(ctx, configId)
+		if err != nil {
+			logger.Warn("unable to fetch test config, skipping", "configId", configId, "err", err)
+			continue
+		}
+		if testConfig.Slo == nil {
+			continue
+		}
+		r, err := report(ctx, store, &testConfig, testPluginIds[configId], now)
+		if err != nil {
+			logger.Warn("unable to compute slo, skipping", "configId", configId, "err", err)
+			continue
+		}
+		reports[configId] = r
+	}
+	return reports, nil
+}
+
+func report(ctx context.Context, store storage.SynHeartStore, testConfig *proto.SynTestConfig, pluginIds []string, now time.Time) (Report, error) {
+	_, window, err := Parse(testConfig.Slo)
+	if err != nil {
+		return Report{}, err
+	}
+	results := []common.TestRunResult{}
+	for _, pluginId := range pluginIds {
+		history, err := store.FetchTestRunHistory(ctx, pluginId, now.Add(-window))
+		if err != nil {
+			return Report{}, err
+		}
+		results = append(results, history...)
+	}
+	return Compute(testConfig, results, now)
+}
+
+// pluginIds Groups the plugin ids (test on an agent) by test config id
+func pluginIds(allStatus map[string]string) map[string][]string {
+	ids := map[string][]string{}
+	for pluginId := range allStatus {
+		testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+		if err != nil {
+			continue
+		}
+		configId := common.ComputeSynTestConfigId(testName, testNs)
+		ids[configId] = append(ids[configId], pluginId)
+	}
+	return ids
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package slo
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		window  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 30 * 24 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"32d", common.TestRunHistoryRetention, false},
+		{"33d", 0, true}, // longer than the test run history
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"1w", 0, true},
+		{"d", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			got, err := ParseWindow(tt.window)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWindow() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		slo        *proto.SLO
+		wantTarget float64
+		wantErr    bool
+	}{
+		{"no slo", nil, 0, true},
+		{"no target", &proto.SLO{Window: "7d"}, 0, true},
+		{"percent", &proto.SLO{Target: "99.9%"}, 0.999, false},
+		{"without the percent sign", &proto.SLO{Target: "99"}, 0.99, false},
+		{"100%", &proto.SLO{Target: "100"}, 0, true},
+		{"0%", &proto.SLO{Target: "0%"}, 0, true},
+		{"invalid target", &proto.SLO{Target: "high"}, 0, true},
+		{"invalid window", &proto.SLO{Target: "99", Window: "1y"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, _, err := Parse(tt.slo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(target-tt.wantTarget) > 1e-9 {
+				t.Errorf("Parse() target = %v, want %v", target, tt.wantTarget)
+			}
+		})
+	}
+	if _, _, err := Parse(nil); !errors.Is(err, ErrNoSLO) {
+		t.Errorf("Parse(nil) error = %v, want ErrNoSLO", err)
+	}
+}
+
+// runs Returns n test runs with the pass ratio, ago before now
+func runs(now time.Time, ago time.Duration, n int, passRatio float64) []common.TestRunResult {
+	var results []common.TestRunResult
+	for i := 0; i < n; i++ {
+		results = append(results, common.TestRunResult{Time: now.Add(-ago), PassRatio: passRatio})
+	}
+	return results
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		slo           *proto.SLO
+		results       []common.TestRunResult
+		wantRuns      int
+		wantAvail     float64
+		wantRemaining float64
+		wantBurnRates map[string]float64
+	}{
+		{
+			name:          "no runs",
+			slo:           &proto.SLO{Target: "99%", Window: "1d"},
+			wantAvail:     1,
+			wantRemaining: 1,
+			wantBurnRates: map[string]float64{},
+		},
+		{
+			name:          "all passing",
+			slo:           &proto.SLO{Target: "99%", Window: "1d"},
+			results:       runs(now, 30*time.Minute, 10, 1),
+			wantRuns:      10,
+			wantAvail:     1,
+			wantRemaining: 1,
+			wantBurnRates: map[string]float64{"1h": 0, "6h": 0, "1d": 0},
+		},
+		{
+			// 1 failure in 100 runs consumes the whole budget of 99%, but burns it 10 times faster in the last hour
+			name: "budget exhausted exactly",
+			slo:  &proto.SLO{Target: "99%", Window: "1d"},
+			results: append(append(runs(now, 10*time.Minute, 1, 0), runs(now, 20*time.Minute, 9, 1)...),
+				runs(now, 2*time.Hour, 90, 1)...),
+			wantRuns:      100,
+			wantAvail:     0.99,
+			wantRemaining: 0,
+			wantBurnRates: map[string]float64{"1h": 10, "6h": 1, "1d": 1},
+		},
+		{
+			name:          "budget overspent",
+			slo:           &proto.SLO{Target: "90%", Window: "1d"},
+			results:       runs(now, time.Hour/2, 4, 0.5),
+			wantRuns:      4,
+			wantAvail:     0.5,
+			wantRemaining: -4,
+			wantBurnRates: map[string]float64{"1h": 5, "6h": 5, "1d": 5},
+		},
+		{
+			name:          "half the budget left",
+			slo:           &proto.SLO{Target: "90%", Window: "1d"},
+			results:       append(runs(now, 3*time.Hour, 19, 1), runs(now, 3*time.Hour, 1, 0)...),
+			wantRuns:      20,
+			wantAvail:     0.95,
+			wantRemaining: 0.5,
+			wantBurnRates: map[string]float64{"6h": 0.5, "1d": 0.5}, // no runs in the last hour
+		},
+		{
+			name:          "runs outside the window are ignored",
+			slo:           &proto.SLO{Target: "99%", Window: "1d"},
+			results:       append(runs(now, 25*time.Hour, 5, 0), runs(now, time.Minute, 5, 1)...),
+			wantRuns:      5,
+			wantAvail:     1,
+			wantRemaining: 1,
+			wantBurnRates: map[string]float64{"1h": 0, "6h": 0, "1d": 0},
+		},
+		{
+			name:          "burn rate windows longer than the slo window are skipped",
+			slo:           &proto.SLO{Target: "99%", Window: "2h"},
+			results:       runs(now, time.Minute, 5, 1),
+			wantRuns:      5,
+			wantAvail:     1,
+			wantRemaining: 1,
+			wantBurnRates: map[string]float64{"1h": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &proto.SynTestConfig{Name: "ping", Namespace: "default", Slo: tt.slo}
+			got, err := Compute(config, tt.results, now)
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+			if got.TestRuns != tt.wantRuns {
+				t.Errorf("TestRuns = %d, want %d", got.TestRuns, tt.wantRuns)
+			}
+			if math.Abs(got.Availability-tt.wantAvail) > 1e-9 {
+				t.Errorf("Availability = %v, want %v", got.Availability, tt.wantAvail)
+			}
+			if math.Abs(got.ErrorBudgetRemaining-tt.wantRemaining) > 1e-9 {
+				t.Errorf("ErrorBudgetRemaining = %v, want %v", got.ErrorBudgetRemaining, tt.wantRemaining)
+			}
+			if len(got.BurnRates) != len(tt.wantBurnRates) {
+				t.Errorf("BurnRates = %v, want %v", got.BurnRates, tt.wantBurnRates)
+			}
+			for name, want := range tt.wantBurnRates {
+				if rate, ok := got.BurnRates[name]; !ok || math.Abs(rate-want) > 1e-9 {
+					t.Errorf("BurnRates[%s] = %v, want %v", name, rate, want)
+				}
+			}
+		})
+	}
+}
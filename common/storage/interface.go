@@ -22,14 +22,20 @@ import (
 	"github.com/cisco-open/synthetic-heart/common"
 	"github.com/cisco-open/synthetic-heart/common/proto"
 	"github.com/hashicorp/go-hclog"
+	"time"
 )
 
 type SynHeartStoreConfig struct {
 	Type       string `yaml:"type"`
 	BufferSize int    `yaml:"bufferSize"`
 	Address    string `yaml:"address"`
+	// ConfigHistoryLength is the number of versions of each test config kept for rollbacks (DefaultConfigHistoryLength if 0)
+	ConfigHistoryLength int `yaml:"configHistoryLength"`
 }
 
+// DefaultConfigHistoryLength is the number of versions of each test config kept, if not configured
+const DefaultConfigHistoryLength = 10
+
 // Interface that a storage for Synthetic Heart must implement
 type SynHeartStore interface {
 	// TestRun functions
@@ -38,8 +44,15 @@ type SynHeartStore interface {
 	FetchLatestTestRun(ctx context.Context, pluginId string) (proto.TestRun, error)
 	FetchLastFailedTestRun(ctx context.Context, pluginId string) (proto.TestRun, error)
 	FetchAllTestRunStatus(ctx context.Context) (map[string]string, error)
+	FetchTestRunHistory(ctx context.Context, pluginId string, since time.Time) ([]common.TestRunResult, error)
+	FetchTestRunHistoryRange(ctx context.Context, pluginId string, since time.Time, until time.Time) ([]common.TestRunResult, error)
 	DeleteAllTestRunInfo(ctx context.Context, pluginId string) error
 
+	// Forwarded plugin logs (of a test run) functions
+	WriteTestRunLogs(ctx context.Context, pluginId string, runId string, logs string, ttl time.Duration) error
+	FetchTestRunLogs(ctx context.Context, pluginId string, runId string) (string, error)
+	FetchTestRunLogIds(ctx context.Context, pluginId string) ([]string, error) // newest first
+
 	// Plugin health status functions
 	WritePluginHealthStatus(ctx context.Context, pluginId string, state common.PluginState) error
 	FetchPluginHealthStatus(ctx context.Context, pluginId string) (common.PluginState, error)
@@ -58,6 +71,29 @@ type SynHeartStore interface {
 	// Deleting config status should be part of DeleteTestConfig
 
 	FetchAllTestConfigSummary(ctx context.Context) (map[string]common.SyntestConfigSummary, error)
+	FetchTestConfigSummary(ctx context.Context, configId string) (common.SyntestConfigSummary, error)
+
+	// Canary functions: a new version of a test config which only runs on some agents first, the stable config
+	// must exist (and writing it removes the canary from the summary)
+	WriteTestConfigCanary(ctx context.Context, config proto.SynTestConfig, canary common.SyntestCanary) error
+	FetchTestConfigCanary(ctx context.Context, configId string) (proto.SynTestConfig, error)
+	DeleteTestConfigCanary(ctx context.Context, configId string) error
+
+	// Config history functions: the last versions of a test config (written by WriteTestConfig), and the version it's
+	// pinned to (the controller keeps a pinned config on that version, instead of the version in the CRD)
+	FetchTestConfigHistory(ctx context.Context, configId string) ([]common.SyntestConfigVersion, error) // newest first
+	RollbackTestConfig(ctx context.Context, configId string, version string) error
+	WriteTestConfigPin(ctx context.Context, configId string, version string) error
+	FetchTestConfigPin(ctx context.Context, configId string) (string, error) // empty if not pinned
+	DeleteTestConfigPin(ctx context.Context, configId string) error
+
+	// Test run request functions: one-shot runs of a test (e.g. from a SynTestRun), the agents running the test run it
+	// immediately and write the result of the run for the request
+	RequestTestRun(ctx context.Context, request common.TestRunRequest) error
+	SubscribeToTestRunRequests(ctx context.Context, channelSize int, requestChan chan<- common.TestRunRequest) error
+	WriteTestRunRequestResult(ctx context.Context, requestId string, agentId string, testRun proto.TestRun) error
+	FetchTestRunRequestResults(ctx context.Context, requestId string) (map[string]proto.TestRun, error) // by agent id
+	DeleteTestRunRequest(ctx context.Context, requestId string) error
 
 	// Agent functions
 	FetchAllAgentStatus(ctx context.Context) (map[string]common.AgentStatus, error)
@@ -66,6 +102,40 @@ type SynHeartStore interface {
 	SubscribeToAgentEvents(ctx context.Context, channelSize int, configChan chan<- string) error
 	NewAgentEvent(ctx context.Context, event string) error
 
+	// Silence functions
+	WriteSilence(ctx context.Context, silence common.Silence) error
+	DeleteSilence(ctx context.Context, silenceId string) error
+	FetchAllSilences(ctx context.Context) (map[string]common.Silence, error)
+
+	// Agent profile functions
+	WriteAgentProfile(ctx context.Context, profile common.AgentProfile) error
+	DeleteAgentProfile(ctx context.Context, name string) error
+	FetchAllAgentProfiles(ctx context.Context) (map[string]common.AgentProfile, error)
+
+	// Garbage collection functions
+	// FetchAllStoredPluginIds Returns the ids of all the plugins with any data in storage (test runs, logs, health)
+	FetchAllStoredPluginIds(ctx context.Context) (map[string]bool, error)
+	// FetchAllStoredConfigIds Returns the ids of all the test configs with any data in storage (config, status, history)
+	FetchAllStoredConfigIds(ctx context.Context) (map[string]bool, error)
+
+	// Federation functions (the summaries of the clusters, in the central storage)
+	WriteClusterSummary(ctx context.Context, summary common.ClusterSummary) error
+	DeleteClusterSummary(ctx context.Context, cluster string) error
+	FetchAllClusterSummaries(ctx context.Context) (map[string]common.ClusterSummary, error)
+
+	// Controller heartbeat functions
+	WriteControllerHeartbeat(ctx context.Context, heartbeat common.ControllerHeartbeat) error
+	FetchControllerHeartbeat(ctx context.Context) (common.ControllerHeartbeat, error) // ErrNotFound if none
+
+	// Namespace policies functions (the quotas of the namespaces, published by the controller)
+	WriteNamespacePolicies(ctx context.Context, policies common.NamespacePolicies) error
+	FetchNamespacePolicies(ctx context.Context) (common.NamespacePolicies, error) // ErrNotFound if none
+
+	// Audit log functions
+	WriteAuditEvent(ctx context.Context, event common.AuditEvent) error
+	// FetchAuditEvents Fetches up to count events (newest first) that match (all if match is nil)
+	FetchAuditEvents(ctx context.Context, count int, match func(event common.AuditEvent) bool) ([]common.AuditEvent, error)
+
 	Close() error
 	Ping(ctx context.Context) error
 }
@@ -28,6 +28,8 @@ import (
 	"github.com/redis/go-redis/v9"
 	"google.golang.org/protobuf/encoding/protojson"
 	"k8s.io/client-go/util/retry"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -36,6 +38,7 @@ type RedisSynHeartStore struct {
 	logger                hclog.Logger
 	protoJsonMarshaller   protojson.MarshalOptions
 	protoJsonUnMarshaller protojson.UnmarshalOptions
+	configHistoryLength   int64
 }
 
 var ErrNotFound = errors.New("not found")
@@ -48,18 +51,41 @@ const (
 	PluginLastUnhealthyFmt = SynTestsBase + "/%s/lastUnhealthy"
 	TestRunLatestFmt       = SynTestsBase + "/%s/latestRun"
 	TestRunLastFailedFmt   = SynTestsBase + "/%s/lastFailedRun"
+	TestRunHistoryFmt      = SynTestsBase + "/%s/history" // sorted set of test run results (by time), to compute SLOs
+	TestRunLogIdsFmt       = SynTestsBase + "/%s/logs"    // sorted set of the run ids (by time) with forwarded logs
+	TestRunLogsFmt         = SynTestsBase + "/%s/logs/%s"
 
-	ConfigBase             = "configs"
-	ConfigSynTestsSummary  = ConfigBase + "/syntests/summary"
-	ConfigSynTestJsonFmt   = ConfigBase + "/syntest/%s/json"
-	ConfigSynTestRawFmt    = ConfigBase + "/syntest/%s/raw"
-	ConfigSynTestStatusFmt = ConfigBase + "/syntest/%s/status"
+	ConfigBase              = "configs"
+	ConfigSynTestsSummary   = ConfigBase + "/syntests/summary"
+	ConfigSynTestJsonFmt    = ConfigBase + "/syntest/%s/json"
+	ConfigSynTestRawFmt     = ConfigBase + "/syntest/%s/raw"
+	ConfigSynTestStatusFmt  = ConfigBase + "/syntest/%s/status"
+	ConfigSynTestCanaryFmt  = ConfigBase + "/syntest/%s/canary"
+	ConfigSynTestHistoryFmt = ConfigBase + "/syntest/%s/history" // list of the last versions of the config (newest first)
+	ConfigSynTestPinFmt     = ConfigBase + "/syntest/%s/pin"
 
 	AgentsAll = "agents/all"
 
+	SilencesAll = "silences/all"
+
+	AgentProfilesAll = "agentProfiles/all"
+
+	ClustersAll = "federation/clusters" // summaries of the clusters of a federation
+
+	AuditLog = "audit/log" // stream of audit events
+
+	ControllerHeartbeatKey = "controller/heartbeat"
+
+	NamespacePoliciesKey = "controller/namespacePolicies"
+
+	TestRunRequestResultsFmt = "runs/%s/results" // results of a requested test run, by agent id
+
 	SynTestChannel = "syntests"
 	ConfigChannel  = "config"
 	AgentChannel   = "agent"
+	RunChannel     = "runs"
+
+	TestRunEventPrefix = "new run: " // prefix of the test run events, followed by the plugin id
 )
 
 func NewRedisSynHeartStore(config SynHeartStoreConfig, log hclog.Logger) RedisSynHeartStore {
@@ -74,6 +100,10 @@ func NewRedisSynHeartStore(config SynHeartStoreConfig, log hclog.Logger) RedisSy
 		EmitUnpopulated: true,
 	}
 	r.protoJsonUnMarshaller = protojson.UnmarshalOptions{}
+	r.configHistoryLength = int64(config.ConfigHistoryLength)
+	if r.configHistoryLength <= 0 {
+		r.configHistoryLength = DefaultConfigHistoryLength
+	}
 	return r
 }
 
@@ -85,6 +115,11 @@ func (r *RedisSynHeartStore) Ping(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
 
+// AddHook Adds a hook to the redis client, e.g. to instrument the redis commands
+func (r *RedisSynHeartStore) AddHook(hook redis.Hook) {
+	r.client.AddHook(hook)
+}
+
 func (r *RedisSynHeartStore) WriteTestRun(ctx context.Context, pluginId string, testRun proto.TestRun) error {
 	r.logger.Info("publishing test result to redis")
 	bytes, err := r.protoJsonMarshaller.Marshal(&testRun)
@@ -105,6 +140,11 @@ func (r *RedisSynHeartStore) WriteTestRun(ctx context.Context, pluginId string,
 		return err
 	}
 
+	err = r.addTestRunHistory(ctx, pluginId, testRun, passRatio)
+	if err != nil {
+		return err
+	}
+
 	// write last failed test run if the test run failed -- so if it passes, next time we have some way of knowing what failed
 	if passRatio < 1 {
 		lastFailedTestRunKey := fmt.Sprintf(TestRunLastFailedFmt, pluginId)
@@ -115,7 +155,7 @@ func (r *RedisSynHeartStore) WriteTestRun(ctx context.Context, pluginId string,
 	}
 
 	// This is to let subscribers know there is a new test run
-	err = r.PublishR(ctx, SynTestChannel, "new run: "+pluginId)
+	err = r.PublishR(ctx, SynTestChannel, TestRunEventPrefix+pluginId)
 	if err != nil {
 		return errors.Wrap(err, "error publishing test run to channel")
 	}
@@ -131,6 +171,107 @@ func (r *RedisSynHeartStore) UpdateTestRunStatus(ctx context.Context, pluginId s
 	return nil
 }
 
+// addTestRunHistory Adds the result (<run id> <runtime ns> <pass ratio>) to the test run history and removes results
+// older than the retention
+func (r *RedisSynHeartStore) addTestRunHistory(ctx context.Context, pluginId string, testRun proto.TestRun, passRatio float64) error {
+	runTime, err := time.Parse(common.TimeFormat, testRun.StartTime)
+	if err != nil {
+		runTime = time.Now()
+	}
+	var runtime time.Duration
+	if endTime, err := time.Parse(common.TimeFormat, testRun.EndTime); err == nil && endTime.After(runTime) {
+		runtime = endTime.Sub(runTime)
+	}
+	historyKey := fmt.Sprintf(TestRunHistoryFmt, pluginId)
+	member := fmt.Sprintf("%s %d %.5f", testRun.Id, runtime.Nanoseconds(), passRatio)
+	err = r.ZAddR(ctx, historyKey, float64(runTime.UnixMilli()), member)
+	if err != nil {
+		return errors.Wrap(err, "error writing test run history")
+	}
+	oldest := time.Now().Add(-common.TestRunHistoryRetention).UnixMilli()
+	err = r.ZRemRangeByScoreR(ctx, historyKey, "-inf", strconv.FormatInt(oldest, 10))
+	if err != nil {
+		return errors.Wrap(err, "error trimming test run history")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchTestRunHistory(ctx context.Context, pluginId string, since time.Time) ([]common.TestRunResult, error) {
+	return r.FetchTestRunHistoryRange(ctx, pluginId, since, time.Time{})
+}
+
+// FetchTestRunHistoryRange Returns the results of the test runs between since and until (no end if zero), oldest first
+func (r *RedisSynHeartStore) FetchTestRunHistoryRange(ctx context.Context, pluginId string, since time.Time,
+	until time.Time) ([]common.TestRunResult, error) {
+	maxScore := "+inf"
+	if !until.IsZero() {
+		maxScore = strconv.FormatInt(until.UnixMilli(), 10)
+	}
+	members, err := r.ZRangeByScoreWithScoresR(ctx, fmt.Sprintf(TestRunHistoryFmt, pluginId), strconv.FormatInt(since.UnixMilli(), 10), maxScore)
+	if err != nil {
+		return []common.TestRunResult{}, errors.Wrap(err, "couldn't fetch test run history for:"+pluginId)
+	}
+	results := make([]common.TestRunResult, 0, len(members))
+	for _, m := range members {
+		member, _ := m.Member.(string)
+		fields := strings.Fields(member)
+		passRatio, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err != nil {
+			r.logger.Warn("unable to parse test run history, skipping", "pluginId", pluginId, "member", member, "err", err)
+			continue
+		}
+		result := common.TestRunResult{
+			Time:      time.UnixMilli(int64(m.Score)),
+			PassRatio: passRatio,
+		}
+		// results written before the runtime was recorded only have the run id and the pass ratio
+		if len(fields) == 3 {
+			if runtime, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				result.Runtime = time.Duration(runtime)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (r *RedisSynHeartStore) WriteTestRunLogs(ctx context.Context, pluginId string, runId string, logs string, ttl time.Duration) error {
+	err := r.SetR(ctx, fmt.Sprintf(TestRunLogsFmt, pluginId, runId), logs, ttl)
+	if err != nil {
+		return errors.Wrap(err, "error writing test run logs")
+	}
+	// index the run, and remove the runs whose logs expired
+	logIdsKey := fmt.Sprintf(TestRunLogIdsFmt, pluginId)
+	now := time.Now()
+	err = r.ZAddR(ctx, logIdsKey, float64(now.UnixMilli()), runId)
+	if err != nil {
+		return errors.Wrap(err, "error writing test run log id")
+	}
+	err = r.ZRemRangeByScoreR(ctx, logIdsKey, "-inf", strconv.FormatInt(now.Add(-ttl).UnixMilli(), 10))
+	if err != nil {
+		return errors.Wrap(err, "error trimming test run log ids")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchTestRunLogs(ctx context.Context, pluginId string, runId string) (string, error) {
+	logs, err := r.GetR(ctx, fmt.Sprintf(TestRunLogsFmt, pluginId, runId))
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", errors.Wrap(err, "couldn't fetch test run logs for:"+pluginId)
+	}
+	return logs, nil
+}
+
+func (r *RedisSynHeartStore) FetchTestRunLogIds(ctx context.Context, pluginId string) ([]string, error) {
+	runIds, err := r.ZRevRangeR(ctx, fmt.Sprintf(TestRunLogIdsFmt, pluginId), 0, -1)
+	if err != nil {
+		return []string{}, errors.Wrap(err, "couldn't fetch test run log ids for:"+pluginId)
+	}
+	return runIds, nil
+}
+
 func (r *RedisSynHeartStore) FetchAllTestConfigSummary(ctx context.Context) (map[string]common.SyntestConfigSummary, error) {
 	synTestConfigSummaries, err := r.HGetAllR(ctx, ConfigSynTestsSummary)
 	if err != nil {
@@ -241,6 +382,24 @@ func (r *RedisSynHeartStore) DeleteAllTestRunInfo(ctx context.Context, pluginId
 	if err != nil {
 		r.logger.Warn(errors.Wrap(err, "couldn't delete last failed test run for:"+pluginId).Error())
 	}
+	err = r.DelR(ctx, fmt.Sprintf(TestRunHistoryFmt, pluginId))
+	if err != nil {
+		r.logger.Warn(errors.Wrap(err, "couldn't delete test run history for:"+pluginId).Error())
+	}
+	runIds, err := r.FetchTestRunLogIds(ctx, pluginId)
+	if err != nil {
+		r.logger.Warn(errors.Wrap(err, "couldn't fetch test run log ids for:"+pluginId).Error())
+	}
+	for _, runId := range runIds {
+		err = r.DelR(ctx, fmt.Sprintf(TestRunLogsFmt, pluginId, runId))
+		if err != nil {
+			r.logger.Warn(errors.Wrap(err, "couldn't delete test run logs for:"+pluginId).Error())
+		}
+	}
+	err = r.DelR(ctx, fmt.Sprintf(TestRunLogIdsFmt, pluginId))
+	if err != nil {
+		r.logger.Warn(errors.Wrap(err, "couldn't delete test run log ids for:"+pluginId).Error())
+	}
 
 	err = r.DelR(ctx, fmt.Sprintf(PluginLatestHealthFmt, pluginId))
 	if err != nil {
@@ -295,6 +454,10 @@ func (r *RedisSynHeartStore) WriteTestConfig(ctx context.Context, config proto.S
 	if err != nil {
 		return errors.Wrap(err, "error writing config"+", testName="+configId)
 	}
+	err = r.addTestConfigHistory(ctx, configId, config.Version, raw, string(b))
+	if err != nil {
+		return errors.Wrap(err, "error adding config to history"+", testName="+configId)
+	}
 	summary := common.SyntestConfigSummary{
 		Name:        config.Name,
 		ConfigId:    configId,
@@ -303,7 +466,9 @@ func (r *RedisSynHeartStore) WriteTestConfig(ctx context.Context, config proto.S
 		Description: config.Description,
 		Namespace:   config.Namespace,
 		Repeat:      config.Repeat,
+		Importance:  config.Importance,
 		Plugin:      config.PluginName,
+		Source:      config.Labels[common.ConfigSourceLabel],
 	}
 	summaryJson, err := json.Marshal(summary)
 	if err != nil {
@@ -320,6 +485,185 @@ func (r *RedisSynHeartStore) WriteTestConfig(ctx context.Context, config proto.S
 	return nil
 }
 
+func (r *RedisSynHeartStore) FetchTestConfigSummary(ctx context.Context, configId string) (common.SyntestConfigSummary, error) {
+	jsonSummary, err := r.HGetR(ctx, ConfigSynTestsSummary, configId)
+	if errors.Is(err, redis.Nil) {
+		return common.SyntestConfigSummary{}, ErrNotFound
+	} else if err != nil {
+		return common.SyntestConfigSummary{}, errors.Wrap(err, "error fetching test config summary"+", testName="+configId)
+	}
+	summary := common.SyntestConfigSummary{}
+	err = json.Unmarshal([]byte(jsonSummary), &summary)
+	if err != nil {
+		return common.SyntestConfigSummary{}, errors.Wrap(err, "error unmarshalling config summary")
+	}
+	return summary, nil
+}
+
+func (r *RedisSynHeartStore) WriteTestConfigCanary(ctx context.Context, config proto.SynTestConfig, canary common.SyntestCanary) error {
+	configId := common.ComputeSynTestConfigId(config.Name, config.Namespace)
+	b, err := r.protoJsonMarshaller.Marshal(&config)
+	if err != nil {
+		return err
+	}
+	err = r.SetR(ctx, fmt.Sprintf(ConfigSynTestCanaryFmt, configId), string(b), 0)
+	if err != nil {
+		return errors.Wrap(err, "error writing canary config"+", testName="+configId)
+	}
+	// the agents find out about the canary from the summary
+	summary, err := r.FetchTestConfigSummary(ctx, configId)
+	if err != nil {
+		return errors.Wrap(err, "error fetching summary of the stable config")
+	}
+	summary.Canary = &canary
+	return r.writeTestConfigSummary(ctx, summary)
+}
+
+func (r *RedisSynHeartStore) FetchTestConfigCanary(ctx context.Context, configId string) (proto.SynTestConfig, error) {
+	msg, err := r.GetR(ctx, fmt.Sprintf(ConfigSynTestCanaryFmt, configId))
+	if errors.Is(err, redis.Nil) {
+		return proto.SynTestConfig{}, ErrNotFound
+	} else if err != nil {
+		return proto.SynTestConfig{}, errors.Wrap(err, "couldn't fetch canary config for:"+configId)
+	}
+	config := proto.SynTestConfig{}
+	err = r.protoJsonUnMarshaller.Unmarshal([]byte(msg), &config)
+	if err != nil {
+		return proto.SynTestConfig{}, errors.Wrap(err, "error un-marshalling canary config from redis")
+	}
+	return config, nil
+}
+
+func (r *RedisSynHeartStore) DeleteTestConfigCanary(ctx context.Context, configId string) error {
+	err := r.DelR(ctx, fmt.Sprintf(ConfigSynTestCanaryFmt, configId))
+	if err != nil {
+		return errors.Wrap(err, "error deleting canary config"+", testName="+configId)
+	}
+	summary, err := r.FetchTestConfigSummary(ctx, configId)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if summary.Canary == nil {
+		return nil
+	}
+	summary.Canary = nil
+	return r.writeTestConfigSummary(ctx, summary)
+}
+
+// addTestConfigHistory Adds a version of the config to its history (unless it's already the latest one), keeping
+// only the last configHistoryLength versions
+func (r *RedisSynHeartStore) addTestConfigHistory(ctx context.Context, configId string, version string, raw string, configJson string) error {
+	key := fmt.Sprintf(ConfigSynTestHistoryFmt, configId)
+	latest, err := r.LRangeR(ctx, key, 0, 0)
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 {
+		latestVersion := common.SyntestConfigVersion{}
+		if json.Unmarshal([]byte(latest[0]), &latestVersion) == nil && latestVersion.Version == version {
+			return nil
+		}
+	}
+	entry, err := json.Marshal(common.SyntestConfigVersion{
+		Version: version,
+		Time:    time.Now(),
+		Raw:     raw,
+		Config:  configJson,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling config version")
+	}
+	err = r.LPushR(ctx, key, string(entry))
+	if err != nil {
+		return err
+	}
+	return r.LTrimR(ctx, key, 0, r.configHistoryLength-1)
+}
+
+func (r *RedisSynHeartStore) FetchTestConfigHistory(ctx context.Context, configId string) ([]common.SyntestConfigVersion, error) {
+	entries, err := r.LRangeR(ctx, fmt.Sprintf(ConfigSynTestHistoryFmt, configId), 0, -1)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching config history"+", testName="+configId)
+	}
+	history := []common.SyntestConfigVersion{}
+	for _, entry := range entries {
+		version := common.SyntestConfigVersion{}
+		err = json.Unmarshal([]byte(entry), &version)
+		if err != nil {
+			r.logger.Warn("error unmarshalling config version, skipping", "testName", configId, "err", err)
+			continue
+		}
+		history = append(history, version)
+	}
+	return history, nil
+}
+
+// RollbackTestConfig Writes a previous version of the config (from its history) as the current config, returns
+// ErrNotFound if the version isn't in the history
+func (r *RedisSynHeartStore) RollbackTestConfig(ctx context.Context, configId string, version string) error {
+	history, err := r.FetchTestConfigHistory(ctx, configId)
+	if err != nil {
+		return err
+	}
+	for _, entry := range history {
+		if entry.Version != version {
+			continue
+		}
+		config := proto.SynTestConfig{}
+		err = r.protoJsonUnMarshaller.Unmarshal([]byte(entry.Config), &config)
+		if err != nil {
+			return errors.Wrap(err, "error un-marshalling config version "+version)
+		}
+		return r.WriteTestConfig(ctx, config, entry.Raw)
+	}
+	return ErrNotFound
+}
+
+func (r *RedisSynHeartStore) WriteTestConfigPin(ctx context.Context, configId string, version string) error {
+	err := r.SetR(ctx, fmt.Sprintf(ConfigSynTestPinFmt, configId), version, 0)
+	if err != nil {
+		return errors.Wrap(err, "error writing config pin"+", testName="+configId)
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchTestConfigPin(ctx context.Context, configId string) (string, error) {
+	version, err := r.GetR(ctx, fmt.Sprintf(ConfigSynTestPinFmt, configId))
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrap(err, "error fetching config pin"+", testName="+configId)
+	}
+	return version, nil
+}
+
+func (r *RedisSynHeartStore) DeleteTestConfigPin(ctx context.Context, configId string) error {
+	err := r.DelR(ctx, fmt.Sprintf(ConfigSynTestPinFmt, configId))
+	if err != nil {
+		return errors.Wrap(err, "error deleting config pin"+", testName="+configId)
+	}
+	return nil
+}
+
+// writeTestConfigSummary Writes the summary of the test config, and notifies the agents of the change
+func (r *RedisSynHeartStore) writeTestConfigSummary(ctx context.Context, summary common.SyntestConfigSummary) error {
+	summaryJson, err := json.Marshal(summary)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling config summary")
+	}
+	err = r.HSetR(ctx, ConfigSynTestsSummary, summary.ConfigId, string(summaryJson))
+	if err != nil {
+		return errors.Wrap(err, "error writing config summary to hashmap"+", testName="+summary.ConfigId)
+	}
+	err = r.PublishR(ctx, ConfigChannel, "update "+summary.ConfigId)
+	if err != nil {
+		return errors.Wrap(err, "error publishing to config channel"+", testName="+summary.ConfigId)
+	}
+	return nil
+}
+
 func (r *RedisSynHeartStore) DeleteTestConfig(ctx context.Context, configId string) error {
 	err := r.DelR(ctx, fmt.Sprintf(ConfigSynTestRawFmt, configId))
 	if err != nil {
@@ -334,6 +678,19 @@ func (r *RedisSynHeartStore) DeleteTestConfig(ctx context.Context, configId stri
 		return errors.Wrap(err, "error deleting syntest config status in ext-storage"+", testName="+configId)
 	}
 
+	err = r.DelR(ctx, fmt.Sprintf(ConfigSynTestCanaryFmt, configId))
+	if err != nil {
+		return errors.Wrap(err, "error deleting syntest canary config in ext-storage"+", testName="+configId)
+	}
+	err = r.DelR(ctx, fmt.Sprintf(ConfigSynTestHistoryFmt, configId))
+	if err != nil {
+		return errors.Wrap(err, "error deleting syntest config history in ext-storage"+", testName="+configId)
+	}
+	err = r.DelR(ctx, fmt.Sprintf(ConfigSynTestPinFmt, configId))
+	if err != nil {
+		return errors.Wrap(err, "error deleting syntest config pin in ext-storage"+", testName="+configId)
+	}
+
 	err = r.HDelR(ctx, ConfigSynTestsSummary, configId)
 	if err != nil {
 		return errors.Wrap(err, "error deleting syntest from 'summary' set in ext-storage"+", testName="+configId)
@@ -386,7 +743,7 @@ func (r *RedisSynHeartStore) WritePluginHealthStatus(ctx context.Context, plugin
 		return errors.Wrap(err, "error writing health status to redis, plugin: "+pluginId)
 	}
 
-	if pluginState.Status != common.Running {
+	if pluginState.Status != common.Running && pluginState.Status != common.Paused {
 		badHealthKey := fmt.Sprintf(PluginLastUnhealthyFmt, pluginId)
 		err = r.SetR(ctx, badHealthKey, string(b), 0)
 		if err != nil {
@@ -517,6 +874,364 @@ func (r *RedisSynHeartStore) NewAgentEvent(ctx context.Context, event string) er
 	return nil
 }
 
+func (r *RedisSynHeartStore) RequestTestRun(ctx context.Context, request common.TestRunRequest) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling test run request")
+	}
+	err = r.PublishR(ctx, RunChannel, string(b))
+	if err != nil {
+		return errors.Wrap(err, "error publishing test run request")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) SubscribeToTestRunRequests(ctx context.Context, channelSize int, requestChan chan<- common.TestRunRequest) error {
+	pubsub := r.client.Subscribe(ctx, RunChannel)
+	// Wait for confirmation that subscription is created before publishing anything.
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error subscribing to channel "+RunChannel)
+	}
+	r.logger.Info("successfully subscribed to channel: " + RunChannel)
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("kill signal received, stopping test run request subscription")
+			return nil
+		case msg := <-pubsub.Channel(redis.WithChannelSize(channelSize)):
+			request := common.TestRunRequest{}
+			err := json.Unmarshal([]byte(msg.Payload), &request)
+			if err != nil {
+				r.logger.Warn("error unmarshalling test run request", "payload", msg.Payload, "err", err)
+				continue
+			}
+			requestChan <- request
+		}
+	}
+}
+
+func (r *RedisSynHeartStore) WriteTestRunRequestResult(ctx context.Context, requestId string, agentId string, testRun proto.TestRun) error {
+	b, err := r.protoJsonMarshaller.Marshal(&testRun)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling test run")
+	}
+	key := fmt.Sprintf(TestRunRequestResultsFmt, requestId)
+	err = r.HSetR(ctx, key, agentId, string(b))
+	if err != nil {
+		return errors.Wrap(err, "error writing test run request result")
+	}
+	err = r.ExpireR(ctx, key, common.TestRunRequestRetention)
+	if err != nil {
+		return errors.Wrap(err, "error setting expiry of test run request results")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchTestRunRequestResults(ctx context.Context, requestId string) (map[string]proto.TestRun, error) {
+	results := map[string]proto.TestRun{}
+	all, err := r.HGetAllR(ctx, fmt.Sprintf(TestRunRequestResultsFmt, requestId))
+	if err != nil {
+		return results, errors.Wrap(err, "couldn't fetch test run request results for:"+requestId)
+	}
+	for agentId, runJson := range all {
+		testRun := proto.TestRun{}
+		err = r.protoJsonUnMarshaller.Unmarshal([]byte(runJson), &testRun)
+		if err != nil {
+			r.logger.Warn("error unmarshalling test run request result", "request", requestId, "agent", agentId, "err", err)
+			continue
+		}
+		results[agentId] = testRun
+	}
+	return results, nil
+}
+
+func (r *RedisSynHeartStore) DeleteTestRunRequest(ctx context.Context, requestId string) error {
+	err := r.DelR(ctx, fmt.Sprintf(TestRunRequestResultsFmt, requestId))
+	if err != nil {
+		return errors.Wrap(err, "error deleting test run request results")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) WriteSilence(ctx context.Context, silence common.Silence) error {
+	b, err := json.Marshal(silence)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling silence")
+	}
+	err = r.HSetR(ctx, SilencesAll, silence.Id, string(b))
+	if err != nil {
+		return errors.Wrap(err, "error writing silence to redis")
+	}
+	// let the agents know, so they sync the silences
+	err = r.PublishR(ctx, ConfigChannel, "silence: "+silence.Id)
+	if err != nil {
+		return errors.Wrap(err, "error publishing silence to config channel")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) DeleteSilence(ctx context.Context, silenceId string) error {
+	err := r.HDelR(ctx, SilencesAll, silenceId)
+	if err != nil {
+		return errors.Wrap(err, "error deleting silence from redis")
+	}
+	err = r.PublishR(ctx, ConfigChannel, "silence deleted: "+silenceId)
+	if err != nil {
+		return errors.Wrap(err, "error publishing silence to config channel")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchAllSilences(ctx context.Context) (map[string]common.Silence, error) {
+	silences, err := r.HGetAllR(ctx, SilencesAll)
+	if err != nil {
+		return map[string]common.Silence{}, errors.Wrap(err, "error fetching silences")
+	}
+	allSilences := map[string]common.Silence{}
+	for id, val := range silences {
+		silence := common.Silence{}
+		err := json.Unmarshal([]byte(val), &silence)
+		if err != nil {
+			return map[string]common.Silence{}, errors.Wrap(err, "error unmarshalling silence")
+		}
+		allSilences[id] = silence
+	}
+	return allSilences, nil
+}
+
+func (r *RedisSynHeartStore) WriteAgentProfile(ctx context.Context, profile common.AgentProfile) error {
+	b, err := json.Marshal(profile)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling agent profile")
+	}
+	err = r.HSetR(ctx, AgentProfilesAll, profile.Name, string(b))
+	if err != nil {
+		return errors.Wrap(err, "error writing agent profile to redis")
+	}
+	// let the agents know, so they apply the profile
+	err = r.PublishR(ctx, ConfigChannel, "agent profile: "+profile.Name)
+	if err != nil {
+		return errors.Wrap(err, "error publishing agent profile to config channel")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) DeleteAgentProfile(ctx context.Context, name string) error {
+	err := r.HDelR(ctx, AgentProfilesAll, name)
+	if err != nil {
+		return errors.Wrap(err, "error deleting agent profile from redis")
+	}
+	err = r.PublishR(ctx, ConfigChannel, "agent profile deleted: "+name)
+	if err != nil {
+		return errors.Wrap(err, "error publishing agent profile to config channel")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchAllAgentProfiles(ctx context.Context) (map[string]common.AgentProfile, error) {
+	profiles, err := r.HGetAllR(ctx, AgentProfilesAll)
+	if err != nil {
+		return map[string]common.AgentProfile{}, errors.Wrap(err, "error fetching agent profiles")
+	}
+	allProfiles := map[string]common.AgentProfile{}
+	for name, val := range profiles {
+		profile := common.AgentProfile{}
+		err := json.Unmarshal([]byte(val), &profile)
+		if err != nil {
+			return map[string]common.AgentProfile{}, errors.Wrap(err, "error unmarshalling agent profile")
+		}
+		allProfiles[name] = profile
+	}
+	return allProfiles, nil
+}
+
+func (r *RedisSynHeartStore) FetchAllStoredPluginIds(ctx context.Context) (map[string]bool, error) {
+	pluginIds := map[string]bool{}
+	// the statuses are kept in hashes, so plugins may have a status without any other key (and vice versa)
+	for _, hash := range []string{AllTestRunStatus, AllPluginStatus} {
+		statuses, err := r.HGetAllR(ctx, hash)
+		if err != nil {
+			return map[string]bool{}, errors.Wrap(err, "error fetching "+hash)
+		}
+		for pluginId := range statuses {
+			pluginIds[pluginId] = true
+		}
+	}
+	keys, err := r.ScanR(ctx, SynTestsBase+"/*")
+	if err != nil {
+		return map[string]bool{}, errors.Wrap(err, "error scanning plugin keys")
+	}
+	for _, key := range keys {
+		// keys are <base>/<test name>/<test namespace>/<pod name>/<pod namespace>/<suffix>
+		comp := strings.SplitN(strings.TrimPrefix(key, SynTestsBase+"/"), "/", 5)
+		if len(comp) < 5 || key == AllTestRunStatus || key == AllPluginStatus {
+			continue
+		}
+		pluginIds[strings.Join(comp[:4], "/")] = true
+	}
+	return pluginIds, nil
+}
+
+func (r *RedisSynHeartStore) FetchAllStoredConfigIds(ctx context.Context) (map[string]bool, error) {
+	summaries, err := r.HGetAllR(ctx, ConfigSynTestsSummary)
+	if err != nil {
+		return map[string]bool{}, errors.Wrap(err, "error fetching all test config summaries")
+	}
+	configIds := map[string]bool{}
+	for configId := range summaries {
+		configIds[configId] = true
+	}
+	prefix := ConfigBase + "/syntest/"
+	keys, err := r.ScanR(ctx, prefix+"*")
+	if err != nil {
+		return map[string]bool{}, errors.Wrap(err, "error scanning config keys")
+	}
+	for _, key := range keys {
+		// keys are <base>/syntest/<test name>/<test namespace>/<suffix>
+		comp := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 3)
+		if len(comp) < 3 {
+			continue
+		}
+		configIds[comp[0]+"/"+comp[1]] = true
+	}
+	return configIds, nil
+}
+
+func (r *RedisSynHeartStore) WriteClusterSummary(ctx context.Context, summary common.ClusterSummary) error {
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling cluster summary")
+	}
+	err = r.HSetR(ctx, ClustersAll, summary.Cluster, string(b))
+	if err != nil {
+		return errors.Wrap(err, "error writing cluster summary to redis")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) DeleteClusterSummary(ctx context.Context, cluster string) error {
+	err := r.HDelR(ctx, ClustersAll, cluster)
+	if err != nil {
+		return errors.Wrap(err, "error deleting cluster summary from redis")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchAllClusterSummaries(ctx context.Context) (map[string]common.ClusterSummary, error) {
+	summaries, err := r.HGetAllR(ctx, ClustersAll)
+	if err != nil {
+		return map[string]common.ClusterSummary{}, errors.Wrap(err, "error fetching cluster summaries")
+	}
+	allSummaries := map[string]common.ClusterSummary{}
+	for cluster, val := range summaries {
+		summary := common.ClusterSummary{}
+		err := json.Unmarshal([]byte(val), &summary)
+		if err != nil {
+			return map[string]common.ClusterSummary{}, errors.Wrap(err, "error unmarshalling cluster summary")
+		}
+		allSummaries[cluster] = summary
+	}
+	return allSummaries, nil
+}
+
+func (r *RedisSynHeartStore) WriteControllerHeartbeat(ctx context.Context, heartbeat common.ControllerHeartbeat) error {
+	b, err := json.Marshal(heartbeat)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling controller heartbeat")
+	}
+	err = r.SetR(ctx, ControllerHeartbeatKey, string(b), 0)
+	if err != nil {
+		return errors.Wrap(err, "error writing controller heartbeat to redis")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchControllerHeartbeat(ctx context.Context) (common.ControllerHeartbeat, error) {
+	val, err := r.GetR(ctx, ControllerHeartbeatKey)
+	if errors.Is(err, redis.Nil) {
+		return common.ControllerHeartbeat{}, ErrNotFound
+	} else if err != nil {
+		return common.ControllerHeartbeat{}, errors.Wrap(err, "error fetching controller heartbeat")
+	}
+	heartbeat := common.ControllerHeartbeat{}
+	err = json.Unmarshal([]byte(val), &heartbeat)
+	if err != nil {
+		return common.ControllerHeartbeat{}, errors.Wrap(err, "error unmarshalling controller heartbeat")
+	}
+	return heartbeat, nil
+}
+
+func (r *RedisSynHeartStore) WriteNamespacePolicies(ctx context.Context, policies common.NamespacePolicies) error {
+	b, err := json.Marshal(policies)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling namespace policies")
+	}
+	err = r.SetR(ctx, NamespacePoliciesKey, string(b), 0)
+	if err != nil {
+		return errors.Wrap(err, "error writing namespace policies to redis")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchNamespacePolicies(ctx context.Context) (common.NamespacePolicies, error) {
+	val, err := r.GetR(ctx, NamespacePoliciesKey)
+	if errors.Is(err, redis.Nil) {
+		return common.NamespacePolicies{}, ErrNotFound
+	} else if err != nil {
+		return common.NamespacePolicies{}, errors.Wrap(err, "error fetching namespace policies")
+	}
+	policies := common.NamespacePolicies{}
+	err = json.Unmarshal([]byte(val), &policies)
+	if err != nil {
+		return common.NamespacePolicies{}, errors.Wrap(err, "error unmarshalling namespace policies")
+	}
+	return policies, nil
+}
+
+func (r *RedisSynHeartStore) WriteAuditEvent(ctx context.Context, event common.AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling audit event")
+	}
+	err = r.XAddR(ctx, AuditLog, common.AuditLogMaxLen, map[string]interface{}{"event": string(b)})
+	if err != nil {
+		return errors.Wrap(err, "error writing audit event")
+	}
+	return nil
+}
+
+func (r *RedisSynHeartStore) FetchAuditEvents(ctx context.Context, count int, match func(event common.AuditEvent) bool) ([]common.AuditEvent, error) {
+	events := []common.AuditEvent{}
+	end := "+"
+	for len(events) < count { // newest first, a page at a time till there are enough matching events
+		msgs, err := r.XRevRangeNR(ctx, AuditLog, end, "-", 1000)
+		if err != nil {
+			return events, errors.Wrap(err, "error fetching audit events")
+		}
+		for _, msg := range msgs {
+			val, _ := msg.Values["event"].(string)
+			event := common.AuditEvent{}
+			err := json.Unmarshal([]byte(val), &event)
+			if err != nil {
+				r.logger.Warn("unable to unmarshal audit event, skipping", "id", msg.ID, "err", err)
+				continue
+			}
+			if match == nil || match(event) {
+				events = append(events, event)
+				if len(events) == count {
+					break
+				}
+			}
+		}
+		if len(msgs) < 1000 {
+			break
+		}
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+	return events, nil
+}
+
 func (r *RedisSynHeartStore) GetR(ctx context.Context, key string) (string, error) {
 	r.logger.Trace("redis cmd", "cmd", "get", "key", key)
 	var val *string
@@ -574,6 +1289,22 @@ func (r *RedisSynHeartStore) SetR(ctx context.Context, key string, val interface
 	})
 }
 
+// Sets the expiry of a key
+func (r *RedisSynHeartStore) ExpireR(ctx context.Context, key string, expiration time.Duration) error {
+	r.logger.Trace("redis cmd", "cmd", "expire", "key", key, "expiration", expiration)
+	return retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		err := r.client.Expire(ctx, key, expiration).Err()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "expire", "err", err)
+		}
+		return err
+	})
+}
+
 // Deletes key and val
 func (r *RedisSynHeartStore) DelR(ctx context.Context, key string) error {
 	r.logger.Trace("redis cmd", "cmd", "del", "key", key)
@@ -622,6 +1353,32 @@ func (r *RedisSynHeartStore) HDelR(ctx context.Context, key string, field string
 	})
 }
 
+// Fetches a field of a hashset
+func (r *RedisSynHeartStore) HGetR(ctx context.Context, key string, field string) (string, error) {
+	r.logger.Trace("redis cmd", "cmd", "hget", "key", key, "field", field)
+	var val *string
+	err := retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isRedisNilError := errors.Is(err, redis.Nil)
+		isCtxError := errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError && !isRedisNilError // retry if all these are true
+	}, func() error {
+		res, err := r.client.HGet(ctx, key, field).Result()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "hget", "err", err)
+			return err
+		} else {
+			val = &res
+			return nil
+		}
+	})
+	if val == nil { // sanity check so we dont dereference a nil pointer
+		tmp := ""
+		val = &tmp
+	}
+	return *val, err
+}
+
 // Fetches all fields and value from a hashset
 func (r *RedisSynHeartStore) HGetAllR(ctx context.Context, key string) (map[string]string, error) {
 	r.logger.Trace("redis cmd", "cmd", "hgetall", "key", key)
@@ -646,3 +1403,187 @@ func (r *RedisSynHeartStore) HGetAllR(ctx context.Context, key string) (map[stri
 	}
 	return *val, err
 }
+
+// Adds a member with a score to a sorted set
+func (r *RedisSynHeartStore) ZAddR(ctx context.Context, key string, score float64, member string) error {
+	r.logger.Trace("redis cmd", "cmd", "zadd", "key", key, "score", score, "member", member)
+	return retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		err := r.client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "zadd", "err", err)
+		}
+		return err
+	})
+}
+
+// Removes the members of a sorted set with a score between min and max (inclusive)
+func (r *RedisSynHeartStore) ZRemRangeByScoreR(ctx context.Context, key string, min string, max string) error {
+	r.logger.Trace("redis cmd", "cmd", "zremrangebyscore", "key", key, "min", min, "max", max)
+	return retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		err := r.client.ZRemRangeByScore(ctx, key, min, max).Err()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "zremrangebyscore", "err", err)
+		}
+		return err
+	})
+}
+
+// Fetches the members (and scores) of a sorted set with a score between min and max (inclusive)
+func (r *RedisSynHeartStore) ZRangeByScoreWithScoresR(ctx context.Context, key string, min string, max string) ([]redis.Z, error) {
+	r.logger.Trace("redis cmd", "cmd", "zrangebyscore", "key", key, "min", min, "max", max)
+	var val []redis.Z
+	err := retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		res, err := r.client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "zrangebyscore", "err", err)
+			return err
+		}
+		val = res
+		return nil
+	})
+	return val, err
+}
+
+// Appends an entry to a stream, trimming it to approximately maxLen entries
+func (r *RedisSynHeartStore) XAddR(ctx context.Context, key string, maxLen int64, values map[string]interface{}) error {
+	r.logger.Trace("redis cmd", "cmd", "xadd", "key", key)
+	return retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		err := r.client.XAdd(ctx, &redis.XAddArgs{Stream: key, MaxLen: maxLen, Approx: true, Values: values}).Err()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "xadd", "err", err)
+		}
+		return err
+	})
+}
+
+// Fetches up to count entries of a stream between the ids end and start, in reverse order (newest first)
+func (r *RedisSynHeartStore) XRevRangeNR(ctx context.Context, key string, end string, start string, count int64) ([]redis.XMessage, error) {
+	r.logger.Trace("redis cmd", "cmd", "xrevrange", "key", key, "end", end, "start", start, "count", count)
+	var val []redis.XMessage
+	err := retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		res, err := r.client.XRevRangeN(ctx, key, end, start, count).Result()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "xrevrange", "err", err)
+			return err
+		}
+		val = res
+		return nil
+	})
+	return val, err
+}
+
+// Fetches the members of a sorted set between the start and stop indexes, ordered from the highest to the lowest score
+func (r *RedisSynHeartStore) ZRevRangeR(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	r.logger.Trace("redis cmd", "cmd", "zrevrange", "key", key, "start", start, "stop", stop)
+	val := []string{}
+	err := retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		res, err := r.client.ZRevRange(ctx, key, start, stop).Result()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "zrevrange", "err", err)
+			return err
+		}
+		val = res
+		return nil
+	})
+	return val, err
+}
+
+// Returns all the keys matching the pattern, scanning the keyspace in batches (so redis isn't blocked like with KEYS)
+func (r *RedisSynHeartStore) ScanR(ctx context.Context, match string) ([]string, error) {
+	r.logger.Trace("redis cmd", "cmd", "scan", "match", match)
+	val := []string{}
+	err := retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		keys := []string{}
+		iter := r.client.Scan(ctx, 0, match, 1000).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "scan", "err", err)
+			return err
+		}
+		val = keys
+		return nil
+	})
+	return val, err
+}
+
+// Prepends a value to a list
+func (r *RedisSynHeartStore) LPushR(ctx context.Context, key string, val string) error {
+	r.logger.Trace("redis cmd", "cmd", "lpush", "key", key)
+	return retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		err := r.client.LPush(ctx, key, val).Err()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "lpush", "err", err)
+		}
+		return err
+	})
+}
+
+// Trims a list to the elements between the start and stop indexes
+func (r *RedisSynHeartStore) LTrimR(ctx context.Context, key string, start int64, stop int64) error {
+	r.logger.Trace("redis cmd", "cmd", "ltrim", "key", key, "start", start, "stop", stop)
+	return retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		err := r.client.LTrim(ctx, key, start, stop).Err()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "ltrim", "err", err)
+		}
+		return err
+	})
+}
+
+// Fetches the elements of a list between the start and stop indexes
+func (r *RedisSynHeartStore) LRangeR(ctx context.Context, key string, start int64, stop int64) ([]string, error) {
+	r.logger.Trace("redis cmd", "cmd", "lrange", "key", key, "start", start, "stop", stop)
+	val := []string{}
+	err := retry.OnError(common.DefaultBackoff, func(err error) bool {
+		_, isRedisError := err.(redis.Error)
+		isCtxError := goerrors.Is(err, context.DeadlineExceeded) || goerrors.Is(err, context.Canceled)
+		return err != nil && !isRedisError && !isCtxError
+	}, func() error {
+		res, err := r.client.LRange(ctx, key, start, stop).Result()
+		if err != nil {
+			r.logger.Error("redis error, trying again...", "cmd", "lrange", "err", err)
+			return err
+		}
+		val = res
+		return nil
+	})
+	return val, err
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package testspec
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateQuota Checks the repeat interval and timeouts of a test are within the limits of the quota (the number of
+// tests is checked by the caller, as it needs the other tests of the namespace). Invalid limits are ignored.
+func ValidateQuota(quota common.SynTestQuota, spec *Spec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	if minRepeat, err := time.ParseDuration(quota.MinRepeat); err == nil && minRepeat > 0 {
+		// a repeat of 0 only runs the test when the tests it depends on run
+		if repeat, err := time.ParseDuration(spec.Repeat); err == nil && repeat > 0 && repeat < minRepeat {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("repeat"),
+				fmt.Sprintf("%s is shorter than the minRepeat (%s) of quota '%s'", spec.Repeat, quota.MinRepeat, quota.Name)))
+		}
+	}
+
+	if maxTimeout, err := time.ParseDuration(quota.MaxTimeout); err == nil && maxTimeout > 0 && spec.Timeouts != nil {
+		timeoutsPath := specPath.Child("timeouts")
+		timeouts := []struct{ name, timeout string }{
+			{"init", spec.Timeouts.Init}, {"run", spec.Timeouts.Run}, {"finish", spec.Timeouts.Finish},
+		}
+		for _, t := range timeouts {
+			if d, err := time.ParseDuration(t.timeout); err == nil && d > maxTimeout {
+				allErrs = append(allErrs, field.Forbidden(timeoutsPath.Child(t.name),
+					fmt.Sprintf("%s is longer than the maxTimeout (%s) of quota '%s'", t.timeout, quota.MaxTimeout, quota.Name)))
+			}
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package testspec
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// inventoryFuncs are the functions which can be used in the configs of tests referencing inventories
+var inventoryFuncs = template.FuncMap{
+	"join": func(sep string, targets []string) string {
+		return strings.Join(targets, sep)
+	},
+}
+
+// InventoryData is the data the config of a test referencing inventories is rendered with
+type InventoryData struct {
+	// Targets of all the inventories of the test (in order, without duplicates)
+	Targets []string
+	// Inventories are the targets of each inventory, by the reference of the test (e.g. "vips")
+	Inventories map[string][]string
+}
+
+// ValidateInventoryRef Returns an error if the reference isn't an inventory name, or <namespace>/<name>
+func ValidateInventoryRef(ref string) error {
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found {
+		namespace, name = "", ref
+	} else if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+		return fmt.Errorf("invalid namespace of inventory '%s': %s", ref, msgs[0])
+	}
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		return fmt.Errorf("invalid inventory '%s': %s", ref, msgs[0])
+	}
+	return nil
+}
+
+// RenderConfig Returns the config of a test rendered with the targets of its inventories (by reference), the config
+// of tests referencing inventories is a go template, e.g. "{{ range .Targets }}..."
+func RenderConfig(config string, refs []string, inventories map[string][]string) (string, error) {
+	tmpl, err := template.New("config").Funcs(inventoryFuncs).Option("missingkey=error").Parse(config)
+	if err != nil {
+		return "", fmt.Errorf("invalid config template: %w", err)
+	}
+	data := InventoryData{Targets: []string{}, Inventories: map[string][]string{}}
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		targets := inventories[ref]
+		data.Inventories[ref] = targets
+		for _, target := range targets {
+			if !seen[target] {
+				seen[target] = true
+				data.Targets = append(data.Targets, target)
+			}
+		}
+	}
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, data)
+	if err != nil {
+		return "", fmt.Errorf("error rendering config: %w", err)
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package testspec
+
+import (
+	"fmt"
+)
+
+// SuiteTest is a test of a suite, its spec is merged with the shared settings of the suite
+type SuiteTest struct {
+	Name string `json:"name"`
+	Spec Spec   `json:"spec"`
+}
+
+// SuiteSpec is the spec of a suite, the same fields (and json) as the spec of a SyntheticTestSuite
+type SuiteSpec struct {
+	Node             string            `json:"node,omitempty"`
+	PodLabelSelector map[string]string `json:"podLabelSelector,omitempty"`
+	Repeat           string            `json:"repeat,omitempty"`
+	Importance       string            `json:"importance,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	MetricLabels     map[string]string `json:"metricLabels,omitempty"`
+	Alerting         *Alerting         `json:"alerting,omitempty"`
+	Tests            []SuiteTest       `json:"tests"`
+}
+
+// Expand Returns the tests of the suite (by their name in the suite), with the shared settings of the suite merged
+// into their specs
+func (s *SuiteSpec) Expand() ([]SuiteTest, error) {
+	names := map[string]bool{}
+	tests := []SuiteTest{}
+	for _, test := range s.Tests {
+		if test.Name == "" {
+			return nil, fmt.Errorf("test with plugin '%s' has no name", test.Spec.Plugin)
+		}
+		if names[test.Name] {
+			return nil, fmt.Errorf("duplicate test name '%s'", test.Name)
+		}
+		names[test.Name] = true
+
+		spec := test.Spec.DeepCopy()
+		// the selectors are used together, so only take them if the test has neither
+		if spec.Node == "" && len(spec.PodLabelSelector) == 0 {
+			spec.Node = s.Node
+			for k, v := range s.PodLabelSelector {
+				if spec.PodLabelSelector == nil {
+					spec.PodLabelSelector = map[string]string{}
+				}
+				spec.PodLabelSelector[k] = v
+			}
+		}
+		if spec.Repeat == "" {
+			spec.Repeat = s.Repeat
+		}
+		if spec.Repeat == "" {
+			return nil, fmt.Errorf("test '%s' has no repeat, and the suite has no default", test.Name)
+		}
+		if spec.Importance == "" {
+			spec.Importance = s.Importance
+		}
+		if spec.Alerting == nil && s.Alerting != nil {
+			spec.Alerting = &Alerting{}
+			Convert(s.Alerting, spec.Alerting)
+		}
+		if len(s.MetricLabels) > 0 {
+			metricLabels := map[string]string{}
+			for k, v := range s.MetricLabels {
+				metricLabels[k] = v
+			}
+			for k, v := range spec.MetricLabels {
+				metricLabels[k] = v
+			}
+			spec.MetricLabels = metricLabels
+		}
+		tests = append(tests, SuiteTest{Name: test.Name, Spec: *spec})
+	}
+	return tests, nil
+}
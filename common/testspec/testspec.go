@@ -0,0 +1,181 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package testspec
+
+// package containing the spec of the tests as the users write it (the spec of a SyntheticTest), with its defaults,
+// its validation and the expansion of the suites. It's shared by the webhooks of the controller and synheartctl lint,
+// which validates manifests offline without depending on the controller.
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+// Group is the api group of the CRDs of the tests
+const Group = "synheart.infra.webex.com"
+
+// Timeouts of the plugin of the test
+type Timeouts struct {
+	Init   string `json:"init,omitempty"`
+	Run    string `json:"run,omitempty"`
+	Finish string `json:"finish,omitempty"`
+}
+
+// Alerting defines when and how alerts are sent for the test
+type Alerting struct {
+	FailureThreshold int32             `json:"failureThreshold,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
+	Disabled         bool              `json:"disabled,omitempty"`
+	SlackChannel     string            `json:"slackChannel,omitempty"`
+}
+
+// SLO defines the availability SLO of the test
+type SLO struct {
+	Target string `json:"target"`
+	Window string `json:"window,omitempty"`
+}
+
+// Rollout defines how changes to the config of the test are rolled out to the agents
+type Rollout struct {
+	CanaryPercent  int32  `json:"canaryPercent,omitempty"`
+	VerifyDuration string `json:"verifyDuration,omitempty"`
+}
+
+// ActiveWindow is a recurring window of time a test runs in
+type ActiveWindow struct {
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+// SecretRef references a key of a kubernetes secret, rendered into the config by the agents
+type SecretRef struct {
+	Name      string `json:"name"`
+	Secret    string `json:"secret"`
+	Key       string `json:"key"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Spec is the spec of a test, the same fields (and json) as the spec of a SyntheticTest (see the controller for
+// their documentation)
+type Spec struct {
+	Plugin              string            `json:"plugin"`
+	Node                string            `json:"node,omitempty"`
+	PodLabelSelector    map[string]string `json:"podLabelSelector,omitempty"`
+	DisplayName         string            `json:"displayName,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	Importance          string            `json:"importance,omitempty"`
+	Repeat              string            `json:"repeat"`
+	DependsOn           []string          `json:"dependsOn,omitempty"`
+	Timeouts            *Timeouts         `json:"timeouts,omitempty"`
+	PluginRestartPolicy string            `json:"pluginRestartPolicy,omitempty"`
+	LogWaitTime         string            `json:"logWaitTime,omitempty"`
+	Config              string            `json:"config,omitempty"`
+	MetricLabels        map[string]string `json:"metricLabels,omitempty"`
+	Alerting            *Alerting         `json:"alerting,omitempty"`
+	SLO                 *SLO              `json:"slo,omitempty"`
+	Rollout             *Rollout          `json:"rollout,omitempty"`
+	ActiveFrom          string            `json:"activeFrom,omitempty"`
+	ActiveUntil         string            `json:"activeUntil,omitempty"`
+	ActiveWindows       []ActiveWindow    `json:"activeWindows,omitempty"`
+	TargetInventories   []string          `json:"targetInventories,omitempty"`
+	SecretRefs          []SecretRef       `json:"secretRefs,omitempty"`
+}
+
+// ProtoActiveWindows Returns the active windows of the test for its config
+func (spec *Spec) ProtoActiveWindows() []*proto.ActiveWindow {
+	var windows []*proto.ActiveWindow
+	for _, w := range spec.ActiveWindows {
+		windows = append(windows, &proto.ActiveWindow{Days: w.Days, Start: w.Start, End: w.End, Timezone: w.Timezone})
+	}
+	return windows
+}
+
+// ProtoSecretRefs Returns the secret refs of the test for its config
+func (spec *Spec) ProtoSecretRefs() []*proto.SecretRef {
+	var refs []*proto.SecretRef
+	for _, r := range spec.SecretRefs {
+		refs = append(refs, &proto.SecretRef{Name: r.Name, Secret: r.Secret, Key: r.Key, Namespace: r.Namespace})
+	}
+	return refs
+}
+
+// DeepCopy Returns a copy of the spec
+func (spec *Spec) DeepCopy() *Spec {
+	out := Spec{}
+	Convert(spec, &out)
+	return &out
+}
+
+// Convert Copies a spec to another type with the same json (out is a pointer), e.g. between the spec of a SyntheticTest
+// and a Spec. Out is reset first, as unmarshalling merges maps. It can't fail, as the specs only have plain fields.
+func Convert(in interface{}, out interface{}) {
+	v := reflect.ValueOf(out).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	b, _ := json.Marshal(in)
+	_ = json.Unmarshal(b, out)
+}
+
+// Default Fills in the defaults of the spec (the same the agents use), and trims the whitespace of the selectors.
+// Invalid values are left as they are, so the validation rejects them.
+func Default(spec *Spec) {
+	if spec.Repeat == "" {
+		spec.Repeat = common.DefaultRepeat.String()
+		if len(spec.DependsOn) > 0 {
+			spec.Repeat = "0s" // only run when the tests it depends on run
+		}
+	}
+	if spec.Timeouts == nil {
+		spec.Timeouts = &Timeouts{}
+	}
+	if spec.Timeouts.Init == "" {
+		spec.Timeouts.Init = common.DefaultInitTimeout.String()
+	}
+	if spec.Timeouts.Run == "" {
+		spec.Timeouts.Run = common.DefaultRunTimeout.String()
+	}
+	if spec.Timeouts.Finish == "" {
+		spec.Timeouts.Finish = common.DefaultFinishTimeout.String()
+	}
+	if spec.LogWaitTime == "" {
+		spec.LogWaitTime = common.DefaultLogWaitTime.String()
+	}
+	if spec.PluginRestartPolicy == "" {
+		spec.PluginRestartPolicy = string(common.DefaultRestartPolicy)
+	}
+	if spec.Importance == "" {
+		spec.Importance = common.DefaultImportance
+	}
+
+	spec.Node = strings.TrimSpace(spec.Node)
+	if spec.Node == "" {
+		spec.Node = "*" // runs on all nodes
+	}
+	if len(spec.PodLabelSelector) > 0 {
+		selector := make(map[string]string, len(spec.PodLabelSelector))
+		for k, v := range spec.PodLabelSelector {
+			selector[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		spec.PodLabelSelector = selector
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package testspec
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/activation"
+	"github.com/cisco-open/synthetic-heart/common/pluginconfig"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/slo"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var metricLabelRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Validate Validates a spec the way the webhook of the controller does, without the quotas which need the cluster. The
+// plugin is checked against the known plugins (skipped if nil). It's also used to lint manifests offline (synheartctl
+// lint).
+func Validate(spec *Spec, plugins map[string]bool) ([]string, field.ErrorList) {
+	allErrs := ValidatePlugin(spec, plugins)
+	allErrs = append(allErrs, ValidateSpec(spec)...)
+	var warnings []string
+	repeat, err := time.ParseDuration(spec.Repeat)
+	if err != nil {
+		return warnings, allErrs
+	}
+	if repeat == 0 && len(spec.DependsOn) == 0 {
+		warnings = append(warnings, "test has no repeat interval and doesn't depend on other tests, so it will never run")
+	}
+	if repeat > 0 && repeat < time.Second {
+		warnings = append(warnings, fmt.Sprintf("repeat interval %s is under a second", repeat))
+	}
+	if spec.Timeouts != nil && repeat > 0 {
+		if run, err := time.ParseDuration(spec.Timeouts.Run); err == nil && run > repeat {
+			warnings = append(warnings, fmt.Sprintf("run timeout %s is longer than the repeat interval %s", run, repeat))
+		}
+	}
+	return warnings, allErrs
+}
+
+// ValidatePlugin Checks the plugin is set, and is one of the known plugins (if they're not nil)
+func ValidatePlugin(spec *Spec, plugins map[string]bool) field.ErrorList {
+	pluginPath := field.NewPath("spec").Child("plugin")
+	if spec.Plugin == "" {
+		return field.ErrorList{field.Required(pluginPath, "plugin is required")}
+	}
+	if plugins == nil || plugins[spec.Plugin] {
+		return nil
+	}
+	var names []string
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return field.ErrorList{field.NotSupported(pluginPath, spec.Plugin, names)}
+}
+
+// ValidateSpec Validates the fields of the spec which don't need any external info
+func ValidateSpec(spec *Spec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	allErrs = append(allErrs, validateDuration(specPath.Child("repeat"), spec.Repeat, true)...)
+	allErrs = append(allErrs, validateDuration(specPath.Child("logWaitTime"), spec.LogWaitTime, false)...)
+	if spec.Timeouts != nil {
+		timeoutsPath := specPath.Child("timeouts")
+		allErrs = append(allErrs, validateDuration(timeoutsPath.Child("init"), spec.Timeouts.Init, false)...)
+		allErrs = append(allErrs, validateDuration(timeoutsPath.Child("run"), spec.Timeouts.Run, false)...)
+		allErrs = append(allErrs, validateDuration(timeoutsPath.Child("finish"), spec.Timeouts.Finish, false)...)
+	}
+
+	// selectors are glob patterns matched against the node name and the agent's pod labels
+	if _, err := filepath.Match(strings.ReplaceAll(spec.Node, "$", "*"), ""); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("node"), spec.Node, "malformed glob pattern"))
+	}
+	selectorPath := specPath.Child("podLabelSelector")
+	for k, val := range spec.PodLabelSelector {
+		switch k {
+		case common.SpecialKeyAgentId, common.SpecialKeyAgentNs, common.SpecialKeyPodName:
+		default:
+			for _, msg := range validation.IsQualifiedName(k) {
+				allErrs = append(allErrs, field.Invalid(selectorPath.Key(k), k, msg))
+			}
+		}
+		if _, err := filepath.Match(val, ""); err != nil {
+			allErrs = append(allErrs, field.Invalid(selectorPath.Key(k), val, "malformed glob pattern"))
+		}
+	}
+	if strings.Contains(spec.Node, "$") && spec.PodLabelSelector[common.SpecialKeyPodName] == "$" {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("node"), spec.Node,
+			"can't have '$' in both node and podLabelSelector, use only one"))
+	}
+
+	switch common.PluginRestartPolicy(spec.PluginRestartPolicy) {
+	case "", common.RestartAlways, common.RestartNever, common.RestartOnError:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("pluginRestartPolicy"), spec.PluginRestartPolicy,
+			[]string{string(common.RestartAlways), string(common.RestartNever), string(common.RestartOnError)}))
+	}
+	if _, ok := common.ImportanceWeights[spec.Importance]; spec.Importance != "" && !ok {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("importance"), spec.Importance,
+			[]string{common.ImportanceCritical, common.ImportanceHigh, common.ImportanceMedium, common.ImportanceLow}))
+	}
+
+	for k := range spec.MetricLabels {
+		if !metricLabelRegex.MatchString(k) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("metricLabels").Key(k), k, "must be a valid prometheus label name"))
+		}
+	}
+	if spec.Alerting != nil && spec.Alerting.FailureThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("alerting", "failureThreshold"), spec.Alerting.FailureThreshold, "must be >= 0"))
+	}
+	if spec.SLO != nil {
+		if _, _, err := slo.Parse(&proto.SLO{Target: spec.SLO.Target, Window: spec.SLO.Window}); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("slo"), spec.SLO, err.Error()))
+		}
+	}
+	if _, err := activation.Parse(&proto.SynTestConfig{ActiveFrom: spec.ActiveFrom, ActiveUntil: spec.ActiveUntil}); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("activeFrom"), spec.ActiveFrom+" - "+spec.ActiveUntil, err.Error()))
+	}
+	if _, err := activation.Parse(&proto.SynTestConfig{ActiveWindows: spec.ProtoActiveWindows()}); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("activeWindows"), spec.ActiveWindows, err.Error()))
+	}
+
+	if err := common.ValidateSecretRefs(spec.ProtoSecretRefs()); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("secretRefs"), spec.SecretRefs, err.Error()))
+	}
+
+	if spec.Rollout != nil {
+		rolloutPath := specPath.Child("rollout")
+		if spec.Rollout.CanaryPercent < 0 || spec.Rollout.CanaryPercent > 100 {
+			allErrs = append(allErrs, field.Invalid(rolloutPath.Child("canaryPercent"), spec.Rollout.CanaryPercent, "must be between 0 and 100"))
+		}
+		allErrs = append(allErrs, validateDuration(rolloutPath.Child("verifyDuration"), spec.Rollout.VerifyDuration, false)...)
+	}
+
+	// all plugins parse their config as yaml, the config of tests referencing inventories is checked once rendered
+	// (without any targets)
+	rendered := spec.Config
+	if len(spec.TargetInventories) > 0 {
+		for i, ref := range spec.TargetInventories {
+			if err := ValidateInventoryRef(ref); err != nil {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("targetInventories").Index(i), ref, err.Error()))
+			}
+		}
+		var err error
+		rendered, err = RenderConfig(spec.Config, spec.TargetInventories, map[string][]string{})
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("config"), spec.Config, err.Error()))
+		}
+	}
+	var config interface{}
+	if err := yaml.Unmarshal([]byte(rendered), &config); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("config"), spec.Config, "invalid yaml: "+err.Error()))
+	} else if errs := pluginconfig.Validate(spec.Plugin, rendered); len(errs) > 0 {
+		// the config of the built-in plugins is also decoded strictly into their config type
+		allErrs = append(allErrs, field.Invalid(specPath.Child("config"), spec.Config,
+			fmt.Sprintf("invalid config for the %s plugin: %s", spec.Plugin, strings.Join(errs, "; "))))
+	}
+	return allErrs
+}
+
+func validateDuration(path *field.Path, value string, required bool) field.ErrorList {
+	if value == "" {
+		if required {
+			return field.ErrorList{field.Required(path, "duration is required, e.g. 5m")}
+		}
+		return nil
+	}
+	dur, err := time.ParseDuration(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, value, "must be a duration, e.g. 5m")}
+	}
+	if dur < 0 {
+		return field.ErrorList{field.Invalid(path, value, "must not be negative")}
+	}
+	return nil
+}
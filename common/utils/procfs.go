@@ -0,0 +1,54 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReadProcUint Reads a file containing a single number (e.g. /proc/sys/net/netfilter/nf_conntrack_max)
+func ReadProcUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "error parsing "+path)
+	}
+	return n, nil
+}
+
+// ReadConntrackUsage Returns the number of entries in, and the max size of, the conntrack table.
+// Note: conntrack is per network namespace (of the reader), so the caller must be in the host network namespace to get
+// the node's usage
+func ReadConntrackUsage(procPath string) (count uint64, max uint64, err error) {
+	count, err = ReadProcUint(filepath.Join(procPath, "sys/net/netfilter/nf_conntrack_count"))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "error reading conntrack count")
+	}
+	max, err = ReadProcUint(filepath.Join(procPath, "sys/net/netfilter/nf_conntrack_max"))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "error reading conntrack max")
+	}
+	return count, max, nil
+}
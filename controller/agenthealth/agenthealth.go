@@ -0,0 +1,234 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agenthealth
+
+// package containing code to detect agents which stopped reporting (while their pod is running), and emit events and
+// metrics for them and the tests they were running, so a test not running on a node isn't mistaken for a passing test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/cisco-open/synthetic-heart/controller/sync"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	DefaultInterval = 30 * time.Second
+
+	ReasonAgentStale     = "AgentStale"
+	ReasonAgentRecovered = "AgentRecovered"
+	ReasonTestNotRunning = "TestNotRunning"
+	ReasonTestRunning    = "TestRunning"
+)
+
+var (
+	agentUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricAgentUp,
+		Help: "Whether the agent (with a running pod) posted a status within the agent status deadline",
+	}, []string{"agent", "node"})
+	statusAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricAgentStatusAge,
+		Help: "Seconds since the last status posted by the agent",
+	}, []string{"agent", "node"})
+	testNotRunning = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricTestNotRunning,
+		Help: "The test isn't running on the node, as the agent which was running it stopped reporting",
+	}, []string{"test_name", "test_namespace", "node"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(agentUp, statusAge, testNotRunning)
+}
+
+// Watcher checks the status of the agents of the running agent pods, and emits events when they go stale or recover
+type Watcher struct {
+	logger    hclog.Logger
+	store     storage.SynHeartStore
+	k8sClient client.Client
+	recorder  record.EventRecorder
+	deadline  time.Duration
+	stale     map[string][]string // tests of the stale agents (from their last status), by agent id
+}
+
+func NewWatcher(logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client, recorder record.EventRecorder) *Watcher {
+	return &Watcher{
+		logger:    logger,
+		store:     store,
+		k8sClient: k8sClient,
+		recorder:  recorder,
+		deadline:  sync.AgentStatusDeadline(logger),
+		stale:     map[string][]string{},
+	}
+}
+
+// Interval Returns how often the agents should be checked (AGENT_HEALTH_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("AGENT_HEALTH_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse AGENT_HEALTH_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Check Checks the status of the agent of every running agent pod, an agent is stale if it didn't post a status within
+// the agent status deadline (pods which started within the deadline are given time to register). The result is written
+// in the heartbeat of the controller.
+func (w *Watcher) Check(ctx context.Context) error {
+	var podList corev1.PodList
+	err := w.k8sClient.List(ctx, &podList, client.MatchingLabels{common.K8sDiscoverLabel: common.K8sDiscoverLabelVal})
+	if err != nil {
+		return errors.Wrap(err, "error listing agent pods")
+	}
+	agents, err := w.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching agents")
+	}
+
+	// reset the metrics, so agents without a running pod (and recovered tests) are removed
+	agentUp.Reset()
+	statusAge.Reset()
+	testNotRunning.Reset()
+	now := time.Now()
+	expected := map[string]bool{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		agentId := common.ComputeAgentId(pod.Name, pod.Namespace)
+		expected[agentId] = true
+		node := pod.Spec.NodeName
+
+		agent, registered := agents[agentId]
+		var lastStatus time.Time
+		if registered && agent.StatusTime != "" {
+			lastStatus, _ = time.Parse(common.TimeFormat, agent.StatusTime)
+		}
+		if lastStatus.IsZero() {
+			// never reported, measure from the start of the pod
+			if pod.Status.StartTime != nil {
+				lastStatus = pod.Status.StartTime.Time
+			} else {
+				lastStatus = pod.CreationTimestamp.Time
+			}
+		}
+		age := now.Sub(lastStatus)
+		statusAge.WithLabelValues(agentId, node).Set(age.Seconds())
+		if age < w.deadline {
+			agentUp.WithLabelValues(agentId, node).Set(1)
+			if tests, wasStale := w.stale[agentId]; wasStale {
+				delete(w.stale, agentId)
+				w.recovered(ctx, pod, agentId, tests)
+			}
+			continue
+		}
+
+		agentUp.WithLabelValues(agentId, node).Set(0)
+		tests, wasStale := w.stale[agentId]
+		if !wasStale {
+			tests = agent.SynTests
+			w.stale[agentId] = tests
+			w.wentStale(ctx, pod, agentId, age, tests)
+		}
+		for _, configId := range tests {
+			name, ns, _ := strings.Cut(configId, "/")
+			testNotRunning.WithLabelValues(name, ns, node).Set(1)
+		}
+	}
+
+	// forget the agents whose pod is gone (e.g. the node was removed), the others are still stale
+	heartbeat := common.ControllerHeartbeat{Time: now, ExpectedAgents: len(expected)}
+	for agentId := range w.stale {
+		if !expected[agentId] {
+			delete(w.stale, agentId)
+			continue
+		}
+		heartbeat.StaleAgents = append(heartbeat.StaleAgents, agentId)
+	}
+	slices.Sort(heartbeat.StaleAgents)
+	heartbeat.LiveAgents = heartbeat.ExpectedAgents - len(heartbeat.StaleAgents)
+
+	// the heartbeat shows the controller is running, and the agents it expects (see the system health of the rest api)
+	err = w.store.WriteControllerHeartbeat(ctx, heartbeat)
+	if err != nil {
+		return errors.Wrap(err, "error writing controller heartbeat")
+	}
+	return nil
+}
+
+// wentStale Emits the events of an agent which stopped reporting, on its pod and on the tests it was running
+func (w *Watcher) wentStale(ctx context.Context, pod *corev1.Pod, agentId string, age time.Duration, tests []string) {
+	w.logger.Warn("agent stopped reporting", "agent", agentId, "node", pod.Spec.NodeName, "lastStatus", age.Round(time.Second))
+	w.recorder.Eventf(pod, corev1.EventTypeWarning, ReasonAgentStale,
+		"agent %s on node %s hasn't posted a status for %s, its %d tests aren't running", agentId, pod.Spec.NodeName,
+		age.Round(time.Second), len(tests))
+	for _, configId := range tests {
+		if obj := w.fetchTest(ctx, configId); obj != nil {
+			w.recorder.Eventf(obj, corev1.EventTypeWarning, ReasonTestNotRunning,
+				"test isn't running on node %s, agent %s stopped reporting", pod.Spec.NodeName, agentId)
+		}
+	}
+}
+
+// recovered Emits the events of an agent which reports again, on its pod and on the tests it was running
+func (w *Watcher) recovered(ctx context.Context, pod *corev1.Pod, agentId string, tests []string) {
+	w.logger.Info("agent reporting again", "agent", agentId, "node", pod.Spec.NodeName)
+	w.recorder.Eventf(pod, corev1.EventTypeNormal, ReasonAgentRecovered,
+		"agent %s on node %s is reporting again", agentId, pod.Spec.NodeName)
+	for _, configId := range tests {
+		if obj := w.fetchTest(ctx, configId); obj != nil {
+			w.recorder.Eventf(obj, corev1.EventTypeNormal, ReasonTestRunning,
+				"test is running again on node %s, agent %s is reporting again", pod.Spec.NodeName, agentId)
+		}
+	}
+}
+
+// fetchTest Returns the SyntheticTest (or ClusterSyntheticTest) of the config id, nil if it doesn't exist
+func (w *Watcher) fetchTest(ctx context.Context, configId string) client.Object {
+	name, ns, _ := strings.Cut(configId, "/")
+	var obj client.Object = &v1.SyntheticTest{}
+	key := types.NamespacedName{Name: name, Namespace: ns}
+	if ns == common.ClusterTestNamespace {
+		obj = &v1.ClusterSyntheticTest{}
+		key.Namespace = ""
+	}
+	err := w.k8sClient.Get(ctx, key, obj)
+	if err != nil {
+		w.logger.Debug("unable to fetch synthetic test, not emitting event", "configId", configId, "err", err)
+		return nil
+	}
+	return obj
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package agentregistry
+
+// package containing code to materialize the agents registered in storage as SynHeartAgent objects (in the namespace
+// and with the name of their pod), so they can be listed with kubectl and watched by automation
+
+import (
+	"context"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/cisco-open/synthetic-heart/controller/sync"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const DefaultInterval = 30 * time.Second
+
+// Registry keeps a SynHeartAgent object for every agent in storage with a pod
+type Registry struct {
+	logger    hclog.Logger
+	store     storage.SynHeartStore
+	k8sClient client.Client
+	scheme    *runtime.Scheme
+	deadline  time.Duration
+}
+
+func NewRegistry(logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client, scheme *runtime.Scheme) *Registry {
+	return &Registry{
+		logger:    logger,
+		store:     store,
+		k8sClient: k8sClient,
+		scheme:    scheme,
+		deadline:  sync.AgentStatusDeadline(logger),
+	}
+}
+
+// Interval Returns how often the SynHeartAgents should be synced (AGENT_REGISTRY_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("AGENT_REGISTRY_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse AGENT_REGISTRY_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Sync Creates or updates the SynHeartAgent of every agent with a pod, and deletes the ones of agents which are gone.
+// The objects are owned by the pods, so they're also garbage collected with them.
+func (r *Registry) Sync(ctx context.Context) error {
+	var podList corev1.PodList
+	err := r.k8sClient.List(ctx, &podList, client.MatchingLabels{common.K8sDiscoverLabel: common.K8sDiscoverLabelVal})
+	if err != nil {
+		return errors.Wrap(err, "error listing agent pods")
+	}
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching agents")
+	}
+
+	registered := map[string]bool{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		agentId := common.ComputeAgentId(pod.Name, pod.Namespace)
+		agent, ok := agents[agentId]
+		if !ok || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		registered[agentId] = true
+		err = r.syncAgent(ctx, pod, agentId, agent)
+		if err != nil {
+			r.logger.Warn("error syncing synheart agent", "agent", agentId, "err", err)
+		}
+	}
+
+	var agentList v1.SynHeartAgentList
+	err = r.k8sClient.List(ctx, &agentList)
+	if err != nil {
+		return errors.Wrap(err, "error listing synheart agents")
+	}
+	for i := range agentList.Items {
+		obj := &agentList.Items[i]
+		if registered[common.ComputeAgentId(obj.Name, obj.Namespace)] {
+			continue
+		}
+		r.logger.Info("deleting synheart agent of agent which is gone", "name", obj.Name, "namespace", obj.Namespace)
+		err = r.k8sClient.Delete(ctx, obj)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			r.logger.Warn("error deleting synheart agent", "name", obj.Name, "namespace", obj.Namespace, "err", err)
+		}
+	}
+	return nil
+}
+
+// syncAgent Creates the SynHeartAgent of the agent if it doesn't exist, and updates its status if it changed
+func (r *Registry) syncAgent(ctx context.Context, pod *corev1.Pod, agentId string, agent common.AgentStatus) error {
+	obj := &v1.SynHeartAgent{}
+	err := r.k8sClient.Get(ctx, types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, obj)
+	if k8serrors.IsNotFound(err) {
+		obj = &v1.SynHeartAgent{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+		err = controllerutil.SetOwnerReference(pod, obj, r.scheme)
+		if err != nil {
+			return errors.Wrap(err, "error setting owner of synheart agent")
+		}
+		err = r.k8sClient.Create(ctx, obj)
+		if err != nil {
+			return errors.Wrap(err, "error creating synheart agent")
+		}
+		r.logger.Info("created synheart agent", "agent", agentId)
+	} else if err != nil {
+		return errors.Wrap(err, "error fetching synheart agent")
+	}
+
+	status := r.agentStatus(pod, agentId, agent)
+	if equality.Semantic.DeepEqual(obj.Status, status) {
+		return nil
+	}
+	obj.Status = status
+	err = r.k8sClient.Status().Update(ctx, obj)
+	if err != nil {
+		return errors.Wrap(err, "error updating synheart agent status")
+	}
+	return nil
+}
+
+// agentStatus Returns the status of the SynHeartAgent from the status of the agent in storage
+func (r *Registry) agentStatus(pod *corev1.Pod, agentId string, agent common.AgentStatus) v1.SynHeartAgentStatus {
+	status := v1.SynHeartAgentStatus{
+		AgentId:      agentId,
+		NodeName:     pod.Spec.NodeName,
+		Version:      agent.AgentConfig.RunTimeInfo.Version,
+		AgentProfile: agent.AgentConfig.AgentProfile,
+		SynTests:     slices.Clone(agent.SynTests),
+		TestCount:    int32(len(agent.SynTests)),
+	}
+	slices.Sort(status.SynTests)
+	for plugin := range agent.AgentConfig.DiscoveredPlugins {
+		status.Plugins = append(status.Plugins, plugin)
+	}
+	slices.Sort(status.Plugins)
+	if lastStatus, err := time.Parse(common.TimeFormat, agent.StatusTime); err == nil {
+		// metav1.Time is serialised in seconds, so it's truncated to compare it with the one from the api server
+		status.LastHeartbeat = metav1.NewTime(lastStatus.Truncate(time.Second))
+		status.Active = time.Since(lastStatus) < r.deadline
+	}
+	return status
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AgentPrometheusProfile is the prometheus config of the agents which can be set by a profile
+type AgentPrometheusProfile struct {
+	// Labels are added to the labels of all metrics (on top of the ones in the agent config)
+	Labels map[string]string `json:"labels,omitempty"`
+	// TestLabelKeys are the keys of the test's metricLabels added to all metrics of the test
+	TestLabelKeys []string `json:"testLabelKeys,omitempty"`
+	// StalenessWindow is after how long series which aren't updated are removed, e.g. "10m"
+	StalenessWindow string `json:"stalenessWindow,omitempty"`
+	// MaxLabelValues is the max number of distinct values per label of custom metrics (-1 to disable)
+	MaxLabelValues int32 `json:"maxLabelValues,omitempty"`
+}
+
+// AgentProfileSpec defines the desired state of AgentProfile
+type AgentProfileSpec struct {
+	// Priority decides which profile is applied when several match an agent (the highest wins)
+	Priority int32 `json:"priority,omitempty"`
+	// NodeSelector is a glob matching the node name of the agents (empty matches all)
+	NodeSelector string `json:"nodeSelector,omitempty"`
+	// PodLabelSelector matches the labels of the agent pods, same as the podLabelSelector of SyntheticTests
+	PodLabelSelector map[string]string `json:"podLabelSelector,omitempty"`
+	// SyncFrequency is how often the agents sync the test configs, e.g. "30s"
+	SyncFrequency string `json:"syncFrequency,omitempty"`
+	// LogLevel of the agents (trace, debug, info, warn or error)
+	LogLevel string `json:"logLevel,omitempty"`
+	// Prometheus config of the agents, changing it restarts the agents
+	Prometheus *AgentPrometheusProfile `json:"prometheus,omitempty"`
+	// AllowedPlugins are the plugins the agents run tests of (empty means all)
+	AllowedPlugins []string `json:"allowedPlugins,omitempty"`
+}
+
+// AgentProfileStatus defines the observed state of AgentProfile
+type AgentProfileStatus struct {
+	// Agents is the number of active agents which applied the profile
+	Agents int32 `json:"agents,omitempty"`
+	// Message is the error of the profile (empty if it was pushed to the agents)
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+//+kubebuilder:printcolumn:name="Agents",type=integer,JSONPath=`.status.agents`
+//+kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AgentProfile is the Schema for the agentprofiles API, agent level config pushed to the matching agents
+type AgentProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AgentProfileSpec   `json:"spec,omitempty"`
+	Status AgentProfileStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AgentProfileList contains a list of AgentProfile
+type AgentProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AgentProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AgentProfile{}, &AgentProfileList{})
+}
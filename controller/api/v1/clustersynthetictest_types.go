@@ -0,0 +1,67 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"github.com/cisco-open/synthetic-heart/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=clustersyntest;clustersyntests
+//+kubebuilder:printcolumn:name="Plugin",type=string,JSONPath=`.spec.plugin`
+//+kubebuilder:printcolumn:name="Passing",type=integer,JSONPath=`.status.passingAgents`
+//+kubebuilder:printcolumn:name="Failing",type=integer,JSONPath=`.status.failingAgents`
+//+kubebuilder:printcolumn:name="Last Run",type=date,JSONPath=`.status.lastRunTime`
+//+kubebuilder:printcolumn:name="Last Failure",type=string,JSONPath=`.status.lastFailureMessage`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterSyntheticTest is the Schema for the clustersynthetictests API, a cluster scoped SyntheticTest
+// for infrastructure tests which don't belong in any namespace
+type ClusterSyntheticTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SyntheticTestSpec   `json:"spec,omitempty"`
+	Status SyntheticTestStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterSyntheticTestList contains a list of ClusterSyntheticTest
+type ClusterSyntheticTestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterSyntheticTest `json:"items"`
+}
+
+// AsSyntheticTest Returns the cluster test as a SyntheticTest in the cluster test namespace, as it's stored for the agents
+func (in *ClusterSyntheticTest) AsSyntheticTest() *SyntheticTest {
+	synTest := &SyntheticTest{
+		TypeMeta:   in.TypeMeta,
+		ObjectMeta: *in.ObjectMeta.DeepCopy(),
+		Spec:       *in.Spec.DeepCopy(),
+		Status:     *in.Status.DeepCopy(),
+	}
+	synTest.Namespace = common.ClusterTestNamespace
+	return synTest
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterSyntheticTest{}, &ClusterSyntheticTestList{})
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ParseManifests Returns the SyntheticTests defined in yaml (or json) manifests, with documents separated by '---'.
+// ClusterSyntheticTests are returned in the cluster test namespace and SyntheticTestSuites are expanded, other kinds
+// are ignored. Tests without a namespace are put in defaultNamespace.
+func ParseManifests(r io.Reader, defaultNamespace string) ([]SyntheticTest, error) {
+	synTests := []SyntheticTest{}
+	decoder := yamlutil.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		raw := runtime.RawExtension{}
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			return synTests, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue // empty document
+		}
+		typeMeta := metav1.TypeMeta{}
+		err = json.Unmarshal(raw.Raw, &typeMeta)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		if typeMeta.GroupVersionKind().Group != GroupVersion.Group {
+			continue
+		}
+
+		switch typeMeta.Kind {
+		case "SyntheticTest":
+			synTest := SyntheticTest{}
+			err = json.Unmarshal(raw.Raw, &synTest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SyntheticTest: %w", err)
+			}
+			if synTest.Namespace == "" {
+				synTest.Namespace = defaultNamespace
+			}
+			synTests = append(synTests, synTest)
+		case "ClusterSyntheticTest":
+			clusterTest := ClusterSyntheticTest{}
+			err = json.Unmarshal(raw.Raw, &clusterTest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ClusterSyntheticTest: %w", err)
+			}
+			synTests = append(synTests, *clusterTest.AsSyntheticTest())
+		case "SyntheticTestSuite":
+			suite := SyntheticTestSuite{}
+			err = json.Unmarshal(raw.Raw, &suite)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SyntheticTestSuite: %w", err)
+			}
+			if suite.Namespace == "" {
+				suite.Namespace = defaultNamespace
+			}
+			suiteTests, err := suite.Expand()
+			if err != nil {
+				return nil, fmt.Errorf("invalid SyntheticTestSuite %s: %w", suite.Name, err)
+			}
+			synTests = append(synTests, suiteTests...)
+		}
+	}
+}
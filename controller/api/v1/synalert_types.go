@@ -0,0 +1,132 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SynAlertCondition is when the alert fires for a test, all the set conditions must hold
+type SynAlertCondition struct {
+	// ConsecutiveFailures is the number of failed runs in a row before a test counts as failing on an agent (default 1)
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+	// FailingAgentsPercent is the minimum percentage of agents (running the test) on which the test must be failing
+	// (default: any agent)
+	FailingAgentsPercent int32 `json:"failingAgentsPercent,omitempty"`
+}
+
+// SlackTarget posts a message to a slack incoming webhook
+type SlackTarget struct {
+	// WebhookUrl is the secret (in the namespace of the alert) containing the incoming webhook url
+	WebhookUrl corev1.SecretKeySelector `json:"webhookUrl"`
+	// Channel overrides the default channel of the webhook
+	Channel string `json:"channel,omitempty"`
+}
+
+// PagerDutyTarget opens an incident (via the events v2 api) while the alert fires
+type PagerDutyTarget struct {
+	// RoutingKey is the secret (in the namespace of the alert) containing the integration key
+	RoutingKey corev1.SecretKeySelector `json:"routingKey"`
+	// Severity of the incidents (critical, error, warning or info), by default it's based on the importance of the test
+	Severity string `json:"severity,omitempty"`
+	// Url of the events api (default https://events.pagerduty.com/v2/enqueue)
+	Url string `json:"url,omitempty"`
+}
+
+// WebhookTarget posts the notification as json to an http endpoint
+type WebhookTarget struct {
+	// Url of the endpoint
+	Url string `json:"url,omitempty"`
+	// UrlSecret is the secret (in the namespace of the alert) containing the url, used instead of Url
+	UrlSecret *corev1.SecretKeySelector `json:"urlSecret,omitempty"`
+	// Headers are extra http headers of the request
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// SynAlertTarget is where the notifications are sent, exactly one of slack, pagerDuty or webhook must be set
+type SynAlertTarget struct {
+	// Name of the target (shown in the status if sending fails)
+	Name      string           `json:"name"`
+	Slack     *SlackTarget     `json:"slack,omitempty"`
+	PagerDuty *PagerDutyTarget `json:"pagerDuty,omitempty"`
+	Webhook   *WebhookTarget   `json:"webhook,omitempty"`
+}
+
+// SynAlertSpec defines the desired state of SynAlert
+type SynAlertSpec struct {
+	// Selector selects the SyntheticTests (in the namespace of the alert) the alert applies to (empty selects all)
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Importances selects the SyntheticTests of these importance levels (empty selects all), tests which don't set an
+	// importance are medium
+	Importances []string `json:"importances,omitempty"`
+	// Condition is when the alert fires for a test (by default when the test fails on any agent)
+	Condition SynAlertCondition `json:"condition,omitempty"`
+	// Targets are notified when the alert starts firing for a test
+	Targets []SynAlertTarget `json:"targets"`
+	// SendResolved notifies the targets when the alert stops firing for a test
+	SendResolved bool `json:"sendResolved,omitempty"`
+}
+
+// FiringTest is a test the alert is firing for
+type FiringTest struct {
+	// Name of the SyntheticTest
+	Name string `json:"name"`
+	// Since is when the alert started firing for the test
+	Since metav1.Time `json:"since"`
+	// FailingAgents are the agents on which the test is failing
+	FailingAgents []string `json:"failingAgents,omitempty"`
+}
+
+// SynAlertStatus defines the observed state of SynAlert
+type SynAlertStatus struct {
+	// Firing are the tests the alert is firing for
+	Firing []FiringTest `json:"firing,omitempty"`
+	// FiringTests is the number of tests the alert is firing for
+	FiringTests int32 `json:"firingTests,omitempty"`
+	// Message is the error of the last evaluation or notification (empty if they succeeded)
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=synalert;synalerts
+//+kubebuilder:printcolumn:name="Firing",type=integer,JSONPath=`.status.firingTests`
+//+kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SynAlert is the Schema for the synalerts API, it routes the failures of SyntheticTests to notification targets
+type SynAlert struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SynAlertSpec   `json:"spec,omitempty"`
+	Status SynAlertStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SynAlertList contains a list of SynAlert
+type SynAlertList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SynAlert `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SynAlert{}, &SynAlertList{})
+}
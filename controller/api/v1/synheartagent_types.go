@@ -0,0 +1,75 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SynHeartAgentStatus is the latest status reported by the agent
+type SynHeartAgentStatus struct {
+	// AgentId is the id of the agent in storage (<pod name>/<namespace>)
+	AgentId string `json:"agentId"`
+	// NodeName is the node the agent runs on
+	NodeName string `json:"nodeName,omitempty"`
+	// Version of the agent
+	Version string `json:"version,omitempty"`
+	// AgentProfile is the name of the AgentProfile applied to the agent (empty if none)
+	AgentProfile string `json:"agentProfile,omitempty"`
+	// Plugins are the test plugins discovered by the agent
+	Plugins []string `json:"plugins,omitempty"`
+	// SynTests are the config ids (<name>/<namespace>) of the tests running on the agent
+	SynTests []string `json:"synTests,omitempty"`
+	// TestCount is the number of tests running on the agent
+	TestCount int32 `json:"testCount"`
+	// LastHeartbeat is when the agent last posted its status
+	LastHeartbeat metav1.Time `json:"lastHeartbeat,omitempty"`
+	// Active is set if the agent posted its status within the agent status deadline
+	Active bool `json:"active"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=synagent;synagents
+//+kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.nodeName`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Tests",type=integer,JSONPath=`.status.testCount`
+//+kubebuilder:printcolumn:name="Active",type=boolean,JSONPath=`.status.active`
+//+kubebuilder:printcolumn:name="Last Heartbeat",type=date,JSONPath=`.status.lastHeartbeat`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SynHeartAgent is the Schema for the synheartagents API, it's created by the controller for every agent (in the
+// namespace and with the name of its pod) and deleted with the pod
+type SynHeartAgent struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status SynHeartAgentStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SynHeartAgentList contains a list of SynHeartAgent
+type SynHeartAgentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SynHeartAgent `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SynHeartAgent{}, &SynHeartAgentList{})
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SynTestQuotaSpec defines the limits on the SyntheticTests of the namespace
+type SynTestQuotaSpec struct {
+	// MaxTests is the max number of SyntheticTests in the namespace (0 means no limit), the oldest tests are deployed
+	MaxTests int32 `json:"maxTests,omitempty"`
+	// MinRepeat is the shortest repeat interval of the tests, e.g. "1m"
+	MinRepeat string `json:"minRepeat,omitempty"`
+	// MaxTimeout is the longest init, run and finish timeout of the tests, e.g. "5m"
+	MaxTimeout string `json:"maxTimeout,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=syntestquota;syntestquotas
+//+kubebuilder:printcolumn:name="Max Tests",type=integer,JSONPath=`.spec.maxTests`
+//+kubebuilder:printcolumn:name="Min Repeat",type=string,JSONPath=`.spec.minRepeat`
+//+kubebuilder:printcolumn:name="Max Timeout",type=string,JSONPath=`.spec.maxTimeout`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SynTestQuota is the Schema for the syntestquotas API, it limits the SyntheticTests of its namespace (all the quotas
+// of a namespace apply)
+type SynTestQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SynTestQuotaSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SynTestQuotaList contains a list of SynTestQuota
+type SynTestQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SynTestQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SynTestQuota{}, &SynTestQuotaList{})
+}
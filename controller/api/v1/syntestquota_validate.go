@@ -0,0 +1,39 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateQuota Checks the repeat interval and timeouts of a test are within the limits of the quota (the number of
+// tests is checked by the caller, as it needs the other tests of the namespace). Invalid limits are ignored.
+func ValidateQuota(quota *SynTestQuota, spec *SyntheticTestSpec) field.ErrorList {
+	return testspec.ValidateQuota(quota.TestQuota(), spec.TestSpec())
+}
+
+// TestQuota Returns the limits of the quota, as published to storage for the rest api
+func (quota *SynTestQuota) TestQuota() common.SynTestQuota {
+	return common.SynTestQuota{
+		Name:       quota.Name,
+		MaxTests:   quota.Spec.MaxTests,
+		MinRepeat:  quota.Spec.MinRepeat,
+		MaxTimeout: quota.Spec.MaxTimeout,
+	}
+}
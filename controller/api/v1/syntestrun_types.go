@@ -0,0 +1,113 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Phases of a SynTestRun
+const (
+	SynTestRunRunning   = "Running"
+	SynTestRunSucceeded = "Succeeded"
+	SynTestRunFailed    = "Failed"
+)
+
+// SynTestRunSpec defines the test to run once
+type SynTestRunSpec struct {
+	// Test is the name of the SyntheticTest (in the namespace of the run) to run
+	Test string `json:"test"`
+	// ClusterTest is set if Test is the name of a ClusterSyntheticTest
+	ClusterTest bool `json:"clusterTest,omitempty"`
+	// Timeout is how long to wait for the results of the agents, e.g. "2m" (default 5m)
+	Timeout string `json:"timeout,omitempty"`
+	// TTLSecondsAfterFinished is how long the run is kept after it finished, it's kept forever if not set
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// SynTestRunResult is the result of the run on an agent
+type SynTestRunResult struct {
+	// Agent is the id of the agent (<pod name>/<namespace>)
+	Agent string `json:"agent"`
+	// Passed is set if the test got its max marks without error
+	Passed bool `json:"passed"`
+	// Marks the test got
+	Marks int64 `json:"marks"`
+	// MaxMarks the test could get
+	MaxMarks int64 `json:"maxMarks"`
+	// Duration of the test run
+	Duration string `json:"duration,omitempty"`
+	// Error of the test run, if any
+	Error string `json:"error,omitempty"`
+}
+
+// SynTestRunStatus defines the observed state of SynTestRun
+type SynTestRunStatus struct {
+	// Phase of the run: Running, Succeeded (passed on all agents) or Failed
+	Phase string `json:"phase,omitempty"`
+	// Message explains the phase
+	Message string `json:"message,omitempty"`
+	// StartTime is when the run was requested from the agents
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the run finished (or timed out)
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Agents are the ids of the agents running the test, the run is requested from them
+	Agents []string `json:"agents,omitempty"`
+	// Results of the run, by agent
+	Results []SynTestRunResult `json:"results,omitempty"`
+	// Passed is the number of agents on which the run passed
+	Passed int32 `json:"passed,omitempty"`
+	// Failed is the number of agents on which the run failed
+	Failed int32 `json:"failed,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=synrun;synruns
+//+kubebuilder:printcolumn:name="Test",type=string,JSONPath=`.spec.test`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Passed",type=integer,JSONPath=`.status.passed`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SynTestRun is the Schema for the syntestruns API, it runs a test once, immediately, on the agents running it (e.g.
+// after a deployment) and collects the results of the agents in its status
+type SynTestRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SynTestRunSpec   `json:"spec,omitempty"`
+	Status SynTestRunStatus `json:"status,omitempty"`
+}
+
+// Finished Returns whether the run succeeded or failed
+func (in *SynTestRun) Finished() bool {
+	return in.Status.Phase == SynTestRunSucceeded || in.Status.Phase == SynTestRunFailed
+}
+
+//+kubebuilder:object:root=true
+
+// SynTestRunList contains a list of SynTestRun
+type SynTestRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SynTestRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SynTestRun{}, &SynTestRunList{})
+}
@@ -17,9 +17,18 @@
 package v1
 
 import (
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/testspec"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// PinVersionAnnotation pins the test to a version of its config (from the config history), instead of the version of
+// its spec
+const PinVersionAnnotation = "synheart.infra.webex.com/pin-version"
+
+// PausedAnnotation pauses the test when set to "true", the agents stop its plugin but keep its data (e.g. history)
+const PausedAnnotation = "synheart.io/paused"
+
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 type Timeouts struct {
@@ -28,6 +37,62 @@ type Timeouts struct {
 	Finish string `json:"finish,omitempty"`
 }
 
+// Alerting defines when and how alerts are sent for the test
+type Alerting struct {
+	// FailureThreshold is the number of consecutive failed runs before an alert fires (0 uses the agent default)
+	FailureThreshold int32 `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+	// Labels are extra labels added to the alerts of the test
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Annotations are extra annotations added to the alerts of the test
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// Disabled turns off alerts for the test
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// SlackChannel is the slack channel to notify (overrides the agent's default channel)
+	SlackChannel string `json:"slackChannel,omitempty" yaml:"slackChannel,omitempty"`
+}
+
+// SLO defines the availability SLO of the test
+type SLO struct {
+	// Target is the availability target in percent, e.g. "99.9"
+	Target string `json:"target" yaml:"target"`
+	// Window is the rolling window of the SLO, e.g. "30d" or "168h" (default 30d)
+	Window string `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+// Rollout defines how changes to the config of the test are rolled out to the agents
+type Rollout struct {
+	// CanaryPercent is the percentage of the agents (running the test) which get a new config first, it's promoted to
+	// all agents once it ran on them for VerifyDuration without erroring or restarting (0 disables canaries)
+	CanaryPercent int32 `json:"canaryPercent,omitempty" yaml:"canaryPercent,omitempty"`
+	// VerifyDuration is how long the new config must run on the canary agents before it's promoted (default 5m)
+	VerifyDuration string `json:"verifyDuration,omitempty" yaml:"verifyDuration,omitempty"`
+}
+
+// ActiveWindow is a recurring window of time a test runs in, e.g. business hours
+type ActiveWindow struct {
+	// Days of the week the window starts on (mon, tue, wed, thu, fri, sat or sun), every day if empty
+	Days []string `json:"days,omitempty" yaml:"days,omitempty"`
+	// Start is the time of day the window starts, e.g. "09:00"
+	Start string `json:"start" yaml:"start"`
+	// End is the time of day the window ends, e.g. "17:00" (the next day if it's before start)
+	End string `json:"end" yaml:"end"`
+	// Timezone of the window, e.g. "Europe/London" (default UTC)
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// SecretRef references a key of a kubernetes secret, which the agent injects in the config of the test when it starts
+// its plugin, so credentials don't appear in the test or in storage
+type SecretRef struct {
+	// Name of the value in the config template (.Secrets.<name>)
+	Name string `json:"name" yaml:"name"`
+	// Secret is the name of the secret
+	Secret string `json:"secret" yaml:"secret"`
+	// Key in the secret
+	Key string `json:"key" yaml:"key"`
+	// Namespace of the secret, the namespace of the test if empty (required for cluster tests)
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
 // SyntheticTestSpec defines the desired state of SyntheticTest
 type SyntheticTestSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -44,6 +109,64 @@ type SyntheticTestSpec struct {
 	PluginRestartPolicy string            `json:"pluginRestartPolicy,omitempty" yaml:"pluginRestartPolicy,omitempty"`
 	LogWaitTime         string            `json:"logWaitTime,omitempty" yaml:"logWaitTime,omitempty"`
 	Config              string            `json:"config,omitempty" yaml:"config,omitempty"`
+	// MetricLabels are extra static labels (e.g. team, service, tier) added to all metrics of the test
+	MetricLabels map[string]string `json:"metricLabels,omitempty" yaml:"metricLabels,omitempty"`
+	// Alerting defines when and how alerts are sent for the test
+	Alerting *Alerting `json:"alerting,omitempty" yaml:"alerting,omitempty"`
+	// SLO defines the availability SLO of the test
+	SLO *SLO `json:"slo,omitempty" yaml:"slo,omitempty"`
+	// Rollout defines how config changes are rolled out to the agents (by default to all agents at once)
+	Rollout *Rollout `json:"rollout,omitempty" yaml:"rollout,omitempty"`
+	// ActiveFrom is when the test starts running (RFC3339), e.g. for tests tied to a migration
+	ActiveFrom string `json:"activeFrom,omitempty" yaml:"activeFrom,omitempty"`
+	// ActiveUntil is when the test stops running (RFC3339)
+	ActiveUntil string `json:"activeUntil,omitempty" yaml:"activeUntil,omitempty"`
+	// ActiveWindows are recurring windows the test only runs in (e.g. business hours), it runs all the time if empty
+	ActiveWindows []ActiveWindow `json:"activeWindows,omitempty" yaml:"activeWindows,omitempty"`
+	// TargetInventories are the TargetInventories whose targets are rendered into the config (a go template), by name
+	// (<namespace>/<name> for cluster tests)
+	TargetInventories []string `json:"targetInventories,omitempty" yaml:"targetInventories,omitempty"`
+	// SecretRefs are keys of kubernetes secrets the agent reads and renders into the config (a go template) as
+	// .Secrets.<name> when it starts the plugin
+	SecretRefs []SecretRef `json:"secretRefs,omitempty" yaml:"secretRefs,omitempty"`
+}
+
+// TestSpec Returns a copy of the spec as a testspec.Spec (the same json), which has the defaults and the validation of
+// the specs shared with synheartctl lint
+func (spec *SyntheticTestSpec) TestSpec() *testspec.Spec {
+	s := testspec.Spec{}
+	testspec.Convert(spec, &s)
+	return &s
+}
+
+// ProtoActiveWindows Returns the active windows of the test for its config
+func (spec *SyntheticTestSpec) ProtoActiveWindows() []*proto.ActiveWindow {
+	var windows []*proto.ActiveWindow
+	for _, w := range spec.ActiveWindows {
+		windows = append(windows, &proto.ActiveWindow{Days: w.Days, Start: w.Start, End: w.End, Timezone: w.Timezone})
+	}
+	return windows
+}
+
+// ProtoSecretRefs Returns the secret refs of the test for its config
+func (spec *SyntheticTestSpec) ProtoSecretRefs() []*proto.SecretRef {
+	var refs []*proto.SecretRef
+	for _, r := range spec.SecretRefs {
+		refs = append(refs, &proto.SecretRef{Name: r.Name, Secret: r.Secret, Key: r.Key, Namespace: r.Namespace})
+	}
+	return refs
+}
+
+// CanaryStatus is the new version of the config being rolled out to the canary agents
+type CanaryStatus struct {
+	// Version of the config running on the canary agents
+	Version string `json:"version"`
+	// Agents running the canary version
+	Agents []string `json:"agents,omitempty"`
+	// StartTime is when the canary was rolled out
+	StartTime metav1.Time `json:"startTime"`
+	// Failed is set when the canary errored on an agent and was rolled back
+	Failed bool `json:"failed,omitempty"`
 }
 
 // SyntheticTestStatus defines the observed state of SyntheticTest
@@ -53,10 +176,31 @@ type SyntheticTestStatus struct {
 	Deployed bool   `json:"deployed,omitempty"`
 	Agent    string `json:"agent,omitempty"`
 	Message  string `json:"message,omitempty"`
+	// PassingAgents is the number of agents on which the latest run of the test passed
+	PassingAgents int32 `json:"passingAgents,omitempty"`
+	// FailingAgents is the number of agents on which the latest run of the test failed
+	FailingAgents int32 `json:"failingAgents,omitempty"`
+	// LastRunTime is when the latest run of the test (on any agent) finished
+	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
+	// LastFailureTime is when the latest failed run of the test (on any agent) finished
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+	// LastFailureMessage is the error (or marks) of the latest failed run of the test
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+	// Canary is the config change being rolled out to the canary agents (or the last one if it was rolled back)
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// PinnedVersion is the version of the config the test is pinned to (empty if it runs the version of its spec)
+	PinnedVersion string `json:"pinnedVersion,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=syntest;syntests
+//+kubebuilder:printcolumn:name="Plugin",type=string,JSONPath=`.spec.plugin`
+//+kubebuilder:printcolumn:name="Passing",type=integer,JSONPath=`.status.passingAgents`
+//+kubebuilder:printcolumn:name="Failing",type=integer,JSONPath=`.status.failingAgents`
+//+kubebuilder:printcolumn:name="Last Run",type=date,JSONPath=`.status.lastRunTime`
+//+kubebuilder:printcolumn:name="Last Failure",type=string,JSONPath=`.status.lastFailureMessage`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SyntheticTest is the Schema for the synthetictests API
 type SyntheticTest struct {
@@ -67,6 +211,11 @@ type SyntheticTest struct {
 	Status SyntheticTestStatus `json:"status,omitempty"`
 }
 
+// Paused Returns whether the test is paused (PausedAnnotation)
+func (in *SyntheticTest) Paused() bool {
+	return in.Annotations[PausedAnnotation] == "true"
+}
+
 //+kubebuilder:object:root=true
 
 // SyntheticTestList contains a list of SyntheticTest
@@ -0,0 +1,104 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+)
+
+// jsonFields Returns the json names of the fields of a struct type, with the types of the nested structs (e.g. of a
+// pointer or a slice of structs)
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fieldType := t.Field(i).Type
+		for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		fields[name] = fieldType
+	}
+	return fields
+}
+
+// TestSpecFieldsMatch Checks the specs of the CRDs and of common/testspec have the same json fields, as they're
+// converted through json (a field missing in testspec would be dropped by the defaulting webhook)
+func TestSpecFieldsMatch(t *testing.T) {
+	pairs := []struct{ crd, spec reflect.Type }{
+		{reflect.TypeOf(SyntheticTestSpec{}), reflect.TypeOf(testspec.Spec{})},
+		{reflect.TypeOf(SyntheticTestSuiteSpec{}), reflect.TypeOf(testspec.SuiteSpec{})},
+	}
+	for len(pairs) > 0 {
+		pair := pairs[0]
+		pairs = pairs[1:]
+		crdFields, specFields := jsonFields(pair.crd), jsonFields(pair.spec)
+		for name, crdType := range crdFields {
+			specType, ok := specFields[name]
+			if !ok {
+				t.Errorf("field %s of %s is missing in %s", name, pair.crd, pair.spec)
+				continue
+			}
+			if crdType.Kind() != specType.Kind() {
+				t.Errorf("field %s of %s is a %s, but a %s in %s", name, pair.crd, crdType.Kind(), specType.Kind(), pair.spec)
+			} else if crdType.Kind() == reflect.Struct {
+				pairs = append(pairs, struct{ crd, spec reflect.Type }{crdType, specType})
+			}
+		}
+		for name := range specFields {
+			if _, ok := crdFields[name]; !ok {
+				t.Errorf("field %s of %s isn't in %s", name, pair.spec, pair.crd)
+			}
+		}
+	}
+}
+
+func TestDefaultSpecKeepsFields(t *testing.T) {
+	spec := SyntheticTestSpec{
+		Plugin:              "dns",
+		Node:                " node-* ",
+		PodLabelSelector:    map[string]string{" app ": " agent "},
+		DisplayName:         "DNS",
+		Description:         "resolves the domains",
+		Importance:          "high",
+		Repeat:              "1m",
+		DependsOn:           []string{"ping"},
+		Timeouts:            &Timeouts{Init: "1s", Run: "2s", Finish: "3s"},
+		PluginRestartPolicy: "never",
+		LogWaitTime:         "5s",
+		Config:              "domains: [{{ join \",\" .Targets }}]",
+		MetricLabels:        map[string]string{"team": "network"},
+		Alerting:            &Alerting{FailureThreshold: 3, Labels: map[string]string{"a": "b"}, SlackChannel: "#alerts"},
+		SLO:                 &SLO{Target: "99.9", Window: "7d"},
+		Rollout:             &Rollout{CanaryPercent: 10, VerifyDuration: "5m"},
+		ActiveFrom:          "2024-01-01T00:00:00Z",
+		ActiveWindows:       []ActiveWindow{{Days: []string{"mon"}, Start: "09:00", End: "17:00", Timezone: "UTC"}},
+		TargetInventories:   []string{"vips"},
+		SecretRefs:          []SecretRef{{Name: "password", Secret: "creds", Key: "password"}},
+	}
+	want := *spec.DeepCopy()
+	want.Node = "node-*"
+	want.PodLabelSelector = map[string]string{"app": "agent"}
+
+	DefaultSpec(&spec)
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("DefaultSpec() = %+v, want %+v", spec, want)
+	}
+}
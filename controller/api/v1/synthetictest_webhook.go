@@ -0,0 +1,195 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var synthetictestlog = logf.Log.WithName("synthetictest-resource")
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks of SyntheticTests,
+// knownPlugins returns the plugins discovered by the active agents
+func (r *SyntheticTest) SetupWebhookWithManager(mgr ctrl.Manager, knownPlugins func(ctx context.Context) (map[string]bool, error)) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&SyntheticTestDefaulter{}).
+		WithValidator(&SyntheticTestValidator{KnownPlugins: knownPlugins, Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-synheart-infra-webex-com-v1-synthetictest,mutating=true,failurePolicy=fail,sideEffects=None,groups=synheart.infra.webex.com,resources=synthetictests,verbs=create;update,versions=v1,name=msynthetictest.kb.io,admissionReviewVersions=v1
+
+// SyntheticTestDefaulter fills in the defaults of SyntheticTests and normalizes their selectors, so the configs are
+// explicit once they're admitted (instead of relying on the defaults of the agents)
+// +kubebuilder:object:generate=false
+type SyntheticTestDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &SyntheticTestDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *SyntheticTestDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	synTest, ok := obj.(*SyntheticTest)
+	if !ok {
+		return fmt.Errorf("expected a SyntheticTest but got a %T", obj)
+	}
+	synthetictestlog.Info("default", "name", synTest.Name, "namespace", synTest.Namespace)
+	DefaultSpec(&synTest.Spec)
+	return nil
+}
+
+// DefaultSpec Fills in the defaults of the spec (the same the agents use), and trims the whitespace of the selectors.
+// Invalid values are left as they are, so the validation rejects them.
+func DefaultSpec(spec *SyntheticTestSpec) {
+	s := spec.TestSpec()
+	testspec.Default(s)
+	testspec.Convert(s, spec)
+}
+
+//+kubebuilder:webhook:path=/validate-synheart-infra-webex-com-v1-synthetictest,mutating=false,failurePolicy=fail,sideEffects=None,groups=synheart.infra.webex.com,resources=synthetictests,verbs=create;update,versions=v1,name=vsynthetictest.kb.io,admissionReviewVersions=v1
+
+// SyntheticTestValidator rejects SyntheticTests which the agents can't run (unknown plugin, invalid durations,
+// malformed selectors or config), so they're caught when applied instead of failing on the agents. The config of the
+// built-in plugins is decoded strictly into their config type (see pluginconfig), other plugins' configs are only
+// checked to be valid yaml. It also rejects tests exceeding the SynTestQuotas of their namespace.
+// +kubebuilder:object:generate=false
+type SyntheticTestValidator struct {
+	KnownPlugins func(ctx context.Context) (map[string]bool, error)
+	// Client reads the quotas and the tests of the namespace
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &SyntheticTestValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *SyntheticTestValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj, true)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *SyntheticTestValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, newObj, false)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *SyntheticTestValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *SyntheticTestValidator) validate(ctx context.Context, obj runtime.Object, create bool) (admission.Warnings, error) {
+	synTest, ok := obj.(*SyntheticTest)
+	if !ok {
+		return nil, fmt.Errorf("expected a SyntheticTest but got a %T", obj)
+	}
+	synthetictestlog.Info("validate", "name", synTest.Name, "namespace", synTest.Namespace)
+
+	plugins, warnings := v.knownPlugins(ctx)
+	specWarnings, allErrs := Validate(&synTest.Spec, plugins)
+	warnings = append(warnings, specWarnings...)
+	for i, ref := range synTest.Spec.ProtoSecretRefs() {
+		if _, err := common.SecretRefNamespace(ref, synTest.Namespace); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "secretRefs").Index(i).Child("namespace"), ref.Namespace, err.Error()))
+		}
+	}
+	quotaWarnings, quotaErrs := v.validateQuotas(ctx, synTest, create)
+	warnings = append(warnings, quotaWarnings...)
+	allErrs = append(allErrs, quotaErrs...)
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(GroupVersion.WithKind("SyntheticTest").GroupKind(), synTest.Name, allErrs)
+}
+
+// knownPlugins Returns the plugins discovered by the active agents, if they can't be fetched (or there are no agents)
+// it returns nil and a warning, so the plugin isn't checked
+func (v *SyntheticTestValidator) knownPlugins(ctx context.Context) (map[string]bool, admission.Warnings) {
+	if v.KnownPlugins == nil {
+		return nil, nil
+	}
+	plugins, err := v.KnownPlugins(ctx)
+	if err != nil {
+		synthetictestlog.Error(err, "unable to fetch the plugins of the agents")
+		return nil, admission.Warnings{"unable to check the plugin exists: " + err.Error()}
+	}
+	if len(plugins) == 0 {
+		return nil, admission.Warnings{"no active agents, unable to check the plugin exists"}
+	}
+	return plugins, nil
+}
+
+// validateQuotas Checks the test is within the SynTestQuotas of its namespace (the number of tests only when it's
+// created), if they can't be fetched it only warns
+func (v *SyntheticTestValidator) validateQuotas(ctx context.Context, synTest *SyntheticTest, create bool) (admission.Warnings, field.ErrorList) {
+	if v.Client == nil {
+		return nil, nil
+	}
+	quotas := SynTestQuotaList{}
+	err := v.Client.List(ctx, &quotas, client.InNamespace(synTest.Namespace))
+	if err != nil {
+		synthetictestlog.Error(err, "unable to fetch the quotas of the namespace", "namespace", synTest.Namespace)
+		return admission.Warnings{"unable to check the quotas of the namespace: " + err.Error()}, nil
+	}
+
+	allErrs := field.ErrorList{}
+	var tests *SyntheticTestList
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		allErrs = append(allErrs, ValidateQuota(quota, &synTest.Spec)...)
+		if !create || quota.Spec.MaxTests <= 0 {
+			continue
+		}
+		if tests == nil {
+			tests = &SyntheticTestList{}
+			err = v.Client.List(ctx, tests, client.InNamespace(synTest.Namespace))
+			if err != nil {
+				synthetictestlog.Error(err, "unable to fetch the tests of the namespace", "namespace", synTest.Namespace)
+				return admission.Warnings{"unable to check the number of tests in the namespace: " + err.Error()}, allErrs
+			}
+		}
+		if len(tests.Items) >= int(quota.Spec.MaxTests) {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("metadata", "namespace"),
+				fmt.Sprintf("namespace already has %d tests, the maxTests of quota '%s'", len(tests.Items), quota.Name)))
+		}
+	}
+	return nil, allErrs
+}
+
+// Validate Validates a spec the way the webhook does, without the quotas which need the cluster. The plugin is checked
+// against the known plugins (skipped if nil). The validation is in common/testspec, so synheartctl lint validates
+// manifests offline the same way.
+func Validate(spec *SyntheticTestSpec, plugins map[string]bool) (admission.Warnings, field.ErrorList) {
+	warnings, allErrs := testspec.Validate(spec.TestSpec(), plugins)
+	return warnings, allErrs
+}
+
+// ValidateSpec Validates the fields of the spec which don't need any external info
+func ValidateSpec(spec *SyntheticTestSpec) field.ErrorList {
+	return testspec.ValidateSpec(spec.TestSpec())
+}
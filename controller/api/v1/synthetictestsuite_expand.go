@@ -0,0 +1,56 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestName Returns the name of the SyntheticTest generated for a test of the suite
+func (s *SyntheticTestSuite) TestName(test SuiteTest) string {
+	return s.Name + "-" + test.Name
+}
+
+// Expand Returns the SyntheticTests of the suite, with the shared settings of the suite merged into their specs (see
+// testspec.SuiteSpec.Expand, also used by synheartctl lint)
+func (s *SyntheticTestSuite) Expand() ([]SyntheticTest, error) {
+	suiteSpec := testspec.SuiteSpec{}
+	testspec.Convert(&s.Spec, &suiteSpec)
+	tests, err := suiteSpec.Expand()
+	if err != nil {
+		return nil, err
+	}
+	synTests := []SyntheticTest{}
+	for _, test := range tests {
+		labels := map[string]string{}
+		for k, v := range s.Spec.Labels {
+			labels[k] = v
+		}
+		labels[SuiteLabel] = s.Name
+		synTest := SyntheticTest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.TestName(SuiteTest{Name: test.Name}),
+				Namespace: s.Namespace,
+				Labels:    labels,
+			},
+		}
+		testspec.Convert(&test.Spec, &synTest.Spec)
+		synTests = append(synTests, synTest)
+	}
+	return synTests, nil
+}
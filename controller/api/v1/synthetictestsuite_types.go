@@ -0,0 +1,91 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SuiteLabel is set on the SyntheticTests generated from a suite, with the name of the suite
+const SuiteLabel = "synheart.infra.webex.com/suite"
+
+// SuiteTest is a test of the suite, its spec is merged with the shared settings of the suite
+type SuiteTest struct {
+	// Name of the test, the generated SyntheticTest is named <suite>-<name>
+	Name string `json:"name"`
+	// Spec of the test, empty fields are taken from the suite
+	Spec SyntheticTestSpec `json:"spec"`
+}
+
+// SyntheticTestSuiteSpec defines the desired state of SyntheticTestSuite
+type SyntheticTestSuiteSpec struct {
+	// Node is the node selector of tests which don't have a node or pod label selector of their own
+	Node string `json:"node,omitempty"`
+	// PodLabelSelector is the pod label selector of tests which don't have a node or pod label selector of their own
+	PodLabelSelector map[string]string `json:"podLabelSelector,omitempty"`
+	// Repeat is the schedule of tests which don't have their own
+	Repeat string `json:"repeat,omitempty"`
+	// Importance is the importance of tests which don't have their own
+	Importance string `json:"importance,omitempty"`
+	// Labels are added to the metadata of all the generated SyntheticTests
+	Labels map[string]string `json:"labels,omitempty"`
+	// MetricLabels are added to the metric labels of all tests (the labels of a test take precedence)
+	MetricLabels map[string]string `json:"metricLabels,omitempty"`
+	// Alerting is the alerting (notification) settings of tests which don't have their own
+	Alerting *Alerting `json:"alerting,omitempty"`
+	// Tests of the suite
+	Tests []SuiteTest `json:"tests"`
+}
+
+// SyntheticTestSuiteStatus defines the observed state of SyntheticTestSuite
+type SyntheticTestSuiteStatus struct {
+	// Tests are the names of the SyntheticTests generated from the suite
+	Tests []string `json:"tests,omitempty"`
+	// FailingTests is the number of tests of the suite which are failing on any agent
+	FailingTests int32 `json:"failingTests,omitempty"`
+	// Message is the error of the last expansion of the suite (empty if it succeeded)
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=syntestsuite;syntestsuites
+//+kubebuilder:printcolumn:name="Failing",type=integer,JSONPath=`.status.failingTests`
+//+kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SyntheticTestSuite is the Schema for the synthetictestsuites API, it bundles SyntheticTests with shared settings
+type SyntheticTestSuite struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SyntheticTestSuiteSpec   `json:"spec,omitempty"`
+	Status SyntheticTestSuiteStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SyntheticTestSuiteList contains a list of SyntheticTestSuite
+type SyntheticTestSuiteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SyntheticTestSuite `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SyntheticTestSuite{}, &SyntheticTestSuiteList{})
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ValidateInventoryRef Returns an error if the reference isn't an inventory name, or <namespace>/<name>
+func ValidateInventoryRef(ref string) error {
+	return testspec.ValidateInventoryRef(ref)
+}
+
+// ParseInventoryRef Returns the inventory referenced by a test in the namespace, namespaced tests reference the
+// inventories of their namespace by name, and cluster tests reference them as <namespace>/<name>
+func ParseInventoryRef(ref string, testNamespace string) (types.NamespacedName, error) {
+	err := ValidateInventoryRef(ref)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found {
+		if testNamespace == common.ClusterTestNamespace {
+			return types.NamespacedName{}, fmt.Errorf("inventory '%s' of a cluster test must be referenced as <namespace>/<name>", ref)
+		}
+		namespace, name = testNamespace, ref
+	} else if testNamespace != common.ClusterTestNamespace && namespace != testNamespace {
+		return types.NamespacedName{}, fmt.Errorf("inventory '%s' isn't in the namespace of the test", ref)
+	}
+	return types.NamespacedName{Name: name, Namespace: namespace}, nil
+}
+
+// RenderConfig Returns the config of the spec rendered with the targets of its inventories (by reference), the config
+// of tests referencing inventories is a go template, e.g. "{{ range .Targets }}..." (see testspec.InventoryData)
+func RenderConfig(spec *SyntheticTestSpec, inventories map[string][]string) (string, error) {
+	return testspec.RenderConfig(spec.Config, spec.TargetInventories, inventories)
+}
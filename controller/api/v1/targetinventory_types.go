@@ -0,0 +1,56 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TargetInventorySpec defines the targets of the inventory
+type TargetInventorySpec struct {
+	// Description of the targets, e.g. "public VIPs of the edge load balancers"
+	Description string `json:"description,omitempty"`
+	// Targets of the inventory, e.g. hostnames, VIPs or regions
+	Targets []string `json:"targets"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=targetinv;targetinvs
+//+kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TargetInventory is the Schema for the targetinventories API, a list of targets (e.g. VIPs or regions) shared by the
+// SyntheticTests referencing it, the controller re-renders their configs when it changes
+type TargetInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TargetInventorySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TargetInventoryList contains a list of TargetInventory
+type TargetInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TargetInventory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TargetInventory{}, &TargetInventoryList{})
+}
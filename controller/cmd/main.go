@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"github.com/cisco-open/synthetic-heart/controller/internal/controller"
+	"github.com/hashicorp/go-hclog"
 	"os"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -30,6 +31,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -94,8 +96,16 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
+	// the tests are reconciled again every resync period (RESYNC_PERIOD env var)
+	syncPeriod := controller.ResyncPeriod(hclog.New(&hclog.LoggerOptions{
+		Name:  "setup",
+		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+	}))
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			SyncPeriod: syncPeriod,
+		},
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
 			SecureServing: secureMetrics,
@@ -129,6 +139,44 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "SyntheticTest")
 		os.Exit(1)
 	}
+	if err = (&controller.SyntheticTestSuiteReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SyntheticTestSuite")
+		os.Exit(1)
+	}
+	if err = (&controller.AgentProfileReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentProfile")
+		os.Exit(1)
+	}
+	if err = (&controller.SynTestRunReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Namespace: os.Getenv("NAMESPACE"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SynTestRun")
+		os.Exit(1)
+	}
+	// the validating webhook needs certs (e.g. from cert-manager), so it's only enabled explicitly
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		logger := hclog.New(&hclog.LoggerOptions{
+			Name:  "webhook",
+			Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+		})
+		store, err := controller.ConnectToStorage(logger)
+		if err != nil {
+			setupLog.Error(err, "unable to connect to storage")
+			os.Exit(1)
+		}
+		if err = (&synheartinfrawebexcomv1.SyntheticTest{}).SetupWebhookWithManager(mgr, controller.KnownPluginsFunc(store, logger)); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SyntheticTest")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,158 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package federation
+
+// package containing code to replicate the summarised results of the tests of the cluster to a central storage, so the
+// health of the tests in all the clusters of a federation can be seen in one place (the restapi of the central storage)
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/controller/healthscore"
+	"github.com/cisco-open/synthetic-heart/controller/summary"
+	"github.com/cisco-open/synthetic-heart/controller/sync"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const DefaultInterval = 1 * time.Minute
+
+// Enabled Returns whether the results should be replicated to a central storage (FEDERATION_STORE_ADDR env var)
+func Enabled() bool {
+	return os.Getenv("FEDERATION_STORE_ADDR") != ""
+}
+
+// ClusterName Returns the name of the cluster in the federation (CLUSTER_NAME env var)
+func ClusterName() (string, error) {
+	cluster := os.Getenv("CLUSTER_NAME")
+	if cluster == "" {
+		return "", errors.New("CLUSTER_NAME env var not set, it's needed to replicate the results")
+	}
+	return cluster, nil
+}
+
+// ConnectToCentralStorage Connects to the central storage of the federation (FEDERATION_STORE_ADDR env var)
+func ConnectToCentralStorage(logger hclog.Logger) (storage.SynHeartStore, error) {
+	store, err := storage.NewSynHeartStore(storage.SynHeartStoreConfig{
+		Type:       "redis",
+		BufferSize: 1000,
+		Address:    os.Getenv("FEDERATION_STORE_ADDR"),
+	}, logger.Named("central-redis"))
+	if err != nil {
+		return store, errors.Wrap(err, "error creating central synheart store (redis) client")
+	}
+	return store, nil
+}
+
+// Interval Returns how often the results should be replicated (FEDERATION_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("FEDERATION_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse FEDERATION_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Replicate Summarises the latest results of every test of the cluster (from the local storage), and writes the summary
+// to the central storage
+func Replicate(ctx context.Context, logger hclog.Logger, cluster string, store storage.SynHeartStore,
+	central storage.SynHeartStore) error {
+	clusterSummary, err := Summarise(ctx, logger, cluster, store)
+	if err != nil {
+		return err
+	}
+	err = central.WriteClusterSummary(ctx, clusterSummary)
+	if err != nil {
+		return errors.Wrap(err, "error writing cluster summary to the central storage")
+	}
+	logger.Debug("replicated cluster summary", "cluster", cluster, "tests", len(clusterSummary.Tests))
+	return nil
+}
+
+// Summarise Returns the summary of the latest results of every test of the cluster. The health score is the share of
+// passing agents, weighted by test importance.
+func Summarise(ctx context.Context, logger hclog.Logger, cluster string, store storage.SynHeartStore) (common.ClusterSummary, error) {
+	clusterSummary := common.ClusterSummary{
+		Cluster: cluster,
+		Time:    time.Now(),
+		Tests:   []common.FederatedTestResult{},
+	}
+	configs, err := store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		return clusterSummary, errors.Wrap(err, "error fetching test config summaries")
+	}
+	results, err := summary.Results(ctx, logger, store)
+	if err != nil {
+		return clusterSummary, err
+	}
+	agents, err := sync.FetchActiveAgents(ctx, store, logger)
+	if err != nil {
+		return clusterSummary, errors.Wrap(err, "error fetching active agents")
+	}
+	clusterSummary.Agents = len(agents)
+
+	score := healthscore.Score{}
+	for configId, config := range configs {
+		test := common.FederatedTestResult{
+			Cluster:   cluster,
+			Name:      config.Name,
+			Namespace: config.Namespace,
+			Plugin:    config.Plugin,
+		}
+		testConfig, err := store.FetchTestConfig(ctx, configId)
+		if err == nil {
+			test.Importance = testConfig.Importance
+		} else {
+			logger.Warn("unable to fetch test config, its importance is unknown", "configId", configId, "err", err)
+		}
+		if res, ok := results[configId]; ok {
+			test.PassingAgents = res.PassingAgents
+			test.FailingAgents = res.FailingAgents
+			test.LastRunTime = res.LastRunTime
+			test.LastFailureTime = res.LastFailureTime
+			test.LastFailureMessage = res.LastFailureMessage
+		}
+		for i := int32(0); i < test.PassingAgents; i++ {
+			score.Add(1, test.Importance)
+		}
+		for i := int32(0); i < test.FailingAgents; i++ {
+			score.Add(0, test.Importance)
+		}
+		clusterSummary.Tests = append(clusterSummary.Tests, test)
+	}
+	sort.Slice(clusterSummary.Tests, func(i, j int) bool {
+		a, b := clusterSummary.Tests[i], clusterSummary.Tests[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	if val, ok := score.Value(); ok {
+		clusterSummary.HealthScore = &val
+	}
+	return clusterSummary, nil
+}
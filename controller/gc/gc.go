@@ -0,0 +1,224 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gc
+
+// package containing code to garbage collect the data left in storage by tests and agents which don't exist anymore
+// (e.g. crashed agents), which the sync doesn't see as it only checks the plugins with a status
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	DefaultInterval = 10 * time.Minute
+	DefaultAgentTTL = 1 * time.Hour
+)
+
+// Kinds of the data collected
+const (
+	KindPlugin = "plugin"
+	KindConfig = "config"
+	KindAgent  = "agent"
+)
+
+var (
+	reclaimed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: common.MetricGCReclaimed,
+		Help: "Number of orphaned plugins, test configs and agents deleted from storage, by kind",
+	}, []string{"kind"})
+	orphans = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricGCOrphans,
+		Help: "Number of orphaned plugins, test configs and agents found in storage by the last run, by kind",
+	}, []string{"kind"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(reclaimed, orphans)
+}
+
+// Collector deletes the orphaned data in storage. Data is only deleted if it's orphaned in two consecutive runs, so
+// data being written while storage is scanned (e.g. a new test) isn't deleted.
+type Collector struct {
+	store     storage.SynHeartStore
+	k8sClient client.Client
+	logger    hclog.Logger
+	agentTTL  time.Duration
+	suspects  map[string]map[string]bool // ids orphaned in the last run, by kind
+}
+
+func NewCollector(store storage.SynHeartStore, k8sClient client.Client, logger hclog.Logger) *Collector {
+	return &Collector{
+		store:     store,
+		k8sClient: k8sClient,
+		logger:    logger,
+		agentTTL:  AgentTTL(logger),
+		suspects:  map[string]map[string]bool{},
+	}
+}
+
+// Interval Returns how often the garbage collection should run (GC_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	return durationFromEnv(logger, "GC_INTERVAL", DefaultInterval)
+}
+
+// AgentTTL Returns how long after its last status an agent is deleted (GC_AGENT_TTL env var)
+func AgentTTL(logger hclog.Logger) time.Duration {
+	return durationFromEnv(logger, "GC_AGENT_TTL", DefaultAgentTTL)
+}
+
+func durationFromEnv(logger hclog.Logger, env string, def time.Duration) time.Duration {
+	val, ok := os.LookupEnv(env)
+	if !ok {
+		return def
+	}
+	dur, err := time.ParseDuration(val)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse "+env+" duration, using default: "+val, "default", def)
+		return def
+	}
+	return dur
+}
+
+// Run Finds the orphaned data in storage, and deletes the data which was also orphaned in the last run:
+//   - test configs (and their status, history...) of tests which aren't a CRD or from another source (e.g. git)
+//   - plugins (test runs, logs and health) of tests which don't exist, or of agents which aren't registered
+//   - agents which didn't post a status for the agent TTL
+func (c *Collector) Run(ctx context.Context) error {
+	agents, err := c.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching agents")
+	}
+	orphanedAgents := map[string]bool{}
+	for agentId, agent := range agents {
+		if agent.StatusTime == "" {
+			continue // registered by the sync for a new pod, the agent hasn't posted a status yet
+		}
+		statusTime, err := time.Parse(common.TimeFormat, agent.StatusTime)
+		if err != nil || time.Since(statusTime) > c.agentTTL {
+			orphanedAgents[agentId] = true
+		}
+	}
+
+	configIds, err := c.knownConfigIds(ctx)
+	if err != nil {
+		return err
+	}
+	storedConfigIds, err := c.store.FetchAllStoredConfigIds(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching stored config ids")
+	}
+	orphanedConfigs := map[string]bool{}
+	for configId := range storedConfigIds {
+		if !configIds[configId] {
+			orphanedConfigs[configId] = true
+		}
+	}
+
+	storedPluginIds, err := c.store.FetchAllStoredPluginIds(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching stored plugin ids")
+	}
+	orphanedPlugins := map[string]bool{}
+	for pluginId := range storedPluginIds {
+		testName, testNs, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil {
+			c.logger.Warn("unable to parse pluginId, skipping", "pluginId", pluginId, "err", err)
+			continue
+		}
+		agentId := common.ComputeAgentId(podName, podNs)
+		_, registered := agents[agentId]
+		if !configIds[common.ComputeSynTestConfigId(testName, testNs)] || !registered || orphanedAgents[agentId] {
+			orphanedPlugins[pluginId] = true
+		}
+	}
+
+	c.collect(ctx, KindPlugin, orphanedPlugins, func(pluginId string) error {
+		return c.store.DeleteAllTestRunInfo(ctx, pluginId)
+	})
+	c.collect(ctx, KindConfig, orphanedConfigs, func(configId string) error {
+		return c.store.DeleteTestConfig(ctx, configId)
+	})
+	c.collect(ctx, KindAgent, orphanedAgents, func(agentId string) error {
+		return c.store.DeleteAgentStatus(ctx, agentId)
+	})
+	return nil
+}
+
+// collect Deletes the orphaned ids of the kind which were also orphaned in the last run, the others are kept as suspects
+func (c *Collector) collect(ctx context.Context, kind string, orphaned map[string]bool, del func(id string) error) {
+	orphans.WithLabelValues(kind).Set(float64(len(orphaned)))
+	deleted := 0
+	for id := range orphaned {
+		if !c.suspects[kind][id] {
+			continue
+		}
+		c.logger.Info("deleting orphaned "+kind+" from storage", "id", id)
+		err := del(id)
+		if err != nil {
+			c.logger.Warn("error deleting orphaned "+kind+", will retry", "id", id, "err", err)
+			continue
+		}
+		delete(orphaned, id)
+		reclaimed.WithLabelValues(kind).Inc()
+		deleted++
+	}
+	c.suspects[kind] = orphaned
+	if deleted > 0 || len(orphaned) > 0 {
+		c.logger.Info("garbage collected "+kind+"s", "deleted", deleted, "suspects", len(orphaned))
+	}
+}
+
+// knownConfigIds Returns the ids of the tests which exist: the CRDs (which may have a status but no config, e.g. if
+// they exceed a quota) and the tests in the config summary (which includes the tests from other sources)
+func (c *Collector) knownConfigIds(ctx context.Context) (map[string]bool, error) {
+	configIds := map[string]bool{}
+	var synTestList v1.SyntheticTestList
+	err := c.k8sClient.List(ctx, &synTestList)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing synTests")
+	}
+	for _, synTest := range synTestList.Items {
+		configIds[common.ComputeSynTestConfigId(synTest.Name, synTest.Namespace)] = true
+	}
+	var clusterTestList v1.ClusterSyntheticTestList
+	err = c.k8sClient.List(ctx, &clusterTestList)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing clusterSynTests")
+	}
+	for _, clusterTest := range clusterTestList.Items {
+		configIds[common.ComputeSynTestConfigId(clusterTest.Name, common.ClusterTestNamespace)] = true
+	}
+	summaries, err := c.store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching test config summaries")
+	}
+	for configId := range summaries {
+		configIds[configId] = true
+	}
+	return configIds, nil
+}
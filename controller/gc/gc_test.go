@@ -0,0 +1,113 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestCollect(t *testing.T) {
+	type run struct {
+		kind         string
+		orphaned     []string
+		failDelete   []string // ids whose deletion errors
+		wantDeleted  []string
+		wantSuspects []string
+	}
+	tests := []struct {
+		name string
+		runs []run
+	}{
+		{
+			name: "orphaned once is only a suspect",
+			runs: []run{
+				{kind: KindPlugin, orphaned: []string{"a"}, wantSuspects: []string{"a"}},
+			},
+		},
+		{
+			name: "orphaned in two consecutive runs is deleted",
+			runs: []run{
+				{kind: KindPlugin, orphaned: []string{"a", "b"}, wantSuspects: []string{"a", "b"}},
+				{kind: KindPlugin, orphaned: []string{"a", "c"}, wantDeleted: []string{"a"}, wantSuspects: []string{"c"}},
+				{kind: KindPlugin, orphaned: []string{"c"}, wantDeleted: []string{"c"}},
+			},
+		},
+		{
+			name: "not orphaned in between is a suspect again",
+			runs: []run{
+				{kind: KindConfig, orphaned: []string{"a"}, wantSuspects: []string{"a"}},
+				{kind: KindConfig}, // e.g. the test was being written while storage was scanned
+				{kind: KindConfig, orphaned: []string{"a"}, wantSuspects: []string{"a"}},
+			},
+		},
+		{
+			name: "failed deletions are retried",
+			runs: []run{
+				{kind: KindAgent, orphaned: []string{"a"}, wantSuspects: []string{"a"}},
+				{kind: KindAgent, orphaned: []string{"a"}, failDelete: []string{"a"}, wantSuspects: []string{"a"}},
+				{kind: KindAgent, orphaned: []string{"a"}, wantDeleted: []string{"a"}},
+			},
+		},
+		{
+			name: "kinds are suspected separately",
+			runs: []run{
+				{kind: KindPlugin, orphaned: []string{"a"}, wantSuspects: []string{"a"}},
+				{kind: KindConfig, orphaned: []string{"a"}, wantSuspects: []string{"a"}},
+				{kind: KindConfig, orphaned: []string{"a"}, wantDeleted: []string{"a"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Collector{logger: hclog.NewNullLogger(), suspects: map[string]map[string]bool{}}
+			for i, r := range tt.runs {
+				orphaned := map[string]bool{}
+				for _, id := range r.orphaned {
+					orphaned[id] = true
+				}
+				var deleted []string
+				c.collect(context.Background(), r.kind, orphaned, func(id string) error {
+					for _, fail := range r.failDelete {
+						if id == fail {
+							return errors.New("storage unavailable")
+						}
+					}
+					deleted = append(deleted, id)
+					return nil
+				})
+				var suspects []string
+				for id := range c.suspects[r.kind] {
+					suspects = append(suspects, id)
+				}
+				sort.Strings(deleted)
+				sort.Strings(suspects)
+				if !reflect.DeepEqual(deleted, r.wantDeleted) {
+					t.Errorf("run %d: deleted = %v, want %v", i, deleted, r.wantDeleted)
+				}
+				if !reflect.DeepEqual(suspects, r.wantSuspects) {
+					t.Errorf("run %d: suspects = %v, want %v", i, suspects, r.wantSuspects)
+				}
+			}
+		})
+	}
+}
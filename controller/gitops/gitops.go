@@ -0,0 +1,199 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gitops
+
+// package containing code to fetch the SyntheticTests defined in a git repository (instead of CRDs), it uses the git
+// (and gpg, to verify signatures) binaries
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultInterval  = 1 * time.Minute
+	DefaultBranch    = "main"
+	DefaultDir       = "/tmp/synheart-gitops"
+	DefaultNamespace = "default"
+)
+
+// Repo is a git repository with SyntheticTest manifests
+type Repo struct {
+	URL    string
+	Branch string
+	// Path is the directory of the manifests in the repo (all its yaml files are read, recursively)
+	Path string
+	// Dir is where the repo is cloned
+	Dir string
+	// SigningKeys is a file with the armored gpg public keys the commits must be signed with (empty to not verify)
+	SigningKeys string
+
+	gnupgHome string
+	logger    hclog.Logger
+}
+
+// Enabled Returns whether a git repository to sync the tests from is configured (GITOPS_REPO env var)
+func Enabled() bool {
+	return os.Getenv("GITOPS_REPO") != ""
+}
+
+// NewRepoFromEnv Returns the repo configured by the GITOPS_* env vars, importing its signing keys if any
+func NewRepoFromEnv(logger hclog.Logger) (*Repo, error) {
+	repo := &Repo{
+		URL:         os.Getenv("GITOPS_REPO"),
+		Branch:      os.Getenv("GITOPS_BRANCH"),
+		Path:        os.Getenv("GITOPS_PATH"),
+		Dir:         os.Getenv("GITOPS_DIR"),
+		SigningKeys: os.Getenv("GITOPS_SIGNING_KEYS"),
+		logger:      logger,
+	}
+	if repo.Branch == "" {
+		repo.Branch = DefaultBranch
+	}
+	if repo.Dir == "" {
+		repo.Dir = DefaultDir
+	}
+	if repo.SigningKeys != "" {
+		// use a keyring of our own, so only the configured keys are trusted
+		gnupgHome, err := os.MkdirTemp("", "synheart-gnupg")
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating gnupg home")
+		}
+		repo.gnupgHome = gnupgHome
+		_, err = repo.run(context.Background(), "", "gpg", "--batch", "--import", repo.SigningKeys)
+		if err != nil {
+			return nil, errors.Wrap(err, "error importing signing keys")
+		}
+	}
+	return repo, nil
+}
+
+// Interval Returns how often the repo should be polled (GITOPS_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("GITOPS_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse GITOPS_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Fetch Updates the clone of the repo to the head of the branch, and returns the commit and the SyntheticTests in the
+// path. If signing keys are configured, the commit must be signed with one of them.
+func (r *Repo) Fetch(ctx context.Context) (string, []v1.SyntheticTest, error) {
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); os.IsNotExist(err) {
+		r.logger.Info("cloning repo", "url", redact(r.URL), "branch", r.Branch)
+		_, err = r.run(ctx, "", "git", "clone", "--branch", r.Branch, "--single-branch", r.URL, r.Dir)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "error cloning repo")
+		}
+	} else {
+		_, err = r.run(ctx, r.Dir, "git", "fetch", "origin", r.Branch)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "error fetching repo")
+		}
+		_, err = r.run(ctx, r.Dir, "git", "reset", "--hard", "FETCH_HEAD")
+		if err != nil {
+			return "", nil, errors.Wrap(err, "error checking out the head of the branch")
+		}
+	}
+	commit, err := r.run(ctx, r.Dir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error reading the commit")
+	}
+	if r.SigningKeys != "" {
+		_, err = r.run(ctx, r.Dir, "git", "verify-commit", commit)
+		if err != nil {
+			return commit, nil, errors.Wrap(err, "commit "+commit+" isn't signed with a trusted key")
+		}
+	}
+
+	synTests := []v1.SyntheticTest{}
+	root := filepath.Join(r.Dir, r.Path)
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fileTests, err := v1.ParseManifests(f, DefaultNamespace)
+		if err != nil {
+			return errors.Wrap(err, "error parsing "+strings.TrimPrefix(path, r.Dir+"/"))
+		}
+		synTests = append(synTests, fileTests...)
+		return nil
+	})
+	if err != nil {
+		return commit, nil, errors.Wrap(err, "error reading manifests")
+	}
+	return commit, synTests, nil
+}
+
+// run Runs a command (in dir if not empty), returning its trimmed output
+func (r *Repo) run(ctx context.Context, dir string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	if r.gnupgHome != "" {
+		cmd.Env = append(cmd.Env, "GNUPGHOME="+r.gnupgHome)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Wrap(err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// redact Hides the password of a repo url, for logging
+func redact(repoUrl string) string {
+	u, err := url.Parse(repoUrl)
+	if err != nil {
+		return repoUrl // e.g. an scp-like ssh url, which has no password
+	}
+	return u.Redacted()
+}
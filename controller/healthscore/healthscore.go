@@ -0,0 +1,151 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package healthscore
+
+// package containing code to roll up test results into a health score per namespace and for the whole cluster
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const DefaultInterval = 1 * time.Minute
+
+var (
+	namespaceScore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricHealthScore,
+		Help: "Health score (0-1) of the namespace: pass ratio of the latest test runs, weighted by test importance",
+	}, []string{"namespace"})
+	clusterScore = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: common.MetricClusterHealthScore,
+		Help: "Health score (0-1) of the cluster: pass ratio of the latest test runs, weighted by test importance",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(namespaceScore, clusterScore)
+}
+
+// Score is an average of pass ratios, weighted by test importance
+type Score struct {
+	weightedSum float64
+	totalWeight float64
+}
+
+func (s *Score) Add(passRatio float64, importance string) {
+	weight := common.ImportanceWeight(importance)
+	s.weightedSum += passRatio * weight
+	s.totalWeight += weight
+}
+
+// Value Returns the score, or false if no results were added
+func (s *Score) Value() (float64, bool) {
+	if s.totalWeight == 0 {
+		return 0, false
+	}
+	return s.weightedSum / s.totalWeight, true
+}
+
+// Interval Returns how often the health score should be computed (HEALTH_SCORE_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("HEALTH_SCORE_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse HEALTH_SCORE_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Compute Computes the health scores from the latest status of every test (on every agent), and exports them as metrics
+func Compute(ctx context.Context, logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client) error {
+	var synTestList v1.SyntheticTestList
+	err := k8sClient.List(ctx, &synTestList)
+	if err != nil {
+		return errors.Wrap(err, "error listing synTests")
+	}
+	importance := map[string]string{}
+	for _, synTest := range synTestList.Items {
+		importance[common.ComputeSynTestConfigId(synTest.Name, synTest.Namespace)] = synTest.Spec.Importance
+	}
+	var clusterTestList v1.ClusterSyntheticTestList
+	err = k8sClient.List(ctx, &clusterTestList)
+	if err != nil {
+		return errors.Wrap(err, "error listing clusterSynTests")
+	}
+	for _, clusterTest := range clusterTestList.Items {
+		importance[common.ComputeSynTestConfigId(clusterTest.Name, common.ClusterTestNamespace)] = clusterTest.Spec.Importance
+	}
+
+	allStatus, err := store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test run status from redis")
+	}
+
+	cluster := Score{}
+	namespaces := map[string]*Score{}
+	for pluginId, status := range allStatus {
+		testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+		if err != nil {
+			logger.Warn("unable to parse pluginId, skipping", "pluginId", pluginId, "err", err)
+			continue
+		}
+		testImportance, ok := importance[common.ComputeSynTestConfigId(testName, testNs)]
+		if !ok { // test doesn't exist anymore (will be cleaned up by sync)
+			continue
+		}
+		passRatio, err := strconv.ParseFloat(status, 64)
+		if err != nil {
+			logger.Warn("unable to parse test run status, skipping", "pluginId", pluginId, "status", status, "err", err)
+			continue
+		}
+		cluster.Add(passRatio, testImportance)
+		if testNs == common.ClusterTestNamespace { // cluster tests only count towards the cluster score
+			continue
+		}
+		if _, ok := namespaces[testNs]; !ok {
+			namespaces[testNs] = &Score{}
+		}
+		namespaces[testNs].Add(passRatio, testImportance)
+	}
+
+	namespaceScore.Reset() // so namespaces with no tests anymore are removed
+	for ns, score := range namespaces {
+		if val, ok := score.Value(); ok {
+			namespaceScore.WithLabelValues(ns).Set(val)
+		}
+	}
+	if val, ok := cluster.Value(); ok {
+		clusterScore.Set(val)
+	}
+	logger.Debug("computed health scores", "namespaces", len(namespaces), "testRuns", len(allStatus))
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package httpsource
+
+// package containing code to fetch a bundle of SyntheticTests from a url (instead of CRDs), polling it with the ETag
+// so unchanged bundles aren't downloaded and parsed again
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultInterval  = 1 * time.Minute
+	DefaultTimeout   = 30 * time.Second
+	DefaultNamespace = "default"
+	MaxBundleSize    = 10 << 20 // 10MiB
+)
+
+// ErrNotModified is returned by Fetch if the bundle didn't change since the last fetch
+var ErrNotModified = errors.New("bundle not modified")
+
+// Source is a url serving a bundle of SyntheticTest manifests (multi document yaml or json)
+type Source struct {
+	URL string
+	// AuthHeader is the value of the Authorization header sent with the requests (e.g. "Bearer <token>"), if not empty
+	AuthHeader string
+
+	etag   string
+	client *http.Client
+	logger hclog.Logger
+}
+
+// Enabled Returns whether a url to sync the tests from is configured (HTTP_SOURCE_URL env var)
+func Enabled() bool {
+	return os.Getenv("HTTP_SOURCE_URL") != ""
+}
+
+// NewSourceFromEnv Returns the source configured by the HTTP_SOURCE_* env vars
+func NewSourceFromEnv(logger hclog.Logger) (*Source, error) {
+	source := &Source{
+		URL:        os.Getenv("HTTP_SOURCE_URL"),
+		AuthHeader: os.Getenv("HTTP_SOURCE_AUTH_HEADER"),
+		client:     &http.Client{Timeout: DefaultTimeout},
+		logger:     logger,
+	}
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid url")
+	}
+	if u.Scheme != "https" {
+		if u.Scheme != "http" {
+			return nil, errors.New("unsupported url scheme: " + u.Scheme)
+		}
+		// plain http is fine for a local server, but the auth header would be sent in the clear
+		logger.Warn("url isn't https, the bundle can be tampered with", "url", u.Redacted())
+	}
+	return source, nil
+}
+
+// Interval Returns how often the url should be polled (HTTP_SOURCE_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("HTTP_SOURCE_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse HTTP_SOURCE_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Fetch Downloads the bundle and returns its revision (the ETag, or the hash of the bundle if the server doesn't send
+// one) and its SyntheticTests. Returns ErrNotModified if the ETag of the bundle is the same as the last fetch.
+func (s *Source) Fetch(ctx context.Context) (string, []v1.SyntheticTest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Accept", "application/yaml, application/json;q=0.9, */*;q=0.8")
+	if s.AuthHeader != "" {
+		req.Header.Set("Authorization", s.AuthHeader)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error fetching bundle")
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		return revision(s.etag, nil), nil, ErrNotModified
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", nil, errors.New("error fetching bundle: " + res.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxBundleSize+1))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error reading bundle")
+	}
+	if len(body) > MaxBundleSize {
+		return "", nil, errors.New("bundle is larger than the max size of 10MiB")
+	}
+	etag := res.Header.Get("ETag")
+	rev := revision(etag, body)
+	synTests, err := v1.ParseManifests(bytes.NewReader(body), DefaultNamespace)
+	if err != nil {
+		return rev, nil, errors.Wrap(err, "error parsing bundle")
+	}
+	// only remember the etag once the bundle is parsed, so a broken bundle is fetched again on the next poll
+	s.etag = etag
+	s.logger.Info("fetched bundle", "revision", rev, "tests", len(synTests))
+	return rev, synTests, nil
+}
+
+// Invalidate Forgets the ETag of the last fetch, so the bundle is downloaded again on the next one (e.g. if syncing it
+// to storage failed)
+func (s *Source) Invalidate() {
+	s.etag = ""
+}
+
+// revision Returns the revision of a bundle for the audit log: its ETag without the quotes, or the hash of the body
+func revision(etag string, body []byte) string {
+	if etag != "" {
+		return strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	}
+	if body == nil {
+		return ""
+	}
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])[:12]
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/cisco-open/synthetic-heart/controller/sync"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// AgentProfileStatusInterval is how often the status (number of agents which applied the profile) is refreshed
+const AgentProfileStatusInterval = 1 * time.Minute
+
+// AgentProfileReconciler reconciles an AgentProfile object, by pushing it to the agents via storage
+type AgentProfileReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=agentprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=agentprofiles/status,verbs=get;update;patch
+
+func (r *AgentProfileReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  fmt.Sprintf("reconcile-profile [%s]", request.Name),
+		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+	})
+
+	store, err := ConnectToStorage(logger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	defer store.Close()
+
+	instance := &synheartv1.AgentProfile{}
+	err = r.Client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			logger.Info("agent profile not found! likely deleted, deleting it from redis")
+			err := store.DeleteAgentProfile(ctx, request.Name)
+			if err != nil {
+				logger.Info("warning: error deleting agent profile", "err", err)
+			}
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	profile, err := agentProfileFromSpec(instance)
+	if err != nil {
+		// nothing to retry until the profile is fixed
+		logger.Error("invalid agent profile", "err", err)
+		r.updateProfileStatus(ctx, instance, synheartv1.AgentProfileStatus{Message: "error: " + err.Error()}, logger)
+		return reconcile.Result{}, nil
+	}
+
+	// only write the profile if it changed, as it makes all agents sync
+	profiles, err := store.FetchAllAgentProfiles(ctx)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error fetching agent profiles")
+	}
+	if existing, ok := profiles[profile.Name]; !ok || !reflect.DeepEqual(existing, profile) {
+		logger.Info("updating agent profile in redis", "name", profile.Name)
+		err = store.WriteAgentProfile(ctx, profile)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "error writing agent profile to redis")
+		}
+	}
+
+	activeAgents, err := sync.FetchActiveAgents(ctx, store, logger)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error fetching active agents")
+	}
+	status := synheartv1.AgentProfileStatus{}
+	for _, agentStatus := range activeAgents {
+		if agentStatus.AgentConfig.AgentProfile == profile.Name {
+			status.Agents++
+		}
+	}
+	r.updateProfileStatus(ctx, instance, status, logger)
+
+	// agents apply the profile when they sync, so refresh the status periodically
+	return reconcile.Result{RequeueAfter: AgentProfileStatusInterval}, nil
+}
+
+// agentProfileFromSpec Converts the AgentProfile CRD to the agent profile stored for the agents, validating its fields
+func agentProfileFromSpec(instance *synheartv1.AgentProfile) (common.AgentProfile, error) {
+	profile := common.AgentProfile{
+		Name:             instance.Name,
+		Priority:         instance.Spec.Priority,
+		NodeSelector:     instance.Spec.NodeSelector,
+		PodLabelSelector: instance.Spec.PodLabelSelector,
+		LogLevel:         instance.Spec.LogLevel,
+		AllowedPlugins:   instance.Spec.AllowedPlugins,
+	}
+	if instance.Spec.SyncFrequency != "" {
+		syncFrequency, err := time.ParseDuration(instance.Spec.SyncFrequency)
+		if err != nil || syncFrequency <= 0 {
+			return profile, errors.New("invalid syncFrequency " + instance.Spec.SyncFrequency)
+		}
+		profile.SyncFrequency = syncFrequency
+	}
+	if instance.Spec.LogLevel != "" && hclog.LevelFromString(instance.Spec.LogLevel) == hclog.NoLevel {
+		return profile, errors.New("invalid logLevel " + instance.Spec.LogLevel)
+	}
+	if instance.Spec.Prometheus != nil {
+		profile.Prometheus = &common.PrometheusProfile{
+			Labels:         instance.Spec.Prometheus.Labels,
+			TestLabelKeys:  instance.Spec.Prometheus.TestLabelKeys,
+			MaxLabelValues: int(instance.Spec.Prometheus.MaxLabelValues),
+		}
+		if instance.Spec.Prometheus.StalenessWindow != "" {
+			window, err := time.ParseDuration(instance.Spec.Prometheus.StalenessWindow)
+			if err != nil || window <= 0 {
+				return profile, errors.New("invalid prometheus stalenessWindow " + instance.Spec.Prometheus.StalenessWindow)
+			}
+			profile.Prometheus.StalenessWindow = window
+		}
+	}
+	return profile, nil
+}
+
+func (r *AgentProfileReconciler) updateProfileStatus(ctx context.Context, instance *synheartv1.AgentProfile,
+	status synheartv1.AgentProfileStatus, logger hclog.Logger) {
+	if instance.Status == status {
+		return
+	}
+	instance.Status = status
+	err := r.Client.Status().Update(ctx, instance)
+	if err != nil {
+		logger.Info("warning: unable to update status of agent profile", "err", err)
+	}
+}
+
+func (r *AgentProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&synheartv1.AgentProfile{}).
+		Complete(r)
+}
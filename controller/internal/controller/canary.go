@@ -0,0 +1,261 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DefaultCanaryVerifyDuration is how long a canary must run without errors before it's promoted, if the test doesn't set it
+const DefaultCanaryVerifyDuration = 5 * time.Minute
+
+// CanaryCheckInterval is how often a canary in progress is checked
+const CanaryCheckInterval = 30 * time.Second
+
+// rolloutCanary Rolls a new version of the config out to the canary agents, and promotes it to all agents once it
+// ran on them long enough without erroring or restarting (or rolls it back if it didn't).
+// Returns done when the config can be written for all agents as usual (it's a new test, or the canary was promoted),
+// otherwise the result of the reconciliation.
+func (r *SyntheticTestReconciler) rolloutCanary(ctx context.Context, instance *synheartv1.SyntheticTest, configId string,
+	node string, podLabelSelector map[string]string, validAgents map[string]bool, store storage.SynHeartStore,
+	logger hclog.Logger) (ctrl.Result, bool, error) {
+
+	stable, err := store.FetchTestConfig(ctx, configId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ctrl.Result{}, true, nil // nothing to compare a new test with, so it's deployed to all agents at once
+	} else if err != nil {
+		return ctrl.Result{}, false, errors.Wrap(err, "error fetching test config from redis")
+	}
+	summary, err := store.FetchTestConfigSummary(ctx, configId)
+	if err != nil {
+		return ctrl.Result{}, false, errors.Wrap(err, "error fetching test config summary from redis")
+	}
+
+	newTestConfig := buildTestConfig(instance, node, podLabelSelector, logger)
+	newTestConfig.Version = configVersion(&newTestConfig)
+	canary := summary.Canary
+	if stable.Version == newTestConfig.Version {
+		// nothing to roll out, or the spec was reverted to the stable version
+		if canary != nil && !canary.Failed {
+			logger.Info("spec reverted to the stable version, removing canary", "version", canary.Version)
+			err = store.DeleteTestConfigCanary(ctx, configId)
+			if err != nil {
+				return ctrl.Result{}, false, errors.Wrap(err, "error deleting canary from redis")
+			}
+		}
+		instance.Status.Canary = canaryStatus(canary)
+		return ctrl.Result{}, true, nil
+	}
+
+	verifyDuration := DefaultCanaryVerifyDuration
+	if d, err := time.ParseDuration(instance.Spec.Rollout.VerifyDuration); err == nil && d > 0 {
+		verifyDuration = d
+	}
+	if canary != nil && canary.Version == newTestConfig.Version {
+		if canary.Failed {
+			// don't retry the same version, the spec needs to change
+			instance.Status.Canary = canaryStatus(canary)
+			r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+				Deployed: true,
+				Message:  "canary of version " + canary.Version + " was rolled back: " + canary.Message,
+				Agent:    "multiple",
+			}, store, logger)
+			return ctrl.Result{}, false, nil
+		}
+
+		verified, restart, failure, err := verifyCanary(ctx, store, *canary, instance.Name, instance.Namespace, validAgents, verifyDuration)
+		if err != nil {
+			return ctrl.Result{}, false, errors.Wrap(err, "error verifying canary")
+		}
+		switch {
+		case failure != "":
+			logger.Warn("canary failed, rolling back", "version", canary.Version, "failure", failure)
+			canary.Failed = true
+			canary.Agents = nil
+			canary.Message = failure
+			err = store.WriteTestConfigCanary(ctx, newTestConfig, *canary)
+			if err != nil {
+				return ctrl.Result{}, false, errors.Wrap(err, "error rolling back canary in redis")
+			}
+			recordCanaryEvent(ctx, store, logger, instance, configId, common.AuditActionRolledBack, stable.Version, canary)
+			instance.Status.Canary = canaryStatus(canary)
+			r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+				Deployed: true,
+				Message:  "canary of version " + canary.Version + " was rolled back: " + failure,
+				Agent:    "multiple",
+			}, store, logger)
+			return ctrl.Result{}, false, nil
+		case verified:
+			logger.Info("canary verified, promoting it to all agents", "version", canary.Version)
+			rawConfig, err := yaml.Marshal(instance.Spec)
+			if err != nil {
+				return ctrl.Result{}, false, errors.Wrap(err, "error marshalling spec yaml")
+			}
+			// writing the stable config removes the canary from the summary, so the agents switch to it at once
+			err = store.WriteTestConfig(ctx, newTestConfig, string(rawConfig))
+			if err != nil {
+				return ctrl.Result{}, false, errors.Wrap(err, "error writing test config to redis")
+			}
+			err = store.DeleteTestConfigCanary(ctx, configId)
+			if err != nil {
+				logger.Warn("error deleting promoted canary config", "err", err)
+			}
+			recordCanaryEvent(ctx, store, logger, instance, configId, common.AuditActionPromoted, stable.Version, canary)
+			instance.Status.Canary = nil
+			return ctrl.Result{}, true, nil
+		case !restart:
+			instance.Status.Canary = canaryStatus(canary)
+			r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+				Deployed: true,
+				Message: fmt.Sprintf("canary of version %s running on %d/%d agents since %s, verifying",
+					canary.Version, len(canary.Agents), len(validAgents), canary.StartedAt.Format(time.RFC3339)),
+				Agent: "multiple",
+			}, store, logger)
+			return ctrl.Result{RequeueAfter: CanaryCheckInterval}, false, nil
+		}
+		logger.Info("canary agents aren't valid anymore, restarting canary", "version", canary.Version)
+	}
+
+	canary = &common.SyntestCanary{
+		Version:   newTestConfig.Version,
+		Agents:    selectCanaryAgents(validAgents, instance.Spec.Rollout.CanaryPercent, newTestConfig.Version),
+		StartedAt: time.Now(),
+	}
+	logger.Info("rolling out canary", "version", canary.Version, "agents", canary.Agents)
+	err = store.WriteTestConfigCanary(ctx, newTestConfig, *canary)
+	if err != nil {
+		return ctrl.Result{}, false, errors.Wrap(err, "error writing canary to redis")
+	}
+	recordCanaryEvent(ctx, store, logger, instance, configId, common.AuditActionCanary, stable.Version, canary)
+	instance.Status.Canary = canaryStatus(canary)
+	r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+		Deployed: true,
+		Message:  fmt.Sprintf("canary of version %s rolled out to %d/%d agents", canary.Version, len(canary.Agents), len(validAgents)),
+		Agent:    "multiple",
+	}, store, logger)
+	return ctrl.Result{RequeueAfter: CanaryCheckInterval}, false, nil
+}
+
+// verifyCanary Checks the state of the plugin of the canary on its agents. Returns whether it ran long enough on all of
+// them, whether the canary needs to be restarted (an agent isn't valid anymore), or why it failed.
+func verifyCanary(ctx context.Context, store storage.SynHeartStore, canary common.SyntestCanary, testName string, testNs string,
+	validAgents map[string]bool, verifyDuration time.Duration) (bool, bool, string, error) {
+	elapsed := time.Since(canary.StartedAt) >= verifyDuration
+	verified := elapsed
+	for _, agentId := range canary.Agents {
+		if !validAgents[agentId] {
+			return false, true, "", nil
+		}
+		state, err := store.FetchPluginHealthStatus(ctx, common.ComputePluginId(testName, testNs, agentId))
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return false, false, "", err
+		}
+		if err != nil || pluginConfigVersion(state) != canary.Version {
+			if elapsed {
+				return false, false, "not running on agent " + agentId + " after " + verifyDuration.String(), nil
+			}
+			verified = false // the agent didn't pick up the canary yet
+			continue
+		}
+		switch {
+		case state.Status == common.Error || state.Status == common.Restarting:
+			return false, false, fmt.Sprintf("plugin %s on agent %s: %s", state.Status, agentId, state.StatusMsg), nil
+		case state.TotalRestarts > 0:
+			return false, false, "plugin restarted " + strconv.Itoa(state.TotalRestarts) + " times on agent " + agentId, nil
+		}
+	}
+	return verified, false, "", nil
+}
+
+// pluginConfigVersion Returns the version of the config the plugin is running (empty if unknown)
+func pluginConfigVersion(state common.PluginState) string {
+	config, ok := state.Config.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	version, _ := config["version"].(string)
+	return version
+}
+
+// selectCanaryAgents Returns the given percentage of the agents (at least one), the same agents are picked for a version
+func selectCanaryAgents(validAgents map[string]bool, percent int32, version string) []string {
+	agents := make([]string, 0, len(validAgents))
+	for agentId := range validAgents {
+		agents = append(agents, agentId)
+	}
+	// spread the canaries of different tests (and versions) over the agents
+	sort.Slice(agents, func(i, j int) bool {
+		return ComputeHash(version+agents[i]) < ComputeHash(version+agents[j])
+	})
+	n := int(math.Ceil(float64(len(agents)) * float64(percent) / 100))
+	if n < 1 {
+		n = 1
+	}
+	if n > len(agents) {
+		n = len(agents)
+	}
+	agents = agents[:n]
+	sort.Strings(agents)
+	return agents
+}
+
+// canaryStatus Returns the status of the canary in the SyntheticTest
+func canaryStatus(canary *common.SyntestCanary) *synheartv1.CanaryStatus {
+	if canary == nil {
+		return nil
+	}
+	return &synheartv1.CanaryStatus{
+		Version:   canary.Version,
+		Agents:    canary.Agents,
+		StartTime: metav1.NewTime(canary.StartedAt),
+		Failed:    canary.Failed,
+	}
+}
+
+func recordCanaryEvent(ctx context.Context, store storage.SynHeartStore, logger hclog.Logger, instance *synheartv1.SyntheticTest,
+	configId string, action string, stableVersion string, canary *common.SyntestCanary) {
+	details := map[string]string{
+		"oldVersion": stableVersion,
+		"version":    canary.Version,
+		"generation": strconv.FormatInt(instance.Generation, 10),
+	}
+	if len(canary.Agents) > 0 {
+		details["agents"] = fmt.Sprint(canary.Agents)
+	}
+	recordAuditEvent(ctx, store, logger, common.AuditEvent{
+		Actor:   lastFieldManager(instance),
+		Kind:    common.AuditKindSynTest,
+		Object:  configId,
+		Action:  action,
+		Message: canary.Message,
+		Details: details,
+	})
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// syncExternalTests Writes the SyntheticTests of a source which isn't a CRD (e.g. a git repo) to storage, and deletes
+// the tests of the source which aren't in it anymore. Tests with the same id as a test from another source (or a CRD)
+// are skipped. Tests failing the checks of the webhook are skipped too, and removed from storage if they're in a
+// namespace which isn't watched, reference the secrets of another namespace or exceed the SynTestQuotas of their
+// namespace (read with c). The revision is the version of the source (e.g. the commit), for the audit log.
+func syncExternalTests(ctx context.Context, store storage.SynHeartStore, c client.Reader, namespaces *namespaceFilter,
+	logger hclog.Logger, source string, revision string, synTests []synheartv1.SyntheticTest) error {
+	summaries, err := store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test config summaries")
+	}
+	quotaList := synheartv1.SynTestQuotaList{}
+	err = c.List(ctx, &quotaList)
+	if err != nil {
+		return errors.Wrap(err, "error listing quotas")
+	}
+	quotas := map[string][]synheartv1.SynTestQuota{}
+	for _, quota := range quotaList.Items {
+		quotas[quota.Namespace] = append(quotas[quota.Namespace], quota)
+	}
+	// tests deployed in each namespace, for the maxTests of the quotas: the tests of the other sources, and the tests of
+	// the source as they're synced
+	namespaceTests := map[string]int{}
+	for _, summary := range summaries {
+		if summary.Source != source {
+			namespaceTests[summary.Namespace]++
+		}
+	}
+
+	wanted := map[string]bool{}
+	for i := range synTests {
+		synTest := &synTests[i]
+		configId := common.ComputeSynTestConfigId(synTest.Name, synTest.Namespace)
+		if wanted[configId] {
+			logger.Warn("duplicate test, skipping it", "configId", configId)
+			continue
+		}
+		wanted[configId] = true
+		if summary, ok := summaries[configId]; ok && summary.Source != source {
+			logger.Warn("test already exists from another source, skipping it", "configId", configId, "source", summary.Source)
+			continue
+		}
+		if errs := synheartv1.ValidateSpec(&synTest.Spec); len(errs) > 0 {
+			logger.Warn("invalid test, skipping it", "configId", configId, "err", errs.ToAggregate().Error())
+			continue
+		}
+		// tests aren't assigned to an agent, as there's no status to keep the assignment in
+		if strings.Contains(synTest.Spec.Node, "$") || synTest.Spec.PodLabelSelector[common.SpecialKeyPodName] == "$" {
+			logger.Warn("agent assignment ('$') isn't supported for tests from "+source+", skipping it", "configId", configId)
+			continue
+		}
+		if synTest.Namespace != common.ClusterTestNamespace {
+			reason, err := namespaces.check(ctx, c, synTest.Namespace)
+			if err != nil {
+				return errors.Wrap(err, "error checking the namespace of "+configId)
+			}
+			if reason != "" {
+				logger.Warn("namespace of the test isn't watched, skipping it", "configId", configId, "reason", reason)
+				delete(wanted, configId) // removed from storage if it was deployed
+				continue
+			}
+		}
+		if violation := externalTestViolation(synTest, quotas[synTest.Namespace], namespaceTests[synTest.Namespace]); violation != "" {
+			logger.Warn("test can't be deployed, skipping it", "configId", configId, "reason", violation)
+			delete(wanted, configId) // removed from storage if it was deployed
+			continue
+		}
+		namespaceTests[synTest.Namespace]++
+
+		labels := map[string]string{}
+		for k, v := range synTest.Labels {
+			labels[k] = v
+		}
+		labels[common.ConfigSourceLabel] = source
+		synTest.Labels = labels
+
+		config := buildTestConfig(synTest, synTest.Spec.Node, synTest.Spec.PodLabelSelector, logger)
+		config.Version = configVersion(&config)
+		oldVersion := summaries[configId].Version
+		if oldVersion == config.Version {
+			continue
+		}
+		rawConfig, err := yaml.Marshal(synTest.Spec)
+		if err != nil {
+			return errors.Wrap(err, "error marshalling spec yaml")
+		}
+		logger.Info("updating test config in redis", "configId", configId, "version", config.Version, "revision", revision)
+		err = store.WriteTestConfig(ctx, config, string(rawConfig))
+		if err != nil {
+			return errors.Wrap(err, "error writing test config to redis")
+		}
+		err = store.WriteTestConfigStatus(ctx, configId, common.SyntestConfigStatus{
+			Deployed: true,
+			Message:  "synced from " + source + " (" + revision + ")",
+			Agent:    "multiple",
+		})
+		if err != nil {
+			logger.Warn("unable to update status in redis", "configId", configId, "err", err)
+		}
+
+		action := common.AuditActionUpdated
+		if oldVersion == "" {
+			action = common.AuditActionCreated
+		}
+		recordAuditEvent(ctx, store, logger, common.AuditEvent{
+			Actor:  source + "@" + revision,
+			Kind:   common.AuditKindSynTest,
+			Object: configId,
+			Action: action,
+			Details: map[string]string{
+				"oldVersion": oldVersion,
+				"version":    config.Version,
+			},
+		})
+	}
+
+	// delete the tests removed from the source
+	for configId, summary := range summaries {
+		if summary.Source != source || wanted[configId] {
+			continue
+		}
+		logger.Info("deleting test removed from "+source, "configId", configId, "revision", revision)
+		err = store.DeleteTestConfig(ctx, configId)
+		if err != nil {
+			logger.Warn("error deleting test config, continuing", "configId", configId, "err", err)
+			continue
+		}
+		recordAuditEvent(ctx, store, logger, common.AuditEvent{
+			Actor:  source + "@" + revision,
+			Kind:   common.AuditKindSynTest,
+			Object: configId,
+			Action: common.AuditActionDeleted,
+		})
+	}
+	return nil
+}
+
+// externalTestViolation Returns why a test from an external source can't be deployed (empty if it can), with the checks
+// of the webhook which need more than its spec: the namespaces of its secret refs, and the quotas of its namespace
+// (tests is the number of tests already deployed in the namespace)
+func externalTestViolation(synTest *synheartv1.SyntheticTest, quotas []synheartv1.SynTestQuota, tests int) string {
+	violations := []string{}
+	for _, ref := range synTest.Spec.ProtoSecretRefs() {
+		if _, err := common.SecretRefNamespace(ref, synTest.Namespace); err != nil {
+			violations = append(violations, "secret ref '"+ref.Name+"': "+err.Error())
+		}
+	}
+	if synTest.Namespace == common.ClusterTestNamespace {
+		return strings.Join(violations, "; ") // cluster tests aren't subject to quotas
+	}
+	for i := range quotas {
+		quota := &quotas[i]
+		for _, err := range synheartv1.ValidateQuota(quota, &synTest.Spec) {
+			violations = append(violations, err.Error())
+		}
+		if quota.Spec.MaxTests > 0 && tests >= int(quota.Spec.MaxTests) {
+			violations = append(violations, fmt.Sprintf("namespace already has %d tests, the maxTests of quota '%s'",
+				tests, quota.Name))
+		}
+	}
+	return strings.Join(violations, "; ")
+}
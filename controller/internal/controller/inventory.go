@@ -0,0 +1,106 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// renderInventories Renders the targets of the TargetInventories the test references into its config (in place).
+// Returns why the config can't be rendered (e.g. an inventory doesn't exist), empty if it was rendered.
+func (r *SyntheticTestReconciler) renderInventories(ctx context.Context, instance *synheartv1.SyntheticTest) (string, error) {
+	if len(instance.Spec.TargetInventories) == 0 {
+		return "", nil
+	}
+	inventories := map[string][]string{}
+	for _, ref := range instance.Spec.TargetInventories {
+		key, err := synheartv1.ParseInventoryRef(ref, instance.Namespace)
+		if err != nil {
+			return err.Error(), nil
+		}
+		inventory := &synheartv1.TargetInventory{}
+		err = r.Client.Get(ctx, key, inventory)
+		if k8serrors.IsNotFound(err) {
+			return "inventory '" + ref + "' not found", nil
+		} else if err != nil {
+			return "", errors.Wrap(err, "error fetching inventory "+ref)
+		}
+		inventories[ref] = inventory.Spec.Targets
+	}
+	config, err := synheartv1.RenderConfig(&instance.Spec, inventories)
+	if err != nil {
+		return err.Error(), nil
+	}
+	instance.Spec.Config = config
+	return "", nil
+}
+
+// rejectUnrenderedConfig Keeps the deployed config of a test whose config can't be rendered (so a missing inventory
+// doesn't stop it), and shows why in its status. It's reconciled again when its inventories change.
+func (r *SyntheticTestReconciler) rejectUnrenderedConfig(ctx context.Context, instance *synheartv1.SyntheticTest, configId string,
+	reason string, store storage.SynHeartStore, logger hclog.Logger) (ctrl.Result, error) {
+	logger.Warn("unable to render the targets of the inventories into the config, keeping the deployed config", "reason", reason)
+	r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+		Deployed: instance.Status.Deployed,
+		Message:  "error: " + reason,
+		Agent:    instance.Status.Agent,
+	}, store, logger)
+	return ctrl.Result{}, nil
+}
+
+// testsOfInventory Returns the requests to reconcile the tests referencing an inventory, when the inventory changes
+func (r *SyntheticTestReconciler) testsOfInventory(ctx context.Context, inventory client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	var synTestList synheartv1.SyntheticTestList
+	err := r.Client.List(ctx, &synTestList, client.InNamespace(inventory.GetNamespace()))
+	if err != nil {
+		return requests
+	}
+	for _, synTest := range synTestList.Items {
+		if slices.Contains(synTest.Spec.TargetInventories, inventory.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: synTest.Name, Namespace: synTest.Namespace},
+			})
+		}
+	}
+	// cluster tests reference the inventories as <namespace>/<name>
+	var clusterTestList synheartv1.ClusterSyntheticTestList
+	err = r.Client.List(ctx, &clusterTestList)
+	if err != nil {
+		return requests
+	}
+	for _, clusterTest := range clusterTestList.Items {
+		if slices.Contains(clusterTest.Spec.TargetInventories, inventory.GetNamespace()+"/"+inventory.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: clusterTest.Name},
+			})
+		}
+	}
+	return requests
+}
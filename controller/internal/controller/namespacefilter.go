@@ -0,0 +1,175 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// namespaceFilter selects the namespaces whose SyntheticTests are deployed, so tests created in other namespaces (e.g.
+// sandboxes) can't be injected into the agents. A namespace is watched if it matches the include names (all if empty)
+// and selector, and doesn't match the exclude names or selector.
+type namespaceFilter struct {
+	include         []string // names of the namespaces, or globs (e.g. "team-*")
+	exclude         []string
+	includeSelector labels.Selector // nil if not set
+	excludeSelector labels.Selector
+}
+
+// namespaceFilterFromEnv Returns the filter configured by the WATCH_NAMESPACES, EXCLUDE_NAMESPACES,
+// WATCH_NAMESPACE_SELECTOR and EXCLUDE_NAMESPACE_SELECTOR env vars, nil if none is set
+func namespaceFilterFromEnv() (*namespaceFilter, error) {
+	filter := &namespaceFilter{
+		include: splitNamespaces(os.Getenv("WATCH_NAMESPACES")),
+		exclude: splitNamespaces(os.Getenv("EXCLUDE_NAMESPACES")),
+	}
+	for _, pattern := range append(append([]string{}, filter.include...), filter.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrap(err, "invalid namespace pattern '"+pattern+"'")
+		}
+	}
+	var err error
+	if selector := os.Getenv("WATCH_NAMESPACE_SELECTOR"); selector != "" {
+		filter.includeSelector, err = labels.Parse(selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid WATCH_NAMESPACE_SELECTOR")
+		}
+	}
+	if selector := os.Getenv("EXCLUDE_NAMESPACE_SELECTOR"); selector != "" {
+		filter.excludeSelector, err = labels.Parse(selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid EXCLUDE_NAMESPACE_SELECTOR")
+		}
+	}
+	if len(filter.include) == 0 && len(filter.exclude) == 0 && !filter.usesLabels() {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+func splitNamespaces(s string) []string {
+	namespaces := []string{}
+	for _, ns := range strings.Split(s, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// usesLabels Returns whether the labels of the namespaces are needed, so they're watched
+func (f *namespaceFilter) usesLabels() bool {
+	return f.includeSelector != nil || f.excludeSelector != nil
+}
+
+// check Returns why the tests of the namespace aren't deployed (empty if they are). A nil filter watches all namespaces.
+func (f *namespaceFilter) check(ctx context.Context, c client.Reader, namespace string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	if len(f.include) > 0 && !matchesAny(f.include, namespace) {
+		return "namespace isn't in the watched namespaces", nil
+	}
+	if matchesAny(f.exclude, namespace) {
+		return "namespace is in the excluded namespaces", nil
+	}
+	if !f.usesLabels() {
+		return "", nil
+	}
+	ns := &corev1.Namespace{}
+	err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+	if err != nil {
+		return "", errors.Wrap(err, "error fetching namespace")
+	}
+	if f.includeSelector != nil && !f.includeSelector.Matches(labels.Set(ns.Labels)) {
+		return "namespace doesn't match the watched namespace selector", nil
+	}
+	if f.excludeSelector != nil && f.excludeSelector.Matches(labels.Set(ns.Labels)) {
+		return "namespace matches the excluded namespace selector", nil
+	}
+	return "", nil
+}
+
+func matchesAny(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectExcludedNamespace Removes the config of a test in a namespace which isn't watched from storage (so the agents
+// stop running it), and shows why in its status
+func (r *SyntheticTestReconciler) rejectExcludedNamespace(ctx context.Context, instance *synheartv1.SyntheticTest,
+	configId string, reason string, store storage.SynHeartStore, logger hclog.Logger) (ctrl.Result, error) {
+	logger.Warn("namespace of the test isn't watched, not deploying it", "reason", reason)
+	_, err := store.FetchTestConfig(ctx, configId)
+	if err == nil {
+		err = store.DeleteTestConfig(ctx, configId)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "error deleting test config of excluded namespace from redis")
+		}
+		recordAuditEvent(ctx, store, logger, common.AuditEvent{
+			Kind:    common.AuditKindSynTest,
+			Object:  configId,
+			Action:  common.AuditActionDeleted,
+			Message: "namespace excluded: " + reason,
+		})
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return ctrl.Result{}, errors.Wrap(err, "error fetching test config from redis")
+	}
+	instance.Status.Canary = nil
+	instance.Status.PinnedVersion = ""
+	r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+		Deployed: false,
+		Message:  "error: namespace excluded: " + reason,
+		Agent:    "",
+	}, store, logger)
+	return ctrl.Result{}, nil
+}
+
+// testsOfNamespace Returns the requests to reconcile the tests in a namespace, when its labels change
+func (r *SyntheticTestReconciler) testsOfNamespace(ctx context.Context, ns client.Object) []reconcile.Request {
+	var synTestList synheartv1.SyntheticTestList
+	err := r.Client.List(ctx, &synTestList, client.InNamespace(ns.GetName()))
+	if err != nil {
+		return []reconcile.Request{}
+	}
+	requests := []reconcile.Request{}
+	for _, synTest := range synTestList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: synTest.Name, Namespace: synTest.Namespace},
+		})
+	}
+	return requests
+}
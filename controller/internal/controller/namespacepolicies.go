@@ -0,0 +1,64 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NamespacePoliciesInterval is how often the quotas of the namespaces are published to storage
+const NamespacePoliciesInterval = 30 * time.Second
+
+// publishNamespacePolicies Writes the quotas of the namespaces, and the namespaces watched if they're filtered, to
+// storage, so the rest api enforces them on the tests written through it (it can't read them from kubernetes)
+func (r *SyntheticTestReconciler) publishNamespacePolicies(ctx context.Context, store storage.SynHeartStore) error {
+	quotas := synheartv1.SynTestQuotaList{}
+	err := r.Client.List(ctx, &quotas)
+	if err != nil {
+		return errors.Wrap(err, "error listing quotas")
+	}
+	policies := common.NamespacePolicies{Time: time.Now(), Quotas: map[string][]common.SynTestQuota{}}
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		policies.Quotas[quota.Namespace] = append(policies.Quotas[quota.Namespace], quota.TestQuota())
+	}
+	if r.namespaces != nil {
+		namespaces := corev1.NamespaceList{}
+		err = r.Client.List(ctx, &namespaces)
+		if err != nil {
+			return errors.Wrap(err, "error listing namespaces")
+		}
+		policies.Filtered = true
+		for _, ns := range namespaces.Items {
+			reason, err := r.namespaces.check(ctx, r.Client, ns.Name)
+			if err != nil {
+				return errors.Wrap(err, "error checking namespace "+ns.Name)
+			}
+			if reason == "" {
+				policies.Watched = append(policies.Watched, ns.Name)
+			}
+		}
+	}
+	return store.WriteNamespacePolicies(ctx, policies)
+}
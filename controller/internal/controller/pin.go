@@ -0,0 +1,111 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// PinCheckInterval is how often a pinned test is reconciled, so a pin removed through the rest api is picked up
+const PinCheckInterval = 1 * time.Minute
+
+// pinnedVersion Returns the version the test is pinned to, the annotation takes precedence over the pin in storage
+// (set when rolling back through the rest api). Empty if the test isn't pinned.
+func pinnedVersion(ctx context.Context, instance *synheartv1.SyntheticTest, configId string, store storage.SynHeartStore) (string, error) {
+	if version := instance.Annotations[synheartv1.PinVersionAnnotation]; version != "" {
+		return version, nil
+	}
+	version, err := store.FetchTestConfigPin(ctx, configId)
+	if err != nil {
+		return "", errors.Wrap(err, "error fetching config pin from redis")
+	}
+	return version, nil
+}
+
+// deployPinnedVersion Makes sure the config in storage is the pinned version (taken from the config history), the
+// spec of the test is ignored till it's unpinned
+func (r *SyntheticTestReconciler) deployPinnedVersion(ctx context.Context, instance *synheartv1.SyntheticTest, configId string,
+	version string, store storage.SynHeartStore, logger hclog.Logger) (ctrl.Result, error) {
+
+	current, err := store.FetchTestConfig(ctx, configId)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return ctrl.Result{}, errors.Wrap(err, "error fetching test config from redis")
+	}
+	instance.Status.PinnedVersion = version
+
+	// a pinned test doesn't roll out canaries
+	if instance.Status.Canary != nil {
+		err = store.DeleteTestConfigCanary(ctx, configId)
+		if err != nil {
+			logger.Warn("error deleting canary config", "err", err)
+		}
+		instance.Status.Canary = nil
+	}
+
+	if current.Version != version {
+		logger.Info("deploying pinned version of the config", "version", version, "oldVersion", current.Version)
+		err = store.RollbackTestConfig(ctx, configId, version)
+		if errors.Is(err, storage.ErrNotFound) {
+			// nothing to retry till the pin is changed
+			logger.Error("pinned version isn't in the config history", "version", version)
+			r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+				Deployed: current.Version != "",
+				Message:  "error: pinned version " + version + " isn't in the config history",
+				Agent:    instance.Status.Agent,
+			}, store, logger)
+			return ctrl.Result{}, nil
+		} else if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "error rolling back test config in redis")
+		}
+		recordAuditEvent(ctx, store, logger, common.AuditEvent{
+			Actor:  lastFieldManager(instance),
+			Kind:   common.AuditKindSynTest,
+			Object: configId,
+			Action: common.AuditActionPinned,
+			Details: map[string]string{
+				"oldVersion": current.Version,
+				"version":    version,
+				"generation": strconv.FormatInt(instance.Generation, 10),
+			},
+		})
+		current, err = store.FetchTestConfig(ctx, configId)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "error fetching test config from redis")
+		}
+	}
+
+	// the pinned config keeps the agent it was assigned to back then
+	agent := "multiple"
+	if agentId, ok := current.PodLabelSelector[common.SpecialKeyAgentId]; ok {
+		agent = agentId
+	}
+	r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+		Deployed: true,
+		Message:  "pinned to version " + version,
+		Agent:    agent,
+	}, store, logger)
+	return ctrl.Result{RequeueAfter: PinCheckInterval}, nil
+}
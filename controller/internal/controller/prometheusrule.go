@@ -0,0 +1,217 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// PrometheusRulePrefix is the prefix of the names of the PrometheusRules generated for the tests
+	PrometheusRulePrefix = "synheart-"
+	// DefaultRuleFailureThreshold is the consecutive failed runs before the failing alert fires (same as the agent)
+	DefaultRuleFailureThreshold = 3
+	// MinAbsentFor is the shortest time the metrics of a test must be missing before the absent alert fires
+	MinAbsentFor = 5 * time.Minute
+)
+
+// prometheusRuleGVK is the PrometheusRule of the prometheus operator, it's unstructured so the operator isn't a
+// dependency (and its CRD doesn't need to be installed if the rules are disabled)
+var prometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+
+// DefaultRuleSeverities maps the importance of a test to the severity label of its alerts
+var DefaultRuleSeverities = common.ImportanceSeverities
+
+// PrometheusRulesEnabled Returns whether a PrometheusRule is generated for every SyntheticTest (PROMETHEUS_RULES env var)
+func PrometheusRulesEnabled() bool {
+	return os.Getenv("PROMETHEUS_RULES") == "true"
+}
+
+// prometheusRuleLabels Returns the labels set on the generated PrometheusRules (PROMETHEUS_RULE_LABELS env var, e.g.
+// "release=prometheus"), so they match the rule selector of the prometheus
+func prometheusRuleLabels(logger hclog.Logger) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("PROMETHEUS_RULE_LABELS"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			logger.Warn("invalid label in PROMETHEUS_RULE_LABELS, ignoring it", "label", pair)
+			continue
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels
+}
+
+func newPrometheusRule() *unstructured.Unstructured {
+	rule := &unstructured.Unstructured{}
+	rule.SetGroupVersionKind(prometheusRuleGVK)
+	return rule
+}
+
+// syncPrometheusRule Creates or updates the PrometheusRule alerting on the test, or deletes it if alerting is disabled
+// for the test or it's paused (its metrics are missing). The rule is owned by the test, so it's garbage collected with it.
+func (r *SyntheticTestReconciler) syncPrometheusRule(ctx context.Context, instance *synheartv1.SyntheticTest, logger hclog.Logger) error {
+	rule := newPrometheusRule()
+	rule.SetName(PrometheusRulePrefix + instance.Name)
+	rule.SetNamespace(instance.Namespace)
+
+	if (instance.Spec.Alerting != nil && instance.Spec.Alerting.Disabled) || instance.Paused() {
+		err := r.Client.Delete(ctx, rule)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "error deleting prometheus rule")
+		}
+		return nil
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, rule, func() error {
+		// don't take over a rule which was created by someone else
+		created := rule.GetCreationTimestamp()
+		if !created.IsZero() && !metav1.IsControlledBy(rule, instance) {
+			return errors.New("prometheus rule " + rule.GetName() + " already exists and isn't owned by the test")
+		}
+		labels := rule.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for k, v := range prometheusRuleLabels(logger) {
+			labels[k] = v
+		}
+		rule.SetLabels(labels)
+		err := unstructured.SetNestedField(rule.Object, prometheusRuleSpec(instance), "spec")
+		if err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(instance, rule, r.Scheme)
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating or updating prometheus rule")
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("prometheus rule "+string(op), "name", rule.GetName())
+	}
+	return nil
+}
+
+// prometheusRuleSpec Returns the spec of the PrometheusRule of the test, with an alert firing when the test fails a
+// number of consecutive runs, and one firing when its metrics are missing (e.g. no agent runs it)
+func prometheusRuleSpec(instance *synheartv1.SyntheticTest) map[string]interface{} {
+	selector := fmt.Sprintf("test_name=%q,test_namespace=%q", instance.Name, instance.Namespace)
+	threshold := int64(DefaultRuleFailureThreshold)
+	labels := map[string]interface{}{}
+	annotations := map[string]interface{}{}
+	if alerting := instance.Spec.Alerting; alerting != nil {
+		if alerting.FailureThreshold > 0 {
+			threshold = int64(alerting.FailureThreshold)
+		}
+		for k, v := range alerting.Labels {
+			labels[k] = v
+		}
+		for k, v := range alerting.Annotations {
+			annotations[k] = v
+		}
+	}
+	importance := common.ImportanceLevel(instance.Spec.Importance)
+	labels["importance"] = importance
+	if _, ok := labels["severity"]; !ok {
+		labels["severity"] = DefaultRuleSeverities[importance]
+	}
+	testName := instance.Namespace + "/" + instance.Name
+
+	failing := map[string]interface{}{
+		"alert":  "SyntheticTestFailing",
+		"expr":   fmt.Sprintf("%s{%s} < %s{%s}", common.MetricMarks, selector, common.MetricMaxMarks, selector),
+		"labels": copyRuleMap(labels),
+		"annotations": withDefaults(annotations, map[string]string{
+			"summary":     "Synthetic test " + testName + " is failing",
+			"description": fmt.Sprintf("Synthetic test %s failed %d consecutive runs on agent {{ $labels.instance }}", testName, threshold),
+		}),
+	}
+	rules := []interface{}{failing}
+
+	// tests without a repeat only run when the tests they depend on run, so they're only checked for failures
+	repeat, err := time.ParseDuration(instance.Spec.Repeat)
+	if err == nil && repeat > 0 {
+		if threshold > 1 {
+			failing["for"] = promDuration(time.Duration(threshold-1) * repeat)
+		}
+		absentFor := 3 * repeat
+		if absentFor < MinAbsentFor {
+			absentFor = MinAbsentFor
+		}
+		rules = append(rules, map[string]interface{}{
+			"alert":  "SyntheticTestAbsent",
+			"expr":   fmt.Sprintf("absent(%s{%s})", common.MetricMarks, selector),
+			"for":    promDuration(absentFor),
+			"labels": copyRuleMap(labels),
+			"annotations": withDefaults(annotations, map[string]string{
+				"summary":     "Synthetic test " + testName + " has no metrics",
+				"description": "No agent exports the results of synthetic test " + testName + ", it may not be running",
+			}),
+		})
+	}
+
+	return map[string]interface{}{
+		"groups": []interface{}{
+			map[string]interface{}{
+				"name":  "synheart." + instance.Namespace + "." + instance.Name,
+				"rules": rules,
+			},
+		},
+	}
+}
+
+// promDuration Formats a duration for prometheus, which doesn't accept fractions (e.g. "1m30s" is "90s")
+func promDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Round(time.Second).Seconds()))
+}
+
+// withDefaults Returns a copy of the map, with the defaults added for the keys it doesn't have
+func withDefaults(m map[string]interface{}, defaults map[string]string) map[string]interface{} {
+	out := copyRuleMap(m)
+	for k, v := range defaults {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// copyRuleMap Returns a copy of the map, as the maps of an unstructured object can't be shared
+func copyRuleMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// QuotaCheckInterval is how often a test exceeding a quota is reconciled, so it's deployed once the quota allows it
+const QuotaCheckInterval = 1 * time.Minute
+
+// checkQuotas Returns why the test exceeds the SynTestQuotas of its namespace (empty if it doesn't). If a namespace
+// has more tests than maxTests (created before the quota, or while the webhook was disabled), the oldest are deployed.
+func (r *SyntheticTestReconciler) checkQuotas(ctx context.Context, instance *synheartv1.SyntheticTest) (string, error) {
+	if instance.Namespace == common.ClusterTestNamespace {
+		return "", nil // cluster tests aren't part of any namespace
+	}
+	quotas := synheartv1.SynTestQuotaList{}
+	err := r.Client.List(ctx, &quotas, client.InNamespace(instance.Namespace))
+	if err != nil {
+		return "", errors.Wrap(err, "error listing quotas of the namespace")
+	}
+
+	violations := []string{}
+	var tests []synheartv1.SyntheticTest
+	for i := range quotas.Items {
+		quota := &quotas.Items[i]
+		for _, err := range synheartv1.ValidateQuota(quota, &instance.Spec) {
+			violations = append(violations, err.Error())
+		}
+		if quota.Spec.MaxTests <= 0 {
+			continue
+		}
+		if tests == nil {
+			tests, err = r.testsByAge(ctx, instance.Namespace)
+			if err != nil {
+				return "", err
+			}
+		}
+		for idx, synTest := range tests {
+			if synTest.Name == instance.Name && idx >= int(quota.Spec.MaxTests) {
+				violations = append(violations, fmt.Sprintf("only the oldest %d tests of the namespace are deployed (maxTests of quota '%s')",
+					quota.Spec.MaxTests, quota.Name))
+			}
+		}
+	}
+	return strings.Join(violations, "; "), nil
+}
+
+// testsByAge Returns the tests of the namespace which aren't being deleted, oldest first
+func (r *SyntheticTestReconciler) testsByAge(ctx context.Context, namespace string) ([]synheartv1.SyntheticTest, error) {
+	list := synheartv1.SyntheticTestList{}
+	err := r.Client.List(ctx, &list, client.InNamespace(namespace))
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing tests of the namespace")
+	}
+	tests := []synheartv1.SyntheticTest{}
+	for _, synTest := range list.Items {
+		if synTest.DeletionTimestamp.IsZero() {
+			tests = append(tests, synTest)
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		if !tests[i].CreationTimestamp.Equal(&tests[j].CreationTimestamp) {
+			return tests[i].CreationTimestamp.Before(&tests[j].CreationTimestamp)
+		}
+		return tests[i].Name < tests[j].Name
+	})
+	return tests, nil
+}
+
+// rejectOverQuota Removes the config of a test exceeding a quota from storage (so the agents stop running it), and
+// shows why in its status
+func (r *SyntheticTestReconciler) rejectOverQuota(ctx context.Context, instance *synheartv1.SyntheticTest, configId string,
+	violation string, store storage.SynHeartStore, logger hclog.Logger) (ctrl.Result, error) {
+	logger.Warn("test exceeds the quota of its namespace, not deploying it", "violation", violation)
+	_, err := store.FetchTestConfig(ctx, configId)
+	if err == nil {
+		err = store.DeleteTestConfig(ctx, configId)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "error deleting test config exceeding quota from redis")
+		}
+		recordAuditEvent(ctx, store, logger, common.AuditEvent{
+			Kind:    common.AuditKindSynTest,
+			Object:  configId,
+			Action:  common.AuditActionDeleted,
+			Message: "exceeds quota: " + violation,
+		})
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return ctrl.Result{}, errors.Wrap(err, "error fetching test config from redis")
+	}
+	instance.Status.Canary = nil
+	instance.Status.PinnedVersion = ""
+	r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
+		Deployed: false,
+		Message:  "error: exceeds quota: " + violation,
+		Agent:    "",
+	}, store, logger)
+	return ctrl.Result{RequeueAfter: QuotaCheckInterval}, nil
+}
+
+// testsOfQuota Returns the requests to reconcile the tests in the namespace of a quota, when the quota changes
+func (r *SyntheticTestReconciler) testsOfQuota(ctx context.Context, quota client.Object) []reconcile.Request {
+	var synTestList synheartv1.SyntheticTestList
+	err := r.Client.List(ctx, &synTestList, client.InNamespace(quota.GetNamespace()))
+	if err != nil {
+		return []reconcile.Request{}
+	}
+	requests := []reconcile.Request{}
+	for _, synTest := range synTestList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: synTest.Name, Namespace: synTest.Namespace},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	DefaultMaxConcurrentReconciles = 1
+	DefaultReconcileBaseDelay      = 5 * time.Millisecond // same as the client-go default rate limiter
+	DefaultReconcileMaxDelay       = 1000 * time.Second
+	DefaultReconcileQPS            = 10
+	DefaultReconcileBurst          = 100
+	DefaultReconcileCoalesceDelay  = 1 * time.Second
+)
+
+// reconcileOptions configures how fast the tests are reconciled, so bulk changes (e.g. applying hundreds of tests)
+// don't overload redis and make the agents sync over and over
+type reconcileOptions struct {
+	maxConcurrent int
+	baseDelay     time.Duration // retry delay of a failed reconcile, doubled on every failure
+	maxDelay      time.Duration
+	qps           float64 // reconciles per second of all tests (after the burst)
+	burst         int
+	coalesceDelay time.Duration // edits of a test within it are reconciled once
+}
+
+// reconcileOptionsFromEnv Returns the options configured by the MAX_CONCURRENT_RECONCILES, RECONCILE_BASE_DELAY,
+// RECONCILE_MAX_DELAY, RECONCILE_QPS, RECONCILE_BURST and RECONCILE_COALESCE_DELAY env vars (or their defaults)
+func reconcileOptionsFromEnv(logger hclog.Logger) reconcileOptions {
+	return reconcileOptions{
+		maxConcurrent: envInt("MAX_CONCURRENT_RECONCILES", DefaultMaxConcurrentReconciles, logger),
+		baseDelay:     envDuration("RECONCILE_BASE_DELAY", DefaultReconcileBaseDelay, logger),
+		maxDelay:      envDuration("RECONCILE_MAX_DELAY", DefaultReconcileMaxDelay, logger),
+		qps:           float64(envInt("RECONCILE_QPS", DefaultReconcileQPS, logger)),
+		burst:         envInt("RECONCILE_BURST", DefaultReconcileBurst, logger),
+		coalesceDelay: envDuration("RECONCILE_COALESCE_DELAY", DefaultReconcileCoalesceDelay, logger),
+	}
+}
+
+// ResyncPeriod Returns how often the cache of the controllers is resynced, which reconciles all the tests again
+// (RESYNC_PERIOD env var), nil for the controller-runtime default
+func ResyncPeriod(logger hclog.Logger) *time.Duration {
+	if _, ok := os.LookupEnv("RESYNC_PERIOD"); !ok {
+		return nil
+	}
+	period := envDuration("RESYNC_PERIOD", 0, logger)
+	if period == 0 {
+		return nil
+	}
+	return &period
+}
+
+func envDuration(name string, def time.Duration, logger hclog.Logger) time.Duration {
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	dur, err := time.ParseDuration(val)
+	if err != nil || dur < 0 {
+		logger.Warn("unable to parse "+name+" duration, using default: "+val, "default", def)
+		return def
+	}
+	return dur
+}
+
+func envInt(name string, def int, logger hclog.Logger) int {
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil || i <= 0 {
+		logger.Warn("unable to parse "+name+", using default: "+val, "default", def)
+		return def
+	}
+	return i
+}
+
+// rateLimiter Returns the rate limiter of the retries of failed (or requeued) reconciles, the per-test exponential
+// backoff and the overall rate limit, whichever is slower
+func (o reconcileOptions) rateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(o.baseDelay, o.maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(o.qps), o.burst)},
+	)
+}
+
+// enqueueCoalesced Returns an event handler which enqueues the test of the event after the coalesce delay (or later, to
+// stay within the rate limit). The queue keeps a single request per test, so successive edits of a test within the
+// delay result in one reconcile (of its latest spec), and one config push to the agents.
+func (o reconcileOptions) enqueueCoalesced() handler.EventHandler {
+	limiter := rate.NewLimiter(rate.Limit(o.qps), o.burst)
+	enqueue := func(obj client.Object, q workqueue.RateLimitingInterface) {
+		delay := o.coalesceDelay
+		if d := limiter.Reserve().Delay(); d > delay {
+			delay = d
+		}
+		q.AddAfter(reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()},
+		}, delay)
+	}
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.ObjectNew, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+	}
+}
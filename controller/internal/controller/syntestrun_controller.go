@@ -0,0 +1,304 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/cisco-open/synthetic-heart/controller/sync"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// SynTestRunPollInterval is how often the results of a running SynTestRun are collected from storage
+	SynTestRunPollInterval   = 2 * time.Second
+	DefaultSynTestRunTimeout = 5 * time.Minute
+	MaxSynTestRunErrorLength = 1024 // errors of the results are truncated, as they can contain the logs of the plugin
+)
+
+// SynTestRunReconciler reconciles a SynTestRun object, by requesting a run of its test from the agents via storage and
+// collecting their results
+type SynTestRunReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Namespace string // namespace of the controller, the only one whose runs can run cluster tests
+}
+
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=syntestruns,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=syntestruns/status,verbs=get;update;patch
+
+func (r *SynTestRunReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  fmt.Sprintf("reconcile-run [%s/%s]", request.Name, request.Namespace),
+		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+	})
+
+	instance := &synheartv1.SynTestRun{}
+	err := r.Client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// the results in storage expire on their own
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if !instance.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	store, err := ConnectToStorage(logger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	defer store.Close()
+
+	if instance.Finished() {
+		return r.cleanupFinishedRun(ctx, instance, store, logger)
+	}
+	if instance.Status.Phase == "" {
+		return r.startRun(ctx, instance, store, logger)
+	}
+	return r.collectResults(ctx, instance, store, logger)
+}
+
+// startRun Requests the run of the test from the agents running it
+func (r *SynTestRunReconciler) startRun(ctx context.Context, instance *synheartv1.SynTestRun, store storage.SynHeartStore,
+	logger hclog.Logger) (ctrl.Result, error) {
+	// cluster tests are controlled by the platform operators, so they can't be run from the namespaces of the teams
+	if instance.Spec.ClusterTest && (r.Namespace == "" || instance.Namespace != r.Namespace) {
+		return r.failRun(ctx, instance, "cluster tests can only be run from the namespace of the controller", logger)
+	}
+	configId := synTestRunConfigId(instance)
+	config, err := store.FetchTestConfig(ctx, configId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return r.failRun(ctx, instance, "test "+configId+" isn't deployed", logger)
+	} else if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error fetching test config")
+	}
+	if config.Paused {
+		return r.failRun(ctx, instance, "test "+configId+" is paused", logger)
+	}
+
+	activeAgents, err := sync.FetchActiveAgents(ctx, store, logger)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error fetching active agents")
+	}
+	agents := []string{}
+	for agentId, agentStatus := range activeAgents {
+		if slices.Contains(agentStatus.SynTests, configId) {
+			agents = append(agents, agentId)
+		}
+	}
+	if len(agents) == 0 {
+		return r.failRun(ctx, instance, "test "+configId+" isn't running on any agent", logger)
+	}
+	slices.Sort(agents)
+
+	// the status is updated first, so the run isn't requested again if the update fails
+	now := metav1.Now()
+	instance.Status = synheartv1.SynTestRunStatus{
+		Phase:     synheartv1.SynTestRunRunning,
+		Message:   fmt.Sprintf("waiting for results of %d agents", len(agents)),
+		StartTime: &now,
+		Agents:    agents,
+	}
+	err = r.Client.Status().Update(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error updating status of syntest run")
+	}
+
+	logger.Info("requesting test run", "test", configId, "agents", agents)
+	err = store.RequestTestRun(ctx, common.TestRunRequest{
+		Id:       string(instance.UID),
+		ConfigId: configId,
+		Time:     now.Time,
+	})
+	if err != nil {
+		return r.failRun(ctx, instance, "error requesting test run: "+err.Error(), logger)
+	}
+	recordAuditEvent(ctx, store, logger, common.AuditEvent{
+		Kind:    common.AuditKindSynTest,
+		Object:  configId,
+		Action:  common.AuditActionTriggered,
+		Message: "requested by syntest run " + instance.Namespace + "/" + instance.Name,
+		Details: map[string]string{"agents": fmt.Sprint(len(agents))},
+	})
+	return reconcile.Result{RequeueAfter: SynTestRunPollInterval}, nil
+}
+
+// collectResults Updates the status with the results of the agents, the run finishes when all the agents reported
+// (or it timed out)
+func (r *SynTestRunReconciler) collectResults(ctx context.Context, instance *synheartv1.SynTestRun, store storage.SynHeartStore,
+	logger hclog.Logger) (ctrl.Result, error) {
+	testRuns, err := store.FetchTestRunRequestResults(ctx, string(instance.UID))
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error fetching test run results")
+	}
+
+	status := instance.Status.DeepCopy()
+	status.Results = nil
+	status.Passed = 0
+	status.Failed = 0
+	pending := []string{}
+	for _, agentId := range status.Agents {
+		testRun, ok := testRuns[agentId]
+		if !ok {
+			pending = append(pending, agentId)
+			continue
+		}
+		result := synTestRunResult(agentId, &testRun)
+		if result.Passed {
+			status.Passed++
+		} else {
+			status.Failed++
+		}
+		status.Results = append(status.Results, result)
+	}
+
+	timeout := DefaultSynTestRunTimeout
+	if instance.Spec.Timeout != "" {
+		timeout, err = time.ParseDuration(instance.Spec.Timeout)
+		if err != nil || timeout <= 0 {
+			logger.Warn("invalid timeout, using default", "timeout", instance.Spec.Timeout, "default", DefaultSynTestRunTimeout)
+			timeout = DefaultSynTestRunTimeout
+		}
+	}
+	timedOut := status.StartTime != nil && time.Since(status.StartTime.Time) > timeout
+
+	now := metav1.Now()
+	switch {
+	case len(pending) == 0 && status.Failed == 0:
+		status.Phase = synheartv1.SynTestRunSucceeded
+		status.Message = fmt.Sprintf("passed on %d agents", status.Passed)
+		status.CompletionTime = &now
+	case len(pending) == 0:
+		status.Phase = synheartv1.SynTestRunFailed
+		status.Message = fmt.Sprintf("failed on %d of %d agents", status.Failed, len(status.Agents))
+		status.CompletionTime = &now
+	case timedOut:
+		status.Phase = synheartv1.SynTestRunFailed
+		status.Message = fmt.Sprintf("timed out waiting for results of %d agents: %v", len(pending), pending)
+		status.CompletionTime = &now
+	default:
+		status.Message = fmt.Sprintf("waiting for results of %d agents", len(pending))
+	}
+
+	instance.Status = *status
+	err = r.Client.Status().Update(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error updating status of syntest run")
+	}
+	if instance.Finished() {
+		logger.Info("test run finished", "phase", status.Phase, "message", status.Message)
+		return r.cleanupFinishedRun(ctx, instance, store, logger)
+	}
+	return reconcile.Result{RequeueAfter: SynTestRunPollInterval}, nil
+}
+
+// cleanupFinishedRun Deletes the run (and its results in storage) once its TTL after it finished expired
+func (r *SynTestRunReconciler) cleanupFinishedRun(ctx context.Context, instance *synheartv1.SynTestRun, store storage.SynHeartStore,
+	logger hclog.Logger) (ctrl.Result, error) {
+	if instance.Spec.TTLSecondsAfterFinished == nil || instance.Status.CompletionTime == nil {
+		return reconcile.Result{}, nil
+	}
+	ttl := time.Duration(*instance.Spec.TTLSecondsAfterFinished) * time.Second
+	remaining := time.Until(instance.Status.CompletionTime.Add(ttl))
+	if remaining > 0 {
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
+	logger.Info("deleting finished test run, ttl expired", "ttl", ttl)
+	err := store.DeleteTestRunRequest(ctx, string(instance.UID))
+	if err != nil {
+		logger.Warn("error deleting test run results", "err", err)
+	}
+	err = r.Client.Delete(ctx, instance)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return reconcile.Result{}, errors.Wrap(err, "error deleting syntest run")
+	}
+	return reconcile.Result{}, nil
+}
+
+// failRun Marks the run as failed without requesting it from the agents (e.g. the test doesn't exist)
+func (r *SynTestRunReconciler) failRun(ctx context.Context, instance *synheartv1.SynTestRun, msg string,
+	logger hclog.Logger) (ctrl.Result, error) {
+	logger.Info("test run failed", "reason", msg)
+	now := metav1.Now()
+	instance.Status.Phase = synheartv1.SynTestRunFailed
+	instance.Status.Message = msg
+	instance.Status.CompletionTime = &now
+	err := r.Client.Status().Update(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error updating status of syntest run")
+	}
+	// requeue to clean it up once its ttl expires
+	return reconcile.Result{Requeue: instance.Spec.TTLSecondsAfterFinished != nil}, nil
+}
+
+// synTestRunConfigId Returns the id of the config of the test of the run
+func synTestRunConfigId(instance *synheartv1.SynTestRun) string {
+	if instance.Spec.ClusterTest {
+		return common.ComputeSynTestConfigId(instance.Spec.Test, common.ClusterTestNamespace)
+	}
+	return common.ComputeSynTestConfigId(instance.Spec.Test, instance.Namespace)
+}
+
+// synTestRunResult Returns the result of the run on an agent
+func synTestRunResult(agentId string, testRun *proto.TestRun) synheartv1.SynTestRunResult {
+	result := synheartv1.SynTestRunResult{
+		Agent: agentId,
+		Error: testRun.Details[common.ErrorKey],
+	}
+	if testRun.TestResult != nil {
+		result.Marks = int64(testRun.TestResult.Marks)
+		result.MaxMarks = int64(testRun.TestResult.MaxMarks)
+		if result.Error == "" {
+			result.Error = testRun.TestResult.Details[common.ErrorKey]
+		}
+		result.Passed = result.Error == "" && testRun.TestResult.Marks >= testRun.TestResult.MaxMarks
+	}
+	start, startErr := time.Parse(common.TimeFormat, testRun.StartTime)
+	end, endErr := time.Parse(common.TimeFormat, testRun.EndTime)
+	if startErr == nil && endErr == nil {
+		result.Duration = end.Sub(start).String()
+	}
+	if len(result.Error) > MaxSynTestRunErrorLength {
+		result.Error = result.Error[:MaxSynTestRunErrorLength-3] + "..."
+	}
+	return result
+}
+
+func (r *SynTestRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&synheartv1.SynTestRun{}).
+		Complete(r)
+}
@@ -22,39 +22,78 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/activation"
 	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/slo"
 	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/controller/agenthealth"
+	"github.com/cisco-open/synthetic-heart/controller/agentregistry"
 	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/cisco-open/synthetic-heart/controller/federation"
+	"github.com/cisco-open/synthetic-heart/controller/gc"
+	"github.com/cisco-open/synthetic-heart/controller/gitops"
+	"github.com/cisco-open/synthetic-heart/controller/healthscore"
+	"github.com/cisco-open/synthetic-heart/controller/httpsource"
+	"github.com/cisco-open/synthetic-heart/controller/slometrics"
+	"github.com/cisco-open/synthetic-heart/controller/summary"
+	"github.com/cisco-open/synthetic-heart/controller/synalert"
 	"github.com/cisco-open/synthetic-heart/controller/sync"
+	"github.com/cisco-open/synthetic-heart/controller/testevents"
 	"github.com/go-logr/logr"
 	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+// SynTestFinalizer is added to the syntests, so their data in storage is cleaned up before they are deleted
+const SynTestFinalizer = "synheart.infra.webex.com/cleanup"
+
 // SyntheticTestReconciler reconciles a SyntheticTest object
 type SyntheticTestReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+
+	namespaces *namespaceFilter // namespaces whose tests are deployed, all if nil
 }
 
 // +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synthetictests,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synthetictests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synthetictests/finalizers,verbs=update
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=clustersynthetictests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=clustersynthetictests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=clustersynthetictests/finalizers,verbs=update
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synalerts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=targetinventories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synalerts/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=syntestquotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synheartagents,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synheartagents/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
 
 func (r *SyntheticTestReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	logger := hclog.New(&hclog.LoggerOptions{
@@ -82,8 +121,16 @@ func (r *SyntheticTestReconciler) Reconcile(ctx context.Context, request ctrl.Re
 		Status:     synheartv1.SyntheticTestStatus{},
 	}
 
-	// Fetch the instance of SyntheticTest
-	err = r.Client.Get(ctx, request.NamespacedName, instance)
+	// Fetch the instance of SyntheticTest, cluster tests (ClusterSyntheticTest) have no namespace
+	var obj client.Object = instance
+	clusterTest := &synheartv1.ClusterSyntheticTest{}
+	testNs := request.Namespace
+	if request.Namespace == "" {
+		obj = clusterTest
+		testNs = common.ClusterTestNamespace
+	}
+	configId := common.ComputeSynTestConfigId(request.Name, testNs)
+	err = r.Client.Get(ctx, request.NamespacedName, obj)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
@@ -92,20 +139,92 @@ func (r *SyntheticTestReconciler) Reconcile(ctx context.Context, request ctrl.Re
 			logger.Info(fmt.Sprintf("Object %v/%v not found! likely deleted, skipping reconciliation",
 				request.NamespacedName.Name, request.NamespacedName.Namespace))
 
-			// Delete test from redis
-			logger.Info("deleting syntest", request.Name, request.Namespace)
-			err := store.DeleteTestConfig(ctx, common.ComputeSynTestConfigId(request.Name, request.Namespace))
+			// Delete test from redis (in case it was deleted without the finalizer)
+			err := deleteSynTestData(ctx, store, logger, configId)
 			if err != nil {
 				logger.Info("warning: error deleting synthetic test", "err", err)
 			}
-
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
-	configId := common.ComputeSynTestConfigId(instance.Name, instance.Namespace)
+	// the syntest is being deleted, so remove all its data from storage before letting it go
+	if !obj.GetDeletionTimestamp().IsZero() {
+		if controllerutil.ContainsFinalizer(obj, SynTestFinalizer) {
+			err = deleteSynTestData(ctx, store, logger, configId)
+			if err != nil {
+				logger.Error("error cleaning up syntest data in storage, will retry", "err", err)
+				return reconcile.Result{}, err
+			}
+			controllerutil.RemoveFinalizer(obj, SynTestFinalizer)
+			err = r.Client.Update(ctx, obj)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "error removing finalizer")
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if controllerutil.AddFinalizer(obj, SynTestFinalizer) {
+		err = r.Client.Update(ctx, obj)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "error adding finalizer")
+		}
+	}
+
+	// cluster tests are reconciled as a syntest in the cluster test namespace
+	if request.Namespace == "" {
+		instance = clusterTest.AsSyntheticTest()
+	}
+
+	// tests in namespaces which aren't watched aren't deployed (cluster tests aren't part of any namespace)
+	if request.Namespace != "" {
+		reason, err := r.namespaces.check(ctx, r.Client, request.Namespace)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if reason != "" {
+			return r.rejectExcludedNamespace(ctx, instance, configId, reason, store, logger)
+		}
+	}
+
+	// keep the alerts of the test in sync with its spec (cluster tests have no namespace to put the rule in)
+	if request.Namespace != "" && PrometheusRulesEnabled() {
+		err = r.syncPrometheusRule(ctx, instance, logger)
+		if err != nil {
+			logger.Warn("unable to sync prometheus rule", "err", err)
+		}
+	}
+
+	// tests exceeding the quotas of their namespace aren't deployed
+	quotaViolation, err := r.checkQuotas(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if quotaViolation != "" {
+		return r.rejectOverQuota(ctx, instance, configId, quotaViolation, store, logger)
+	}
+
+	// a pinned test stays on the pinned version of its config, whatever its spec is
+	pinned, err := pinnedVersion(ctx, instance, configId, store)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if pinned != "" {
+		return r.deployPinnedVersion(ctx, instance, configId, pinned, store, logger)
+	}
+	instance.Status.PinnedVersion = ""
+
+	// the targets of the inventories the test references are rendered into its config
+	reason, err := r.renderInventories(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if reason != "" {
+		return r.rejectUnrenderedConfig(ctx, instance, configId, reason, store, logger)
+	}
 
 	// check if the test has the special key for node/pod assignment
 	needsNodeAssignment := strings.Contains(instance.Spec.Node, "$")
@@ -211,15 +330,34 @@ func (r *SyntheticTestReconciler) Reconcile(ctx context.Context, request ctrl.Re
 		agent = "multiple"
 	}
 
+	// roll config changes out to the canary agents first (only for tests running on multiple agents)
+	if agent == "multiple" && instance.Spec.Rollout != nil && instance.Spec.Rollout.CanaryPercent > 0 {
+		result, done, err := r.rolloutCanary(ctx, instance, configId, node, podLabelSelector, validAgents, store, logger)
+		if !done {
+			return result, err
+		}
+	} else if instance.Status.Canary != nil {
+		// canaries were disabled, the config is written for all agents
+		err = store.DeleteTestConfigCanary(ctx, configId)
+		if err != nil {
+			logger.Warn("error deleting canary config", "err", err)
+		}
+		instance.Status.Canary = nil
+	}
+
 	err = r.updateTestConfigInRedis(ctx, instance, configId, agent, node, podLabelSelector, store, logger)
 	if err != nil {
 		logger.Error("error updating test config in redis", "name", instance.Name, "err", err.Error())
 		return reconcile.Result{}, errors.Wrap(err, "error updating test config in redis")
 	}
 	// update status
+	message := "deployed to agent"
+	if instance.Paused() {
+		message = "deployed to agent, paused"
+	}
 	r.updateTestStatus(ctx, instance, configId, common.SyntestConfigStatus{
 		Deployed: true,
-		Message:  "deployed to agent",
+		Message:  message,
 		Agent:    agent,
 	}, store, logger)
 
@@ -239,6 +377,94 @@ func (r *SyntheticTestReconciler) updateTestConfigInRedis(ctx context.Context, i
 		}
 	}
 
+	newTestConfig := buildTestConfig(instance, node, podLabelSelector, logger)
+
+	// check if the version in redis is the same as CRD
+	configHash := configVersion(&newTestConfig)
+	onLatestVersion := configInRedis.Version == configHash
+
+	// return if the synthetic test is on the latest version and theres no change in the agent its supposed to run on
+	if onLatestVersion && instance.Status.Agent == newAgent {
+		logger.Info("synthetic test is already on latest version and no changes in agent", "version", configHash, "agent", newAgent)
+		return nil
+	}
+
+	// we need to update the version in redis
+
+	// update the version
+	newTestConfig.Version = configHash
+
+	// marshal the CRD as the "raw config"
+	rawConfig, err := yaml.Marshal(instance.Spec)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling spec yaml")
+	}
+
+	// write to redis
+	logger.Info("updating test config in redis", "name", instance.Name, "version", configHash, "newAgent", newAgent)
+	err = store.WriteTestConfig(ctx, newTestConfig, string(rawConfig))
+	if err != nil {
+		return errors.Wrap(err, "error writing test config to redis")
+	}
+
+	action := common.AuditActionUpdated
+	if configInRedis.Version == "" {
+		action = common.AuditActionCreated
+	} else if onLatestVersion {
+		action = common.AuditActionReassigned
+	} else if newTestConfig.Paused != configInRedis.Paused {
+		action = common.AuditActionResumed
+		if newTestConfig.Paused {
+			action = common.AuditActionPaused
+		}
+	}
+	recordAuditEvent(ctx, store, logger, common.AuditEvent{
+		Actor:  lastFieldManager(instance),
+		Kind:   common.AuditKindSynTest,
+		Object: configId,
+		Action: action,
+		Details: map[string]string{
+			"oldVersion": configInRedis.Version,
+			"version":    configHash,
+			"generation": strconv.FormatInt(instance.Generation, 10),
+			"agent":      newAgent,
+		},
+	})
+	return nil
+}
+
+// buildTestConfig Returns the config of the syntest stored for the agents (without its version)
+func buildTestConfig(instance *synheartv1.SyntheticTest, node string, podLabelSelector map[string]string, logger hclog.Logger) proto.SynTestConfig {
+	var alerting *proto.Alerting
+	if instance.Spec.Alerting != nil {
+		alerting = &proto.Alerting{
+			FailureThreshold: instance.Spec.Alerting.FailureThreshold,
+			Labels:           instance.Spec.Alerting.Labels,
+			Annotations:      instance.Spec.Alerting.Annotations,
+			Disabled:         instance.Spec.Alerting.Disabled,
+			SlackChannel:     instance.Spec.Alerting.SlackChannel,
+		}
+	}
+
+	var testSlo *proto.SLO
+	if instance.Spec.SLO != nil {
+		testSlo = &proto.SLO{
+			Target: instance.Spec.SLO.Target,
+			Window: instance.Spec.SLO.Window,
+		}
+		if _, _, err := slo.Parse(testSlo); err != nil {
+			logger.Warn("invalid slo, ignoring it", "name", instance.Name, "err", err)
+			testSlo = nil
+		}
+	}
+
+	activeFrom, activeUntil, activeWindows := instance.Spec.ActiveFrom, instance.Spec.ActiveUntil, instance.Spec.ProtoActiveWindows()
+	if _, err := activation.Parse(&proto.SynTestConfig{ActiveFrom: activeFrom, ActiveUntil: activeUntil,
+		ActiveWindows: activeWindows}); err != nil {
+		logger.Warn("invalid activation, ignoring it", "name", instance.Name, "err", err)
+		activeFrom, activeUntil, activeWindows = "", "", nil
+	}
+
 	timeouts := proto.Timeouts{}
 	if instance.Spec.Timeouts != nil {
 		timeouts = proto.Timeouts{
@@ -247,7 +473,7 @@ func (r *SyntheticTestReconciler) updateTestConfigInRedis(ctx context.Context, i
 			Finish: instance.Spec.Timeouts.Finish,
 		}
 	}
-	newTestConfig := proto.SynTestConfig{
+	return proto.SynTestConfig{
 		Name:                instance.Name,
 		Version:             "", // we assign this later
 		Labels:              instance.ObjectMeta.Labels,
@@ -264,37 +490,46 @@ func (r *SyntheticTestReconciler) updateTestConfigInRedis(ctx context.Context, i
 		PluginRestartPolicy: instance.Spec.PluginRestartPolicy,
 		LogWaitTime:         instance.Spec.LogWaitTime,
 		Config:              instance.Spec.Config,
+		MetricLabels:        instance.Spec.MetricLabels,
+		Alerting:            alerting,
+		Slo:                 testSlo,
+		ActiveFrom:          activeFrom,
+		ActiveUntil:         activeUntil,
+		ActiveWindows:       activeWindows,
+		Paused:              instance.Paused(),
+		SecretRefs:          instance.Spec.ProtoSecretRefs(),
 	}
+}
 
-	// check if the version in redis is the same as CRD
-	configHash := ComputeHash(fmt.Sprintf("%v", newTestConfig))
-	onLatestVersion := configInRedis.Version == configHash
-
-	// return if the synthetic test is on the latest version and theres no change in the agent its supposed to run on
-	if onLatestVersion && instance.Status.Agent == newAgent {
-		logger.Info("synthetic test is already on latest version and no changes in agent", "version", configHash, "agent", newAgent)
-		return nil
-	}
-
-	// we need to update the version in redis
-
-	// update the version
-	newTestConfig.Version = configHash
+// configVersion Returns the version of the config (a hash of it), the version field must be empty
+func configVersion(config *proto.SynTestConfig) string {
+	return ComputeHash(fmt.Sprintf("%v", *config))
+}
 
-	// marshal the CRD as the "raw config"
-	rawConfig, err := yaml.Marshal(instance.Spec)
+// recordAuditEvent Adds an event (recorded by the controller) to the audit log, errors are only logged
+func recordAuditEvent(ctx context.Context, store storage.SynHeartStore, logger hclog.Logger, event common.AuditEvent) {
+	event.Time = time.Now()
+	event.Source = "controller"
+	err := store.WriteAuditEvent(ctx, event)
 	if err != nil {
-		return errors.Wrap(err, "error marshalling spec yaml")
+		logger.Warn("error writing audit event", "object", event.Object, "action", event.Action, "err", err)
 	}
+}
 
-	// write to redis
-	logger.Info("updating test config in redis", "name", instance.Name, "version", configHash, "newAgent", newAgent)
-	err = store.WriteTestConfig(ctx, newTestConfig, string(rawConfig))
-	if err != nil {
-		return errors.Wrap(err, "error writing test config to redis")
+// lastFieldManager Returns the manager (e.g. kubectl, argocd) that last changed the spec of the syntest
+func lastFieldManager(instance *synheartv1.SyntheticTest) string {
+	manager := ""
+	var lastChange time.Time
+	for _, entry := range instance.ManagedFields {
+		if entry.Subresource != "" || entry.Time == nil {
+			continue
+		}
+		if !entry.Time.Time.Before(lastChange) {
+			manager = entry.Manager
+			lastChange = entry.Time.Time
+		}
 	}
-
-	return nil
+	return manager
 }
 
 func (r *SyntheticTestReconciler) getActiveAgents(ctx context.Context, store storage.SynHeartStore, logger hclog.Logger) (map[string]common.AgentStatus, error) {
@@ -311,7 +546,7 @@ func (r *SyntheticTestReconciler) getValidAgentsForTest(activeAgents map[string]
 
 	for agentId, agentStatus := range activeAgents {
 		// check if the agent is valid for the syntest
-		ok, err := common.IsAgentValidForSynTest(agentStatus.AgentConfig, agentId, instance.Name, instance.Namespace, node, podLabelSelector, instance.Labels, logger)
+		ok, err := common.IsAgentValidForSynTest(agentStatus.AgentConfig, agentId, instance.Name, instance.Namespace, instance.Spec.Plugin, node, podLabelSelector, instance.Labels, logger)
 		if err != nil {
 			return validAgents, errors.Wrap(err, "error checking agent selector")
 		}
@@ -339,7 +574,17 @@ func (r *SyntheticTestReconciler) updateTestStatus(ctx context.Context, instance
 	instance.Status.Deployed = status.Deployed
 	instance.Status.Agent = status.Agent
 	instance.Status.Message = status.Message
-	err = r.Client.Status().Update(ctx, instance)
+	var obj client.Object = instance
+	if instance.Namespace == common.ClusterTestNamespace {
+		clusterTest := &synheartv1.ClusterSyntheticTest{
+			ObjectMeta: *instance.ObjectMeta.DeepCopy(),
+			Spec:       instance.Spec,
+			Status:     instance.Status,
+		}
+		clusterTest.Namespace = ""
+		obj = clusterTest
+	}
+	err = r.Client.Status().Update(ctx, obj)
 	if err != nil {
 		logger.Info("warning: unable to update status in CRD", "err", err)
 	}
@@ -370,10 +615,19 @@ func ConnectToStorage(logger hclog.Logger) (storage.SynHeartStore, error) {
 	if !ok {
 		logger.Error("SYNHEART_STORE_ADDR env var not set")
 	}
+	historyLength := 0
+	if val, ok := os.LookupEnv("CONFIG_HISTORY_LENGTH"); ok {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			logger.Warn("invalid CONFIG_HISTORY_LENGTH env var, using default", "val", val, "default", storage.DefaultConfigHistoryLength)
+		}
+		historyLength = n
+	}
 	store, err := storage.NewSynHeartStore(storage.SynHeartStoreConfig{
-		Type:       "redis",
-		BufferSize: 1000,
-		Address:    addr,
+		Type:                "redis",
+		BufferSize:          1000,
+		Address:             addr,
+		ConfigHistoryLength: historyLength,
 	}, logger.Named("redis"))
 	if err != nil {
 		return store, errors.Wrap(err, "error creating synheart store (redis) client")
@@ -382,6 +636,25 @@ func ConnectToStorage(logger hclog.Logger) (storage.SynHeartStore, error) {
 
 }
 
+// KnownPluginsFunc Returns a function fetching the plugins discovered by the active agents (used by the validating webhook)
+func KnownPluginsFunc(store storage.SynHeartStore, logger hclog.Logger) func(ctx context.Context) (map[string]bool, error) {
+	return func(ctx context.Context) (map[string]bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		activeAgents, err := sync.FetchActiveAgents(ctx, store, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "error fetching active agents")
+		}
+		plugins := map[string]bool{}
+		for _, agentStatus := range activeAgents {
+			for name := range agentStatus.AgentConfig.DiscoveredPlugins {
+				plugins[name] = true
+			}
+		}
+		return plugins, nil
+	}
+}
+
 // Returns an array of reconcile requests for Synthetic Tests
 func (r *SyntheticTestReconciler) ReconcileForExternalEvents(context context.Context, c client.Client) []reconcile.Request {
 	requests := []reconcile.Request{}
@@ -398,10 +671,25 @@ func (r *SyntheticTestReconciler) ReconcileForExternalEvents(context context.Con
 			},
 		})
 	}
+	var clusterTestList synheartv1.ClusterSyntheticTestList
+	err = c.List(context, &clusterTestList)
+	if err != nil {
+		return requests
+	}
+	for _, clusterTest := range clusterTestList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: clusterTest.Name},
+		})
+	}
 	return requests
 }
 
 func (r *SyntheticTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	namespaces, err := namespaceFilterFromEnv()
+	if err != nil {
+		return errors.Wrap(err, "invalid namespace filter")
+	}
+	r.namespaces = namespaces
 
 	// channel to receive redis events, so we can reconcile
 	eventChan := make(chan event.GenericEvent, 100)
@@ -431,6 +719,293 @@ func (r *SyntheticTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}
 	}()
 
+	// compute the health score rollup periodically
+	go func() {
+		log := logger.Named("health-score")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		ticker := time.NewTicker(healthscore.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := healthscore.Compute(context.Background(), log, store, mgr.GetClient())
+			if err != nil {
+				log.Error("error computing health score", "err", err)
+			}
+		}
+	}()
+
+	// periodically compute the slos of the tests and export them as metrics
+	go func() {
+		log := logger.Named("slo")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		ticker := time.NewTicker(slometrics.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := slometrics.Compute(context.Background(), log, store)
+			if err != nil {
+				log.Error("error computing slos", "err", err)
+			}
+		}
+	}()
+
+	// periodically summarise the latest results of the tests in their status
+	go func() {
+		log := logger.Named("summary")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		ticker := time.NewTicker(summary.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := summary.Update(context.Background(), log, store, mgr.GetClient())
+			if err != nil {
+				log.Error("error updating status summaries", "err", err)
+			}
+		}
+	}()
+
+	// periodically evaluate the synAlerts and notify their targets
+	go func() {
+		log := logger.Named("synalert")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		evaluator := synalert.NewEvaluator(log, store, mgr.GetClient(), mgr.GetAPIReader())
+		ticker := time.NewTicker(synalert.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := evaluator.Evaluate(context.Background())
+			if err != nil {
+				log.Error("error evaluating synAlerts", "err", err)
+			}
+		}
+	}()
+
+	// emit kubernetes events when a test starts failing or recovers on an agent
+	go func() {
+		log := logger.Named("events")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		emitter := testevents.NewEmitter(log, store, mgr.GetClient(), mgr.GetEventRecorderFor("synheart-controller"))
+		err = emitter.Init(context.Background())
+		if err != nil {
+			log.Warn("unable to load the state of the tests, events may be emitted for existing failures", "err", err)
+		}
+		err = emitter.Run(context.Background())
+		if err != nil {
+			log.Error("couldn't watch test runs, check redis connection", "err", err)
+			os.Exit(1)
+		}
+	}()
+
+	// emit kubernetes events and metrics when the agent of a running pod stops reporting, so the tests it ran aren't
+	// silently missing on its node
+	go func() {
+		log := logger.Named("agent-health")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		watcher := agenthealth.NewWatcher(log, store, mgr.GetClient(), mgr.GetEventRecorderFor("synheart-controller"))
+		ticker := time.NewTicker(agenthealth.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := watcher.Check(context.Background())
+			if err != nil {
+				log.Error("error checking agent health", "err", err)
+			}
+		}
+	}()
+
+	// keep a SynHeartAgent object for every agent, so they can be listed with kubectl
+	go func() {
+		log := logger.Named("agent-registry")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		registry := agentregistry.NewRegistry(log, store, mgr.GetClient(), mgr.GetScheme())
+		ticker := time.NewTicker(agentregistry.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := registry.Sync(context.Background())
+			if err != nil {
+				log.Error("error syncing synheart agents", "err", err)
+			}
+		}
+	}()
+
+	// periodically publish the quotas and the watched namespaces, so the rest api enforces them on the tests written
+	// through it
+	go func() {
+		log := logger.Named("namespace-policies")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		ticker := time.NewTicker(NamespacePoliciesInterval)
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := r.publishNamespacePolicies(context.Background(), store)
+			if err != nil {
+				log.Error("error publishing namespace policies", "err", err)
+			}
+		}
+	}()
+
+	// periodically delete the data left in storage by tests and agents which don't exist anymore
+	go func() {
+		log := logger.Named("gc")
+		store, err := ConnectToStorage(log)
+		if err != nil {
+			log.Error("couldn't connect to storage", "err", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		collector := gc.NewCollector(store, mgr.GetClient(), log)
+		ticker := time.NewTicker(gc.Interval(log))
+		defer ticker.Stop()
+		for {
+			<-ticker.C
+			err := collector.Run(context.Background())
+			if err != nil {
+				log.Error("error collecting orphaned data", "err", err)
+			}
+		}
+	}()
+
+	// replicate the summarised results of the tests to the central storage of the federation, if one is configured
+	if federation.Enabled() {
+		go func() {
+			log := logger.Named("federation")
+			cluster, err := federation.ClusterName()
+			if err != nil {
+				log.Error("invalid federation config", "err", err)
+				os.Exit(1)
+			}
+			store, err := ConnectToStorage(log)
+			if err != nil {
+				log.Error("couldn't connect to storage", "err", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+			central, err := federation.ConnectToCentralStorage(log)
+			if err != nil {
+				log.Error("couldn't connect to central storage", "err", err)
+				os.Exit(1)
+			}
+			defer central.Close()
+			ticker := time.NewTicker(federation.Interval(log))
+			defer ticker.Stop()
+			for {
+				<-ticker.C
+				err := federation.Replicate(context.Background(), log, cluster, store, central)
+				if err != nil {
+					log.Error("error replicating results to the central storage", "err", err)
+				}
+			}
+		}()
+	}
+
+	// sync the tests defined in a git repo (instead of CRDs), if one is configured
+	if gitops.Enabled() {
+		go func() {
+			log := logger.Named("gitops")
+			store, err := ConnectToStorage(log)
+			if err != nil {
+				log.Error("couldn't connect to storage", "err", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+			repo, err := gitops.NewRepoFromEnv(log)
+			if err != nil {
+				log.Error("invalid git repo config", "err", err)
+				os.Exit(1)
+			}
+			ticker := time.NewTicker(gitops.Interval(log))
+			defer ticker.Stop()
+			for {
+				commit, synTests, err := repo.Fetch(context.Background())
+				if err != nil {
+					log.Error("error fetching tests from git, keeping the current ones", "commit", commit, "err", err)
+				} else {
+					err = syncExternalTests(context.Background(), store, mgr.GetAPIReader(), r.namespaces, log, common.ConfigSourceGit, commit, synTests)
+					if err != nil {
+						log.Error("error syncing tests from git", "commit", commit, "err", err)
+					}
+				}
+				<-ticker.C
+			}
+		}()
+	}
+
+	// sync the tests from a bundle served over http(s), if a url is configured
+	if httpsource.Enabled() {
+		go func() {
+			log := logger.Named("http-source")
+			store, err := ConnectToStorage(log)
+			if err != nil {
+				log.Error("couldn't connect to storage", "err", err)
+				os.Exit(1)
+			}
+			defer store.Close()
+			source, err := httpsource.NewSourceFromEnv(log)
+			if err != nil {
+				log.Error("invalid http source config", "err", err)
+				os.Exit(1)
+			}
+			ticker := time.NewTicker(httpsource.Interval(log))
+			defer ticker.Stop()
+			for {
+				revision, synTests, err := source.Fetch(context.Background())
+				if errors.Is(err, httpsource.ErrNotModified) {
+					log.Debug("bundle not modified", "revision", revision)
+				} else if err != nil {
+					log.Error("error fetching tests from url, keeping the current ones", "revision", revision, "err", err)
+				} else {
+					err = syncExternalTests(context.Background(), store, mgr.GetAPIReader(), r.namespaces, log, common.ConfigSourceHTTP, revision, synTests)
+					if err != nil {
+						log.Error("error syncing tests from url", "revision", revision, "err", err)
+						source.Invalidate()
+					}
+				}
+				<-ticker.C
+			}
+		}()
+	}
+
 	// subscribe to redis channel for agent registration and un-registration events
 	go func() {
 		log := logger.Named("agent-watch")
@@ -458,8 +1033,29 @@ func (r *SyntheticTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}
 	}()
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&synheartv1.SyntheticTest{}).
+	// edits of the tests are coalesced and rate limited, so bulk changes don't cause a storm of config pushes
+	reconcileOpts := reconcileOptionsFromEnv(logger.Named("reconcile"))
+	builder := ctrl.NewControllerManagedBy(mgr).
+		Named("synthetictest").
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: reconcileOpts.maxConcurrent,
+			RateLimiter:             reconcileOpts.rateLimiter(),
+		}).
+		Watches(&synheartv1.SyntheticTest{}, reconcileOpts.enqueueCoalesced()).
+		Watches(&synheartv1.ClusterSyntheticTest{}, reconcileOpts.enqueueCoalesced()).
+		Watches(&synheartv1.SynTestQuota{}, handler.EnqueueRequestsFromMapFunc(r.testsOfQuota)).
+		Watches(&synheartv1.TargetInventory{}, handler.EnqueueRequestsFromMapFunc(r.testsOfInventory))
+	if r.namespaces != nil && r.namespaces.usesLabels() {
+		// deploy or remove the tests of a namespace when its labels change
+		builder = builder.Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.testsOfNamespace),
+			ctrlbuilder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	if PrometheusRulesEnabled() {
+		// re-create the rules if they're changed or deleted
+		builder = builder.Watches(newPrometheusRule(), handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(),
+			&synheartv1.SyntheticTest{}, handler.OnlyControllerOwner()))
+	}
+	return builder.
 		WatchesRawSource(&source.Channel{
 			Source:         eventChan,
 			DestBufferSize: 5,
@@ -469,3 +1065,47 @@ func (r *SyntheticTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			}),
 		).Complete(r)
 }
+
+// deleteSynTestData Deletes the config of the syntest and all the test runs and plugin state of it (on all agents) from storage
+func deleteSynTestData(ctx context.Context, store storage.SynHeartStore, logger hclog.Logger, configId string) error {
+	logger.Info("deleting syntest", "configId", configId)
+	err := store.DeleteTestConfig(ctx, configId)
+	if err != nil {
+		return err
+	}
+
+	// plugins may have a status without a test run yet (and vice versa), so check both
+	pluginIds := map[string]bool{}
+	testRunStatus, err := store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test run status")
+	}
+	pluginStatus, err := store.FetchAllPluginStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching plugin status")
+	}
+	for pluginId := range testRunStatus {
+		pluginIds[pluginId] = true
+	}
+	for pluginId := range pluginStatus {
+		pluginIds[pluginId] = true
+	}
+	for pluginId := range pluginIds {
+		testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || common.ComputeSynTestConfigId(testName, testNs) != configId {
+			continue
+		}
+		logger.Info("deleting test run data", "pluginId", pluginId)
+		err = store.DeleteAllTestRunInfo(ctx, pluginId)
+		if err != nil {
+			return errors.Wrap(err, "error deleting test run data of "+pluginId)
+		}
+	}
+
+	recordAuditEvent(ctx, store, logger, common.AuditEvent{
+		Kind:   common.AuditKindSynTest,
+		Object: configId,
+		Action: common.AuditActionDeleted,
+	})
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	synheartv1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SyntheticTestSuiteReconciler reconciles a SyntheticTestSuite object, by expanding it into SyntheticTests
+type SyntheticTestSuiteReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synthetictestsuites,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synthetictestsuites/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=synheart.infra.webex.com,resources=synthetictestsuites/finalizers,verbs=update
+
+func (r *SyntheticTestSuiteReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  fmt.Sprintf("reconcile-suite [%s/%s]", request.Name, request.Namespace),
+		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+	})
+
+	suite := &synheartv1.SyntheticTestSuite{}
+	err := r.Client.Get(ctx, request.NamespacedName, suite)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// the tests of the suite are garbage collected, as the suite is their owner
+			logger.Info("suite not found! likely deleted, skipping reconciliation")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if !suite.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	synTests, err := suite.Expand()
+	if err != nil {
+		// nothing to retry until the suite is fixed
+		logger.Error("invalid suite", "err", err)
+		r.updateSuiteStatus(ctx, suite, "error: "+err.Error(), logger)
+		return reconcile.Result{}, nil
+	}
+
+	wanted := map[string]bool{}
+	for i := range synTests {
+		desired := &synTests[i]
+		wanted[desired.Name] = true
+		synTest := &synheartv1.SyntheticTest{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+		op, err := controllerutil.CreateOrUpdate(ctx, r.Client, synTest, func() error {
+			// don't take over a test which was created by someone else
+			if !synTest.CreationTimestamp.IsZero() && !metav1.IsControlledBy(synTest, suite) {
+				return errors.New("syntest " + synTest.Name + " already exists and isn't part of the suite")
+			}
+			if synTest.Labels == nil {
+				synTest.Labels = map[string]string{}
+			}
+			for k, v := range desired.Labels {
+				synTest.Labels[k] = v
+			}
+			synTest.Spec = desired.Spec
+			return controllerutil.SetControllerReference(suite, synTest, r.Scheme)
+		})
+		if err != nil {
+			logger.Error("error creating or updating syntest", "name", desired.Name, "err", err)
+			r.updateSuiteStatus(ctx, suite, "error: "+err.Error(), logger)
+			return reconcile.Result{}, err
+		}
+		if op != controllerutil.OperationResultNone {
+			logger.Info("syntest of suite "+string(op), "name", desired.Name)
+		}
+	}
+
+	// delete the tests which were removed from the suite
+	var owned synheartv1.SyntheticTestList
+	err = r.Client.List(ctx, &owned, client.InNamespace(suite.Namespace), client.MatchingLabels{synheartv1.SuiteLabel: suite.Name})
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "error listing syntests of suite")
+	}
+	for i := range owned.Items {
+		synTest := &owned.Items[i]
+		if wanted[synTest.Name] || !metav1.IsControlledBy(synTest, suite) {
+			continue
+		}
+		logger.Info("deleting syntest removed from suite", "name", synTest.Name)
+		err = r.Client.Delete(ctx, synTest)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, errors.Wrap(err, "error deleting syntest "+synTest.Name)
+		}
+	}
+
+	r.updateSuiteStatus(ctx, suite, "", logger)
+	return reconcile.Result{}, nil
+}
+
+// updateSuiteStatus Updates the status of the suite with its tests, and how many of them are failing
+func (r *SyntheticTestSuiteReconciler) updateSuiteStatus(ctx context.Context, suite *synheartv1.SyntheticTestSuite, message string, logger hclog.Logger) {
+	status := synheartv1.SyntheticTestSuiteStatus{Message: message}
+	for _, test := range suite.Spec.Tests {
+		name := suite.TestName(test)
+		synTest := synheartv1.SyntheticTest{}
+		err := r.Client.Get(ctx, client.ObjectKey{Name: name, Namespace: suite.Namespace}, &synTest)
+		if err != nil || !metav1.IsControlledBy(&synTest, suite) {
+			continue
+		}
+		status.Tests = append(status.Tests, name)
+		if synTest.Status.FailingAgents > 0 {
+			status.FailingTests++
+		}
+	}
+	if equality.Semantic.DeepEqual(suite.Status, status) {
+		return
+	}
+	suite.Status = status
+	err := r.Client.Status().Update(ctx, suite)
+	if err != nil {
+		logger.Info("warning: unable to update status of suite", "err", err)
+	}
+}
+
+func (r *SyntheticTestSuiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&synheartv1.SyntheticTestSuite{}).
+		Owns(&synheartv1.SyntheticTest{}).
+		Complete(r)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package slometrics
+
+// package containing code to export the availability SLOs of the tests (error budget, burn rates) as metrics
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/slo"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const DefaultInterval = 5 * time.Minute
+
+var (
+	testLabels = []string{"test_name", "test_namespace"}
+	target     = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricSLOTarget,
+		Help: "Availability target (0-1) of the test's SLO",
+	}, testLabels)
+	availability = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricSLOAvailability,
+		Help: "Availability (0-1) of the test in the SLO window: average pass ratio of the test runs on all agents",
+	}, testLabels)
+	budgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricSLOBudgetRemaining,
+		Help: "Ratio of the error budget left in the SLO window (negative once exhausted)",
+	}, testLabels)
+	burnRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: common.MetricSLOBurnRate,
+		Help: "Rate at which the error budget is consumed over the window (1 exhausts it at the end of the SLO window)",
+	}, append(testLabels, "window"))
+)
+
+func init() {
+	metrics.Registry.MustRegister(target, availability, budgetRemaining, burnRate)
+}
+
+// Interval Returns how often the slos should be computed (SLO_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("SLO_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse SLO_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Compute Computes the slos of all tests with an slo from the test run history, and exports them as metrics
+func Compute(ctx context.Context, logger hclog.Logger, store storage.SynHeartStore) error {
+	reports, err := slo.FetchAllReports(ctx, store, logger, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// reset so tests with no slo anymore are removed
+	target.Reset()
+	availability.Reset()
+	budgetRemaining.Reset()
+	burnRate.Reset()
+	for _, report := range reports {
+		target.WithLabelValues(report.TestName, report.TestNamespace).Set(report.Target)
+		if report.TestRuns == 0 {
+			continue
+		}
+		availability.WithLabelValues(report.TestName, report.TestNamespace).Set(report.Availability)
+		budgetRemaining.WithLabelValues(report.TestName, report.TestNamespace).Set(report.ErrorBudgetRemaining)
+		for window, rate := range report.BurnRates {
+			burnRate.WithLabelValues(report.TestName, report.TestNamespace, window).Set(rate)
+		}
+	}
+	logger.Debug("computed slos", "tests", len(reports))
+	return nil
+}
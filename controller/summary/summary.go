@@ -0,0 +1,223 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package summary
+
+// package containing code to summarise the latest results of the tests (from storage) in the status of the SyntheticTests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const DefaultInterval = 1 * time.Minute
+
+// MaxFailureMessageLength is the max length of the failure message in the status
+const MaxFailureMessageLength = 256
+
+// Result is the summary of the latest results of a test on all agents
+type Result struct {
+	PassingAgents      int32
+	FailingAgents      int32
+	LastRunTime        time.Time
+	LastFailureTime    time.Time
+	LastFailureMessage string
+}
+
+// Interval Returns how often the status summary should be updated (STATUS_SUMMARY_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("STATUS_SUMMARY_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse STATUS_SUMMARY_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// Update Summarises the latest results of every test (on every agent) and writes them in the status of the SyntheticTests
+func Update(ctx context.Context, logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client) error {
+	var synTestList v1.SyntheticTestList
+	err := k8sClient.List(ctx, &synTestList)
+	if err != nil {
+		return errors.Wrap(err, "error listing synTests")
+	}
+
+	results, err := Results(ctx, logger, store)
+	if err != nil {
+		return err
+	}
+
+	updated := 0
+	for i := range synTestList.Items {
+		synTest := &synTestList.Items[i]
+		res, ok := results[common.ComputeSynTestConfigId(synTest.Name, synTest.Namespace)]
+		if !ok {
+			res = &Result{}
+		}
+		patch := client.MergeFrom(synTest.DeepCopy())
+		if !apply(&synTest.Status, res) {
+			continue
+		}
+		err := k8sClient.Status().Patch(ctx, synTest, patch)
+		if err != nil {
+			logger.Warn("unable to update status summary", "name", synTest.Name, "namespace", synTest.Namespace, "err", err)
+			continue
+		}
+		updated++
+	}
+
+	var clusterTestList v1.ClusterSyntheticTestList
+	err = k8sClient.List(ctx, &clusterTestList)
+	if err != nil {
+		return errors.Wrap(err, "error listing clusterSynTests")
+	}
+	for i := range clusterTestList.Items {
+		clusterTest := &clusterTestList.Items[i]
+		res, ok := results[common.ComputeSynTestConfigId(clusterTest.Name, common.ClusterTestNamespace)]
+		if !ok {
+			res = &Result{}
+		}
+		patch := client.MergeFrom(clusterTest.DeepCopy())
+		if !apply(&clusterTest.Status, res) {
+			continue
+		}
+		err := k8sClient.Status().Patch(ctx, clusterTest, patch)
+		if err != nil {
+			logger.Warn("unable to update status summary", "name", clusterTest.Name, "err", err)
+			continue
+		}
+		updated++
+	}
+	logger.Debug("updated status summaries", "tests", len(synTestList.Items)+len(clusterTestList.Items), "updated", updated)
+	return nil
+}
+
+// Results Returns the summary of the latest results of every test (on every agent), by config id
+func Results(ctx context.Context, logger hclog.Logger, store storage.SynHeartStore) (map[string]*Result, error) {
+	allStatus, err := store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching test run status from redis")
+	}
+	results := map[string]*Result{}
+	for pluginId, status := range allStatus {
+		testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+		if err != nil {
+			logger.Warn("unable to parse pluginId, skipping", "pluginId", pluginId, "err", err)
+			continue
+		}
+		configId := common.ComputeSynTestConfigId(testName, testNs)
+		if _, ok := results[configId]; !ok {
+			results[configId] = &Result{}
+		}
+		addResult(ctx, logger, store, results[configId], pluginId, status)
+	}
+	return results, nil
+}
+
+// addResult Adds the latest result of a test on an agent (pluginId) to the summary of the test
+func addResult(ctx context.Context, logger hclog.Logger, store storage.SynHeartStore, res *Result, pluginId string, status string) {
+	passRatio, err := strconv.ParseFloat(status, 64)
+	if err != nil {
+		logger.Warn("unable to parse test run status, skipping", "pluginId", pluginId, "status", status, "err", err)
+		return
+	}
+	if passRatio >= 1 {
+		res.PassingAgents++
+	} else {
+		res.FailingAgents++
+	}
+
+	latest, err := store.FetchLatestTestRun(ctx, pluginId)
+	if err == nil {
+		if t, err := time.Parse(common.TimeFormat, latest.EndTime); err == nil && t.After(res.LastRunTime) {
+			res.LastRunTime = t
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		logger.Warn("unable to fetch latest test run", "pluginId", pluginId, "err", err)
+	}
+
+	failed, err := store.FetchLastFailedTestRun(ctx, pluginId)
+	if err == nil {
+		if t, err := time.Parse(common.TimeFormat, failed.EndTime); err == nil && t.After(res.LastFailureTime) {
+			res.LastFailureTime = t
+			res.LastFailureMessage = failureMessage(&failed)
+		}
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		logger.Warn("unable to fetch last failed test run", "pluginId", pluginId, "err", err)
+	}
+}
+
+// failureMessage Returns the error of the failed test run, or its marks if there's no error
+func failureMessage(testRun *proto.TestRun) string {
+	msg := ""
+	if testRun.TestResult != nil {
+		msg = testRun.TestResult.Details[common.ErrorKey]
+		if msg == "" {
+			msg = fmt.Sprintf("marks: %d/%d", testRun.TestResult.Marks, testRun.TestResult.MaxMarks)
+		}
+	}
+	if testRun.AgentId != "" {
+		msg = testRun.AgentId + ": " + msg
+	}
+	if len(msg) > MaxFailureMessageLength {
+		msg = msg[:MaxFailureMessageLength-3] + "..."
+	}
+	return msg
+}
+
+// apply Sets the summary in the status, returns whether the status changed
+func apply(status *v1.SyntheticTestStatus, res *Result) bool {
+	changed := status.PassingAgents != res.PassingAgents || status.FailingAgents != res.FailingAgents ||
+		status.LastFailureMessage != res.LastFailureMessage ||
+		!timeEqual(status.LastRunTime, res.LastRunTime) || !timeEqual(status.LastFailureTime, res.LastFailureTime)
+	status.PassingAgents = res.PassingAgents
+	status.FailingAgents = res.FailingAgents
+	status.LastRunTime = metaTime(res.LastRunTime)
+	status.LastFailureTime = metaTime(res.LastFailureTime)
+	status.LastFailureMessage = res.LastFailureMessage
+	return changed
+}
+
+func metaTime(t time.Time) *metav1.Time {
+	if t.IsZero() {
+		return nil
+	}
+	mt := metav1.NewTime(t.Truncate(time.Second)) // metav1.Time is serialised with second precision
+	return &mt
+}
+
+func timeEqual(mt *metav1.Time, t time.Time) bool {
+	if mt == nil {
+		return t.IsZero()
+	}
+	return mt.Time.Equal(t.Truncate(time.Second))
+}
@@ -0,0 +1,394 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package synalert
+
+// package containing code to evaluate the SynAlerts against the latest results of the tests (from storage), and
+// notify their targets when they start (or stop) firing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const DefaultInterval = 30 * time.Second
+
+// DefaultRepeat is used to compute how much test run history is needed for tests whose repeat can't be parsed
+const DefaultRepeat = 1 * time.Hour
+
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Notification is sent to the targets of an alert when it starts (or stops) firing for a test
+type Notification struct {
+	State         string    `json:"state"`
+	Alert         string    `json:"alert"`
+	Namespace     string    `json:"namespace"`
+	Test          string    `json:"test"`
+	DisplayName   string    `json:"displayName"`
+	Importance    string    `json:"importance"`
+	FailingAgents []string  `json:"failingAgents"`
+	TotalAgents   int       `json:"totalAgents"`
+	Since         time.Time `json:"since"`
+}
+
+// Interval Returns how often the alerts should be evaluated (SYNALERT_INTERVAL env var)
+func Interval(logger hclog.Logger) time.Duration {
+	interval, ok := os.LookupEnv("SYNALERT_INTERVAL")
+	if !ok {
+		return DefaultInterval
+	}
+	dur, err := time.ParseDuration(interval)
+	if err != nil || dur <= 0 {
+		logger.Warn("unable to parse SYNALERT_INTERVAL duration, using default: "+interval, "default", DefaultInterval)
+		return DefaultInterval
+	}
+	return dur
+}
+
+// agentResult is the latest result of a test on an agent
+type agentResult struct {
+	pluginId  string
+	passRatio float64
+}
+
+// Evaluator evaluates the SynAlerts, and keeps their firing tests in their status
+type Evaluator struct {
+	logger       hclog.Logger
+	store        storage.SynHeartStore
+	k8sClient    client.Client
+	secretReader client.Reader // reads the secrets of the targets (uncached, so the controller doesn't watch all secrets)
+	senders      senders
+}
+
+func NewEvaluator(logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client, secretReader client.Reader) *Evaluator {
+	return &Evaluator{
+		logger:       logger,
+		store:        store,
+		k8sClient:    k8sClient,
+		secretReader: secretReader,
+		senders:      newSenders(),
+	}
+}
+
+// Evaluate Evaluates all the SynAlerts against the latest results of the tests, and notifies their targets
+func (e *Evaluator) Evaluate(ctx context.Context) error {
+	var alertList v1.SynAlertList
+	err := e.k8sClient.List(ctx, &alertList)
+	if err != nil {
+		return errors.Wrap(err, "error listing synAlerts")
+	}
+	if len(alertList.Items) == 0 {
+		return nil
+	}
+
+	var synTestList v1.SyntheticTestList
+	err = e.k8sClient.List(ctx, &synTestList)
+	if err != nil {
+		return errors.Wrap(err, "error listing synTests")
+	}
+	synTests := map[string][]*v1.SyntheticTest{} // by namespace
+	for i := range synTestList.Items {
+		synTest := &synTestList.Items[i]
+		synTests[synTest.Namespace] = append(synTests[synTest.Namespace], synTest)
+	}
+
+	allStatus, err := e.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test run status from redis")
+	}
+	results := map[string]map[string]agentResult{} // by test config id, then agent id
+	for pluginId, status := range allStatus {
+		testName, testNs, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil {
+			e.logger.Warn("unable to parse pluginId, skipping", "pluginId", pluginId, "err", err)
+			continue
+		}
+		passRatio, err := strconv.ParseFloat(status, 64)
+		if err != nil {
+			e.logger.Warn("unable to parse test run status, skipping", "pluginId", pluginId, "status", status, "err", err)
+			continue
+		}
+		configId := common.ComputeSynTestConfigId(testName, testNs)
+		if _, ok := results[configId]; !ok {
+			results[configId] = map[string]agentResult{}
+		}
+		results[configId][common.ComputeAgentId(podName, podNs)] = agentResult{pluginId: pluginId, passRatio: passRatio}
+	}
+
+	silences, err := e.store.FetchAllSilences(ctx)
+	if err != nil {
+		e.logger.Warn("unable to fetch silences, evaluating without them", "err", err)
+	}
+	activeSilences := []common.Silence{}
+	now := time.Now()
+	for _, silence := range silences {
+		if silence.IsActive(now) {
+			activeSilences = append(activeSilences, silence)
+		}
+	}
+
+	for i := range alertList.Items {
+		alert := &alertList.Items[i]
+		patch := client.MergeFrom(alert.DeepCopy())
+		status := e.evaluateAlert(ctx, alert, synTests[alert.Namespace], results, activeSilences)
+		if equality.Semantic.DeepEqual(alert.Status, status) {
+			continue
+		}
+		alert.Status = status
+		err := e.k8sClient.Status().Patch(ctx, alert, patch)
+		if err != nil {
+			e.logger.Warn("unable to update status of synAlert", "name", alert.Name, "namespace", alert.Namespace, "err", err)
+		}
+	}
+	return nil
+}
+
+// evaluateAlert Evaluates the alert against the tests it selects, notifies its targets of the tests it started (or
+// stopped) firing for, and returns its new status
+func (e *Evaluator) evaluateAlert(ctx context.Context, alert *v1.SynAlert, synTests []*v1.SyntheticTest,
+	results map[string]map[string]agentResult, silences []common.Silence) v1.SynAlertStatus {
+	logger := e.logger.With("alert", alert.Namespace+"/"+alert.Name)
+	status := v1.SynAlertStatus{Firing: []v1.FiringTest{}}
+	err := Validate(alert)
+	if err != nil {
+		// keep the firing tests, so they aren't notified again once the alert is fixed
+		status.Firing = alert.Status.Firing
+		status.FiringTests = alert.Status.FiringTests
+		status.Message = "error: " + err.Error()
+		return status
+	}
+	selector := labels.Everything()
+	if alert.Spec.Selector != nil {
+		selector, _ = metav1.LabelSelectorAsSelector(alert.Spec.Selector) // checked by Validate
+	}
+
+	wasFiring := map[string]v1.FiringTest{}
+	for _, firing := range alert.Status.Firing {
+		wasFiring[firing.Name] = firing
+	}
+	errs := []string{}
+	notify := func(n Notification) {
+		for _, target := range alert.Spec.Targets {
+			// pagerduty incidents are always resolved, other targets only if they want it
+			if n.State == StateResolved && !alert.Spec.SendResolved && target.PagerDuty == nil {
+				continue
+			}
+			err := e.senders.send(ctx, e.secretReader, alert.Namespace, target, n)
+			if err != nil {
+				logger.Error("error notifying target", "target", target.Name, "test", n.Test, "err", err)
+				errs = append(errs, fmt.Sprintf("error notifying %s: %v", target.Name, err))
+			}
+		}
+	}
+
+	for _, synTest := range synTests {
+		if !selector.Matches(labels.Set(synTest.Labels)) || (synTest.Spec.Alerting != nil && synTest.Spec.Alerting.Disabled) {
+			continue
+		}
+		if len(alert.Spec.Importances) > 0 && !slices.Contains(alert.Spec.Importances, common.ImportanceLevel(synTest.Spec.Importance)) {
+			continue
+		}
+		configId := common.ComputeSynTestConfigId(synTest.Name, synTest.Namespace)
+		failingAgents, totalAgents := e.failingAgents(ctx, alert, synTest, results[configId], silences)
+		if !isFiring(alert.Spec.Condition, len(failingAgents), totalAgents) {
+			continue
+		}
+		firing, ok := wasFiring[synTest.Name]
+		delete(wasFiring, synTest.Name)
+		if !ok {
+			firing = v1.FiringTest{Name: synTest.Name, Since: metav1.Now()}
+			logger.Info("alert firing", "test", synTest.Name, "failingAgents", failingAgents)
+			notify(newNotification(StateFiring, alert, synTest.Name, synTest, failingAgents, totalAgents, firing.Since.Time))
+		}
+		firing.FailingAgents = failingAgents
+		status.Firing = append(status.Firing, firing)
+	}
+
+	// the tests which aren't firing anymore (or were deleted, or aren't selected anymore) are resolved
+	resolved := []string{}
+	for name := range wasFiring {
+		resolved = append(resolved, name)
+	}
+	sort.Strings(resolved)
+	for _, name := range resolved {
+		logger.Info("alert resolved", "test", name)
+		var synTest *v1.SyntheticTest
+		for _, t := range synTests {
+			if t.Name == name {
+				synTest = t
+			}
+		}
+		notify(newNotification(StateResolved, alert, name, synTest, []string{}, len(results[common.ComputeSynTestConfigId(name, alert.Namespace)]),
+			wasFiring[name].Since.Time))
+	}
+
+	sort.Slice(status.Firing, func(i, j int) bool { return status.Firing[i].Name < status.Firing[j].Name })
+	status.FiringTests = int32(len(status.Firing))
+	if len(status.Firing) == 0 {
+		status.Firing = nil
+	}
+	status.Message = strings.Join(errs, "; ")
+	return status
+}
+
+// failingAgents Returns the (sorted) agents on which the test is failing according to the condition of the alert,
+// ignoring silenced agents, and the total number of agents running the test
+func (e *Evaluator) failingAgents(ctx context.Context, alert *v1.SynAlert, synTest *v1.SyntheticTest,
+	agentResults map[string]agentResult, silences []common.Silence) ([]string, int) {
+	testConfig := &proto.SynTestConfig{Name: synTest.Name, Namespace: synTest.Namespace, Labels: synTest.Labels}
+	consecutiveFailures := int(alert.Spec.Condition.ConsecutiveFailures)
+	failing := []string{}
+	for agentId, res := range agentResults {
+		if res.passRatio >= 1 || silenced(silences, testConfig, agentId) {
+			continue
+		}
+		if consecutiveFailures > 1 {
+			failures, err := e.consecutiveFailures(ctx, res.pluginId, synTest.Spec.Repeat, consecutiveFailures)
+			if err != nil {
+				e.logger.Warn("unable to fetch test run history, skipping agent", "pluginId", res.pluginId, "err", err)
+				continue
+			}
+			if failures < consecutiveFailures {
+				continue
+			}
+		}
+		failing = append(failing, agentId)
+	}
+	sort.Strings(failing)
+	return failing, len(agentResults)
+}
+
+// consecutiveFailures Returns how many of the latest runs of the test on the agent (pluginId) failed in a row, only
+// looking at enough history to count up to max
+func (e *Evaluator) consecutiveFailures(ctx context.Context, pluginId string, repeat string, max int) (int, error) {
+	interval, err := time.ParseDuration(repeat)
+	if err != nil || interval <= 0 {
+		interval = DefaultRepeat
+	}
+	// runs may be delayed (e.g. by retries), so look at twice the history needed
+	history, err := e.store.FetchTestRunHistory(ctx, pluginId, time.Now().Add(-2*time.Duration(max)*interval))
+	if err != nil {
+		return 0, err
+	}
+	failures := 0
+	for i := len(history) - 1; i >= 0 && history[i].PassRatio < 1; i-- {
+		failures++
+	}
+	return failures, nil
+}
+
+// silenced Returns whether the test running on the agent matches any of the (active) silences
+func silenced(silences []common.Silence, testConfig *proto.SynTestConfig, agentId string) bool {
+	for _, silence := range silences {
+		if silence.Matches(testConfig, agentId) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFiring Returns whether the alert fires for a test failing on the given number of agents
+func isFiring(condition v1.SynAlertCondition, failingAgents int, totalAgents int) bool {
+	if failingAgents == 0 {
+		return false
+	}
+	return failingAgents*100 >= int(condition.FailingAgentsPercent)*totalAgents
+}
+
+// Validate Returns an error if the alert is invalid
+func Validate(alert *v1.SynAlert) error {
+	if alert.Spec.Selector != nil {
+		_, err := metav1.LabelSelectorAsSelector(alert.Spec.Selector)
+		if err != nil {
+			return errors.Wrap(err, "invalid selector")
+		}
+	}
+	for _, importance := range alert.Spec.Importances {
+		if _, ok := common.ImportanceWeights[importance]; !ok {
+			return errors.New("invalid importance '" + importance + "', must be critical, high, medium or low")
+		}
+	}
+	if alert.Spec.Condition.ConsecutiveFailures < 0 {
+		return errors.New("consecutiveFailures must not be negative")
+	}
+	if alert.Spec.Condition.FailingAgentsPercent < 0 || alert.Spec.Condition.FailingAgentsPercent > 100 {
+		return errors.New("failingAgentsPercent must be between 0 and 100")
+	}
+	if len(alert.Spec.Targets) == 0 {
+		return errors.New("no targets")
+	}
+	for _, target := range alert.Spec.Targets {
+		set := 0
+		if target.Slack != nil {
+			set++
+		}
+		if target.PagerDuty != nil {
+			set++
+		}
+		if target.Webhook != nil {
+			set++
+			if target.Webhook.Url == "" && target.Webhook.UrlSecret == nil {
+				return fmt.Errorf("target %s: webhook has no url", target.Name)
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("target %s: exactly one of slack, pagerDuty or webhook must be set", target.Name)
+		}
+	}
+	return nil
+}
+
+func newNotification(state string, alert *v1.SynAlert, testName string, synTest *v1.SyntheticTest,
+	failingAgents []string, totalAgents int, since time.Time) Notification {
+	n := Notification{
+		State:         state,
+		Alert:         alert.Name,
+		Namespace:     alert.Namespace,
+		Test:          testName,
+		DisplayName:   testName,
+		FailingAgents: failingAgents,
+		TotalAgents:   totalAgents,
+		Since:         since,
+	}
+	// the test may have been deleted when it's resolved
+	if synTest != nil {
+		n.Importance = common.ImportanceLevel(synTest.Spec.Importance)
+		if synTest.Spec.DisplayName != "" {
+			n.DisplayName = synTest.Spec.DisplayName
+		}
+	}
+	return n
+}
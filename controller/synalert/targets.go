@@ -0,0 +1,167 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package synalert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const DefaultPagerDutyUrl = "https://events.pagerduty.com/v2/enqueue"
+
+// DefaultPagerDutySeverities maps the importance of a test to the severity of its incidents
+var DefaultPagerDutySeverities = map[string]string{
+	common.ImportanceCritical: "critical",
+	common.ImportanceHigh:     "error",
+	common.ImportanceMedium:   "warning",
+	common.ImportanceLow:      "info",
+}
+
+// senders send the notifications to the targets
+type senders struct {
+	client *http.Client
+}
+
+func newSenders() senders {
+	return senders{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// send Sends the notification to the target, reading its secrets from the namespace of the alert
+func (s senders) send(ctx context.Context, secretReader client.Reader, namespace string, target v1.SynAlertTarget, n Notification) error {
+	switch {
+	case target.Slack != nil:
+		url, err := readSecret(ctx, secretReader, namespace, target.Slack.WebhookUrl)
+		if err != nil {
+			return err
+		}
+		return s.post(ctx, url, nil, map[string]string{"channel": target.Slack.Channel, "text": slackText(n)})
+	case target.PagerDuty != nil:
+		routingKey, err := readSecret(ctx, secretReader, namespace, target.PagerDuty.RoutingKey)
+		if err != nil {
+			return err
+		}
+		url := target.PagerDuty.Url
+		if url == "" {
+			url = DefaultPagerDutyUrl
+		}
+		return s.post(ctx, url, nil, pagerDutyEvent(routingKey, target.PagerDuty.Severity, n))
+	case target.Webhook != nil:
+		url := target.Webhook.Url
+		if target.Webhook.UrlSecret != nil {
+			var err error
+			url, err = readSecret(ctx, secretReader, namespace, *target.Webhook.UrlSecret)
+			if err != nil {
+				return err
+			}
+		}
+		return s.post(ctx, url, target.Webhook.Headers, n)
+	}
+	return errors.New("no slack, pagerDuty or webhook in target")
+}
+
+// post Posts the payload as json to the url
+func (s senders) post(ctx context.Context, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling payload")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("target returned %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
+
+// readSecret Returns the value of the key in the secret
+func readSecret(ctx context.Context, secretReader client.Reader, namespace string, ref corev1.SecretKeySelector) (string, error) {
+	secret := corev1.Secret{}
+	err := secretReader.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &secret)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading secret "+ref.Name)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", errors.New("key " + ref.Key + " not found in secret " + ref.Name)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+func slackText(n Notification) string {
+	if n.State == StateResolved {
+		return fmt.Sprintf(":large_green_circle: *%s* recovered (%s/%s)\n*Alert:* %s", n.DisplayName, n.Namespace, n.Test, n.Alert)
+	}
+	return fmt.Sprintf(":red_circle: *%s* is failing on %d/%d agents (%s/%s)\n*Alert:* %s\n*Importance:* %s\n*Failing agents:* %s",
+		n.DisplayName, len(n.FailingAgents), n.TotalAgents, n.Namespace, n.Test, n.Alert, n.Importance,
+		strings.Join(n.FailingAgents, ", "))
+}
+
+// pagerDutyEvent Returns the events v2 api payload triggering (or resolving) the incident of the alert for the test
+func pagerDutyEvent(routingKey string, severity string, n Notification) map[string]interface{} {
+	event := map[string]interface{}{
+		"routing_key": routingKey,
+		"dedup_key":   fmt.Sprintf("synthetic-heart/synalert/%s/%s/%s", n.Namespace, n.Alert, n.Test),
+	}
+	if n.State == StateResolved {
+		event["event_action"] = "resolve"
+		return event
+	}
+	if severity == "" {
+		severity = DefaultPagerDutySeverities[strings.ToLower(n.Importance)]
+	}
+	if severity == "" {
+		severity = "error"
+	}
+	event["event_action"] = "trigger"
+	event["payload"] = map[string]interface{}{
+		"summary":   fmt.Sprintf("Synthetic test %s is failing on %d/%d agents", n.DisplayName, len(n.FailingAgents), n.TotalAgents),
+		"source":    "synheart-controller",
+		"severity":  severity,
+		"component": n.Test,
+		"group":     n.Namespace,
+		"custom_details": map[string]interface{}{
+			"alert":         n.Alert,
+			"failingAgents": n.FailingAgents,
+			"since":         n.Since.Format(time.RFC3339),
+		},
+	}
+	return event
+}
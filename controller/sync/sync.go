@@ -62,6 +62,11 @@ func All(client client.Client, logger hclog.Logger) {
 		logger.Warn("error cleaning up syntests", "err", err)
 	}
 
+	err = AgentProfiles(ctx, logger, store, client)
+	if err != nil {
+		logger.Warn("error cleaning up agent profiles", "err", err)
+	}
+
 	logger.Info("sync complete")
 
 }
@@ -132,6 +137,14 @@ func SynTests(ctx context.Context, logger hclog.Logger, store storage.SynHeartSt
 	for _, synTest := range synTestList.Items {
 		synTestMap[common.ComputeSynTestConfigId(synTest.Name, synTest.Namespace)] = synTest
 	}
+	var clusterTestList v1.ClusterSyntheticTestList
+	err = k8sClient.List(ctx, &clusterTestList)
+	if err != nil {
+		logger.Error("error listing clusterSynTests", "err", err)
+	}
+	for _, clusterTest := range clusterTestList.Items {
+		synTestMap[common.ComputeSynTestConfigId(clusterTest.Name, common.ClusterTestNamespace)] = *clusterTest.AsSyntheticTest()
+	}
 
 	// Get all syntest configs from redis
 	synTestsInRedis, err := store.FetchAllTestConfigSummary(ctx)
@@ -140,7 +153,10 @@ func SynTests(ctx context.Context, logger hclog.Logger, store storage.SynHeartSt
 	}
 
 	// check if the syntest crd actually exists, otherwise delete the syntest record from redis
-	for synTestConfigId, _ := range synTestsInRedis {
+	for synTestConfigId, summary := range synTestsInRedis {
+		if summary.Source != "" {
+			continue // not from a CRD, its source deletes it
+		}
 		_, ok := synTestMap[synTestConfigId]
 		if !ok {
 			logger.Info("deleting old syntest from redis: " + synTestConfigId)
@@ -197,6 +213,36 @@ func SynTests(ctx context.Context, logger hclog.Logger, store storage.SynHeartSt
 	return nil
 }
 
+// AgentProfiles deletes the agent profiles from redis whose CRD doesn't exist anymore
+func AgentProfiles(ctx context.Context, logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client) error {
+	logger.Info("syncing agent profiles")
+	var profileList v1.AgentProfileList
+	err := k8sClient.List(ctx, &profileList)
+	if err != nil {
+		return errors.Wrap(err, "error listing agent profiles")
+	}
+	profileMap := map[string]bool{}
+	for _, profile := range profileList.Items {
+		profileMap[profile.Name] = true
+	}
+
+	profilesInRedis, err := store.FetchAllAgentProfiles(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching agent profiles from redis")
+	}
+	for name := range profilesInRedis {
+		if profileMap[name] {
+			continue
+		}
+		logger.Info("deleting old agent profile from redis: " + name)
+		err := store.DeleteAgentProfile(ctx, name)
+		if err != nil {
+			logger.Warn("error deleting agent profile, continuing", "profile", name, "err", err)
+		}
+	}
+	return nil
+}
+
 // FetchActiveAgents fetches active agents from redis
 func FetchActiveAgents(ctx context.Context, store storage.SynHeartStore, logger hclog.Logger) (map[string]common.AgentStatus, error) {
 	logger.Info("fetching active agents from redis")
@@ -215,23 +261,26 @@ func FetchActiveAgents(ctx context.Context, store storage.SynHeartStore, logger
 		}
 		statusLastUpdateAge := time.Now().Sub(statusTime)
 
-		// get the agent status deadline from env
-		agentStatusDeadline, ok := os.LookupEnv("AGENT_STATUS_DEADLINE")
-		if !ok {
-			logger.Error("no AGENT_STATUS_DEADLINE in env")
-			os.Exit(1)
-		}
-		dur, err := time.ParseDuration(agentStatusDeadline)
-		if err != nil {
-			logger.Error("unable to parse AGENT_STATUS_DEADLINE duration: "+agentStatusDeadline, "err", err.Error())
-			os.Exit(1)
-		}
-
 		// if the last update was too long ago, delete this agent from active agent map
-		if statusLastUpdateAge.Seconds() >= dur.Seconds() {
+		if statusLastUpdateAge.Seconds() >= AgentStatusDeadline(logger).Seconds() {
 			logger.Info("agent not active, name: " + agentName)
 			delete(agents, agentName)
 		}
 	}
 	return agents, nil
 }
+
+// AgentStatusDeadline Returns how long an agent is considered active after its last status (AGENT_STATUS_DEADLINE env var)
+func AgentStatusDeadline(logger hclog.Logger) time.Duration {
+	agentStatusDeadline, ok := os.LookupEnv("AGENT_STATUS_DEADLINE")
+	if !ok {
+		logger.Error("no AGENT_STATUS_DEADLINE in env")
+		os.Exit(1)
+	}
+	dur, err := time.ParseDuration(agentStatusDeadline)
+	if err != nil {
+		logger.Error("unable to parse AGENT_STATUS_DEADLINE duration: "+agentStatusDeadline, "err", err.Error())
+		os.Exit(1)
+	}
+	return dur
+}
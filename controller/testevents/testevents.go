@@ -0,0 +1,165 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package testevents
+
+// package containing code to emit kubernetes events on the SyntheticTests when a test starts failing or recovers on an agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	v1 "github.com/cisco-open/synthetic-heart/controller/api/v1"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ReasonTestFailing   = "TestFailing"
+	ReasonTestRecovered = "TestRecovered"
+
+	// MaxMessageLength is the max length of the error in the event message
+	MaxMessageLength = 512
+)
+
+// Emitter emits events when the state (passing/failing) of a test on an agent changes
+type Emitter struct {
+	logger          hclog.Logger
+	store           storage.SynHeartStore
+	k8sClient       client.Client
+	recorder        record.EventRecorder
+	namespaceEvents bool            // whether the events are also emitted on the namespace of the test
+	passing         map[string]bool // whether the latest run of the test on the agent passed (by plugin id)
+}
+
+func NewEmitter(logger hclog.Logger, store storage.SynHeartStore, k8sClient client.Client, recorder record.EventRecorder) *Emitter {
+	return &Emitter{
+		logger:          logger,
+		store:           store,
+		k8sClient:       k8sClient,
+		recorder:        recorder,
+		namespaceEvents: os.Getenv("NAMESPACE_EVENTS") == "true",
+		passing:         map[string]bool{},
+	}
+}
+
+// Init Loads the current state of the tests, so no events are emitted for them when the controller (re)starts
+func (e *Emitter) Init(ctx context.Context) error {
+	allStatus, err := e.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test run status from redis")
+	}
+	for pluginId, status := range allStatus {
+		passRatio, err := strconv.ParseFloat(status, 64)
+		if err != nil {
+			continue
+		}
+		e.passing[pluginId] = passRatio >= 1
+	}
+	return nil
+}
+
+// Run Watches for new test runs and emits events when a test starts failing or recovers
+func (e *Emitter) Run(ctx context.Context) error {
+	testRunChan := make(chan string, 100)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- e.store.SubscribeToTestRunEvents(ctx, 1000, testRunChan)
+	}()
+	for {
+		select {
+		case msg := <-testRunChan:
+			e.HandleTestRun(ctx, strings.TrimPrefix(msg, storage.TestRunEventPrefix))
+		case err := <-errChan:
+			return errors.Wrap(err, "error subscribing to test runs")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// HandleTestRun Checks the latest run of the test on the agent (plugin id), and emits an event if its state changed
+func (e *Emitter) HandleTestRun(ctx context.Context, pluginId string) {
+	testRun, err := e.store.FetchLatestTestRun(ctx, pluginId)
+	if err != nil {
+		e.logger.Warn("unable to fetch latest test run", "pluginId", pluginId, "err", err)
+		return
+	}
+	if testRun.TestResult == nil || testRun.TestConfig == nil {
+		return
+	}
+	passing := testRun.TestResult.Marks >= testRun.TestResult.MaxMarks
+	wasPassing, seen := e.passing[pluginId]
+	e.passing[pluginId] = passing
+	if (seen && wasPassing == passing) || (!seen && passing) {
+		return
+	}
+
+	// cluster tests (ClusterSyntheticTest) have no namespace
+	var obj client.Object = &v1.SyntheticTest{}
+	name := testRun.TestConfig.Namespace + "/" + testRun.TestConfig.Name
+	key := types.NamespacedName{Name: testRun.TestConfig.Name, Namespace: testRun.TestConfig.Namespace}
+	if testRun.TestConfig.Namespace == common.ClusterTestNamespace {
+		obj = &v1.ClusterSyntheticTest{}
+		name = testRun.TestConfig.Name
+		key.Namespace = ""
+	}
+	err = e.k8sClient.Get(ctx, key, obj)
+	if err != nil {
+		e.logger.Warn("unable to fetch synthetic test, not emitting event", "pluginId", pluginId, "err", err)
+		return
+	}
+	objects := []client.Object{obj}
+	if e.namespaceEvents && key.Namespace != "" {
+		objects = append(objects, &corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: key.Namespace},
+		})
+	}
+	for _, o := range objects {
+		if passing {
+			e.recorder.Eventf(o, corev1.EventTypeNormal, ReasonTestRecovered,
+				"test %s recovered on agent %s", name, testRun.AgentId)
+		} else {
+			e.recorder.Eventf(o, corev1.EventTypeWarning, ReasonTestFailing,
+				"test %s failing on agent %s: %s", name, testRun.AgentId, failureMessage(&testRun))
+		}
+	}
+	e.logger.Debug("emitted event", "pluginId", pluginId, "passing", passing)
+}
+
+// failureMessage Returns the error of the failed test run, or its marks if there's no error
+func failureMessage(testRun *proto.TestRun) string {
+	msg := testRun.TestResult.Details[common.ErrorKey]
+	if msg == "" {
+		msg = fmt.Sprintf("marks: %d/%d", testRun.TestResult.Marks, testRun.TestResult.MaxMarks)
+	}
+	if len(msg) > MaxMessageLength {
+		msg = msg[:MaxMessageLength-3] + "..."
+	}
+	return msg
+}
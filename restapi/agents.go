@@ -0,0 +1,255 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	gmux "github.com/gorilla/mux"
+)
+
+// DefaultAgentStatusDeadline is how long after its last status an agent is considered stale, if not configured (the
+// default of the controller)
+const DefaultAgentStatusDeadline = 60 * time.Second
+
+// GetAgentsHealth Returns the health of the agents sorted by id, filtered by the state and namespace query params
+func (r *RestApi) GetAgentsHealth(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	state, namespace := req.URL.Query().Get("state"), req.URL.Query().Get("namespace")
+	if state != "" && state != client.AgentLive && state != client.AgentStale && state != client.AgentPending {
+		http.Error(w, "invalid state, must be live, stale or pending", http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching agent statuses", "err", err)
+		http.Error(w, "unable to fetch agents", http.StatusInternalServerError)
+		return
+	}
+	healths := []client.AgentHealth{}
+	for agentId, agent := range agents {
+		health := r.agentHealth(req, agentId, agent)
+		if (state == "" || health.State == state) && (namespace == "" || health.Namespace == namespace) {
+			healths = append(healths, health)
+		}
+	}
+	slices.SortFunc(healths, func(a, b client.AgentHealth) int { return strings.Compare(a.Id, b.Id) })
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(healths)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// GetAgent Returns the health of an agent and the tests it's running, with the state of their plugins and the status
+// of their latest run
+func (r *RestApi) GetAgent(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	agentId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no agent id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching agent statuses", "err", err)
+		http.Error(w, "unable to fetch agent", http.StatusInternalServerError)
+		return
+	}
+	agent, ok := agents[agentId]
+	if !ok {
+		http.Error(w, "no agent found", http.StatusNotFound)
+		return
+	}
+	allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to fetch agent", http.StatusInternalServerError)
+		return
+	}
+	pluginStates, err := r.store.FetchAllPluginStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching plugin status", "err", err)
+		http.Error(w, "unable to fetch agent", http.StatusInternalServerError)
+		return
+	}
+
+	details := client.AgentDetails{AgentHealth: r.agentHealth(req, agentId, agent), Tests: []client.AgentTest{}}
+	for _, configId := range agent.SynTests {
+		if !canAccessTest(req, configId) {
+			continue
+		}
+		pluginId := configId + "/" + agentId
+		test := client.AgentTest{ConfigId: configId, PluginId: pluginId, PluginState: pluginStates[pluginId],
+			Status: client.HealthUnknown}
+		if passRatio, ok := allStatus[pluginId]; ok {
+			test.Status = pluginStatus(passRatio)
+			if ratio, err := strconv.ParseFloat(passRatio, 64); err == nil {
+				test.PassRatio = &ratio
+			}
+		}
+		details.Tests = append(details.Tests, test)
+	}
+	slices.SortFunc(details.Tests, func(a, b client.AgentTest) int { return strings.Compare(a.ConfigId, b.ConfigId) })
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(details)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// DeregisterAgent Deletes a stale agent from storage with the data of its plugins (test runs, logs and health), e.g.
+// after its node was removed, instead of waiting for the garbage collection of the controller. Live agents can't be
+// deregistered (they would register again with their next status), and only authenticated users who can access all the
+// namespaces can deregister agents, as they're shared by the tests of all the namespaces.
+func (r *RestApi) DeregisterAgent(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	agentId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no agent id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	identity, authenticated := IdentityFromContext(req.Context())
+	if !authenticated {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "deregistering agents needs authentication", http.StatusUnauthorized)
+		return
+	}
+	if identity.Restricted() {
+		http.Error(w, "deregistering agents needs access to all namespaces", http.StatusForbidden)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching agent statuses", "err", err)
+		http.Error(w, "unable to deregister agent", http.StatusInternalServerError)
+		return
+	}
+	agent, ok := agents[agentId]
+	if !ok {
+		http.Error(w, "no agent found", http.StatusNotFound)
+		return
+	}
+	health := r.agentHealth(req, agentId, agent)
+	if health.State == client.AgentLive {
+		http.Error(w, "agent is live (last heartbeat "+strconv.FormatFloat(health.HeartbeatAge, 'f', 0, 64)+
+			"s ago), only stale agents can be deregistered", http.StatusConflict)
+		return
+	}
+
+	pluginIds, err := r.store.FetchAllStoredPluginIds(ctx)
+	if err != nil {
+		r.logger.Error("error fetching stored plugin ids", "err", err)
+		http.Error(w, "unable to deregister agent", http.StatusInternalServerError)
+		return
+	}
+	resp := client.AgentDeregistration{Agent: agentId, Plugins: []string{}}
+	for pluginId := range pluginIds {
+		_, _, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || common.ComputeAgentId(podName, podNs) != agentId {
+			continue
+		}
+		err = r.store.DeleteAllTestRunInfo(ctx, pluginId)
+		if err != nil {
+			r.logger.Error("error deleting plugin data of agent", "agent", agentId, "id", pluginId, "err", err)
+			http.Error(w, "unable to delete the data of plugin "+pluginId, http.StatusInternalServerError)
+			return
+		}
+		resp.Plugins = append(resp.Plugins, pluginId)
+	}
+	slices.Sort(resp.Plugins)
+	// the status is deleted last, so a failed deregistration can be retried
+	err = r.store.DeleteAgentStatus(ctx, agentId)
+	if err != nil {
+		r.logger.Error("error deleting agent status", "agent", agentId, "err", err)
+		http.Error(w, "unable to deregister agent", http.StatusInternalServerError)
+		return
+	}
+	r.logger.Info("deregistered agent", "agent", agentId, "plugins", len(resp.Plugins))
+	err = r.store.NewAgentEvent(ctx, "deregistered agent: "+agentId)
+	if err != nil {
+		r.logger.Warn("error publishing agent event", "agent", agentId, "err", err)
+	}
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:  identity.Name,
+		Kind:   common.AuditKindAgent,
+		Object: agentId,
+		Action: common.AuditActionDeregistered,
+		Details: map[string]string{
+			"lastHeartbeat": agent.StatusTime,
+			"plugins":       strconv.Itoa(len(resp.Plugins)),
+		},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// agentHealth Returns the health of the agent from its status, with the number of its tests the request can access
+func (r *RestApi) agentHealth(req *http.Request, agentId string, agent common.AgentStatus) client.AgentHealth {
+	podName, namespace, _ := strings.Cut(agentId, "/")
+	health := client.AgentHealth{
+		Id:           agentId,
+		PodName:      podName,
+		Namespace:    namespace,
+		NodeName:     agent.AgentConfig.RunTimeInfo.NodeName,
+		Version:      agent.AgentConfig.RunTimeInfo.Version,
+		AgentProfile: agent.AgentConfig.AgentProfile,
+		State:        client.AgentPending,
+	}
+	for _, configId := range agent.SynTests {
+		if canAccessTest(req, configId) {
+			health.TestCount++
+		}
+	}
+	if agent.StatusTime == "" {
+		return health // registered by the controller for a new pod
+	}
+	health.State = client.AgentStale
+	if lastStatus, err := time.Parse(common.TimeFormat, agent.StatusTime); err == nil {
+		health.LastHeartbeat = &lastStatus
+		health.HeartbeatAge = time.Since(lastStatus).Seconds()
+		if time.Since(lastStatus) < r.agentStatusDeadline() {
+			health.State = client.AgentLive
+		}
+	}
+	return health
+}
+
+// agentStatusDeadline Returns how long after its last status an agent is considered stale
+func (r *RestApi) agentStatusDeadline() time.Duration {
+	if r.config.AgentStatusDeadline <= 0 {
+		return DefaultAgentStatusDeadline
+	}
+	return r.config.AgentStatusDeadline
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	gmux "github.com/gorilla/mux"
+)
+
+// AuditMiddleware Records every mutating call (POST, PUT, PATCH and DELETE, except graphql queries) in the audit log,
+// so the changes made through the api are traceable like the changes of the CRDs: who made it (the identity, if
+// authentication is enabled), the path and the status code, and the version (config hash) of the test before and after
+// the call, if it's on a test
+func (r *RestApi) AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, req)
+			return
+		}
+		if req.URL.Path == "/api/v1/graphql" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		configId := auditedTestId(req)
+		oldVersion := r.testConfigVersion(configId)
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+
+		event := common.AuditEvent{
+			Kind:   common.AuditKindApiCall,
+			Object: req.URL.Path,
+			Action: req.Method,
+			Details: map[string]string{
+				"path":   req.URL.RequestURI(),
+				"status": strconv.Itoa(sw.status),
+				"ip":     r.requestIP(req),
+			},
+		}
+		if identity, ok := IdentityFromContext(req.Context()); ok {
+			event.Actor = identity.Name
+		}
+		if configId != "" {
+			event.Object = configId
+			event.Details["oldVersion"] = oldVersion
+			event.Details["version"] = r.testConfigVersion(configId)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		r.recordAuditEvent(ctx, event)
+	})
+}
+
+// auditedTestId Returns the config id of the test of a call on a test config or a syntest, empty otherwise
+func auditedTestId(req *http.Request) string {
+	vars := gmux.Vars(req)
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/api/v1/testconfig/") && vars["id"] != "":
+		return testId(vars["id"])
+	case strings.HasPrefix(req.URL.Path, "/api/v1/syntests/") && vars["name"] != "":
+		namespace := req.URL.Query().Get("namespace")
+		if namespace == "" {
+			return ""
+		}
+		return common.ComputeSynTestConfigId(vars["name"], namespace)
+	}
+	return ""
+}
+
+// testConfigVersion Returns the version of a test config, empty if it doesn't exist (or can't be fetched)
+func (r *RestApi) testConfigVersion(configId string) string {
+	if configId == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	summary, err := r.store.FetchTestConfigSummary(ctx, configId)
+	if err != nil {
+		return ""
+	}
+	return summary.Version
+}
+
+// requestActor Returns the actor of a request, the name of its identity (empty if authentication isn't enabled). The
+// actor set by the client in the body isn't authenticated, it's only recorded as a note (see withClaimedActor)
+func requestActor(req *http.Request) string {
+	if identity, ok := IdentityFromContext(req.Context()); ok {
+		return identity.Name
+	}
+	return ""
+}
+
+// withClaimedActor Adds the actor set by the client of a request (e.g. the user of a portal calling the api with its
+// service account) to the details of its audit event, as claimedActor
+func withClaimedActor(details map[string]string, claimedActor string) map[string]string {
+	if claimedActor != "" {
+		details["claimedActor"] = claimedActor
+	}
+	return details
+}
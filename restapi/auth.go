@@ -0,0 +1,241 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+// AuthConfig configures the authentication of the requests to the rest api, requests must have a bearer token (a
+// JWT or a static token) if any is configured
+type AuthConfig struct {
+	// IssuerUrl of the OIDC provider, its keys are discovered from <issuer>/.well-known/openid-configuration
+	IssuerUrl string `yaml:"issuerUrl"`
+	// JwksUrl of the keys the JWTs are signed with, instead of the discovered ones (e.g. issuers without discovery)
+	JwksUrl string `yaml:"jwksUrl"`
+	// Audience of the JWTs (e.g. the client id), required with an issuer so tokens issued to other clients are rejected
+	Audience string `yaml:"audience"`
+	// UsernameClaim is the claim with the name of the user (default sub)
+	UsernameClaim string `yaml:"usernameClaim"`
+	// GroupsClaim is the claim with the groups of the user (default groups)
+	GroupsClaim string `yaml:"groupsClaim"`
+	// StaticTokens are long-lived tokens for service accounts (e.g. CI or scripts)
+	StaticTokens []StaticToken `yaml:"staticTokens"`
+	// PublicPaths are the paths (or globs, e.g. /api/v1/badge/*/*) which don't need a token (default /api/v1/ping and
+	// /api/v1/healthz/system for health checks, /openapi.json and /metrics for scraping, and the static files of the
+	// dashboard, which calls the api with the token of the user)
+	PublicPaths []string `yaml:"publicPaths"`
+	// NamespacesClaim is the claim with the namespaces the user can read the syntests of (e.g. mapped from its teams)
+	NamespacesClaim string `yaml:"namespacesClaim"`
+	// NamespaceRules grant users and groups access to the syntests of namespaces. If neither the rules nor the
+	// namespaces claim are configured, all authenticated requests can access all namespaces.
+	NamespaceRules []NamespaceRule `yaml:"namespaceRules"`
+	// TenantClaim is the claim with the name of the tenant of the user (multi-tenant mode)
+	TenantClaim string `yaml:"tenantClaim"`
+	// Tenants enables the multi-tenant mode: every request must belong to a tenant (from the tenant claim, or its users
+	// and groups), and only sees the tests of its tenant (in addition to the namespace rules)
+	Tenants []TenantConfig `yaml:"tenants"`
+}
+
+// StaticToken is a token of a service account, the token is read from TokenFile if set (e.g. a mounted secret)
+type StaticToken struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token"`
+	TokenFile string   `yaml:"tokenFile"`
+	Groups    []string `yaml:"groups"`
+}
+
+const (
+	DefaultUsernameClaim = "sub"
+	DefaultGroupsClaim   = "groups"
+)
+
+// DefaultPublicPaths are the paths which don't need a token, if not configured
+var DefaultPublicPaths = []string{"/api/v1/ping", "/api/v1/healthz/system", "/openapi.json", "/metrics",
+	"/dashboard", "/dashboard/", "/dashboard/*"}
+
+// Identity is the authenticated user (or service account) of a request
+type Identity struct {
+	Name   string
+	Groups []string
+	// Namespaces the user can access the syntests of (names or globs), all namespaces if AllNamespaces is set
+	Namespaces    []string
+	AllNamespaces bool
+	// Tenant of the user in multi-tenant mode (nil otherwise), it only sees the tests of the tenant
+	Tenant *Tenant
+}
+
+type identityKey struct{}
+
+// IdentityFromContext Returns the identity of the authenticated request, false if authentication isn't enabled
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Authenticator checks the bearer tokens of the requests
+type Authenticator struct {
+	config   AuthConfig
+	verifier *oidc.IDTokenVerifier // nil if no issuer is configured
+	tokens   []StaticToken
+	tenants  []*Tenant
+	logger   hclog.Logger
+}
+
+// NewAuthenticator Returns the authenticator of the config, nil if no authentication is configured. The keys of the
+// issuer are discovered at once (so a wrong issuer fails fast).
+func NewAuthenticator(config AuthConfig, logger hclog.Logger) (*Authenticator, error) {
+	if config.IssuerUrl == "" && config.JwksUrl == "" && len(config.StaticTokens) == 0 {
+		if len(config.Tenants) > 0 {
+			return nil, errors.New("tenants require authentication (an issuer or static tokens)")
+		}
+		return nil, nil
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = DefaultUsernameClaim
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = DefaultGroupsClaim
+	}
+	if config.PublicPaths == nil {
+		config.PublicPaths = DefaultPublicPaths
+	}
+	a := &Authenticator{config: config, logger: logger.Named("auth")}
+	tenants, err := newTenants(config.Tenants)
+	if err != nil {
+		return nil, errors.Wrap(err, "error configuring tenants")
+	}
+	a.tenants = tenants
+
+	if (config.IssuerUrl != "" || config.JwksUrl != "") && config.Audience == "" {
+		return nil, errors.New("audience is required with an issuer, otherwise the tokens of any client of the issuer are accepted")
+	}
+	oidcConfig := &oidc.Config{ClientID: config.Audience}
+	if config.JwksUrl != "" {
+		if config.IssuerUrl == "" {
+			return nil, errors.New("issuerUrl is required with jwksUrl")
+		}
+		a.verifier = oidc.NewVerifier(config.IssuerUrl, oidc.NewRemoteKeySet(context.Background(), config.JwksUrl), oidcConfig)
+	} else if config.IssuerUrl != "" {
+		// the provider keeps using the context to refresh its keys, so it's never cancelled
+		provider, err := oidc.NewProvider(context.Background(), config.IssuerUrl)
+		if err != nil {
+			return nil, errors.Wrap(err, "error discovering oidc provider "+config.IssuerUrl)
+		}
+		a.verifier = provider.Verifier(oidcConfig)
+	}
+
+	for _, token := range config.StaticTokens {
+		if token.TokenFile != "" {
+			b, err := os.ReadFile(token.TokenFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "error reading token file of "+token.Name)
+			}
+			token.Token = strings.TrimSpace(string(b))
+		}
+		if token.Name == "" || token.Token == "" {
+			return nil, errors.New("static tokens must have a name and a token")
+		}
+		a.tokens = append(a.tokens, token)
+	}
+	return a, nil
+}
+
+// isPublic Returns whether the path matches one of the public paths
+func (a *Authenticator) isPublic(urlPath string) bool {
+	return slices.ContainsFunc(a.config.PublicPaths, func(p string) bool {
+		ok, err := path.Match(p, urlPath)
+		return err == nil && ok
+	})
+}
+
+// Middleware Rejects the requests (except to the public paths) without a valid bearer token, and adds the identity of
+// the token to the context of the others
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if a.isPublic(req.URL.Path) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		rawToken, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || rawToken == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "no bearer token provided", http.StatusUnauthorized)
+			return
+		}
+		identity, err := a.authenticate(req.Context(), rawToken)
+		if err != nil {
+			a.logger.Info("rejected request", "path", req.URL.Path, "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		if len(a.tenants) > 0 && identity.Tenant == nil {
+			a.logger.Info("rejected request without tenant", "path", req.URL.Path, "user", identity.Name)
+			http.Error(w, "no tenant of the user "+identity.Name, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), identityKey{}, identity)))
+	})
+}
+
+// authenticate Returns the identity of the token, if it's a static token or a valid JWT of the issuer
+func (a *Authenticator) authenticate(ctx context.Context, rawToken string) (Identity, error) {
+	for _, token := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(rawToken), []byte(token.Token)) == 1 {
+			identity := Identity{Name: token.Name, Groups: token.Groups}
+			a.authorize(&identity, nil)
+			return identity, nil
+		}
+	}
+	if a.verifier == nil {
+		return Identity{}, errors.New("unknown static token")
+	}
+	idToken, err := a.verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "error verifying jwt")
+	}
+	claims := map[string]interface{}{}
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "error parsing claims of jwt")
+	}
+	identity := Identity{}
+	identity.Name, _ = claims[a.config.UsernameClaim].(string)
+	if identity.Name == "" {
+		return Identity{}, errors.New("jwt has no " + a.config.UsernameClaim + " claim")
+	}
+	if groups, ok := claims[a.config.GroupsClaim].([]interface{}); ok {
+		for _, group := range groups {
+			if g, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, g)
+			}
+		}
+	}
+	a.authorize(&identity, claims)
+	return identity, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   AuthConfig
+		wantErr  bool
+		wantNone bool
+	}{
+		{name: "no authentication", config: AuthConfig{}, wantNone: true},
+		{name: "issuer without audience", config: AuthConfig{IssuerUrl: "https://dex.example.com"}, wantErr: true},
+		{name: "jwks without audience", config: AuthConfig{IssuerUrl: "https://dex.example.com", JwksUrl: "https://dex.example.com/keys"}, wantErr: true},
+		{name: "jwks without issuer", config: AuthConfig{JwksUrl: "https://dex.example.com/keys", Audience: "synheart"}, wantErr: true},
+		{name: "jwks with audience", config: AuthConfig{IssuerUrl: "https://dex.example.com", JwksUrl: "https://dex.example.com/keys", Audience: "synheart"}},
+		{name: "static tokens", config: AuthConfig{StaticTokens: []StaticToken{{Name: "ci", Token: "secret"}}}},
+		{name: "static token without name", config: AuthConfig{StaticTokens: []StaticToken{{Token: "secret"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAuthenticator(tt.config, hclog.NewNullLogger())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAuthenticator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (a == nil) != tt.wantNone {
+				t.Errorf("NewAuthenticator() = %v, want nil %v", a, tt.wantNone)
+			}
+		})
+	}
+}
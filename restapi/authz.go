@@ -0,0 +1,148 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	gmux "github.com/gorilla/mux"
+)
+
+// NamespaceRule grants the users and groups access to the syntests of the namespaces
+type NamespaceRule struct {
+	Users      []string `yaml:"users"`
+	Groups     []string `yaml:"groups"`
+	Namespaces []string `yaml:"namespaces"` // names or globs, e.g. "payments-*" ("_cluster" for cluster tests)
+}
+
+// namespacedPaths are the paths of the endpoints of a single test (or plugin), the namespace of the test is the second
+// component of their id (<name>/<namespace>[/<agent pod name>/<agent namespace>])
+var namespacedPaths = []string{"/api/v1/testconfig/", "/api/v1/plugin/", "/api/v1/testrun/", "/api/v1/slo/"}
+
+// authorize Sets the namespaces of the identity from the namespace rules and the namespaces claim of its token, and its
+// tenant in multi-tenant mode
+func (a *Authenticator) authorize(identity *Identity, claims map[string]interface{}) {
+	identity.Tenant = a.tenant(*identity, claims)
+	if len(a.config.NamespaceRules) == 0 && a.config.NamespacesClaim == "" {
+		identity.AllNamespaces = true
+		return
+	}
+	for _, rule := range a.config.NamespaceRules {
+		if slices.Contains(rule.Users, identity.Name) ||
+			slices.ContainsFunc(rule.Groups, func(g string) bool { return slices.Contains(identity.Groups, g) }) {
+			identity.Namespaces = append(identity.Namespaces, rule.Namespaces...)
+		}
+	}
+	if namespaces, ok := claims[a.config.NamespacesClaim].([]interface{}); ok && a.config.NamespacesClaim != "" {
+		for _, namespace := range namespaces {
+			if ns, ok := namespace.(string); ok {
+				identity.Namespaces = append(identity.Namespaces, ns)
+			}
+		}
+	}
+}
+
+// CanAccess Returns whether the identity can access all the syntests of the namespace
+func (identity Identity) CanAccess(namespace string) bool {
+	return identity.inNamespaces(namespace) && (identity.Tenant == nil || identity.Tenant.hasNamespace(namespace))
+}
+
+// CanAccessTest Returns whether the identity can access the syntest of the config id, in multi-tenant mode it must
+// also belong to its tenant (by namespace or label selector)
+func (identity Identity) CanAccessTest(configId string) bool {
+	return identity.inNamespaces(idNamespace(configId)) && (identity.Tenant == nil || identity.Tenant.hasTest(configId))
+}
+
+// Restricted Returns whether the identity can't access all the syntests (namespace rules without "*", or a tenant)
+func (identity Identity) Restricted() bool {
+	return !(identity.AllNamespaces || slices.Contains(identity.Namespaces, "*")) || identity.Tenant != nil
+}
+
+func (identity Identity) inNamespaces(namespace string) bool {
+	if identity.AllNamespaces {
+		return true
+	}
+	for _, pattern := range identity.Namespaces {
+		if match, err := path.Match(pattern, namespace); err == nil && match {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeMiddleware Rejects the requests to the endpoints of a single test in a namespace the identity can't access,
+// the list endpoints only return the namespaces it can access (see canAccess)
+func AuthorizeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		identity, ok := IdentityFromContext(req.Context())
+		if !ok || !identity.Restricted() ||
+			!slices.ContainsFunc(namespacedPaths, func(p string) bool { return strings.HasPrefix(req.URL.Path, p) }) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		configId := testId(gmux.Vars(req)["id"])
+		if !identity.CanAccessTest(configId) {
+			http.Error(w, "no access to the test "+configId, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// idNamespace Returns the namespace of the test of a config id or plugin id (its second component)
+func idNamespace(id string) string {
+	_, namespace, _ := strings.Cut(id, "/")
+	namespace, _, _ = strings.Cut(namespace, "/")
+	return namespace
+}
+
+// testId Returns the config id of the test of a config id or plugin id (its first two components)
+func testId(id string) string {
+	name, rest, _ := strings.Cut(id, "/")
+	namespace, _, _ := strings.Cut(rest, "/")
+	return common.ComputeSynTestConfigId(name, namespace)
+}
+
+// canAccess Returns whether the request can access all the syntests of the namespace (always if authentication isn't
+// enabled), to filter the results of the list endpoints which only have the namespace of the tests (e.g. federation)
+func canAccess(req *http.Request, namespace string) bool {
+	identity, ok := IdentityFromContext(req.Context())
+	return !ok || identity.CanAccess(namespace)
+}
+
+// canAccessSilence Returns whether the request can access the silence (always if authentication isn't enabled), users
+// restricted to some namespaces can only access the silences limited to testNamespaces they have access to
+func canAccessSilence(req *http.Request, silence common.Silence) bool {
+	identity, ok := IdentityFromContext(req.Context())
+	if !ok || !identity.Restricted() {
+		return true
+	}
+	return len(silence.TestNamespaces) > 0 && !slices.ContainsFunc(silence.TestNamespaces, func(ns string) bool {
+		return !identity.CanAccess(ns)
+	})
+}
+
+// canAccessTest Returns whether the request can access the syntest of a config id or plugin id (always if
+// authentication isn't enabled), to filter the results of the list endpoints
+func canAccessTest(req *http.Request, id string) bool {
+	identity, ok := IdentityFromContext(req.Context())
+	return !ok || identity.CanAccessTest(testId(id))
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cisco-open/synthetic-heart/common"
+)
+
+func TestCanAccessSilence(t *testing.T) {
+	restricted := &Identity{Name: "jane", Namespaces: []string{"payments", "payments-*"}}
+	tests := []struct {
+		name     string
+		identity *Identity // nil without authentication
+		silence  common.Silence
+		want     bool
+	}{
+		{"no authentication", nil, common.Silence{}, true},
+		{"all namespaces", &Identity{Name: "admin", AllNamespaces: true}, common.Silence{}, true},
+		{"namespace glob", &Identity{Name: "sre", Namespaces: []string{"*"}}, common.Silence{TestNamespaces: []string{"dns"}}, true},
+		{"restricted without namespaces", restricted, common.Silence{TestNames: []string{"dns-external"}}, false},
+		{"restricted in namespaces", restricted, common.Silence{TestNamespaces: []string{"payments", "payments-eu"}}, true},
+		{"restricted partly in namespaces", restricted, common.Silence{TestNamespaces: []string{"payments", "dns"}}, false},
+		{"restricted other namespace", restricted, common.Silence{TestNamespaces: []string{"dns"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/silence/abc", nil)
+			if tt.identity != nil {
+				req = req.WithContext(context.WithValue(req.Context(), identityKey{}, *tt.identity))
+			}
+			if got := canAccessSilence(req, tt.silence); got != tt.want {
+				t.Errorf("canAccessSilence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	gmux "github.com/gorilla/mux"
+)
+
+// Colors of the badges
+const (
+	BadgeColorPassing = "#4c1"
+	BadgeColorFailing = "#e05d44"
+	BadgeColorUnknown = "#9f9f9f"
+)
+
+// badgeSvg is a flat (shields.io style) badge: width, label, message, label width, message width, color, label x,
+// message x
+const badgeSvg = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">` +
+	`<title>%[2]s: %[3]s</title>` +
+	`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+	`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>` +
+	`<g clip-path="url(#r)"><rect width="%[4]d" height="20" fill="#555"/><rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/>` +
+	`<rect width="%[1]d" height="20" fill="url(#s)"/></g>` +
+	`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+	`<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text><text x="%[7]d" y="14">%[2]s</text>` +
+	`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text><text x="%[8]d" y="14">%[3]s</text></g></svg>`
+
+// GetBadge Returns an svg badge with the current status of a test (passing if it passes on all the agents running it)
+// and its pass rate over the window query param (default the last day), to embed in readmes and wikis. The label is the
+// name of the test, or the label query param.
+func (r *RestApi) GetBadge(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	vars := gmux.Vars(req)
+	namespace, name := vars["namespace"], vars["test"]
+	if !canAccessTest(req, common.ComputeSynTestConfigId(name, namespace)) {
+		http.Error(w, "no access to the test "+name+"/"+namespace, http.StatusForbidden)
+		return
+	}
+	window := DefaultResultsRange
+	if wnd := req.URL.Query().Get("window"); wnd != "" {
+		var err error
+		window, err = time.ParseDuration(wnd)
+		if err != nil || window <= 0 || window > common.TestRunHistoryRetention {
+			http.Error(w, "invalid window, must be a positive duration up to "+common.TestRunHistoryRetention.String(),
+				http.StatusBadRequest)
+			return
+		}
+	}
+	label := req.URL.Query().Get("label")
+	if label == "" {
+		label = name
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	configId := common.ComputeSynTestConfigId(name, namespace)
+	allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to fetch test status", http.StatusInternalServerError)
+		return
+	}
+	message, color := "unknown", BadgeColorUnknown
+	runs, passed := 0, 0.0
+	failing := false
+	since := time.Now().Add(-window)
+	for pluginId, status := range allStatus {
+		testName, testNs, _, _, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || common.ComputeSynTestConfigId(testName, testNs) != configId {
+			continue
+		}
+		if passRatio, err := strconv.ParseFloat(status, 64); err != nil || passRatio < 1 {
+			failing = true
+		}
+		history, err := r.store.FetchTestRunHistory(ctx, pluginId, since)
+		if err != nil {
+			r.logger.Error("error fetching test run history", "id", pluginId, "err", err)
+			http.Error(w, "unable to fetch test status", http.StatusInternalServerError)
+			return
+		}
+		for _, res := range history {
+			runs++
+			passed += res.PassRatio
+		}
+	}
+	if runs > 0 {
+		message, color = "passing", BadgeColorPassing
+		if failing {
+			message, color = "failing", BadgeColorFailing
+		}
+		message += " " + strconv.FormatFloat(100*passed/float64(runs), 'f', 1, 64) + "%"
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// the badges are live, so they shouldn't be cached (e.g. by the image proxy of github)
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate, max-age=0")
+	_, err = w.Write([]byte(badge(label, message, color)))
+	if err != nil {
+		r.logger.Error("error writing badge", "err", err)
+	}
+}
+
+// badge Returns the svg of a badge, the width of the text is estimated from its length
+func badge(label string, message string, color string) string {
+	labelWidth := 7*len(label) + 10
+	messageWidth := 7*len(message) + 10
+	return fmt.Sprintf(badgeSvg, labelWidth+messageWidth, html.EscapeString(label), html.EscapeString(message),
+		labelWidth, messageWidth, color, labelWidth/2, labelWidth+messageWidth/2)
+}
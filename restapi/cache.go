@@ -0,0 +1,204 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long the responses of the hot endpoints are cached, if not configured
+const DefaultCacheTTL = 5 * time.Second
+
+// MaxCacheEntries is the max number of cached responses (e.g. of different filters), responses aren't cached when
+// it's full of unexpired ones
+const MaxCacheEntries = 1000
+
+// Topics of the cached responses, a response is dropped from the cache when an event of its topic is published in
+// storage (before its ttl)
+const (
+	cacheTopicConfigs  = "configs"  // config channel: test configs, silences, agent profiles
+	cacheTopicTestRuns = "testRuns" // syntests channel: new test runs
+	cacheTopicAgents   = "agents"   // agent channel
+	cacheTopicNone     = ""         // only expires (e.g. plugin status, which isn't published)
+)
+
+// responseCache caches the responses of the endpoints polled by many dashboards, to reduce the load on storage. A nil
+// cache is disabled.
+type responseCache struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	topic   string
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// newResponseCache Returns a cache with the ttl (DefaultCacheTTL if 0), or nil if the ttl is negative
+func newResponseCache(ttl time.Duration) *responseCache {
+	if ttl < 0 {
+		return nil
+	}
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &responseCache{ttl: ttl, entries: map[string]*cachedResponse{}}
+}
+
+// handler Returns the handler with its successful GET responses cached, by tenant, url and identity (as the list
+// endpoints only return the tests it can access). The lookups are recorded in the metrics of the cache of the name.
+func (c *responseCache) handler(name string, topic string, next http.HandlerFunc) http.HandlerFunc {
+	if c == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			next(w, req)
+			return
+		}
+		key := name + " " + req.URL.RequestURI()
+		if identity, ok := IdentityFromContext(req.Context()); ok {
+			if identity.Tenant != nil {
+				key = identity.Tenant.Name() + "/" + key
+			}
+			key += " " + identity.Name + " " + strings.Join(identity.Namespaces, ",")
+		}
+		if entry, ok := c.get(key); ok {
+			observeCache(name, true)
+			for k, v := range entry.header {
+				w.Header()[k] = v
+			}
+			_, _ = w.Write(entry.body)
+			return
+		}
+		observeCache(name, false)
+
+		rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		next(rec, req)
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(rec.body.Bytes())
+		if rec.status == http.StatusOK {
+			c.set(key, &cachedResponse{topic: topic, header: rec.header, body: rec.body.Bytes(), expires: time.Now().Add(c.ttl)})
+		}
+	}
+}
+
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *responseCache) set(key string, entry *cachedResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.entries) >= MaxCacheEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= MaxCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = entry
+}
+
+// invalidate Drops the cached responses of the topic
+func (c *responseCache) invalidate(topic string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for k, e := range c.entries {
+		if e.topic == topic {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// watchInvalidations Drops the cached responses of the topics when events are published in storage, till the context
+// is done. The subscriptions are retried if they fail, in the meantime the responses only expire.
+func (r *RestApi) watchInvalidations(ctx context.Context) {
+	if r.cache == nil {
+		return
+	}
+	subscriptions := map[string]func(ctx context.Context, channelSize int, events chan<- string) error{
+		cacheTopicConfigs:  r.store.SubscribeToConfigEvents,
+		cacheTopicTestRuns: r.store.SubscribeToTestRunEvents,
+		cacheTopicAgents:   r.store.SubscribeToAgentEvents,
+	}
+	for topic, subscribe := range subscriptions {
+		events := make(chan string, 1000)
+		go func() {
+			for ctx.Err() == nil {
+				err := subscribe(ctx, 1000, events)
+				if err != nil {
+					r.logger.Warn("error subscribing to storage events, retrying", "topic", topic, "err", err)
+				}
+				select {
+				case <-ctx.Done():
+				case <-time.After(PingRefreshFrequency):
+				}
+			}
+		}()
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-events:
+					r.cache.invalidate(topic)
+				}
+			}
+		}()
+	}
+}
+
+// responseRecorder buffers the response of a handler so it can be cached
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
@@ -0,0 +1,677 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+// package containing a go client of the rest api (see openapi.json for the spec of the endpoints), so other tooling
+// can consume synthetic-heart programmatically
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/slo"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+// Client of the rest api
+type Client struct {
+	// BaseUrl of the rest api, e.g. http://synheart-restapi.synthetic-heart.svc:8080
+	BaseUrl string
+	// Token is the bearer token sent with the requests (if the rest api has authentication enabled)
+	Token string
+	// HTTPClient used for the requests
+	HTTPClient *http.Client
+}
+
+// Error is returned when the rest api responds with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rest api error %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound Returns whether the error is a 404 of the rest api
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+func NewClient(baseUrl string, token string) *Client {
+	return &Client{
+		BaseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// ListOptions are the pagination and filters of the results and agents
+type ListOptions struct {
+	Limit     int
+	Offset    int
+	Test      string
+	Namespace string
+	Agent     string
+	Status    string // passing or failing (results only)
+	Since     time.Time
+	Until     time.Time
+}
+
+func (opts ListOptions) values() url.Values {
+	v := url.Values{}
+	if opts.Limit > 0 {
+		v.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		v.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	setIfNotEmpty(v, "test", opts.Test)
+	setIfNotEmpty(v, "namespace", opts.Namespace)
+	setIfNotEmpty(v, "agent", opts.Agent)
+	setIfNotEmpty(v, "status", opts.Status)
+	setTime(v, "since", opts.Since)
+	setTime(v, "until", opts.Until)
+	return v
+}
+
+// AuditQuery are the filters of the audit events
+type AuditQuery struct {
+	Count  int
+	Kind   string
+	Action string
+	Source string
+	Actor  string
+	Object string // prefix of the id of the object
+	Since  time.Time
+}
+
+func (q AuditQuery) values() url.Values {
+	v := url.Values{}
+	if q.Count > 0 {
+		v.Set("count", strconv.Itoa(q.Count))
+	}
+	setIfNotEmpty(v, "kind", q.Kind)
+	setIfNotEmpty(v, "action", q.Action)
+	setIfNotEmpty(v, "source", q.Source)
+	setIfNotEmpty(v, "actor", q.Actor)
+	setIfNotEmpty(v, "object", q.Object)
+	setTime(v, "since", q.Since)
+	return v
+}
+
+// ResultsQuery is the time range (the last day by default), agent and aggregation of the results of a test
+type ResultsQuery struct {
+	Since  time.Time
+	Until  time.Time
+	Agent  string
+	Bucket time.Duration // aggregates the results in buckets of this duration if set
+	Limit  int           // of the results, if not aggregated
+	Offset int
+}
+
+func (q ResultsQuery) values(namespace string) url.Values {
+	v := ListOptions{Limit: q.Limit, Offset: q.Offset, Namespace: namespace, Agent: q.Agent, Since: q.Since, Until: q.Until}.values()
+	if q.Bucket > 0 {
+		v.Set("bucket", q.Bucket.String())
+	}
+	return v
+}
+
+// FederatedTestsQuery are the filters of the federated tests
+type FederatedTestsQuery struct {
+	Cluster   string
+	Namespace string
+	Plugin    string
+	Failing   bool
+}
+
+func (q FederatedTestsQuery) values() url.Values {
+	v := url.Values{}
+	setIfNotEmpty(v, "cluster", q.Cluster)
+	setIfNotEmpty(v, "namespace", q.Namespace)
+	setIfNotEmpty(v, "plugin", q.Plugin)
+	if q.Failing {
+		v.Set("failing", "true")
+	}
+	return v
+}
+
+// Ping Returns the overall health of the tests
+func (c *Client) Ping(ctx context.Context) (PingResponse, error) {
+	resp := PingResponse{}
+	err := c.getJson(ctx, "/api/v1/ping", nil, &resp)
+	return resp, err
+}
+
+// SystemHealth Returns the health of the whole installation, with an *Error (503) if it isn't ok
+func (c *Client) SystemHealth(ctx context.Context) (SystemHealth, error) {
+	health := SystemHealth{}
+	err := c.getJson(ctx, "/api/v1/healthz/system", nil, &health)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable {
+		// the health is still returned when it isn't ok
+		_ = json.Unmarshal([]byte(apiErr.Message), &health)
+	}
+	return health, err
+}
+
+// Agents Returns the status of the agents (by agent id), and the number of agents matching the filters
+func (c *Client) Agents(ctx context.Context, opts ListOptions) (map[string]common.AgentStatus, int, error) {
+	agents := map[string]common.AgentStatus{}
+	total, err := c.getJsonPage(ctx, "/api/v1/agents", opts.values(), &agents)
+	return agents, total, err
+}
+
+// AgentsHealth Returns the health of the agents (sorted by agent id), with the ones in the state if not empty
+func (c *Client) AgentsHealth(ctx context.Context, state string) ([]AgentHealth, error) {
+	agents := []AgentHealth{}
+	var query url.Values
+	if state != "" {
+		query = url.Values{"state": []string{state}}
+	}
+	err := c.getJson(ctx, "/api/v1/agents/health", query, &agents)
+	return agents, err
+}
+
+// Agent Returns the health of an agent (<pod name>/<namespace>) and the tests it's running
+func (c *Client) Agent(ctx context.Context, agentId string) (AgentDetails, error) {
+	agent := AgentDetails{}
+	err := c.getJson(ctx, "/api/v1/agent/"+agentId, nil, &agent)
+	return agent, err
+}
+
+// DeregisterAgent Deletes a stale agent and the data of its plugins from storage
+func (c *Client) DeregisterAgent(ctx context.Context, agentId string) (AgentDeregistration, error) {
+	resp := AgentDeregistration{}
+	err := c.do(ctx, http.MethodDelete, "/api/v1/agent/"+agentId, nil, nil, &resp)
+	return resp, err
+}
+
+// TestConfigSummaries Returns the summaries of the test configs (by config id)
+func (c *Client) TestConfigSummaries(ctx context.Context) (map[string]common.SyntestConfigSummary, error) {
+	summaries := map[string]common.SyntestConfigSummary{}
+	err := c.getJson(ctx, "/api/v1/testconfigs/summary", nil, &summaries)
+	return summaries, err
+}
+
+// TestConfig Returns the config of a test (<name>/<namespace>)
+func (c *Client) TestConfig(ctx context.Context, configId string) (TestConfig, error) {
+	config := TestConfig{}
+	err := c.getJson(ctx, "/api/v1/testconfig/"+configId, nil, &config)
+	return config, err
+}
+
+// WatchTestConfigSummaries Watches the summaries of the test configs, calling handle with every event (ADDED for the
+// current summaries first), until the watch times out (timeout of the rest api if 0), ctx is done or handle errors
+func (c *Client) WatchTestConfigSummaries(ctx context.Context, timeout time.Duration,
+	handle func(event WatchEvent[common.SyntestConfigSummary]) error) error {
+	return watch(ctx, c, "/api/v1/testconfigs/summary", timeout, handle)
+}
+
+// WatchTestConfig Watches a test config (<name>/<namespace>), same as WatchTestConfigSummaries
+func (c *Client) WatchTestConfig(ctx context.Context, configId string, timeout time.Duration,
+	handle func(event WatchEvent[TestConfig]) error) error {
+	return watch(ctx, c, "/api/v1/testconfig/"+configId, timeout, handle)
+}
+
+// TestConfigHistory Returns the versions of a test config (newest first), and the version it's pinned to
+func (c *Client) TestConfigHistory(ctx context.Context, configId string) (TestConfigHistory, error) {
+	history := TestConfigHistory{}
+	err := c.getJson(ctx, "/api/v1/testconfig/"+configId+"/history", nil, &history)
+	return history, err
+}
+
+// RollbackTestConfig Rolls a test config back to a version of its history, and pins it to that version
+func (c *Client) RollbackTestConfig(ctx context.Context, configId string, rollback RollbackRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/testconfig/"+configId+"/rollback", nil, rollback, nil)
+}
+
+// DeleteTestConfigPin Unpins a test config, the version of its spec is deployed again
+func (c *Client) DeleteTestConfigPin(ctx context.Context, configId string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/testconfig/"+configId+"/pin", nil, nil, nil)
+}
+
+// SynTests Returns the current status of the tests matching the label selector (e.g. team=payments) in the namespace
+// (all the tests if both are empty), sorted by config id
+func (c *Client) SynTests(ctx context.Context, labelSelector string, namespace string) ([]TestStatus, error) {
+	tests := []TestStatus{}
+	query := url.Values{}
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	err := c.getJson(ctx, "/api/v1/syntests", query, &tests)
+	return tests, err
+}
+
+// PutSynTest Creates or updates a test managed through the rest api
+func (c *Client) PutSynTest(ctx context.Context, name string, namespace string, test SynTestRequest) (SynTestResponse, error) {
+	resp := SynTestResponse{}
+	query := url.Values{"namespace": []string{namespace}}
+	err := c.do(ctx, http.MethodPut, "/api/v1/syntests/"+name, query, test, &resp)
+	return resp, err
+}
+
+// DeleteSynTest Deletes a test managed through the rest api
+func (c *Client) DeleteSynTest(ctx context.Context, name string, namespace string) error {
+	query := url.Values{"namespace": []string{namespace}}
+	return c.do(ctx, http.MethodDelete, "/api/v1/syntests/"+name, query, nil, nil)
+}
+
+// TriggerTest Requests a run of a test at once, on all the agents running it (or the agents of the trigger)
+func (c *Client) TriggerTest(ctx context.Context, name string, namespace string, trigger TriggerRequest) (TriggerResponse, error) {
+	resp := TriggerResponse{}
+	query := url.Values{"namespace": []string{namespace}}
+	err := c.do(ctx, http.MethodPost, "/api/v1/syntests/"+name+"/trigger", query, trigger, &resp)
+	return resp, err
+}
+
+// TriggeredRun Returns the results of a triggered run of a test (by agent id), the agents which haven't run it yet
+// are missing
+func (c *Client) TriggeredRun(ctx context.Context, name string, namespace string, runId string) (map[string]*proto.TestRun, error) {
+	run := TriggeredRun{}
+	query := url.Values{"namespace": []string{namespace}}
+	err := c.getJson(ctx, "/api/v1/syntests/"+name+"/runs/"+runId, query, &run)
+	if err != nil {
+		return nil, err
+	}
+	results := map[string]*proto.TestRun{}
+	for agentId, raw := range run.Results {
+		testRun := &proto.TestRun{}
+		err = protojson.Unmarshal(raw, testRun)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding test run of agent %s: %w", agentId, err)
+		}
+		results[agentId] = testRun
+	}
+	return results, nil
+}
+
+// RunLogs Returns the logs of a run of a test (a triggered run or a test run) by agent id, on all the agents or the
+// agent if not empty
+func (c *Client) RunLogs(ctx context.Context, name string, namespace string, runId string, agent string) (RunLogs, error) {
+	logs := RunLogs{}
+	query := url.Values{"namespace": []string{namespace}}
+	if agent != "" {
+		query.Set("agent", agent)
+	}
+	err := c.getJson(ctx, "/api/v1/syntests/"+name+"/runs/"+runId+"/logs", query, &logs)
+	return logs, err
+}
+
+// TestResults Returns the results of a test over a time range, and the total number of results (if not aggregated)
+func (c *Client) TestResults(ctx context.Context, name string, namespace string, q ResultsQuery) (TestResults, int, error) {
+	results := TestResults{}
+	total, err := c.getJsonPage(ctx, "/api/v1/syntests/"+name+"/results", q.values(namespace), &results)
+	return results, total, err
+}
+
+// CompareAgents Returns the comparison of the results of a test across the agents running it, with the stats of the
+// runs between since and until (the last day if zero)
+func (c *Client) CompareAgents(ctx context.Context, name string, namespace string, since time.Time,
+	until time.Time) (AgentComparison, error) {
+	comparison := AgentComparison{}
+	err := c.getJson(ctx, "/api/v1/syntests/"+name+"/agents",
+		ListOptions{Namespace: namespace, Since: since, Until: until}.values(), &comparison)
+	return comparison, err
+}
+
+// ExportTestResults Writes the results of a test over a time range to out, as csv or ndjson (ExportFormat* values),
+// the response is compressed with gzip on the wire
+func (c *Client) ExportTestResults(ctx context.Context, name string, namespace string, format string, q ResultsQuery,
+	out io.Writer) error {
+	query := q.values(namespace)
+	query.Set("format", format)
+	_, err := c.request(ctx, http.MethodGet, "/api/v1/syntests/"+name+"/results/export", query, nil, out)
+	return err
+}
+
+// Stats Returns the statistics of the test runs grouped by test, namespace or agent (StatsBy* values), filtered by
+// test, namespace, agent and time range (the last day by default)
+func (c *Client) Stats(ctx context.Context, groupBy string, opts ListOptions) (Stats, error) {
+	stats := Stats{}
+	err := c.getJson(ctx, "/api/v1/stats/"+groupBy, opts.values(), &stats)
+	return stats, err
+}
+
+// Graphql Executes a graphql query and decodes its data into out. If some fields errored, the rest of the data is
+// decoded and the errors are returned as GraphqlErrors.
+func (c *Client) Graphql(ctx context.Context, query GraphqlRequest, out interface{}) error {
+	resp := GraphqlResponse{}
+	err := c.do(ctx, http.MethodPost, "/api/v1/graphql", nil, query, &resp)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(resp.Data) > 0 {
+		err = json.Unmarshal(resp.Data, out)
+		if err != nil {
+			return fmt.Errorf("error decoding graphql data: %w", err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	return nil
+}
+
+// PluginStatus Returns the status of the plugins of the tests on every agent (by plugin id)
+func (c *Client) PluginStatus(ctx context.Context) (map[string]string, error) {
+	status := map[string]string{}
+	err := c.getJson(ctx, "/api/v1/plugins/status", nil, &status)
+	return status, err
+}
+
+// PluginHealth Returns the latest health of the plugin of a test on an agent
+// (<test name>/<test namespace>/<agent pod name>/<agent namespace>)
+func (c *Client) PluginHealth(ctx context.Context, pluginId string) (common.PluginState, error) {
+	state := common.PluginState{}
+	err := c.getJson(ctx, "/api/v1/plugin/"+pluginId+"/health", nil, &state)
+	return state, err
+}
+
+// PluginLastUnhealthy Returns the last unhealthy state of the plugin of a test on an agent
+func (c *Client) PluginLastUnhealthy(ctx context.Context, pluginId string) (common.PluginState, error) {
+	state := common.PluginState{}
+	err := c.getJson(ctx, "/api/v1/plugin/"+pluginId+"/lastUnhealthy", nil, &state)
+	return state, err
+}
+
+// TestRunStatus Returns the pass ratio of the latest run of the tests on every agent (by plugin id), and the number of
+// results matching the filters
+func (c *Client) TestRunStatus(ctx context.Context, opts ListOptions) (map[string]string, int, error) {
+	status := map[string]string{}
+	total, err := c.getJsonPage(ctx, "/api/v1/testruns/status", opts.values(), &status)
+	return status, total, err
+}
+
+// StreamTestRuns Streams the test runs as they finish on the agents, filtered by the test, namespace, agent and status
+// of the options (the other options are ignored), calling handle with every run until the stream times out (timeout of
+// the rest api if 0), ctx is done or handle errors
+func (c *Client) StreamTestRuns(ctx context.Context, opts ListOptions, timeout time.Duration, handle func(run TestRunEvent) error) error {
+	query := ListOptions{Test: opts.Test, Namespace: opts.Namespace, Agent: opts.Agent, Status: opts.Status}.values()
+	return stream(ctx, c, "/api/v1/testruns/stream", query, timeout, func(event WatchEvent[TestRunEvent]) error {
+		return handle(event.Object)
+	})
+}
+
+// LatestTestRun Returns the latest run of a test on an agent
+func (c *Client) LatestTestRun(ctx context.Context, pluginId string) (*proto.TestRun, error) {
+	return c.getTestRun(ctx, "/api/v1/testrun/"+pluginId+"/latest")
+}
+
+// LastFailedTestRun Returns the last failed run of a test on an agent
+func (c *Client) LastFailedTestRun(ctx context.Context, pluginId string) (*proto.TestRun, error) {
+	return c.getTestRun(ctx, "/api/v1/testrun/"+pluginId+"/lastFailed")
+}
+
+// ForwardedLogIds Returns the ids of the runs of a test on an agent with forwarded logs, newest first
+func (c *Client) ForwardedLogIds(ctx context.Context, pluginId string) ([]string, error) {
+	runIds := []string{}
+	err := c.getJson(ctx, "/api/v1/testrun/"+pluginId+"/logs", nil, &runIds)
+	return runIds, err
+}
+
+// ForwardedLogs Returns the forwarded logs of a run of a test on an agent
+func (c *Client) ForwardedLogs(ctx context.Context, pluginId string, runId string) (string, error) {
+	var logs bytes.Buffer
+	err := c.do(ctx, http.MethodGet, "/api/v1/testrun/"+pluginId+"/logs/"+runId, nil, nil, &logs)
+	return logs.String(), err
+}
+
+// SLOs Returns the SLOs of all the tests with one (by config id)
+func (c *Client) SLOs(ctx context.Context) (map[string]slo.Report, error) {
+	reports := map[string]slo.Report{}
+	err := c.getJson(ctx, "/api/v1/slos", nil, &reports)
+	return reports, err
+}
+
+// SLO Returns the SLO of a test
+func (c *Client) SLO(ctx context.Context, configId string) (slo.Report, error) {
+	report := slo.Report{}
+	err := c.getJson(ctx, "/api/v1/slo/"+configId, nil, &report)
+	return report, err
+}
+
+// AuditEvents Returns the latest audit events (newest first) matching the query
+func (c *Client) AuditEvents(ctx context.Context, q AuditQuery) ([]common.AuditEvent, error) {
+	events := []common.AuditEvent{}
+	err := c.getJson(ctx, "/api/v1/audit", q.values(), &events)
+	return events, err
+}
+
+// Silences Returns all the silences
+func (c *Client) Silences(ctx context.Context) ([]common.Silence, error) {
+	silences := []common.Silence{}
+	err := c.getJson(ctx, "/api/v1/silences", nil, &silences)
+	return silences, err
+}
+
+// CreateSilence Creates a silence, and returns it (with its id)
+func (c *Client) CreateSilence(ctx context.Context, silence common.Silence) (common.Silence, error) {
+	created := common.Silence{}
+	err := c.do(ctx, http.MethodPost, "/api/v1/silences", nil, silence, &created)
+	return created, err
+}
+
+// DeleteSilence Deletes a silence
+func (c *Client) DeleteSilence(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/silence/"+id, nil, nil, nil)
+}
+
+// Clusters Returns the overview of every cluster of the federation
+func (c *Client) Clusters(ctx context.Context) ([]ClusterOverview, error) {
+	clusters := []ClusterOverview{}
+	err := c.getJson(ctx, "/api/v1/federation/clusters", nil, &clusters)
+	return clusters, err
+}
+
+// Cluster Returns the summary of a cluster of the federation, with the results of its tests
+func (c *Client) Cluster(ctx context.Context, cluster string) (common.ClusterSummary, error) {
+	summary := common.ClusterSummary{}
+	err := c.getJson(ctx, "/api/v1/federation/cluster/"+cluster, nil, &summary)
+	return summary, err
+}
+
+// DeleteCluster Removes a cluster from the federation
+func (c *Client) DeleteCluster(ctx context.Context, cluster string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/federation/cluster/"+cluster, nil, nil, nil)
+}
+
+// FederatedTests Returns the results of the tests of all the clusters of the federation matching the query
+func (c *Client) FederatedTests(ctx context.Context, q FederatedTestsQuery) ([]common.FederatedTestResult, error) {
+	tests := []common.FederatedTestResult{}
+	err := c.getJson(ctx, "/api/v1/federation/tests", q.values(), &tests)
+	return tests, err
+}
+
+func (c *Client) getJson(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// getJsonPage Gets a page of a list endpoint, and returns the total number of items (of all pages)
+func (c *Client) getJsonPage(ctx context.Context, path string, query url.Values, out interface{}) (int, error) {
+	var body bytes.Buffer
+	header, err := c.request(ctx, http.MethodGet, path, query, nil, &body)
+	if err != nil {
+		return 0, err
+	}
+	err = json.Unmarshal(body.Bytes(), out)
+	if err != nil {
+		return 0, fmt.Errorf("error decoding response of %s: %w", path, err)
+	}
+	total, _ := strconv.Atoi(header.Get(TotalCountHeader))
+	return total, nil
+}
+
+// watch Decodes the events of a watch as they're streamed, the http client timeout doesn't apply (the watch ends at
+// its timeout)
+func watch[T any](ctx context.Context, c *Client, path string, timeout time.Duration, handle func(event WatchEvent[T]) error) error {
+	return stream(ctx, c, path, url.Values{"watch": []string{"true"}}, timeout, handle)
+}
+
+// stream Decodes the events of a stream of WatchEvent (a watch), see watch
+func stream[T any](ctx context.Context, c *Client, path string, query url.Values, timeout time.Duration,
+	handle func(event WatchEvent[T]) error) error {
+	if timeout > 0 {
+		query.Set("timeoutSeconds", strconv.Itoa(int(timeout.Seconds())))
+	}
+	streaming := *c
+	httpClient := *c.HTTPClient
+	httpClient.Timeout = 0
+	streaming.HTTPClient = &httpClient
+
+	ctx, cancel := context.WithCancel(ctx)
+	body, out := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := streaming.request(ctx, http.MethodGet, path, query, nil, out)
+		_ = out.CloseWithError(err) // EOF if the watch ended
+	}()
+	defer func() {
+		cancel()
+		_ = body.Close()
+		<-done
+	}()
+
+	decoder := json.NewDecoder(body)
+	for {
+		event := WatchEvent[json.RawMessage]{}
+		err := decoder.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if event.Type == WatchError {
+			status := WatchStatus{}
+			_ = json.Unmarshal(event.Object, &status)
+			return fmt.Errorf("error watching %s: %s", path, status.Message)
+		}
+		object := WatchEvent[T]{Type: event.Type}
+		err = json.Unmarshal(event.Object, &object.Object)
+		if err != nil {
+			return fmt.Errorf("error decoding event of %s: %w", path, err)
+		}
+		err = handle(object)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) getTestRun(ctx context.Context, path string) (*proto.TestRun, error) {
+	var body bytes.Buffer
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &body)
+	if err != nil {
+		return nil, err
+	}
+	testRun := &proto.TestRun{}
+	err = protojson.Unmarshal(body.Bytes(), testRun)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding test run: %w", err)
+	}
+	return testRun, nil
+}
+
+// do Sends the request (with the json of in as body, if any), and decodes the json response into out (or copies it,
+// if out is a buffer)
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) error {
+	if buf, ok := out.(*bytes.Buffer); ok {
+		_, err := c.request(ctx, method, path, query, in, buf)
+		return err
+	}
+	var body bytes.Buffer
+	_, err := c.request(ctx, method, path, query, in, &body)
+	if err != nil || out == nil {
+		return err
+	}
+	err = json.Unmarshal(body.Bytes(), out)
+	if err != nil {
+		return fmt.Errorf("error decoding response of %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, query url.Values, in interface{},
+	out io.Writer) (http.Header, error) {
+	var reqBody io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	u := c.BaseUrl + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	_, err = io.Copy(out, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response of %s: %w", path, err)
+	}
+	return resp.Header, nil
+}
+
+func setIfNotEmpty(v url.Values, key string, value string) {
+	if value != "" {
+		v.Set(key, value)
+	}
+}
+
+func setTime(v url.Values, key string, t time.Time) {
+	if !t.IsZero() {
+		v.Set(key, t.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+)
+
+// TotalCountHeader is the header with the number of items matching the filters of a list endpoint (of all pages)
+const TotalCountHeader = "X-Total-Count"
+
+// Types of the events of a watch of the test configs (?watch=true)
+const (
+	WatchAdded    = "ADDED"
+	WatchModified = "MODIFIED"
+	WatchDeleted  = "DELETED"
+	WatchError    = "ERROR"
+)
+
+// WatchContentType is the content type of a watch, a stream of WatchEvent (newline delimited json)
+const WatchContentType = "application/json;stream=watch"
+
+// Groups of the stats endpoints (/api/v1/stats/<group>)
+const (
+	StatsByTest      = "tests"
+	StatsByNamespace = "namespaces"
+	StatsByAgent     = "agents"
+)
+
+// Formats of the result exports (/api/v1/syntests/<name>/results/export)
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// Types of the requests and responses of the rest api which aren't in the common package, shared by the rest api and
+// the client (see openapi.json)
+
+// PingResponse is the overall health of the tests (the response of /api/v1/ping)
+type PingResponse struct {
+	Message     string                    `json:"message"`
+	LastUpdated string                    `json:"lastUpdated"`
+	Details     string                    `json:"details"`
+	Status      int                       `json:"status"`
+	FailedTests map[string]FailedTestInfo `json:"failedTests"`
+}
+
+type FailedTestInfo struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	TestConfigId string `json:"testId"`
+	DisplayName  string `json:"displayName"`
+	Status       int    `json:"status"`
+}
+
+// TestConfig is the config of a test, as stored for the agents (a SynTestConfig) and as defined (the spec of the CRD)
+type TestConfig struct {
+	TestConfig   json.RawMessage `json:"testConfig"`
+	ConfigStatus json.RawMessage `json:"configStatus"`
+	RawConfig    string          `json:"rawConfig"`
+}
+
+// WatchEvent is an event of a watch: the object added or modified, the last object sent if deleted, or a WatchStatus
+// if the watch failed (which ends it)
+type WatchEvent[T any] struct {
+	Type   string `json:"type"`
+	Object T      `json:"object"`
+}
+
+// WatchStatus is the object of an ERROR event of a watch
+type WatchStatus struct {
+	Message string `json:"message"`
+}
+
+// TestRunEvent is a test run which finished on an agent, streamed by the rest api as ADDED events of a watch
+type TestRunEvent struct {
+	Test           string    `json:"test"` // <name>/<namespace>
+	Agent          string    `json:"agent"`
+	RunId          string    `json:"runId"`
+	Time           time.Time `json:"time"` // start time of the run
+	PassRatio      float64   `json:"passRatio"`
+	RuntimeSeconds float64   `json:"runtimeSeconds,omitempty"` // 0 if unknown
+	Trigger        string    `json:"trigger,omitempty"`        // type of trigger, e.g. timer
+	Error          string    `json:"error,omitempty"`          // error detail of the result, if any
+}
+
+// TestConfigHistory is the history of the versions of a test config (newest first)
+type TestConfigHistory struct {
+	PinnedVersion string                        `json:"pinnedVersion,omitempty"`
+	Versions      []common.SyntestConfigVersion `json:"versions"`
+}
+
+// RollbackRequest is the body of a test config rollback
+type RollbackRequest struct {
+	Version string `json:"version"`
+	Actor   string `json:"actor,omitempty"` // recorded as claimedActor, the actor is the authenticated identity
+	Comment string `json:"comment,omitempty"`
+}
+
+// ClusterOverview is the overview of a cluster of the federation (without the results of its tests)
+type ClusterOverview struct {
+	Cluster      string    `json:"cluster"`
+	Time         time.Time `json:"time"`
+	Stale        bool      `json:"stale"` // no summary was received from the cluster for FederationStaleAfter
+	HealthScore  *float64  `json:"healthScore,omitempty"`
+	Agents       int       `json:"agents"`
+	Tests        int       `json:"tests"`
+	FailingTests int       `json:"failingTests"`
+}
+
+// TriggerRequest is the (optional) body of a request to run a test at once
+type TriggerRequest struct {
+	// Agents to run the test on (ids), all the agents running it if empty
+	Agents  []string `json:"agents,omitempty"`
+	Actor   string   `json:"actor,omitempty"` // recorded as claimedActor, the actor is the authenticated identity
+	Comment string   `json:"comment,omitempty"`
+}
+
+// TriggerResponse is the run requested from the agents, its results can be polled with its id
+type TriggerResponse struct {
+	RunId    string   `json:"runId"`
+	ConfigId string   `json:"configId"`
+	Agents   []string `json:"agents"` // agents the run was requested on
+}
+
+// TriggeredRun are the results of a requested run, by agent id (the agents which haven't reported yet are missing)
+type TriggeredRun struct {
+	RunId   string                     `json:"runId"`
+	Results map[string]json.RawMessage `json:"results"` // test runs (proto json)
+}
+
+// TestStatus is the current status of a test, from its latest run on every agent running it
+type TestStatus struct {
+	ConfigId    string            `json:"configId"`
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	DisplayName string            `json:"displayName,omitempty"`
+	Plugin      string            `json:"plugin"`
+	Importance  string            `json:"importance,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"` // labels of the config (only with a label selector)
+	// Status is failing if the latest run failed on any agent, unknown if the test hasn't run on any agent
+	Status        string             `json:"status"`
+	PassingAgents int                `json:"passingAgents"`
+	FailingAgents []string           `json:"failingAgents"`
+	Agents        map[string]float64 `json:"agents,omitempty"` // pass ratio of the latest run, by agent id
+}
+
+// RunLogs are the logs of a run of a test, by agent id
+type RunLogs struct {
+	RunId    string            `json:"runId"`
+	ConfigId string            `json:"configId"`
+	Logs     map[string]string `json:"logs"`
+}
+
+// SynTestSpec is the spec of a test managed through the rest api, the same as the spec of a SyntheticTest (without the
+// fields only the controller handles, e.g. rollout and targetInventories)
+type SynTestSpec struct {
+	Plugin              string                `json:"plugin"`
+	Node                string                `json:"node,omitempty"`
+	PodLabelSelector    map[string]string     `json:"podLabelSelector,omitempty"`
+	DisplayName         string                `json:"displayName,omitempty"`
+	Description         string                `json:"description,omitempty"`
+	Importance          string                `json:"importance,omitempty"`
+	Repeat              string                `json:"repeat,omitempty"` // defaults to 1m
+	DependsOn           []string              `json:"dependsOn,omitempty"`
+	Timeouts            *proto.Timeouts       `json:"timeouts,omitempty"`
+	PluginRestartPolicy string                `json:"pluginRestartPolicy,omitempty"`
+	LogWaitTime         string                `json:"logWaitTime,omitempty"`
+	Config              string                `json:"config,omitempty"`
+	MetricLabels        map[string]string     `json:"metricLabels,omitempty"`
+	Alerting            *proto.Alerting       `json:"alerting,omitempty"`
+	SLO                 *proto.SLO            `json:"slo,omitempty"`
+	ActiveFrom          string                `json:"activeFrom,omitempty"`
+	ActiveUntil         string                `json:"activeUntil,omitempty"`
+	ActiveWindows       []*proto.ActiveWindow `json:"activeWindows,omitempty"`
+	Paused              bool                  `json:"paused,omitempty"`
+}
+
+// SynTestRequest is the body of a request creating or updating a test
+type SynTestRequest struct {
+	Labels  map[string]string `json:"labels,omitempty"`
+	Spec    SynTestSpec       `json:"spec"`
+	Actor   string            `json:"actor,omitempty"` // recorded as claimedActor, the actor is the authenticated identity
+	Comment string            `json:"comment,omitempty"`
+}
+
+// SynTestResponse is the test config written by a create or update request
+type SynTestResponse struct {
+	ConfigId string   `json:"configId"`
+	Version  string   `json:"version"`
+	Created  bool     `json:"created"`
+	Warnings []string `json:"warnings,omitempty"` // e.g. the test never runs, or the plugin couldn't be checked
+}
+
+// TestResults are the results of a test over a time range, either every test run (oldest first) or aggregated in buckets
+type TestResults struct {
+	ConfigId string         `json:"configId"`
+	Since    time.Time      `json:"since"`
+	Until    time.Time      `json:"until"`
+	Bucket   string         `json:"bucket,omitempty"`  // duration of the buckets, if aggregated
+	Results  []TestResult   `json:"results,omitempty"` // if not aggregated
+	Buckets  []ResultBucket `json:"buckets,omitempty"` // if aggregated
+}
+
+// TestResult is the result of a test run on an agent
+type TestResult struct {
+	Agent          string    `json:"agent"`
+	Time           time.Time `json:"time"`
+	PassRatio      float64   `json:"passRatio"`
+	RuntimeSeconds float64   `json:"runtimeSeconds,omitempty"` // 0 if unknown
+}
+
+// ResultBucket are the aggregated results of the test runs which started in a bucket of time
+type ResultBucket struct {
+	Start    time.Time `json:"start"`
+	Runs     int       `json:"runs"`
+	PassRate float64   `json:"passRate"` // average pass ratio of the runs (0 if there are none)
+	// Runtime percentiles in seconds, of the runs with a known runtime
+	RuntimeP50 float64 `json:"runtimeP50,omitempty"`
+	RuntimeP90 float64 `json:"runtimeP90,omitempty"`
+	RuntimeP99 float64 `json:"runtimeP99,omitempty"`
+}
+
+// Stats are the statistics of the test runs of the groups (tests, namespaces or agents) over a time range
+type Stats struct {
+	GroupBy string               `json:"groupBy"`
+	Since   time.Time            `json:"since"`
+	Until   time.Time            `json:"until"`
+	Groups  map[string]TestStats `json:"groups"` // by config id, namespace or agent id
+}
+
+// TestStats are the statistics of the test runs of a group
+type TestStats struct {
+	Runs     int     `json:"runs"`
+	Failures int     `json:"failures"` // runs which didn't fully pass
+	PassRate float64 `json:"passRate"` // average pass ratio of the runs
+	// Outages is the number of times a test started failing on an agent, Recoveries the number of times it passed again
+	Outages    int `json:"outages"`
+	Recoveries int `json:"recoveries"`
+	// MTTRSeconds is the mean time to recovery, from the first failed run of an outage to the next passed run
+	MTTRSeconds float64 `json:"mttrSeconds,omitempty"`
+}
+
+// GraphqlRequest is a graphql query with its variables
+type GraphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphqlResponse is the result of a graphql query, the fields which errored are null in the data
+type GraphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphqlErrors   `json:"errors,omitempty"`
+}
+
+type GraphqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"` // path of the field which errored, field names and list indexes
+}
+
+// GraphqlErrors are the errors of a graphql query
+type GraphqlErrors []GraphqlError
+
+func (e GraphqlErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		path := make([]string, len(err.Path))
+		for j, elem := range err.Path {
+			path[j] = fmt.Sprint(elem)
+		}
+		msgs[i] = err.Message
+		if len(path) > 0 {
+			msgs[i] = strings.Join(path, ".") + ": " + err.Message
+		}
+	}
+	return "graphql errors: " + strings.Join(msgs, "; ")
+}
+
+// AgentComparison compares the results of a test across the agents running it, to see which agents (and nodes) are
+// affected when it fails
+type AgentComparison struct {
+	ConfigId string        `json:"configId"`
+	Since    time.Time     `json:"since"`
+	Until    time.Time     `json:"until"`
+	Agents   []AgentResult `json:"agents"` // failing agents first, then by agent id
+	// FailingAgents and FailingNodes are the agents (and their nodes) on which the latest run of the test failed
+	FailingAgents []string      `json:"failingAgents"`
+	FailingNodes  []string      `json:"failingNodes"`
+	Runtime       RuntimeSpread `json:"runtime"`
+	// DifferingDetails are the details of the latest runs whose values differ between the agents, by key then agent
+	// (an empty value if an agent doesn't have the detail)
+	DifferingDetails map[string]map[string]string `json:"differingDetails,omitempty"`
+}
+
+// AgentResult is the latest result of a test on an agent, and the stats of its runs between since and until
+type AgentResult struct {
+	Agent     string            `json:"agent"`
+	Node      string            `json:"node,omitempty"`
+	Status    string            `json:"status"`    // passing or failing, from the latest run
+	PassRatio float64           `json:"passRatio"` // of the latest run
+	LatestRun *time.Time        `json:"latestRun,omitempty"`
+	Error     string            `json:"error,omitempty"`   // error of the latest run
+	Details   map[string]string `json:"details,omitempty"` // details of the latest run (without the logs and metrics)
+	Runs      int               `json:"runs"`
+	Failures  int               `json:"failures"`
+	PassRate  float64           `json:"passRate"`
+	// LastFailure is the latest failed run between since and until
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	RuntimeP50  float64    `json:"runtimeP50,omitempty"`
+	RuntimeP90  float64    `json:"runtimeP90,omitempty"`
+	// RuntimeDeviation is the runtime p50 of the agent relative to the median of the agents, e.g. 2 if it's twice as slow
+	RuntimeDeviation float64 `json:"runtimeDeviation,omitempty"`
+}
+
+// RuntimeSpread is the spread of the runtime p50 (in seconds) of the agents
+type RuntimeSpread struct {
+	Min          float64 `json:"min"`
+	Median       float64 `json:"median"`
+	Max          float64 `json:"max"`
+	SlowestAgent string  `json:"slowestAgent,omitempty"`
+}
+
+// Statuses of the checks of the system health
+const (
+	HealthOk      = "ok"
+	HealthFailing = "failing"
+	HealthUnknown = "unknown" // the check couldn't be done, e.g. the storage is unreachable
+)
+
+// SystemHealth is the health of the whole installation (the response of /api/v1/healthz/system), its status is ok if
+// all the checks are
+type SystemHealth struct {
+	Status        string             `json:"status"`
+	Time          time.Time          `json:"time"`
+	Storage       HealthCheck        `json:"storage"`
+	Controller    ControllerHealth   `json:"controller"`
+	Agents        AgentsHealth       `json:"agents"`
+	CriticalTests CriticalTestHealth `json:"criticalTests"`
+}
+
+// HealthCheck is the status of a check of the system health, with a message if it isn't ok
+type HealthCheck struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ControllerHealth is failing if the controller didn't post a heartbeat within the deadline
+type ControllerHealth struct {
+	HealthCheck
+	LastHeartbeat *time.Time `json:"lastHeartbeat,omitempty"`
+}
+
+// AgentsHealth is failing if some agents expected by the controller (with a running pod) stopped reporting
+type AgentsHealth struct {
+	HealthCheck
+	Expected   int      `json:"expected"`
+	Live       int      `json:"live"`
+	Registered int      `json:"registered"` // agents with a status in storage (including the ones whose pod is gone)
+	Stale      []string `json:"stale,omitempty"`
+}
+
+// CriticalTestHealth is failing if the latest run of a critical test failed on any agent
+type CriticalTestHealth struct {
+	HealthCheck
+	Total   int `json:"total"`
+	Failing int `json:"failing"`
+}
+
+// States of the agents in their health
+const (
+	AgentLive    = "live"    // posted its status within the agent status deadline
+	AgentStale   = "stale"   // stopped posting its status (e.g. its pod is gone), it can be deregistered
+	AgentPending = "pending" // registered by the controller for a new pod, it hasn't posted a status yet
+)
+
+// AgentHealth is the health of an agent, from the last status it posted (its heartbeat)
+type AgentHealth struct {
+	Id            string     `json:"id"`
+	PodName       string     `json:"podName"`
+	Namespace     string     `json:"namespace"`
+	NodeName      string     `json:"nodeName,omitempty"`
+	Version       string     `json:"version,omitempty"`
+	AgentProfile  string     `json:"agentProfile,omitempty"`
+	State         string     `json:"state"`
+	LastHeartbeat *time.Time `json:"lastHeartbeat,omitempty"`
+	// HeartbeatAge is the time since the last heartbeat in seconds (0 if there is none)
+	HeartbeatAge float64 `json:"heartbeatAgeSeconds,omitempty"`
+	TestCount    int     `json:"testCount"`
+}
+
+// AgentDetails is the health of an agent and the tests it's running
+type AgentDetails struct {
+	AgentHealth
+	Tests []AgentTest `json:"tests"`
+}
+
+// AgentTest is a test running on an agent, with the state of its plugin and the status of its latest run
+type AgentTest struct {
+	ConfigId    string   `json:"configId"`
+	PluginId    string   `json:"pluginId"`
+	PluginState string   `json:"pluginState,omitempty"` // e.g. running or restarting (empty if the plugin has no state)
+	Status      string   `json:"status"`                // passing or failing, unknown if the test hasn't run yet
+	PassRatio   *float64 `json:"passRatio,omitempty"`   // of the latest run
+}
+
+// AgentDeregistration is the result of deregistering a stale agent
+type AgentDeregistration struct {
+	Agent string `json:"agent"`
+	// Plugins are the plugins of the agent whose data (test runs, logs, health) was deleted
+	Plugins []string `json:"plugins"`
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+// Generates a grafana dashboard (json) for the syntests and agents currently registered in redis
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/grafana"
+	"github.com/hashicorp/go-hclog"
+)
+
+func main() {
+	storeAddr := flag.String("redis", "localhost:6379", "address of the synthetic heart redis")
+	out := flag.String("out", "", "file to write the dashboard to (stdout if empty)")
+	title := flag.String("title", grafana.DefaultTitle, "title of the dashboard")
+	uid := flag.String("uid", grafana.DefaultUid, "uid of the dashboard")
+	agentLabel := flag.String("agent-label", grafana.DefaultAgentLabel, "prometheus label with the pod name of the agent")
+	flag.Parse()
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "dashboard-gen",
+		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	store := storage.NewRedisSynHeartStore(storage.SynHeartStoreConfig{
+		Type:       "redis",
+		BufferSize: 1000,
+		Address:    *storeAddr,
+	}, logger.Named("redis"))
+	defer store.Close()
+
+	summaries, err := store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	agents, err := store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// sort, so the dashboard doesn't change if the tests and agents haven't
+	tests := []common.SyntestConfigSummary{}
+	for _, summary := range summaries {
+		tests = append(tests, summary)
+	}
+	sort.Slice(tests, func(i, j int) bool { return tests[i].ConfigId < tests[j].ConfigId })
+	agentIds := []string{}
+	for agentId := range agents {
+		agentIds = append(agentIds, agentId)
+	}
+	sort.Strings(agentIds)
+
+	dashboard := grafana.GenerateDashboard(tests, agentIds, grafana.Options{
+		Title:      *title,
+		Uid:        *uid,
+		AgentLabel: *agentLabel,
+	})
+	b, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(append(b, '\n'))
+	} else {
+		err = os.WriteFile(*out, b, 0644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger.Info("generated dashboard", "tests", len(tests), "agents", len(agentIds))
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+// listAgents Lists the agents with their state (live, stale or pending) and last heartbeat
+func listAgents(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("agents", flag.ExitOnError)
+	state := fs.String("state", "", "only the agents in the state: live, stale or pending")
+	namespace := fs.String("n", "", "namespace of the agents (all if empty)")
+	parseFlags(fs, args)
+
+	agents, err := c.AgentsHealth(ctx, *state)
+	if err != nil {
+		return err
+	}
+	filtered := []client.AgentHealth{}
+	rows := [][]string{}
+	for _, agent := range agents {
+		if *namespace != "" && agent.Namespace != *namespace {
+			continue
+		}
+		filtered = append(filtered, agent)
+		heartbeat := "-"
+		if agent.LastHeartbeat != nil {
+			heartbeat = age(*agent.LastHeartbeat) + " ago"
+		}
+		rows = append(rows, []string{agent.Namespace, agent.PodName, agent.State, agent.NodeName, agent.Version,
+			strconv.Itoa(agent.TestCount), heartbeat})
+	}
+	return printOutput(filtered, []string{"NAMESPACE", "POD", "STATE", "NODE", "VERSION", "TESTS", "LAST HEARTBEAT"}, rows)
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+// ExecLabel is set on the temporary tests of exec, to find the ones left behind (e.g. with -keep)
+const ExecLabel = "synheart.io/exec"
+
+// execTest Runs a temporary test once on the selected agents through the rest api, and deletes it: to probe from specific
+// nodes during incidents. The test has no repeat, so the agents only run it when it's triggered.
+func execTest(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	namespace := fs.String("n", "", "namespace of the temporary test, the agents must run the tests of the namespace")
+	pluginName := fs.String("plugin", "", "name of the plugin (httpPing with -url)")
+	configFile := fs.String("config", "", "file with the config of the plugin, none if empty")
+	url := fs.String("url", "", "url to check with the httpPing plugin, instead of -plugin and -config")
+	agents := fs.String("agents", "", "comma separated ids of the agents to run the test on, all the agents on -node if empty")
+	node := fs.String("node", "*", "glob of the nodes of the agents, if there are no -agents")
+	timeout := fs.Duration("timeout", common.DefaultRunTimeout, "timeout of the run")
+	wait := fs.Duration("wait", 2*time.Minute, "how long to wait for the agents to run the test")
+	keep := fs.Bool("keep", false, "don't delete the test afterwards")
+	if args := parseFlags(fs, args); len(args) > 0 {
+		return fmt.Errorf("unexpected args %v", args)
+	}
+	if *namespace == "" {
+		return fmt.Errorf("no namespace, use -n")
+	}
+
+	spec := client.SynTestSpec{
+		Plugin:              *pluginName,
+		Node:                *node,
+		Description:         "ad-hoc test run by synheartctl exec",
+		Repeat:              "0s", // only run when triggered
+		Timeouts:            &proto.Timeouts{Run: timeout.String()},
+		PluginRestartPolicy: string(common.RestartNever),
+		Alerting:            &proto.Alerting{Disabled: true},
+	}
+	switch {
+	case *url != "" && (*pluginName != "" || *configFile != ""):
+		return fmt.Errorf("-url can't be used with -plugin or -config")
+	case *url != "":
+		spec.Plugin = "httpPing"
+		spec.Config = "address: " + *url + "\n"
+	case *pluginName == "":
+		return fmt.Errorf("no plugin, use -plugin or -url")
+	case *configFile != "":
+		b, err := os.ReadFile(filepath.Clean(*configFile))
+		if err != nil {
+			return fmt.Errorf("error reading plugin config: %w", err)
+		}
+		spec.Config = string(b)
+	}
+
+	b := make([]byte, 3)
+	_, err := rand.Read(b)
+	if err != nil {
+		return err
+	}
+	name := "exec-" + hex.EncodeToString(b)
+	// a test per agent, selecting it by id, so only the selected agents start the plugin
+	tests := map[string]client.SynTestSpec{name: spec}
+	if *agents != "" {
+		tests = map[string]client.SynTestSpec{}
+		for i, agent := range strings.Split(*agents, ",") {
+			agentSpec := spec
+			agentSpec.PodLabelSelector = map[string]string{common.SpecialKeyAgentId: strings.TrimSpace(agent)}
+			tests[fmt.Sprintf("%s-%d", name, i)] = agentSpec
+		}
+	}
+
+	for _, testName := range sortedKeys(tests) {
+		_, err := c.PutSynTest(ctx, testName, *namespace, client.SynTestRequest{
+			Labels:  map[string]string{ExecLabel: "true"},
+			Spec:    tests[testName],
+			Comment: "synheartctl exec",
+		})
+		if err != nil {
+			return err
+		}
+		if !*keep {
+			defer deleteExecTest(c, testName, *namespace)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "created test %s/%s, waiting for the agents to run it\n", name, *namespace)
+
+	deadline := time.Now().Add(*wait)
+	resp := client.TriggerResponse{}
+	results := map[string]*proto.TestRun{}
+	for _, testName := range sortedKeys(tests) {
+		triggered, err := triggerWhenRunning(ctx, c, testName, *namespace, deadline)
+		if err != nil {
+			return err
+		}
+		runResults, err := waitForRun(ctx, c, testName, *namespace, triggered, time.Until(deadline))
+		if err != nil {
+			return err
+		}
+		resp.Agents = append(resp.Agents, triggered.Agents...)
+		for agent, run := range runResults {
+			results[agent] = run
+		}
+	}
+
+	err = printRunResults(resp, results, *wait)
+	if output == "table" {
+		for _, agent := range sortedKeys(results) {
+			printDetails(agent, results[agent].GetTestResult().GetDetails())
+		}
+	}
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, run := range results {
+		if runPassRatio(run) < 1 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("test failed on %d agents", failed)
+	}
+	return nil
+}
+
+// triggerWhenRunning Triggers a run of a test as soon as agents run it (once they synced its config), until the deadline
+func triggerWhenRunning(ctx context.Context, c *client.Client, name string, namespace string,
+	deadline time.Time) (client.TriggerResponse, error) {
+	for {
+		resp, err := c.TriggerTest(ctx, name, namespace, client.TriggerRequest{Comment: "synheartctl exec"})
+		apiErr := &client.Error{}
+		if err == nil || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+			return resp, err
+		}
+		if time.Now().After(deadline) {
+			return resp, fmt.Errorf("no agent runs test %s/%s, check the agents run the tests of the namespace: %w", name, namespace, err)
+		}
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// deleteExecTest Deletes a temporary test of exec, even if the command was interrupted
+func deleteExecTest(c *client.Client, name string, namespace string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := c.DeleteSynTest(ctx, name, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to delete test %s/%s: %v\n", name, namespace, err)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// lintResult is the result of linting a SyntheticTest of a manifest
+type lintResult struct {
+	File      string   `json:"file"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// manifestTest is a SyntheticTest or ClusterSyntheticTest of a manifest
+type manifestTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              testspec.Spec          `json:"spec,omitempty"`
+	Status            map[string]interface{} `json:"status,omitempty"` // not linted
+}
+
+// manifestSuite is a SyntheticTestSuite of a manifest
+type manifestSuite struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              testspec.SuiteSpec     `json:"spec,omitempty"`
+	Status            map[string]interface{} `json:"status,omitempty"` // not linted
+}
+
+// lint Validates SyntheticTest manifests offline, with the same validation as the admission webhook of the controller
+// (except the quotas), so the configs can be checked in CI before they're applied
+func lint(_ context.Context, _ *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	plugins := fs.String("plugins", "", "comma separated names of the known plugins, discovered from -plugin-path if empty")
+	pluginPath := fs.String("plugin-path", DefaultPluginPath, "glob of the plugin files (test-<plugin name>), the plugins aren't checked if none are found")
+	strict := fs.Bool("strict", false, "fail on warnings too")
+	files := parseFlags(fs, args)
+	if len(files) == 0 {
+		return fmt.Errorf("no manifests, expected files or directories (- for stdin)")
+	}
+
+	knownPlugins, err := lintPlugins(*plugins, *pluginPath)
+	if err != nil {
+		return err
+	}
+	if knownPlugins == nil {
+		fmt.Fprintf(os.Stderr, "warning: no plugins found in %s, the plugins aren't checked (use -plugins or -plugin-path)\n", *pluginPath)
+	}
+
+	results := []lintResult{}
+	for _, file := range files {
+		r, err := lintPath(file, knownPlugins)
+		if err != nil {
+			return err
+		}
+		results = append(results, r...)
+	}
+
+	numErrs, numWarnings := 0, 0
+	rows := [][]string{}
+	for _, r := range results {
+		numErrs += len(r.Errors)
+		numWarnings += len(r.Warnings)
+		object := r.Kind + " " + r.Name
+		if r.Namespace != "" {
+			object += "/" + r.Namespace
+		}
+		for _, e := range r.Errors {
+			rows = append(rows, []string{r.File, object, "error", e})
+		}
+		for _, w := range r.Warnings {
+			rows = append(rows, []string{r.File, object, "warning", w})
+		}
+	}
+	if output == "json" || len(rows) > 0 {
+		err = printOutput(results, []string{"FILE", "OBJECT", "LEVEL", "MESSAGE"}, rows)
+		if err != nil {
+			return err
+		}
+	}
+	if output != "json" {
+		fmt.Fprintf(os.Stderr, "%d tests linted, %d errors, %d warnings\n", len(results), numErrs, numWarnings)
+	}
+	if numErrs > 0 || (*strict && numWarnings > 0) {
+		return fmt.Errorf("lint failed")
+	}
+	return nil
+}
+
+// lintPlugins Returns the known plugins, from the list if it isn't empty, otherwise discovered from the glob (nil if
+// none are found)
+func lintPlugins(list string, path string) (map[string]bool, error) {
+	plugins := map[string]bool{}
+	if list != "" {
+		for _, name := range strings.Split(list, ",") {
+			plugins[strings.TrimSpace(name)] = true
+		}
+		return plugins, nil
+	}
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering plugins from path %s: %w", path, err)
+	}
+	for _, file := range files {
+		if name, found := strings.CutPrefix(filepath.Base(file), "test-"); found {
+			plugins[name] = true
+		}
+	}
+	if len(plugins) == 0 {
+		return nil, nil
+	}
+	return plugins, nil
+}
+
+// lintPath Lints the manifests of a file, the yaml files of a directory (recursively), or stdin if the path is -
+func lintPath(path string, plugins map[string]bool) ([]lintResult, error) {
+	if path == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading stdin: %w", err)
+		}
+		return lintManifests("stdin", b, plugins), nil
+	}
+	results := []lintResult{}
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := filepath.Ext(file)
+		if d.IsDir() || (file != path && ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		b, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return err
+		}
+		results = append(results, lintManifests(file, b, plugins)...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifests: %w", err)
+	}
+	return results, nil
+}
+
+// lintManifests Lints the SyntheticTests, ClusterSyntheticTests and SyntheticTestSuites (each test once expanded) of
+// a multi-document yaml file, the other objects are ignored
+func lintManifests(file string, b []byte, plugins map[string]bool) []lintResult {
+	results := []lintResult{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return results
+		}
+		if err != nil {
+			return append(results, lintResult{File: file, Kind: "document", Name: fmt.Sprint(i), Errors: []string{err.Error()}})
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			results = append(results, lintResult{File: file, Kind: "document", Name: fmt.Sprint(i), Errors: []string{err.Error()}})
+			continue
+		}
+		if typeMeta.GroupVersionKind().Group != testspec.Group {
+			continue
+		}
+
+		switch typeMeta.Kind {
+		case "SyntheticTest", "ClusterSyntheticTest":
+			results = append(results, lintTest(file, doc, typeMeta, plugins))
+		case "SyntheticTestSuite":
+			results = append(results, lintSuite(file, doc, typeMeta, plugins)...)
+		}
+	}
+}
+
+// lintTest Decodes a test strictly (unknown fields are errors, like the schema of the CRD) and validates it
+func lintTest(file string, doc []byte, typeMeta metav1.TypeMeta, plugins map[string]bool) lintResult {
+	synTest := manifestTest{}
+	result := lintResult{File: file, Kind: typeMeta.Kind}
+	if err := yaml.UnmarshalStrict(doc, &synTest); err != nil {
+		result.Errors = []string{err.Error()}
+		// still validate the rest of the test if the error is only unknown fields
+		if err := yaml.Unmarshal(doc, &synTest); err != nil {
+			return result
+		}
+	}
+	result.Name, result.Namespace = synTest.Name, synTest.Namespace
+	if synTest.Name == "" {
+		result.Errors = append(result.Errors, field.Required(field.NewPath("metadata", "name"), "name is required").Error())
+	}
+	validateSpec(&result, &synTest.Spec, plugins)
+	return result
+}
+
+// lintSuite Decodes a suite strictly and lints each of its tests, as expanded by the controller
+func lintSuite(file string, doc []byte, typeMeta metav1.TypeMeta, plugins map[string]bool) []lintResult {
+	suite := manifestSuite{}
+	results := []lintResult{}
+	if err := yaml.UnmarshalStrict(doc, &suite); err != nil {
+		results = append(results, lintResult{File: file, Kind: typeMeta.Kind, Errors: []string{err.Error()}})
+		if err := yaml.Unmarshal(doc, &suite); err != nil {
+			return results
+		}
+		results[0].Name, results[0].Namespace = suite.Name, suite.Namespace
+	}
+	tests, err := suite.Spec.Expand()
+	if err != nil {
+		return append(results, lintResult{File: file, Kind: typeMeta.Kind, Name: suite.Name, Namespace: suite.Namespace, Errors: []string{err.Error()}})
+	}
+	for i := range tests {
+		test := &tests[i]
+		// named like the SyntheticTests generated by the controller
+		result := lintResult{File: file, Kind: typeMeta.Kind, Name: suite.Name + "-" + test.Name, Namespace: suite.Namespace}
+		validateSpec(&result, &test.Spec, plugins)
+		results = append(results, result)
+	}
+	return results
+}
+
+// validateSpec Fills in the defaults of the spec like the defaulting webhook, and adds the errors and warnings of the
+// validating webhook to the result
+func validateSpec(result *lintResult, spec *testspec.Spec, plugins map[string]bool) {
+	testspec.Default(spec)
+	warnings, errs := testspec.Validate(spec, plugins)
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+// Command line tool for the day-to-day operations of synthetic heart (listing the tests and agents, their latest
+// results, triggering runs, pausing tests...), talking to the rest api
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+const (
+	DefaultServer = "http://localhost:51230"
+	// ServerEnv and TokenEnv are the environment variables with the defaults of the -server and -token flags
+	ServerEnv = "SYNHEART_SERVER"
+	TokenEnv  = "SYNHEART_TOKEN"
+)
+
+// command is a subcommand of synheartctl, run with the args after its name
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *client.Client, args []string) error
+}
+
+var commands = map[string]command{
+	"tests":   {"list the tests and their status", listTests},
+	"agents":  {"list the agents and their health", listAgents},
+	"results": {"show the latest results of a test", showResults},
+	"tail":    {"print the results of the tests as they arrive", tail},
+	"trigger": {"run a test at once, and wait for its results", trigger},
+	"pause":   {"pause a test (managed through the rest api)", pause},
+	"resume":  {"resume a paused test (managed through the rest api)", resume},
+	"run":     {"run a test once with a plugin launched locally (no redis or kubernetes)", run},
+	"lint":    {"validate SyntheticTest manifests offline, like the admission webhook", lint},
+	"exec":    {"run a temporary test once on some agents, and delete it", execTest},
+	"metrics": {"print the prometheus metrics of the tests and their labels, or a grafana dashboard", metrics},
+}
+
+// output is the format of the output of the commands: table or json
+var output = "table"
+
+func main() {
+	server := flag.String("server", envOr(ServerEnv, DefaultServer), "url of the rest api (env "+ServerEnv+")")
+	token := flag.String("token", os.Getenv(TokenEnv), "bearer token, if the rest api has authentication enabled (env "+TokenEnv+")")
+	flag.StringVar(&output, "o", output, "output format: table or json")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	if output != "table" && output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid output %q, must be table or json\n", output)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := cmd.run(ctx, client.NewClient(*server, *token), flag.Args()[1:])
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: synheartctl [flags] <command> [command flags] [args]")
+	fmt.Fprintln(out, "\nCommands:")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-9s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\nRun synheartctl <command> -h for the flags of a command.")
+}
+
+// parseFlags Parses the flags of a command, which can be before or after its args (unlike the flag package), and
+// returns the args
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	positional := []string{}
+	for {
+		_ = fs.Parse(args) // the flag sets exit on error
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// testArg Returns the name and namespace of the test of a command, from its only arg (<name> with the namespace flag,
+// or <name>/<namespace>)
+func testArg(args []string, namespace string) (string, string, error) {
+	if len(args) != 1 {
+		return "", "", fmt.Errorf("expected the name of a test, got %d args", len(args))
+	}
+	name, ns, found := strings.Cut(args[0], "/")
+	if found {
+		namespace = ns
+	}
+	if namespace == "" {
+		return "", "", fmt.Errorf("no namespace of the test %s, use -n or <name>/<namespace>", name)
+	}
+	return name, namespace, nil
+}
+
+// printOutput Prints v as json if the output is json, otherwise the rows as a table
+func printOutput(v interface{}, header []string, rows [][]string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// status Returns whether a pass ratio is passing or failing
+func status(passRatio float64) string {
+	if passRatio < 1 {
+		return "failing"
+	}
+	return "passing"
+}
+
+// age Returns the time since t, rounded for display
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < time.Hour:
+		return d.Round(time.Minute).String()
+	default:
+		return d.Round(time.Hour).String()
+	}
+}
+
+// sortedKeys Returns the keys of a map, sorted
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func envOr(key string, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
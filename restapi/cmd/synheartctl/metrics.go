@@ -0,0 +1,144 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/cisco-open/synthetic-heart/restapi/grafana"
+)
+
+// metrics Prints the prometheus metrics exported for the tests (matching a label selector) with their labels and a
+// query of each, or a grafana dashboard with panels for them
+func metrics(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ExitOnError)
+	selector := fs.String("l", "", "label selector of the tests, e.g. team=payments")
+	namespace := fs.String("n", "", "namespace of the tests (all if empty)")
+	dashboard := fs.Bool("dashboard", false, "print a grafana dashboard (json) with panels for the tests and agents instead")
+	title := fs.String("title", grafana.DefaultTitle, "title of the dashboard")
+	uid := fs.String("uid", grafana.DefaultUid, "uid of the dashboard")
+	agentLabel := fs.String("agent-label", grafana.DefaultAgentLabel, "label of the agent pod name added by the prometheus scrape config")
+	args = parseFlags(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most the name of a test, got %d args", len(args))
+	}
+	name := ""
+	if len(args) == 1 {
+		var ns string
+		var found bool
+		name, ns, found = strings.Cut(args[0], "/")
+		if found {
+			*namespace = ns
+		}
+	}
+
+	tests, err := c.SynTests(ctx, *selector, *namespace)
+	if err != nil {
+		return err
+	}
+	all := []grafana.TestMetrics{}
+	for _, test := range tests {
+		if name != "" && test.Name != name {
+			continue
+		}
+		m, err := testMetrics(ctx, c, test)
+		if err != nil {
+			return fmt.Errorf("error getting the metrics of the test %s: %w", test.ConfigId, err)
+		}
+		all = append(all, m)
+	}
+	if name != "" && len(all) == 0 {
+		return fmt.Errorf("no test %s", args[0])
+	}
+
+	if *dashboard {
+		return printDashboard(ctx, c, all, grafana.Options{Title: *title, Uid: *uid, AgentLabel: *agentLabel})
+	}
+	rows := [][]string{}
+	for _, m := range all {
+		for _, metric := range m.Metrics {
+			rows = append(rows, []string{m.Namespace, m.Name, metric.Name, metric.Type, metric.Source,
+				strings.Join(metric.Labels, ","), metric.Query})
+		}
+	}
+	return printOutput(all, []string{"NAMESPACE", "TEST", "METRIC", "TYPE", "SOURCE", "LABELS", "QUERY"}, rows)
+}
+
+// testMetrics Returns the metrics of a test, with the custom metrics in its latest run on an agent (if it has run)
+func testMetrics(ctx context.Context, c *client.Client, test client.TestStatus) (grafana.TestMetrics, error) {
+	config, err := c.TestConfig(ctx, test.ConfigId)
+	if err != nil {
+		return grafana.TestMetrics{}, err
+	}
+	testConfig := proto.SynTestConfig{}
+	err = json.Unmarshal(config.TestConfig, &testConfig)
+	if err != nil {
+		return grafana.TestMetrics{}, fmt.Errorf("error decoding the config of the test: %w", err)
+	}
+
+	status, _, err := c.TestRunStatus(ctx, client.ListOptions{Test: test.Name, Namespace: test.Namespace})
+	if err != nil {
+		return grafana.TestMetrics{}, err
+	}
+	var run *proto.TestRun
+	for _, pluginId := range sortedKeys(status) {
+		run, err = c.LatestTestRun(ctx, pluginId)
+		if err != nil {
+			return grafana.TestMetrics{}, err
+		}
+		if _, ok := run.GetTestResult().GetDetails()[common.PrometheusKey]; ok {
+			break // the agents run the same plugin, so the custom metrics of one are enough
+		}
+	}
+	return grafana.MetricsOfTest(test.ConfigId, &testConfig, run)
+}
+
+// printDashboard Prints a grafana dashboard with panels for the tests and the agents
+func printDashboard(ctx context.Context, c *client.Client, tests []grafana.TestMetrics, opts grafana.Options) error {
+	summaries, err := c.TestConfigSummaries(ctx)
+	if err != nil {
+		return err
+	}
+	agents, err := c.AgentsHealth(ctx, "")
+	if err != nil {
+		return err
+	}
+	opts.Metrics = map[string]grafana.TestMetrics{}
+	selected := []common.SyntestConfigSummary{}
+	for _, m := range tests {
+		if summary, ok := summaries[m.ConfigId]; ok {
+			selected = append(selected, summary)
+			opts.Metrics[m.ConfigId] = m
+		}
+	}
+	agentIds := []string{}
+	for _, agent := range agents {
+		agentIds = append(agentIds, agent.Id)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(grafana.GenerateDashboard(selected, agentIds, opts))
+}
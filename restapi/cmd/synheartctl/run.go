@@ -0,0 +1,250 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultPluginPath is where the plugins are discovered by default, where the agent Makefile builds them (from the
+// root of the repo)
+const DefaultPluginPath = "./agent/bin/plugins/*"
+
+// run Runs a test once with a plugin launched locally (without redis or kubernetes), like an agent would, and prints
+// the result: for developing plugins
+func run(ctx context.Context, _ *client.Client, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	pluginName := fs.String("plugin", "", "name of the plugin, e.g. httpPing")
+	configFile := fs.String("config", "", "file with the config of the plugin (the config of the test spec), none if empty")
+	pluginPath := fs.String("plugin-path", DefaultPluginPath, "glob of the plugin files (test-<plugin name>)")
+	pluginCmd := fs.String("cmd", "", "command running the plugin file (e.g. python3), the file is run itself if empty")
+	timeout := fs.Duration("timeout", common.DefaultRunTimeout, "timeout of the run (and of the init and finish)")
+	logs := fs.Bool("logs", false, "print the logs of the plugin even if the test passes")
+	if args := parseFlags(fs, args); len(args) > 0 {
+		return fmt.Errorf("unexpected args %v", args)
+	}
+	if *pluginName == "" {
+		return fmt.Errorf("no plugin, use -plugin")
+	}
+
+	cmd, err := discoverPlugin(*pluginName, *pluginPath, *pluginCmd)
+	if err != nil {
+		return err
+	}
+	config := proto.SynTestConfig{
+		Name:       "local",
+		Namespace:  "local",
+		PluginName: *pluginName,
+		Repeat:     common.DefaultRepeat.String(),
+		Timeouts: &proto.Timeouts{
+			Init:   timeout.String(),
+			Run:    timeout.String(),
+			Finish: timeout.String(),
+		},
+	}
+	if *configFile != "" {
+		b, err := os.ReadFile(filepath.Clean(*configFile))
+		if err != nil {
+			return fmt.Errorf("error reading plugin config: %w", err)
+		}
+		config.Config = string(b)
+	}
+
+	pluginLogs := &syncBuffer{}
+	testRun, err := runPlugin(ctx, cmd, &config, *timeout, pluginLogs)
+	if err != nil {
+		if pluginLogs.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "--- PLUGIN LOGS ---\n%s", pluginLogs.String())
+		}
+		return err
+	}
+	passed := runPassRatio(testRun) == 1
+	if output == "json" {
+		b, err := protojson.MarshalOptions{Multiline: true}.Marshal(testRun)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else {
+		printTestRun(testRun)
+	}
+	if (*logs || !passed) && pluginLogs.Len() > 0 {
+		fmt.Fprintf(os.Stderr, "\n--- PLUGIN LOGS ---\n%s", pluginLogs.String())
+	}
+	if !passed {
+		return fmt.Errorf("test failed")
+	}
+	return nil
+}
+
+// discoverPlugin Returns the command running a plugin, found in the files of the glob (same as the plugin discovery
+// of the agent)
+func discoverPlugin(name string, path string, cmd string) ([]string, error) {
+	files, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering plugins from path %s: %w", path, err)
+	}
+	for _, file := range files {
+		if filepath.Base(file) != "test-"+name {
+			continue
+		}
+		if cmd != "" {
+			return []string{cmd, file}, nil
+		}
+		if !filepath.IsAbs(file) && !strings.HasPrefix(file, "./") {
+			file = "./" + file
+		}
+		return []string{file}, nil
+	}
+	return nil, fmt.Errorf("plugin %s not found in %s (file test-%s), build it or set -plugin-path", name, path, name)
+}
+
+// runPlugin Launches the plugin, initialises it with the config, performs one test run and finishes it
+func runPlugin(ctx context.Context, cmd []string, config *proto.SynTestConfig, timeout time.Duration,
+	pluginLogs *syncBuffer) (*proto.TestRun, error) {
+	pluginClient := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  common.DefaultTestPluginHandshakeConfig,
+		Plugins:          map[string]plugin.Plugin{config.PluginName: &common.SynTestGRPCPlugin{}},
+		Cmd:              exec.Command(cmd[0], cmd[1:]...),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger:           hclog.New(&hclog.LoggerOptions{Level: hclog.Off}),
+		Stderr:           pluginLogs,
+	})
+	defer pluginClient.Kill()
+	rpcClient, err := pluginClient.Client()
+	if err != nil {
+		return nil, fmt.Errorf("error launching plugin: %w", err)
+	}
+	raw, err := rpcClient.Dispense(config.PluginName)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to plugin: %w", err)
+	}
+	synTest := raw.(common.SynTestPlugin)
+
+	err = withTimeout(ctx, timeout, func() error { return synTest.Initialise(*config) })
+	if err != nil {
+		return nil, fmt.Errorf("error initialising plugin: %w", err)
+	}
+	trigger := proto.Trigger{TriggerType: common.TriggerTypeRun, Details: "synheartctl"}
+	failed := common.FailedTestResult()
+	testRun := &proto.TestRun{
+		Id:         "local",
+		AgentId:    "local",
+		StartTime:  time.Now().Format(common.TimeFormat),
+		TestConfig: config,
+		Trigger:    &trigger,
+		TestResult: &failed,
+	}
+	err = withTimeout(ctx, timeout, func() error {
+		result, err := synTest.PerformTest(trigger)
+		if err == nil {
+			testRun.TestResult = &result
+		}
+		return err
+	})
+	testRun.EndTime = time.Now().Format(common.TimeFormat)
+	if err != nil {
+		testRun.TestResult = &failed
+		testRun.Details = map[string]string{common.ErrorKey: err.Error()}
+	}
+	err = withTimeout(ctx, timeout, synTest.Finish)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: error finishing plugin:", err)
+	}
+	return testRun, nil
+}
+
+// withTimeout Calls f, and returns an error if it doesn't return within the timeout (f keeps running, the plugin is
+// killed at the end)
+func withTimeout(ctx context.Context, timeout time.Duration, f func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- f()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// printTestRun Prints the result of a test run, its details sorted by key
+func printTestRun(testRun *proto.TestRun) {
+	passRatio := runPassRatio(testRun)
+	fmt.Printf("%s  %d/%d marks, pass ratio %.2f, runtime %s\n", strings.ToUpper(status(passRatio)),
+		testRun.TestResult.Marks, testRun.TestResult.MaxMarks, passRatio, runtime(runRuntime(testRun).Seconds()))
+	printDetails("details", testRun.TestResult.Details)
+	printDetails("run details", testRun.Details)
+}
+
+func printDetails(title string, details map[string]string) {
+	if len(details) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, k := range sortedKeys(details) {
+		v := strings.TrimRight(details[k], "\n")
+		if strings.Contains(v, "\n") { // e.g. yaml, indented under the key
+			fmt.Printf("  %s:\n    %s\n", k, strings.ReplaceAll(v, "\n", "\n    "))
+			continue
+		}
+		fmt.Printf("  %s: %s\n", k, v)
+	}
+}
+
+// syncBuffer is a buffer for the logs of the plugin, written while the plugin runs
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Len()
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
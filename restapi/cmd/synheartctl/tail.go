@@ -0,0 +1,110 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+// ANSI colors of the pass/fail status of the runs printed by tail
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+// tail Prints the runs of the tests on every agent as they finish, from the stream of the test runs of the rest api
+// (reconnecting when the stream times out), like stern for synthetic tests
+func tail(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	test := fs.String("test", "", "name of the test, or <name>/<namespace> (all tests if empty)")
+	namespace := fs.String("n", "", "namespace of the tests (all if empty)")
+	agent := fs.String("agent", "", "id of the agent, <pod name>/<namespace> (all agents if empty)")
+	failing := fs.Bool("failing", false, "only print the failed runs")
+	noColor := fs.Bool("no-color", os.Getenv("NO_COLOR") != "", "don't color the output (not colored if it isn't a terminal)")
+	if args := parseFlags(fs, args); len(args) > 0 {
+		return fmt.Errorf("unexpected args %v", args)
+	}
+
+	opts := client.ListOptions{Namespace: *namespace, Agent: *agent}
+	if name, ns, found := strings.Cut(*test, "/"); found {
+		opts.Test, opts.Namespace = name, ns
+	} else {
+		opts.Test = *test
+	}
+	if *failing {
+		opts.Status = "failing"
+	}
+	color := !*noColor && isTerminal(os.Stdout)
+	for ctx.Err() == nil {
+		err := c.StreamTestRuns(ctx, opts, 0, func(run client.TestRunEvent) error {
+			printTestRunEvent(run, color)
+			return nil
+		})
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second): // the stream timed out, reconnect
+		}
+	}
+	return nil
+}
+
+// printTestRunEvent Prints a test run with its status (colored), runtime and error, a line of json if the output is json
+func printTestRunEvent(run client.TestRunEvent, color bool) {
+	if output == "json" {
+		_ = json.NewEncoder(os.Stdout).Encode(run)
+		return
+	}
+	t := run.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	state := fmt.Sprintf("%-8s", status(run.PassRatio))
+	if color {
+		stateColor := colorGreen
+		if run.PassRatio < 1 {
+			stateColor = colorRed
+		}
+		state = stateColor + state + colorReset
+	}
+	line := fmt.Sprintf("%s  %s %s on %s  %s", t.Local().Format(time.TimeOnly), state, run.Test, run.Agent,
+		runtime(run.RuntimeSeconds))
+	if run.PassRatio > 0 && run.PassRatio < 1 {
+		line += fmt.Sprintf(" (pass ratio %.2f)", run.PassRatio)
+	}
+	if run.Error != "" {
+		line += "  " + strings.ReplaceAll(run.Error, "\n", " ")
+	}
+	fmt.Println(line)
+}
+
+// isTerminal Returns whether the file is a terminal (a character device)
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
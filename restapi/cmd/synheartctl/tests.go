@@ -0,0 +1,251 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+// listTests Lists the tests (matching a label selector) with the status of their latest runs
+func listTests(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("tests", flag.ExitOnError)
+	selector := fs.String("l", "", "label selector of the tests, e.g. team=payments")
+	namespace := fs.String("n", "", "namespace of the tests (all if empty)")
+	parseFlags(fs, args)
+
+	tests, err := c.SynTests(ctx, *selector, *namespace)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, test := range tests {
+		rows = append(rows, []string{test.Namespace, test.Name, test.Plugin, test.Status,
+			strconv.Itoa(test.PassingAgents), strconv.Itoa(len(test.FailingAgents))})
+	}
+	return printOutput(tests, []string{"NAMESPACE", "NAME", "PLUGIN", "STATUS", "PASSING", "FAILING"}, rows)
+}
+
+// showResults Shows the latest results of a test (on every agent, or one)
+func showResults(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("results", flag.ExitOnError)
+	namespace := fs.String("n", "", "namespace of the test")
+	agent := fs.String("agent", "", "id of the agent (<pod>/<namespace>), all the agents if empty")
+	since := fs.Duration("since", time.Hour, "how far back to look for results")
+	limit := fs.Int("limit", 20, "max number of results shown (the latest)")
+	name, ns, err := testArg(parseFlags(fs, args), *namespace)
+	if err != nil {
+		return err
+	}
+
+	results, _, err := c.TestResults(ctx, name, ns, client.ResultsQuery{Since: time.Now().Add(-*since), Agent: *agent})
+	if err != nil {
+		return err
+	}
+	if len(results.Results) > *limit {
+		results.Results = results.Results[len(results.Results)-*limit:]
+	}
+	rows := [][]string{}
+	for _, result := range results.Results {
+		rows = append(rows, []string{result.Time.Local().Format(time.DateTime), result.Agent, status(result.PassRatio),
+			strconv.FormatFloat(result.PassRatio, 'f', -1, 64), runtime(result.RuntimeSeconds)})
+	}
+	return printOutput(results.Results, []string{"TIME", "AGENT", "STATUS", "PASS RATIO", "RUNTIME"}, rows)
+}
+
+// trigger Runs a test at once and waits for the results of the agents
+func trigger(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("trigger", flag.ExitOnError)
+	namespace := fs.String("n", "", "namespace of the test")
+	agents := fs.String("agents", "", "comma separated ids of the agents to run the test on, all the agents running it if empty")
+	wait := fs.Duration("wait", time.Minute, "how long to wait for the results (0 doesn't wait)")
+	comment := fs.String("comment", "", "comment recorded in the audit log")
+	name, ns, err := testArg(parseFlags(fs, args), *namespace)
+	if err != nil {
+		return err
+	}
+
+	request := client.TriggerRequest{Comment: *comment}
+	if *agents != "" {
+		request.Agents = strings.Split(*agents, ",")
+	}
+	resp, err := c.TriggerTest(ctx, name, ns, request)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "triggered run %s on %d agents\n", resp.RunId, len(resp.Agents))
+	if *wait <= 0 {
+		return nil
+	}
+
+	results, err := waitForRun(ctx, c, name, ns, resp, *wait)
+	if err != nil {
+		return err
+	}
+	return printRunResults(resp, results, *wait)
+}
+
+// waitForRun Polls the results of a triggered run until all its agents reported them, or the wait is over
+func waitForRun(ctx context.Context, c *client.Client, name string, namespace string, resp client.TriggerResponse,
+	wait time.Duration) (map[string]*proto.TestRun, error) {
+	deadline := time.Now().Add(wait)
+	results := map[string]*proto.TestRun{}
+	var err error
+	for len(results) < len(resp.Agents) && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+		results, err = c.TriggeredRun(ctx, name, namespace, resp.RunId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// printRunResults Prints the results of a triggered run by agent, and returns an error if some agents haven't
+// reported them
+func printRunResults(resp client.TriggerResponse, results map[string]*proto.TestRun, wait time.Duration) error {
+	rows := [][]string{}
+	for _, agent := range resp.Agents {
+		run, ok := results[agent]
+		if !ok {
+			rows = append(rows, []string{agent, "pending", "-", "-"})
+			continue
+		}
+		passRatio := runPassRatio(run)
+		rows = append(rows, []string{agent, status(passRatio), strconv.FormatFloat(passRatio, 'f', -1, 64),
+			runtime(runRuntime(run).Seconds())})
+	}
+	err := printOutput(results, []string{"AGENT", "STATUS", "PASS RATIO", "RUNTIME"}, rows)
+	if err != nil {
+		return err
+	}
+	if len(results) < len(resp.Agents) {
+		return fmt.Errorf("%d agents haven't reported the results of the run after %s", len(resp.Agents)-len(results), wait)
+	}
+	return nil
+}
+
+func pause(ctx context.Context, c *client.Client, args []string) error {
+	return setPaused(ctx, c, "pause", args, true)
+}
+
+func resume(ctx context.Context, c *client.Client, args []string) error {
+	return setPaused(ctx, c, "resume", args, false)
+}
+
+// setPaused Pauses or resumes a test managed through the rest api, by writing its spec again with paused set. The tests
+// of the CRDs are paused with the synheart.io/paused annotation instead.
+func setPaused(ctx context.Context, c *client.Client, cmd string, args []string, paused bool) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	namespace := fs.String("n", "", "namespace of the test")
+	comment := fs.String("comment", "", "comment recorded in the audit log")
+	name, ns, err := testArg(parseFlags(fs, args), *namespace)
+	if err != nil {
+		return err
+	}
+
+	configId := common.ComputeSynTestConfigId(name, ns)
+	summaries, err := c.TestConfigSummaries(ctx)
+	if err != nil {
+		return err
+	}
+	summary, ok := summaries[configId]
+	if !ok {
+		return fmt.Errorf("no test %s", configId)
+	}
+	switch summary.Source {
+	case common.ConfigSourceAPI:
+	case "":
+		value := strconv.FormatBool(paused)
+		return fmt.Errorf("test %s is a SyntheticTest, %s it with: kubectl annotate -n %s synthetictest %s synheart.io/paused=%s --overwrite",
+			configId, cmd, ns, name, value)
+	default:
+		return fmt.Errorf("test %s isn't managed through the rest api (source: %s)", configId, summary.Source)
+	}
+
+	config, err := c.TestConfig(ctx, configId)
+	if err != nil {
+		return err
+	}
+	test := client.SynTestRequest{Comment: *comment}
+	err = json.Unmarshal([]byte(config.RawConfig), &test.Spec)
+	if err != nil {
+		return fmt.Errorf("error decoding the spec of the test: %w", err)
+	}
+	stored := struct {
+		Labels map[string]string `json:"labels"`
+	}{}
+	err = json.Unmarshal(config.TestConfig, &stored)
+	if err != nil {
+		return fmt.Errorf("error decoding the config of the test: %w", err)
+	}
+	test.Labels = stored.Labels
+	if test.Spec.Paused == paused {
+		fmt.Fprintf(os.Stderr, "test %s is already %sd\n", configId, cmd)
+		return nil
+	}
+	test.Spec.Paused = paused
+	_, err = c.PutSynTest(ctx, name, ns, test)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%sd test %s\n", cmd, configId)
+	return nil
+}
+
+// runPassRatio Returns the pass ratio of a test run
+func runPassRatio(run *proto.TestRun) float64 {
+	if run.TestResult == nil || run.TestResult.MaxMarks == 0 {
+		return 0
+	}
+	return float64(run.TestResult.Marks) / float64(run.TestResult.MaxMarks)
+}
+
+// runRuntime Returns how long a test run took, 0 if unknown
+func runRuntime(run *proto.TestRun) time.Duration {
+	start, err := time.Parse(common.TimeFormat, run.StartTime)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(common.TimeFormat, run.EndTime)
+	if err != nil || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// runtime Formats a runtime in seconds, - if unknown
+func runtime(seconds float64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond).String()
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/pkg/errors"
+)
+
+// CompareAgents Returns the comparison of the results of a test (in the namespace query param) across the agents
+// running it: which agents and nodes fail, the spread of the runtime, the details of the latest run on each agent and
+// which of them differ, and the stats of the runs between since and until (the last day by default)
+func (r *RestApi) CompareAgents(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	opts, err := parseListOptions(req.URL.Query())
+	if err == nil {
+		err = opts.defaultTimeRange()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to compare agents", http.StatusInternalServerError)
+		return
+	}
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching agent statuses", "err", err)
+		http.Error(w, "unable to compare agents", http.StatusInternalServerError)
+		return
+	}
+
+	comparison := client.AgentComparison{ConfigId: configId, Since: opts.Since, Until: opts.Until,
+		Agents: []client.AgentResult{}, FailingAgents: []string{}, FailingNodes: []string{}}
+	for pluginId, passRatio := range allStatus {
+		testName, testNs, agentPod, agentNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || common.ComputeSynTestConfigId(testName, testNs) != configId {
+			continue
+		}
+		res, err := r.agentResult(ctx, pluginId, common.ComputeAgentId(agentPod, agentNs), passRatio, opts)
+		if err != nil {
+			r.logger.Error("error fetching results of agent", "id", pluginId, "err", err)
+			http.Error(w, "unable to compare agents", http.StatusInternalServerError)
+			return
+		}
+		res.Node = agents[res.Agent].AgentConfig.RunTimeInfo.NodeName
+		comparison.Agents = append(comparison.Agents, res)
+	}
+	if len(comparison.Agents) == 0 {
+		http.Error(w, "no results of the test "+configId, http.StatusNotFound)
+		return
+	}
+	compareAgents(&comparison)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(comparison)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// agentResult Returns the latest result of the plugin of the test on the agent, and the stats of its runs between since
+// and until
+func (r *RestApi) agentResult(ctx context.Context, pluginId string, agentId string, passRatio string,
+	opts ListOptions) (client.AgentResult, error) {
+	res := client.AgentResult{Agent: agentId, Status: pluginStatus(passRatio)}
+	res.PassRatio, _ = strconv.ParseFloat(passRatio, 64)
+
+	run, err := r.store.FetchLatestTestRun(ctx, pluginId)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return res, errors.Wrap(err, "error fetching latest test run")
+	}
+	if err == nil {
+		if end, err := time.Parse(common.TimeFormat, run.EndTime); err == nil {
+			res.LatestRun = &end
+		}
+		for k, v := range run.GetTestResult().GetDetails() {
+			if k == common.LogKey || k == common.PrometheusKey {
+				continue
+			}
+			if res.Details == nil {
+				res.Details = map[string]string{}
+			}
+			res.Details[k] = v
+		}
+		res.Error = res.Details[common.ErrorKey]
+	}
+
+	history, err := r.store.FetchTestRunHistoryRange(ctx, pluginId, opts.Since, opts.Until)
+	if err != nil {
+		return res, errors.Wrap(err, "error fetching test run history")
+	}
+	passed := 0.0
+	runtimes := []float64{}
+	for _, h := range history {
+		res.Runs++
+		passed += h.PassRatio
+		if h.PassRatio < 1 {
+			res.Failures++
+			res.LastFailure = &h.Time
+		}
+		if h.Runtime > 0 {
+			runtimes = append(runtimes, h.Runtime.Seconds())
+		}
+	}
+	if res.Runs > 0 {
+		res.PassRate = passed / float64(res.Runs)
+	}
+	slices.Sort(runtimes)
+	res.RuntimeP50 = percentile(runtimes, 50)
+	res.RuntimeP90 = percentile(runtimes, 90)
+	return res, nil
+}
+
+// compareAgents Sorts the results of the agents (failing first) and sets the failing agents and nodes, the spread of the
+// runtime and the details which differ between the agents
+func compareAgents(comparison *client.AgentComparison) {
+	slices.SortFunc(comparison.Agents, func(a, b client.AgentResult) int {
+		if a.Status != b.Status {
+			if a.Status == StatusFailing {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Agent, b.Agent)
+	})
+
+	runtimes := []float64{}
+	keys := map[string]bool{}
+	for _, res := range comparison.Agents {
+		if res.Status == StatusFailing {
+			comparison.FailingAgents = append(comparison.FailingAgents, res.Agent)
+			if res.Node != "" && !slices.Contains(comparison.FailingNodes, res.Node) {
+				comparison.FailingNodes = append(comparison.FailingNodes, res.Node)
+			}
+		}
+		if res.RuntimeP50 > 0 {
+			runtimes = append(runtimes, res.RuntimeP50)
+			if res.RuntimeP50 > comparison.Runtime.Max {
+				comparison.Runtime.Max = res.RuntimeP50
+				comparison.Runtime.SlowestAgent = res.Agent
+			}
+		}
+		for k := range res.Details {
+			keys[k] = true
+		}
+	}
+	slices.Sort(comparison.FailingNodes)
+	slices.Sort(runtimes)
+	if len(runtimes) > 0 {
+		comparison.Runtime.Min = runtimes[0]
+		comparison.Runtime.Median = percentile(runtimes, 50)
+		for i, res := range comparison.Agents {
+			if res.RuntimeP50 > 0 {
+				comparison.Agents[i].RuntimeDeviation = res.RuntimeP50 / comparison.Runtime.Median
+			}
+		}
+	}
+
+	// only the agents with a latest run are compared, a detail differs if its value isn't the same on all of them
+	for k := range keys {
+		values := map[string]string{}
+		differs := false
+		first, seen := "", false
+		for _, res := range comparison.Agents {
+			if res.LatestRun == nil {
+				continue
+			}
+			v := res.Details[k]
+			values[res.Agent] = v
+			if !seen {
+				first, seen = v, true
+			} else if v != first {
+				differs = true
+			}
+		}
+		if !differs {
+			continue
+		}
+		if comparison.DifferingDetails == nil {
+			comparison.DifferingDetails = map[string]map[string]string{}
+		}
+		comparison.DifferingDetails[k] = values
+	}
+}
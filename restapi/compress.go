@@ -0,0 +1,155 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// DefaultCompressionMinSize is the min size of the responses compressed, if not configured (smaller responses aren't
+// worth the overhead)
+const DefaultCompressionMinSize = 1024
+
+// CompressionConfig configures the gzip compression of the responses (of the clients accepting it)
+type CompressionConfig struct {
+	// Disabled turns off the compression
+	Disabled bool `yaml:"disabled"`
+	// MinSize is the min size in bytes of the responses compressed (default 1024)
+	MinSize int `yaml:"minSize"`
+	// Level is the gzip compression level, 1 (fastest) to 9 (best), the default of gzip if not set
+	Level int `yaml:"level"`
+}
+
+// CompressionMiddleware Returns the middleware compressing the responses with gzip if the client accepts it, nil if the
+// compression is disabled. Responses already encoded by their handler (e.g. the exports) are left as they are.
+func CompressionMiddleware(config CompressionConfig) func(http.Handler) http.Handler {
+	if config.Disabled {
+		return nil
+	}
+	if config.MinSize <= 0 {
+		config.MinSize = DefaultCompressionMinSize
+	}
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodHead || !acceptsGzip(req) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			gw := &gzipResponseWriter{ResponseWriter: w, config: config, status: http.StatusOK}
+			defer gw.Close()
+			next.ServeHTTP(gw, req)
+		})
+	}
+}
+
+// acceptsGzip Returns whether the client accepts gzip encoded responses
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if (name == "gzip" || name == "*") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of the response till it's large enough to be compressed (or it's flushed)
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	config  CompressionConfig
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided {
+		return
+	}
+	w.status = status
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) >= w.config.MinSize {
+			if err := w.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide Writes the header and the buffered response, compressed if set and the handler didn't encode it itself
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	if compress && w.Header().Get("Content-Encoding") == "" {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.config.Level)
+		if err != nil {
+			gz = gzip.NewWriter(w.ResponseWriter)
+		}
+		w.gz = gz
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	_, err := w.Write(buf)
+	return err
+}
+
+// Flush Flushes the response (e.g. of a stream), which is compressed from then on
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close Writes the rest of the response, small responses are written uncompressed
+func (w *gzipResponseWriter) Close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// dashboardFiles is the web ui served at /dashboard/ (plain html, css and js, see the Dashboard section of the README)
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHandler Returns the handler serving the files of the dashboard under /dashboard/
+func dashboardHandler() (http.Handler, error) {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		return nil, err
+	}
+	fileServer := http.StripPrefix("/dashboard/", http.FileServer(http.FS(files)))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// the files change with the binary, so browsers must revalidate them after an upgrade
+		w.Header().Set("Cache-Control", "no-cache")
+		fileServer.ServeHTTP(w, req)
+	}), nil
+}
@@ -0,0 +1,399 @@
+/*
+ * Copyright 2024 Cisco Systems, Inc. and its affiliates
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Dashboard of the tests served by the rest api (see the Dashboard section of the README). It has no dependencies or
+// build step: the views are rendered from the graphql endpoint, and the history from the results endpoint.
+(function () {
+  "use strict";
+
+  // the dashboard is served at <rest api>/dashboard/, so the api is relative to it
+  const API = "../api/v1";
+  const TOKEN_KEY = "synheart-token";
+  const REFRESH_INTERVAL = 30000;
+  const SPARKLINE_CONCURRENCY = 4;
+
+  const main = document.getElementById("main");
+  let refreshTimer = null;
+
+  // --- api ---
+
+  async function request(path, options) {
+    options = options || {};
+    const headers = Object.assign({}, options.headers);
+    const token = localStorage.getItem(TOKEN_KEY);
+    if (token) {
+      headers["Authorization"] = "Bearer " + token;
+    }
+    const resp = await fetch(API + path, Object.assign({}, options, {headers: headers}));
+    if (resp.status === 401) {
+      throw new Error("the rest api requires a token, set it at the top right");
+    }
+    if (!resp.ok) {
+      throw new Error((await resp.text()).trim() || resp.statusText);
+    }
+    return resp;
+  }
+
+  async function graphql(query, variables) {
+    const resp = await request("/graphql", {
+      method: "POST",
+      headers: {"Content-Type": "application/json"},
+      body: JSON.stringify({query: query, variables: variables || {}}),
+    });
+    const body = await resp.json();
+    if (body.errors && body.errors.length > 0 && !body.data) {
+      throw new Error(body.errors.map((e) => e.message).join("; "));
+    }
+    return body.data;
+  }
+
+  // hourly pass rate of a test over the last day
+  async function history(name, namespace, agent) {
+    const params = new URLSearchParams({namespace: namespace, bucket: "1h"});
+    if (agent) {
+      params.set("agent", agent);
+    }
+    const resp = await request("/syntests/" + encodeURIComponent(name) + "/results?" + params.toString());
+    return (await resp.json()).buckets || [];
+  }
+
+  // --- rendering helpers ---
+
+  function esc(s) {
+    return String(s === null || s === undefined ? "" : s)
+      .replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;")
+      .replace(/"/g, "&quot;").replace(/'/g, "&#39;");
+  }
+
+  function status(s) {
+    const cls = s === "passing" || s === "failing" ? s : "unknown";
+    return '<span class="status ' + cls + '">' + esc(s || "unknown") + "</span>";
+  }
+
+  function time(t) {
+    if (!t) {
+      return '<span class="muted">-</span>';
+    }
+    const d = new Date(t);
+    return isNaN(d) ? esc(t) : '<span title="' + esc(d.toISOString()) + '">' + esc(d.toLocaleString()) + "</span>";
+  }
+
+  function testLink(test) {
+    return '<a href="#/test/' + encodeURIComponent(test.namespace) + "/" + encodeURIComponent(test.name) + '">' +
+      esc(test.displayName || test.name) + "</a>";
+  }
+
+  function runLink(namespace, name, agentId, label) {
+    return '<a href="#/run/' + encodeURIComponent(namespace) + "/" + encodeURIComponent(name) + "/" +
+      encodeURIComponent(agentId) + '">' + label + "</a>";
+  }
+
+  // sparkline Returns an svg line of the pass rate of the buckets (gaps for the buckets without runs)
+  function sparkline(buckets, width, height) {
+    if (buckets.length === 0) {
+      return '<span class="muted">no runs</span>';
+    }
+    const step = buckets.length > 1 ? width / (buckets.length - 1) : 0;
+    const segments = [];
+    let segment = [];
+    let last = null;
+    buckets.forEach((b, i) => {
+      if (b.runs === 0) {
+        if (segment.length > 0) {
+          segments.push(segment);
+        }
+        segment = [];
+        return;
+      }
+      last = b;
+      const y = 2 + (1 - b.passRate) * (height - 4);
+      segment.push((i * step).toFixed(1) + "," + y.toFixed(1));
+    });
+    if (segment.length > 0) {
+      segments.push(segment);
+    }
+    const color = last && last.passRate >= 1 ? "var(--passing)" : "var(--failing)";
+    const lines = segments.map((s) => {
+      if (s.length === 1) {
+        const [x, y] = s[0].split(",");
+        return '<circle cx="' + x + '" cy="' + y + '" r="1.5" fill="' + color + '"/>';
+      }
+      return '<polyline points="' + s.join(" ") + '" fill="none" stroke="' + color + '" stroke-width="1.5"/>';
+    });
+    return '<svg class="sparkline" width="' + width + '" height="' + height + '" viewBox="0 0 ' + width + " " +
+      height + '"><title>pass rate over the last day</title>' + lines.join("") + "</svg>";
+  }
+
+  // loadSparklines Renders the sparklines of the elements with a data-test attribute, a few at a time
+  function loadSparklines(root) {
+    const queue = Array.from(root.querySelectorAll("[data-test]"));
+    const next = async () => {
+      const el = queue.shift();
+      if (!el) {
+        return;
+      }
+      try {
+        const buckets = await history(el.dataset.test, el.dataset.namespace, el.dataset.agent);
+        el.innerHTML = sparkline(buckets, Number(el.dataset.width || 120), Number(el.dataset.height || 24));
+      } catch (e) {
+        el.innerHTML = '<span class="error" title="' + esc(e.message) + '">error</span>';
+      }
+      await next();
+    };
+    for (let i = 0; i < SPARKLINE_CONCURRENCY; i++) {
+      next();
+    }
+  }
+
+  function showError(e) {
+    main.innerHTML = '<p class="error">' + esc(e.message) + "</p>";
+  }
+
+  // --- views ---
+
+  // testsView Lists the tests with their status, the agents they pass on and their history
+  async function testsView(params) {
+    const data = await graphql(`{
+      tests { id name namespace displayName plugin importance status plugins { status } }
+    }`);
+    const tests = data.tests || [];
+    const namespaces = Array.from(new Set(tests.map((t) => t.namespace))).sort();
+    const filter = {
+      namespace: params.get("namespace") || "",
+      status: params.get("status") || "",
+      q: (params.get("q") || "").toLowerCase(),
+    };
+    const shown = tests.filter((t) =>
+      (!filter.namespace || t.namespace === filter.namespace) &&
+      (!filter.status || (t.status || "unknown") === filter.status) &&
+      (!filter.q || (t.name + " " + (t.displayName || "") + " " + t.plugin).toLowerCase().includes(filter.q)));
+    shown.sort((a, b) => (a.status === "failing" ? 0 : 1) - (b.status === "failing" ? 0 : 1) || a.id.localeCompare(b.id));
+    const failing = tests.filter((t) => t.status === "failing").length;
+
+    main.innerHTML =
+      "<h2>Tests <span class=\"muted\">(" + tests.length + ", " + failing + " failing)</span></h2>" +
+      '<form class="filters" id="filters">' +
+      '<select name="namespace"><option value="">All namespaces</option>' +
+      namespaces.map((ns) => "<option" + (ns === filter.namespace ? " selected" : "") + ">" + esc(ns) + "</option>").join("") +
+      "</select>" +
+      '<select name="status"><option value="">Any status</option>' +
+      ["passing", "failing", "unknown"].map((s) => "<option" + (s === filter.status ? " selected" : "") + ">" + s + "</option>").join("") +
+      "</select>" +
+      '<input name="q" placeholder="Search" value="' + esc(filter.q) + '">' +
+      "</form>" +
+      "<table><thead><tr><th>Status</th><th>Test</th><th>Namespace</th><th>Plugin</th><th>Importance</th>" +
+      "<th>Agents passing</th><th>Last day</th></tr></thead><tbody>" +
+      shown.map((t) => {
+        const passing = t.plugins.filter((p) => p.status === "passing").length;
+        return "<tr><td>" + status(t.status) + "</td><td>" + testLink(t) + "</td><td>" + esc(t.namespace) +
+          "</td><td>" + esc(t.plugin) + "</td><td>" + esc(t.importance) + "</td><td>" + passing + " / " +
+          t.plugins.length + '</td><td data-test="' + esc(t.name) + '" data-namespace="' + esc(t.namespace) +
+          '"><span class="muted">...</span></td></tr>';
+      }).join("") +
+      "</tbody></table>" +
+      (shown.length === 0 ? '<p class="muted">No tests</p>' : "");
+
+    const form = document.getElementById("filters");
+    form.addEventListener("change", () => applyFilters(form));
+    form.addEventListener("submit", (e) => {
+      e.preventDefault();
+      applyFilters(form);
+    });
+    loadSparklines(main);
+  }
+
+  function applyFilters(form) {
+    const params = new URLSearchParams();
+    new FormData(form).forEach((v, k) => {
+      if (v) {
+        params.set(k, v);
+      }
+    });
+    location.hash = "#/" + (params.toString() ? "?" + params.toString() : "");
+  }
+
+  // matrixView Shows the status of every test (rows) on every agent (columns)
+  async function matrixView() {
+    const data = await graphql(`{
+      agents { id nodeName version statusTime }
+      tests { id name namespace displayName plugins { agentId passRatio status } }
+    }`);
+    const agents = (data.agents || []).sort((a, b) => a.id.localeCompare(b.id));
+    const tests = (data.tests || []).sort((a, b) => a.id.localeCompare(b.id));
+    main.innerHTML =
+      "<h2>Agents <span class=\"muted\">(" + agents.length + " agents, " + tests.length + " tests)</span></h2>" +
+      '<table class="matrix"><thead><tr><th></th>' +
+      agents.map((a) => '<th class="agent" title="' + esc("node " + (a.nodeName || "?") + ", version " +
+        (a.version || "?") + ", last status " + (a.statusTime || "?")) + '">' + esc(a.id) + "</th>").join("") +
+      "</tr></thead><tbody>" +
+      tests.map((t) => {
+        const byAgent = {};
+        t.plugins.forEach((p) => { byAgent[p.agentId] = p; });
+        return "<tr><td>" + testLink(t) + "</td>" + agents.map((a) => {
+          const p = byAgent[a.id];
+          if (!p) {
+            return '<td class="cell none" title="not running on ' + esc(a.id) + '"></td>';
+          }
+          const cls = p.passRatio >= 1 ? "passing" : p.passRatio > 0 ? "partial" : "failing";
+          return '<td class="cell ' + cls + '" title="' + esc(t.id + " on " + a.id + ": pass ratio " + p.passRatio) +
+            '">' + runLink(t.namespace, t.name, a.id, "") + "</td>";
+        }).join("") + "</tr>";
+      }).join("") +
+      "</tbody></table>" +
+      (tests.length === 0 ? '<p class="muted">No tests</p>' : "");
+  }
+
+  // testView Shows a test with its status on every agent
+  async function testView(namespace, name) {
+    const data = await graphql(`query($name: String!, $namespace: String!) {
+      test(name: $name, namespace: $namespace) {
+        id name namespace displayName description plugin repeat importance version source status
+        plugins { agentId status passRatio agent { nodeName } latestRun { endTime error } }
+      }
+    }`, {name: name, namespace: namespace});
+    const t = data.test;
+    if (!t) {
+      main.innerHTML = '<p class="muted">Test ' + esc(name + "/" + namespace) + " not found</p>";
+      return;
+    }
+    main.innerHTML =
+      "<h2>" + status(t.status) + " " + esc(t.displayName || t.name) + "</h2>" +
+      "<dl>" +
+      "<dt>Id</dt><dd>" + esc(t.id) + "</dd>" +
+      (t.description ? "<dt>Description</dt><dd>" + esc(t.description) + "</dd>" : "") +
+      "<dt>Plugin</dt><dd>" + esc(t.plugin) + "</dd>" +
+      "<dt>Repeat</dt><dd>" + esc(t.repeat) + "</dd>" +
+      "<dt>Importance</dt><dd>" + esc(t.importance || "-") + "</dd>" +
+      "<dt>Version</dt><dd>" + esc(t.version) + (t.source ? " (" + esc(t.source) + ")" : "") + "</dd>" +
+      '<dt>Last day</dt><dd data-test="' + esc(t.name) + '" data-namespace="' + esc(t.namespace) +
+      '" data-width="480" data-height="48"></dd>' +
+      "</dl>" +
+      "<h2>Agents</h2>" +
+      "<table><thead><tr><th>Status</th><th>Agent</th><th>Node</th><th>Pass ratio</th><th>Latest run</th>" +
+      "<th>Error</th><th>Last day</th></tr></thead><tbody>" +
+      t.plugins.map((p) => "<tr><td>" + status(p.status) + "</td><td>" +
+        runLink(t.namespace, t.name, p.agentId, esc(p.agentId)) + "</td><td>" + esc(p.agent && p.agent.nodeName) +
+        "</td><td>" + esc(p.passRatio) + "</td><td>" + time(p.latestRun && p.latestRun.endTime) + "</td><td>" +
+        esc(p.latestRun && p.latestRun.error) + '</td><td data-test="' + esc(t.name) + '" data-namespace="' +
+        esc(t.namespace) + '" data-agent="' + esc(p.agentId) + '"></td></tr>').join("") +
+      "</tbody></table>";
+    loadSparklines(main);
+  }
+
+  // runView Shows the state of a test on an agent, with the details of its latest run and its last runs
+  async function runView(namespace, name, agentId) {
+    const data = await graphql(`query($name: String!, $namespace: String!, $agent: String) {
+      test(name: $name, namespace: $namespace) {
+        id name namespace displayName
+        plugins(agent: $agent) {
+          id status passRatio
+          state { status statusMsg restarts totalRestarts runningSince lastUpdated }
+          latestRun { id startTime endTime trigger marks maxMarks error details { key value } }
+          lastFailedRun { endTime error }
+          runs(limit: 20) { time status passRatio runtimeSeconds }
+        }
+      }
+    }`, {name: name, namespace: namespace, agent: agentId});
+    const t = data.test;
+    const p = t && t.plugins[0];
+    if (!p) {
+      main.innerHTML = '<p class="muted">No results of ' + esc(name + "/" + namespace) + " on " + esc(agentId) + "</p>";
+      return;
+    }
+    const run = p.latestRun || {};
+    const details = (run.details || []).filter((d) => d.key !== "_log" && d.key !== "_prometheus" && d.key !== "_error");
+    const log = (run.details || []).find((d) => d.key === "_log");
+    const state = p.state || {};
+    main.innerHTML =
+      "<h2>" + status(p.status) + " " + testLink(t) + " on " + esc(agentId) + "</h2>" +
+      "<dl>" +
+      "<dt>Plugin id</dt><dd>" + esc(p.id) + "</dd>" +
+      "<dt>Plugin state</dt><dd>" + esc(state.status || "-") + (state.statusMsg ? ": " + esc(state.statusMsg) : "") +
+      " (" + esc(state.restarts || 0) + " restarts, running since " + time(state.runningSince) + ")</dd>" +
+      "<dt>Latest run</dt><dd>" + esc(run.id) + ", " + time(run.startTime) + " to " + time(run.endTime) +
+      " (" + esc(run.trigger) + ")</dd>" +
+      "<dt>Marks</dt><dd>" + esc(run.marks) + " / " + esc(run.maxMarks) + "</dd>" +
+      (run.error ? '<dt>Error</dt><dd class="error">' + esc(run.error) + "</dd>" : "") +
+      details.map((d) => "<dt>" + esc(d.key) + "</dt><dd>" + esc(d.value) + "</dd>").join("") +
+      "<dt>Last failure</dt><dd>" + (p.lastFailedRun ? time(p.lastFailedRun.endTime) + " " +
+        esc(p.lastFailedRun.error) : '<span class="muted">-</span>') + "</dd>" +
+      "</dl>" +
+      (log ? "<h2>Logs of the latest run</h2><pre>" + esc(log.value) + "</pre>" : "") +
+      "<h2>Last runs</h2>" +
+      "<table><thead><tr><th>Status</th><th>Time</th><th>Pass ratio</th><th>Runtime</th></tr></thead><tbody>" +
+      p.runs.map((r) => "<tr><td>" + status(r.status) + "</td><td>" + time(r.time) + "</td><td>" +
+        esc(r.passRatio) + "</td><td>" + (r.runtimeSeconds ? esc(r.runtimeSeconds.toFixed(3)) + "s" : "-") +
+        "</td></tr>").join("") +
+      "</tbody></table>";
+  }
+
+  // --- routing ---
+
+  // route Renders the view of the location hash: #/ (tests), #/matrix, #/test/<namespace>/<name> or
+  // #/run/<namespace>/<name>/<agent id>
+  async function route() {
+    const hash = location.hash.replace(/^#\/?/, "");
+    const [path, query] = hash.split("?");
+    const parts = path.split("/").map(decodeURIComponent);
+    const params = new URLSearchParams(query || "");
+    const view = parts[0] || "tests";
+    document.querySelectorAll("header nav a").forEach((a) => {
+      a.classList.toggle("active", a.dataset.view === view || (view === "test" && a.dataset.view === "tests"));
+    });
+    try {
+      if (view === "matrix") {
+        await matrixView();
+      } else if (view === "test" && parts.length === 3) {
+        await testView(parts[1], parts[2]);
+      } else if (view === "run" && parts.length === 4) {
+        await runView(parts[1], parts[2], parts[3]);
+      } else {
+        await testsView(params);
+      }
+    } catch (e) {
+      showError(e);
+    }
+  }
+
+  // refresh Re-renders the list views periodically (not the detail views, so they don't jump while being read)
+  function scheduleRefresh() {
+    clearInterval(refreshTimer);
+    refreshTimer = setInterval(() => {
+      const view = location.hash.replace(/^#\/?/, "").split(/[/?]/)[0];
+      const typing = document.activeElement && document.activeElement.tagName === "INPUT";
+      if ((view === "" || view === "matrix") && !typing) {
+        route();
+      }
+    }, REFRESH_INTERVAL);
+  }
+
+  const tokenInput = document.getElementById("token");
+  tokenInput.value = localStorage.getItem(TOKEN_KEY) || "";
+  document.getElementById("token-form").addEventListener("submit", (e) => {
+    e.preventDefault();
+    if (tokenInput.value) {
+      localStorage.setItem(TOKEN_KEY, tokenInput.value);
+    } else {
+      localStorage.removeItem(TOKEN_KEY);
+    }
+    route();
+  });
+  window.addEventListener("hashchange", route);
+  route();
+  scheduleRefresh();
+})();
@@ -0,0 +1,273 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+// Dev mode of the rest api: runs an in-memory redis, an agent and the rest api in a single process, with sample tests,
+// to try synthetic heart on a laptop without kubernetes or redis
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/template"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cisco-open/synthetic-heart/agent/pluginmanager"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DevAddress    = "localhost:51230"
+	DevPluginPath = "./agent/bin/plugins/*" // where make build-agent builds the plugins, from the root of the repo
+	// DevAgentPodName and DevAgentNamespace are the pod name and namespace of the agent, if not set in the env
+	DevAgentPodName   = "synheart-agent-dev"
+	DevAgentNamespace = "synthetic-heart"
+)
+
+// devAgentConfig is the sample agent config of the dev mode
+//
+//go:embed dev/agent.yaml
+var devAgentConfig string
+
+// devSynTests are the sample tests of the dev mode
+//
+//go:embed dev/syntests.yaml
+var devSynTests string
+
+// devValues are the values of the templates of the agent config and the tests of the dev mode
+type devValues struct {
+	Server         string // url of the rest api
+	Address        string // host:port of the rest api
+	StorageAddress string // address of the in-memory redis
+	PluginPath     string // glob of the plugins of the agent
+	LabelFile      string // file with the pod labels of the agent
+}
+
+// devSynTest is a test created at the start of the dev mode
+type devSynTest struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	client.SynTestRequest
+}
+
+// runDev Runs the dev mode until it's interrupted
+func runDev(args []string) error {
+	fs := flag.NewFlagSet("dev", flag.ExitOnError)
+	address := fs.String("address", DevAddress, "address of the rest api")
+	storageAddress := fs.String("storage-address", "localhost:0", "address of the in-memory redis (a random port if 0)")
+	pluginPath := fs.String("plugin-path", DevPluginPath, "relative glob of the plugins of the agent")
+	agentConfig := fs.String("agent-config", "", "template of the agent config (the sample config if empty)")
+	tests := fs.String("tests", "", "template of the tests created at start: a yaml list of name, namespace, labels and spec (the sample tests if empty)")
+	noTests := fs.Bool("no-tests", false, "don't create any test at start")
+	_ = fs.Parse(args)
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "dev",
+		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
+	})
+
+	// the agent exits if it finds no plugins, so check them first
+	plugins, err := pluginmanager.DiscoverPlugins(common.PluginDiscoveryConfig{Path: *pluginPath}, pluginmanager.SyntestPrefix)
+	if err != nil {
+		return err
+	}
+	if len(plugins) == 0 {
+		return fmt.Errorf("no plugins found in %s, build them with make build-agent in ./agent and run the dev mode from the root of the repo", *pluginPath)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the in-memory redis is used by the rest api and the agent like the redis of a deployment
+	redis := miniredis.NewMiniRedis()
+	err = redis.StartAddr(*storageAddress)
+	if err != nil {
+		return errors.Wrap(err, "error starting the in-memory redis")
+	}
+	defer redis.Close()
+
+	restApi, err := NewRestApiFromConfig(RestApiConfig{
+		Address:                    *address,
+		StorageAddress:             redis.Addr(),
+		SynTestWrites:              true,
+		AllowUnauthenticatedWrites: true, // dev mode has no authentication
+	})
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", *address)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "synheart-dev-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	values := devValues{
+		Server:         "http://" + listener.Addr().String(),
+		Address:        listener.Addr().String(),
+		StorageAddress: redis.Addr(),
+		PluginPath:     *pluginPath,
+		LabelFile:      filepath.Join(dir, "labels"),
+	}
+
+	restApiDone := make(chan error, 1)
+	go func() {
+		restApiDone <- restApi.Serve(ctx, listener)
+	}()
+	if !*noTests {
+		err = createDevTests(ctx, logger, *tests, values, plugins)
+		if err != nil {
+			stop()
+			<-restApiDone
+			return err
+		}
+	}
+
+	agentId, agentConfigPath, err := setupDevAgent(dir, *agentConfig, values)
+	if err != nil {
+		stop()
+		<-restApiDone
+		return err
+	}
+	pm, err := pluginmanager.NewPluginManager(agentConfigPath)
+	if err != nil {
+		stop()
+		<-restApiDone
+		return err
+	}
+	agentDone := make(chan error, 1)
+	go func() {
+		agentDone <- pm.Start(ctx)
+	}()
+
+	logger.Info("synthetic heart running, press ctrl-c to stop", "restapi", values.Server,
+		"dashboard", values.Server+"/dashboard/", "redis", redis.Addr(), "agent", agentId)
+
+	// run until interrupted, or the rest api or the agent stops
+	var restApiErr, agentErr error
+	select {
+	case <-ctx.Done():
+		restApiErr, agentErr = <-restApiDone, <-agentDone
+	case restApiErr = <-restApiDone:
+		stop()
+		agentErr = <-agentDone
+	case agentErr = <-agentDone:
+		stop()
+		restApiErr = <-restApiDone
+	}
+	if restApiErr != nil {
+		return errors.Wrap(restApiErr, "error running the rest api")
+	}
+	if agentErr != nil {
+		return errors.Wrap(agentErr, "error running the agent")
+	}
+	return nil
+}
+
+// setupDevAgent Sets up what the agent gets from kubernetes (its node, pod and namespace in the env, and its pod labels
+// in a file) and writes its config, returns the id of the agent and the path of its config
+func setupDevAgent(dir string, configTemplate string, values devValues) (string, string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	env := map[string]string{"NODE_NAME": hostname, "POD_NAME": DevAgentPodName, "NAMESPACE": DevAgentNamespace}
+	for k, v := range env {
+		if os.Getenv(k) == "" {
+			_ = os.Setenv(k, v)
+		}
+	}
+	labels := fmt.Sprintf("%s=\"%s\"\n", common.K8sDiscoverLabel, common.K8sDiscoverLabelVal)
+	err = os.WriteFile(values.LabelFile, []byte(labels), 0o600)
+	if err != nil {
+		return "", "", errors.Wrap(err, "error writing the pod labels of the agent")
+	}
+
+	config, err := renderDevTemplate("agent config", configTemplate, devAgentConfig, values)
+	if err != nil {
+		return "", "", err
+	}
+	configPath := filepath.Join(dir, "agent.yaml")
+	err = os.WriteFile(configPath, config, 0o600)
+	if err != nil {
+		return "", "", errors.Wrap(err, "error writing the agent config")
+	}
+	return common.ComputeAgentId(os.Getenv("POD_NAME"), os.Getenv("NAMESPACE")), configPath, nil
+}
+
+// createDevTests Creates the tests through the rest api, except the ones of plugins the agent doesn't have
+func createDevTests(ctx context.Context, logger hclog.Logger, testsTemplate string, values devValues,
+	plugins map[string][]string) error {
+	b, err := renderDevTemplate("tests", testsTemplate, devSynTests, values)
+	if err != nil {
+		return err
+	}
+	tests := []devSynTest{}
+	err = yaml.UnmarshalStrict(b, &tests)
+	if err != nil {
+		return errors.Wrap(err, "error decoding the tests")
+	}
+	c := client.NewClient(values.Server, "")
+	for _, test := range tests {
+		if _, ok := plugins[test.Spec.Plugin]; !ok {
+			logger.Warn("plugin of the test not found, not creating it", "test", test.Name, "plugin", test.Spec.Plugin)
+			continue
+		}
+		test.Actor = "dev"
+		resp, err := c.PutSynTest(ctx, test.Name, test.Namespace, test.SynTestRequest)
+		if err != nil {
+			return errors.Wrap(err, "error creating the test "+test.Name)
+		}
+		logger.Info("created test", "test", resp.ConfigId, "plugin", test.Spec.Plugin)
+	}
+	return nil
+}
+
+// renderDevTemplate Renders a template of the dev mode, read from path (the default if empty)
+func renderDevTemplate(name string, path string, defaultTemplate string, values devValues) ([]byte, error) {
+	text := defaultTemplate
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading the "+name)
+		}
+		text = string(b)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing the "+name)
+	}
+	buf := bytes.Buffer{}
+	err = tmpl.Execute(&buf, values)
+	if err != nil {
+		return nil, errors.Wrap(err, "error rendering the "+name)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	gmux "github.com/gorilla/mux"
+)
+
+// ExportTimeout is how long an export of results can take, they're larger than the responses of the other endpoints
+const ExportTimeout = 5 * time.Minute
+
+// ExportTestResults Streams the results of a test (in the namespace query param) between since and until as csv or
+// ndjson (format query param), agent by agent, compressed with gzip if the client accepts it
+func (r *RestApi) ExportTestResults(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	opts, err := parseListOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = opts.defaultTimeRange()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = client.ExportFormatCSV
+	}
+	contentType := "text/csv"
+	switch format {
+	case client.ExportFormatCSV:
+	case client.ExportFormatNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		http.Error(w, "format must be "+client.ExportFormatCSV+" or "+client.ExportFormatNDJSON, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ExportTimeout)
+	defer cancel()
+	plugins, err := r.testPlugins(ctx, configId, opts.Agent)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to export results", http.StatusInternalServerError)
+		return
+	}
+	agents := []string{}
+	for agentId := range plugins {
+		agents = append(agents, agentId)
+	}
+	slices.Sort(agents)
+
+	name, namespace := gmux.Vars(req)["name"], req.URL.Query().Get("namespace")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+"_"+namespace+"_"+
+		opts.Since.UTC().Format("20060102T150405Z")+"."+format+`"`)
+	w.Header().Set("Vary", "Accept-Encoding")
+	var out io.Writer = w
+	if strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	// the headers are sent with the first results, errors after that can only be logged
+	writer, err := newResultWriter(out, format, name, namespace)
+	if err != nil {
+		r.logger.Warn("error writing export", "id", configId, "err", err)
+		return
+	}
+	exported := 0
+	for _, agentId := range agents {
+		history, err := r.store.FetchTestRunHistoryRange(ctx, plugins[agentId], opts.Since, opts.Until)
+		if err != nil {
+			r.logger.Error("error fetching test run history, export truncated", "id", plugins[agentId], "err", err)
+			return
+		}
+		for _, res := range history {
+			err = writer.write(testResult(agentId, res))
+			if err != nil {
+				r.logger.Warn("error writing export, export truncated", "id", configId, "err", err)
+				return
+			}
+		}
+		exported += len(history)
+	}
+	err = writer.flush()
+	if err != nil {
+		r.logger.Warn("error writing export", "id", configId, "err", err)
+		return
+	}
+	r.logger.Info("exported test results", "id", configId, "format", format, "results", exported)
+}
+
+// resultWriter writes the results of a test as csv (with a header) or ndjson
+type resultWriter struct {
+	test      string
+	namespace string
+	csv       *csv.Writer
+	json      *json.Encoder
+}
+
+// exportedResult is a line of an ndjson export
+type exportedResult struct {
+	Test      string `json:"test"`
+	Namespace string `json:"namespace"`
+	client.TestResult
+}
+
+var csvHeader = []string{"test", "namespace", "agent", "time", "passRatio", "runtimeSeconds"}
+
+// newResultWriter Returns a writer of the results in the format, the csv header is written at once
+func newResultWriter(out io.Writer, format string, test string, namespace string) (*resultWriter, error) {
+	w := &resultWriter{test: test, namespace: namespace}
+	if format == client.ExportFormatNDJSON {
+		w.json = json.NewEncoder(out)
+		return w, nil
+	}
+	w.csv = csv.NewWriter(out)
+	return w, w.csv.Write(csvHeader)
+}
+
+func (w *resultWriter) write(res client.TestResult) error {
+	if w.json != nil {
+		return w.json.Encode(exportedResult{Test: w.test, Namespace: w.namespace, TestResult: res})
+	}
+	return w.csv.Write([]string{
+		w.test,
+		w.namespace,
+		res.Agent,
+		res.Time.UTC().Format(time.RFC3339Nano),
+		strconv.FormatFloat(res.PassRatio, 'f', -1, 64),
+		strconv.FormatFloat(res.RuntimeSeconds, 'f', -1, 64),
+	})
+}
+
+// flush Writes the buffered results (csv)
+func (w *resultWriter) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
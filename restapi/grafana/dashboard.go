@@ -0,0 +1,255 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grafana
+
+// package to generate grafana dashboards for the registered syntests and agents
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cisco-open/synthetic-heart/common"
+)
+
+const (
+	DefaultTitle      = "Synthetic Heart"
+	DefaultUid        = "synthetic-heart"
+	DefaultAgentLabel = "pod"
+	DatasourceVar     = "${datasource}"
+	gridWidth         = 24
+	panelHeight       = 8
+)
+
+// Options of the generated dashboard
+type Options struct {
+	Title      string
+	Uid        string
+	AgentLabel string // label of the agent (pod name) added by the prometheus scrape config
+	// Metrics of the tests by config id (optional), adds panels for the slo and the custom metrics of the plugins
+	Metrics map[string]TestMetrics
+}
+
+// Dashboard is the (subset of the) grafana dashboard json model
+type Dashboard struct {
+	Uid           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Editable      bool       `json:"editable"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+type Variable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type Panel struct {
+	Id          int          `json:"id"`
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	GridPos     GridPos      `json:"gridPos"`
+	Datasource  *Datasource  `json:"datasource,omitempty"`
+	Targets     []Target     `json:"targets,omitempty"`
+	FieldConfig *FieldConfig `json:"fieldConfig,omitempty"`
+	Collapsed   bool         `json:"collapsed,omitempty"`
+}
+
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Datasource struct {
+	Type string `json:"type"`
+	Uid  string `json:"uid"`
+}
+
+type Target struct {
+	RefId        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+}
+
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+type FieldDefaults struct {
+	Unit string   `json:"unit,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// builder lays out the panels of the dashboard in rows
+type builder struct {
+	opts   Options
+	panels []Panel
+	x, y   int
+}
+
+// GenerateDashboard Generates a dashboard with an overview row, a row per test and a row per agent
+func GenerateDashboard(tests []common.SyntestConfigSummary, agentIds []string, opts Options) Dashboard {
+	if opts.Title == "" {
+		opts.Title = DefaultTitle
+	}
+	if opts.Uid == "" {
+		opts.Uid = DefaultUid
+	}
+	if opts.AgentLabel == "" {
+		opts.AgentLabel = DefaultAgentLabel
+	}
+	b := builder{opts: opts}
+
+	b.row("Overview")
+	b.panel("stat", "Cluster health score", "Pass ratio of all tests, weighted by importance",
+		ratioUnit(), target(common.MetricClusterHealthScore, ""))
+	b.panel("timeseries", "Health score by namespace", "Pass ratio of the tests in the namespace, weighted by importance",
+		ratioUnit(), target(common.MetricHealthScore, "{{namespace}}"))
+	b.panel("stat", "Failing tests", "Number of tests (on all agents) that did not get max marks in their latest run",
+		nil, target(fmt.Sprintf("count((%s < %s)) or vector(0)", common.MetricMarks, common.MetricMaxMarks), ""))
+
+	for _, test := range tests {
+		title := test.DisplayName
+		if title == "" {
+			title = test.Name
+		}
+		sel := fmt.Sprintf(`test_name="%s",test_namespace="%s"`, escape(test.Name), escape(test.Namespace))
+		b.row(fmt.Sprintf("Test: %s (%s/%s)", title, test.Namespace, test.Name))
+		b.panel("timeseries", "Pass ratio", test.Description, ratioUnit(),
+			target(fmt.Sprintf("%s{%s} / %s{%s}", common.MetricMarks, sel, common.MetricMaxMarks, sel), b.agentLegend()))
+		b.panel("timeseries", "Runtime", "Runtime of the latest test run", &FieldConfig{Defaults: FieldDefaults{Unit: "s"}},
+			target(fmt.Sprintf("%s{%s} / 1e9", common.MetricRuntime, sel), b.agentLegend()))
+		b.panel("timeseries", "Plugin restarts", "Restarts of the plugin running the test",
+			nil, target(fmt.Sprintf("increase(%s{%s}[$__rate_interval])", common.MetricPluginRestarts, sel), b.agentLegend()))
+		for _, metric := range opts.Metrics[test.ConfigId].Metrics {
+			switch {
+			case metric.Name == common.MetricSLOBudgetRemaining:
+				b.panel("timeseries", "SLO error budget remaining", metric.Help, &FieldConfig{Defaults: FieldDefaults{Unit: "percentunit"}},
+					target(fmt.Sprintf("%s{%s}", metric.Name, sel), ""))
+			case metric.Source == SourcePlugin:
+				legend := b.agentLegend()
+				for _, label := range metric.Labels {
+					if !slices.Contains(opts.Metrics[test.ConfigId].Labels, label) { // the labels of the plugin
+						legend += " {{" + label + "}}"
+					}
+				}
+				b.panel("timeseries", metric.Name, metric.Help, nil, target(metric.Query, legend))
+			}
+		}
+	}
+
+	for _, agentId := range agentIds {
+		pod := strings.SplitN(agentId, "/", 2)[0] // agent ids are usually podName/namespace
+		sel := fmt.Sprintf(`%s="%s"`, opts.AgentLabel, escape(pod))
+		b.row("Agent: " + agentId)
+		b.panel("timeseries", "Pass ratio by test", "", ratioUnit(),
+			target(fmt.Sprintf("%s{%s} / %s{%s}", common.MetricMarks, sel, common.MetricMaxMarks, sel), "{{test_namespace}}/{{test_name}}"))
+		b.panel("timeseries", "Running syntests", "Number of syntests with a running plugin",
+			nil, target(fmt.Sprintf("%s{%s}", common.MetricRunningSynTests, sel), ""))
+		b.panel("timeseries", "Broadcaster queue depth", "Test runs waiting to be processed by the exporters",
+			nil, target(fmt.Sprintf("%s{%s}", common.MetricBroadcasterQueue, sel), "{{queue}}"))
+	}
+
+	return Dashboard{
+		Uid:           opts.Uid,
+		Title:         opts.Title,
+		Tags:          []string{"synthetic-heart"},
+		Editable:      true,
+		SchemaVersion: 39,
+		Refresh:       "1m",
+		Time:          TimeRange{From: "now-6h", To: "now"},
+		Templating: Templating{List: []Variable{{
+			Name:  "datasource",
+			Label: "Datasource",
+			Type:  "datasource",
+			Query: "prometheus",
+		}}},
+		Panels: b.panels,
+	}
+}
+
+// row Adds a row panel, the following panels are placed under it
+func (b *builder) row(title string) {
+	if b.x > 0 {
+		b.y += panelHeight
+		b.x = 0
+	}
+	b.panels = append(b.panels, Panel{
+		Id:      len(b.panels) + 1,
+		Type:    "row",
+		Title:   title,
+		GridPos: GridPos{H: 1, W: gridWidth, X: 0, Y: b.y},
+	})
+	b.y += 1
+}
+
+// panel Adds a panel in the current row, three panels fit in a row
+func (b *builder) panel(panelType, title, description string, fieldConfig *FieldConfig, targets ...Target) {
+	w := gridWidth / 3
+	if b.x+w > gridWidth {
+		b.y += panelHeight
+		b.x = 0
+	}
+	b.panels = append(b.panels, Panel{
+		Id:          len(b.panels) + 1,
+		Type:        panelType,
+		Title:       title,
+		Description: description,
+		GridPos:     GridPos{H: panelHeight, W: w, X: b.x, Y: b.y},
+		Datasource:  &Datasource{Type: "prometheus", Uid: DatasourceVar},
+		Targets:     targets,
+		FieldConfig: fieldConfig,
+	})
+	b.x += w
+}
+
+func (b *builder) agentLegend() string {
+	return "{{" + b.opts.AgentLabel + "}}"
+}
+
+func target(expr string, legend string) Target {
+	return Target{RefId: "A", Expr: expr, LegendFormat: legend}
+}
+
+func ratioUnit() *FieldConfig {
+	min, max := 0.0, 1.0
+	return &FieldConfig{Defaults: FieldDefaults{Unit: "percentunit", Min: &min, Max: &max}}
+}
+
+// escape Escapes a label value for a promql string
+func escape(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val)
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grafana
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// invalidMetricNameRegex matches the chars the agents replace in the names of custom metrics
+var invalidMetricNameRegex = regexp.MustCompile(`[^a-zA-Z_][^a-zA-Z0-9_]*`)
+
+// Sources of the metrics of a test
+const (
+	SourceAgent      = "agent"      // exported by the agents running the test
+	SourceController = "controller" // exported by the controller
+	SourcePlugin     = "plugin"     // custom gauge reported by the plugin, exported by the agents
+)
+
+// TestMetrics are the prometheus metrics exported for a test
+type TestMetrics struct {
+	ConfigId  string `json:"configId"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Labels are the labels of the test on the metrics of the agents, the metric labels of the test are only added if
+	// they're in the testLabelKeys of the agents
+	Labels   []string `json:"labels"`
+	Selector string   `json:"selector"` // promql label matchers of the test
+	Metrics  []Metric `json:"metrics"`
+}
+
+// Metric is a prometheus metric of a test
+type Metric struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"` // gauge, counter or histogram
+	Source string `json:"source"`
+	Help   string `json:"help"`
+	// Labels of the metric, besides the labels of the agent config and of the prometheus scrape config (e.g. the pod)
+	Labels []string `json:"labels"`
+	Query  string   `json:"query"` // promql query of the metric for the test
+}
+
+// MetricsOfTest Returns the metrics exported for a test: the metrics of the agents, the slo metrics of the controller
+// (if the test has an slo) and the custom gauges of the plugin in a run of the test (nil if it hasn't run yet)
+func MetricsOfTest(configId string, test *proto.SynTestConfig, run *proto.TestRun) (TestMetrics, error) {
+	sel := fmt.Sprintf(`test_name="%s",test_namespace="%s"`, escape(test.Name), escape(test.Namespace))
+	m := TestMetrics{
+		ConfigId:  configId,
+		Name:      test.Name,
+		Namespace: test.Namespace,
+		Labels:    append([]string{"test_name", "test_namespace", "importance"}, sortedKeys(test.MetricLabels)...),
+		Selector:  sel,
+	}
+	testLabels := []string{"test_name", "test_namespace"} // labels of the self metrics of the agents and the controller
+
+	add := func(name string, metricType string, source string, help string, labels []string, query string) {
+		m.Metrics = append(m.Metrics, Metric{Name: name, Type: metricType, Source: source, Help: help,
+			Labels: labels, Query: query})
+	}
+	gauge := func(name string, source string, help string, labels []string) {
+		add(name, "gauge", source, help, labels, fmt.Sprintf("%s{%s}", name, sel))
+	}
+	gauge(common.MetricMarks, SourceAgent, "The marks obtained in the test", m.Labels)
+	gauge(common.MetricMaxMarks, SourceAgent, "The max marks in the test", m.Labels)
+	gauge(common.MetricRuntime, SourceAgent, "The runtime of the test in nano seconds", m.Labels)
+	add(common.MetricTestRuns, "counter", SourceAgent, "The number of test runs, by result (pass or fail)",
+		append(append([]string{}, m.Labels...), "result"),
+		fmt.Sprintf("sum by (result) (rate(%s{%s}[5m]))", common.MetricTestRuns, sel))
+	add(common.MetricRuntimeHist, "histogram", SourceAgent,
+		"The runtime of the test in seconds (classic and/or native buckets, depending on the agent config)", m.Labels,
+		fmt.Sprintf("histogram_quantile(0.95, sum by (le) (rate(%s_bucket{%s}[5m])))", common.MetricRuntimeHist, sel))
+	gauge(common.MetricSilenced, SourceAgent, "Whether notifications of the test are silenced (e.g. maintenance window)", m.Labels)
+	gauge(common.MetricFlapping, SourceAgent, "Whether the test is flapping (only if flap detection is enabled on the agents)", m.Labels)
+	add(common.MetricPluginRestarts, "counter", SourceAgent, "The number of times the plugin of the test was restarted",
+		testLabels, fmt.Sprintf("increase(%s{%s}[1h])", common.MetricPluginRestarts, sel))
+	if test.Slo != nil {
+		gauge(common.MetricSLOTarget, SourceController, "Availability target (0-1) of the test's SLO", testLabels)
+		gauge(common.MetricSLOAvailability, SourceController, "Availability (0-1) of the test in the SLO window", testLabels)
+		gauge(common.MetricSLOBudgetRemaining, SourceController,
+			"Ratio of the error budget left in the SLO window (negative once exhausted)", testLabels)
+		gauge(common.MetricSLOBurnRate, SourceController, "Rate at which the error budget is consumed over the window",
+			append(append([]string{}, testLabels...), "window"))
+	}
+
+	if run == nil {
+		return m, nil
+	}
+	promMetricsStr, ok := run.GetTestResult().GetDetails()[common.PrometheusKey]
+	if !ok {
+		return m, nil
+	}
+	promMetrics := common.PrometheusMetrics{}
+	err := yaml.Unmarshal([]byte(promMetricsStr), &promMetrics)
+	if err != nil {
+		return m, fmt.Errorf("error decoding the prometheus metrics of the test run: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, g := range promMetrics.Gauges {
+		name := CustomMetricName(g.Name)
+		if seen[name] {
+			continue // the same gauge with other label values
+		}
+		seen[name] = true
+		labels := []string{}
+		for _, k := range sortedKeys(g.Labels) {
+			if !slices.Contains(m.Labels, k) { // the agents override the labels of the test
+				labels = append(labels, k)
+			}
+		}
+		gauge(name, SourcePlugin, g.Help, append(labels, m.Labels...))
+	}
+	return m, nil
+}
+
+// CustomMetricName Returns the name of the metric of a custom gauge reported by a plugin, like the agents export it
+func CustomMetricName(gauge string) string {
+	return invalidMetricNameRegex.ReplaceAllString("syntheticheart_"+gauge, "_")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
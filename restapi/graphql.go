@@ -0,0 +1,700 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/cisco-open/synthetic-heart/restapi/graphql"
+	"github.com/pkg/errors"
+)
+
+// DefaultGraphqlRunsLimit is the number of runs of a test (or plugin) returned by default
+const DefaultGraphqlRunsLimit = 5
+
+// GraphqlMaxDepth is the maximum depth of the fields of a query, as tests and agents reference each other
+const GraphqlMaxDepth = 8
+
+// graphqlSchema is the schema of the graphql api, over the same data as the rest endpoints (tests, agents, test runs
+// and plugin states)
+var graphqlSchema = newGraphqlSchema()
+
+// Graphql Executes a graphql query, posted as json ({"query": ..., "variables": ..., "operationName": ...}) or in the
+// query params of a GET. Only the tests in the namespaces of the identity can be queried.
+func (r *RestApi) Graphql(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	gqlReq := graphql.Request{}
+	if req.Method == http.MethodPost {
+		err := json.NewDecoder(req.Body).Decode(&gqlReq)
+		if err != nil {
+			http.Error(w, "invalid graphql request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := req.URL.Query()
+		gqlReq.Query, gqlReq.OperationName = query.Get("query"), query.Get("operationName")
+		if v := query.Get("variables"); v != "" {
+			err := json.Unmarshal([]byte(v), &gqlReq.Variables)
+			if err != nil {
+				http.Error(w, "invalid graphql variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if gqlReq.Query == "" {
+		http.Error(w, "no graphql query provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ctx = context.WithValue(ctx, graphqlLoaderKey{}, &graphqlLoader{r: r, req: req})
+	resp := graphqlSchema.Execute(ctx, gqlReq)
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Data == nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+type graphqlLoaderKey struct{}
+
+// graphqlLoader fetches the data of a query from storage, the tests, agents and test run status are fetched once per
+// query (not per field), its lookups are recorded as the "graphql" cache
+type graphqlLoader struct {
+	r         *RestApi
+	req       *http.Request
+	summaries map[string]common.SyntestConfigSummary
+	status    map[string]string
+	agents    map[string]common.AgentStatus
+}
+
+func loaderFromContext(ctx context.Context) *graphqlLoader {
+	return ctx.Value(graphqlLoaderKey{}).(*graphqlLoader)
+}
+
+// gqlAgent is the source of an Agent, its status is nil if the agent isn't in storage anymore (e.g. for old runs)
+type gqlAgent struct {
+	id     string
+	status *common.AgentStatus
+}
+
+// gqlPlugin is the source of a Plugin, i.e. a test running on an agent
+type gqlPlugin struct {
+	id        string
+	configId  string
+	agentId   string
+	passRatio string
+}
+
+func (l *graphqlLoader) tests(ctx context.Context) (map[string]common.SyntestConfigSummary, error) {
+	observeCache("graphql", l.summaries != nil)
+	if l.summaries != nil {
+		return l.summaries, nil
+	}
+	summaries, err := l.r.store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		l.r.logger.Error("error fetching test config summaries", "err", err)
+		return nil, errors.New("unable to fetch tests")
+	}
+	l.summaries = map[string]common.SyntestConfigSummary{}
+	for configId, summary := range summaries {
+		if canAccessTest(l.req, configId) {
+			l.summaries[configId] = summary
+		}
+	}
+	return l.summaries, nil
+}
+
+func (l *graphqlLoader) test(ctx context.Context, configId string) (interface{}, error) {
+	tests, err := l.tests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if summary, ok := tests[configId]; ok {
+		return summary, nil
+	}
+	return nil, nil
+}
+
+func (l *graphqlLoader) testRunStatus(ctx context.Context) (map[string]string, error) {
+	observeCache("graphql", l.status != nil)
+	if l.status != nil {
+		return l.status, nil
+	}
+	status, err := l.r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		l.r.logger.Error("error fetching test run status", "err", err)
+		return nil, errors.New("unable to fetch test run status")
+	}
+	l.status = status
+	return l.status, nil
+}
+
+func (l *graphqlLoader) agentStatus(ctx context.Context) (map[string]common.AgentStatus, error) {
+	observeCache("graphql", l.agents != nil)
+	if l.agents != nil {
+		return l.agents, nil
+	}
+	agents, err := l.r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		l.r.logger.Error("error fetching agent statuses", "err", err)
+		return nil, errors.New("unable to fetch agents")
+	}
+	l.agents = agents
+	return l.agents, nil
+}
+
+// agent Returns the agent of the id, with its status if it's in storage
+func (l *graphqlLoader) agent(ctx context.Context, agentId string) (*gqlAgent, error) {
+	agents, err := l.agentStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	agent := &gqlAgent{id: agentId}
+	if status, ok := agents[agentId]; ok {
+		agent.status = &status
+	}
+	return agent, nil
+}
+
+// plugins Returns the plugins (with results) of the tests the identity can access, of a test (config id) and agent
+// if set, sorted by id
+func (l *graphqlLoader) plugins(ctx context.Context, configId string, agentId string) ([]gqlPlugin, error) {
+	allStatus, err := l.testRunStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plugins := []gqlPlugin{}
+	for pluginId, passRatio := range allStatus {
+		testName, testNs, agentPod, agentNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || !canAccessTest(l.req, pluginId) {
+			continue
+		}
+		plugin := gqlPlugin{
+			id:        pluginId,
+			configId:  common.ComputeSynTestConfigId(testName, testNs),
+			agentId:   common.ComputeAgentId(agentPod, agentNs),
+			passRatio: passRatio,
+		}
+		if (configId == "" || plugin.configId == configId) && (agentId == "" || plugin.agentId == agentId) {
+			plugins = append(plugins, plugin)
+		}
+	}
+	slices.SortFunc(plugins, func(a, b gqlPlugin) int { return strings.Compare(a.id, b.id) })
+	return plugins, nil
+}
+
+// runs Returns the results of the plugins from the test run history, newest first, filtered by the args (limit,
+// status, since and until, like the list options of the rest endpoints)
+func (l *graphqlLoader) runs(ctx context.Context, plugins []gqlPlugin, args map[string]interface{}) ([]client.TestResult, error) {
+	query := url.Values{}
+	for _, arg := range []string{"status", "since", "until"} {
+		if v, ok := args[arg].(string); ok {
+			query.Set(arg, v)
+		}
+	}
+	query.Set("limit", strconv.Itoa(args["limit"].(int)))
+	opts, err := parseListOptions(query)
+	if err == nil {
+		err = opts.defaultTimeRange()
+	}
+	if err != nil {
+		return nil, err
+	}
+	results := []client.TestResult{}
+	for _, plugin := range plugins {
+		history, err := l.r.store.FetchTestRunHistoryRange(ctx, plugin.id, opts.Since, opts.Until)
+		if err != nil {
+			l.r.logger.Error("error fetching test run history", "id", plugin.id, "err", err)
+			return nil, errors.New("unable to fetch test run history")
+		}
+		for _, res := range history {
+			if opts.Status == "" || opts.Status == resultStatus(res.PassRatio) {
+				results = append(results, testResult(plugin.agentId, res))
+			}
+		}
+	}
+	slices.SortFunc(results, func(a, b client.TestResult) int {
+		if c := b.Time.Compare(a.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Agent, b.Agent)
+	})
+	return results[:min(opts.Limit, len(results))], nil
+}
+
+// testRun Returns the latest (or last failed) run of the plugin, nil if it has none
+func (l *graphqlLoader) testRun(ctx context.Context, plugin gqlPlugin, lastFailed bool) (interface{}, error) {
+	fetch := l.r.store.FetchLatestTestRun
+	if lastFailed {
+		fetch = l.r.store.FetchLastFailedTestRun
+	}
+	run, err := fetch(ctx, plugin.id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		l.r.logger.Error("error fetching test run", "id", plugin.id, "err", err)
+		return nil, errors.New("unable to fetch test run")
+	}
+	return &run, nil
+}
+
+// pluginStatus Returns whether the latest run of the plugin passed or failed
+func pluginStatus(passRatio string) string {
+	ratio, err := strconv.ParseFloat(passRatio, 64)
+	if err != nil {
+		return StatusFailing
+	}
+	return resultStatus(ratio)
+}
+
+func resultStatus(passRatio float64) string {
+	if passRatio < 1 {
+		return StatusFailing
+	}
+	return StatusPassing
+}
+
+// testStatus Returns whether the latest runs of the test passed on all agents, nil if it has no results
+func testStatus(plugins []gqlPlugin) interface{} {
+	if len(plugins) == 0 {
+		return nil
+	}
+	for _, plugin := range plugins {
+		if pluginStatus(plugin.passRatio) == StatusFailing {
+			return StatusFailing
+		}
+	}
+	return StatusPassing
+}
+
+// gqlDetail is the source of a Detail, a key and value of the details of a test result
+type gqlDetail struct {
+	key   string
+	value string
+}
+
+// field Returns a field without arguments
+func field(t graphql.Type, resolve graphql.ResolveFunc) *graphql.Field {
+	return &graphql.Field{Type: t, Resolve: resolve}
+}
+
+// stringField Returns a String field of the source
+func stringField[T any](get func(src T) string) *graphql.Field {
+	return field(graphql.String, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(src.(T)), nil
+	})
+}
+
+// optionalString Returns the string, or nil if it's empty
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// runsArgs Returns the arguments of the runs of a test (filtered by agent) or plugin
+func runsArgs(agent bool) map[string]*graphql.Argument {
+	args := map[string]*graphql.Argument{
+		"limit":  {Type: graphql.Int, Default: DefaultGraphqlRunsLimit},
+		"status": {Type: graphql.String},
+		"since":  {Type: graphql.String},
+		"until":  {Type: graphql.String},
+	}
+	if agent {
+		args["agent"] = &graphql.Argument{Type: graphql.String}
+	}
+	return args
+}
+
+func newGraphqlSchema() *graphql.Schema {
+	testType := &graphql.Object{Name: "Test"}
+	agentType := &graphql.Object{Name: "Agent"}
+	pluginType := &graphql.Object{Name: "Plugin"}
+	resultType := &graphql.Object{Name: "Result"}
+	testRunType := &graphql.Object{Name: "TestRun"}
+	pluginStateType := &graphql.Object{Name: "PluginState"}
+	detailType := &graphql.Object{Name: "Detail"}
+
+	agentField := func(getId func(src interface{}) string) *graphql.Field {
+		return field(agentType, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return loaderFromContext(ctx).agent(ctx, getId(src))
+		})
+	}
+	testField := func(getId func(src interface{}) string) *graphql.Field {
+		return field(testType, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return loaderFromContext(ctx).test(ctx, getId(src))
+		})
+	}
+
+	testType.Fields = map[string]*graphql.Field{
+		"id":          stringField(func(t common.SyntestConfigSummary) string { return t.ConfigId }),
+		"name":        stringField(func(t common.SyntestConfigSummary) string { return t.Name }),
+		"namespace":   stringField(func(t common.SyntestConfigSummary) string { return t.Namespace }),
+		"displayName": stringField(func(t common.SyntestConfigSummary) string { return t.DisplayName }),
+		"description": stringField(func(t common.SyntestConfigSummary) string { return t.Description }),
+		"plugin":      stringField(func(t common.SyntestConfigSummary) string { return t.Plugin }),
+		"repeat":      stringField(func(t common.SyntestConfigSummary) string { return t.Repeat }),
+		"importance":  stringField(func(t common.SyntestConfigSummary) string { return t.Importance }),
+		"version":     stringField(func(t common.SyntestConfigSummary) string { return t.Version }),
+		"source":      stringField(configSource),
+		"status": field(graphql.String, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			plugins, err := loaderFromContext(ctx).plugins(ctx, src.(common.SyntestConfigSummary).ConfigId, "")
+			if err != nil {
+				return nil, err
+			}
+			return testStatus(plugins), nil
+		}),
+		"plugins": {
+			Type: &graphql.List{OfType: pluginType},
+			Args: map[string]*graphql.Argument{"agent": {Type: graphql.String}, "status": {Type: graphql.String}},
+			Resolve: func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+				agent, _ := args["agent"].(string)
+				plugins, err := loaderFromContext(ctx).plugins(ctx, src.(common.SyntestConfigSummary).ConfigId, agent)
+				if err != nil {
+					return nil, err
+				}
+				if status, ok := args["status"].(string); ok {
+					plugins = slices.DeleteFunc(plugins, func(p gqlPlugin) bool { return pluginStatus(p.passRatio) != status })
+				}
+				return plugins, nil
+			},
+		},
+		"failingAgents": field(&graphql.List{OfType: agentType}, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			l := loaderFromContext(ctx)
+			plugins, err := l.plugins(ctx, src.(common.SyntestConfigSummary).ConfigId, "")
+			if err != nil {
+				return nil, err
+			}
+			agents := []*gqlAgent{}
+			for _, plugin := range plugins {
+				if pluginStatus(plugin.passRatio) != StatusFailing {
+					continue
+				}
+				agent, err := l.agent(ctx, plugin.agentId)
+				if err != nil {
+					return nil, err
+				}
+				agents = append(agents, agent)
+			}
+			return agents, nil
+		}),
+		"runs": {
+			Type: &graphql.List{OfType: resultType},
+			Args: runsArgs(true),
+			Resolve: func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+				l := loaderFromContext(ctx)
+				agent, _ := args["agent"].(string)
+				plugins, err := l.plugins(ctx, src.(common.SyntestConfigSummary).ConfigId, agent)
+				if err != nil {
+					return nil, err
+				}
+				return l.runs(ctx, plugins, args)
+			},
+		},
+	}
+
+	agentType.Fields = map[string]*graphql.Field{
+		"id": stringField(func(a *gqlAgent) string { return a.id }),
+		"podName": stringField(func(a *gqlAgent) string {
+			podName, _, _ := strings.Cut(a.id, "/")
+			return podName
+		}),
+		"namespace": stringField(func(a *gqlAgent) string {
+			_, namespace, _ := strings.Cut(a.id, "/")
+			return namespace
+		}),
+		"statusTime": field(graphql.String, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if status := src.(*gqlAgent).status; status != nil {
+				return optionalString(status.StatusTime), nil
+			}
+			return nil, nil
+		}),
+		"version": field(graphql.String, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if status := src.(*gqlAgent).status; status != nil {
+				return optionalString(status.AgentConfig.RunTimeInfo.Version), nil
+			}
+			return nil, nil
+		}),
+		"nodeName": field(graphql.String, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if status := src.(*gqlAgent).status; status != nil {
+				return optionalString(status.AgentConfig.RunTimeInfo.NodeName), nil
+			}
+			return nil, nil
+		}),
+		"agentProfile": field(graphql.String, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			if status := src.(*gqlAgent).status; status != nil {
+				return optionalString(status.AgentConfig.AgentProfile), nil
+			}
+			return nil, nil
+		}),
+		"discoveredPlugins": field(&graphql.List{OfType: graphql.String}, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			plugins := []string{}
+			if status := src.(*gqlAgent).status; status != nil {
+				for plugin := range status.AgentConfig.DiscoveredPlugins {
+					plugins = append(plugins, plugin)
+				}
+			}
+			slices.Sort(plugins)
+			return plugins, nil
+		}),
+		"tests": field(&graphql.List{OfType: testType}, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			agent := src.(*gqlAgent)
+			tests := []common.SyntestConfigSummary{}
+			if agent.status == nil {
+				return tests, nil
+			}
+			all, err := loaderFromContext(ctx).tests(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, configId := range agent.status.SynTests {
+				if summary, ok := all[configId]; ok {
+					tests = append(tests, summary)
+				}
+			}
+			slices.SortFunc(tests, func(a, b common.SyntestConfigSummary) int { return strings.Compare(a.ConfigId, b.ConfigId) })
+			return tests, nil
+		}),
+		"plugins": {
+			Type: &graphql.List{OfType: pluginType},
+			Args: map[string]*graphql.Argument{"status": {Type: graphql.String}},
+			Resolve: func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+				plugins, err := loaderFromContext(ctx).plugins(ctx, "", src.(*gqlAgent).id)
+				if err != nil {
+					return nil, err
+				}
+				if status, ok := args["status"].(string); ok {
+					plugins = slices.DeleteFunc(plugins, func(p gqlPlugin) bool { return pluginStatus(p.passRatio) != status })
+				}
+				return plugins, nil
+			},
+		},
+	}
+
+	pluginType.Fields = map[string]*graphql.Field{
+		"id":      stringField(func(p gqlPlugin) string { return p.id }),
+		"agentId": stringField(func(p gqlPlugin) string { return p.agentId }),
+		"agent":   agentField(func(src interface{}) string { return src.(gqlPlugin).agentId }),
+		"test":    testField(func(src interface{}) string { return src.(gqlPlugin).configId }),
+		"status":  stringField(func(p gqlPlugin) string { return pluginStatus(p.passRatio) }),
+		"passRatio": field(graphql.Float, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			ratio, err := strconv.ParseFloat(src.(gqlPlugin).passRatio, 64)
+			if err != nil {
+				return nil, nil
+			}
+			return ratio, nil
+		}),
+		"state": field(pluginStateType, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			l := loaderFromContext(ctx)
+			state, err := l.r.store.FetchPluginHealthStatus(ctx, src.(gqlPlugin).id)
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, nil
+			} else if err != nil {
+				l.r.logger.Error("error fetching plugin health", "id", src.(gqlPlugin).id, "err", err)
+				return nil, errors.New("unable to fetch plugin state")
+			}
+			return state, nil
+		}),
+		"latestRun": field(testRunType, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return loaderFromContext(ctx).testRun(ctx, src.(gqlPlugin), false)
+		}),
+		"lastFailedRun": field(testRunType, func(ctx context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return loaderFromContext(ctx).testRun(ctx, src.(gqlPlugin), true)
+		}),
+		"runs": {
+			Type: &graphql.List{OfType: resultType},
+			Args: runsArgs(false),
+			Resolve: func(ctx context.Context, src interface{}, args map[string]interface{}) (interface{}, error) {
+				return loaderFromContext(ctx).runs(ctx, []gqlPlugin{src.(gqlPlugin)}, args)
+			},
+		},
+	}
+
+	resultType.Fields = map[string]*graphql.Field{
+		"agentId": stringField(func(res client.TestResult) string { return res.Agent }),
+		"agent":   agentField(func(src interface{}) string { return src.(client.TestResult).Agent }),
+		"time":    stringField(func(res client.TestResult) string { return res.Time.Format(time.RFC3339Nano) }),
+		"status":  stringField(func(res client.TestResult) string { return resultStatus(res.PassRatio) }),
+		"passRatio": field(graphql.Float, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(client.TestResult).PassRatio, nil
+		}),
+		"runtimeSeconds": field(graphql.Float, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(client.TestResult).RuntimeSeconds, nil
+		}),
+	}
+
+	testRunType.Fields = map[string]*graphql.Field{
+		"id":        stringField(func(run *proto.TestRun) string { return run.Id }),
+		"agentId":   stringField(func(run *proto.TestRun) string { return run.AgentId }),
+		"agent":     agentField(func(src interface{}) string { return src.(*proto.TestRun).AgentId }),
+		"startTime": stringField(func(run *proto.TestRun) string { return run.StartTime }),
+		"endTime":   stringField(func(run *proto.TestRun) string { return run.EndTime }),
+		"trigger":   stringField(func(run *proto.TestRun) string { return run.GetTrigger().GetTriggerType() }),
+		"marks": field(graphql.Float, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return float64(src.(*proto.TestRun).GetTestResult().GetMarks()), nil
+		}),
+		"maxMarks": field(graphql.Float, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return float64(src.(*proto.TestRun).GetTestResult().GetMaxMarks()), nil
+		}),
+		"error": field(graphql.String, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return optionalString(src.(*proto.TestRun).GetTestResult().GetDetails()[common.ErrorKey]), nil
+		}),
+		"details": field(&graphql.List{OfType: detailType}, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			details := []gqlDetail{}
+			for key, value := range src.(*proto.TestRun).GetTestResult().GetDetails() {
+				details = append(details, gqlDetail{key: key, value: value})
+			}
+			slices.SortFunc(details, func(a, b gqlDetail) int { return strings.Compare(a.key, b.key) })
+			return details, nil
+		}),
+	}
+	detailType.Fields = map[string]*graphql.Field{
+		"key":   stringField(func(d gqlDetail) string { return d.key }),
+		"value": stringField(func(d gqlDetail) string { return d.value }),
+	}
+
+	pluginStateType.Fields = map[string]*graphql.Field{
+		"status":         stringField(func(s common.PluginState) string { return string(s.Status) }),
+		"statusMsg":      stringField(func(s common.PluginState) string { return s.StatusMsg }),
+		"restartBackOff": stringField(func(s common.PluginState) string { return s.RestartBackOff }),
+		"runningSince":   stringField(func(s common.PluginState) string { return s.RunningSince.Format(time.RFC3339Nano) }),
+		"lastUpdated":    stringField(func(s common.PluginState) string { return s.LastUpdated.Format(time.RFC3339Nano) }),
+		"restarts": field(graphql.Int, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(common.PluginState).Restarts, nil
+		}),
+		"totalRestarts": field(graphql.Int, func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(common.PluginState).TotalRestarts, nil
+		}),
+	}
+
+	queryType := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"tests": {
+			Type: &graphql.List{OfType: testType},
+			Args: map[string]*graphql.Argument{
+				"name":      {Type: graphql.String},
+				"namespace": {Type: graphql.String},
+				"plugin":    {Type: graphql.String},
+				"status":    {Type: graphql.String},
+			},
+			Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				l := loaderFromContext(ctx)
+				all, err := l.tests(ctx)
+				if err != nil {
+					return nil, err
+				}
+				status, _ := args["status"].(string)
+				if status != "" && status != StatusPassing && status != StatusFailing {
+					return nil, fmt.Errorf("status must be %s or %s", StatusPassing, StatusFailing)
+				}
+				tests := []common.SyntestConfigSummary{}
+				for _, summary := range all {
+					if !matchArg(args, "name", summary.Name) || !matchArg(args, "namespace", summary.Namespace) ||
+						!matchArg(args, "plugin", summary.Plugin) {
+						continue
+					}
+					if status != "" {
+						plugins, err := l.plugins(ctx, summary.ConfigId, "")
+						if err != nil {
+							return nil, err
+						}
+						if testStatus(plugins) != status {
+							continue
+						}
+					}
+					tests = append(tests, summary)
+				}
+				slices.SortFunc(tests, func(a, b common.SyntestConfigSummary) int { return strings.Compare(a.ConfigId, b.ConfigId) })
+				return tests, nil
+			},
+		},
+		"test": {
+			Type: testType,
+			Args: map[string]*graphql.Argument{
+				"name":      {Type: graphql.String, Required: true},
+				"namespace": {Type: graphql.String, Required: true},
+			},
+			Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				configId := common.ComputeSynTestConfigId(args["name"].(string), args["namespace"].(string))
+				return loaderFromContext(ctx).test(ctx, configId)
+			},
+		},
+		"agents": {
+			Type: &graphql.List{OfType: agentType},
+			Args: map[string]*graphql.Argument{"namespace": {Type: graphql.String}},
+			Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				l := loaderFromContext(ctx)
+				all, err := l.agentStatus(ctx)
+				if err != nil {
+					return nil, err
+				}
+				agentIds := []string{}
+				for agentId := range all {
+					_, agentNs, _ := strings.Cut(agentId, "/")
+					if matchArg(args, "namespace", agentNs) {
+						agentIds = append(agentIds, agentId)
+					}
+				}
+				slices.Sort(agentIds)
+				agents := []*gqlAgent{}
+				for _, agentId := range agentIds {
+					agent, err := l.agent(ctx, agentId)
+					if err != nil {
+						return nil, err
+					}
+					agents = append(agents, agent)
+				}
+				return agents, nil
+			},
+		},
+		"agent": {
+			Type: agentType,
+			Args: map[string]*graphql.Argument{"id": {Type: graphql.String, Required: true}},
+			Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				agent, err := loaderFromContext(ctx).agent(ctx, args["id"].(string))
+				if err != nil || agent.status == nil {
+					return nil, err
+				}
+				return agent, nil
+			},
+		},
+	}}
+	return &graphql.Schema{Query: queryType, MaxDepth: GraphqlMaxDepth}
+}
+
+// matchArg Returns whether the value matches the string argument, if it's set
+func matchArg(args map[string]interface{}, arg string, value string) bool {
+	v, ok := args[arg].(string)
+	return !ok || v == value
+}
@@ -0,0 +1,393 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Request is a graphql request, as posted in json
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request, data is nil if the request is invalid
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Execute Parses, validates and executes the query of the request. A field whose resolver errors is null, and the
+// error is returned in the errors of the response with the path of the field.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	op, err := operation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	if op.Type != "query" {
+		return &Response{Errors: []*Error{{Message: "only queries are supported, not " + op.Type + "s"}}}
+	}
+
+	vars := map[string]interface{}{}
+	for _, def := range op.Variables {
+		v, ok := req.Variables[def.Name]
+		if !ok {
+			v = def.DefaultValue
+		}
+		vars[def.Name] = v
+	}
+	e := &executor{doc: doc, vars: vars, maxDepth: s.MaxDepth}
+	if errs := e.validate(s.Query, op.SelectionSet, nil, map[string]bool{}); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	data := e.executeObject(ctx, s.Query, nil, op.SelectionSet, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// operation Returns the operation of the document to execute
+func operation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document has multiple operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	doc      *Document
+	vars     map[string]interface{}
+	maxDepth int
+	errors   []*Error
+}
+
+// validate Checks the fields exist, their arguments are valid, objects (only) have a selection and the fields aren't
+// deeper than the max depth
+func (e *executor) validate(obj *Object, set []Selection, path []interface{}, visiting map[string]bool) []*Error {
+	var errs []*Error
+	fail := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &Error{Message: fmt.Sprintf(format, args...), Path: appendPath(path, field)})
+	}
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *FieldSelection:
+			if sel.Name == "__typename" {
+				continue
+			}
+			if e.maxDepth > 0 && len(path) >= e.maxDepth {
+				fail(sel.Alias, "the query is nested more than %d fields deep", e.maxDepth)
+				continue
+			}
+			field, ok := obj.Fields[sel.Name]
+			if !ok {
+				fail(sel.Alias, "cannot query field %q on type %s", sel.Name, obj.Name)
+				continue
+			}
+			for name := range sel.Arguments {
+				if _, ok := field.Args[name]; !ok {
+					fail(sel.Alias, "unknown argument %q on field %s.%s", name, obj.Name, sel.Name)
+				}
+			}
+			if _, err := e.arguments(field, sel.Arguments); err != nil {
+				fail(sel.Alias, "%s", err)
+			}
+			child := namedType(field.Type)
+			if childObj, ok := child.(*Object); ok {
+				if len(sel.SelectionSet) == 0 {
+					fail(sel.Alias, "field %s.%s of type %s must have a selection of subfields", obj.Name, sel.Name, field.Type)
+					continue
+				}
+				errs = append(errs, e.validate(childObj, sel.SelectionSet, appendPath(path, sel.Alias), visiting)...)
+			} else if len(sel.SelectionSet) > 0 {
+				fail(sel.Alias, "field %s.%s of type %s can't have a selection of subfields", obj.Name, sel.Name, field.Type)
+			}
+		case *FragmentSpread:
+			frag, ok := e.doc.Fragments[sel.Name]
+			if !ok {
+				errs = append(errs, &Error{Message: fmt.Sprintf("unknown fragment %q", sel.Name), Path: path})
+				continue
+			}
+			if visiting[sel.Name] {
+				errs = append(errs, &Error{Message: fmt.Sprintf("fragment %q spreads itself", sel.Name), Path: path})
+				continue
+			}
+			visiting[sel.Name] = true
+			errs = append(errs, e.validate(obj, frag.SelectionSet, path, visiting)...)
+			delete(visiting, sel.Name)
+		case *InlineFragment:
+			errs = append(errs, e.validate(obj, sel.SelectionSet, path, visiting)...)
+		}
+	}
+	return errs
+}
+
+// arguments Returns the arguments of the field, with the variables substituted and coerced to their types
+func (e *executor) arguments(field *Field, values map[string]Value) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for name, arg := range field.Args {
+		var v interface{}
+		if value, ok := values[name]; ok {
+			resolved, err := e.resolveValue(value)
+			if err != nil {
+				return nil, err
+			}
+			v, err = coerce(arg.Type, resolved)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q: %w", name, err)
+			}
+		}
+		if v == nil { // not set, or null
+			v = arg.Default
+		}
+		if v == nil && arg.Required {
+			return nil, fmt.Errorf("argument %q of type %s is required", name, arg.Type)
+		}
+		if v != nil {
+			args[name] = v
+		}
+	}
+	return args, nil
+}
+
+// resolveValue Substitutes the variables in a value
+func (e *executor) resolveValue(v Value) (interface{}, error) {
+	switch v := v.(type) {
+	case Variable:
+		val, ok := e.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", v)
+		}
+		return val, nil
+	case Enum:
+		return string(v), nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range v {
+			r, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// skip Returns whether the selection is excluded by its @skip or @include directive
+func (e *executor) skip(directives []*Directive) bool {
+	for _, d := range directives {
+		v, _ := e.resolveValue(d.Arguments["if"])
+		b, _ := v.(bool)
+		if (d.Name == "skip" && b) || (d.Name == "include" && !b) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectFields Returns the fields of the selection set (with the fragments expanded), in the order of the query
+func (e *executor) collectFields(set []Selection, fields *[]*FieldSelection) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *FieldSelection:
+			if !e.skip(sel.Directives) {
+				*fields = append(*fields, sel)
+			}
+		case *FragmentSpread:
+			if !e.skip(sel.Directives) {
+				e.collectFields(e.doc.Fragments[sel.Name].SelectionSet, fields)
+			}
+		case *InlineFragment:
+			if !e.skip(sel.Directives) {
+				e.collectFields(sel.SelectionSet, fields)
+			}
+		}
+	}
+}
+
+func (e *executor) executeObject(ctx context.Context, obj *Object, source interface{}, set []Selection, path []interface{}) *OrderedMap {
+	var fields []*FieldSelection
+	e.collectFields(set, &fields)
+	result := &OrderedMap{}
+	for _, sel := range fields {
+		if _, ok := result.index(sel.Alias); ok {
+			continue // the same field selected twice (e.g. in a fragment)
+		}
+		if sel.Name == "__typename" {
+			result.Set(sel.Alias, obj.Name)
+			continue
+		}
+		field := obj.Fields[sel.Name]
+		fieldPath := appendPath(path, sel.Alias)
+		args, err := e.arguments(field, sel.Arguments)
+		if err != nil {
+			e.errors = append(e.errors, &Error{Message: err.Error(), Path: fieldPath})
+			result.Set(sel.Alias, nil)
+			continue
+		}
+		v, err := field.Resolve(ctx, source, args)
+		if err != nil {
+			e.errors = append(e.errors, &Error{Message: err.Error(), Path: fieldPath})
+			result.Set(sel.Alias, nil)
+			continue
+		}
+		result.Set(sel.Alias, e.complete(ctx, field.Type, v, sel.SelectionSet, fieldPath))
+	}
+	return result
+}
+
+// complete Returns the value of a field, resolving the subfields of objects
+func (e *executor) complete(ctx context.Context, t Type, v interface{}, set []Selection, path []interface{}) interface{} {
+	if isNil(v) {
+		return nil
+	}
+	switch t := t.(type) {
+	case *Object:
+		return e.executeObject(ctx, t, v, set, path)
+	case *List:
+		items := reflect.ValueOf(v)
+		if items.Kind() != reflect.Slice {
+			e.errors = append(e.errors, &Error{Message: fmt.Sprintf("expected a list, resolved %T", v), Path: path})
+			return nil
+		}
+		out := make([]interface{}, items.Len())
+		for i := range out {
+			out[i] = e.complete(ctx, t.OfType, items.Index(i).Interface(), set, appendPath(path, i))
+		}
+		return out
+	}
+	return v
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func namedType(t Type) Type {
+	for {
+		l, ok := t.(*List)
+		if !ok {
+			return t
+		}
+		t = l.OfType
+	}
+}
+
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	return append(append([]interface{}{}, path...), elem)
+}
+
+// OrderedMap is the result of an object, serialized with its fields in the order of the query
+type OrderedMap struct {
+	keys   []string
+	values []interface{}
+}
+
+func (m *OrderedMap) index(key string) (int, bool) {
+	for i, k := range m.keys {
+		if k == key {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Set Sets the value of the key, keeping its position if it's already set
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if i, ok := m.index(key); ok {
+		m.values[i] = value
+		return
+	}
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// Get Returns the value of the key
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	i, ok := m.index(key)
+	if !ok {
+		return nil, false
+	}
+	return m.values[i], true
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testNode struct {
+	id   string
+	tags []string
+}
+
+// testSchema Returns a schema with a recursive type, for the tests
+func testSchema(maxDepth int) *Schema {
+	nodeType := &Object{Name: "Node"}
+	nodeType.Fields = map[string]*Field{
+		"id": {Type: ID, Resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(*testNode).id, nil
+		}},
+		"tags": {Type: &List{OfType: String}, Resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return src.(*testNode).tags, nil
+		}},
+		"child": {Type: nodeType, Resolve: func(_ context.Context, src interface{}, _ map[string]interface{}) (interface{}, error) {
+			return &testNode{id: src.(*testNode).id + "/child"}, nil
+		}},
+	}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"hello": {Type: String, Args: map[string]*Argument{"name": {Type: String, Default: "world"}},
+			Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				return "hello " + args["name"].(string), nil
+			}},
+		"double": {Type: Int, Args: map[string]*Argument{"n": {Type: Int, Required: true}},
+			Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				return args["n"].(int) * 2, nil
+			}},
+		"nodes": {Type: &List{OfType: nodeType}, Args: map[string]*Argument{"ids": {Type: &List{OfType: ID}}},
+			Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				var nodes []*testNode
+				ids, _ := args["ids"].([]interface{})
+				for _, id := range ids {
+					nodes = append(nodes, &testNode{id: id.(string), tags: []string{"a"}})
+				}
+				return nodes, nil
+			}},
+		"node": {Type: nodeType, Resolve: func(_ context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return &testNode{id: "n"}, nil
+		}},
+		"missing": {Type: nodeType, Resolve: func(_ context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return (*testNode)(nil), nil
+		}},
+		"fail": {Type: String, Resolve: func(_ context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+			return nil, errors.New("unable to fetch")
+		}},
+	}}
+	return &Schema{Query: query, MaxDepth: maxDepth}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      Request
+		maxDepth int
+		want     string // json of the response
+	}{
+		{
+			name: "fields and aliases",
+			req:  Request{Query: `{ hello other: hello(name: "you") node { __typename id } missing { id } }`},
+			want: `{"data":{"hello":"hello world","other":"hello you","node":{"__typename":"Node","id":"n"},"missing":null}}`,
+		},
+		{
+			name: "lists",
+			req:  Request{Query: `{ nodes(ids: ["a", 2]) { id tags } single: nodes(ids: "c") { id } }`},
+			want: `{"data":{"nodes":[{"id":"a","tags":["a"]},{"id":"2","tags":["a"]}],"single":[{"id":"c"}]}}`,
+		},
+		{
+			name: "resolver error",
+			req:  Request{Query: `{ hello fail }`},
+			want: `{"data":{"hello":"hello world","fail":null},"errors":[{"message":"unable to fetch","path":["fail"]}]}`,
+		},
+		{
+			name: "fragments",
+			req: Request{Query: `
+				{ node { ...ids ... on Node { child { ...ids } } } }
+				fragment ids on Node { id ...more }
+				fragment more on Node { id __typename }`},
+			want: `{"data":{"node":{"id":"n","__typename":"Node","child":{"id":"n/child","__typename":"Node"}}}}`,
+		},
+		{
+			name: "unknown fragment",
+			req:  Request{Query: `{ node { ...ids } }`},
+			want: `{"data":null,"errors":[{"message":"unknown fragment \"ids\"","path":["node"]}]}`,
+		},
+		{
+			name: "fragment cycle",
+			req:  Request{Query: `{ node { ...a } } fragment a on Node { id ...b } fragment b on Node { child { id } ...a }`},
+			want: `{"data":null,"errors":[{"message":"fragment \"a\" spreads itself","path":["node"]}]}`,
+		},
+		{
+			name: "skip and include",
+			req: Request{Query: `query($skip: Boolean = true) {
+				a: hello @skip(if: $skip) b: hello @include(if: $skip) node { ... @skip(if: true) { id } ...f @include(if: false) }
+			} fragment f on Node { id }`},
+			want: `{"data":{"b":"hello world","node":{}}}`,
+		},
+		{
+			name: "variables",
+			req: Request{
+				Query:     `query($name: String, $n: Int!, $ids: [ID] = ["x"]) { hello(name: $name) double(n: $n) nodes(ids: $ids) { id } }`,
+				Variables: map[string]interface{}{"name": "vars", "n": 21.0},
+			},
+			want: `{"data":{"hello":"hello vars","double":42,"nodes":[{"id":"x"}]}}`,
+		},
+		{
+			name: "null variable uses the default of the argument",
+			req:  Request{Query: `query($name: String) { hello(name: $name) }`},
+			want: `{"data":{"hello":"hello world"}}`,
+		},
+		{
+			name: "undefined variable",
+			req:  Request{Query: `{ hello(name: $name) }`},
+			want: `{"data":null,"errors":[{"message":"variable $name is not defined","path":["hello"]}]}`,
+		},
+		{
+			name: "variable of the wrong type",
+			req:  Request{Query: `query($n: Int) { double(n: $n) }`, Variables: map[string]interface{}{"n": 1.5}},
+			want: `{"data":null,"errors":[{"message":"argument \"n\": expected a value of type Int, found 1.5","path":["double"]}]}`,
+		},
+		{
+			name: "missing required argument",
+			req:  Request{Query: `query($n: Int) { double(n: $n) }`},
+			want: `{"data":null,"errors":[{"message":"argument \"n\" of type Int is required","path":["double"]}]}`,
+		},
+		{
+			name: "int out of range",
+			req:  Request{Query: `{ double(n: 3000000000) }`},
+			want: `{"data":null,"errors":[{"message":"argument \"n\": expected a value of type Int, found 3000000000","path":["double"]}]}`,
+		},
+		{
+			name: "unknown field and argument",
+			req:  Request{Query: `{ tests hello(names: "a") }`},
+			want: `{"data":null,"errors":[{"message":"cannot query field \"tests\" on type Query","path":["tests"]},{"message":"unknown argument \"names\" on field Query.hello","path":["hello"]}]}`,
+		},
+		{
+			name: "object without a selection",
+			req:  Request{Query: `{ node }`},
+			want: `{"data":null,"errors":[{"message":"field Query.node of type Node must have a selection of subfields","path":["node"]}]}`,
+		},
+		{
+			name: "scalar with a selection",
+			req:  Request{Query: `{ hello { id } }`},
+			want: `{"data":null,"errors":[{"message":"field Query.hello of type String can't have a selection of subfields","path":["hello"]}]}`,
+		},
+		{
+			name: "mutation",
+			req:  Request{Query: `mutation { hello }`},
+			want: `{"data":null,"errors":[{"message":"only queries are supported, not mutations"}]}`,
+		},
+		{
+			name: "operation name",
+			req:  Request{Query: `query A { hello } query B { other: hello }`, OperationName: "B"},
+			want: `{"data":{"other":"hello world"}}`,
+		},
+		{
+			name: "missing operation name",
+			req:  Request{Query: `query A { hello } query B { other: hello }`},
+			want: `{"data":null,"errors":[{"message":"operationName is required when the document has multiple operations"}]}`,
+		},
+		{
+			name: "unknown operation name",
+			req:  Request{Query: `query A { hello }`, OperationName: "B"},
+			want: `{"data":null,"errors":[{"message":"unknown operation \"B\""}]}`,
+		},
+		{
+			name: "syntax error",
+			req:  Request{Query: `{ hello `},
+			want: `{"data":null,"errors":[{"message":"syntax error at 8: expected a name, found end of document"}]}`,
+		},
+		{
+			name:     "at the max depth",
+			req:      Request{Query: `{ node { child { id } } }`},
+			maxDepth: 3,
+			want:     `{"data":{"node":{"child":{"id":"n/child"}}}}`,
+		},
+		{
+			name:     "deeper than the max depth",
+			req:      Request{Query: `{ node { child { child { id } } } }`},
+			maxDepth: 3,
+			want:     `{"data":null,"errors":[{"message":"the query is nested more than 3 fields deep","path":["node","child","child","id"]}]}`,
+		},
+		{
+			name:     "deeper than the max depth in a fragment",
+			req:      Request{Query: `{ node { ...f } } fragment f on Node { child { child { __typename id } } }`},
+			maxDepth: 3,
+			want:     `{"data":null,"errors":[{"message":"the query is nested more than 3 fields deep","path":["node","child","child","id"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := testSchema(tt.maxDepth).Execute(context.Background(), tt.req)
+			got, err := json.Marshal(resp)
+			if err != nil {
+				t.Fatalf("error marshalling the response: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Execute() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteNoDepthLimit(t *testing.T) {
+	query := "{ node { " + strings.Repeat("child { ", 20) + "id" + strings.Repeat(" }", 20) + " } }"
+	resp := testSchema(0).Execute(context.Background(), Request{Query: query})
+	if len(resp.Errors) > 0 {
+		t.Errorf("Execute() errors = %v, want none", resp.Errors)
+	}
+}
@@ -0,0 +1,643 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a parsed graphql request document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query (mutations and subscriptions are parsed, but can't be executed)
+type Operation struct {
+	Type         string // query, mutation or subscription
+	Name         string
+	Variables    []*VariableDefinition
+	SelectionSet []Selection
+}
+
+type VariableDefinition struct {
+	Name         string
+	DefaultValue Value // nil if none
+}
+
+// Fragment is a named fragment (fragment <name> on <type> {...}), its type condition isn't checked
+type Fragment struct {
+	Name         string
+	SelectionSet []Selection
+}
+
+// Selection is a *FieldSelection, *FragmentSpread or *InlineFragment
+type Selection interface{}
+
+type FieldSelection struct {
+	Alias        string // the name if there's no alias
+	Name         string
+	Arguments    map[string]Value
+	Directives   []*Directive
+	SelectionSet []Selection
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+type InlineFragment struct {
+	Directives   []*Directive
+	SelectionSet []Selection
+}
+
+type Directive struct {
+	Name      string
+	Arguments map[string]Value
+}
+
+// Value is a literal or a variable of an argument
+type Value interface{}
+
+// Variable is a reference to a variable in a Value
+type Variable string
+
+// Enum is an enum value in a Value
+type Enum string
+
+// MaxNesting is the maximum nesting of the selection sets, values and types of a document, so the parser can't exhaust
+// the stack
+const MaxNesting = 64
+
+// Parse Returns the document of the query, or a syntax error
+func Parse(query string) (*Document, error) {
+	p := &parser{lexer: lexer{src: query}}
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peekPunct("{"):
+			set, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", SelectionSet: set})
+		case p.tok.kind == tokName && p.tok.value == "fragment":
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.Fragments[frag.Name] = frag
+		case p.tok.kind == tokName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		default:
+			return nil, p.errorf("unexpected %s", p.tok)
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("syntax error: no operation in the document")
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lexer lexer
+	tok   token
+	depth int // nesting of the current selection set, value or type
+}
+
+func (p *parser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peekPunct(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == punct
+}
+
+// skipPunct Consumes the punctuator if it's the current token, and returns whether it was
+func (p *parser) skipPunct(punct string) (bool, error) {
+	if !p.peekPunct(punct) {
+		return false, nil
+	}
+	return true, p.next()
+}
+
+func (p *parser) expectPunct(punct string) error {
+	if !p.peekPunct(punct) {
+		return p.errorf("expected %q, found %s", punct, p.tok)
+	}
+	return p.next()
+}
+
+// nest Increments the nesting (decremented by the returned function), errors if it's too deep
+func (p *parser) nest() (func(), error) {
+	p.depth++
+	if p.depth > MaxNesting {
+		return nil, p.errorf("nested more than %d levels deep", MaxNesting)
+	}
+	return func() { p.depth-- }, nil
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.errorf("expected a name, found %s", p.tok)
+	}
+	name := p.tok.value
+	return name, p.next()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: p.tok.value}
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skipPunct("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peekPunct(")") {
+			def, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.Variables = append(op.Variables, def)
+		}
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	op.SelectionSet, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) variableDefinition() (*VariableDefinition, error) {
+	err := p.expectPunct("$")
+	if err != nil {
+		return nil, err
+	}
+	def := &VariableDefinition{}
+	if def.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct(":"); err != nil {
+		return nil, err
+	}
+	if err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skipPunct("="); err != nil {
+		return nil, err
+	} else if ok {
+		if def.DefaultValue, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	return def, nil
+}
+
+// typeRef Skips the type of a variable, the arguments are coerced to the types of the schema
+func (p *parser) typeRef() error {
+	unnest, err := p.nest()
+	if err != nil {
+		return err
+	}
+	defer unnest()
+	if ok, err := p.skipPunct("["); err != nil {
+		return err
+	} else if ok {
+		if err = p.typeRef(); err != nil {
+			return err
+		}
+		if err = p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err = p.name(); err != nil {
+		return err
+	}
+	_, err = p.skipPunct("!")
+	return err
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	frag := &Fragment{}
+	if frag.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokName || p.tok.value != "on" {
+		return nil, p.errorf("expected \"on\", found %s", p.tok)
+	}
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	if _, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	frag.SelectionSet, err = p.selectionSet()
+	return frag, err
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	unnest, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer unnest()
+	if err = p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var set []Selection
+	for !p.peekPunct("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, sel)
+	}
+	if len(set) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return set, p.next()
+}
+
+func (p *parser) selection() (Selection, error) {
+	if ok, err := p.skipPunct("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			spread := &FragmentSpread{}
+			if spread.Name, err = p.name(); err != nil {
+				return nil, err
+			}
+			spread.Directives, err = p.directives()
+			return spread, err
+		}
+		if p.tok.kind == tokName { // on <type>
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			if _, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		inline := &InlineFragment{}
+		if inline.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		inline.SelectionSet, err = p.selectionSet()
+		return inline, err
+	}
+
+	field := &FieldSelection{}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	field.Alias, field.Name = name, name
+	if ok, err := p.skipPunct(":"); err != nil {
+		return nil, err
+	} else if ok {
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if field.Arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		field.SelectionSet, err = p.selectionSet()
+	}
+	return field, err
+}
+
+func (p *parser) arguments() (map[string]Value, error) {
+	args := map[string]Value{}
+	if ok, err := p.skipPunct("("); err != nil || !ok {
+		return args, err
+	}
+	for !p.peekPunct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.next()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var directives []*Directive
+	for p.peekPunct("@") {
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		d := &Directive{}
+		if d.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.Arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// value Parses a value, variables aren't allowed in constant values (the defaults of variables)
+func (p *parser) value(constant bool) (Value, error) {
+	unnest, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer unnest()
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		i, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid int %s", tok.value)
+		}
+		return i, p.next()
+	case tokFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %s", tok.value)
+		}
+		return f, p.next()
+	case tokString:
+		return tok.value, p.next()
+	case tokName:
+		var v Value = Enum(tok.value)
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		}
+		return v, p.next()
+	}
+	switch {
+	case p.peekPunct("$") && !constant:
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return Variable(name), err
+	case p.peekPunct("["):
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.peekPunct("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case p.peekPunct("{"):
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		obj := map[string]interface{}{}
+		for !p.peekPunct("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	}
+	return nil, p.errorf("expected a value, found %s", tok)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of document"
+	case tokString:
+		return strconv.Quote(t.value)
+	}
+	return t.value
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	// skip whitespace, commas and comments
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			break
+		}
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, value: "...", pos: start}, nil
+	case strings.ContainsRune("!$():=@[]{|}", rune(c)):
+		l.pos++
+		return token{kind: tokPunct, value: string(c), pos: start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case c == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, fmt.Errorf("syntax error at %d: unexpected character %q", start, r)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	return token{kind: kind, value: l.src[start:l.pos], pos: start}, nil
+}
+
+// string Lexes a string (block strings aren't supported)
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokString, value: sb.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				sb.WriteByte(esc)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("syntax error at %d: invalid unicode escape", l.pos)
+				}
+				r, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("syntax error at %d: invalid unicode escape", l.pos)
+				}
+				sb.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("syntax error at %d: invalid escape \\%c", l.pos-1, esc)
+			}
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("syntax error at %d: unterminated string", start)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
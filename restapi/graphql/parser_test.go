@@ -0,0 +1,147 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"empty", "", "no operation"},
+		{"only a comment", "# { tests }", "no operation"},
+		{"unclosed selection set", "{ tests { id }", "expected a name, found end of document"},
+		{"unopened selection set", "tests { id } }", "unexpected tests"},
+		{"empty selection set", "{ }", "empty selection set"},
+		{"missing argument value", "{ tests(name: ) { id } }", "expected a value"},
+		{"missing argument colon", "{ tests(name \"a\") { id } }", "expected \":\""},
+		{"unterminated string", "{ tests(name: \"a) { id } }", "unterminated string"},
+		{"newline in string", "{ tests(name: \"a\nb\") { id } }", "unterminated string"},
+		{"invalid escape", "{ tests(name: \"\\x\") { id } }", "invalid escape"},
+		{"invalid unicode escape", "{ tests(name: \"\\u12\") { id } }", "invalid unicode escape"},
+		{"invalid character", "{ tests; }", "unexpected character ';'"},
+		{"int out of range", "{ tests(limit: 99999999999999999999) { id } }", "invalid int"},
+		{"variable in a default", "query($a: Int = $b) { tests { id } }", "expected a value"},
+		{"variable without a type", "query($a) { tests { id } }", "expected \":\""},
+		{"unclosed list type", "query($a: [String) { tests { id } }", "expected \"]\""},
+		{"fragment without a type", "fragment f { id } { tests { ...f } }", "expected \"on\""},
+		{"spread without a name", "{ tests { ... } }", "expected \"{\""},
+		{"directive without a name", "{ tests @ { id } }", "expected a name"},
+		{"too deep selection sets", strings.Repeat("{ a ", MaxNesting+1) + strings.Repeat("}", MaxNesting+1), "nested more than"},
+		{"too deep list value", "{ a(b: " + strings.Repeat("[", MaxNesting+1) + strings.Repeat("]", MaxNesting+1) + ") }", "nested more than"},
+		{"too deep object value", "{ a(b: " + strings.Repeat("{c: ", MaxNesting+1) + "1" + strings.Repeat("}", MaxNesting+1) + ") }", "nested more than"},
+		{"too deep list type", "query($a: " + strings.Repeat("[", MaxNesting+1) + "Int" + strings.Repeat("]", MaxNesting+1) + ") { a }", "nested more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			if err == nil {
+				t.Fatalf("Parse() error = nil, want %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# named query with variables
+		query Failing($status: String = "failing", $limit: Int, $ids: [ID!]!) @cached {
+			failing: tests(status: $status, limit: 5) {
+				...testFields
+				... on Test @include(if: true) { plugin }
+			}
+		}
+		fragment testFields on Test { id, name }
+		{ agents { id } }`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(doc.Operations) != 2 {
+		t.Fatalf("Parse() operations = %d, want 2", len(doc.Operations))
+	}
+	op := doc.Operations[0]
+	if op.Type != "query" || op.Name != "Failing" {
+		t.Errorf("operation = %s %s, want query Failing", op.Type, op.Name)
+	}
+	var vars []string
+	for _, v := range op.Variables {
+		vars = append(vars, v.Name)
+	}
+	if !reflect.DeepEqual(vars, []string{"status", "limit", "ids"}) || op.Variables[0].DefaultValue != "failing" ||
+		op.Variables[1].DefaultValue != nil {
+		t.Errorf("variables = %v (defaults %v, %v)", vars, op.Variables[0].DefaultValue, op.Variables[1].DefaultValue)
+	}
+
+	field := op.SelectionSet[0].(*FieldSelection)
+	if field.Alias != "failing" || field.Name != "tests" {
+		t.Errorf("field = %s: %s, want failing: tests", field.Alias, field.Name)
+	}
+	wantArgs := map[string]Value{"status": Variable("status"), "limit": int64(5)}
+	if !reflect.DeepEqual(field.Arguments, wantArgs) {
+		t.Errorf("arguments = %v, want %v", field.Arguments, wantArgs)
+	}
+	if spread, ok := field.SelectionSet[0].(*FragmentSpread); !ok || spread.Name != "testFields" {
+		t.Errorf("selection 0 = %#v, want a spread of testFields", field.SelectionSet[0])
+	}
+	if inline, ok := field.SelectionSet[1].(*InlineFragment); !ok || len(inline.Directives) != 1 || inline.Directives[0].Name != "include" {
+		t.Errorf("selection 1 = %#v, want an inline fragment with @include", field.SelectionSet[1])
+	}
+	if frag, ok := doc.Fragments["testFields"]; !ok || len(frag.SelectionSet) != 2 {
+		t.Errorf("fragments = %v, want testFields with 2 fields", doc.Fragments)
+	}
+	if doc.Operations[1].Type != "query" || doc.Operations[1].Name != "" {
+		t.Errorf("operation 1 = %s %q, want an anonymous query", doc.Operations[1].Type, doc.Operations[1].Name)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Value
+	}{
+		{`-12`, int64(-12)},
+		{`1.5e3`, 1500.0},
+		{`"a\"b\u00e9\n"`, "a\"bé\n"},
+		{`true`, true},
+		{`null`, nil},
+		{`FAILING`, Enum("FAILING")},
+		{`$v`, Variable("v")},
+		{`[1, "a", [$v]]`, []interface{}{int64(1), "a", []interface{}{Variable("v")}}},
+		{`{a: 1, b: {c: $v}}`, map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": Variable("v")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			doc, err := Parse("{ f(arg: " + tt.value + ") }")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := doc.Operations[0].SelectionSet[0].(*FieldSelection).Arguments["arg"]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
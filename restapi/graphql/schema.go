@@ -0,0 +1,145 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+// package implementing the subset of graphql needed to query the data of the rest api: queries with nested
+// selections, aliases, arguments, variables, fragments and the skip/include directives (no mutations, subscriptions
+// or introspection)
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Type is a *Scalar, *Object or *List
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type, its values are serialized as they're returned by the resolvers
+type Scalar struct {
+	Name string
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+var (
+	String  = &Scalar{Name: "String"}
+	Int     = &Scalar{Name: "Int"}
+	Float   = &Scalar{Name: "Float"}
+	Boolean = &Scalar{Name: "Boolean"}
+	ID      = &Scalar{Name: "ID"}
+)
+
+// Object is a type with fields, it needs a selection of its fields in the query
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// List is a list of values of a type, resolvers return it as a slice
+type List struct {
+	OfType Type
+}
+
+func (l *List) String() string { return "[" + l.OfType.String() + "]" }
+
+// ResolveFunc Returns the value of a field of the source (the value of the parent object), the arguments are
+// coerced to the types of the field's arguments, and have their defaults if they weren't set
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+type Field struct {
+	Type    Type
+	Args    map[string]*Argument
+	Resolve ResolveFunc
+}
+
+// Argument of a field, only scalar arguments (or lists of them) are supported
+type Argument struct {
+	Type     Type
+	Default  interface{} // nil if none
+	Required bool
+}
+
+// Schema is the schema of the queries
+type Schema struct {
+	Query    *Object
+	MaxDepth int // maximum depth of the fields of a query (0 for no limit), as objects can reference each other
+}
+
+// coerce Converts the value of an argument (from the query or the json variables) to the type of the argument
+func coerce(t Type, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *List:
+		list, ok := v.([]interface{})
+		if !ok {
+			list = []interface{}{v} // a single value is coerced to a list of one
+		}
+		out := make([]interface{}, len(list))
+		for i, item := range list {
+			c, err := coerce(t.OfType, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	case *Scalar:
+		switch t {
+		case Int:
+			switch n := v.(type) {
+			case int64:
+				if n >= math.MinInt32 && n <= math.MaxInt32 {
+					return int(n), nil
+				}
+			case float64: // json numbers
+				if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+					return int(n), nil
+				}
+			}
+		case Float:
+			switch n := v.(type) {
+			case int64:
+				return float64(n), nil
+			case float64:
+				return n, nil
+			}
+		case Boolean:
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+		case String, ID:
+			switch s := v.(type) {
+			case string:
+				return s, nil
+			case int64:
+				if t == ID {
+					return fmt.Sprint(s), nil
+				}
+			}
+		default:
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a value of type %s, found %v", t, v)
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/pkg/errors"
+)
+
+// DefaultControllerHeartbeatDeadline is how long after its last heartbeat the controller is considered down, if not
+// configured (it writes one every time it checks the agents, every 30s by default)
+const DefaultControllerHeartbeatDeadline = 3 * time.Minute
+
+// GetSystemHealth Returns the health of the whole installation: whether the storage is reachable, the controller
+// posts its heartbeat, the agents it expects are live and the critical tests pass. The status code is 503 if any of
+// the checks fails, so it can be used by a single uptime check.
+func (r *RestApi) GetSystemHealth(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	health := client.SystemHealth{Status: client.HealthOk, Time: time.Now()}
+	health.Storage = client.HealthCheck{Status: client.HealthOk}
+	if err := r.store.Ping(ctx); err != nil {
+		r.logger.Warn("storage is unreachable", "err", err)
+		health.Storage = client.HealthCheck{Status: client.HealthFailing, Message: "storage is unreachable"}
+		// the other checks need the storage
+		health.Controller.HealthCheck = client.HealthCheck{Status: client.HealthUnknown}
+		health.Agents.HealthCheck = client.HealthCheck{Status: client.HealthUnknown}
+		health.CriticalTests.HealthCheck = client.HealthCheck{Status: client.HealthUnknown}
+	} else {
+		r.controllerHealth(ctx, &health)
+		r.criticalTestsHealth(ctx, &health)
+	}
+	for _, check := range []client.HealthCheck{health.Storage, health.Controller.HealthCheck, health.Agents.HealthCheck,
+		health.CriticalTests.HealthCheck} {
+		if check.Status != client.HealthOk {
+			health.Status = client.HealthFailing
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if health.Status != client.HealthOk {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(health)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// controllerHealth Sets the health of the controller and the agents, from the last heartbeat of the controller (with
+// the agents it expects) and the agents registered in storage
+func (r *RestApi) controllerHealth(ctx context.Context, health *client.SystemHealth) {
+	deadline := r.config.ControllerHeartbeatDeadline
+	if deadline <= 0 {
+		deadline = DefaultControllerHeartbeatDeadline
+	}
+	// the expected agents are only known from a recent heartbeat
+	health.Agents.HealthCheck = client.HealthCheck{Status: client.HealthUnknown,
+		Message: "the expected agents are unknown without a recent controller heartbeat"}
+	heartbeat, err := r.store.FetchControllerHeartbeat(ctx)
+	if errors.Is(err, storage.ErrNotFound) {
+		health.Controller.HealthCheck = client.HealthCheck{Status: client.HealthFailing,
+			Message: "the controller never posted a heartbeat"}
+		return
+	} else if err != nil {
+		r.logger.Error("error fetching controller heartbeat", "err", err)
+		health.Controller.HealthCheck = client.HealthCheck{Status: client.HealthUnknown,
+			Message: "unable to fetch the controller heartbeat"}
+		return
+	}
+	health.Controller.LastHeartbeat = &heartbeat.Time
+	if age := time.Since(heartbeat.Time); age > deadline {
+		health.Controller.HealthCheck = client.HealthCheck{Status: client.HealthFailing,
+			Message: "no controller heartbeat for " + age.Round(time.Second).String()}
+		return
+	}
+	health.Controller.HealthCheck = client.HealthCheck{Status: client.HealthOk}
+
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching agent statuses", "err", err)
+		health.Agents.HealthCheck = client.HealthCheck{Status: client.HealthUnknown, Message: "unable to fetch the agents"}
+		return
+	}
+	health.Agents.Registered = len(agents)
+	health.Agents.Expected = heartbeat.ExpectedAgents
+	health.Agents.Live = heartbeat.LiveAgents
+	health.Agents.Stale = heartbeat.StaleAgents
+	health.Agents.HealthCheck = client.HealthCheck{Status: client.HealthOk}
+	if heartbeat.LiveAgents < heartbeat.ExpectedAgents {
+		health.Agents.HealthCheck = client.HealthCheck{Status: client.HealthFailing,
+			Message: strconv.Itoa(heartbeat.ExpectedAgents-heartbeat.LiveAgents) + " agents stopped reporting"}
+	}
+}
+
+// criticalTestsHealth Sets the health of the critical tests, they fail if their latest run failed on any agent
+func (r *RestApi) criticalTestsHealth(ctx context.Context, health *client.SystemHealth) {
+	summaries, err := r.store.FetchAllTestConfigSummary(ctx)
+	if err == nil {
+		var allStatus map[string]string
+		allStatus, err = r.store.FetchAllTestRunStatus(ctx)
+		if err == nil {
+			failing := map[string]bool{}
+			for pluginId, passRatio := range allStatus {
+				if pluginStatus(passRatio) == StatusFailing {
+					failing[testId(pluginId)] = true
+				}
+			}
+			for configId, summary := range summaries {
+				if summary.Importance != common.ImportanceCritical {
+					continue
+				}
+				health.CriticalTests.Total++
+				if failing[configId] {
+					health.CriticalTests.Failing++
+				}
+			}
+		}
+	}
+	if err != nil {
+		r.logger.Error("error fetching the critical tests", "err", err)
+		health.CriticalTests.HealthCheck = client.HealthCheck{Status: client.HealthUnknown, Message: "unable to fetch the tests"}
+		return
+	}
+	health.CriticalTests.HealthCheck = client.HealthCheck{Status: client.HealthOk}
+	if health.CriticalTests.Failing > 0 {
+		health.CriticalTests.HealthCheck = client.HealthCheck{Status: client.HealthFailing,
+			Message: strconv.Itoa(health.CriticalTests.Failing) + " critical tests are failing"}
+	}
+}
@@ -18,21 +18,31 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/cisco-open/synthetic-heart/common"
 	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/slo"
 	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
 	gmux "github.com/gorilla/mux"
 	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/cors"
 	"io/ioutil"
 	"log"
+	"maps"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -41,29 +51,22 @@ import (
 
 const PingRefreshFrequency = 15 * time.Second
 
+// openApiSpec is the OpenAPI v3 spec of the rest api, served at /openapi.json (keep it in sync with the routes)
+//
+//go:embed openapi.json
+var openApiSpec []byte
+
 type RestApi struct {
 	config        RestApiConfig
 	srv           *http.Server
 	store         storage.RedisSynHeartStore
-	PingResponse  PingApiResponse
+	PingResponse  client.PingResponse
 	pingRespMutex *sync.Mutex
 	logger        hclog.Logger
-}
-
-type PingApiResponse struct {
-	Message     string                    `json:"message"`
-	LastUpdated string                    `json:"lastUpdated"`
-	Details     string                    `json:"details"`
-	Status      int                       `json:"status"`
-	FailedTests map[string]FailedTestInfo `json:"failedTests"`
-}
-
-type FailedTestInfo struct {
-	Name         string `json:"name"`
-	Namespace    string `json:"namespace"`
-	TestConfigId string `json:"testId"`
-	DisplayName  string `json:"displayName"`
-	Status       int    `json:"status"`
+	cache         *responseCache
+	watchers      *notifier[struct{}]
+	runWatchers   *notifier[string]
+	tenants       []*Tenant // multi-tenant mode if not empty
 }
 
 type RestApiConfig struct {
@@ -71,60 +74,201 @@ type RestApiConfig struct {
 	StorageAddress string `yaml:"storageAddress"`
 	UIAddress      string `yaml:"uiAddress"`
 	DebugMode      bool   `yaml:"debugMode"`
+	// ConfigHistoryLength is the number of versions of each test config kept (must match the controller)
+	ConfigHistoryLength int `yaml:"configHistoryLength"`
+	// FederationStaleAfter is how long after its last summary a cluster of the federation is considered stale
+	FederationStaleAfter time.Duration `yaml:"federationStaleAfter"`
+	// Auth configures the authentication of the requests (none if not set)
+	Auth AuthConfig `yaml:"auth"`
+	// SynTestWrites enables the endpoints creating, updating and deleting tests (see syntests.go)
+	SynTestWrites bool `yaml:"synTestWrites"`
+	// AllowUnauthenticatedWrites serves the endpoints changing data (e.g. silences) without authentication, they're
+	// only served when auth is configured otherwise
+	AllowUnauthenticatedWrites bool `yaml:"allowUnauthenticatedWrites"`
+	// RateLimit limits the rate of the requests of each client (disabled if not set)
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	// CORS configures the origins and headers allowed for browser based clients (all origins by default)
+	CORS CORSConfig `yaml:"cors"`
+	// Compression configures the gzip compression of the responses (enabled by default)
+	Compression CompressionConfig `yaml:"compression"`
+	// CacheTTL is how long the responses of the hot endpoints are cached (default 5s, negative disables the cache)
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	// ControllerHeartbeatDeadline is how long after its last heartbeat the controller is considered down in the system
+	// health (default 3m)
+	ControllerHeartbeatDeadline time.Duration `yaml:"controllerHeartbeatDeadline"`
+	// AgentStatusDeadline is how long after its last status an agent is considered stale (default 60s, same as the
+	// controller)
+	AgentStatusDeadline time.Duration `yaml:"agentStatusDeadline"`
+	// DisableDashboard turns off the web ui served at /dashboard/
+	DisableDashboard bool `yaml:"disableDashboard"`
+}
+
+// CORSConfig configures the cross-origin requests of browser based clients (e.g. a ui hosted on another origin)
+type CORSConfig struct {
+	// AllowedOrigins are the origins (or patterns with a *, e.g. https://*.example.com) allowed to make requests, all
+	// origins if empty
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	// AllowedHeaders are request headers allowed in addition to the default ones (e.g. Authorization)
+	AllowedHeaders []string `yaml:"allowedHeaders"`
+	// ExposedHeaders are response headers exposed to the clients in addition to the default ones (e.g. X-Total-Count)
+	ExposedHeaders []string `yaml:"exposedHeaders"`
+	// AllowCredentials allows requests with credentials (cookies or client certificates)
+	AllowCredentials bool `yaml:"allowCredentials"`
+	// MaxAge is how long (in seconds) the browsers can cache the result of a preflight request (0 for their default)
+	MaxAge int `yaml:"maxAge"`
 }
 
+// DefaultFederationStaleAfter is how long after its last summary a cluster is considered stale, if not configured
+const DefaultFederationStaleAfter = 5 * time.Minute
+
 func NewRestApi(configPath string) (*RestApi, error) {
+	pluginConfig := RestApiConfig{}
+	b, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return &RestApi{}, errors.Wrap(err, "error reading file")
+	}
 
+	err = common.ParseYMLConfig(string(b), &pluginConfig)
+	if err != nil {
+		return &RestApi{}, errors.Wrap(err, "error parsing config")
+	}
+	return NewRestApiFromConfig(pluginConfig)
+}
+
+// NewRestApiFromConfig Creates the rest api from its parsed config
+func NewRestApiFromConfig(pluginConfig RestApiConfig) (*RestApi, error) {
 	r := RestApi{}
-	pluginConfig := RestApiConfig{}
 	r.pingRespMutex = &sync.Mutex{}
 
 	r.logger = hclog.New(&hclog.LoggerOptions{
 		Name:  "restapi",
 		Level: hclog.LevelFromString(os.Getenv("LOG_LEVEL")),
 	})
+	r.config = pluginConfig
+	r.cache = newResponseCache(pluginConfig.CacheTTL)
+	r.watchers = newNotifier[struct{}](1)
+	r.runWatchers = newNotifier[string](TestRunStreamBuffer)
 
-	b, err := ioutil.ReadFile(configPath)
+	auth, err := NewAuthenticator(pluginConfig.Auth, r.logger)
 	if err != nil {
-		return &RestApi{}, errors.Wrap(err, "error reading file")
+		return &RestApi{}, errors.Wrap(err, "error setting up authentication")
 	}
-
-	err = common.ParseYMLConfig(string(b), &pluginConfig)
-	if err != nil {
-		return &RestApi{}, errors.Wrap(err, "error parsing config")
+	// the endpoints changing data are only served to authenticated users, unless explicitly allowed (e.g. dev mode)
+	writes := auth != nil || pluginConfig.AllowUnauthenticatedWrites
+	if pluginConfig.SynTestWrites && !writes {
+		return &RestApi{}, errors.New("synTestWrites needs authentication (auth), or allowUnauthenticatedWrites")
+	}
+	if !writes {
+		r.logger.Info("authentication isn't configured, the endpoints changing data are disabled")
 	}
-	r.config = pluginConfig
 
 	router := gmux.NewRouter()
 
 	// Setup HTTP response
 	router.HandleFunc("/ui", r.RedirectToUi)
+	if !pluginConfig.DisableDashboard {
+		dashboard, err := dashboardHandler()
+		if err != nil {
+			return &RestApi{}, errors.Wrap(err, "error loading dashboard")
+		}
+		router.Handle("/dashboard", http.RedirectHandler("/dashboard/", http.StatusMovedPermanently))
+		router.PathPrefix("/dashboard/").Handler(dashboard).Methods(http.MethodGet, http.MethodHead)
+	}
 
+	router.HandleFunc("/openapi.json", r.GetOpenApiSpec).Methods(http.MethodGet)
+	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/ping", r.GetPing)
-	router.HandleFunc("/api/v1/agents", r.GetAllAgents)
-	router.HandleFunc("/api/v1/testconfigs/summary", r.GetAllTests)
+	router.HandleFunc("/api/v1/healthz/system", r.GetSystemHealth).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/agents", r.cache.handler("agents", cacheTopicAgents, r.GetAllAgents))
+	router.HandleFunc("/api/v1/agents/health", r.GetAgentsHealth).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/agent/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}", r.GetAgent).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/agent/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}", r.DeregisterAgent).Methods(http.MethodDelete)
+	router.HandleFunc("/api/v1/testconfigs/summary", r.WatchAllTests).Methods(http.MethodGet).Queries("watch", "true")
+	router.HandleFunc("/api/v1/testconfigs/summary", r.cache.handler("testConfigSummary", cacheTopicConfigs, r.GetAllTests))
+	router.HandleFunc("/api/v1/testconfig/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}", r.WatchTestConfig).Methods(http.MethodGet).Queries("watch", "true")
 	router.HandleFunc("/api/v1/testconfig/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}", r.GetTestConfig)
-	router.HandleFunc("/api/v1/plugins/status", r.GetAllPluginStatus)
+	router.HandleFunc("/api/v1/testconfig/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/history", r.GetTestConfigHistory).Methods(http.MethodGet)
+	if writes {
+		router.HandleFunc("/api/v1/testconfig/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/rollback", r.RollbackTestConfig).Methods(http.MethodPost)
+		router.HandleFunc("/api/v1/testconfig/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/pin", r.DeleteTestConfigPin).Methods(http.MethodDelete)
+	}
+	if pluginConfig.SynTestWrites {
+		router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}", r.PutSynTest).Methods(http.MethodPut)
+		router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}", r.DeleteSynTest).Methods(http.MethodDelete)
+	}
+	router.HandleFunc("/api/v1/syntests", r.GetSynTests).Methods(http.MethodGet)
+	if writes {
+		router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}/trigger", r.TriggerTest).Methods(http.MethodPost)
+	}
+	router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}/runs/{runId:[a-zA-z0-9-]+}", r.GetTriggeredRun).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}/runs/{runId:[a-zA-z0-9-]+}/logs", r.GetRunLogs).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}/results", r.GetTestResults).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}/agents", r.CompareAgents).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/syntests/{name:[a-zA-z0-9-]+}/results/export", r.ExportTestResults).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/plugins/status", r.cache.handler("pluginStatus", cacheTopicNone, r.GetAllPluginStatus))
 	router.HandleFunc("/api/v1/plugin/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/health", r.GetPluginHealth)
 	router.HandleFunc("/api/v1/plugin/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/lastUnhealthy", r.GetPluginHealth)
-	router.HandleFunc("/api/v1/testruns/status", r.GetAllTestStatus)
+	router.HandleFunc("/api/v1/testruns/stream", r.StreamTestRuns).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/testruns/status", r.cache.handler("testRunStatus", cacheTopicTestRuns, r.GetAllTestStatus))
 	router.HandleFunc("/api/v1/testrun/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/latest", r.GetTestRun)
 	router.HandleFunc("/api/v1/testrun/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/lastFailed", r.GetTestRun)
 	router.HandleFunc("/api/v1/testrun/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/latest/logs", r.GetTestLogs)
 	router.HandleFunc("/api/v1/testrun/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/lastFailed/logs", r.GetTestLogs)
+	router.HandleFunc("/api/v1/testrun/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/logs", r.GetForwardedLogIds)
+	router.HandleFunc("/api/v1/testrun/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}/logs/{runId:[a-zA-z0-9-]+}", r.GetForwardedLogs)
+	router.HandleFunc("/api/v1/stats/{groupBy:tests|namespaces|agents}", r.GetStats).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/graphql", r.Graphql).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/api/v1/badge/{namespace:[a-zA-z0-9-]+}/{test:[a-zA-z0-9-]+}.svg", r.GetBadge).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/slos", r.GetAllSLOs)
+	router.HandleFunc("/api/v1/slo/{id:[a-zA-z0-9-]+\\/[a-zA-z0-9-]+}", r.GetSLO)
+	router.HandleFunc("/api/v1/audit", r.GetAuditEvents)
+	router.HandleFunc("/api/v1/silences", r.GetAllSilences).Methods(http.MethodGet)
+	if writes {
+		router.HandleFunc("/api/v1/silences", r.CreateSilence).Methods(http.MethodPost)
+		router.HandleFunc("/api/v1/silence/{id:[a-zA-z0-9-]+}", r.DeleteSilence).Methods(http.MethodDelete)
+	}
+	router.HandleFunc("/api/v1/federation/clusters", r.GetAllClusters).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/federation/cluster/{cluster:[a-zA-z0-9-.]+}", r.GetCluster).Methods(http.MethodGet)
+	if writes {
+		router.HandleFunc("/api/v1/federation/cluster/{cluster:[a-zA-z0-9-.]+}", r.DeleteCluster).Methods(http.MethodDelete)
+	}
+	router.HandleFunc("/api/v1/federation/tests", r.GetFederatedTests).Methods(http.MethodGet)
 
 	if pluginConfig.DebugMode {
 		router.PathPrefix("/debug/").Handler(http.DefaultServeMux)
 	}
-	handler := cors.Default().Handler(router)
+	router.Use(MetricsMiddleware)
+	if auth != nil {
+		router.Use(auth.Middleware, TenantMetricsMiddleware, AuthorizeMiddleware)
+		r.tenants = auth.tenants
+	}
+	if limiter := NewRateLimiter(pluginConfig.RateLimit); limiter != nil {
+		router.Use(limiter.Middleware)
+	}
+	router.Use(r.AuditMiddleware)
+	var handler http.Handler = router
+	if compress := CompressionMiddleware(pluginConfig.Compression); compress != nil {
+		handler = compress(handler)
+	}
+	handler = cors.New(cors.Options{
+		AllowedOrigins: pluginConfig.CORS.AllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: append([]string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization"},
+			pluginConfig.CORS.AllowedHeaders...),
+		ExposedHeaders:   append([]string{client.TotalCountHeader, "Retry-After"}, pluginConfig.CORS.ExposedHeaders...),
+		AllowCredentials: pluginConfig.CORS.AllowCredentials,
+		MaxAge:           pluginConfig.CORS.MaxAge,
+	}).Handler(handler)
 	srv := &http.Server{Addr: r.config.Address, Handler: handler}
 	r.srv = srv
 
 	extStore := storage.NewRedisSynHeartStore(storage.SynHeartStoreConfig{
-		Type:       "redis",
-		BufferSize: 1000,
-		Address:    r.config.StorageAddress,
+		Type:                "redis",
+		BufferSize:          1000,
+		Address:             r.config.StorageAddress,
+		ConfigHistoryLength: r.config.ConfigHistoryLength,
 	}, r.logger)
+	extStore.AddHook(storageMetricsHook{})
 	r.store = extStore
 
 	return &r, nil
@@ -143,6 +287,11 @@ func (r *RestApi) Finish() error {
 func (r *RestApi) RedirectToUi(w http.ResponseWriter, req *http.Request) {
 	r.PrintIPAndUserAgent(req)
 	redirectUrl := r.config.UIAddress
+	if redirectUrl == "" && !r.config.DisableDashboard {
+		// the embedded dashboard, if no ui address set
+		http.Redirect(w, req, "/dashboard/", http.StatusSeeOther)
+		return
+	}
 	if redirectUrl == "" {
 		r.logger.Warn("No UI address configured, redirecting to base url")
 		redirectUrl = req.URL.Path // set it back to root path, if no ui address set
@@ -156,22 +305,70 @@ func (r *RestApi) RedirectToUi(w http.ResponseWriter, req *http.Request) {
 	return
 }
 
+// GetAllAgents Returns the status of the agents (by agent id), filtered and paginated by the query params: test (agents
+// running the test), namespace (of the agent), agent, since/until (last status time), limit and offset
 func (r *RestApi) GetAllAgents(w http.ResponseWriter, req *http.Request) {
 	r.PrintIPAndUserAgent(req)
+	opts, err := parseListOptions(req.URL.Query())
+	if err == nil && opts.Status != "" {
+		err = errors.New("status filter is only supported by the results")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	agents, err := r.store.FetchAllAgentStatus(ctx)
 	if err != nil {
 		r.logger.Error("error fetching agent statuses", "err", err)
 		http.Error(w, "unable to fetch all tests", http.StatusInternalServerError)
+		return
+	}
+	identity, ok := IdentityFromContext(req.Context())
+	agentIds := []string{}
+	for agentId, agent := range agents {
+		if ok && identity.Tenant != nil {
+			// the agents are shared, the tenants only see their tests running on them
+			agent.SynTests = slices.DeleteFunc(agent.SynTests, func(configId string) bool {
+				return !identity.CanAccessTest(configId)
+			})
+			agents[agentId] = agent
+		}
+		if r.matchAgent(opts, agentId, agent) {
+			agentIds = append(agentIds, agentId)
+		}
+	}
+	page := map[string]common.AgentStatus{}
+	for _, agentId := range opts.paginate(w, agentIds) {
+		page[agentId] = agents[agentId]
 	}
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(agents)
+	err = json.NewEncoder(w).Encode(page)
 	if err != nil {
 		r.logger.Error("error encoding json", "err", err)
 	}
 }
 
+// matchAgent Returns whether the agent matches the filters
+func (r *RestApi) matchAgent(opts ListOptions, agentId string, agent common.AgentStatus) bool {
+	_, agentNs, _ := strings.Cut(agentId, "/")
+	if (opts.Agent != "" && agentId != opts.Agent) || (opts.Namespace != "" && agentNs != opts.Namespace) {
+		return false
+	}
+	if opts.Test != "" && !slices.ContainsFunc(agent.SynTests, func(configId string) bool {
+		testName, _, _ := strings.Cut(configId, "/")
+		return testName == opts.Test
+	}) {
+		return false
+	}
+	if opts.hasTimeRange() {
+		statusTime, err := time.Parse(common.TimeFormat, agent.StatusTime)
+		return err == nil && opts.inTimeRange(statusTime)
+	}
+	return true
+}
+
 func (r *RestApi) GetAllTests(w http.ResponseWriter, req *http.Request) {
 	r.PrintIPAndUserAgent(req)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -180,7 +377,11 @@ func (r *RestApi) GetAllTests(w http.ResponseWriter, req *http.Request) {
 	if err != nil {
 		r.logger.Error("error fetching syntests", "err", err)
 		http.Error(w, "unable to fetch all tests", http.StatusInternalServerError)
+		return
 	}
+	maps.DeleteFunc(syntests, func(configId string, _ common.SyntestConfigSummary) bool {
+		return !canAccessTest(req, configId)
+	})
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(syntests)
 	if err != nil {
@@ -188,23 +389,80 @@ func (r *RestApi) GetAllTests(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// GetAllTestStatus Returns the pass ratio of the latest run of the tests on every agent (by plugin id), filtered and
+// paginated by the query params: test, namespace (of the test), agent, status (passing or failing), since/until (end
+// time of the latest run), limit and offset
 func (r *RestApi) GetAllTestStatus(w http.ResponseWriter, req *http.Request) {
 	r.PrintIPAndUserAgent(req)
+	opts, err := parseListOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	w.Header().Set("Content-Type", "application/json")
 	status, err := r.store.FetchAllTestRunStatus(ctx)
 	if err != nil {
 		r.logger.Error("error fetching status from extStore", "err", err)
 		http.Error(w, "error fetching status from extStore", http.StatusInternalServerError)
 		return
 	}
-	err = json.NewEncoder(w).Encode(status)
+	pluginIds := []string{}
+	for pluginId, passRatio := range status {
+		if !canAccessTest(req, pluginId) {
+			continue
+		}
+		match, err := r.matchTestRun(ctx, opts, pluginId, passRatio)
+		if err != nil {
+			r.logger.Error("error fetching latest test run from extStore", "id", pluginId, "err", err)
+			http.Error(w, "error fetching latest test run from extStore", http.StatusInternalServerError)
+			return
+		}
+		if match {
+			pluginIds = append(pluginIds, pluginId)
+		}
+	}
+	page := map[string]string{}
+	for _, pluginId := range opts.paginate(w, pluginIds) {
+		page[pluginId] = status[pluginId]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
 	if err != nil {
 		r.logger.Error("error encoding json", "err", err)
 	}
 }
 
+// matchTestRun Returns whether the latest run of the test on an agent (plugin id) matches the filters, the run is only
+// fetched to filter by time
+func (r *RestApi) matchTestRun(ctx context.Context, opts ListOptions, pluginId string, passRatio string) (bool, error) {
+	testName, testNs, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+	if err != nil {
+		return false, nil
+	}
+	if (opts.Test != "" && testName != opts.Test) || (opts.Namespace != "" && testNs != opts.Namespace) ||
+		(opts.Agent != "" && common.ComputeAgentId(podName, podNs) != opts.Agent) {
+		return false, nil
+	}
+	if opts.Status != "" {
+		ratio, err := strconv.ParseFloat(passRatio, 64)
+		if err != nil || (ratio == 1) != (opts.Status == StatusPassing) {
+			return false, nil
+		}
+	}
+	if !opts.hasTimeRange() {
+		return true, nil
+	}
+	testRun, err := r.store.FetchLatestTestRun(ctx, pluginId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	endTime, err := time.Parse(common.TimeFormat, testRun.EndTime)
+	return err == nil && opts.inTimeRange(endTime), nil
+}
+
 func (r *RestApi) GetAllPluginStatus(w http.ResponseWriter, req *http.Request) {
 	r.PrintIPAndUserAgent(req)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -216,6 +474,9 @@ func (r *RestApi) GetAllPluginStatus(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "error fetching plugin status from extStore", http.StatusInternalServerError)
 		return
 	}
+	maps.DeleteFunc(status, func(pluginId string, _ string) bool {
+		return !canAccessTest(req, pluginId)
+	})
 	err = json.NewEncoder(w).Encode(status)
 	if err != nil {
 		r.logger.Error("error encoding json", "err", err)
@@ -269,8 +530,7 @@ func (r *RestApi) GetTestConfig(w http.ResponseWriter, req *http.Request) {
 	defer cancel()
 
 	// Using GetR to obtain the json directly from redis instead using a function that parses the json
-	// Get Config
-	testConfig, err := r.store.GetR(ctx, fmt.Sprintf(storage.ConfigSynTestJsonFmt, configId))
+	testConfig, err := r.fetchTestConfig(ctx, configId)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			http.Error(w, "no test config found", http.StatusNotFound)
@@ -282,47 +542,145 @@ func (r *RestApi) GetTestConfig(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	// Get raw config (i.e. crd) for the test plugin
-	raw, err := r.store.GetR(ctx, fmt.Sprintf(storage.ConfigSynTestRawFmt, configId))
+	err = json.NewEncoder(w).Encode(testConfig)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			http.Error(w, "no test config found", http.StatusNotFound)
-			return
-		} else {
-			r.logger.Error("error getting raw config for syntest", "id", configId, "err", err)
-			http.Error(w, "unable to fetch config", http.StatusInternalServerError)
-			return
-		}
+		r.logger.Error("error encoding json", "err", err)
+		http.Error(w, "unable to fetch test run", http.StatusInternalServerError)
+		return
 	}
+}
 
-	// Get the status of the test config (i.e. CRD status)
-	status, err := r.store.GetR(ctx, fmt.Sprintf(storage.ConfigSynTestStatusFmt, configId))
+func (r *RestApi) GetTestConfigHistory(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	history, err := r.store.FetchTestConfigHistory(ctx, configId)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
+		r.logger.Error("error fetching config history", "id", configId, "err", err)
+		http.Error(w, "unable to fetch config history", http.StatusInternalServerError)
+		return
+	}
+	if len(history) == 0 {
+		http.Error(w, "no test config found", http.StatusNotFound)
+		return
+	}
+	pinned, err := r.store.FetchTestConfigPin(ctx, configId)
+	if err != nil {
+		r.logger.Error("error fetching config pin", "id", configId, "err", err)
+		http.Error(w, "unable to fetch config history", http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(client.TestConfigHistory{
+		PinnedVersion: pinned,
+		Versions:      history,
+	})
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// RollbackTestConfig Rolls the test config back to a version from its history, and pins it to that version so the
+// controller doesn't deploy the version of the spec again (till the pin is deleted)
+func (r *RestApi) RollbackTestConfig(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	rollback := client.RollbackRequest{}
+	err := json.NewDecoder(req.Body).Decode(&rollback)
+	if err != nil {
+		http.Error(w, "invalid rollback: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rollback.Version == "" {
+		http.Error(w, "invalid rollback: no version provided", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	current, err := r.store.FetchTestConfig(ctx, configId)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
 			http.Error(w, "no test config found", http.StatusNotFound)
 			return
-		} else {
-			r.logger.Error("error getting config status for syntest", "id", configId, "err", err)
-			http.Error(w, "unable to fetch config", http.StatusInternalServerError)
+		}
+		r.logger.Error("error fetching test config", "id", configId, "err", err)
+		http.Error(w, "unable to roll back test config", http.StatusInternalServerError)
+		return
+	}
+	// pin first, so the controller doesn't overwrite the rolled back config
+	err = r.store.WriteTestConfigPin(ctx, configId, rollback.Version)
+	if err != nil {
+		r.logger.Error("error writing config pin", "id", configId, "err", err)
+		http.Error(w, "unable to roll back test config", http.StatusInternalServerError)
+		return
+	}
+	if current.Version != rollback.Version {
+		err = r.store.RollbackTestConfig(ctx, configId, rollback.Version)
+		if err != nil {
+			// don't leave a pin the controller can't deploy
+			if pinErr := r.store.DeleteTestConfigPin(ctx, configId); pinErr != nil {
+				r.logger.Warn("error deleting config pin", "id", configId, "err", pinErr)
+			}
+			if errors.Is(err, storage.ErrNotFound) {
+				http.Error(w, "version "+rollback.Version+" not found in the config history", http.StatusNotFound)
+				return
+			}
+			r.logger.Error("error rolling back test config", "id", configId, "version", rollback.Version, "err", err)
+			http.Error(w, "unable to roll back test config", http.StatusInternalServerError)
 			return
 		}
 	}
+	r.logger.Info("rolled back test config", "id", configId, "version", rollback.Version, "oldVersion", current.Version)
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:   requestActor(req),
+		Kind:    common.AuditKindSynTest,
+		Object:  configId,
+		Action:  common.AuditActionPinned,
+		Message: rollback.Comment,
+		Details: withClaimedActor(map[string]string{
+			"oldVersion": current.Version,
+			"version":    rollback.Version,
+		}, rollback.Actor),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	type Response struct {
-		TestConfig   json.RawMessage `json:"testConfig"`
-		ConfigStatus json.RawMessage `json:"configStatus"`
-		RawConfig    string          `json:"rawConfig"`
+// DeleteTestConfigPin Unpins the test config, the controller deploys the version of the spec again on its next
+// reconciliation
+func (r *RestApi) DeleteTestConfigPin(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
 	}
-	err = json.NewEncoder(w).Encode(Response{
-		TestConfig:   json.RawMessage(testConfig),
-		ConfigStatus: json.RawMessage(status),
-		RawConfig:    raw,
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := r.store.DeleteTestConfigPin(ctx, configId)
 	if err != nil {
-		r.logger.Error("error encoding json", "err", err)
-		http.Error(w, "unable to fetch test run", http.StatusInternalServerError)
+		r.logger.Error("error deleting config pin", "id", configId, "err", err)
+		http.Error(w, "unable to delete config pin", http.StatusInternalServerError)
 		return
 	}
+	r.logger.Info("deleted config pin", "id", configId)
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:  requestActor(req),
+		Kind:   common.AuditKindSynTest,
+		Object: configId,
+		Action: common.AuditActionUnpinned,
+	})
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (r *RestApi) GetTestRun(w http.ResponseWriter, req *http.Request) {
@@ -397,6 +755,463 @@ func (r *RestApi) GetTestLogs(w http.ResponseWriter, req *http.Request) {
 	w.Write([]byte(logs))
 }
 
+func (r *RestApi) GetAllSLOs(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	reports, err := slo.FetchAllReports(ctx, &r.store, r.logger, time.Now())
+	if err != nil {
+		r.logger.Error("error computing slos", "err", err)
+		http.Error(w, "error computing slos", http.StatusInternalServerError)
+		return
+	}
+	maps.DeleteFunc(reports, func(configId string, _ slo.Report) bool {
+		return !canAccessTest(req, configId)
+	})
+	err = json.NewEncoder(w).Encode(reports)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+func (r *RestApi) GetSLO(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report, err := slo.FetchReport(ctx, &r.store, configId, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "no test config found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, slo.ErrNoSLO) {
+			http.Error(w, "test has no slo", http.StatusNotFound)
+			return
+		}
+		r.logger.Error("error computing slo", "id", configId, "err", err)
+		http.Error(w, "unable to compute slo", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// GetAuditEvents Returns the latest audit events (newest first), filtered by the query params:
+// count (default 100), kind, action, source, actor, object (prefix, e.g. a test id also matches its plugins) and since
+// (RFC3339)
+func (r *RestApi) GetAuditEvents(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	query := req.URL.Query()
+	count := 100
+	if c := query.Get("count"); c != "" {
+		var err error
+		count, err = strconv.Atoi(c)
+		if err != nil || count <= 0 || count > common.AuditLogMaxLen {
+			http.Error(w, fmt.Sprintf("count must be between 1 and %d", common.AuditLogMaxLen), http.StatusBadRequest)
+			return
+		}
+	}
+	var since time.Time
+	if s := query.Get("since"); s != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, "invalid since, must be RFC3339: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	kind, action, source, object := query.Get("kind"), query.Get("action"), query.Get("source"), query.Get("object")
+	actor := query.Get("actor")
+	match := func(event common.AuditEvent) bool {
+		return (kind == "" || event.Kind == kind) &&
+			(action == "" || event.Action == action) &&
+			(source == "" || event.Source == source) &&
+			(actor == "" || event.Actor == actor) &&
+			strings.HasPrefix(event.Object, object) &&
+			!event.Time.Before(since) &&
+			(event.Kind == common.AuditKindSilence || event.Kind == common.AuditKindAgent ||
+				// the calls which aren't on a test have the path as object
+				(event.Kind == common.AuditKindApiCall && strings.HasPrefix(event.Object, "/")) ||
+				canAccessTest(req, event.Object))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	events, err := r.store.FetchAuditEvents(ctx, count, match)
+	if err != nil {
+		r.logger.Error("error fetching audit events from extStore", "err", err)
+		http.Error(w, "error fetching audit events from extStore", http.StatusInternalServerError)
+		return
+	}
+	err = json.NewEncoder(w).Encode(events)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// recordAuditEvent Adds an event (recorded by the rest api) to the audit log, errors are only logged
+func (r *RestApi) recordAuditEvent(ctx context.Context, event common.AuditEvent) {
+	event.Time = time.Now()
+	event.Source = "restapi"
+	err := r.store.WriteAuditEvent(ctx, event)
+	if err != nil {
+		r.logger.Warn("error writing audit event", "object", event.Object, "action", event.Action, "err", err)
+	}
+}
+
+func (r *RestApi) GetAllSilences(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	silences, err := r.store.FetchAllSilences(ctx)
+	if err != nil {
+		r.logger.Error("error fetching silences from extStore", "err", err)
+		http.Error(w, "error fetching silences from extStore", http.StatusInternalServerError)
+		return
+	}
+	maps.DeleteFunc(silences, func(_ string, silence common.Silence) bool {
+		return !canAccessSilence(req, silence)
+	})
+	err = json.NewEncoder(w).Encode(silences)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+func (r *RestApi) CreateSilence(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	silence := common.Silence{}
+	err := json.NewDecoder(req.Body).Decode(&silence)
+	if err != nil {
+		http.Error(w, "invalid silence: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		http.Error(w, "invalid silence: endsAt must be after startsAt", http.StatusBadRequest)
+		return
+	}
+	// the silence is created by the identity, the createdBy of the client is only kept in the audit log
+	claimedActor := silence.CreatedBy
+	if actor := requestActor(req); actor != "" {
+		silence.CreatedBy = actor
+	}
+	// users restricted to some namespaces can only silence the tests of their namespaces
+	if !canAccessSilence(req, silence) {
+		http.Error(w, "silence must be limited to testNamespaces you have access to", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if silence.Id == "" {
+		b := make([]byte, 8)
+		_, err = rand.Read(b)
+		if err != nil {
+			r.logger.Error("error generating silence id", "err", err)
+			http.Error(w, "unable to generate silence id", http.StatusInternalServerError)
+			return
+		}
+		silence.Id = hex.EncodeToString(b)
+	} else {
+		// users restricted to some namespaces can't replace the silences of other namespaces either
+		silences, err := r.store.FetchAllSilences(ctx)
+		if err != nil {
+			r.logger.Error("error fetching silences from extStore", "err", err)
+			http.Error(w, "unable to write silence", http.StatusInternalServerError)
+			return
+		}
+		if existing, ok := silences[silence.Id]; ok && !canAccessSilence(req, existing) {
+			http.Error(w, "no access to the silence "+silence.Id, http.StatusForbidden)
+			return
+		}
+	}
+	err = r.store.WriteSilence(ctx, silence)
+	if err != nil {
+		r.logger.Error("error writing silence to extStore", "id", silence.Id, "err", err)
+		http.Error(w, "unable to write silence", http.StatusInternalServerError)
+		return
+	}
+	r.logger.Info("created silence", "id", silence.Id, "startsAt", silence.StartsAt, "endsAt", silence.EndsAt)
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:   requestActor(req),
+		Kind:    common.AuditKindSilence,
+		Object:  silence.Id,
+		Action:  common.AuditActionCreated,
+		Message: silence.Comment,
+		Details: withClaimedActor(map[string]string{
+			"startsAt": silence.StartsAt.Format(time.RFC3339),
+			"endsAt":   silence.EndsAt.Format(time.RFC3339),
+		}, claimedActor),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(silence)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+func (r *RestApi) DeleteSilence(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	id, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no silence id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	silences, err := r.store.FetchAllSilences(ctx)
+	if err != nil {
+		r.logger.Error("error fetching silences from extStore", "err", err)
+		http.Error(w, "unable to delete silence", http.StatusInternalServerError)
+		return
+	}
+	// users restricted to some namespaces can only delete the silences of their namespaces
+	if silence, ok := silences[id]; ok && !canAccessSilence(req, silence) {
+		http.Error(w, "no access to the silence "+id, http.StatusForbidden)
+		return
+	}
+	err = r.store.DeleteSilence(ctx, id)
+	if err != nil {
+		r.logger.Error("error deleting silence from extStore", "id", id, "err", err)
+		http.Error(w, "unable to delete silence", http.StatusInternalServerError)
+		return
+	}
+	r.logger.Info("deleted silence", "id", id)
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:  requestActor(req),
+		Kind:   common.AuditKindSilence,
+		Object: id,
+		Action: common.AuditActionDeleted,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAllClusters Returns the overview of every cluster of the federation, sorted by name
+func (r *RestApi) GetAllClusters(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	summaries, err := r.store.FetchAllClusterSummaries(ctx)
+	if err != nil {
+		r.logger.Error("error fetching cluster summaries from extStore", "err", err)
+		http.Error(w, "error fetching cluster summaries from extStore", http.StatusInternalServerError)
+		return
+	}
+	clusters := []client.ClusterOverview{}
+	for _, summary := range summaries {
+		overview := client.ClusterOverview{
+			Cluster:     summary.Cluster,
+			Time:        summary.Time,
+			Stale:       r.isStale(summary),
+			HealthScore: summary.HealthScore,
+			Agents:      summary.Agents,
+			Tests:       len(summary.Tests),
+		}
+		for _, test := range summary.Tests {
+			if test.FailingAgents > 0 {
+				overview.FailingTests++
+			}
+		}
+		clusters = append(clusters, overview)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Cluster < clusters[j].Cluster })
+	err = json.NewEncoder(w).Encode(clusters)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// GetCluster Returns the summary of a cluster of the federation, with the results of its tests
+func (r *RestApi) GetCluster(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	cluster, ok := gmux.Vars(req)["cluster"]
+	if !ok {
+		http.Error(w, "no cluster provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	summaries, err := r.store.FetchAllClusterSummaries(ctx)
+	if err != nil {
+		r.logger.Error("error fetching cluster summaries from extStore", "err", err)
+		http.Error(w, "error fetching cluster summaries from extStore", http.StatusInternalServerError)
+		return
+	}
+	summary, ok := summaries[cluster]
+	if !ok {
+		http.Error(w, "no cluster found", http.StatusNotFound)
+		return
+	}
+	summary.Tests = slices.DeleteFunc(summary.Tests, func(test common.FederatedTestResult) bool {
+		return !canAccess(req, test.Namespace)
+	})
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(summary)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// DeleteCluster Removes a (decommissioned) cluster from the federation, it's added again if its controller still
+// replicates its results. Only authenticated users who can access all the namespaces can remove clusters, as they have
+// the tests of all the namespaces.
+func (r *RestApi) DeleteCluster(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	cluster, ok := gmux.Vars(req)["cluster"]
+	if !ok {
+		http.Error(w, "no cluster provided", http.StatusUnprocessableEntity)
+		return
+	}
+	identity, authenticated := IdentityFromContext(req.Context())
+	if !authenticated {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "removing clusters needs authentication", http.StatusUnauthorized)
+		return
+	}
+	if identity.Restricted() {
+		http.Error(w, "removing clusters needs access to all namespaces", http.StatusForbidden)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := r.store.DeleteClusterSummary(ctx, cluster)
+	if err != nil {
+		r.logger.Error("error deleting cluster summary from extStore", "cluster", cluster, "err", err)
+		http.Error(w, "unable to delete cluster", http.StatusInternalServerError)
+		return
+	}
+	r.logger.Info("deleted cluster summary", "cluster", cluster)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFederatedTests Returns the results of the tests of all the clusters of the federation, filtered by the query
+// params: cluster, namespace, plugin and failing (true to only return the tests failing on any agent)
+func (r *RestApi) GetFederatedTests(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	query := req.URL.Query()
+	cluster, namespace, plugin := query.Get("cluster"), query.Get("namespace"), query.Get("plugin")
+	failing := query.Get("failing") == "true"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	summaries, err := r.store.FetchAllClusterSummaries(ctx)
+	if err != nil {
+		r.logger.Error("error fetching cluster summaries from extStore", "err", err)
+		http.Error(w, "error fetching cluster summaries from extStore", http.StatusInternalServerError)
+		return
+	}
+	tests := []common.FederatedTestResult{}
+	for _, summary := range summaries {
+		if cluster != "" && summary.Cluster != cluster {
+			continue
+		}
+		for _, test := range summary.Tests {
+			if (namespace != "" && test.Namespace != namespace) || (plugin != "" && test.Plugin != plugin) ||
+				(failing && test.FailingAgents == 0) || !canAccess(req, test.Namespace) {
+				continue
+			}
+			tests = append(tests, test)
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		a, b := tests[i], tests[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	err = json.NewEncoder(w).Encode(tests)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// isStale Returns whether no summary was received from the cluster for FederationStaleAfter
+func (r *RestApi) isStale(summary common.ClusterSummary) bool {
+	staleAfter := r.config.FederationStaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultFederationStaleAfter
+	}
+	return time.Since(summary.Time) > staleAfter
+}
+
+func (r *RestApi) GetForwardedLogIds(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	id, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	runIds, err := r.store.FetchTestRunLogIds(ctx, id)
+	if err != nil {
+		r.logger.Error("error fetching forwarded log ids", "id", id, "err", err)
+		http.Error(w, "unable to fetch forwarded log ids", http.StatusInternalServerError)
+		return
+	}
+	err = json.NewEncoder(w).Encode(runIds)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+func (r *RestApi) GetForwardedLogs(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	vars := gmux.Vars(req)
+	id, ok := vars["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	runId, ok := vars["runId"]
+	if !ok {
+		http.Error(w, "no run id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	logs, err := r.store.FetchTestRunLogs(ctx, id, runId)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "no logs found", http.StatusNotFound)
+			return
+		}
+		r.logger.Error("error fetching forwarded logs", "id", id, "runId", runId, "err", err)
+		http.Error(w, "unable to fetch logs", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(logs))
+}
+
+// GetOpenApiSpec Returns the OpenAPI spec of the rest api
+func (r *RestApi) GetOpenApiSpec(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openApiSpec)
+}
+
 func (r *RestApi) GetPing(w http.ResponseWriter, req *http.Request) {
 	r.PrintIPAndUserAgent(req)
 	w.Header().Set("Content-Type", "application/json")
@@ -431,17 +1246,17 @@ func (r *RestApi) UpdatePingResponse(ctx context.Context, redisAddress string) {
 		return
 	}
 
-	resp := PingApiResponse{
+	resp := client.PingResponse{
 		Message:     "",
 		LastUpdated: "",
 		Details:     "",
-		FailedTests: map[string]FailedTestInfo{},
+		FailedTests: map[string]client.FailedTestInfo{},
 		Status:      0,
 	}
 
 	maxFailedTestNames := 3
 	failedTestNames := map[string]bool{}
-	failedTests := map[string]FailedTestInfo{} // Used to construct the details string later
+	failedTests := map[string]client.FailedTestInfo{} // Used to construct the details string later
 	overallStatus := 3
 
 	for pluginId, passRatioStr := range allStatus {
@@ -464,7 +1279,7 @@ func (r *RestApi) UpdatePingResponse(ctx context.Context, redisAddress string) {
 			if failedTestInfo, ok := failedTests[testName]; ok {
 				failedTests[testName] = failedTestInfo
 			} else {
-				fti := FailedTestInfo{
+				fti := client.FailedTestInfo{
 					Name:         testName,
 					Namespace:    testNs,
 					TestConfigId: configId,
@@ -544,18 +1359,33 @@ func GetLegacyStatus(passRatio float64) int {
 }
 
 func (r *RestApi) PrintIPAndUserAgent(req *http.Request) {
-	ip := req.Header.Get("X-Real-Ip")
-	if ip == "" {
-		ip = req.Header.Get("X-Forwarded-For")
-	}
-	if ip == "" {
-		ip = req.RemoteAddr
-	}
 	userAgent := req.UserAgent()
-	r.logger.Info("request", "path", req.URL.Path, "ip", ip, "user", userAgent)
+	r.logger.Info("request", "path", req.URL.Path, "ip", r.requestIP(req), "user", userAgent)
+}
+
+// requestIP Returns the ip of the client of the request (from the proxy headers, if trusted, see RateLimitConfig)
+func (r *RestApi) requestIP(req *http.Request) string {
+	return clientIP(req, r.config.RateLimit.TrustProxyHeaders)
 }
 
 func main() {
+	// dev mode runs an in-memory redis and an agent along with the rest api (see dev.go)
+	if len(os.Args) > 1 && os.Args[1] == "dev" {
+		err := runDev(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// simulation mode writes the results of fake agents to storage (see simulate.go)
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		err := runSimulate(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	configFilePath := ""
 	if len(os.Args) > 1 { // Override if a path has been provided - used for debugging
 		configFilePath = os.Args[1]
@@ -566,24 +1396,51 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	listener, err := net.Listen("tcp", restApi.config.Address)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = restApi.Serve(context.Background(), listener)
+	if err != nil {
+		log.Println("error running server: ", err)
+		os.Exit(1)
+	}
+}
+
+// Serve Starts the background routines of the rest api and serves the requests on the listener, until ctx is done
+func (r *RestApi) Serve(ctx context.Context, listener net.Listener) error {
+	// Drop the cached responses when the data changes in storage, keep the tests selected by the tenants up to date and
+	// notify the watches of the test configs and the streams of the test runs
+	go r.watchInvalidations(ctx)
+	go r.watchTenants(ctx)
+	go r.watchConfigEvents(ctx)
+	go r.watchTestRunEvents(ctx)
+
 	// Start the Ping Api polling/updating
 	go func() {
 		ticker := time.NewTicker(PingRefreshFrequency)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
-				ctx, cancel := context.WithTimeout(context.Background(), PingRefreshFrequency)
-				restApi.UpdatePingResponse(ctx, restApi.config.StorageAddress)
+				pingCtx, cancel := context.WithTimeout(ctx, PingRefreshFrequency)
+				r.UpdatePingResponse(pingCtx, r.config.StorageAddress)
 				cancel()
+			case <-ctx.Done():
+				err := r.Finish()
+				if err != nil {
+					r.logger.Warn("error shutting down the server", "err", err)
+				}
+				return
 			}
 		}
 	}()
 
 	// Start the Server
-	log.Println("running server at: " + restApi.config.Address)
-	err = restApi.srv.ListenAndServe()
+	log.Println("running server at: " + listener.Addr().String())
+	err := r.srv.Serve(listener)
 	if err != nil && err != http.ErrServerClosed {
-		log.Println("error running server: ", err)
-		os.Exit(1)
+		return err
 	}
+	return nil
 }
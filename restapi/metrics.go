@@ -0,0 +1,153 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	gmux "github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+// Metrics about the rest api itself (rather than the tests), served at /metrics
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "syntheticheart_restapi_request_duration_seconds",
+		Help: "The latency of the requests, by route (path template), method and status code",
+	}, []string{"route", "method", "code"})
+	requestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "syntheticheart_restapi_requests_in_flight",
+		Help: "The number of requests being served",
+	})
+	storageCmdDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "syntheticheart_restapi_storage_command_duration_seconds",
+		Help: "The latency of the external storage (redis) commands, pipelines are observed as a single pipeline command",
+	}, []string{"command"})
+	storageCmdErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "syntheticheart_restapi_storage_command_errors_total",
+		Help: "The number of failed external storage (redis) commands",
+	}, []string{"command"})
+	rateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "syntheticheart_restapi_rate_limited_requests_total",
+		Help: "The number of requests rejected because their client exceeded its rate limit, by route",
+	}, []string{"route"})
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "syntheticheart_restapi_cache_requests_total",
+		Help: "The number of lookups of the caches of the rest api, by cache and result (hit or miss)",
+	}, []string{"cache", "result"})
+	tenantRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "syntheticheart_restapi_tenant_requests_total",
+		Help: "The number of requests of the tenants (multi-tenant mode), by tenant, route (path template) and status code",
+	}, []string{"tenant", "route", "code"})
+)
+
+// MetricsMiddleware Records the latency and status of the requests, by the path template of their route (so the
+// ids in the paths don't make new series), unmatched requests aren't recorded
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		route := routeTemplate(req)
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+		requestDuration.WithLabelValues(route, req.Method, strconv.Itoa(sw.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeTemplate Returns the path template of the route of the request
+func routeTemplate(req *http.Request) string {
+	if r := gmux.CurrentRoute(req); r != nil {
+		if tmpl, err := r.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return "unknown"
+}
+
+// statusWriter records the status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush Flushes the response if the underlying writer supports it (e.g. streamed exports)
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// observeCache Records a lookup of a cache
+func observeCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// storageMetricsHook is a redis hook recording the latency of the storage commands, a missing key isn't an error
+type storageMetricsHook struct{}
+
+func (storageMetricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (storageMetricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observeStorageCmd(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (storageMetricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observeStorageCmd("pipeline", start, err)
+		return err
+	}
+}
+
+func observeStorageCmd(command string, start time.Time, err error) {
+	storageCmdDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, redis.Nil) {
+		storageCmdErrors.WithLabelValues(command).Inc()
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+// MaxListLimit is the max number of items returned by a page of a list endpoint
+const MaxListLimit = 10000
+
+// Values of the status filter of the results
+const (
+	StatusPassing = "passing"
+	StatusFailing = "failing"
+)
+
+// ListOptions are the pagination and filters of the list endpoints (results and agents), from the query params:
+// limit, offset, test, namespace, agent, status (results only) and since/until (RFC3339)
+type ListOptions struct {
+	Limit     int // 0 for no limit
+	Offset    int
+	Test      string // name of the test
+	Namespace string // namespace of the test (results) or of the agent (agents)
+	Agent     string // id of the agent (<pod name>/<namespace>)
+	Status    string // passing or failing
+	Since     time.Time
+	Until     time.Time
+}
+
+// parseListOptions Returns the list options of the query, or an error if any is invalid
+func parseListOptions(query url.Values) (ListOptions, error) {
+	opts := ListOptions{
+		Test:      query.Get("test"),
+		Namespace: query.Get("namespace"),
+		Agent:     query.Get("agent"),
+		Status:    query.Get("status"),
+	}
+	var err error
+	if l := query.Get("limit"); l != "" {
+		opts.Limit, err = strconv.Atoi(l)
+		if err != nil || opts.Limit <= 0 || opts.Limit > MaxListLimit {
+			return ListOptions{}, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+		}
+	}
+	if o := query.Get("offset"); o != "" {
+		opts.Offset, err = strconv.Atoi(o)
+		if err != nil || opts.Offset < 0 {
+			return ListOptions{}, fmt.Errorf("offset must be a positive number")
+		}
+	}
+	if opts.Status != "" && opts.Status != StatusPassing && opts.Status != StatusFailing {
+		return ListOptions{}, fmt.Errorf("status must be %s or %s", StatusPassing, StatusFailing)
+	}
+	if s := query.Get("since"); s != "" {
+		opts.Since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return ListOptions{}, fmt.Errorf("invalid since, must be RFC3339: %w", err)
+		}
+	}
+	if u := query.Get("until"); u != "" {
+		opts.Until, err = time.Parse(time.RFC3339, u)
+		if err != nil {
+			return ListOptions{}, fmt.Errorf("invalid until, must be RFC3339: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// inTimeRange Returns whether the time is within since and until (if set)
+func (opts ListOptions) inTimeRange(t time.Time) bool {
+	return !t.Before(opts.Since) && (opts.Until.IsZero() || !t.After(opts.Until))
+}
+
+// hasTimeRange Returns whether the items are filtered by time
+func (opts ListOptions) hasTimeRange() bool {
+	return !opts.Since.IsZero() || !opts.Until.IsZero()
+}
+
+// paginate Returns the page of the (matching) keys, sorted like the keys of the json maps the list endpoints return,
+// and sets the total number of keys in the TotalCountHeader
+func (opts ListOptions) paginate(w http.ResponseWriter, keys []string) []string {
+	w.Header().Set(client.TotalCountHeader, strconv.Itoa(len(keys)))
+	sort.Strings(keys)
+	if opts.Offset >= len(keys) {
+		return []string{}
+	}
+	keys = keys[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(keys) {
+		keys = keys[:opts.Limit]
+	}
+	return keys
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitIdleTimeout is how long the limiter of a client is kept after its last request
+const RateLimitIdleTimeout = 10 * time.Minute
+
+// RateLimitConfig limits the rate of the requests of each client (a token bucket per authenticated user, or per ip
+// if the request isn't authenticated), so one client (e.g. a runaway dashboard) can't degrade the api for everyone
+type RateLimitConfig struct {
+	// RequestsPerSecond is the rate of requests allowed per client (0 disables rate limiting)
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	// Burst is the number of requests a client can make at once (default twice the rate, at least 1)
+	Burst int `yaml:"burst"`
+	// TrustProxyHeaders uses the ip in the X-Real-Ip or X-Forwarded-For header (set by a proxy), instead of the
+	// address of the connection (also for the ip in the logs and the audit log)
+	TrustProxyHeaders bool `yaml:"trustProxyHeaders"`
+	// Quotas override the rate of some clients, e.g. a higher rate for the ui or a CI service account
+	Quotas []RateLimitQuota `yaml:"quotas"`
+}
+
+// RateLimitQuota is the rate of a client, by user name (of the token) or ip
+type RateLimitQuota struct {
+	User              string  `yaml:"user"`
+	IP                string  `yaml:"ip"`
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+}
+
+// RateLimiter limits the rate of the requests of the clients
+type RateLimiter struct {
+	config    RateLimitConfig
+	lock      sync.Mutex
+	limiters  map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter Returns the rate limiter of the config, nil if rate limiting isn't enabled
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.RequestsPerSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{config: config, limiters: map[string]*clientLimiter{}, lastSweep: time.Now()}
+}
+
+// Middleware Rejects the requests of the clients exceeding their rate with a 429, and the Retry-After header with
+// the seconds till they can make a request again. It must run after the authentication, to limit users by name.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, ip := "", clientIP(req, l.config.TrustProxyHeaders)
+		if identity, ok := IdentityFromContext(req.Context()); ok {
+			user = identity.Name
+		}
+		reservation := l.limiter(user, ip).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			rateLimitedRequests.WithLabelValues(routeTemplate(req)).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "rate limit exceeded, retry later", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// limiter Returns the limiter of the user (if authenticated) or ip, with the rate of its quota if it has one
+func (l *RateLimiter) limiter(user string, ip string) *rate.Limiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > RateLimitIdleTimeout {
+		for key, cl := range l.limiters {
+			if now.Sub(cl.lastSeen) > RateLimitIdleTimeout {
+				delete(l.limiters, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := "ip:" + ip
+	if user != "" {
+		key = "user:" + user
+	}
+	cl, ok := l.limiters[key]
+	if !ok {
+		rps, burst := l.config.RequestsPerSecond, l.config.Burst
+		for _, quota := range l.config.Quotas {
+			if (user != "" && quota.User == user) || (user == "" && quota.IP != "" && quota.IP == ip) {
+				rps, burst = quota.RequestsPerSecond, quota.Burst
+				break
+			}
+		}
+		if burst <= 0 {
+			burst = max(1, int(math.Ceil(2*rps)))
+		}
+		cl = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		l.limiters[key] = cl
+	}
+	cl.lastSeen = now
+	return cl.limiter
+}
+
+// clientIP Returns the ip of the client of the request, from the X-Real-Ip or X-Forwarded-For header only if they're
+// trusted (set by a proxy), as clients can set them
+func clientIP(req *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		if ip := req.Header.Get("X-Real-Ip"); ip != "" {
+			return ip
+		}
+		if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",") // the first address is the client
+			return strings.TrimSpace(ip)
+		}
+	}
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return ip
+}
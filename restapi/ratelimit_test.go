@@ -0,0 +1,196 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	type request struct {
+		at          time.Duration // since the first request
+		wantAllowed bool
+	}
+	tests := []struct {
+		name     string
+		rps      float64
+		burst    int
+		requests []request
+	}{
+		{
+			name: "burst then refill at the rate",
+			rps:  1, burst: 2,
+			requests: []request{
+				{0, true}, {0, true}, {0, false}, // the burst is spent
+				{500 * time.Millisecond, false}, // half a token
+				{time.Second, true}, {time.Second, false},
+				{5 * time.Second, true}, {5 * time.Second, true}, {5 * time.Second, false}, // refilled up to the burst only
+			},
+		},
+		{
+			name: "default burst of twice the rate",
+			rps:  2,
+			requests: []request{
+				{0, true}, {0, true}, {0, true}, {0, true}, {0, false},
+				{500 * time.Millisecond, true}, {500 * time.Millisecond, false},
+			},
+		},
+		{
+			name: "default burst at least 1",
+			rps:  0.2,
+			requests: []request{
+				{0, true}, {0, false},
+				{4 * time.Second, false},
+				{5 * time.Second, true},
+			},
+		},
+	}
+	start := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(RateLimitConfig{RequestsPerSecond: tt.rps, Burst: tt.burst})
+			limiter := l.limiter("", "10.0.0.1")
+			for i, r := range tt.requests {
+				if got := limiter.AllowN(start.Add(r.at), 1); got != r.wantAllowed {
+					t.Errorf("request %d at %s: allowed = %v, want %v", i, r.at, got, r.wantAllowed)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterClients(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{
+		RequestsPerSecond: 1,
+		Burst:             1,
+		Quotas: []RateLimitQuota{
+			{User: "ci", RequestsPerSecond: 10, Burst: 5},
+			{IP: "10.0.0.9", RequestsPerSecond: 3},
+		},
+	})
+	tests := []struct {
+		name      string
+		user      string
+		ip        string
+		wantBurst int
+	}{
+		{"unauthenticated", "", "10.0.0.1", 1},
+		{"user", "alice", "10.0.0.1", 1},
+		{"user quota", "ci", "10.0.0.1", 5},
+		{"ip quota, default burst", "", "10.0.0.9", 6},
+		{"ip quota doesn't apply to users", "alice", "10.0.0.9", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.limiter(tt.user, tt.ip).Burst(); got != tt.wantBurst {
+				t.Errorf("burst = %d, want %d", got, tt.wantBurst)
+			}
+		})
+	}
+
+	// users have their own bucket, whatever their ip, and don't share the bucket of their ip
+	now := time.Now()
+	if !l.limiter("", "10.0.0.1").AllowN(now, 1) || l.limiter("", "10.0.0.1").AllowN(now, 1) {
+		t.Errorf("the bucket of the ip should allow one request")
+	}
+	if !l.limiter("bob", "10.0.0.1").AllowN(now, 1) || l.limiter("bob", "10.0.0.2").AllowN(now, 1) {
+		t.Errorf("the bucket of the user should allow one request, from any ip")
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RequestsPerSecond: 1})
+	l.limiter("", "10.0.0.1")
+	l.limiter("", "10.0.0.2")
+	l.limiters["ip:10.0.0.1"].lastSeen = time.Now().Add(-2 * RateLimitIdleTimeout)
+	l.lastSweep = time.Now().Add(-2 * RateLimitIdleTimeout)
+
+	l.limiter("", "10.0.0.3")
+	if _, ok := l.limiters["ip:10.0.0.1"]; ok {
+		t.Errorf("the idle limiter wasn't removed")
+	}
+	if len(l.limiters) != 2 {
+		t.Errorf("limiters = %d, want 2", len(l.limiters))
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	if l := NewRateLimiter(RateLimitConfig{}); l != nil {
+		t.Errorf("NewRateLimiter() = %v, want nil without a rate", l)
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{RequestsPerSecond: 0.5, Burst: 1})
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	request := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tests", nil)
+		req.RemoteAddr = "10.0.0.1:51234"
+		if user != "" {
+			req = req.WithContext(context.WithValue(req.Context(), identityKey{}, Identity{Name: user}))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request(""); rec.Code != http.StatusOK {
+		t.Errorf("first request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	rec := request("")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second request status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want \"2\"", got)
+	}
+	if rec := request("alice"); rec.Code != http.StatusOK {
+		t.Errorf("request of a user status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		trust   bool
+		want    string
+	}{
+		{"remote address", nil, false, "10.0.0.1"},
+		{"untrusted real ip", map[string]string{"X-Real-Ip": "1.2.3.4"}, false, "10.0.0.1"},
+		{"untrusted forwarded for", map[string]string{"X-Forwarded-For": "1.2.3.4"}, false, "10.0.0.1"},
+		{"real ip", map[string]string{"X-Real-Ip": "1.2.3.4", "X-Forwarded-For": "5.6.7.8"}, true, "1.2.3.4"},
+		{"forwarded for", map[string]string{"X-Forwarded-For": " 1.2.3.4 , 5.6.7.8"}, true, "1.2.3.4"},
+		{"trusted without headers", nil, true, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:51234"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := clientIP(req, tt.trust); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
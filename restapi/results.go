@@ -0,0 +1,194 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/pkg/errors"
+)
+
+// DefaultResultsRange is the time range of the results and stats of the tests (up to now), if the query has no since
+const DefaultResultsRange = 24 * time.Hour
+
+// GetTestResults Returns the results of a test (in the namespace query param) between since and until (from the test
+// run history, so within its retention), on all agents or the agent of the query. With a bucket duration the results
+// are aggregated (pass rate and runtime percentiles per bucket), otherwise every result is returned (paginated).
+func (r *RestApi) GetTestResults(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	opts, err := parseListOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = opts.defaultTimeRange()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var bucket time.Duration
+	if b := req.URL.Query().Get("bucket"); b != "" {
+		bucket, err = time.ParseDuration(b)
+		if err != nil || bucket <= 0 {
+			http.Error(w, "invalid bucket, must be a positive duration, e.g. 1h", http.StatusBadRequest)
+			return
+		}
+		if opts.Until.Sub(opts.Since)/bucket >= MaxListLimit {
+			http.Error(w, "too many buckets, max "+strconv.Itoa(MaxListLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	plugins, err := r.testPlugins(ctx, configId, opts.Agent)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to fetch results", http.StatusInternalServerError)
+		return
+	}
+	results := []client.TestResult{}
+	for agentId, pluginId := range plugins {
+		history, err := r.store.FetchTestRunHistoryRange(ctx, pluginId, opts.Since, opts.Until)
+		if err != nil {
+			r.logger.Error("error fetching test run history", "id", pluginId, "err", err)
+			http.Error(w, "unable to fetch results", http.StatusInternalServerError)
+			return
+		}
+		for _, res := range history {
+			results = append(results, testResult(agentId, res))
+		}
+	}
+	slices.SortFunc(results, func(a, b client.TestResult) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Agent, b.Agent)
+	})
+
+	resp := client.TestResults{ConfigId: configId, Since: opts.Since, Until: opts.Until}
+	if bucket > 0 {
+		resp.Bucket = bucket.String()
+		resp.Buckets = aggregateResults(results, opts.Since, opts.Until, bucket)
+	} else {
+		w.Header().Set(client.TotalCountHeader, strconv.Itoa(len(results)))
+		results = results[min(opts.Offset, len(results)):]
+		if opts.Limit > 0 && opts.Limit < len(results) {
+			results = results[:opts.Limit]
+		}
+		resp.Results = results
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// testPlugins Returns the plugin ids of a test (with results) by agent id, only the one of the agent if set
+func (r *RestApi) testPlugins(ctx context.Context, configId string, agent string) (map[string]string, error) {
+	allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plugins := map[string]string{}
+	for pluginId := range allStatus {
+		testName, testNs, agentPod, agentNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || common.ComputeSynTestConfigId(testName, testNs) != configId {
+			continue
+		}
+		agentId := common.ComputeAgentId(agentPod, agentNs)
+		if agent == "" || agent == agentId {
+			plugins[agentId] = pluginId
+		}
+	}
+	return plugins, nil
+}
+
+func testResult(agentId string, res common.TestRunResult) client.TestResult {
+	return client.TestResult{
+		Agent:          agentId,
+		Time:           res.Time,
+		PassRatio:      res.PassRatio,
+		RuntimeSeconds: res.Runtime.Seconds(),
+	}
+}
+
+// defaultTimeRange Sets the time range of the results to the last DefaultResultsRange (up to until) if not set, or returns
+// an error if it's empty
+func (opts *ListOptions) defaultTimeRange() error {
+	if opts.Until.IsZero() {
+		opts.Until = time.Now()
+	}
+	if opts.Since.IsZero() {
+		opts.Since = opts.Until.Add(-DefaultResultsRange)
+	}
+	if !opts.Since.Before(opts.Until) {
+		return errors.New("since must be before until")
+	}
+	return nil
+}
+
+// aggregateResults Returns the buckets (from since) of the results sorted by time, including the buckets without runs
+func aggregateResults(results []client.TestResult, since time.Time, until time.Time, bucket time.Duration) []client.ResultBucket {
+	buckets := []client.ResultBucket{}
+	i := 0
+	for start := since; start.Before(until); start = start.Add(bucket) {
+		end := start.Add(bucket)
+		b := client.ResultBucket{Start: start}
+		passed := 0.0
+		runtimes := []float64{}
+		for ; i < len(results) && results[i].Time.Before(end); i++ {
+			b.Runs++
+			passed += results[i].PassRatio
+			if results[i].RuntimeSeconds > 0 {
+				runtimes = append(runtimes, results[i].RuntimeSeconds)
+			}
+		}
+		if b.Runs > 0 {
+			b.PassRate = passed / float64(b.Runs)
+		}
+		slices.Sort(runtimes)
+		b.RuntimeP50 = percentile(runtimes, 50)
+		b.RuntimeP90 = percentile(runtimes, 90)
+		b.RuntimeP99 = percentile(runtimes, 99)
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// percentile Returns the percentile of the sorted values (nearest rank), 0 if there are none
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
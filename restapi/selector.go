@@ -0,0 +1,115 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// GetSynTests Returns the current status of the tests matching the labelSelector query param (a kubernetes label
+// selector on the labels of their config, e.g. team=payments,tier!=low) and the namespace query param, sorted by config
+// id, so a status page of a team doesn't need to fetch and filter all the tests
+func (r *RestApi) GetSynTests(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	query := req.URL.Query()
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		http.Error(w, "invalid labelSelector: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	namespace := query.Get("namespace")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	summaries, err := r.store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		r.logger.Error("error fetching test config summaries", "err", err)
+		http.Error(w, "unable to fetch tests", http.StatusInternalServerError)
+		return
+	}
+	allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to fetch tests", http.StatusInternalServerError)
+		return
+	}
+	agents := map[string]map[string]float64{} // pass ratio of the latest run by agent id, by config id
+	for pluginId, passRatio := range allStatus {
+		configId := testId(pluginId)
+		ratio, err := strconv.ParseFloat(passRatio, 64)
+		if err != nil {
+			ratio = 0 // same as pluginStatus, an unparsable ratio is failing
+		}
+		if agents[configId] == nil {
+			agents[configId] = map[string]float64{}
+		}
+		agents[configId][strings.TrimPrefix(pluginId, configId+"/")] = ratio
+	}
+
+	tests := []client.TestStatus{}
+	for configId, summary := range summaries {
+		if (namespace != "" && summary.Namespace != namespace) || !canAccessTest(req, configId) {
+			continue
+		}
+		// the labels are only in the configs, so they're only fetched for the tests the request can see
+		var testLabels map[string]string
+		if !selector.Empty() {
+			config, err := r.store.FetchTestConfig(ctx, configId)
+			if err != nil {
+				r.logger.Error("error fetching test config", "id", configId, "err", err)
+				http.Error(w, "unable to fetch tests", http.StatusInternalServerError)
+				return
+			}
+			testLabels = config.Labels
+			if !selector.Matches(labels.Set(testLabels)) {
+				continue
+			}
+		}
+		test := client.TestStatus{ConfigId: configId, Name: summary.Name, Namespace: summary.Namespace,
+			DisplayName: summary.DisplayName, Plugin: summary.Plugin, Importance: summary.Importance, Labels: testLabels,
+			Status: client.HealthUnknown, Agents: agents[configId], FailingAgents: []string{}}
+		for agentId, ratio := range test.Agents {
+			if resultStatus(ratio) == StatusFailing {
+				test.FailingAgents = append(test.FailingAgents, agentId)
+			} else {
+				test.PassingAgents++
+			}
+		}
+		slices.Sort(test.FailingAgents)
+		if len(test.FailingAgents) > 0 {
+			test.Status = StatusFailing
+		} else if test.PassingAgents > 0 {
+			test.Status = StatusPassing
+		}
+		tests = append(tests, test)
+	}
+	slices.SortFunc(tests, func(a, b client.TestStatus) int { return strings.Compare(a.ConfigId, b.ConfigId) })
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(tests)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
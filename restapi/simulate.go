@@ -0,0 +1,594 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+// Simulation mode of the rest api: registers fake agents and writes plausible results of tests into storage, like real
+// agents would, to try dashboards, alerts and the performance of the rest api at scale before real agents exist
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	SimAgentPrefix    = "synheart-sim-agent-" // prefix of the pod names of the fake agents
+	SimAgentNamespace = "synheart-sim"
+	SimAgentVersion   = "simulated"
+	SimStatsInterval  = 10 * time.Second // how often the stats of the simulation are logged
+)
+
+// SimConfig is the config of the simulation
+type SimConfig struct {
+	StorageAddress string
+	Agents         int           // number of fake agents
+	Nodes          int           // number of nodes the agents are spread on (one per agent if 0)
+	Tests          int           // number of generated tests
+	Namespaces     int           // number of namespaces the generated tests are spread on
+	Existing       bool          // also simulate the results of the tests already in storage
+	Repeat         time.Duration // repeat of the generated tests
+	Latency        time.Duration // median runtime of the test runs
+	MTBF           time.Duration // mean time between outages of a test (no outages if 0)
+	Outage         time.Duration // mean duration of the outages
+	Flaky          float64       // probability of a failed run outside outages
+	Backfill       time.Duration // results written for the past, before the simulation starts
+	Heartbeat      time.Duration // how often the agents write their status
+	Seed           uint64        // seed of the random results (random if 0)
+	Keep           bool          // keep the agents, tests and results in storage when the simulation stops
+}
+
+// simPlugin is a plugin of the generated tests, with its config (of a target) and the errors of its failed runs
+type simPlugin struct {
+	name   string
+	config string
+	errors []string
+}
+
+var simPlugins = []simPlugin{
+	{"httpPing", "address: https://%s\nexpectedCodeRegex: \"^2\"\n",
+		[]string{"unexpected status code 503", "context deadline exceeded", "connection refused"}},
+	{"dns", "domains: [%s]\nrepeats: 3\n",
+		[]string{"lookup failed: no such host", "i/o timeout", "server misbehaving"}},
+	{"netDial", "net: tcp\naddr: %s:443\n",
+		[]string{"connection refused", "i/o timeout", "no route to host"}},
+	{"tlsScan", "targets:\n  - addr: %s:443\n",
+		[]string{"certificate expires in 6 days", "tls handshake timeout", "weak cipher suite offered"}},
+}
+
+var simTeams = []string{"payments", "search", "platform", "identity", "storage"}
+
+var simImportances = []string{common.ImportanceCritical, common.ImportanceHigh, common.ImportanceMedium,
+	common.ImportanceLow}
+
+// Simulator registers fake agents and writes the results of the tests they "run" to storage
+type Simulator struct {
+	config SimConfig
+	logger hclog.Logger
+	store  storage.RedisSynHeartStore
+	start  time.Time // time the results of the simulation start from (now minus the backfill)
+	tests  []*simTest
+	agents []*simAgent
+
+	runs       atomic.Int64
+	failedRuns atomic.Int64
+	errors     atomic.Int64
+	writeTime  atomic.Int64 // total time spent writing test runs, in ns
+}
+
+// simTest is a test of the simulation, with its outages (e.g. its target being down) shared by all its agents
+type simTest struct {
+	config    *proto.SynTestConfig
+	repeat    time.Duration
+	maxMarks  uint64
+	errors    []string
+	generated bool // generated by the simulation (deleted when it stops)
+
+	lock    sync.Mutex
+	rand    *rand.Rand
+	outages []simOutage // sorted by time, generated up to until
+	until   time.Time
+}
+
+// simOutage is an outage of a test, failing its runs on some (or all) of its agents
+type simOutage struct {
+	start      time.Time
+	end        time.Time
+	agentRatio float64 // ratio of the agents failing during the outage
+	seed       uint64
+	err        string
+}
+
+// simAgent is a fake agent, running all the tests of the simulation
+type simAgent struct {
+	index   int
+	id      string
+	pod     string
+	node    string
+	rand    *rand.Rand
+	plugins []*simAgentPlugin
+}
+
+// simAgentPlugin is the "plugin" of a test in a fake agent
+type simAgentPlugin struct {
+	id      string
+	test    *simTest
+	runtime time.Duration // median runtime of the test on the agent
+	next    time.Time     // time of the next run
+}
+
+// NewSimulator Creates a simulator, writing to the storage at the address of the config (logging with storeLogger)
+func NewSimulator(config SimConfig, logger hclog.Logger, storeLogger hclog.Logger) *Simulator {
+	if config.Nodes <= 0 || config.Nodes > config.Agents {
+		config.Nodes = config.Agents
+	}
+	if config.Namespaces <= 0 {
+		config.Namespaces = 1
+	}
+	if config.Seed == 0 {
+		config.Seed = rand.Uint64()
+	}
+	return &Simulator{
+		config: config,
+		logger: logger,
+		store: storage.NewRedisSynHeartStore(storage.SynHeartStoreConfig{
+			Type:       "redis",
+			BufferSize: 1000,
+			Address:    config.StorageAddress,
+		}, storeLogger),
+	}
+}
+
+// Run Runs the simulation until the context is done
+func (s *Simulator) Run(ctx context.Context) error {
+	defer s.store.Close()
+	err := s.store.Ping(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error connecting to storage")
+	}
+	s.start = time.Now().Add(-s.config.Backfill)
+
+	err = s.setupTests(ctx)
+	if err != nil {
+		return err
+	}
+	if len(s.tests) == 0 {
+		return errors.New("no tests to simulate, generate some with -tests or use the existing ones with -existing")
+	}
+	s.setupAgents()
+	s.logger.Info("simulation started", "agents", len(s.agents), "tests", len(s.tests), "seed", s.config.Seed,
+		"backfill", s.config.Backfill)
+
+	wg := sync.WaitGroup{}
+	for _, agent := range s.agents {
+		wg.Add(1)
+		go func(agent *simAgent) {
+			defer wg.Done()
+			s.runAgent(ctx, agent)
+		}(agent)
+	}
+	s.logStats(ctx)
+	wg.Wait()
+
+	if !s.config.Keep {
+		s.deleteTests()
+	}
+	s.logger.Info("simulation stopped", "runs", s.runs.Load(), "failedRuns", s.failedRuns.Load(),
+		"storageErrors", s.errors.Load())
+	return nil
+}
+
+// setupTests Generates the tests of the simulation (and writes their config to storage), and loads the existing ones
+func (s *Simulator) setupTests(ctx context.Context) error {
+	r := rand.New(rand.NewPCG(s.config.Seed, 0))
+	for i := 0; i < s.config.Tests; i++ {
+		plugin := simPlugins[i%len(simPlugins)]
+		name, namespace, test := simTestRequest(i, s.config, plugin)
+		version, err := specVersion(test.Labels, &test.Spec)
+		if err != nil {
+			return errors.Wrap(err, "error computing config version")
+		}
+		raw, err := json.Marshal(test.Spec)
+		if err != nil {
+			return errors.Wrap(err, "error marshalling spec json")
+		}
+		err = s.store.WriteTestConfig(ctx, synTestConfig(name, namespace, version, test.Labels, &test.Spec), string(raw))
+		if err != nil {
+			return errors.Wrap(err, "error writing test config of "+name)
+		}
+		config := synTestConfig(name, namespace, version, test.Labels, &test.Spec)
+		s.tests = append(s.tests, s.newTest(&config, s.config.Repeat, plugin.errors, true, r))
+	}
+	if !s.config.Existing {
+		return nil
+	}
+
+	summaries, err := s.store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test config summaries")
+	}
+	for _, configId := range slices.Sorted(maps.Keys(summaries)) {
+		if summaries[configId].Source == common.ConfigSourceSim {
+			continue // generated by a previous simulation
+		}
+		config, err := s.store.FetchTestConfig(ctx, configId)
+		if err != nil {
+			return errors.Wrap(err, "error fetching test config of "+configId)
+		}
+		if config.Paused {
+			continue
+		}
+		repeat, err := time.ParseDuration(config.Repeat)
+		if err != nil || repeat <= 0 {
+			repeat = s.config.Repeat
+		}
+		s.tests = append(s.tests, s.newTest(&config, repeat, []string{"test failed"}, false, r))
+	}
+	return nil
+}
+
+// simTestRequest Returns the name, namespace, labels and spec of the i-th generated test
+func simTestRequest(i int, config SimConfig, plugin simPlugin) (string, string, client.SynTestRequest) {
+	target := fmt.Sprintf("service-%d.example.com", i)
+	team := simTeams[i%len(simTeams)]
+	test := client.SynTestRequest{
+		Labels: map[string]string{common.ConfigSourceLabel: common.ConfigSourceSim, "team": team},
+		Spec: client.SynTestSpec{
+			Plugin:       plugin.name,
+			DisplayName:  fmt.Sprintf("%s of %s", plugin.name, target),
+			Description:  "simulated test",
+			Importance:   simImportances[i%len(simImportances)],
+			Repeat:       config.Repeat.String(),
+			Config:       fmt.Sprintf(plugin.config, target),
+			MetricLabels: map[string]string{"team": team},
+		},
+	}
+	if i%2 == 0 {
+		test.Spec.SLO = &proto.SLO{Target: "99.5", Window: "7d"}
+	}
+	return fmt.Sprintf("sim-%s-%d", plugin.name, i), fmt.Sprintf("sim-%d", i%config.Namespaces), test
+}
+
+// newTest Creates a test of the simulation
+func (s *Simulator) newTest(config *proto.SynTestConfig, repeat time.Duration, errs []string, generated bool,
+	r *rand.Rand) *simTest {
+	maxMarks := uint64(1)
+	if r.IntN(4) == 0 {
+		maxMarks = uint64(2 + r.IntN(3)) // tests checking a few things (e.g. domains), passing partially
+	}
+	return &simTest{
+		config:    config,
+		repeat:    repeat,
+		maxMarks:  maxMarks,
+		errors:    errs,
+		generated: generated,
+		rand:      rand.New(rand.NewPCG(s.config.Seed, r.Uint64())),
+		until:     s.start,
+	}
+}
+
+// setupAgents Creates the fake agents, running all the tests, with the runs of every test spread over its repeat
+func (s *Simulator) setupAgents() {
+	for i := 0; i < s.config.Agents; i++ {
+		pod := fmt.Sprintf("%s%d", SimAgentPrefix, i)
+		agent := &simAgent{
+			index: i,
+			id:    common.ComputeAgentId(pod, SimAgentNamespace),
+			pod:   pod,
+			node:  fmt.Sprintf("sim-node-%d", i%s.config.Nodes),
+			rand:  rand.New(rand.NewPCG(s.config.Seed, uint64(i)+1)),
+		}
+		agentLatency := math.Exp(agent.rand.NormFloat64() * 0.2) // e.g. the distance of the node to the targets
+		for _, test := range s.tests {
+			testLatency := math.Exp(agent.rand.NormFloat64() * 0.5)
+			agent.plugins = append(agent.plugins, &simAgentPlugin{
+				id:      common.ComputePluginId(test.config.Name, test.config.Namespace, agent.id),
+				test:    test,
+				runtime: time.Duration(float64(s.config.Latency) * agentLatency * testLatency),
+				next:    s.start.Add(time.Duration(agent.rand.Int64N(int64(test.repeat)))),
+			})
+		}
+		s.agents = append(s.agents, agent)
+	}
+}
+
+// runAgent Runs a fake agent until the context is done: writes its results for the backfill, then writes its status
+// and the results of its tests as they're due
+func (s *Simulator) runAgent(ctx context.Context, agent *simAgent) {
+	_ = s.store.NewAgentEvent(ctx, "new agent: "+agent.id)
+	s.writeAgentStatus(ctx, agent)
+	s.runDueTests(ctx, agent, time.Now())
+	s.writeAgentStatus(ctx, agent)
+
+	runTicker := time.NewTicker(time.Second)
+	defer runTicker.Stop()
+	heartbeatTicker := time.NewTicker(s.config.Heartbeat)
+	defer heartbeatTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if !s.config.Keep {
+				s.deleteAgent(agent)
+			}
+			return
+		case <-heartbeatTicker.C:
+			s.writeAgentStatus(ctx, agent)
+		case now := <-runTicker.C:
+			s.runDueTests(ctx, agent, now)
+		}
+	}
+}
+
+// runDueTests Writes the results of the runs of the tests of the agent due before now
+func (s *Simulator) runDueTests(ctx context.Context, agent *simAgent, now time.Time) {
+	for _, plugin := range agent.plugins {
+		for !plugin.next.After(now) {
+			if ctx.Err() != nil {
+				return
+			}
+			result, details, runtime := s.testResult(agent, plugin, plugin.next)
+			start := time.Now()
+			err := s.store.WriteTestRun(ctx, plugin.id, proto.TestRun{
+				Id:         fmt.Sprintf("%016x", agent.rand.Uint64()),
+				AgentId:    agent.id,
+				StartTime:  plugin.next.Format(common.TimeFormat),
+				EndTime:    plugin.next.Add(runtime).Format(common.TimeFormat),
+				TestConfig: plugin.test.config,
+				Trigger:    &proto.Trigger{TriggerType: common.TriggerTypeTimer},
+				TestResult: result,
+				Details:    details,
+			})
+			s.writeTime.Add(int64(time.Since(start)))
+			if err != nil && ctx.Err() == nil {
+				s.errors.Add(1)
+				s.logger.Error("error writing test run", "pluginId", plugin.id, "err", err)
+			}
+			s.runs.Add(1)
+			if result.Marks < result.MaxMarks {
+				s.failedRuns.Add(1)
+			}
+			plugin.next = plugin.next.Add(plugin.test.repeat)
+		}
+	}
+}
+
+// testResult Returns the result, details and runtime of a run of a test on an agent: failing during the outages of the
+// test (on the agents the outage affects), and at random (flaky) otherwise, with a runtime around the median runtime of
+// the test on the agent
+func (s *Simulator) testResult(agent *simAgent, plugin *simAgentPlugin, start time.Time) (*proto.TestResult,
+	map[string]string, time.Duration) {
+	test := plugin.test
+	runtime := time.Duration(float64(plugin.runtime) * math.Exp(agent.rand.NormFloat64()*0.25))
+	result := proto.TestResult{Marks: test.maxMarks, MaxMarks: test.maxMarks}
+	details := map[string]string{}
+
+	failure := ""
+	if outage, ok := test.outageAt(start, s.config.MTBF, s.config.Outage); ok && outage.affects(agent) {
+		failure = outage.err
+	} else if agent.rand.Float64() < s.config.Flaky {
+		failure = test.errors[agent.rand.IntN(len(test.errors))]
+	}
+	if failure != "" {
+		result.Marks = uint64(agent.rand.Int64N(int64(test.maxMarks)))
+		details[common.ErrorKey] = failure
+		runtime = time.Duration(float64(runtime) * (1 + 4*agent.rand.Float64())) // failures are often slower (retries, timeouts)
+	}
+
+	return &result, details, runtime
+}
+
+// outageAt Returns the outage of the test at a time, if there's one. The outages are generated as the time goes by,
+// with exponentially distributed durations and times between them, so agents (and the backfill) see the same ones
+func (t *simTest) outageAt(at time.Time, mtbf time.Duration, duration time.Duration) (simOutage, bool) {
+	if mtbf <= 0 {
+		return simOutage{}, false
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for !t.until.After(at) {
+		start := t.until.Add(time.Duration(t.rand.ExpFloat64() * float64(mtbf)))
+		outage := simOutage{
+			start:      start,
+			end:        start.Add(time.Duration(t.rand.ExpFloat64() * float64(duration))),
+			agentRatio: 1,
+			seed:       t.rand.Uint64(),
+			err:        t.errors[t.rand.IntN(len(t.errors))],
+		}
+		if t.rand.IntN(2) == 0 {
+			outage.agentRatio = 0.1 + 0.4*t.rand.Float64() // partial outage, e.g. of a zone
+		}
+		t.outages = append(t.outages, outage)
+		t.until = outage.end
+	}
+	i := sort.Search(len(t.outages), func(i int) bool {
+		return t.outages[i].end.After(at)
+	})
+	if i < len(t.outages) && !t.outages[i].start.After(at) {
+		return t.outages[i], true
+	}
+	return simOutage{}, false
+}
+
+// affects Returns whether the runs of the agent fail during the outage
+func (o simOutage) affects(agent *simAgent) bool {
+	return rand.New(rand.NewPCG(o.seed, uint64(agent.index))).Float64() < o.agentRatio
+}
+
+// writeAgentStatus Writes the status of the agent and of its plugins, like the heartbeat of an agent
+func (s *Simulator) writeAgentStatus(ctx context.Context, agent *simAgent) {
+	now := time.Now()
+	status := common.AgentStatus{
+		SynTests:   []string{},
+		StatusTime: now.Format(common.TimeFormat),
+		AgentConfig: common.AgentConfig{
+			SyncFrequency: s.config.Heartbeat,
+			RunTimeInfo: common.AgentInfo{
+				NodeName:       agent.node,
+				PodName:        agent.pod,
+				PodLabels:      map[string]string{common.K8sDiscoverLabel: common.K8sDiscoverLabelVal},
+				AgentNamespace: SimAgentNamespace,
+				Version:        SimAgentVersion,
+			},
+		},
+	}
+	for _, plugin := range agent.plugins {
+		status.SynTests = append(status.SynTests,
+			common.ComputeSynTestConfigId(plugin.test.config.Name, plugin.test.config.Namespace))
+		if !slices.Contains(status.AgentConfig.MatchTestNamespaces, plugin.test.config.Namespace) {
+			status.AgentConfig.MatchTestNamespaces = append(status.AgentConfig.MatchTestNamespaces,
+				plugin.test.config.Namespace)
+		}
+	}
+	err := s.store.WriteAgentStatus(ctx, agent.id, status)
+	if err != nil && ctx.Err() == nil {
+		s.errors.Add(1)
+		s.logger.Error("error writing agent status", "agentId", agent.id, "err", err)
+	}
+
+	for _, plugin := range agent.plugins {
+		err := s.store.WritePluginHealthStatus(ctx, plugin.id, common.PluginState{
+			Status:       common.Running,
+			StatusMsg:    "running",
+			Config:       plugin.test.config.Config,
+			RunningSince: s.start,
+			LastUpdated:  now,
+		})
+		if err != nil && ctx.Err() == nil {
+			s.errors.Add(1)
+			s.logger.Error("error writing plugin status", "pluginId", plugin.id, "err", err)
+		}
+	}
+}
+
+// deleteAgent Deletes the agent and the results of its tests from storage
+func (s *Simulator) deleteAgent(agent *simAgent) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, plugin := range agent.plugins {
+		err := s.store.DeleteAllTestRunInfo(ctx, plugin.id)
+		if err != nil {
+			s.logger.Warn("error deleting test runs", "pluginId", plugin.id, "err", err)
+		}
+	}
+	err := s.store.DeleteAgentStatus(ctx, agent.id)
+	if err != nil {
+		s.logger.Warn("error deleting agent status", "agentId", agent.id, "err", err)
+	}
+	_ = s.store.NewAgentEvent(ctx, "exiting agent: "+agent.id)
+}
+
+// deleteTests Deletes the configs of the generated tests from storage
+func (s *Simulator) deleteTests() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, test := range s.tests {
+		if !test.generated {
+			continue
+		}
+		configId := common.ComputeSynTestConfigId(test.config.Name, test.config.Namespace)
+		err := s.store.DeleteTestConfig(ctx, configId)
+		if err != nil {
+			s.logger.Warn("error deleting test config", "configId", configId, "err", err)
+		}
+	}
+}
+
+// logStats Logs the number of runs written, their rate and the storage errors every SimStatsInterval, until the
+// context is done
+func (s *Simulator) logStats(ctx context.Context) {
+	ticker := time.NewTicker(SimStatsInterval)
+	defer ticker.Stop()
+	lastRuns, lastWriteTime := int64(0), int64(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runs, writeTime := s.runs.Load(), s.writeTime.Load()
+			avgWrite := time.Duration(0)
+			if runs > lastRuns {
+				avgWrite = time.Duration((writeTime - lastWriteTime) / (runs - lastRuns))
+			}
+			s.logger.Info("simulation stats", "runs", runs,
+				"runsPerSecond", fmt.Sprintf("%.1f", float64(runs-lastRuns)/SimStatsInterval.Seconds()),
+				"failedRuns", s.failedRuns.Load(), "storageErrors", s.errors.Load(), "avgWrite", avgWrite)
+			lastRuns, lastWriteTime = runs, writeTime
+		}
+	}
+}
+
+// runSimulate Runs the simulation mode until it's interrupted
+func runSimulate(args []string) error {
+	config := SimConfig{}
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	fs.StringVar(&config.StorageAddress, "storage-address", "localhost:6379", "address of the redis to write to")
+	fs.IntVar(&config.Agents, "agents", 10, "number of fake agents")
+	fs.IntVar(&config.Nodes, "nodes", 0, "number of nodes the agents are spread on (one per agent if 0)")
+	fs.IntVar(&config.Tests, "tests", 20, "number of generated tests, run by every agent")
+	fs.IntVar(&config.Namespaces, "namespaces", 3, "number of namespaces the generated tests are spread on")
+	fs.BoolVar(&config.Existing, "existing", false, "also simulate the results of the tests already in storage")
+	fs.DurationVar(&config.Repeat, "repeat", time.Minute, "repeat of the generated tests")
+	fs.DurationVar(&config.Latency, "latency", 200*time.Millisecond, "median runtime of the test runs")
+	fs.DurationVar(&config.MTBF, "mtbf", 6*time.Hour, "mean time between outages of a test (no outages if 0)")
+	fs.DurationVar(&config.Outage, "outage", 10*time.Minute, "mean duration of the outages")
+	fs.Float64Var(&config.Flaky, "flaky", 0.01, "probability of a failed run outside outages")
+	fs.DurationVar(&config.Backfill, "backfill", 0, "results written for the past before the simulation starts (e.g. 24h)")
+	fs.DurationVar(&config.Heartbeat, "heartbeat", 10*time.Second, "how often the agents write their status")
+	fs.Uint64Var(&config.Seed, "seed", 0, "seed of the random results, to repeat a simulation (random if 0)")
+	fs.BoolVar(&config.Keep, "keep", false, "keep the agents, tests and results in storage when the simulation stops")
+	_ = fs.Parse(args)
+	if config.Agents <= 0 || config.Repeat <= 0 || config.Heartbeat <= 0 {
+		return errors.New("agents, repeat and heartbeat must be positive")
+	}
+
+	level := hclog.LevelFromString(os.Getenv("LOG_LEVEL"))
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "simulate",
+		Level: level,
+	})
+	// the store logs every test run it writes at info, too much with many agents
+	storeLevel := hclog.Warn
+	if level == hclog.Trace || level == hclog.Debug {
+		storeLevel = level
+	}
+	storeLogger := hclog.New(&hclog.LoggerOptions{
+		Name:  "simulate",
+		Level: storeLevel,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return NewSimulator(config, logger, storeLogger).Run(ctx)
+}
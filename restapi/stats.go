@@ -0,0 +1,136 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	gmux "github.com/gorilla/mux"
+)
+
+// GetStats Returns the statistics (pass rate, failures, outages and mean time to recovery) of the test runs between
+// since and until, grouped by test, namespace or agent (the group in the path), filtered by test, namespace and agent.
+// They're computed from the test run history, like the SLOs.
+func (r *RestApi) GetStats(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	groupBy := gmux.Vars(req)["groupBy"]
+	opts, err := parseListOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = opts.defaultTimeRange()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching test run status", "err", err)
+		http.Error(w, "unable to fetch stats", http.StatusInternalServerError)
+		return
+	}
+	groups := map[string]*statsAccumulator{}
+	for pluginId := range allStatus {
+		testName, testNs, agentPod, agentNs, err := common.GetPluginIdComponents(pluginId)
+		if err != nil || !canAccessTest(req, pluginId) {
+			continue
+		}
+		agentId := common.ComputeAgentId(agentPod, agentNs)
+		if (opts.Test != "" && opts.Test != testName) || (opts.Namespace != "" && opts.Namespace != testNs) ||
+			(opts.Agent != "" && opts.Agent != agentId) {
+			continue
+		}
+		group := common.ComputeSynTestConfigId(testName, testNs)
+		switch groupBy {
+		case client.StatsByNamespace:
+			group = testNs
+		case client.StatsByAgent:
+			group = agentId
+		}
+
+		history, err := r.store.FetchTestRunHistoryRange(ctx, pluginId, opts.Since, opts.Until)
+		if err != nil {
+			r.logger.Error("error fetching test run history", "id", pluginId, "err", err)
+			http.Error(w, "unable to fetch stats", http.StatusInternalServerError)
+			return
+		}
+		if groups[group] == nil {
+			groups[group] = &statsAccumulator{}
+		}
+		groups[group].add(history)
+	}
+
+	stats := client.Stats{GroupBy: groupBy, Since: opts.Since, Until: opts.Until, Groups: map[string]client.TestStats{}}
+	for group, acc := range groups {
+		stats.Groups[group] = acc.stats()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// statsAccumulator sums up the test run history of the tests (on agents) of a group
+type statsAccumulator struct {
+	client.TestStats
+	passed        float64
+	timeToRecover time.Duration
+}
+
+// add Adds the results of a test on an agent. An outage starts with a failed run (or with the first run in the
+// time range, if it failed) and ends with the next passed run.
+func (a *statsAccumulator) add(history []common.TestRunResult) {
+	slices.SortFunc(history, func(x, y common.TestRunResult) int { return x.Time.Compare(y.Time) })
+	var failingSince time.Time
+	for _, res := range history {
+		a.Runs++
+		a.passed += res.PassRatio
+		if res.PassRatio < 1 {
+			a.Failures++
+			if failingSince.IsZero() {
+				failingSince = res.Time
+				a.Outages++
+			}
+		} else if !failingSince.IsZero() {
+			a.Recoveries++
+			a.timeToRecover += res.Time.Sub(failingSince)
+			failingSince = time.Time{}
+		}
+	}
+}
+
+func (a *statsAccumulator) stats() client.TestStats {
+	stats := a.TestStats
+	if stats.Runs > 0 {
+		stats.PassRate = a.passed / float64(stats.Runs)
+	}
+	if stats.Recoveries > 0 {
+		stats.MTTRSeconds = a.timeToRecover.Seconds() / float64(stats.Recoveries)
+	}
+	return stats
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+)
+
+// TestRunStreamBuffer is the number of test runs buffered for each stream of the test runs, the runs are dropped for a
+// stream which doesn't keep up
+const TestRunStreamBuffer = 100
+
+// watchTestRunEvents Sends the test run events in storage (the plugin ids of the new runs) to the streams of the test runs
+func (r *RestApi) watchTestRunEvents(ctx context.Context) {
+	events := make(chan string, 1000)
+	go func() {
+		for ctx.Err() == nil {
+			err := r.store.SubscribeToTestRunEvents(ctx, 1000, events)
+			if err != nil {
+				r.logger.Warn("error subscribing to test run events, retrying", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(PingRefreshFrequency):
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			r.runWatchers.notify(strings.TrimPrefix(event, storage.TestRunEventPrefix))
+		}
+	}
+}
+
+// StreamTestRuns Streams the test runs as they finish on the agents (ADDED events of a watch), filtered by test,
+// namespace, agent and status, until the request is done or times out (timeoutSeconds, like a watch)
+func (r *RestApi) StreamTestRuns(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	opts, err := parseListOptions(req.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	timeout, err := watchTimeout(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+	runs, stop := r.runWatchers.add()
+	defer stop()
+
+	stream, ok := newWatchStream(w)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	for {
+		var pluginId string
+		select {
+		case <-ctx.Done():
+			return
+		case pluginId = <-runs:
+		}
+		if !canAccessTest(req, pluginId) {
+			continue
+		}
+		run, ok := r.testRunEvent(ctx, opts, pluginId)
+		if !ok {
+			continue
+		}
+		if err := stream.send(client.WatchAdded, run); err != nil {
+			r.logger.Debug("error sending test run, client gone?", "path", req.URL.Path, "err", err)
+			return
+		}
+	}
+}
+
+// testRunEvent Returns the latest run of the test on an agent (plugin id), if it matches the filters (the time range
+// is ignored)
+func (r *RestApi) testRunEvent(ctx context.Context, opts ListOptions, pluginId string) (client.TestRunEvent, bool) {
+	testName, testNs, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+	if err != nil {
+		return client.TestRunEvent{}, false
+	}
+	if (opts.Test != "" && testName != opts.Test) || (opts.Namespace != "" && testNs != opts.Namespace) ||
+		(opts.Agent != "" && common.ComputeAgentId(podName, podNs) != opts.Agent) {
+		return client.TestRunEvent{}, false
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	testRun, err := r.store.FetchLatestTestRun(fetchCtx, pluginId)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Warn("error fetching latest test run from extStore", "id", pluginId, "err", err)
+		}
+		return client.TestRunEvent{}, false
+	}
+	run := client.TestRunEvent{
+		Test:  common.ComputeSynTestConfigId(testName, testNs),
+		Agent: common.ComputeAgentId(podName, podNs),
+		RunId: testRun.Id,
+	}
+	if testRun.TestResult != nil {
+		if testRun.TestResult.MaxMarks > 0 {
+			run.PassRatio = float64(testRun.TestResult.Marks) / float64(testRun.TestResult.MaxMarks)
+		}
+		run.Error = testRun.TestResult.Details[common.ErrorKey]
+	}
+	if opts.Status != "" && (run.PassRatio == 1) != (opts.Status == StatusPassing) {
+		return client.TestRunEvent{}, false
+	}
+	if testRun.Trigger != nil {
+		run.Trigger = testRun.Trigger.TriggerType
+	}
+	if start, err := time.Parse(common.TimeFormat, testRun.StartTime); err == nil {
+		run.Time = start
+		if end, err := time.Parse(common.TimeFormat, testRun.EndTime); err == nil && end.After(start) {
+			run.RuntimeSeconds = end.Sub(start).Seconds()
+		}
+	}
+	return run, true
+}
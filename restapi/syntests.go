@@ -0,0 +1,358 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/common/testspec"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	gmux "github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// Tests managed through the rest api are written directly to storage (like the tests synced from a git repo), with
+// the source label set to ConfigSourceAPI, so the controller doesn't delete them. Tests from another source (e.g. a
+// CRD) can't be changed through the rest api. They can't reference secrets (secretRefs isn't a field of the spec), as
+// the rest api can't check the user can read them.
+
+var dnsLabelRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// PutSynTest Creates or updates a test (in the namespace query param) from the spec in the body
+func (r *RestApi) PutSynTest(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	name, namespace := gmux.Vars(req)["name"], req.URL.Query().Get("namespace")
+	if !dnsLabelRegex.MatchString(name) {
+		http.Error(w, "invalid test name: must be a lowercase dns label", http.StatusBadRequest)
+		return
+	}
+	if namespace != common.ClusterTestNamespace && !dnsLabelRegex.MatchString(namespace) {
+		http.Error(w, "invalid test namespace: must be a lowercase dns label", http.StatusBadRequest)
+		return
+	}
+	test := client.SynTestRequest{}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&test)
+	if err != nil {
+		http.Error(w, "invalid test: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if test.Spec.Repeat == "" {
+		test.Spec.Repeat = common.DefaultRepeat.String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	spec := &testspec.Spec{}
+	testspec.Convert(&test.Spec, spec)
+	warnings, errs := r.validateSynTestSpec(ctx, spec)
+	if len(errs) > 0 {
+		http.Error(w, "invalid test: "+strings.Join(errs, "; "), http.StatusBadRequest)
+		return
+	}
+
+	labels := map[string]string{}
+	for k, v := range test.Labels {
+		labels[k] = v
+	}
+	labels[common.ConfigSourceLabel] = common.ConfigSourceAPI
+	rawConfig, err := json.MarshalIndent(test.Spec, "", "  ")
+	if err != nil {
+		r.logger.Error("error marshalling spec json", "id", configId, "err", err)
+		http.Error(w, "unable to write test config", http.StatusInternalServerError)
+		return
+	}
+	version, err := specVersion(labels, &test.Spec)
+	if err != nil {
+		r.logger.Error("error computing config version", "id", configId, "err", err)
+		http.Error(w, "unable to write test config", http.StatusInternalServerError)
+		return
+	}
+
+	oldVersion := ""
+	summary, err := r.store.FetchTestConfigSummary(ctx, configId)
+	if err == nil {
+		if summary.Source != common.ConfigSourceAPI {
+			http.Error(w, "test exists and isn't managed through the rest api (source: "+configSource(summary)+")",
+				http.StatusConflict)
+			return
+		}
+		oldVersion = summary.Version
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		r.logger.Error("error fetching test config summary", "id", configId, "err", err)
+		http.Error(w, "unable to write test config", http.StatusInternalServerError)
+		return
+	}
+
+	violations, err := r.namespaceViolations(ctx, namespace, spec, oldVersion == "")
+	if err != nil {
+		r.logger.Error("error checking the policies of the namespace", "id", configId, "err", err)
+		http.Error(w, "unable to write test config", http.StatusInternalServerError)
+		return
+	}
+	if len(violations) > 0 {
+		http.Error(w, "test violates the policies of the namespace: "+strings.Join(violations, "; "), http.StatusForbidden)
+		return
+	}
+
+	resp := client.SynTestResponse{ConfigId: configId, Version: version, Created: oldVersion == "", Warnings: warnings}
+	if oldVersion != version {
+		err = r.store.WriteTestConfig(ctx, synTestConfig(name, namespace, version, labels, &test.Spec), string(rawConfig))
+		if err != nil {
+			r.logger.Error("error writing test config", "id", configId, "err", err)
+			http.Error(w, "unable to write test config", http.StatusInternalServerError)
+			return
+		}
+		actor := requestActor(req)
+		message := "written through the rest api"
+		if actor != "" {
+			message += " by " + actor
+		}
+		err = r.store.WriteTestConfigStatus(ctx, configId, common.SyntestConfigStatus{
+			Deployed:  true,
+			Message:   message,
+			Agent:     "multiple",
+			Timestamp: time.Now().Format(common.TimeFormat),
+		})
+		if err != nil {
+			r.logger.Warn("unable to update status in redis", "id", configId, "err", err)
+		}
+		action := common.AuditActionUpdated
+		if resp.Created {
+			action = common.AuditActionCreated
+		}
+		r.logger.Info("wrote test config", "id", configId, "version", version, "oldVersion", oldVersion)
+		r.recordAuditEvent(ctx, common.AuditEvent{
+			Actor:   actor,
+			Kind:    common.AuditKindSynTest,
+			Object:  configId,
+			Action:  action,
+			Message: test.Comment,
+			Details: withClaimedActor(map[string]string{
+				"oldVersion": oldVersion,
+				"version":    version,
+			}, test.Actor),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// DeleteSynTest Deletes a test (in the namespace query param) managed through the rest api
+func (r *RestApi) DeleteSynTest(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	summary, err := r.store.FetchTestConfigSummary(ctx, configId)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "no test config found", http.StatusNotFound)
+			return
+		}
+		r.logger.Error("error fetching test config summary", "id", configId, "err", err)
+		http.Error(w, "unable to delete test config", http.StatusInternalServerError)
+		return
+	}
+	if summary.Source != common.ConfigSourceAPI {
+		http.Error(w, "test isn't managed through the rest api (source: "+configSource(summary)+")", http.StatusConflict)
+		return
+	}
+	err = r.store.DeleteTestConfig(ctx, configId)
+	if err != nil {
+		r.logger.Error("error deleting test config", "id", configId, "err", err)
+		http.Error(w, "unable to delete test config", http.StatusInternalServerError)
+		return
+	}
+	r.logger.Info("deleted test config", "id", configId)
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:  requestActor(req),
+		Kind:   common.AuditKindSynTest,
+		Object: configId,
+		Action: common.AuditActionDeleted,
+		Details: map[string]string{
+			"oldVersion": summary.Version,
+		},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateSynTestSpec Validates the spec the way the SyntheticTest webhook of the controller does (see
+// testspec.Validate), with the plugin checked against the plugins of the active agents. Returns the warnings and errors.
+func (r *RestApi) validateSynTestSpec(ctx context.Context, spec *testspec.Spec) ([]string, []string) {
+	plugins, warnings := r.knownPlugins(ctx)
+	specWarnings, allErrs := testspec.Validate(spec, plugins)
+	warnings = append(warnings, specWarnings...)
+
+	var errs []string
+	for _, err := range allErrs {
+		errs = append(errs, err.Error())
+	}
+	// tests aren't assigned to an agent, as there's no CRD status to keep the assignment in
+	if strings.Contains(spec.Node, "$") || spec.PodLabelSelector[common.SpecialKeyPodName] == "$" {
+		errs = append(errs, "spec.node: agent assignment ('$') isn't supported for tests managed through the rest api")
+	}
+	return warnings, errs
+}
+
+// knownPlugins Returns the plugins discovered by the active agents, if they can't be fetched (or there are no agents)
+// it returns nil and a warning, so the plugin isn't checked (like the webhook of the controller)
+func (r *RestApi) knownPlugins(ctx context.Context) (map[string]bool, []string) {
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Warn("unable to fetch the plugins of the agents", "err", err)
+		return nil, []string{"unable to check the plugin exists: " + err.Error()}
+	}
+	plugins := map[string]bool{}
+	for _, agent := range agents {
+		if lastStatus, err := time.Parse(common.TimeFormat, agent.StatusTime); err == nil && time.Since(lastStatus) < r.agentStatusDeadline() {
+			for name := range agent.AgentConfig.DiscoveredPlugins {
+				plugins[name] = true
+			}
+		}
+	}
+	if len(plugins) == 0 {
+		return nil, []string{"no active agents, unable to check the plugin exists"}
+	}
+	return plugins, nil
+}
+
+// namespaceViolations Returns why the test can't be deployed in its namespace: the namespace isn't watched by the
+// controller, or the test exceeds the SynTestQuotas of the namespace (the number of tests only when it's created). The
+// policies are published to storage by the controller, if it hasn't published any they aren't checked.
+func (r *RestApi) namespaceViolations(ctx context.Context, namespace string, spec *testspec.Spec, create bool) ([]string, error) {
+	if namespace == common.ClusterTestNamespace {
+		return nil, nil // cluster tests aren't part of any namespace
+	}
+	policies, err := r.store.FetchNamespacePolicies(ctx)
+	if errors.Is(err, storage.ErrNotFound) {
+		r.logger.Warn("no namespace policies published by the controller, not checking them", "namespace", namespace)
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error fetching namespace policies")
+	}
+	if policies.Filtered && !slices.Contains(policies.Watched, namespace) {
+		return []string{"namespace isn't watched by the controller"}, nil
+	}
+
+	var violations []string
+	tests := -1 // tests of the namespace, counted once needed
+	for _, quota := range policies.Quotas[namespace] {
+		for _, err := range testspec.ValidateQuota(quota, spec) {
+			violations = append(violations, err.Error())
+		}
+		if !create || quota.MaxTests <= 0 {
+			continue
+		}
+		if tests < 0 {
+			summaries, err := r.store.FetchAllTestConfigSummary(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "error fetching test config summaries")
+			}
+			tests = 0
+			for _, summary := range summaries {
+				if summary.Namespace == namespace {
+					tests++
+				}
+			}
+		}
+		if tests >= int(quota.MaxTests) {
+			violations = append(violations, fmt.Sprintf("namespace already has %d tests, the maxTests of quota '%s'", tests, quota.Name))
+		}
+	}
+	return violations, nil
+}
+
+// synTestConfig Returns the config of the test stored for the agents
+func synTestConfig(name string, namespace string, version string, labels map[string]string,
+	spec *client.SynTestSpec) proto.SynTestConfig {
+	timeouts := proto.Timeouts{}
+	if spec.Timeouts != nil {
+		timeouts = proto.Timeouts{Init: spec.Timeouts.Init, Run: spec.Timeouts.Run, Finish: spec.Timeouts.Finish}
+	}
+	return proto.SynTestConfig{
+		Name:                name,
+		Version:             version,
+		Labels:              labels,
+		PluginName:          spec.Plugin,
+		DisplayName:         spec.DisplayName,
+		Description:         spec.Description,
+		Importance:          spec.Importance,
+		Repeat:              spec.Repeat,
+		NodeSelector:        spec.Node,
+		PodLabelSelector:    spec.PodLabelSelector,
+		Namespace:           namespace,
+		DependsOn:           spec.DependsOn,
+		Timeouts:            &timeouts,
+		PluginRestartPolicy: spec.PluginRestartPolicy,
+		LogWaitTime:         spec.LogWaitTime,
+		Config:              spec.Config,
+		MetricLabels:        spec.MetricLabels,
+		Alerting:            spec.Alerting,
+		Slo:                 spec.SLO,
+		ActiveFrom:          spec.ActiveFrom,
+		ActiveUntil:         spec.ActiveUntil,
+		ActiveWindows:       spec.ActiveWindows,
+		Paused:              spec.Paused,
+	}
+}
+
+// specVersion Returns the version of the config of a test (a hash of its labels and spec)
+func specVersion(labels map[string]string, spec *client.SynTestSpec) (string, error) {
+	b, err := json.Marshal(struct {
+		Labels map[string]string   `json:"labels"`
+		Spec   *client.SynTestSpec `json:"spec"`
+	}{labels, spec})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", md5.Sum(b)), nil
+}
+
+// configSource Returns the source of a test config, for the errors
+func configSource(summary common.SyntestConfigSummary) string {
+	if summary.Source == "" {
+		return "crd"
+	}
+	return summary.Source
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	gmux "github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// newTestRestApi Returns a rest api backed by an in-memory redis
+func newTestRestApi(t *testing.T) *RestApi {
+	redis := miniredis.RunT(t)
+	store := storage.NewRedisSynHeartStore(storage.SynHeartStoreConfig{
+		Type:       "redis",
+		BufferSize: 1000,
+		Address:    redis.Addr(),
+	}, hclog.NewNullLogger())
+	t.Cleanup(func() { store.Close() })
+	return &RestApi{store: store, logger: hclog.NewNullLogger()}
+}
+
+func putSynTest(r *RestApi, name string, namespace string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/syntests/"+name+"?namespace="+namespace, strings.NewReader(body))
+	req = gmux.SetURLVars(req, map[string]string{"name": name})
+	w := httptest.NewRecorder()
+	r.PutSynTest(w, req)
+	return w
+}
+
+func TestPutSynTestValidation(t *testing.T) {
+	r := newTestRestApi(t)
+	ctx := context.Background()
+	err := r.store.WriteAgentStatus(ctx, "agent-0/synthetic-heart", common.AgentStatus{
+		StatusTime:  time.Now().Format(common.TimeFormat),
+		AgentConfig: common.AgentConfig{DiscoveredPlugins: map[string][]string{"dns": {"./dns"}}},
+	})
+	if err != nil {
+		t.Fatalf("error writing agent status: %v", err)
+	}
+	err = r.store.WriteNamespacePolicies(ctx, common.NamespacePolicies{
+		Time:     time.Now(),
+		Quotas:   map[string][]common.SynTestQuota{"team-a": {{Name: "default", MaxTests: 1, MinRepeat: "1m"}}},
+		Filtered: true,
+		Watched:  []string{"team-a", "team-b"},
+	})
+	if err != nil {
+		t.Fatalf("error writing namespace policies: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		test      string
+		namespace string
+		body      string
+		want      int
+	}{
+		{"valid", "dns", "team-a", `{"spec": {"plugin": "dns", "repeat": "1m", "config": "domains: [example.com]"}}`, http.StatusCreated},
+		{"update", "dns", "team-a", `{"spec": {"plugin": "dns", "repeat": "5m", "config": "domains: [example.com]"}}`, http.StatusOK},
+		{"unknown plugin", "curl", "team-b", `{"spec": {"plugin": "curl"}}`, http.StatusBadRequest},
+		{"unknown config field", "dns", "team-b", `{"spec": {"plugin": "dns", "config": "domain: example.com"}}`, http.StatusBadRequest},
+		{"invalid importance", "dns", "team-b", `{"spec": {"plugin": "dns", "importance": "urgent"}}`, http.StatusBadRequest},
+		{"agent assignment", "dns", "team-b", `{"spec": {"plugin": "dns", "node": "$"}}`, http.StatusBadRequest},
+		{"repeat under the quota", "dns", "team-a", `{"spec": {"plugin": "dns", "repeat": "10s"}}`, http.StatusForbidden},
+		{"max tests of the quota", "dns-2", "team-a", `{"spec": {"plugin": "dns", "repeat": "1m"}}`, http.StatusForbidden},
+		{"namespace without quota", "dns", "team-b", `{"spec": {"plugin": "dns", "repeat": "10s"}}`, http.StatusCreated},
+		{"namespace not watched", "dns", "sandbox", `{"spec": {"plugin": "dns", "repeat": "1m"}}`, http.StatusForbidden},
+		{"cluster test", "dns", common.ClusterTestNamespace, `{"spec": {"plugin": "dns", "repeat": "1m"}}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := putSynTest(r, tt.test, tt.namespace, tt.body)
+			if w.Code != tt.want {
+				t.Errorf("PutSynTest() status = %d, want %d (%s)", w.Code, tt.want, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TenantRefreshInterval is how often the tests selected by the label selectors of the tenants are refreshed (they're
+// also refreshed when a test config changes)
+const TenantRefreshInterval = time.Minute
+
+// TenantConfig is a tenant of a shared synthetic heart (multi-tenant mode), its users only see the tests (and their
+// results) in its namespaces or matching its label selector
+type TenantConfig struct {
+	Name string `yaml:"name"`
+	// Users and Groups of the tenant, in addition to the users whose tenant claim is the name of the tenant
+	Users  []string `yaml:"users"`
+	Groups []string `yaml:"groups"`
+	// Namespaces of the tests of the tenant (names or globs, "_cluster" for cluster tests)
+	Namespaces []string `yaml:"namespaces"`
+	// LabelSelector selects the tests of the tenant (in any namespace) by the labels of their config, the values can
+	// be globs
+	LabelSelector map[string]string `yaml:"labelSelector"`
+}
+
+// Tenant is a tenant with the tests selected by its label selector
+type Tenant struct {
+	config TenantConfig
+	tests  atomic.Pointer[map[string]bool] // config ids of the tests matching the label selector
+}
+
+// newTenants Returns the tenants of the configs, an error if a tenant has no name (or a duplicate one) or nothing
+// selecting its tests
+func newTenants(configs []TenantConfig) ([]*Tenant, error) {
+	tenants := []*Tenant{}
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, errors.New("tenants must have a name")
+		}
+		if slices.ContainsFunc(tenants, func(t *Tenant) bool { return t.config.Name == config.Name }) {
+			return nil, errors.New("duplicate tenant " + config.Name)
+		}
+		if len(config.Namespaces) == 0 && len(config.LabelSelector) == 0 {
+			return nil, errors.New("tenant " + config.Name + " must have namespaces or a label selector")
+		}
+		tenant := &Tenant{config: config}
+		tenant.tests.Store(&map[string]bool{})
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+// Name Returns the name of the tenant
+func (t *Tenant) Name() string {
+	return t.config.Name
+}
+
+// hasNamespace Returns whether all the tests of the namespace belong to the tenant
+func (t *Tenant) hasNamespace(namespace string) bool {
+	return slices.ContainsFunc(t.config.Namespaces, func(pattern string) bool {
+		match, err := path.Match(pattern, namespace)
+		return err == nil && match
+	})
+}
+
+// hasTest Returns whether the test of the config id belongs to the tenant (by namespace or label selector)
+func (t *Tenant) hasTest(configId string) bool {
+	return t.hasNamespace(idNamespace(configId)) || (*t.tests.Load())[configId]
+}
+
+// selects Returns whether the labels of a test match the label selector of the tenant
+func (t *Tenant) selects(labels map[string]string) bool {
+	if len(t.config.LabelSelector) == 0 {
+		return false
+	}
+	for k, pattern := range t.config.LabelSelector {
+		v, ok := labels[k]
+		if !ok {
+			return false
+		}
+		if match, err := path.Match(pattern, v); err != nil || !match {
+			return false
+		}
+	}
+	return true
+}
+
+// tenant Returns the tenant of the identity: the one named by the tenant claim of its token, or the first one with
+// its user or one of its groups (nil if none)
+func (a *Authenticator) tenant(identity Identity, claims map[string]interface{}) *Tenant {
+	if name, ok := claims[a.config.TenantClaim].(string); ok && a.config.TenantClaim != "" {
+		for _, tenant := range a.tenants {
+			if tenant.config.Name == name {
+				return tenant
+			}
+		}
+	}
+	for _, tenant := range a.tenants {
+		if slices.Contains(tenant.config.Users, identity.Name) ||
+			slices.ContainsFunc(tenant.config.Groups, func(g string) bool { return slices.Contains(identity.Groups, g) }) {
+			return tenant
+		}
+	}
+	return nil
+}
+
+// TenantMetricsMiddleware Records the requests of the tenants, by tenant, route and status code. It must run after
+// the authentication.
+func TenantMetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		identity, ok := IdentityFromContext(req.Context())
+		if !ok || identity.Tenant == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+		tenantRequests.WithLabelValues(identity.Tenant.Name(), routeTemplate(req), strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// watchTenants Refreshes the tests selected by the label selectors of the tenants every TenantRefreshInterval and when
+// a test config changes, till the context is done
+func (r *RestApi) watchTenants(ctx context.Context) {
+	if !slices.ContainsFunc(r.tenants, func(t *Tenant) bool { return len(t.config.LabelSelector) > 0 }) {
+		return
+	}
+	events := make(chan string, 1000)
+	go func() {
+		for ctx.Err() == nil {
+			err := r.store.SubscribeToConfigEvents(ctx, 1000, events)
+			if err != nil {
+				r.logger.Warn("error subscribing to config events, retrying", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(PingRefreshFrequency):
+			}
+		}
+	}()
+	ticker := time.NewTicker(TenantRefreshInterval)
+	defer ticker.Stop()
+	for {
+		err := r.refreshTenants(ctx)
+		if err != nil {
+			r.logger.Warn("error refreshing the tests of the tenants", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-events:
+			// the controller writes many configs at once, so the events queued meanwhile are coalesced
+			for len(events) > 0 {
+				<-events
+			}
+		}
+	}
+}
+
+// refreshTenants Sets the tests selected by the label selectors of the tenants, from the labels of the test configs
+func (r *RestApi) refreshTenants(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	summaries, err := r.store.FetchAllTestConfigSummary(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error fetching test config summaries")
+	}
+	tests := make([]map[string]bool, len(r.tenants))
+	for i := range tests {
+		tests[i] = map[string]bool{}
+	}
+	for configId := range summaries {
+		config, err := r.store.FetchTestConfig(ctx, configId)
+		if err != nil {
+			r.logger.Warn("error fetching test config for the tenants", "id", configId, "err", err)
+			continue
+		}
+		for i, tenant := range r.tenants {
+			if tenant.selects(config.Labels) {
+				tests[i][configId] = true
+			}
+		}
+	}
+	for i, tenant := range r.tenants {
+		tenant.tests.Store(&tests[i])
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/proto"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	gmux "github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// TriggerTest Requests a run of a test (in the namespace query param) at once, on all the agents running it or on the
+// agents of the request. The agents publish their results under the returned run id (see GetTriggeredRun).
+func (r *RestApi) TriggerTest(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	trigger := client.TriggerRequest{}
+	if req.ContentLength != 0 {
+		err := json.NewDecoder(req.Body).Decode(&trigger)
+		if err != nil {
+			http.Error(w, "invalid trigger: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := r.store.FetchTestConfig(ctx, configId)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "no test config found", http.StatusNotFound)
+			return
+		}
+		r.logger.Error("error fetching test config", "id", configId, "err", err)
+		http.Error(w, "unable to trigger test", http.StatusInternalServerError)
+		return
+	}
+	agents, err := r.store.FetchAllAgentStatus(ctx)
+	if err != nil {
+		r.logger.Error("error fetching agent statuses", "err", err)
+		http.Error(w, "unable to trigger test", http.StatusInternalServerError)
+		return
+	}
+	running := []string{}
+	for agentId, agent := range agents {
+		if slices.Contains(agent.SynTests, configId) {
+			running = append(running, agentId)
+		}
+	}
+	for _, agentId := range trigger.Agents {
+		if !slices.Contains(running, agentId) {
+			http.Error(w, "test isn't running on agent "+agentId, http.StatusBadRequest)
+			return
+		}
+	}
+	if len(trigger.Agents) > 0 {
+		running = trigger.Agents
+	}
+	if len(running) == 0 {
+		http.Error(w, "test isn't running on any agent", http.StatusConflict)
+		return
+	}
+	slices.Sort(running)
+
+	b := make([]byte, 8)
+	_, err = rand.Read(b)
+	if err != nil {
+		r.logger.Error("error generating run id", "err", err)
+		http.Error(w, "unable to generate run id", http.StatusInternalServerError)
+		return
+	}
+	runId := hex.EncodeToString(b)
+	err = r.store.RequestTestRun(ctx, common.TestRunRequest{
+		Id:       runId,
+		ConfigId: configId,
+		Time:     time.Now(),
+		Agents:   trigger.Agents,
+	})
+	if err != nil {
+		r.logger.Error("error requesting test run", "id", configId, "err", err)
+		http.Error(w, "unable to trigger test", http.StatusInternalServerError)
+		return
+	}
+	r.logger.Info("triggered test", "id", configId, "runId", runId, "agents", len(running))
+	r.recordAuditEvent(ctx, common.AuditEvent{
+		Actor:   requestActor(req),
+		Kind:    common.AuditKindSynTest,
+		Object:  configId,
+		Action:  common.AuditActionTriggered,
+		Message: trigger.Comment,
+		Details: withClaimedActor(map[string]string{
+			"runId":  runId,
+			"agents": strings.Join(running, ","),
+		}, trigger.Actor),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(client.TriggerResponse{
+		RunId:    runId,
+		ConfigId: configId,
+		Agents:   running,
+	})
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// GetTriggeredRun Returns the results of a triggered run of a test (by agent id), the agents which haven't run it yet
+// are missing. The results are kept for a day.
+func (r *RestApi) GetTriggeredRun(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	runId := gmux.Vars(req)["runId"]
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	// Using HGetAllR to obtain the json of the test runs directly from redis instead using a function that parses it
+	results, err := r.store.HGetAllR(ctx, fmt.Sprintf(storage.TestRunRequestResultsFmt, runId))
+	if err != nil {
+		r.logger.Error("error fetching triggered run results", "id", configId, "runId", runId, "err", err)
+		http.Error(w, "unable to fetch triggered run", http.StatusInternalServerError)
+		return
+	}
+	run := client.TriggeredRun{RunId: runId, Results: map[string]json.RawMessage{}}
+	for agentId, testRun := range results {
+		var test struct {
+			TestConfig struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"testConfig"`
+		}
+		err = json.Unmarshal([]byte(testRun), &test)
+		if err != nil || common.ComputeSynTestConfigId(test.TestConfig.Name, test.TestConfig.Namespace) != configId {
+			continue // not a run of the test
+		}
+		run.Results[agentId] = json.RawMessage(testRun)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(run)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// GetRunLogs Returns the logs of a run of a test (in the namespace query param) by agent, on all the agents or the agent
+// of the query. The run id is either the id of a triggered run (the logs are in the results of the agents), or the id
+// of a test run (its forwarded logs, or the logs of the latest or last failed run of the agents if it's one of them).
+func (r *RestApi) GetRunLogs(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := r.syntestId(w, req)
+	if !ok {
+		return
+	}
+	runId, agent := gmux.Vars(req)["runId"], req.URL.Query().Get("agent")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	runLogs := client.RunLogs{RunId: runId, ConfigId: configId, Logs: map[string]string{}}
+	results, err := r.store.FetchTestRunRequestResults(ctx, runId)
+	if err != nil {
+		r.logger.Error("error fetching triggered run results", "id", configId, "runId", runId, "err", err)
+		http.Error(w, "unable to fetch run logs", http.StatusInternalServerError)
+		return
+	}
+	for agentId := range results {
+		if agent != "" && agentId != agent {
+			continue
+		}
+		// the test runs aren't copied, as they contain a lock
+		testConfig := results[agentId].TestConfig
+		if common.ComputeSynTestConfigId(testConfig.GetName(), testConfig.GetNamespace()) != configId {
+			continue // not a run of the test
+		}
+		logs, err := r.testRunLogs(ctx, configId+"/"+agentId, results[agentId].Id, results[agentId].Details)
+		if err != nil {
+			r.logger.Error("error fetching logs of triggered run", "id", configId, "runId", runId, "agent", agentId, "err", err)
+			http.Error(w, "unable to fetch run logs", http.StatusInternalServerError)
+			return
+		}
+		runLogs.Logs[agentId] = logs
+	}
+
+	if len(results) == 0 {
+		// not a triggered run, so the id of a test run on one of the agents
+		allStatus, err := r.store.FetchAllTestRunStatus(ctx)
+		if err != nil {
+			r.logger.Error("error fetching test run status", "err", err)
+			http.Error(w, "unable to fetch run logs", http.StatusInternalServerError)
+			return
+		}
+		for pluginId := range allStatus {
+			_, _, podName, podNs, err := common.GetPluginIdComponents(pluginId)
+			agentId := common.ComputeAgentId(podName, podNs)
+			if err != nil || testId(pluginId) != configId || (agent != "" && agentId != agent) {
+				continue
+			}
+			logs, found, err := r.pluginRunLogs(ctx, pluginId, runId)
+			if err != nil {
+				r.logger.Error("error fetching run logs", "id", pluginId, "runId", runId, "err", err)
+				http.Error(w, "unable to fetch run logs", http.StatusInternalServerError)
+				return
+			}
+			if found {
+				runLogs.Logs[agentId] = logs
+			}
+		}
+	}
+
+	if len(runLogs.Logs) == 0 {
+		http.Error(w, "no logs found for run "+runId, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(runLogs)
+	if err != nil {
+		r.logger.Error("error encoding json", "err", err)
+	}
+}
+
+// pluginRunLogs Returns the logs of a test run of a plugin, from the forwarded logs or the latest or last failed run of
+// the plugin, false if the plugin has no run with the id
+func (r *RestApi) pluginRunLogs(ctx context.Context, pluginId string, runId string) (string, bool, error) {
+	logs, err := r.store.FetchTestRunLogs(ctx, pluginId, runId)
+	if err == nil {
+		return logs, true, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", false, err
+	}
+	for _, fetch := range []func(context.Context, string) (proto.TestRun, error){r.store.FetchLatestTestRun,
+		r.store.FetchLastFailedTestRun} {
+		testRun, err := fetch(ctx, pluginId)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return "", false, err
+		}
+		if testRun.Id == runId {
+			return testRun.Details[common.LogKey], true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// testRunLogs Returns the logs in the details of a test run, or its forwarded logs if they aren't in the details (empty
+// if there are none)
+func (r *RestApi) testRunLogs(ctx context.Context, pluginId string, runId string, details map[string]string) (string, error) {
+	if logs, ok := details[common.LogKey]; ok {
+		return logs, nil
+	}
+	logs, err := r.store.FetchTestRunLogs(ctx, pluginId, runId)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", nil
+	}
+	return logs, err
+}
+
+// syntestId Returns the config id of the test of a request (name in the path and namespace query param), false if
+// it's invalid or not accessible (the error is written)
+func (r *RestApi) syntestId(w http.ResponseWriter, req *http.Request) (string, bool) {
+	name := gmux.Vars(req)["name"]
+	namespace := req.URL.Query().Get("namespace")
+	if namespace == "" {
+		http.Error(w, "no test namespace provided", http.StatusBadRequest)
+		return "", false
+	}
+	configId := common.ComputeSynTestConfigId(name, namespace)
+	if !canAccessTest(req, configId) {
+		http.Error(w, "no access to the test "+configId, http.StatusForbidden)
+		return "", false
+	}
+	return configId, true
+}
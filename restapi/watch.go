@@ -0,0 +1,288 @@
+// Copyright 2024 Cisco Systems, Inc. and its affiliates
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco-open/synthetic-heart/common"
+	"github.com/cisco-open/synthetic-heart/common/storage"
+	"github.com/cisco-open/synthetic-heart/restapi/client"
+	gmux "github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultWatchTimeout is how long a watch of the test configs lasts if the request has no timeoutSeconds
+	DefaultWatchTimeout = 5 * time.Minute
+	// MaxWatchTimeout is the longest a watch of the test configs can last
+	MaxWatchTimeout = time.Hour
+	// WatchResyncInterval is how often the watches compare the test configs in storage with the ones sent, in case a
+	// config event was missed (e.g. while resubscribing)
+	WatchResyncInterval = 30 * time.Second
+)
+
+// notifier Notifies the watches of the events in storage
+type notifier[E any] struct {
+	mutex    sync.Mutex
+	size     int // size of the channel of each watch
+	watchers map[chan E]bool
+}
+
+func newNotifier[E any](size int) *notifier[E] {
+	return &notifier[E]{size: size, watchers: map[chan E]bool{}}
+}
+
+// add Returns a channel notified (without blocking) of the events, and a func to stop the notifications
+func (n *notifier[E]) add() (<-chan E, func()) {
+	events := make(chan E, n.size)
+	n.mutex.Lock()
+	n.watchers[events] = true
+	n.mutex.Unlock()
+	return events, func() {
+		n.mutex.Lock()
+		delete(n.watchers, events)
+		n.mutex.Unlock()
+	}
+}
+
+// notify Sends the event to the watches, it's dropped for the watches whose channel is full
+func (n *notifier[E]) notify(event E) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for events := range n.watchers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// watchConfigEvents Notifies the watches of the test configs when a config is updated or deleted in storage
+func (r *RestApi) watchConfigEvents(ctx context.Context) {
+	events := make(chan string, 1000)
+	go func() {
+		for ctx.Err() == nil {
+			err := r.store.SubscribeToConfigEvents(ctx, 1000, events)
+			if err != nil {
+				r.logger.Warn("error subscribing to config events, retrying", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(PingRefreshFrequency):
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			// silences and agent profiles are published on the same channel
+			if strings.HasPrefix(event, "update ") || strings.HasPrefix(event, "deleting ") {
+				r.watchers.notify(struct{}{}) // the watches fetch the configs again, so one pending event is enough
+			}
+		}
+	}
+}
+
+// watchTimeout Parses the timeoutSeconds query param of a watch
+func watchTimeout(req *http.Request) (time.Duration, error) {
+	param := req.URL.Query().Get("timeoutSeconds")
+	if param == "" {
+		return DefaultWatchTimeout, nil
+	}
+	seconds, err := strconv.Atoi(param)
+	if err != nil || seconds <= 0 {
+		return 0, errors.New("invalid timeoutSeconds")
+	}
+	return min(time.Duration(seconds)*time.Second, MaxWatchTimeout), nil
+}
+
+// watchStream Writes the events of a watch as newline delimited json, flushing each event
+type watchStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	encoder *json.Encoder
+}
+
+func newWatchStream(w http.ResponseWriter) (*watchStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", client.WatchContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &watchStream{w: w, flusher: flusher, encoder: json.NewEncoder(w)}, true
+}
+
+func (s *watchStream) send(eventType string, object any) error {
+	err := s.encoder.Encode(client.WatchEvent[any]{Type: eventType, Object: object})
+	if err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// watch Sends the changes of the objects returned by fetch (by key) until the request is done or times out: ADDED for
+// the objects when the watch starts and the new ones, MODIFIED for the changed ones and DELETED (with the last object
+// sent) for the removed ones. A fetch error is sent as an ERROR event, which ends the watch.
+func watch[T any](r *RestApi, w http.ResponseWriter, req *http.Request, fetch func(ctx context.Context) (map[string]T, error)) {
+	timeout, err := watchTimeout(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+	notify, stop := r.watchers.add()
+	defer stop()
+
+	// fetch the objects before writing the response, so a watch that can't start fails with a status code
+	objects, err := fetchForWatch(ctx, fetch)
+	if err != nil {
+		r.logger.Error("error fetching the objects of a watch", "path", req.URL.Path, "err", err)
+		http.Error(w, "unable to start watch", http.StatusInternalServerError)
+		return
+	}
+	stream, ok := newWatchStream(w)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	sent := map[string]T{}
+	resync := time.NewTicker(WatchResyncInterval)
+	defer resync.Stop()
+	for {
+		for key, object := range objects {
+			previous, ok := sent[key]
+			eventType := client.WatchAdded
+			if ok {
+				if reflect.DeepEqual(previous, object) {
+					continue
+				}
+				eventType = client.WatchModified
+			}
+			if err := stream.send(eventType, object); err != nil {
+				r.logger.Debug("error sending watch event, client gone?", "path", req.URL.Path, "err", err)
+				return
+			}
+		}
+		for key, object := range sent {
+			if _, ok := objects[key]; ok {
+				continue
+			}
+			if err := stream.send(client.WatchDeleted, object); err != nil {
+				r.logger.Debug("error sending watch event, client gone?", "path", req.URL.Path, "err", err)
+				return
+			}
+		}
+		sent = objects
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		case <-resync.C:
+		}
+		objects, err = fetchForWatch(ctx, fetch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Error("error fetching the objects of a watch", "path", req.URL.Path, "err", err)
+			_ = stream.send(client.WatchError, client.WatchStatus{Message: "unable to fetch the watched objects"})
+			return
+		}
+	}
+}
+
+func fetchForWatch[T any](ctx context.Context, fetch func(ctx context.Context) (map[string]T, error)) (map[string]T, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return fetch(ctx)
+}
+
+// WatchAllTests Streams the changes of the summaries of the test configs (see watch), for ?watch=true on the summary
+func (r *RestApi) WatchAllTests(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	watch(r, w, req, func(ctx context.Context) (map[string]common.SyntestConfigSummary, error) {
+		syntests, err := r.store.FetchAllTestConfigSummary(ctx)
+		if err != nil {
+			return nil, err
+		}
+		maps.DeleteFunc(syntests, func(configId string, _ common.SyntestConfigSummary) bool {
+			return !canAccessTest(req, configId)
+		})
+		return syntests, nil
+	})
+}
+
+// WatchTestConfig Streams the changes of a test config (see watch), for ?watch=true on the test config. A config that
+// doesn't exist yet is ADDED when it's created.
+func (r *RestApi) WatchTestConfig(w http.ResponseWriter, req *http.Request) {
+	r.PrintIPAndUserAgent(req)
+	configId, ok := gmux.Vars(req)["id"]
+	if !ok {
+		http.Error(w, "no test id provided", http.StatusUnprocessableEntity)
+		return
+	}
+	watch(r, w, req, func(ctx context.Context) (map[string]client.TestConfig, error) {
+		testConfig, err := r.fetchTestConfig(ctx, configId)
+		if errors.Is(err, redis.Nil) {
+			return map[string]client.TestConfig{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]client.TestConfig{configId: testConfig}, nil
+	})
+}
+
+// fetchTestConfig Returns the config, raw config and status of a test config (redis.Nil if any of them is missing)
+func (r *RestApi) fetchTestConfig(ctx context.Context, configId string) (client.TestConfig, error) {
+	testConfig, err := r.store.GetR(ctx, fmt.Sprintf(storage.ConfigSynTestJsonFmt, configId))
+	if err != nil {
+		return client.TestConfig{}, errors.Wrap(err, "error getting config")
+	}
+	raw, err := r.store.GetR(ctx, fmt.Sprintf(storage.ConfigSynTestRawFmt, configId))
+	if err != nil {
+		return client.TestConfig{}, errors.Wrap(err, "error getting raw config")
+	}
+	status, err := r.store.GetR(ctx, fmt.Sprintf(storage.ConfigSynTestStatusFmt, configId))
+	if err != nil {
+		return client.TestConfig{}, errors.Wrap(err, "error getting config status")
+	}
+	return client.TestConfig{
+		TestConfig:   json.RawMessage(testConfig),
+		ConfigStatus: json.RawMessage(status),
+		RawConfig:    raw,
+	}, nil
+}